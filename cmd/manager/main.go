@@ -14,18 +14,25 @@ import (
 	"time"
 
 	"github.com/elastic/cloud-on-k8s/pkg/about"
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
 	apmv1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1"
 	apmv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1beta1"
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	esv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1beta1"
 	entsv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/enterprisesearch/v1beta1"
 	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
 	kbv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1beta1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/agent"
+	agentassn "github.com/elastic/cloud-on-k8s/pkg/controller/agentassociation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver"
 	asesassn "github.com/elastic/cloud-on-k8s/pkg/controller/apmserverelasticsearchassociation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/beat"
+	beatassn "github.com/elastic/cloud-on-k8s/pkg/controller/beatassociation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/container"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
 	controllerscheme "github.com/elastic/cloud-on-k8s/pkg/controller/common/scheme"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
@@ -36,11 +43,14 @@ import (
 	kbassn "github.com/elastic/cloud-on-k8s/pkg/controller/kibanaassociation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/license"
 	licensetrial "github.com/elastic/cloud-on-k8s/pkg/controller/license/trial"
+	monitoringassn "github.com/elastic/cloud-on-k8s/pkg/controller/monitoringassociation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/nodetuning"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/remoteca"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/webhook"
 	"github.com/elastic/cloud-on-k8s/pkg/dev"
 	"github.com/elastic/cloud-on-k8s/pkg/dev/portforward"
 	licensing "github.com/elastic/cloud-on-k8s/pkg/license"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/net"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/rbac"
 	"github.com/spf13/cobra"
@@ -49,6 +59,7 @@ import (
 	"go.uber.org/automaxprocs/maxprocs"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp" // allow gcp authentication
@@ -64,6 +75,11 @@ const (
 	DefaultMetricPort        = 0 // disabled
 	WebhookConfigurationName = "elastic-webhook.k8s.elastic.co"
 	WebhookPort              = 9443
+
+	DefaultLeaderElectionID            = "elastic-operator-leader"
+	DefaultLeaderElectionLeaseDuration = 15 * time.Second
+	DefaultLeaderElectionRenewDeadline = 10 * time.Second
+	DefaultLeaderElectionRetryPeriod   = 2 * time.Second
 )
 
 var (
@@ -98,6 +114,12 @@ func init() {
 		certificates.DefaultCertValidity,
 		"Duration representing how long before a newly created CA cert expires",
 	)
+	Cmd.Flags().String(
+		operator.CertManagerIssuerFlag,
+		"",
+		"cert-manager issuer of the TLS certificates, formatted as Issuer/<name> or ClusterIssuer/<name>. "+
+			"Certificates are self-signed if empty",
+	)
 	Cmd.Flags().Duration(
 		operator.CertRotateBeforeFlag,
 		certificates.DefaultRotateBefore,
@@ -123,6 +145,11 @@ func init() {
 		false, // Set to false for backward compatibility
 		"Restrict cross-namespace resource association through RBAC (eg. referencing Elasticsearch from Kibana)",
 	)
+	Cmd.Flags().Bool(
+		operator.EnableLeaderElectionFlag,
+		true,
+		"Enable leader election. Enabling this will ensure there is only one active operator.",
+	)
 	Cmd.Flags().Bool(
 		operator.EnableTracingFlag,
 		false,
@@ -132,6 +159,43 @@ func init() {
 		false,
 		"Enables a validating webhook server in the operator process.",
 	)
+	Cmd.Flags().String(
+		operator.LeaderElectionIDFlag,
+		DefaultLeaderElectionID,
+		"Name of the ConfigMap used as a lock for leader election",
+	)
+	Cmd.Flags().Duration(
+		operator.LeaderElectionLeaseDurationFlag,
+		DefaultLeaderElectionLeaseDuration,
+		"Duration non-leader operators wait before attempting to acquire the leadership",
+	)
+	Cmd.Flags().String(
+		operator.LeaderElectionNamespaceFlag,
+		"",
+		"K8s namespace of the ConfigMap used as a lock for leader election (defaults to the operator namespace)",
+	)
+	Cmd.Flags().Duration(
+		operator.LeaderElectionRenewDeadlineFlag,
+		DefaultLeaderElectionRenewDeadline,
+		"Duration the leader operator retries refreshing the leadership before giving it up",
+	)
+	Cmd.Flags().Duration(
+		operator.LeaderElectionRetryPeriodFlag,
+		DefaultLeaderElectionRetryPeriod,
+		"Duration operators wait between tries of leader election actions",
+	)
+	Cmd.Flags().Bool(
+		operator.ManageNetworkPoliciesFlag,
+		false,
+		"Reconciles NetworkPolicies allowing the traffic the operator knows about to Elasticsearch, Kibana and Enterprise Search Pods, "+
+			"unless disabled on a per-resource basis",
+	)
+	Cmd.Flags().Bool(
+		operator.ManageNodeTuningFlag,
+		false,
+		"Raises the vm.max_map_count kernel setting of the Kubernetes nodes hosting Elasticsearch Pods through a privileged DaemonSet "+
+			"in the operator namespace. Requires permissions to update nodes",
+	)
 	Cmd.Flags().Bool(
 		operator.ManageWebhookCertsFlag,
 		true,
@@ -152,11 +216,21 @@ func init() {
 		nil,
 		"comma-separated list of namespaces in which this operator should manage resources (defaults to all namespaces)",
 	)
+	Cmd.Flags().String(
+		operator.NodeTuningImageFlag,
+		nodetuning.DefaultImage,
+		"Container image of the node tuning DaemonSet, which must provide sh and sysctl",
+	)
 	Cmd.Flags().String(
 		operator.OperatorNamespaceFlag,
 		"",
 		"K8s namespace the operator runs in",
 	)
+	Cmd.Flags().Bool(
+		operator.ValidateStorageClassFlag,
+		true,
+		"Specifies whether the operator should retrieve storage classes to verify volume expansion support. Can be disabled if cluster-wide storage class RBAC access is not available.",
+	)
 	Cmd.Flags().String(
 		operator.WebhookCertDirFlag,
 		// this is controller-runtime's own default, copied here for making the default explicit when using `--help`
@@ -280,6 +354,9 @@ func execute() {
 	opts.MetricsBindAddress = fmt.Sprintf(":%d", metricsPort) // 0 to disable
 
 	opts.Port = WebhookPort
+
+	setupLeaderElection(&opts, operatorNamespace)
+
 	mgr, err := ctrl.NewManager(cfg, opts)
 	if err != nil {
 		log.Error(err, "unable to create controller manager")
@@ -291,6 +368,14 @@ func execute() {
 	log.V(1).Info("Using certificate authority rotation parameters", operator.CACertValidityFlag, caCertValidity, operator.CACertRotateBeforeFlag, caCertRotateBefore)
 	certValidity, certRotateBefore := ValidateCertExpirationFlags(operator.CertValidityFlag, operator.CertRotateBeforeFlag)
 	log.V(1).Info("Using certificate rotation parameters", operator.CertValidityFlag, certValidity, operator.CertRotateBeforeFlag, certRotateBefore)
+	certManagerIssuer, err := certificates.ParseIssuerRef(viper.GetString(operator.CertManagerIssuerFlag))
+	if err != nil {
+		log.Error(err, "invalid cert-manager issuer", "flag", operator.CertManagerIssuerFlag)
+		os.Exit(1)
+	}
+	if certManagerIssuer != nil {
+		log.Info("Issuing TLS certificates with cert-manager", "issuer", certManagerIssuer.String())
+	}
 
 	// Setup a client to set the operator uuid config map
 	clientset, err := kubernetes.NewForConfig(cfg)
@@ -304,6 +389,16 @@ func execute() {
 		log.Error(err, "unable to get operator info")
 		os.Exit(1)
 	}
+	serverVersion, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		log.Error(err, "unable to get the Kubernetes version")
+		os.Exit(1)
+	}
+	networkPoliciesSupported, err := networkpolicy.Supported(serverVersion)
+	if err != nil {
+		log.Error(err, "unable to parse the Kubernetes version", "version", serverVersion.GitVersion)
+		os.Exit(1)
+	}
 	log.Info("Setting up controllers")
 	var tracer *apm.Tracer
 	if viper.GetBool(operator.EnableTracingFlag) {
@@ -321,12 +416,18 @@ func execute() {
 			Validity:     certValidity,
 			RotateBefore: certRotateBefore,
 		},
-		MaxConcurrentReconciles: viper.GetInt(operator.MaxConcurrentReconcilesFlag),
-		Tracer:                  tracer,
+		CertManagerIssuer:        certManagerIssuer,
+		ManageNetworkPolicies:    viper.GetBool(operator.ManageNetworkPoliciesFlag),
+		NetworkPoliciesSupported: networkPoliciesSupported,
+		ManageNodeTuning:         viper.GetBool(operator.ManageNodeTuningFlag),
+		NodeTuningImage:          viper.GetString(operator.NodeTuningImageFlag),
+		MaxConcurrentReconciles:  viper.GetInt(operator.MaxConcurrentReconcilesFlag),
+		Tracer:                   tracer,
+		ValidateStorageClass:     viper.GetBool(operator.ValidateStorageClassFlag),
 	}
 
 	if viper.GetBool(operator.EnableWebhookFlag) {
-		setupWebhook(mgr, params.CertRotation, clientset)
+		setupWebhook(mgr, params, clientset)
 	}
 
 	enforceRbacOnRefs := viper.GetBool(operator.EnforceRBACOnRefsFlag)
@@ -354,6 +455,14 @@ func execute() {
 		log.Error(err, "unable to create controller", "controller", "EnterpriseSearch")
 		os.Exit(1)
 	}
+	if err = beat.Add(mgr, params); err != nil {
+		log.Error(err, "unable to create controller", "controller", "Beat")
+		os.Exit(1)
+	}
+	if err = agent.Add(mgr, params); err != nil {
+		log.Error(err, "unable to create controller", "controller", "Agent")
+		os.Exit(1)
+	}
 	if err = asesassn.Add(mgr, accessReviewer, params); err != nil {
 		log.Error(err, "unable to create controller", "controller", "ApmServerElasticsearchAssociation")
 		os.Exit(1)
@@ -366,11 +475,39 @@ func execute() {
 		log.Error(err, "unable to create controller", "controller", "EnterpriseSearchAssociation")
 		os.Exit(1)
 	}
+	if err = beatassn.Add(mgr, accessReviewer, params); err != nil {
+		log.Error(err, "unable to create controller", "controller", "BeatAssociation")
+		os.Exit(1)
+	}
+	if err = agentassn.Add(mgr, accessReviewer, params); err != nil {
+		log.Error(err, "unable to create controller", "controller", "AgentAssociation")
+		os.Exit(1)
+	}
+	if err = monitoringassn.Add(mgr, accessReviewer, params); err != nil {
+		log.Error(err, "unable to create controller", "controller", "MonitoringAssociation")
+		os.Exit(1)
+	}
 	if err = remoteca.Add(mgr, accessReviewer, params); err != nil {
 		log.Error(err, "unable to create controller", "controller", "RemoteClusterCertificateAuthorites")
 		os.Exit(1)
 	}
 
+	if params.ManageNodeTuning {
+		if err = nodetuning.Add(mgr, params); err != nil {
+			log.Error(err, "unable to create controller", "controller", "NodeTuning")
+			os.Exit(1)
+		}
+	} else if err := mgr.Add(manager.RunnableFunc(func(<-chan struct{}) error {
+		// Remove the node tuning DaemonSet and node labels left over from a previous run with node tuning enabled.
+		if err := nodetuning.GarbageCollect(k8s.WrapClient(mgr.GetClient()), mgr.GetAPIReader(), operatorNamespace); err != nil {
+			log.Error(err, "node tuning garbage collection failed")
+		}
+		return nil
+	})); err != nil {
+		log.Error(err, "unable to add the node tuning garbage collector to the manager")
+		os.Exit(1)
+	}
+
 	if err = license.Add(mgr, params); err != nil {
 		log.Error(err, "unable to create controller", "controller", "License")
 		os.Exit(1)
@@ -380,15 +517,23 @@ func execute() {
 		os.Exit(1)
 	}
 
-	// Garbage collect any orphaned user Secrets leftover from deleted resources while the operator was not running.
-	garbageCollectUsers(cfg, managedNamespaces)
-
-	go func() {
-		time.Sleep(10 * time.Second)         // wait some arbitrary time for the manager to start
-		mgr.GetCache().WaitForCacheSync(nil) // wait until k8s client cache is initialized
+	// The following runnables are only started by the elected leader, once the manager cache is synced.
+	if err := mgr.Add(manager.RunnableFunc(func(<-chan struct{}) error {
+		// Garbage collect any orphaned user Secrets leftover from deleted resources while the operator was not running.
+		garbageCollectUsers(cfg, managedNamespaces)
+		return nil
+	})); err != nil {
+		log.Error(err, "unable to add the users garbage collector to the manager")
+		os.Exit(1)
+	}
+	if err := mgr.Add(manager.RunnableFunc(func(<-chan struct{}) error {
 		r := licensing.NewResourceReporter(mgr.GetClient())
 		r.Start(operatorNamespace, licensing.ResourceReporterFrequency)
-	}()
+		return nil
+	})); err != nil {
+		log.Error(err, "unable to add the resource reporter to the manager")
+		os.Exit(1)
+	}
 
 	log.Info("Starting the manager", "uuid", operatorInfo.OperatorUUID,
 		"namespace", operatorNamespace, "version", operatorInfo.BuildInfo.Version,
@@ -400,6 +545,41 @@ func execute() {
 	}
 }
 
+// setupLeaderElection configures the leader election of the manager: when enabled, only the elected operator runs
+// the controllers and the runnables requiring leader election, while the others stand by.
+func setupLeaderElection(opts *ctrl.Options, operatorNamespace string) {
+	opts.LeaderElection = viper.GetBool(operator.EnableLeaderElectionFlag)
+	if !opts.LeaderElection {
+		log.Info("Leader election disabled")
+		return
+	}
+
+	opts.LeaderElectionID = viper.GetString(operator.LeaderElectionIDFlag)
+	opts.LeaderElectionNamespace = viper.GetString(operator.LeaderElectionNamespaceFlag)
+	if opts.LeaderElectionNamespace == "" {
+		opts.LeaderElectionNamespace = operatorNamespace
+	}
+
+	leaseDuration := viper.GetDuration(operator.LeaderElectionLeaseDurationFlag)
+	renewDeadline := viper.GetDuration(operator.LeaderElectionRenewDeadlineFlag)
+	retryPeriod := viper.GetDuration(operator.LeaderElectionRetryPeriodFlag)
+	if renewDeadline >= leaseDuration {
+		log.Error(fmt.Errorf("%s must be larger than %s", operator.LeaderElectionLeaseDurationFlag, operator.LeaderElectionRenewDeadlineFlag), "")
+		os.Exit(1)
+	}
+	if retryPeriod >= renewDeadline {
+		log.Error(fmt.Errorf("%s must be larger than %s", operator.LeaderElectionRenewDeadlineFlag, operator.LeaderElectionRetryPeriodFlag), "")
+		os.Exit(1)
+	}
+	opts.LeaseDuration = &leaseDuration
+	opts.RenewDeadline = &renewDeadline
+	opts.RetryPeriod = &retryPeriod
+
+	log.Info("Leader election enabled", "id", opts.LeaderElectionID, "namespace", opts.LeaderElectionNamespace,
+		operator.LeaderElectionLeaseDurationFlag, leaseDuration, operator.LeaderElectionRenewDeadlineFlag, renewDeadline,
+		operator.LeaderElectionRetryPeriodFlag, retryPeriod)
+}
+
 func ValidateCertExpirationFlags(validityFlag string, rotateBeforeFlag string) (time.Duration, time.Duration) {
 	certValidity := viper.GetDuration(validityFlag)
 	certRotateBefore := viper.GetDuration(rotateBeforeFlag)
@@ -419,6 +599,10 @@ func garbageCollectUsers(cfg *rest.Config, managedNamespaces []string) {
 	err = ugc.
 		For(&apmv1.ApmServerList{}, asesassn.AssociationLabelNamespace, asesassn.AssociationLabelName).
 		For(&kbv1.KibanaList{}, kbassn.AssociationLabelNamespace, kbassn.AssociationLabelName).
+		For(&beatv1beta1.BeatList{}, beatassn.AssociationLabelNamespace, beatassn.AssociationLabelName).
+		For(&agentv1alpha1.AgentList{}, agentassn.AssociationLabelNamespace, agentassn.AssociationLabelName).
+		For(&esv1.ElasticsearchList{}, monitoringassn.ElasticsearchAssociationLabelNamespace, monitoringassn.ElasticsearchAssociationLabelName).
+		For(&kbv1.KibanaList{}, monitoringassn.KibanaAssociationLabelNamespace, monitoringassn.KibanaAssociationLabelName).
 		DoGarbageCollection()
 	if err != nil {
 		log.Error(err, "user garbage collector failed")
@@ -426,7 +610,7 @@ func garbageCollectUsers(cfg *rest.Config, managedNamespaces []string) {
 	}
 }
 
-func setupWebhook(mgr manager.Manager, certRotation certificates.RotationParams, clientset kubernetes.Interface) {
+func setupWebhook(mgr manager.Manager, params operator.Parameters, clientset kubernetes.Interface) {
 	manageWebhookCerts := viper.GetBool(operator.ManageWebhookCertsFlag)
 	if manageWebhookCerts {
 		log.Info("Automatic management of the webhook certificates enabled")
@@ -435,10 +619,14 @@ func setupWebhook(mgr manager.Manager, certRotation certificates.RotationParams,
 			Namespace:                viper.GetString(operator.OperatorNamespaceFlag),
 			SecretName:               viper.GetString(operator.WebhookSecretFlag),
 			WebhookConfigurationName: WebhookConfigurationName,
-			Rotation:                 certRotation,
+			Rotation:                 params.CertRotation,
+			CertManagerIssuer:        params.CertManagerIssuer,
+			DynamicClient:            dynamic.NewForConfigOrDie(mgr.GetConfig()),
 		}
 
-		// Force a first reconciliation to create the resources before the server is started
+		// Force a first reconciliation to create the resources before the server is started.
+		// This is done by all the operators, since each of them serves the webhook, but the reconciliation is
+		// idempotent: afterwards certificates are only rotated by the webhook controller, running on the leader.
 		if err := webhookParams.ReconcileResources(clientset); err != nil {
 			log.Error(err, "unable to setup and fill the webhook certificates")
 			os.Exit(1)
@@ -455,10 +643,15 @@ func setupWebhook(mgr manager.Manager, certRotation certificates.RotationParams,
 		runtime.Object
 		SetupWebhookWithManager(manager.Manager) error
 	}{
+		&agentv1alpha1.Agent{},
 		&apmv1.ApmServer{},
 		&apmv1beta1.ApmServer{},
+		&beatv1beta1.Beat{},
 		&entsv1beta1.EnterpriseSearch{},
 		&esv1.Elasticsearch{},
+		&esv1.ElasticsearchUser{},
+		&esv1.ElasticsearchRole{},
+		&esv1.ElasticsearchRoleMapping{},
 		&esv1beta1.Elasticsearch{},
 		&kbv1.Kibana{},
 		&kbv1beta1.Kibana{},
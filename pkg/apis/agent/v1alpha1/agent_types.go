@@ -0,0 +1,260 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1alpha1
+
+import (
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentMode defines how the Elastic Agent is managed.
+type AgentMode string
+
+const (
+	// AgentStandaloneMode denotes running the Elastic Agent with a configuration provided in the resource spec.
+	AgentStandaloneMode AgentMode = "standalone"
+	// AgentFleetMode denotes running the Elastic Agent enrolled into Fleet, which then manages its configuration.
+	AgentFleetMode AgentMode = "fleet"
+)
+
+// AgentSpec defines the desired state of an Elastic Agent.
+type AgentSpec struct {
+	// Version of the Elastic Agent.
+	Version string `json:"version"`
+
+	// Mode specifies the management mode of the Elastic Agent: `standalone` or `fleet`. Defaults to `standalone`.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=standalone;fleet
+	Mode AgentMode `json:"mode,omitempty"`
+
+	// FleetServerEnabled determines whether this Elastic Agent runs a Fleet Server, other Elastic Agents can enroll
+	// into. Can only be used in `fleet` mode.
+	// +kubebuilder:validation:Optional
+	FleetServerEnabled bool `json:"fleetServerEnabled,omitempty"`
+
+	// ElasticsearchRef is a reference to an Elasticsearch cluster running in the same Kubernetes cluster.
+	// In `standalone` mode, it is used as the default output. In `fleet` mode, it is used by the Fleet Server.
+	ElasticsearchRef commonv1.ObjectSelector `json:"elasticsearchRef,omitempty"`
+
+	// KibanaRef is a reference to a Kibana instance running in the same Kubernetes cluster.
+	// It is used to set up Fleet and to retrieve enrollment tokens in `fleet` mode.
+	KibanaRef commonv1.ObjectSelector `json:"kibanaRef,omitempty"`
+
+	// FleetServerRef is a reference to an Elastic Agent running a Fleet Server in the same Kubernetes cluster,
+	// this Elastic Agent enrolls into. Can only be used in `fleet` mode, when the Fleet Server is not enabled.
+	FleetServerRef commonv1.ObjectSelector `json:"fleetServerRef,omitempty"`
+
+	// Image is the Elastic Agent Docker image to deploy.
+	// +kubebuilder:validation:Optional
+	Image string `json:"image,omitempty"`
+
+	// Config holds the Elastic Agent configuration. Can only be used in `standalone` mode.
+	// +kubebuilder:validation:Optional
+	Config *commonv1.Config `json:"config,omitempty"`
+
+	// HTTP holds the HTTP layer configuration for the Fleet Server.
+	// +kubebuilder:validation:Optional
+	HTTP commonv1.HTTPConfig `json:"http,omitempty"`
+
+	// SecureSettings is a list of references to Kubernetes secrets containing sensitive configuration options
+	// for the Elastic Agent.
+	// +kubebuilder:validation:Optional
+	SecureSettings []commonv1.SecretSource `json:"secureSettings,omitempty"`
+
+	// DaemonSet specifies the Elastic Agent should be deployed as a DaemonSet, and allows providing its spec.
+	// Cannot be used along with `deployment`.
+	// +kubebuilder:validation:Optional
+	DaemonSet *DaemonSetSpec `json:"daemonSet,omitempty"`
+
+	// Deployment specifies the Elastic Agent should be deployed as a Deployment, and allows providing its spec.
+	// Cannot be used along with `daemonSet`.
+	// +kubebuilder:validation:Optional
+	Deployment *DeploymentSpec `json:"deployment,omitempty"`
+
+	// ServiceAccountName is used to check access from the current resource to Elasticsearch, Kibana and Fleet Server
+	// resources in a different namespace. Can only be used if ECK is enforcing RBAC on references.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// DaemonSetSpec holds the specification of an Elastic Agent deployed as a DaemonSet.
+type DaemonSetSpec struct {
+	// PodTemplate provides customisation options (labels, annotations, affinity rules, resource requests, and so on)
+	// for the Elastic Agent pods.
+	// +kubebuilder:validation:Optional
+	PodTemplate corev1.PodTemplateSpec `json:"podTemplate,omitempty"`
+
+	// UpdateStrategy is the DaemonSet update strategy used to replace existing Elastic Agent pods.
+	// +kubebuilder:validation:Optional
+	UpdateStrategy appsv1.DaemonSetUpdateStrategy `json:"updateStrategy,omitempty"`
+}
+
+// DeploymentSpec holds the specification of an Elastic Agent deployed as a Deployment.
+type DeploymentSpec struct {
+	// PodTemplate provides customisation options (labels, annotations, affinity rules, resource requests, and so on)
+	// for the Elastic Agent pods.
+	// +kubebuilder:validation:Optional
+	PodTemplate corev1.PodTemplateSpec `json:"podTemplate,omitempty"`
+
+	// Replicas is the number of Elastic Agent pods to run. Defaults to 1.
+	// +kubebuilder:validation:Optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Strategy is the Deployment strategy used to replace existing Elastic Agent pods.
+	// +kubebuilder:validation:Optional
+	Strategy appsv1.DeploymentStrategy `json:"strategy,omitempty"`
+}
+
+// AgentHealth expresses the health of the Elastic Agent instances.
+type AgentHealth string
+
+const (
+	// AgentRedHealth means no instance is currently available.
+	AgentRedHealth AgentHealth = "red"
+	// AgentYellowHealth means some, but not all, of the expected instances are available.
+	AgentYellowHealth AgentHealth = "yellow"
+	// AgentGreenHealth means all the expected instances are available.
+	AgentGreenHealth AgentHealth = "green"
+)
+
+// AgentStatus defines the observed state of an Elastic Agent.
+type AgentStatus struct {
+	// AvailableNodes is the number of available Elastic Agent pods.
+	// +kubebuilder:validation:Optional
+	AvailableNodes int32 `json:"availableNodes,omitempty"`
+
+	// ExpectedNodes is the number of Elastic Agent pods that should be running.
+	// +kubebuilder:validation:Optional
+	ExpectedNodes int32 `json:"expectedNodes,omitempty"`
+
+	// Health of the deployment.
+	// +kubebuilder:validation:Optional
+	Health AgentHealth `json:"health,omitempty"`
+
+	// ElasticsearchAssociationStatus is the status of the association to an Elasticsearch cluster.
+	// +kubebuilder:validation:Optional
+	ElasticsearchAssociationStatus commonv1.AssociationStatus `json:"elasticsearchAssociationStatus,omitempty"`
+
+	// KibanaAssociationStatus is the status of the association to a Kibana instance.
+	// +kubebuilder:validation:Optional
+	KibanaAssociationStatus commonv1.AssociationStatus `json:"kibanaAssociationStatus,omitempty"`
+
+	// FleetServerAssociationStatus is the status of the association to a Fleet Server.
+	// +kubebuilder:validation:Optional
+	FleetServerAssociationStatus commonv1.AssociationStatus `json:"fleetServerAssociationStatus,omitempty"`
+}
+
+// IsDegraded returns true if the current status is worse than the previous.
+func (as AgentStatus) IsDegraded(prev AgentStatus) bool {
+	return prev.Health == AgentGreenHealth && as.Health != AgentGreenHealth
+}
+
+// +kubebuilder:object:root=true
+
+// Agent is the Schema for the Agents API.
+// +kubebuilder:resource:categories=elastic,shortName=agent
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="health",type="string",JSONPath=".status.health"
+// +kubebuilder:printcolumn:name="available",type="integer",JSONPath=".status.availableNodes",description="Available nodes"
+// +kubebuilder:printcolumn:name="expected",type="integer",JSONPath=".status.expectedNodes",description="Expected nodes"
+// +kubebuilder:printcolumn:name="version",type="string",JSONPath=".spec.version",description="Agent version"
+// +kubebuilder:printcolumn:name="age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:storageversion
+type Agent struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec        AgentSpec                 `json:"spec,omitempty"`
+	Status      AgentStatus               `json:"status,omitempty"`
+	esAssocConf *commonv1.AssociationConf `json:"-"` //nolint:govet
+	kbAssocConf *commonv1.AssociationConf `json:"-"` //nolint:govet
+	fsAssocConf *commonv1.AssociationConf `json:"-"` //nolint:govet
+}
+
+// IsMarkedForDeletion returns true if the Elastic Agent is going to be deleted
+func (a *Agent) IsMarkedForDeletion() bool {
+	return !a.DeletionTimestamp.IsZero()
+}
+
+// Mode returns the management mode of the Elastic Agent, defaulting to standalone.
+func (a *Agent) Mode() AgentMode {
+	if a.Spec.Mode == "" {
+		return AgentStandaloneMode
+	}
+	return a.Spec.Mode
+}
+
+// FleetModeEnabled returns true if the Elastic Agent is enrolled into Fleet.
+func (a *Agent) FleetModeEnabled() bool {
+	return a.Mode() == AgentFleetMode
+}
+
+// FleetServerEnabled returns true if the Elastic Agent runs a Fleet Server.
+func (a *Agent) FleetServerEnabled() bool {
+	return a.FleetModeEnabled() && a.Spec.FleetServerEnabled
+}
+
+func (a *Agent) ServiceAccountName() string {
+	return a.Spec.ServiceAccountName
+}
+
+func (a *Agent) SecureSettings() []commonv1.SecretSource {
+	return a.Spec.SecureSettings
+}
+
+func (a *Agent) ElasticsearchRef() commonv1.ObjectSelector {
+	return a.Spec.ElasticsearchRef
+}
+
+func (a *Agent) AssociationConf() *commonv1.AssociationConf {
+	return a.esAssocConf
+}
+
+func (a *Agent) SetAssociationConf(assocConf *commonv1.AssociationConf) {
+	a.esAssocConf = assocConf
+}
+
+func (a *Agent) KibanaRef() commonv1.ObjectSelector {
+	return a.Spec.KibanaRef
+}
+
+// KibanaAssociationConf returns the configuration of the association to Kibana, if any.
+func (a *Agent) KibanaAssociationConf() *commonv1.AssociationConf {
+	return a.kbAssocConf
+}
+
+// SetKibanaAssociationConf sets the configuration of the association to Kibana.
+func (a *Agent) SetKibanaAssociationConf(assocConf *commonv1.AssociationConf) {
+	a.kbAssocConf = assocConf
+}
+
+func (a *Agent) FleetServerRef() commonv1.ObjectSelector {
+	return a.Spec.FleetServerRef
+}
+
+// FleetServerAssociationConf returns the configuration of the association to a Fleet Server, if any.
+func (a *Agent) FleetServerAssociationConf() *commonv1.AssociationConf {
+	return a.fsAssocConf
+}
+
+// SetFleetServerAssociationConf sets the configuration of the association to a Fleet Server.
+func (a *Agent) SetFleetServerAssociationConf(assocConf *commonv1.AssociationConf) {
+	a.fsAssocConf = assocConf
+}
+
+// +kubebuilder:object:root=true
+
+// AgentList contains a list of Elastic Agents.
+type AgentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Agent `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Agent{}, &AgentList{})
+}
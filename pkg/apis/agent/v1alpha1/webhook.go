@@ -0,0 +1,168 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var (
+	groupKind     = schema.GroupKind{Group: GroupVersion.Group, Kind: "Agent"}
+	validationLog = logf.Log.WithName("agent-v1alpha1-validation")
+
+	// FleetMinVersion is the lowest version from which Elastic Agents can be managed in Fleet mode,
+	// as the Fleet Server was introduced in 7.13.0.
+	FleetMinVersion = version.From(7, 13, 0)
+
+	defaultChecks = []func(*Agent) field.ErrorList{
+		checkNoUnknownFields,
+		checkNameLength,
+		checkSupportedVersion,
+		checkSpec,
+		checkModeSettings,
+	}
+
+	updateChecks = []func(old, curr *Agent) field.ErrorList{
+		checkNoDowngrade,
+	}
+)
+
+// +kubebuilder:webhook:path=/validate-agent-k8s-elastic-co-v1alpha1-agent,mutating=false,failurePolicy=ignore,groups=agent.k8s.elastic.co,resources=agents,verbs=create;update,versions=v1alpha1,name=elastic-agent-validation-v1alpha1.k8s.elastic.co
+
+var _ webhook.Validator = &Agent{}
+
+func (a *Agent) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(a).
+		Complete()
+}
+
+func (a *Agent) ValidateCreate() error {
+	validationLog.V(1).Info("Validate create", "name", a.Name)
+	return a.validate(nil)
+}
+
+func (a *Agent) ValidateDelete() error {
+	validationLog.V(1).Info("Validate delete", "name", a.Name)
+	return nil
+}
+
+func (a *Agent) ValidateUpdate(old runtime.Object) error {
+	validationLog.V(1).Info("Validate update", "name", a.Name)
+	oldObj, ok := old.(*Agent)
+	if !ok {
+		return errors.New("cannot cast old object to Agent type")
+	}
+
+	return a.validate(oldObj)
+}
+
+func (a *Agent) validate(old *Agent) error {
+	var errors field.ErrorList
+	if old != nil {
+		for _, uc := range updateChecks {
+			if err := uc(old, a); err != nil {
+				errors = append(errors, err...)
+			}
+		}
+
+		if len(errors) > 0 {
+			return apierrors.NewInvalid(groupKind, a.Name, errors)
+		}
+	}
+
+	for _, dc := range defaultChecks {
+		if err := dc(a); err != nil {
+			errors = append(errors, err...)
+		}
+	}
+
+	if len(errors) > 0 {
+		return apierrors.NewInvalid(groupKind, a.Name, errors)
+	}
+	return nil
+}
+
+func checkNoUnknownFields(a *Agent) field.ErrorList {
+	return commonv1.NoUnknownFields(a, a.ObjectMeta)
+}
+
+func checkNameLength(a *Agent) field.ErrorList {
+	return commonv1.CheckNameLength(a)
+}
+
+func checkSupportedVersion(a *Agent) field.ErrorList {
+	return commonv1.CheckSupportedStackVersion(a.Spec.Version, version.SupportedAgentVersions)
+}
+
+func checkSpec(a *Agent) field.ErrorList {
+	var errs field.ErrorList
+	if (a.Spec.DaemonSet == nil && a.Spec.Deployment == nil) || (a.Spec.DaemonSet != nil && a.Spec.Deployment != nil) {
+		errs = append(errs, field.Forbidden(
+			field.NewPath("spec"),
+			"Exactly one of daemonSet or deployment must be specified",
+		))
+	}
+	return errs
+}
+
+func checkModeSettings(a *Agent) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	switch a.Mode() {
+	case AgentStandaloneMode:
+		if a.Spec.Config == nil {
+			errs = append(errs, field.Required(specPath.Child("config"), "Elastic Agent configuration must be specified in standalone mode"))
+		}
+		if a.Spec.FleetServerEnabled {
+			errs = append(errs, field.Forbidden(specPath.Child("fleetServerEnabled"), "Fleet Server can only be enabled in fleet mode"))
+		}
+		if a.Spec.KibanaRef.IsDefined() {
+			errs = append(errs, field.Forbidden(specPath.Child("kibanaRef"), "kibanaRef can only be used in fleet mode"))
+		}
+		if a.Spec.FleetServerRef.IsDefined() {
+			errs = append(errs, field.Forbidden(specPath.Child("fleetServerRef"), "fleetServerRef can only be used in fleet mode"))
+		}
+	case AgentFleetMode:
+		if v, err := version.Parse(a.Spec.Version); err == nil && !v.IsSameOrAfter(FleetMinVersion) {
+			errs = append(errs, field.Invalid(specPath.Child("version"), a.Spec.Version,
+				fmt.Sprintf("fleet mode requires version %s or higher", FleetMinVersion)))
+		}
+		if a.Spec.Config != nil {
+			errs = append(errs, field.Forbidden(specPath.Child("config"), "Elastic Agent configuration is managed by Fleet in fleet mode"))
+		}
+		if !a.Spec.KibanaRef.IsDefined() {
+			errs = append(errs, field.Required(specPath.Child("kibanaRef"), "kibanaRef is required in fleet mode"))
+		}
+		if a.Spec.FleetServerEnabled {
+			if !a.Spec.ElasticsearchRef.IsDefined() {
+				errs = append(errs, field.Required(specPath.Child("elasticsearchRef"), "elasticsearchRef is required when Fleet Server is enabled"))
+			}
+			if a.Spec.FleetServerRef.IsDefined() {
+				errs = append(errs, field.Forbidden(specPath.Child("fleetServerRef"), "fleetServerRef cannot be used when Fleet Server is enabled"))
+			}
+		} else if !a.Spec.FleetServerRef.IsDefined() {
+			errs = append(errs, field.Required(specPath.Child("fleetServerRef"), "fleetServerRef is required in fleet mode when Fleet Server is not enabled"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(specPath.Child("mode"), a.Spec.Mode, []string{string(AgentStandaloneMode), string(AgentFleetMode)}))
+	}
+	return errs
+}
+
+func checkNoDowngrade(prev, curr *Agent) field.ErrorList {
+	return commonv1.CheckNoDowngrade(prev.Spec.Version, curr.Spec.Version)
+}
@@ -0,0 +1,235 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1alpha1_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/test"
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestWebhook(t *testing.T) {
+	testCases := []test.ValidationWebhookTestCase{
+		{
+			Name:      "create-valid-standalone",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				return serialize(t, mkAgent(uid))
+			},
+			Check: test.ValidationWebhookSucceeded,
+		},
+		{
+			Name:      "create-valid-fleet-server",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				return serialize(t, mkFleetServer(uid))
+			},
+			Check: test.ValidationWebhookSucceeded,
+		},
+		{
+			Name:      "create-valid-fleet-agent",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkFleetServer(uid)
+				a.Spec.FleetServerEnabled = false
+				a.Spec.ElasticsearchRef = commonv1.ObjectSelector{}
+				a.Spec.FleetServerRef = commonv1.ObjectSelector{Name: "fleet-server"}
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookSucceeded,
+		},
+		{
+			Name:      "long-name",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkAgent(uid)
+				a.SetName(strings.Repeat("x", 100))
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`metadata.name: Too long: must have at most 36 bytes`,
+			),
+		},
+		{
+			Name:      "unsupported-version-lower",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkAgent(uid)
+				a.Spec.Version = "7.9.0"
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.version: Invalid value: "7.9.0": Unsupported version: version 7.9.0 is lower than the lowest supported version`,
+			),
+		},
+		{
+			Name:      "both-deployment-and-daemonset",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkAgent(uid)
+				a.Spec.Deployment = &agentv1alpha1.DeploymentSpec{}
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec: Forbidden: Exactly one of daemonSet or deployment must be specified`,
+			),
+		},
+		{
+			Name:      "standalone-no-config",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkAgent(uid)
+				a.Spec.Config = nil
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.config: Required value: Elastic Agent configuration must be specified in standalone mode`,
+			),
+		},
+		{
+			Name:      "standalone-fleet-server-enabled",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkAgent(uid)
+				a.Spec.FleetServerEnabled = true
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.fleetServerEnabled: Forbidden: Fleet Server can only be enabled in fleet mode`,
+			),
+		},
+		{
+			Name:      "fleet-unsupported-version",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkFleetServer(uid)
+				a.Spec.Version = "7.12.0"
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.version: Invalid value: "7.12.0": fleet mode requires version 7.13.0 or higher`,
+			),
+		},
+		{
+			Name:      "fleet-with-config",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkFleetServer(uid)
+				cfg := commonv1.NewConfig(map[string]interface{}{"inputs": []interface{}{}})
+				a.Spec.Config = &cfg
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.config: Forbidden: Elastic Agent configuration is managed by Fleet in fleet mode`,
+			),
+		},
+		{
+			Name:      "fleet-no-kibana",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkFleetServer(uid)
+				a.Spec.KibanaRef = commonv1.ObjectSelector{}
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.kibanaRef: Required value: kibanaRef is required in fleet mode`,
+			),
+		},
+		{
+			Name:      "fleet-server-no-elasticsearch",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkFleetServer(uid)
+				a.Spec.ElasticsearchRef = commonv1.ObjectSelector{}
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.elasticsearchRef: Required value: elasticsearchRef is required when Fleet Server is enabled`,
+			),
+		},
+		{
+			Name:      "fleet-agent-no-fleet-server",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkFleetServer(uid)
+				a.Spec.FleetServerEnabled = false
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.fleetServerRef: Required value: fleetServerRef is required in fleet mode when Fleet Server is not enabled`,
+			),
+		},
+		{
+			Name:      "version-downgrade",
+			Operation: admissionv1beta1.Update,
+			OldObject: func(t *testing.T, uid string) []byte {
+				a := mkAgent(uid)
+				a.Spec.Version = "7.13.1"
+				return serialize(t, a)
+			},
+			Object: func(t *testing.T, uid string) []byte {
+				a := mkAgent(uid)
+				a.Spec.Version = "7.13.0"
+				return serialize(t, a)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.version: Forbidden: Version downgrades are not supported`,
+			),
+		},
+	}
+
+	validator := &agentv1alpha1.Agent{}
+	gvk := metav1.GroupVersionKind{Group: agentv1alpha1.GroupVersion.Group, Version: agentv1alpha1.GroupVersion.Version, Kind: "Agent"}
+	test.RunValidationWebhookTests(t, gvk, validator, testCases...)
+}
+
+func mkAgent(uid string) *agentv1alpha1.Agent {
+	cfg := commonv1.NewConfig(map[string]interface{}{"inputs": []interface{}{}})
+	return &agentv1alpha1.Agent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "webhook-test",
+			UID:  types.UID(uid),
+		},
+		Spec: agentv1alpha1.AgentSpec{
+			Version:   "7.13.0",
+			Config:    &cfg,
+			DaemonSet: &agentv1alpha1.DaemonSetSpec{},
+		},
+	}
+}
+
+func mkFleetServer(uid string) *agentv1alpha1.Agent {
+	return &agentv1alpha1.Agent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "webhook-test",
+			UID:  types.UID(uid),
+		},
+		Spec: agentv1alpha1.AgentSpec{
+			Version:            "7.13.0",
+			Mode:               agentv1alpha1.AgentFleetMode,
+			FleetServerEnabled: true,
+			ElasticsearchRef:   commonv1.ObjectSelector{Name: "es"},
+			KibanaRef:          commonv1.ObjectSelector{Name: "kb"},
+			Deployment:         &agentv1alpha1.DeploymentSpec{},
+		},
+	}
+}
+
+func serialize(t *testing.T, a *agentv1alpha1.Agent) []byte {
+	t.Helper()
+
+	objBytes, err := json.Marshal(a)
+	require.NoError(t, err)
+
+	return objBytes
+}
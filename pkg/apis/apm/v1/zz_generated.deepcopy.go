@@ -19,7 +19,7 @@ func (in *ApmServer) DeepCopyInto(out *ApmServer) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	if in.assocConf != nil {
 		in, out := &in.assocConf, &out.assocConf
 		*out = new(commonv1.AssociationConf)
@@ -109,7 +109,7 @@ func (in *ApmServerSpec) DeepCopy() *ApmServerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApmServerStatus) DeepCopyInto(out *ApmServerStatus) {
 	*out = *in
-	out.ReconcilerStatus = in.ReconcilerStatus
+	in.ReconcilerStatus.DeepCopyInto(&out.ReconcilerStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApmServerStatus.
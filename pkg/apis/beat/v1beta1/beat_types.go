@@ -0,0 +1,206 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1beta1
+
+import (
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KnownTypes are the well-known Beat types, for which the Docker image is defaulted by the operator.
+var KnownTypes = []string{"auditbeat", "filebeat", "heartbeat", "journalbeat", "metricbeat", "packetbeat"}
+
+// IsKnownType returns true if the given Beat type is a well-known one.
+func IsKnownType(beatType string) bool {
+	for _, t := range KnownTypes {
+		if t == beatType {
+			return true
+		}
+	}
+	return false
+}
+
+// BeatSpec defines the desired state of a Beat.
+type BeatSpec struct {
+	// Type is the type of the Beat to deploy (filebeat, metricbeat, heartbeat, auditbeat, journalbeat, packetbeat, etc.).
+	// Any string can be used, but well-known types will have the image field defaulted and have the appropriate
+	// Elasticsearch roles created automatically.
+	// +kubebuilder:validation:MaxLength=20
+	// +kubebuilder:validation:Pattern=[a-zA-Z0-9-]+
+	Type string `json:"type"`
+
+	// Version of the Beat.
+	Version string `json:"version"`
+
+	// ElasticsearchRef is a reference to an Elasticsearch cluster running in the same Kubernetes cluster.
+	ElasticsearchRef commonv1.ObjectSelector `json:"elasticsearchRef,omitempty"`
+
+	// KibanaRef is a reference to a Kibana instance running in the same Kubernetes cluster.
+	// It allows automatic setup of dashboards and visualizations.
+	KibanaRef commonv1.ObjectSelector `json:"kibanaRef,omitempty"`
+
+	// Image is the Beat Docker image to deploy. Version and Type have to match the Beat in the image.
+	// +kubebuilder:validation:Optional
+	Image string `json:"image,omitempty"`
+
+	// Config holds the Beat configuration.
+	// +kubebuilder:validation:Optional
+	Config *commonv1.Config `json:"config,omitempty"`
+
+	// DaemonSet specifies the Beat should be deployed as a DaemonSet, and allows providing its spec.
+	// Cannot be used along with `deployment`.
+	// +kubebuilder:validation:Optional
+	DaemonSet *DaemonSetSpec `json:"daemonSet,omitempty"`
+
+	// Deployment specifies the Beat should be deployed as a Deployment, and allows providing its spec.
+	// Cannot be used along with `daemonSet`.
+	// +kubebuilder:validation:Optional
+	Deployment *DeploymentSpec `json:"deployment,omitempty"`
+
+	// ServiceAccountName is used to check access from the current resource to Elasticsearch and Kibana resources
+	// in a different namespace. Can only be used if ECK is enforcing RBAC on references.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// DaemonSetSpec holds the specification of a Beat deployed as a DaemonSet.
+type DaemonSetSpec struct {
+	// PodTemplate provides customisation options (labels, annotations, affinity rules, resource requests, and so on)
+	// for the Beat pods.
+	// +kubebuilder:validation:Optional
+	PodTemplate corev1.PodTemplateSpec `json:"podTemplate,omitempty"`
+
+	// UpdateStrategy is the DaemonSet update strategy used to replace existing Beat pods.
+	// +kubebuilder:validation:Optional
+	UpdateStrategy appsv1.DaemonSetUpdateStrategy `json:"updateStrategy,omitempty"`
+}
+
+// DeploymentSpec holds the specification of a Beat deployed as a Deployment.
+type DeploymentSpec struct {
+	// PodTemplate provides customisation options (labels, annotations, affinity rules, resource requests, and so on)
+	// for the Beat pods.
+	// +kubebuilder:validation:Optional
+	PodTemplate corev1.PodTemplateSpec `json:"podTemplate,omitempty"`
+
+	// Replicas is the number of Beat pods to run. Defaults to 1.
+	// +kubebuilder:validation:Optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Strategy is the Deployment strategy used to replace existing Beat pods.
+	// +kubebuilder:validation:Optional
+	Strategy appsv1.DeploymentStrategy `json:"strategy,omitempty"`
+}
+
+// BeatHealth expresses the health of the Beat instances.
+type BeatHealth string
+
+const (
+	// BeatRedHealth means no instance is currently available.
+	BeatRedHealth BeatHealth = "red"
+	// BeatYellowHealth means some, but not all, of the expected instances are available.
+	BeatYellowHealth BeatHealth = "yellow"
+	// BeatGreenHealth means all the expected instances are available.
+	BeatGreenHealth BeatHealth = "green"
+)
+
+// BeatStatus defines the observed state of a Beat.
+type BeatStatus struct {
+	// AvailableNodes is the number of available Beat pods.
+	// +kubebuilder:validation:Optional
+	AvailableNodes int32 `json:"availableNodes,omitempty"`
+
+	// ExpectedNodes is the number of Beat pods that should be running.
+	// +kubebuilder:validation:Optional
+	ExpectedNodes int32 `json:"expectedNodes,omitempty"`
+
+	// Health of the deployment.
+	// +kubebuilder:validation:Optional
+	Health BeatHealth `json:"health,omitempty"`
+
+	// ElasticsearchAssociationStatus is the status of the association to an Elasticsearch cluster.
+	// +kubebuilder:validation:Optional
+	ElasticsearchAssociationStatus commonv1.AssociationStatus `json:"elasticsearchAssociationStatus,omitempty"`
+
+	// KibanaAssociationStatus is the status of the association to a Kibana instance.
+	// +kubebuilder:validation:Optional
+	KibanaAssociationStatus commonv1.AssociationStatus `json:"kibanaAssociationStatus,omitempty"`
+}
+
+// IsDegraded returns true if the current status is worse than the previous.
+func (bs BeatStatus) IsDegraded(prev BeatStatus) bool {
+	return prev.Health == BeatGreenHealth && bs.Health != BeatGreenHealth
+}
+
+// +kubebuilder:object:root=true
+
+// Beat is the Schema for the Beats API.
+// +kubebuilder:resource:categories=elastic,shortName=beat
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="health",type="string",JSONPath=".status.health"
+// +kubebuilder:printcolumn:name="available",type="integer",JSONPath=".status.availableNodes",description="Available nodes"
+// +kubebuilder:printcolumn:name="expected",type="integer",JSONPath=".status.expectedNodes",description="Expected nodes"
+// +kubebuilder:printcolumn:name="type",type="string",JSONPath=".spec.type",description="Beat type"
+// +kubebuilder:printcolumn:name="version",type="string",JSONPath=".spec.version",description="Beat version"
+// +kubebuilder:printcolumn:name="age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:storageversion
+type Beat struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec        BeatSpec                  `json:"spec,omitempty"`
+	Status      BeatStatus                `json:"status,omitempty"`
+	esAssocConf *commonv1.AssociationConf `json:"-"` //nolint:govet
+	kbAssocConf *commonv1.AssociationConf `json:"-"` //nolint:govet
+}
+
+// IsMarkedForDeletion returns true if the Beat is going to be deleted
+func (b *Beat) IsMarkedForDeletion() bool {
+	return !b.DeletionTimestamp.IsZero()
+}
+
+func (b *Beat) ServiceAccountName() string {
+	return b.Spec.ServiceAccountName
+}
+
+func (b *Beat) ElasticsearchRef() commonv1.ObjectSelector {
+	return b.Spec.ElasticsearchRef
+}
+
+func (b *Beat) AssociationConf() *commonv1.AssociationConf {
+	return b.esAssocConf
+}
+
+func (b *Beat) SetAssociationConf(assocConf *commonv1.AssociationConf) {
+	b.esAssocConf = assocConf
+}
+
+func (b *Beat) KibanaRef() commonv1.ObjectSelector {
+	return b.Spec.KibanaRef
+}
+
+// KibanaAssociationConf returns the configuration of the association to Kibana, if any.
+func (b *Beat) KibanaAssociationConf() *commonv1.AssociationConf {
+	return b.kbAssocConf
+}
+
+// SetKibanaAssociationConf sets the configuration of the association to Kibana.
+func (b *Beat) SetKibanaAssociationConf(assocConf *commonv1.AssociationConf) {
+	b.kbAssocConf = assocConf
+}
+
+// +kubebuilder:object:root=true
+
+// BeatList contains a list of Beats.
+type BeatList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Beat `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Beat{}, &BeatList{})
+}
@@ -0,0 +1,139 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1beta1_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/test"
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestWebhook(t *testing.T) {
+	testCases := []test.ValidationWebhookTestCase{
+		{
+			Name:      "create-valid",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				b := mkBeat(uid)
+				return serialize(t, b)
+			},
+			Check: test.ValidationWebhookSucceeded,
+		},
+		{
+			Name:      "long-name",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				b := mkBeat(uid)
+				b.SetName(strings.Repeat("x", 100))
+				return serialize(t, b)
+			},
+			Check: test.ValidationWebhookFailed(
+				`metadata.name: Too long: must have at most 36 bytes`,
+			),
+		},
+		{
+			Name:      "unsupported-version-lower",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				b := mkBeat(uid)
+				b.Spec.Version = "6.8.0"
+				return serialize(t, b)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.version: Invalid value: "6.8.0": Unsupported version: version 6.8.0 is lower than the lowest supported version`,
+			),
+		},
+		{
+			Name:      "no-deployment-nor-daemonset",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				b := mkBeat(uid)
+				b.Spec.DaemonSet = nil
+				return serialize(t, b)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec: Forbidden: Exactly one of daemonSet or deployment must be specified`,
+			),
+		},
+		{
+			Name:      "both-deployment-and-daemonset",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				b := mkBeat(uid)
+				b.Spec.Deployment = &beatv1beta1.DeploymentSpec{}
+				return serialize(t, b)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec: Forbidden: Exactly one of daemonSet or deployment must be specified`,
+			),
+		},
+		{
+			Name:      "no-config",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				b := mkBeat(uid)
+				b.Spec.Config = nil
+				return serialize(t, b)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.config: Required value: Beat configuration must be specified`,
+			),
+		},
+		{
+			Name:      "version-downgrade",
+			Operation: admissionv1beta1.Update,
+			OldObject: func(t *testing.T, uid string) []byte {
+				b := mkBeat(uid)
+				b.Spec.Version = "7.8.1"
+				return serialize(t, b)
+			},
+			Object: func(t *testing.T, uid string) []byte {
+				b := mkBeat(uid)
+				b.Spec.Version = "7.8.0"
+				return serialize(t, b)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.version: Forbidden: Version downgrades are not supported`,
+			),
+		},
+	}
+
+	validator := &beatv1beta1.Beat{}
+	gvk := metav1.GroupVersionKind{Group: beatv1beta1.GroupVersion.Group, Version: beatv1beta1.GroupVersion.Version, Kind: "Beat"}
+	test.RunValidationWebhookTests(t, gvk, validator, testCases...)
+}
+
+func mkBeat(uid string) *beatv1beta1.Beat {
+	cfg := commonv1.NewConfig(map[string]interface{}{"metricbeat.modules": []interface{}{}})
+	return &beatv1beta1.Beat{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "webhook-test",
+			UID:  types.UID(uid),
+		},
+		Spec: beatv1beta1.BeatSpec{
+			Type:      "metricbeat",
+			Version:   "7.8.0",
+			Config:    &cfg,
+			DaemonSet: &beatv1beta1.DaemonSetSpec{},
+		},
+	}
+}
+
+func serialize(t *testing.T, b *beatv1beta1.Beat) []byte {
+	t.Helper()
+
+	objBytes, err := json.Marshal(b)
+	require.NoError(t, err)
+
+	return objBytes
+}
@@ -40,13 +40,29 @@ type Associator interface {
 	SetAssociationConf(*AssociationConf)
 }
 
+// AuthType is the type of credentials used by an associated resource to authenticate against Elasticsearch.
+type AuthType string
+
+const (
+	// BasicAuth is a file realm user: the auth secret key is the username, and its value the password.
+	BasicAuth AuthType = ""
+	// ServiceAccountTokenAuth is a service account token: the value of the auth secret key is the token.
+	ServiceAccountTokenAuth AuthType = "serviceAccountToken"
+	// APIKeyAuth is an API key: the value of the auth secret key is the API key in the "id:api_key" format.
+	APIKeyAuth AuthType = "apiKey"
+	// ClientCertificateAuth is a client certificate issued by the operator: the auth secret holds the certificate
+	// and its private key under the tls.crt and tls.key keys.
+	ClientCertificateAuth AuthType = "clientCertificate"
+)
+
 // AssociationConf holds the association configuration of an Elasticsearch cluster.
 type AssociationConf struct {
-	AuthSecretName string `json:"authSecretName"`
-	AuthSecretKey  string `json:"authSecretKey"`
-	CACertProvided bool   `json:"caCertProvided"`
-	CASecretName   string `json:"caSecretName"`
-	URL            string `json:"url"`
+	AuthSecretName string   `json:"authSecretName"`
+	AuthSecretKey  string   `json:"authSecretKey"`
+	AuthType       AuthType `json:"authType,omitempty"`
+	CACertProvided bool     `json:"caCertProvided"`
+	CASecretName   string   `json:"caSecretName"`
+	URL            string   `json:"url"`
 }
 
 // IsConfigured returns true if all the fields are set.
@@ -92,6 +108,13 @@ func (ac *AssociationConf) GetAuthSecretKey() string {
 	return ac.AuthSecretKey
 }
 
+func (ac *AssociationConf) GetAuthType() AuthType {
+	if ac == nil {
+		return BasicAuth
+	}
+	return ac.AuthType
+}
+
 func (ac *AssociationConf) GetCACertProvided() bool {
 	if ac == nil {
 		return false
@@ -17,6 +17,17 @@ import (
 // ReconcilerStatus represents status information about desired/available nodes.
 type ReconcilerStatus struct {
 	AvailableNodes int32 `json:"availableNodes,omitempty"`
+	// Version of the stack resource currently running. During version upgrades, multiple versions may run
+	// in parallel: this value specifies the lowest version currently running.
+	// +kubebuilder:validation:Optional
+	Version string `json:"version,omitempty"`
+	// ObservedGeneration is the most recent generation observed for this resource. It corresponds to the
+	// metadata generation, which is updated on mutation by the API Server.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions holds the current service state of the resource.
+	// +kubebuilder:validation:Optional
+	Conditions Conditions `json:"conditions,omitempty"`
 }
 
 // SecretRef is a reference to a secret that exists in the same namespace.
@@ -86,6 +97,10 @@ type TLSOptions struct {
 	// - `tls.crt`: The certificate (or a chain).
 	// - `tls.key`: The private key to the first certificate in the certificate chain.
 	Certificate SecretRef `json:"certificate,omitempty"`
+
+	// Client configures the authentication of clients with certificates. Only supported by Elasticsearch.
+	// +kubebuilder:validation:Optional
+	Client *ClientAuthentication `json:"client,omitempty"`
 }
 
 // Enabled returns true when TLS is enabled based on this option struct.
@@ -94,6 +109,41 @@ func (tls TLSOptions) Enabled() bool {
 	return selfSigned == nil || !selfSigned.Disabled || tls.Certificate.SecretName != ""
 }
 
+// ClientAuthenticationEnabled returns true when clients can authenticate with certificates.
+func (tls TLSOptions) ClientAuthenticationEnabled() bool {
+	return tls.Enabled() && tls.Client != nil
+}
+
+// ClientAuthenticationMode defines whether clients must present a certificate.
+type ClientAuthenticationMode string
+
+const (
+	// ClientAuthenticationOptional requests a certificate from the clients, without requiring it.
+	ClientAuthenticationOptional ClientAuthenticationMode = "optional"
+	// ClientAuthenticationRequired rejects the clients which do not present a trusted certificate.
+	ClientAuthenticationRequired ClientAuthenticationMode = "required"
+)
+
+// ClientAuthentication holds the configuration of the authentication of clients with certificates.
+type ClientAuthentication struct {
+	// Mode defines whether clients must present a certificate: optional (default) or required.
+	// +kubebuilder:validation:Enum=optional;required
+	Mode ClientAuthenticationMode `json:"mode,omitempty"`
+
+	// CertificateAuthorities is a reference to a Kubernetes secret that contains, under the `ca.crt` key, the
+	// certificate authorities trusted to issue client certificates. The certificate authority the operator issues
+	// the client certificates of the associated resources with is always trusted.
+	CertificateAuthorities SecretRef `json:"certificateAuthorities,omitempty"`
+}
+
+// ModeOrDefault returns the client authentication mode, optional if not specified.
+func (ca *ClientAuthentication) ModeOrDefault() ClientAuthenticationMode {
+	if ca == nil || ca.Mode == "" {
+		return ClientAuthenticationOptional
+	}
+	return ca.Mode
+}
+
 // SelfSignedCertificate holds configuration for the self-signed certificate generated by the operator.
 type SelfSignedCertificate struct {
 	// SubjectAlternativeNames is a list of SANs to include in the generated HTTP TLS certificate.
@@ -142,6 +192,22 @@ func (p *PodDisruptionBudgetTemplate) IsDisabled() bool {
 	return reflect.DeepEqual(p, &PodDisruptionBudgetTemplate{})
 }
 
+// NetworkPolicyOptions configures the NetworkPolicy reconciled by the operator for a resource.
+type NetworkPolicyOptions struct {
+	// Enabled controls whether the operator reconciles a NetworkPolicy allowing the ingress traffic it knows about
+	// to the Pods of the resource. Defaults to the value of the manage-network-policies operator flag.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// IsEnabled returns true if a NetworkPolicy must be reconciled, given the default set at the operator level.
+func (n NetworkPolicyOptions) IsEnabled(operatorDefault bool) bool {
+	if n.Enabled == nil {
+		return operatorDefault
+	}
+	return *n.Enabled
+}
+
 // SecretSource defines a data source based on a Kubernetes Secret.
 type SecretSource struct {
 	// SecretName is the name of the secret.
@@ -163,3 +229,65 @@ type KeyToPath struct {
 	// +kubebuilder:validation:Optional
 	Path string `json:"path,omitempty"`
 }
+
+// ConditionType defines the condition of an Elastic resource.
+type ConditionType string
+
+const (
+	// ReconciliationComplete indicates whether the last reconciliation of the resource succeeded, and its
+	// specification is fully applied.
+	ReconciliationComplete ConditionType = "ReconciliationComplete"
+	// RunningDesiredVersion indicates whether all the Pods of the resource run the version specified in its
+	// specification.
+	RunningDesiredVersion ConditionType = "RunningDesiredVersion"
+)
+
+// Condition represents the latest available observations of a resource's current state.
+type Condition struct {
+	// Type of the condition.
+	Type ConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status v1.ConditionStatus `json:"status"`
+	// Last time the condition transitioned from one status to another.
+	// +kubebuilder:validation:Optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// A human readable message indicating details about the transition.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+}
+
+// Conditions is a list of conditions, with at most one condition per type.
+type Conditions []Condition
+
+// Index returns the index of the condition with the given type, or -1 if not found.
+func (c Conditions) Index(conditionType ConditionType) int {
+	for i, condition := range c {
+		if condition.Type == conditionType {
+			return i
+		}
+	}
+	return -1
+}
+
+// MergeWith returns a copy of the conditions updated with the given ones.
+// The last transition time of an existing condition is preserved if its status does not change.
+func (c Conditions) MergeWith(nextConditions ...Condition) Conditions {
+	merged := make(Conditions, len(c))
+	copy(merged, c)
+	for _, next := range nextConditions {
+		if next.LastTransitionTime.IsZero() {
+			next.LastTransitionTime = metav1.Now()
+		}
+		index := merged.Index(next.Type)
+		switch {
+		case index < 0:
+			merged = append(merged, next)
+		case merged[index].Status == next.Status:
+			next.LastTransitionTime = merged[index].LastTransitionTime
+			merged[index] = next
+		default:
+			merged[index] = next
+		}
+	}
+	return merged
+}
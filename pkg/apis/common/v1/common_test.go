@@ -4,7 +4,14 @@
 
 package v1
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 func TestTLSOptions_Enabled(t *testing.T) {
 	type fields struct {
@@ -132,3 +139,28 @@ func TestHTTPConfig_Scheme(t *testing.T) {
 		})
 	}
 }
+
+func TestConditions_MergeWith(t *testing.T) {
+	past := metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	existing := Conditions{
+		{Type: "A", Status: corev1.ConditionTrue, LastTransitionTime: past, Message: "a"},
+		{Type: "B", Status: corev1.ConditionTrue, LastTransitionTime: past, Message: "b"},
+	}
+	merged := existing.MergeWith(
+		Condition{Type: "A", Status: corev1.ConditionTrue, Message: "updated a"},
+		Condition{Type: "B", Status: corev1.ConditionFalse},
+		Condition{Type: "C", Status: corev1.ConditionTrue},
+	)
+	require.Len(t, merged, 3)
+	// same status: the transition time is preserved, the message is updated
+	require.Equal(t, Condition{Type: "A", Status: corev1.ConditionTrue, LastTransitionTime: past, Message: "updated a"}, merged[0])
+	// status changed: the transition time is updated
+	require.Equal(t, corev1.ConditionFalse, merged[1].Status)
+	require.True(t, merged[1].LastTransitionTime.After(past.Time))
+	// new condition
+	require.Equal(t, 2, merged.Index("C"))
+	require.False(t, merged[2].LastTransitionTime.IsZero())
+	// the original conditions are left untouched
+	require.Equal(t, "a", existing[0].Message)
+	require.Equal(t, corev1.ConditionTrue, existing[1].Status)
+}
@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Monitoring holds references to the Elasticsearch clusters receiving the metrics and the logs of a resource.
+type Monitoring struct {
+	// Metrics holds references to the Elasticsearch clusters receiving the metrics of this resource.
+	// +kubebuilder:validation:Optional
+	Metrics MetricsMonitoring `json:"metrics,omitempty"`
+	// Logs holds references to the Elasticsearch clusters receiving the logs of this resource.
+	// +kubebuilder:validation:Optional
+	Logs LogsMonitoring `json:"logs,omitempty"`
+}
+
+// MetricsMonitoring holds references to the Elasticsearch clusters receiving the metrics of a resource.
+type MetricsMonitoring struct {
+	// ElasticsearchRefs is a reference to a list of monitoring Elasticsearch clusters running in the same Kubernetes cluster.
+	// Due to existing limitations, only a single Elasticsearch cluster is currently supported.
+	// +kubebuilder:validation:Optional
+	ElasticsearchRefs []ObjectSelector `json:"elasticsearchRefs,omitempty"`
+}
+
+// LogsMonitoring holds references to the Elasticsearch clusters receiving the logs of a resource.
+type LogsMonitoring struct {
+	// ElasticsearchRefs is a reference to a list of monitoring Elasticsearch clusters running in the same Kubernetes cluster.
+	// Due to existing limitations, only a single Elasticsearch cluster is currently supported.
+	// +kubebuilder:validation:Optional
+	ElasticsearchRefs []ObjectSelector `json:"elasticsearchRefs,omitempty"`
+}
+
+// IsDefined returns true if metrics or logs are shipped to a monitoring Elasticsearch cluster.
+func (m Monitoring) IsDefined() bool {
+	return m.MetricsRef().IsDefined() || m.LogsRef().IsDefined()
+}
+
+// MetricsRef returns the reference to the Elasticsearch cluster receiving the metrics, if any.
+func (m Monitoring) MetricsRef() *ObjectSelector {
+	return firstRef(m.Metrics.ElasticsearchRefs)
+}
+
+// LogsRef returns the reference to the Elasticsearch cluster receiving the logs, if any.
+func (m Monitoring) LogsRef() *ObjectSelector {
+	return firstRef(m.Logs.ElasticsearchRefs)
+}
+
+func firstRef(refs []ObjectSelector) *ObjectSelector {
+	if len(refs) == 0 {
+		return nil
+	}
+	return &refs[0]
+}
+
+// Monitored interface represents an Elastic stack application whose metrics and logs can be shipped to
+// monitoring Elasticsearch clusters, through Metricbeat and Filebeat sidecar containers.
+// +kubebuilder:object:generate=false
+type Monitored interface {
+	metav1.Object
+	runtime.Object
+	MonitoringSpec() Monitoring
+	ServiceAccountName() string
+}
@@ -93,6 +93,31 @@ func CheckNoDowngrade(prev, curr string) field.ErrorList {
 	return nil
 }
 
+// MonitoringMinVersion is the first version of the Elastic Stack whose metrics and logs can be shipped to a monitoring
+// Elasticsearch cluster through Metricbeat and Filebeat sidecar containers.
+var MonitoringMinVersion = version.MustParse("7.14.0")
+
+// CheckMonitoring checks that a single monitoring Elasticsearch cluster is referenced for metrics and for logs, and
+// that the given version supports monitoring through Beats sidecar containers.
+func CheckMonitoring(monitoring Monitoring, ver string) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("spec").Child("monitoring")
+	if len(monitoring.Metrics.ElasticsearchRefs) > 1 {
+		errs = append(errs, field.TooMany(path.Child("metrics", "elasticsearchRefs"), len(monitoring.Metrics.ElasticsearchRefs), 1))
+	}
+	if len(monitoring.Logs.ElasticsearchRefs) > 1 {
+		errs = append(errs, field.TooMany(path.Child("logs", "elasticsearchRefs"), len(monitoring.Logs.ElasticsearchRefs), 1))
+	}
+	if !monitoring.IsDefined() {
+		return errs
+	}
+	if v, err := version.Parse(ver); err == nil && !v.IsSameOrAfter(MonitoringMinVersion) {
+		errs = append(errs, field.Invalid(path, ver,
+			fmt.Sprintf("Stack monitoring requires version %s or above", MonitoringMinVersion)))
+	}
+	return errs
+}
+
 func parseVersion(ver string) (*version.Version, field.ErrorList) {
 	v, err := version.Parse(ver)
 	if err != nil {
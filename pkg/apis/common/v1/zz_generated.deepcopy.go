@@ -25,6 +25,59 @@ func (in *AssociationConf) DeepCopy() *AssociationConf {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientAuthentication) DeepCopyInto(out *ClientAuthentication) {
+	*out = *in
+	out.CertificateAuthorities = in.CertificateAuthorities
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientAuthentication.
+func (in *ClientAuthentication) DeepCopy() *ClientAuthentication {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientAuthentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in Conditions) DeepCopyInto(out *Conditions) {
+	{
+		in := &in
+		*out = make(Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Conditions.
+func (in Conditions) DeepCopy() Conditions {
+	if in == nil {
+		return nil
+	}
+	out := new(Conditions)
+	in.DeepCopyInto(out)
+	return *out
+}
+
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Config.
 func (in *Config) DeepCopy() *Config {
 	if in == nil {
@@ -67,6 +120,83 @@ func (in *KeyToPath) DeepCopy() *KeyToPath {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogsMonitoring) DeepCopyInto(out *LogsMonitoring) {
+	*out = *in
+	if in.ElasticsearchRefs != nil {
+		in, out := &in.ElasticsearchRefs, &out.ElasticsearchRefs
+		*out = make([]ObjectSelector, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsMonitoring.
+func (in *LogsMonitoring) DeepCopy() *LogsMonitoring {
+	if in == nil {
+		return nil
+	}
+	out := new(LogsMonitoring)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsMonitoring) DeepCopyInto(out *MetricsMonitoring) {
+	*out = *in
+	if in.ElasticsearchRefs != nil {
+		in, out := &in.ElasticsearchRefs, &out.ElasticsearchRefs
+		*out = make([]ObjectSelector, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsMonitoring.
+func (in *MetricsMonitoring) DeepCopy() *MetricsMonitoring {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsMonitoring)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Monitoring) DeepCopyInto(out *Monitoring) {
+	*out = *in
+	in.Metrics.DeepCopyInto(&out.Metrics)
+	in.Logs.DeepCopyInto(&out.Logs)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Monitoring.
+func (in *Monitoring) DeepCopy() *Monitoring {
+	if in == nil {
+		return nil
+	}
+	out := new(Monitoring)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicyOptions) DeepCopyInto(out *NetworkPolicyOptions) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicyOptions.
+func (in *NetworkPolicyOptions) DeepCopy() *NetworkPolicyOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicyOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectSelector) DeepCopyInto(out *ObjectSelector) {
 	*out = *in
@@ -102,6 +232,13 @@ func (in *PodDisruptionBudgetTemplate) DeepCopy() *PodDisruptionBudgetTemplate {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReconcilerStatus) DeepCopyInto(out *ReconcilerStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcilerStatus.
@@ -210,6 +347,11 @@ func (in *TLSOptions) DeepCopyInto(out *TLSOptions) {
 		(*in).DeepCopyInto(*out)
 	}
 	out.Certificate = in.Certificate
+	if in.Client != nil {
+		in, out := &in.Client, &out.Client
+		*out = new(ClientAuthentication)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSOptions.
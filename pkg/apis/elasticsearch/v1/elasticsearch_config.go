@@ -28,6 +28,15 @@ type Node struct {
 	Data   bool `config:"data"`
 	Ingest bool `config:"ingest"`
 	ML     bool `config:"ml"`
+	// Store is the store section of the node settings.
+	Store NodeStore `config:"store"`
+}
+
+// NodeStore is the node.store section in elasticsearch.yml.
+type NodeStore struct {
+	// AllowMmap is false if memory mapping is disabled, in which case the vm.max_map_count kernel setting of the
+	// Kubernetes node does not matter.
+	AllowMmap bool `config:"allow_mmap"`
 }
 
 // ElasticsearchSettings is a typed subset of elasticsearch.yml for purposes of the operator.
@@ -43,6 +52,9 @@ var DefaultCfg = ElasticsearchSettings{
 		Data:   true,
 		Ingest: true,
 		ML:     true,
+		Store: NodeStore{
+			AllowMmap: true,
+		},
 	},
 }
 
@@ -173,6 +173,7 @@ func TestConfig_Unpack(t *testing.T) {
 					Data:   true,
 					Ingest: true,
 					ML:     true,
+					Store:  NodeStore{AllowMmap: true},
 				},
 				Cluster: ClusterSettings{
 					InitialMasterNodes: []string{"a", "b"},
@@ -180,6 +181,24 @@ func TestConfig_Unpack(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "memory mapping disabled",
+			args: &commonv1.Config{
+				Data: map[string]interface{}{
+					"node.store.allow_mmap": false,
+				},
+			},
+			want: ElasticsearchSettings{
+				Node: Node{
+					Master: true,
+					Data:   true,
+					Ingest: true,
+					ML:     true,
+					Store:  NodeStore{AllowMmap: false},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:    "Unpack is nil safe",
 			args:    nil,
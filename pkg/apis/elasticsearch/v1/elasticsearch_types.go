@@ -5,6 +5,8 @@
 package v1
 
 import (
+	"time"
+
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -63,12 +65,52 @@ type ElasticsearchSpec struct {
 	// RemoteClusters enables you to establish uni-directional connections to a remote Elasticsearch cluster.
 	// +optional
 	RemoteClusters []RemoteCluster `json:"remoteClusters,omitempty"`
+
+	// Autoscaling holds the storage-based autoscaling policies of the NodeSets.
+	// +kubebuilder:validation:Optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// Snapshots holds the snapshot repositories and snapshot lifecycle policies to configure in Elasticsearch.
+	// +kubebuilder:validation:Optional
+	Snapshots *SnapshotsSpec `json:"snapshots,omitempty"`
+
+	// Monitoring enables you to collect and ship log and monitoring data of this Elasticsearch cluster.
+	// See https://www.elastic.co/guide/en/elasticsearch/reference/current/monitor-elasticsearch-cluster.html.
+	// Metricbeat and Filebeat are deployed in the same Pod as sidecars and each one sends data to one or two different
+	// Elasticsearch monitoring clusters running in the same Kubernetes cluster.
+	// +kubebuilder:validation:Optional
+	Monitoring commonv1.Monitoring `json:"monitoring,omitempty"`
+
+	// NetworkPolicy controls the NetworkPolicy reconciled by the operator to allow the ingress traffic it knows about
+	// to the Elasticsearch Pods.
+	// +kubebuilder:validation:Optional
+	NetworkPolicy commonv1.NetworkPolicyOptions `json:"networkPolicy,omitempty"`
 }
 
 // TransportConfig holds the transport layer settings for Elasticsearch.
 type TransportConfig struct {
 	// Service defines the template for the associated Kubernetes Service object.
 	Service commonv1.ServiceTemplate `json:"service,omitempty"`
+	// TLS defines options for configuring TLS on the transport layer.
+	TLS TransportTLSOptions `json:"tls,omitempty"`
+}
+
+// TransportTLSOptions holds the TLS configuration options of the transport layer.
+type TransportTLSOptions struct {
+	// SubjectAlternativeNames is a list of SANs to include in the generated node transport TLS certificates.
+	SubjectAlternativeNames []commonv1.SubjectAlternativeName `json:"subjectAltNames,omitempty"`
+	// Certificate is a reference to a Kubernetes secret that contains the CA certificate
+	// and private key for generating node certificates.
+	// The referenced secret should contain the following:
+	//
+	// - `ca.crt`: The CA certificate in PEM format.
+	// - `ca.key`: The private key for the CA certificate in PEM format.
+	Certificate commonv1.SecretRef `json:"certificate,omitempty"`
+}
+
+// UserDefinedCA returns true if the transport certificates are issued by a CA provided by the user.
+func (tto TransportTLSOptions) UserDefinedCA() bool {
+	return tto.Certificate.SecretName != ""
 }
 
 // RemoteCluster declares a remote Elasticsearch cluster connection.
@@ -82,8 +124,41 @@ type RemoteCluster struct {
 	// ElasticsearchRef is a reference to an Elasticsearch cluster running within the same k8s cluster.
 	ElasticsearchRef commonv1.ObjectSelector `json:"elasticsearchRef,omitempty"`
 
-	// TODO: Allow the user to specify some options (transport.compress, transport.ping_schedule)
+	// Seeds is a list of transport addresses (host:port) of nodes of a remote cluster running outside of the
+	// k8s cluster. The local cluster connects to the remote cluster in sniff mode.
+	Seeds []string `json:"seeds,omitempty"`
+
+	// ProxyAddress is the transport address (host:port) of a proxy in front of a remote cluster running outside of the
+	// k8s cluster. The local cluster connects to the remote cluster in proxy mode, which requires Elasticsearch 7.7.0 or above.
+	ProxyAddress string `json:"proxyAddress,omitempty"`
+
+	// ServerName is the server name sent in the TLS SNI extension when connecting to the proxy address.
+	ServerName string `json:"serverName,omitempty"`
+
+	// CertificateAuthorities is a reference to a Kubernetes secret that contains the CA certificates of a remote
+	// cluster running outside of the k8s cluster, in PEM format under the `ca.crt` key.
+	// They are added to the certificate authorities trusted by the transport layer of the local cluster.
+	CertificateAuthorities commonv1.SecretRef `json:"certificateAuthorities,omitempty"`
+
+	// SkipUnavailable indicates whether the remote cluster is skipped by cross-cluster searches when it is unavailable.
+	SkipUnavailable *bool `json:"skipUnavailable,omitempty"`
+
+	// Compress indicates whether requests sent to the remote cluster are compressed.
+	Compress *bool `json:"compress,omitempty"`
+
+	// PingSchedule is the interval at which application-level ping messages are sent to keep the connections to the
+	// remote cluster alive (eg. 30s).
+	PingSchedule string `json:"pingSchedule,omitempty"`
+}
 
+// IsExternal returns true if the remote cluster runs outside of the k8s cluster.
+func (r RemoteCluster) IsExternal() bool {
+	return !r.ElasticsearchRef.IsDefined() && (len(r.Seeds) > 0 || r.ProxyAddress != "")
+}
+
+// IsProxyMode returns true if the local cluster connects to the remote cluster through a proxy.
+func (r RemoteCluster) IsProxyMode() bool {
+	return r.ProxyAddress != ""
 }
 
 func (r RemoteCluster) ConfigHash() string {
@@ -99,12 +174,148 @@ func (es ElasticsearchSpec) NodeCount() int32 {
 	return count
 }
 
+// DefaultAutoscalingCooldownPeriod is the minimum duration between two scaling operations of a NodeSet,
+// if not specified in its autoscaling policy.
+var DefaultAutoscalingCooldownPeriod = 10 * time.Minute
+
+// AutoscalingSpec holds the autoscaling policies of an Elasticsearch cluster.
+type AutoscalingSpec struct {
+	// Policies is the list of storage-based autoscaling policies, at most one per NodeSet.
+	Policies []StorageAutoscalingPolicy `json:"policies,omitempty"`
+}
+
+// GetPolicy returns the autoscaling policy of the given NodeSet, or nil if the NodeSet is not autoscaled.
+func (as *AutoscalingSpec) GetPolicy(nodeSetName string) *StorageAutoscalingPolicy {
+	if as == nil {
+		return nil
+	}
+	for i, policy := range as.Policies {
+		if policy.NodeSet == nodeSetName {
+			return &as.Policies[i]
+		}
+	}
+	return nil
+}
+
+// StorageAutoscalingPolicy adjusts the number of nodes of a NodeSet according to its disk usage.
+type StorageAutoscalingPolicy struct {
+	// NodeSet is the name of the NodeSet the policy applies to.
+	// +kubebuilder:validation:MinLength=1
+	NodeSet string `json:"nodeSet"`
+
+	// MinCount is the minimum number of nodes of the NodeSet.
+	// +kubebuilder:validation:Minimum=1
+	MinCount int32 `json:"minCount"`
+
+	// MaxCount is the maximum number of nodes of the NodeSet.
+	// +kubebuilder:validation:Minimum=1
+	MaxCount int32 `json:"maxCount"`
+
+	// TargetDiskUsagePercent is the ratio of used disk space, in percent, the NodeSet should stay under.
+	// Nodes are added when the disk usage exceeds it, and removed when fewer nodes can hold the data below it.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	TargetDiskUsagePercent int32 `json:"targetDiskUsagePercent"`
+
+	// CooldownPeriod is the minimum duration between two scaling operations of the NodeSet. Defaults to 10m.
+	// +kubebuilder:validation:Optional
+	CooldownPeriod *metav1.Duration `json:"cooldownPeriod,omitempty"`
+}
+
+// CooldownPeriodOrDefault returns the cooldown period of the policy, or the default one if not specified.
+func (p StorageAutoscalingPolicy) CooldownPeriodOrDefault() time.Duration {
+	if p.CooldownPeriod == nil {
+		return DefaultAutoscalingCooldownPeriod
+	}
+	return p.CooldownPeriod.Duration
+}
+
+// SnapshotsSpec holds the snapshot repositories and snapshot lifecycle policies of an Elasticsearch cluster.
+// Repositories and policies removed from the specification are also removed from Elasticsearch.
+type SnapshotsSpec struct {
+	// Repositories are the snapshot repositories to register.
+	Repositories []SnapshotRepository `json:"repositories,omitempty"`
+	// Policies are the snapshot lifecycle management policies to create. Requires Elasticsearch 7.4.0 or above.
+	Policies []SnapshotPolicy `json:"policies,omitempty"`
+}
+
+// SnapshotRepository declares a snapshot repository.
+type SnapshotRepository struct {
+	// Name of the repository.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Type of the repository, for example s3, gcs, azure or fs.
+	// +kubebuilder:validation:MinLength=1
+	Type string `json:"type"`
+	// Settings of the repository, as expected by the Elasticsearch snapshot repository API.
+	// Credentials must not be set here, they should be added to the Elasticsearch keystore through SecureSettings,
+	// for example as s3.client.default.access_key and s3.client.default.secret_key.
+	Settings *commonv1.Config `json:"settings,omitempty"`
+}
+
+// SnapshotPolicy declares a snapshot lifecycle management policy.
+type SnapshotPolicy struct {
+	// Name of the policy.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Schedule is the cron expression defining when snapshots are taken.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// SnapshotName is the name given to the snapshots, supporting date math. Defaults to <policy-name-{now/d}>.
+	// +kubebuilder:validation:Optional
+	SnapshotName string `json:"snapshotName,omitempty"`
+	// Repository is the name of the repository snapshots are stored in.
+	// +kubebuilder:validation:MinLength=1
+	Repository string `json:"repository"`
+	// Config holds the configuration of the snapshots, such as the indices to include.
+	Config *commonv1.Config `json:"config,omitempty"`
+	// Retention defines which snapshots are deleted.
+	// +kubebuilder:validation:Optional
+	Retention *SnapshotRetention `json:"retention,omitempty"`
+}
+
+// SnapshotNameOrDefault returns the name given to the snapshots taken by the policy.
+func (p SnapshotPolicy) SnapshotNameOrDefault() string {
+	if p.SnapshotName == "" {
+		return "<" + p.Name + "-{now/d}>"
+	}
+	return p.SnapshotName
+}
+
+// SnapshotRetention defines the retention of the snapshots taken by a snapshot lifecycle policy.
+type SnapshotRetention struct {
+	// ExpireAfter is the duration after which snapshots are deleted, for example 30d.
+	ExpireAfter string `json:"expireAfter,omitempty"`
+	// MinCount is the minimum number of snapshots to retain, even if they expired.
+	MinCount *int32 `json:"minCount,omitempty"`
+	// MaxCount is the maximum number of snapshots to retain, even if they did not expire yet.
+	MaxCount *int32 `json:"maxCount,omitempty"`
+}
+
 // Auth contains user authentication and authorization security settings for Elasticsearch.
 type Auth struct {
 	// Roles to propagate to the Elasticsearch cluster.
 	Roles []RoleSource `json:"roles,omitempty"`
 	// FileRealm to propagate to the Elasticsearch cluster.
 	FileRealm []FileRealmSource `json:"fileRealm,omitempty"`
+	// Rotation defines the periodic rotation of the passwords of the elastic user, the operator user and the users
+	// of the associated resources.
+	// +kubebuilder:validation:Optional
+	Rotation *CredentialsRotation `json:"rotation,omitempty"`
+}
+
+// CredentialsRotation defines the periodic rotation of the passwords managed by the operator.
+type CredentialsRotation struct {
+	// MaxAge is the maximum age of the passwords, for example "720h". The passwords are rotated once they reach it.
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// MaxAgeOrZero returns the maximum age of the passwords, or zero if they are not rotated periodically.
+func (r *CredentialsRotation) MaxAgeOrZero() time.Duration {
+	if r == nil || r.MaxAge == nil {
+		return 0
+	}
+	return r.MaxAge.Duration
 }
 
 // RoleSource references roles to create in the Elasticsearch cluster.
@@ -191,6 +402,60 @@ type NodeSet struct {
 	// See: https://www.elastic.co/guide/en/cloud-on-k8s/current/k8s-volume-claim-templates.html
 	// +kubebuilder:validation:Optional
 	VolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
+
+	// ZoneAwareness spreads the Pods of this NodeSet across the zones of the Kubernetes nodes, and configures
+	// Elasticsearch shard allocation awareness so that copies of a shard are allocated to different zones.
+	// +kubebuilder:validation:Optional
+	ZoneAwareness *ZoneAwareness `json:"zoneAwareness,omitempty"`
+
+	// VolumeClaimTemplatesUpdateStrategy specifies how changes to the VolumeClaimTemplates are applied.
+	// ExpandOnly only allows storage requests to be increased, if the storage class supports volume expansion.
+	// Migrate allows any change: the nodes of this NodeSet are replaced by the nodes of a new StatefulSet with the
+	// updated claims, once their data has been migrated. Storage increases are still applied through volume expansion.
+	// Defaults to ExpandOnly.
+	// +kubebuilder:validation:Enum=ExpandOnly;Migrate
+	// +kubebuilder:validation:Optional
+	VolumeClaimTemplatesUpdateStrategy VolumeClaimTemplatesUpdateStrategy `json:"volumeClaimTemplatesUpdateStrategy,omitempty"`
+}
+
+// VolumeClaimTemplatesUpdateStrategy specifies how changes to the VolumeClaimTemplates of a NodeSet are applied.
+type VolumeClaimTemplatesUpdateStrategy string
+
+const (
+	// VolumeClaimTemplatesExpandOnly only allows storage requests to be increased.
+	VolumeClaimTemplatesExpandOnly VolumeClaimTemplatesUpdateStrategy = "ExpandOnly"
+	// VolumeClaimTemplatesMigrate replaces the nodes of the NodeSet by nodes with the updated claims.
+	VolumeClaimTemplatesMigrate VolumeClaimTemplatesUpdateStrategy = "Migrate"
+)
+
+// AllowsStorageMigration returns true if changes to the VolumeClaimTemplates of this NodeSet are applied by migrating
+// its data to new nodes.
+func (n NodeSet) AllowsStorageMigration() bool {
+	return n.VolumeClaimTemplatesUpdateStrategy == VolumeClaimTemplatesMigrate
+}
+
+// DefaultZoneTopologyKey is the Kubernetes node label holding the zone of the node.
+const DefaultZoneTopologyKey = "topology.kubernetes.io/zone"
+
+// ZoneAwareness specifies how the Pods of a NodeSet are spread across zones.
+type ZoneAwareness struct {
+	// TopologyKey is the key of the Kubernetes node label whose value is the zone of the node.
+	// Defaults to topology.kubernetes.io/zone.
+	// +kubebuilder:validation:Optional
+	TopologyKey string `json:"topologyKey,omitempty"`
+
+	// Zones restricts the scheduling of the Pods to the Kubernetes nodes in these zones.
+	// Pods can be scheduled in any zone if not specified.
+	// +kubebuilder:validation:Optional
+	Zones []string `json:"zones,omitempty"`
+}
+
+// TopologyKeyOrDefault returns the topology key, or the default one if not specified.
+func (z ZoneAwareness) TopologyKeyOrDefault() string {
+	if z.TopologyKey == "" {
+		return DefaultZoneTopologyKey
+	}
+	return z.TopologyKey
 }
 
 // GetESContainerTemplate returns the Elasticsearch container (if set) from the NodeSet's PodTemplate
@@ -205,8 +470,47 @@ func (n NodeSet) GetESContainerTemplate() *corev1.Container {
 
 // UpdateStrategy specifies how updates to the cluster should be performed.
 type UpdateStrategy struct {
+	// Type of the update strategy. RollingUpgrade restarts the nodes whose specification changed one at a time, within
+	// the limits of the ChangeBudget. FullClusterRestart disables shards allocation, flushes the indices, then restarts
+	// together all the nodes whose specification changed. Shards allocation is enabled again once the restarted nodes
+	// are back in the cluster and the cluster health is at least yellow. Defaults to RollingUpgrade.
+	// +kubebuilder:validation:Enum=RollingUpgrade;FullClusterRestart
+	// +kubebuilder:validation:Optional
+	Type UpdateStrategyType `json:"type,omitempty"`
+
 	// ChangeBudget defines the constraints to consider when applying changes to the Elasticsearch cluster.
 	ChangeBudget ChangeBudget `json:"changeBudget,omitempty"`
+
+	// MaintenanceWindows restrict the changes that restart or remove Elasticsearch nodes to the given recurring time
+	// windows. Other changes, such as the creation of new nodes, are applied immediately. Changes that restart or
+	// remove nodes are applied at any time if not specified.
+	// +kubebuilder:validation:Optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// MaintenanceWindow is a recurring time window.
+type MaintenanceWindow struct {
+	// Schedule is a cron expression defining when the window starts, evaluated in UTC. It is made of 5 fields: minute,
+	// hour, day of month, month and day of week. For example "0 2 * * sat,sun" starts the window at 02:00 UTC on
+	// Saturdays and Sundays.
+	Schedule string `json:"schedule"`
+	// Duration of the window, for example "3h".
+	Duration metav1.Duration `json:"duration"`
+}
+
+// UpdateStrategyType is the type of an UpdateStrategy.
+type UpdateStrategyType string
+
+const (
+	// RollingUpgradeStrategyType restarts the nodes one at a time.
+	RollingUpgradeStrategyType UpdateStrategyType = "RollingUpgrade"
+	// FullClusterRestartStrategyType restarts the nodes all together.
+	FullClusterRestartStrategyType UpdateStrategyType = "FullClusterRestart"
+)
+
+// IsFullClusterRestart returns true if the nodes must be restarted all together.
+func (s UpdateStrategy) IsFullClusterRestart() bool {
+	return s.Type == FullClusterRestartStrategyType
 }
 
 // ChangeBudget defines the constraints to consider when applying changes to the Elasticsearch cluster.
@@ -300,11 +604,190 @@ const (
 	ElasticsearchResourceInvalid ElasticsearchOrchestrationPhase = "Invalid"
 )
 
+const (
+	// VolumeExpansionInProgress indicates that the storage request of some PersistentVolumeClaims has been increased,
+	// and the underlying volumes are being resized.
+	VolumeExpansionInProgress commonv1.ConditionType = "VolumeExpansionInProgress"
+	// FileSystemResizePending indicates that some volumes have been resized, but their file system still needs to be
+	// resized by the kubelet on the node the Pod is running on.
+	FileSystemResizePending commonv1.ConditionType = "FileSystemResizePending"
+	// VolumeExpansionIgnored indicates that the storage increase of some volume claim templates is ignored, because
+	// their storage class does not allow volume expansion.
+	VolumeExpansionIgnored commonv1.ConditionType = "VolumeExpansionIgnored"
+	// ElasticsearchReachable indicates whether the Elasticsearch HTTP API can be reached through its service.
+	ElasticsearchReachable commonv1.ConditionType = "ElasticsearchReachable"
+)
+
 // ElasticsearchStatus defines the observed state of Elasticsearch
 type ElasticsearchStatus struct {
 	commonv1.ReconcilerStatus `json:",inline"`
 	Health                    ElasticsearchHealth             `json:"health,omitempty"`
 	Phase                     ElasticsearchOrchestrationPhase `json:"phase,omitempty"`
+	// Autoscaling holds the status of the autoscaled NodeSets.
+	// +kubebuilder:validation:Optional
+	Autoscaling []NodeSetAutoscalingStatus `json:"autoscaling,omitempty"`
+	// Snapshots holds the status of the snapshot lifecycle policies.
+	// +kubebuilder:validation:Optional
+	Snapshots []SnapshotPolicyStatus `json:"snapshots,omitempty"`
+	// InProgressOperations provides details about the changes the operator is applying to the Elasticsearch cluster.
+	// +kubebuilder:validation:Optional
+	InProgressOperations InProgressOperations `json:"inProgressOperations,omitempty"`
+}
+
+// InProgressOperations provides details about the changes the operator is applying to the Elasticsearch cluster.
+type InProgressOperations struct {
+	// Upscale lists the StatefulSets whose nodes creation is postponed.
+	// +kubebuilder:validation:Optional
+	Upscale []UpscaleOperation `json:"upscale,omitempty"`
+	// Downscale lists the nodes to be removed from the cluster once their data is migrated.
+	// +kubebuilder:validation:Optional
+	Downscale []DownscaleOperation `json:"downscale,omitempty"`
+	// Upgrade lists the nodes to be restarted to apply a specification change.
+	// +kubebuilder:validation:Optional
+	Upgrade []UpgradeOperation `json:"upgrade,omitempty"`
+	// StorageMigration lists the NodeSets whose nodes are being replaced to apply a change of their volume claim templates.
+	// +kubebuilder:validation:Optional
+	StorageMigration []StorageMigrationOperation `json:"storageMigration,omitempty"`
+	// FullClusterRestart describes the full cluster restart in progress, if any.
+	// +kubebuilder:validation:Optional
+	FullClusterRestart *FullClusterRestartOperation `json:"fullClusterRestart,omitempty"`
+
+	// Postponed describes the operations waiting for the next maintenance window, if any.
+	// +kubebuilder:validation:Optional
+	Postponed *PostponedOperations `json:"postponed,omitempty"`
+}
+
+// PostponedOperations describes the operations waiting for the next maintenance window.
+type PostponedOperations struct {
+	// NextMaintenanceWindow is the start time of the next maintenance window.
+	NextMaintenanceWindow metav1.Time `json:"nextMaintenanceWindow"`
+	// Restarts lists the nodes to be restarted during the next maintenance window.
+	Restarts []string `json:"restarts,omitempty"`
+	// Removals lists the nodes to be removed during the next maintenance window.
+	Removals []string `json:"removals,omitempty"`
+}
+
+// UpscaleOperation describes the postponed creation of the nodes of a StatefulSet.
+type UpscaleOperation struct {
+	// StatefulSet is the name of the StatefulSet.
+	StatefulSet string `json:"statefulSet"`
+	// CurrentReplicas is the number of replicas currently allowed for the StatefulSet.
+	CurrentReplicas int32 `json:"currentReplicas"`
+	// TargetReplicas is the number of replicas expected in the StatefulSet.
+	TargetReplicas int32 `json:"targetReplicas"`
+	// Reason explains why the creation of the remaining nodes is postponed.
+	Reason string `json:"reason"`
+}
+
+// StorageMigrationOperation describes the replacement of the nodes of a NodeSet by the nodes of a new StatefulSet with
+// updated volume claim templates.
+type StorageMigrationOperation struct {
+	// NodeSet is the name of the NodeSet.
+	NodeSet string `json:"nodeSet"`
+	// StatefulSet is the name of the StatefulSet with the updated volume claim templates.
+	StatefulSet string `json:"statefulSet"`
+	// ReplacedStatefulSets are the names of the StatefulSets whose nodes are being replaced.
+	ReplacedStatefulSets []string `json:"replacedStatefulSets"`
+	// RemainingNodes is the number of nodes left in the replaced StatefulSets.
+	RemainingNodes int32 `json:"remainingNodes"`
+}
+
+// FullClusterRestartOperation describes a full cluster restart.
+type FullClusterRestartOperation struct {
+	// Phase of the full cluster restart.
+	Phase FullClusterRestartPhase `json:"phase"`
+	// Nodes lists the restarted nodes that are not back in the cluster yet.
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// FullClusterRestartPhase is the phase of a full cluster restart.
+type FullClusterRestartPhase string
+
+const (
+	// FullClusterRestartRestarting means the Pods of the nodes to restart have been deleted, and the operator waits for
+	// the nodes to join the cluster again.
+	FullClusterRestartRestarting FullClusterRestartPhase = "Restarting"
+	// FullClusterRestartRecovering means all the nodes are back in the cluster, and the operator waits for the cluster
+	// health to be at least yellow before enabling shards allocation.
+	FullClusterRestartRecovering FullClusterRestartPhase = "Recovering"
+)
+
+// DownscaleOperation describes a node to be removed from the cluster.
+type DownscaleOperation struct {
+	// Node is the name of the node.
+	Node string `json:"node"`
+	// RemainingShards is the number of shards still allocated to the node, to be migrated before the node is removed.
+	RemainingShards int `json:"remainingShards"`
+}
+
+// UpgradeStatus is the status of a node to be restarted during a rolling upgrade.
+type UpgradeStatus string
+
+const (
+	// UpgradePending means the node waits for the restart of other nodes, within the limits of the change budget.
+	UpgradePending UpgradeStatus = "Pending"
+	// UpgradeBlocked means a predicate currently prevents the node from being restarted.
+	UpgradeBlocked UpgradeStatus = "Blocked"
+	// UpgradeRestarting means the Pod of the node has been deleted to be recreated with the expected specification.
+	UpgradeRestarting UpgradeStatus = "Restarting"
+)
+
+// UpgradeOperation describes a node to be restarted during a rolling upgrade.
+type UpgradeOperation struct {
+	// Node is the name of the node.
+	Node string `json:"node"`
+	// Status of the node restart.
+	Status UpgradeStatus `json:"status"`
+	// Predicate is the name of the predicate preventing the node from being restarted, if the status is Blocked.
+	// +kubebuilder:validation:Optional
+	Predicate string `json:"predicate,omitempty"`
+}
+
+// SnapshotPolicyStatus holds the status of a snapshot lifecycle policy.
+type SnapshotPolicyStatus struct {
+	// Name of the policy.
+	Name string `json:"name"`
+	// LastSuccess is the last snapshot successfully taken by the policy.
+	// +kubebuilder:validation:Optional
+	LastSuccess *SnapshotResult `json:"lastSuccess,omitempty"`
+	// LastFailure is the last snapshot the policy failed to take.
+	// +kubebuilder:validation:Optional
+	LastFailure *SnapshotResult `json:"lastFailure,omitempty"`
+}
+
+// SnapshotResult holds the result of a snapshot taken by a snapshot lifecycle policy.
+type SnapshotResult struct {
+	// SnapshotName is the name of the snapshot.
+	SnapshotName string `json:"snapshotName"`
+	// Time is the time the snapshot was taken.
+	Time metav1.Time `json:"time"`
+	// Details holds the failure details, if any.
+	// +kubebuilder:validation:Optional
+	Details string `json:"details,omitempty"`
+}
+
+// NodeSetAutoscalingStatus holds the status of an autoscaled NodeSet.
+type NodeSetAutoscalingStatus struct {
+	// Name of the NodeSet.
+	Name string `json:"name"`
+	// Count is the number of nodes required by the autoscaling policy.
+	Count int32 `json:"count"`
+	// DiskUsagePercent is the last observed ratio of used disk space of the NodeSet, in percent.
+	// +kubebuilder:validation:Optional
+	DiskUsagePercent *int32 `json:"diskUsagePercent,omitempty"`
+	// LastScaleTime is the last time the number of nodes was changed by the autoscaling policy.
+	// +kubebuilder:validation:Optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+}
+
+// GetAutoscalingStatus returns the autoscaling status of the given NodeSet, if any.
+func (es ElasticsearchStatus) GetAutoscalingStatus(nodeSetName string) (NodeSetAutoscalingStatus, bool) {
+	for _, status := range es.Autoscaling {
+		if status.Name == nodeSetName {
+			return status, true
+		}
+	}
+	return NodeSetAutoscalingStatus{}, false
 }
 
 type ZenDiscoveryStatus struct {
@@ -344,6 +827,16 @@ func (es Elasticsearch) SecureSettings() []commonv1.SecretSource {
 	return es.Spec.SecureSettings
 }
 
+// ServiceAccountName returns the name of the service account used to check access to resources in other namespaces.
+func (es *Elasticsearch) ServiceAccountName() string {
+	return es.Spec.ServiceAccountName
+}
+
+// MonitoringSpec returns the references to the Elasticsearch clusters receiving the metrics and logs of this cluster.
+func (es *Elasticsearch) MonitoringSpec() commonv1.Monitoring {
+	return es.Spec.Monitoring
+}
+
 // +kubebuilder:object:root=true
 
 // ElasticsearchList contains a list of Elasticsearch clusters
@@ -7,6 +7,8 @@ package v1
 const (
 	ClusterName = "cluster.name"
 
+	ClusterRoutingAllocationAwarenessAttributes = "cluster.routing.allocation.awareness.attributes"
+
 	DiscoveryZenMinimumMasterNodes = "discovery.zen.minimum_master_nodes"
 	ClusterInitialMasterNodes      = "cluster.initial_master_nodes"
 
@@ -18,20 +20,30 @@ const (
 
 	NodeName = "node.name"
 
+	NodeAttrZone = "node.attr.zone"
+
 	PathData = "path.data"
 	PathLogs = "path.logs"
 
-	XPackSecurityAuthcRealmsFileFile1Order     = "xpack.security.authc.realms.file.file1.order"     // 7.x realm syntax
-	XPackSecurityAuthcRealmsFile1Order         = "xpack.security.authc.realms.file1.order"          // 6.x realm syntax
-	XPackSecurityAuthcRealmsFile1Type          = "xpack.security.authc.realms.file1.type"           // 6.x realm syntax
-	XPackSecurityAuthcRealmsNativeNative1Order = "xpack.security.authc.realms.native.native1.order" // 7.x realm syntax
-	XPackSecurityAuthcRealmsNative1Order       = "xpack.security.authc.realms.native1.order"        // 6.x realm syntax
-	XPackSecurityAuthcRealmsNative1Type        = "xpack.security.authc.realms.native1.type"         // 6.x realm syntax
+	XPackSecurityAuthcRealmsFileFile1Order                = "xpack.security.authc.realms.file.file1.order"                 // 7.x realm syntax
+	XPackSecurityAuthcRealmsFile1Order                    = "xpack.security.authc.realms.file1.order"                      // 6.x realm syntax
+	XPackSecurityAuthcRealmsFile1Type                     = "xpack.security.authc.realms.file1.type"                       // 6.x realm syntax
+	XPackSecurityAuthcRealmsNativeNative1Order            = "xpack.security.authc.realms.native.native1.order"             // 7.x realm syntax
+	XPackSecurityAuthcRealmsNative1Order                  = "xpack.security.authc.realms.native1.order"                    // 6.x realm syntax
+	XPackSecurityAuthcRealmsNative1Type                   = "xpack.security.authc.realms.native1.type"                     // 6.x realm syntax
+	XPackSecurityAuthcRealmsPkiPki1Order                  = "xpack.security.authc.realms.pki.pki1.order"                   // 7.x realm syntax
+	XPackSecurityAuthcRealmsPkiPki1RoleMapping            = "xpack.security.authc.realms.pki.pki1.files.role_mapping"      // 7.x realm syntax
+	XPackSecurityAuthcRealmsPkiPki1CertificateAuthorities = "xpack.security.authc.realms.pki.pki1.certificate_authorities" // 7.x realm syntax
+	XPackSecurityAuthcRealmsPki1Order                     = "xpack.security.authc.realms.pki1.order"                       // 6.x realm syntax
+	XPackSecurityAuthcRealmsPki1Type                      = "xpack.security.authc.realms.pki1.type"                        // 6.x realm syntax
+	XPackSecurityAuthcRealmsPki1RoleMapping               = "xpack.security.authc.realms.pki1.files.role_mapping"          // 6.x realm syntax
+	XPackSecurityAuthcRealmsPki1CertificateAuthorities    = "xpack.security.authc.realms.pki1.certificate_authorities"     // 6.x realm syntax
 
 	XPackSecurityAuthcReservedRealmEnabled          = "xpack.security.authc.reserved_realm.enabled"
 	XPackSecurityEnabled                            = "xpack.security.enabled"
 	XPackSecurityHttpSslCertificate                 = "xpack.security.http.ssl.certificate"
 	XPackSecurityHttpSslCertificateAuthorities      = "xpack.security.http.ssl.certificate_authorities"
+	XPackSecurityHttpSslClientAuthentication        = "xpack.security.http.ssl.client_authentication"
 	XPackSecurityHttpSslEnabled                     = "xpack.security.http.ssl.enabled"
 	XPackSecurityHttpSslKey                         = "xpack.security.http.ssl.key"
 	XPackSecurityTransportSslCertificate            = "xpack.security.transport.ssl.certificate"
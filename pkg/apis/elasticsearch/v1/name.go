@@ -21,9 +21,11 @@ const (
 	transportServiceSuffix            = "transport"
 	elasticUserSecretSuffix           = "elastic-user"
 	internalUsersSecretSuffix         = "internal-users"
+	credentialsRotationSecretSuffix   = "credentials-rotation"
 	unicastHostsConfigMapSuffix       = "unicast-hosts"
 	licenseSecretSuffix               = "license"
 	defaultPodDisruptionBudget        = "default"
+	networkPolicySuffix               = "network-policy"
 	scriptsConfigMapSuffix            = "scripts"
 	transportCertificatesSecretSuffix = "transport-certificates"
 
@@ -34,7 +36,17 @@ const (
 	// remoteCaNameSuffix is a suffix for the secret that contains the concatenation of all the remote CAs
 	remoteCaNameSuffix = "remote-ca"
 
+	planConfigMapSuffix = "plan"
+
+	// httpClientCertsSecretSuffix is a suffix for the secret holding the client certificate authorities trusted on
+	// the HTTP layer, and the client certificate of the operator
+	httpClientCertsSecretSuffix = "http-client-certs"
+
 	controllerRevisionHashLen = 10
+
+	// StorageMigrationSuffixLength is the length of the suffix appended to the name of a NodeSet to name the
+	// StatefulSet its nodes are migrated to when its volume claim templates change.
+	StorageMigrationSuffixLength = 5
 )
 
 var (
@@ -48,12 +60,16 @@ var (
 		elasticUserSecretSuffix,
 		rolesAndFileRealmSecretSuffix,
 		internalUsersSecretSuffix,
+		credentialsRotationSecretSuffix,
 		unicastHostsConfigMapSuffix,
 		licenseSecretSuffix,
 		defaultPodDisruptionBudget,
+		networkPolicySuffix,
 		scriptsConfigMapSuffix,
 		transportCertificatesSecretSuffix,
 		remoteCaNameSuffix,
+		planConfigMapSuffix,
+		httpClientCertsSecretSuffix,
 	}
 )
 
@@ -80,12 +96,21 @@ func validateNames(es *Elasticsearch) error {
 		}
 
 		// length of the ordinal suffix that will be added to the pods of this sset (dash + ordinal)
-		podOrdinalSuffixLen := len(strconv.FormatInt(int64(nodeSet.Count), 10)) + 1
+		maxCount := nodeSet.Count
+		if policy := es.Spec.Autoscaling.GetPolicy(nodeSet.Name); policy != nil && policy.MaxCount > maxCount {
+			maxCount = policy.MaxCount
+		}
+		podOrdinalSuffixLen := len(strconv.FormatInt(int64(maxCount), 10)) + 1
+		// the StatefulSet created to migrate the storage of the NodeSet has a suffixed name
+		migrationSuffixLen := 0
+		if nodeSet.AllowsStorageMigration() {
+			migrationSuffixLen = StorageMigrationSuffixLength + 1
+		}
 		// there should be enough space for the ordinal suffix and the controller revision hash
-		if utilvalidation.LabelValueMaxLength-len(ssetName) < podOrdinalSuffixLen+controllerRevisionHashLen {
+		if utilvalidation.LabelValueMaxLength-len(ssetName) < podOrdinalSuffixLen+controllerRevisionHashLen+migrationSuffixLen {
 			return errors.Errorf("generated StatefulSet name '%s' exceeds allowed length of %d",
 				ssetName,
-				utilvalidation.LabelValueMaxLength-podOrdinalSuffixLen-controllerRevisionHashLen)
+				utilvalidation.LabelValueMaxLength-podOrdinalSuffixLen-controllerRevisionHashLen-migrationSuffixLen)
 		}
 	}
 
@@ -136,6 +161,11 @@ func InternalUsersSecret(esName string) string {
 	return ESNamer.Suffix(esName, internalUsersSecretSuffix)
 }
 
+// CredentialsRotationSecret returns the name of the secret holding the state of the rotation of the credentials.
+func CredentialsRotationSecret(esName string) string {
+	return ESNamer.Suffix(esName, credentialsRotationSecretSuffix)
+}
+
 // UnicastHostsConfigMap returns the name of the ConfigMap that holds the list of seed nodes for a given cluster.
 func UnicastHostsConfigMap(esName string) string {
 	return ESNamer.Suffix(esName, unicastHostsConfigMapSuffix)
@@ -153,6 +183,23 @@ func DefaultPodDisruptionBudget(esName string) string {
 	return ESNamer.Suffix(esName, defaultPodDisruptionBudget)
 }
 
+// NetworkPolicy returns the name of the NetworkPolicy allowing the traffic the operator knows about to the Pods of
+// the given cluster.
+func NetworkPolicy(esName string) string {
+	return ESNamer.Suffix(esName, networkPolicySuffix)
+}
+
 func RemoteCaSecretName(esName string) string {
 	return ESNamer.Suffix(esName, remoteCaNameSuffix)
 }
+
+// PlanConfigMap returns the name of the ConfigMap holding the reconciliation plan of the cluster in dry-run mode.
+func PlanConfigMap(esName string) string {
+	return ESNamer.Suffix(esName, planConfigMapSuffix)
+}
+
+// HTTPClientCertsSecret returns the name of the secret holding the client certificate authorities trusted on the
+// HTTP layer of the cluster, and the client certificate of the operator.
+func HTTPClientCertsSecret(esName string) string {
+	return ESNamer.Suffix(esName, httpClientCertsSecretSuffix)
+}
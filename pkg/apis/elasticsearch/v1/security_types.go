@@ -0,0 +1,242 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+)
+
+// PasswordSecretKey is the key of the password in the secret referenced by an ElasticsearchUser.
+const PasswordSecretKey = "password"
+
+// SecuritySyncPhase is the phase of the synchronization of a security resource with Elasticsearch.
+type SecuritySyncPhase string
+
+const (
+	// SecuritySyncSynced indicates the resource is synchronized with Elasticsearch.
+	SecuritySyncSynced SecuritySyncPhase = "Synced"
+	// SecuritySyncError indicates the resource could not be synchronized with Elasticsearch.
+	SecuritySyncError SecuritySyncPhase = "Error"
+)
+
+// SecuritySyncStatus is the status of the synchronization of a security resource with Elasticsearch.
+type SecuritySyncStatus struct {
+	// Phase is the phase of the synchronization with Elasticsearch.
+	Phase SecuritySyncPhase `json:"phase,omitempty"`
+	// Message is a human readable message indicating details about the last synchronization.
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration is the generation of the resource last synchronized with Elasticsearch.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// SecurityResource is a security resource synchronized with an Elasticsearch cluster.
+// +kubebuilder:object:generate=false
+type SecurityResource interface {
+	metav1.Object
+	runtime.Object
+	// ElasticsearchRef returns a reference to the Elasticsearch cluster in which the resource is synchronized.
+	ElasticsearchRef() commonv1.ObjectSelector
+	// SecurityName returns the name of the resource in Elasticsearch.
+	SecurityName() string
+	// SyncStatus returns the status of the synchronization of the resource.
+	SyncStatus() *SecuritySyncStatus
+}
+
+// ElasticsearchUserSpec holds the specification of a user of the native realm.
+type ElasticsearchUserSpec struct {
+	// ElasticsearchRef is a reference to the Elasticsearch cluster in which the user is created.
+	// The Elasticsearch cluster must be in the same namespace.
+	ElasticsearchRef commonv1.ObjectSelector `json:"elasticsearchRef"`
+	// Username is the name of the user in Elasticsearch. Defaults to the name of the resource.
+	// +kubebuilder:validation:Optional
+	Username string `json:"username,omitempty"`
+	// PasswordSecret is a reference to a secret that contains the password of the user under the `password` key.
+	PasswordSecret commonv1.SecretRef `json:"passwordSecret"`
+	// Roles is the list of roles granted to the user.
+	// +kubebuilder:validation:Optional
+	Roles []string `json:"roles,omitempty"`
+	// FullName is the full name of the user.
+	// +kubebuilder:validation:Optional
+	FullName string `json:"fullName,omitempty"`
+	// Email is the email of the user.
+	// +kubebuilder:validation:Optional
+	Email string `json:"email,omitempty"`
+	// Enabled indicates whether the user is enabled. Defaults to true.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ElasticsearchUser represents a user of the native realm of an Elasticsearch cluster.
+// +kubebuilder:resource:categories=elastic,shortName=esuser
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="elasticsearch",type="string",JSONPath=".spec.elasticsearchRef.name"
+// +kubebuilder:printcolumn:name="phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="age",type="date",JSONPath=".metadata.creationTimestamp"
+type ElasticsearchUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ElasticsearchUserSpec `json:"spec,omitempty"`
+	Status SecuritySyncStatus    `json:"status,omitempty"`
+}
+
+// ElasticsearchRef returns a reference to the Elasticsearch cluster in which the user is created.
+func (u *ElasticsearchUser) ElasticsearchRef() commonv1.ObjectSelector {
+	return u.Spec.ElasticsearchRef.WithDefaultNamespace(u.Namespace)
+}
+
+// SecurityName returns the name of the user in Elasticsearch.
+func (u *ElasticsearchUser) SecurityName() string {
+	if u.Spec.Username != "" {
+		return u.Spec.Username
+	}
+	return u.Name
+}
+
+// SyncStatus returns the status of the synchronization of the user.
+func (u *ElasticsearchUser) SyncStatus() *SecuritySyncStatus {
+	return &u.Status
+}
+
+// +kubebuilder:object:root=true
+
+// ElasticsearchUserList contains a list of ElasticsearchUser.
+type ElasticsearchUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ElasticsearchUser `json:"items"`
+}
+
+// ElasticsearchRoleSpec holds the specification of a role.
+type ElasticsearchRoleSpec struct {
+	// ElasticsearchRef is a reference to the Elasticsearch cluster in which the role is created.
+	// The Elasticsearch cluster must be in the same namespace.
+	ElasticsearchRef commonv1.ObjectSelector `json:"elasticsearchRef"`
+	// RoleName is the name of the role in Elasticsearch. Defaults to the name of the resource.
+	// +kubebuilder:validation:Optional
+	RoleName string `json:"roleName,omitempty"`
+	// Definition is the definition of the role, as expected by the Elasticsearch create or update roles API
+	// (cluster and indices privileges, applications, run_as...).
+	Definition *commonv1.Config `json:"definition"`
+}
+
+// +kubebuilder:object:root=true
+
+// ElasticsearchRole represents a role of an Elasticsearch cluster.
+// +kubebuilder:resource:categories=elastic,shortName=esrole
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="elasticsearch",type="string",JSONPath=".spec.elasticsearchRef.name"
+// +kubebuilder:printcolumn:name="phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="age",type="date",JSONPath=".metadata.creationTimestamp"
+type ElasticsearchRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ElasticsearchRoleSpec `json:"spec,omitempty"`
+	Status SecuritySyncStatus    `json:"status,omitempty"`
+}
+
+// ElasticsearchRef returns a reference to the Elasticsearch cluster in which the role is created.
+func (r *ElasticsearchRole) ElasticsearchRef() commonv1.ObjectSelector {
+	return r.Spec.ElasticsearchRef.WithDefaultNamespace(r.Namespace)
+}
+
+// SecurityName returns the name of the role in Elasticsearch.
+func (r *ElasticsearchRole) SecurityName() string {
+	if r.Spec.RoleName != "" {
+		return r.Spec.RoleName
+	}
+	return r.Name
+}
+
+// SyncStatus returns the status of the synchronization of the role.
+func (r *ElasticsearchRole) SyncStatus() *SecuritySyncStatus {
+	return &r.Status
+}
+
+// +kubebuilder:object:root=true
+
+// ElasticsearchRoleList contains a list of ElasticsearchRole.
+type ElasticsearchRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ElasticsearchRole `json:"items"`
+}
+
+// ElasticsearchRoleMappingSpec holds the specification of a role mapping.
+type ElasticsearchRoleMappingSpec struct {
+	// ElasticsearchRef is a reference to the Elasticsearch cluster in which the role mapping is created.
+	// The Elasticsearch cluster must be in the same namespace.
+	ElasticsearchRef commonv1.ObjectSelector `json:"elasticsearchRef"`
+	// MappingName is the name of the role mapping in Elasticsearch. Defaults to the name of the resource.
+	// +kubebuilder:validation:Optional
+	MappingName string `json:"mappingName,omitempty"`
+	// Enabled indicates whether the role mapping is enabled. Defaults to true.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled,omitempty"`
+	// Roles is the list of roles granted to the users matching the rules.
+	// +kubebuilder:validation:MinItems=1
+	Roles []string `json:"roles"`
+	// Rules are the rules matching the users, as expected by the Elasticsearch role mapping API
+	// (eg. {"field": {"realm.name": "saml1"}}).
+	Rules *commonv1.Config `json:"rules"`
+}
+
+// +kubebuilder:object:root=true
+
+// ElasticsearchRoleMapping represents a role mapping of an Elasticsearch cluster, used to grant roles to the users
+// of external realms such as SAML or LDAP.
+// +kubebuilder:resource:categories=elastic,shortName=esrolemapping
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="elasticsearch",type="string",JSONPath=".spec.elasticsearchRef.name"
+// +kubebuilder:printcolumn:name="phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="age",type="date",JSONPath=".metadata.creationTimestamp"
+type ElasticsearchRoleMapping struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ElasticsearchRoleMappingSpec `json:"spec,omitempty"`
+	Status SecuritySyncStatus           `json:"status,omitempty"`
+}
+
+// ElasticsearchRef returns a reference to the Elasticsearch cluster in which the role mapping is created.
+func (m *ElasticsearchRoleMapping) ElasticsearchRef() commonv1.ObjectSelector {
+	return m.Spec.ElasticsearchRef.WithDefaultNamespace(m.Namespace)
+}
+
+// SecurityName returns the name of the role mapping in Elasticsearch.
+func (m *ElasticsearchRoleMapping) SecurityName() string {
+	if m.Spec.MappingName != "" {
+		return m.Spec.MappingName
+	}
+	return m.Name
+}
+
+// SyncStatus returns the status of the synchronization of the role mapping.
+func (m *ElasticsearchRoleMapping) SyncStatus() *SecuritySyncStatus {
+	return &m.Status
+}
+
+// +kubebuilder:object:root=true
+
+// ElasticsearchRoleMappingList contains a list of ElasticsearchRoleMapping.
+type ElasticsearchRoleMappingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ElasticsearchRoleMapping `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(
+		&ElasticsearchUser{}, &ElasticsearchUserList{},
+		&ElasticsearchRole{}, &ElasticsearchRoleList{},
+		&ElasticsearchRoleMapping{}, &ElasticsearchRoleMappingList{},
+	)
+}
@@ -0,0 +1,116 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// CrossNamespaceReferenceMsg is the message of the validation error of a user, role or role mapping referencing an
+// Elasticsearch cluster in another namespace: only the resources in the namespace of the cluster are synchronized.
+const CrossNamespaceReferenceMsg = "referencing an Elasticsearch cluster in another namespace is not supported"
+
+// +kubebuilder:webhook:path=/validate-elasticsearch-k8s-elastic-co-v1-elasticsearchuser,mutating=false,failurePolicy=ignore,groups=elasticsearch.k8s.elastic.co,resources=elasticsearchusers,verbs=create;update,versions=v1,name=elastic-esuser-validation-v1.k8s.elastic.co
+// +kubebuilder:webhook:path=/validate-elasticsearch-k8s-elastic-co-v1-elasticsearchrole,mutating=false,failurePolicy=ignore,groups=elasticsearch.k8s.elastic.co,resources=elasticsearchroles,verbs=create;update,versions=v1,name=elastic-esrole-validation-v1.k8s.elastic.co
+// +kubebuilder:webhook:path=/validate-elasticsearch-k8s-elastic-co-v1-elasticsearchrolemapping,mutating=false,failurePolicy=ignore,groups=elasticsearch.k8s.elastic.co,resources=elasticsearchrolemappings,verbs=create;update,versions=v1,name=elastic-esrolemapping-validation-v1.k8s.elastic.co
+
+var (
+	securityValidationLog = logf.Log.WithName("es-security-validation")
+
+	_ webhook.Validator = &ElasticsearchUser{}
+	_ webhook.Validator = &ElasticsearchRole{}
+	_ webhook.Validator = &ElasticsearchRoleMapping{}
+)
+
+func (u *ElasticsearchUser) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(u).
+		Complete()
+}
+
+func (u *ElasticsearchUser) ValidateCreate() error {
+	securityValidationLog.V(1).Info("validate create", "kind", "ElasticsearchUser", "name", u.Name)
+	return validateSecurityResource(u, "ElasticsearchUser")
+}
+
+func (u *ElasticsearchUser) ValidateUpdate(_ runtime.Object) error {
+	securityValidationLog.V(1).Info("validate update", "kind", "ElasticsearchUser", "name", u.Name)
+	return validateSecurityResource(u, "ElasticsearchUser")
+}
+
+// ValidateDelete is required to implement webhook.Validator, but we do not actually validate deletes
+func (u *ElasticsearchUser) ValidateDelete() error {
+	return nil
+}
+
+func (r *ElasticsearchRole) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+func (r *ElasticsearchRole) ValidateCreate() error {
+	securityValidationLog.V(1).Info("validate create", "kind", "ElasticsearchRole", "name", r.Name)
+	return validateSecurityResource(r, "ElasticsearchRole")
+}
+
+func (r *ElasticsearchRole) ValidateUpdate(_ runtime.Object) error {
+	securityValidationLog.V(1).Info("validate update", "kind", "ElasticsearchRole", "name", r.Name)
+	return validateSecurityResource(r, "ElasticsearchRole")
+}
+
+// ValidateDelete is required to implement webhook.Validator, but we do not actually validate deletes
+func (r *ElasticsearchRole) ValidateDelete() error {
+	return nil
+}
+
+func (m *ElasticsearchRoleMapping) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(m).
+		Complete()
+}
+
+func (m *ElasticsearchRoleMapping) ValidateCreate() error {
+	securityValidationLog.V(1).Info("validate create", "kind", "ElasticsearchRoleMapping", "name", m.Name)
+	return validateSecurityResource(m, "ElasticsearchRoleMapping")
+}
+
+func (m *ElasticsearchRoleMapping) ValidateUpdate(_ runtime.Object) error {
+	securityValidationLog.V(1).Info("validate update", "kind", "ElasticsearchRoleMapping", "name", m.Name)
+	return validateSecurityResource(m, "ElasticsearchRoleMapping")
+}
+
+// ValidateDelete is required to implement webhook.Validator, but we do not actually validate deletes
+func (m *ElasticsearchRoleMapping) ValidateDelete() error {
+	return nil
+}
+
+// IsCrossNamespaceReference returns true if the given user, role or role mapping references an Elasticsearch cluster
+// in another namespace. Such resources are ignored, since only the resources in the namespace of the Elasticsearch
+// cluster are synchronized.
+func IsCrossNamespaceReference(resource SecurityResource) bool {
+	return resource.ElasticsearchRef().Namespace != resource.GetNamespace()
+}
+
+func validateSecurityResource(resource SecurityResource, kind string) error {
+	if !IsCrossNamespaceReference(resource) {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: "elasticsearch.k8s.elastic.co", Kind: kind},
+		resource.GetName(),
+		field.ErrorList{field.Invalid(
+			field.NewPath("spec").Child("elasticsearchRef", "namespace"),
+			resource.ElasticsearchRef().Namespace,
+			CrossNamespaceReferenceMsg,
+		)},
+	)
+}
@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+)
+
+func TestSecurityResources_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     commonv1.ObjectSelector
+		wantErr bool
+	}{
+		{
+			name:    "implicit namespace",
+			ref:     commonv1.ObjectSelector{Name: "es"},
+			wantErr: false,
+		},
+		{
+			name:    "same namespace",
+			ref:     commonv1.ObjectSelector{Namespace: "ns", Name: "es"},
+			wantErr: false,
+		},
+		{
+			name:    "other namespace",
+			ref:     commonv1.ObjectSelector{Namespace: "other", Name: "es"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := metav1.ObjectMeta{Namespace: "ns", Name: "resource"}
+			user := &ElasticsearchUser{ObjectMeta: meta, Spec: ElasticsearchUserSpec{ElasticsearchRef: tt.ref}}
+			role := &ElasticsearchRole{ObjectMeta: meta, Spec: ElasticsearchRoleSpec{ElasticsearchRef: tt.ref}}
+			mapping := &ElasticsearchRoleMapping{ObjectMeta: meta, Spec: ElasticsearchRoleMappingSpec{ElasticsearchRef: tt.ref}}
+			for _, err := range []error{
+				user.ValidateCreate(), user.ValidateUpdate(user),
+				role.ValidateCreate(), role.ValidateUpdate(role),
+				mapping.ValidateCreate(), mapping.ValidateUpdate(mapping),
+			} {
+				if tt.wantErr {
+					require.Error(t, err)
+					require.Contains(t, err.Error(), CrossNamespaceReferenceMsg)
+				} else {
+					require.NoError(t, err)
+				}
+			}
+		})
+	}
+}
@@ -8,30 +8,61 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"time"
 
 	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	esversion "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/version"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/chrono"
 	netutil "github.com/elastic/cloud-on-k8s/pkg/utils/net"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 const (
-	cfgInvalidMsg            = "Configuration invalid"
-	masterRequiredMsg        = "Elasticsearch needs to have at least one master node"
-	parseVersionErrMsg       = "Cannot parse Elasticsearch version"
-	parseStoredVersionErrMsg = "Cannot parse current Elasticsearch version"
-	invalidSanIPErrMsg       = "Invalid SAN IP address"
-	pvcImmutableMsg          = "Volume claim templates cannot be modified"
-	invalidNamesErrMsg       = "Elasticsearch configuration would generate resources with invalid names"
-	unsupportedVersionErrMsg = "Unsupported version"
-	unsupportedConfigErrMsg  = "Configuration setting is reserved for internal use. User-configured use is unsupported"
-	duplicateNodeSets        = "NodeSet names must be unique"
-	noDowngradesMsg          = "Downgrades are not supported"
-	unsupportedVersionMsg    = "Unsupported version"
-	unsupportedUpgradeMsg    = "Unsupported version upgrade path"
+	cfgInvalidMsg                = "Configuration invalid"
+	masterRequiredMsg            = "Elasticsearch needs to have at least one master node"
+	parseVersionErrMsg           = "Cannot parse Elasticsearch version"
+	parseStoredVersionErrMsg     = "Cannot parse current Elasticsearch version"
+	invalidSanIPErrMsg           = "Invalid SAN IP address"
+	pvcImmutableMsg              = "Volume claim templates cannot be modified, except for storage request increases, unless volumeClaimTemplatesUpdateStrategy is Migrate"
+	invalidNamesErrMsg           = "Elasticsearch configuration would generate resources with invalid names"
+	unsupportedVersionErrMsg     = "Unsupported version"
+	unsupportedConfigErrMsg      = "Configuration setting is reserved for internal use. User-configured use is unsupported"
+	duplicateNodeSets            = "NodeSet names must be unique"
+	noDowngradesMsg              = "Downgrades are not supported"
+	unsupportedVersionMsg        = "Unsupported version"
+	unsupportedUpgradeMsg        = "Unsupported version upgrade path"
+	autoscalingNodeSetMsg        = "Autoscaling policy must reference an existing NodeSet"
+	autoscalingDuplicateMsg      = "Autoscaling policies must reference distinct NodeSets"
+	autoscalingCountsMsg         = "Autoscaling policy minCount must be at least 1 and at most maxCount"
+	autoscalingTargetMsg         = "Autoscaling policy targetDiskUsagePercent must be between 1 and 100"
+	autoscalingMasterMsg         = "Autoscaling policy cannot reference a master-eligible NodeSet"
+	snapshotDuplicateMsg         = "Snapshot repository and policy names must be unique"
+	snapshotCredentialsMsg       = "Snapshot repository credentials must be provided through secureSettings"
+	snapshotPolicyVersionMsg     = "Snapshot lifecycle policies require Elasticsearch 7.4.0 or above"
+	remoteClusterDuplicateMsg    = "Remote cluster names must be unique"
+	remoteClusterModeMsg         = "Remote cluster must specify exactly one of elasticsearchRef, seeds or proxyAddress"
+	remoteClusterProxyVersionMsg = "Remote cluster proxy mode requires Elasticsearch 7.7.0 or above"
+	remoteClusterServerNameMsg   = "Remote cluster serverName can only be set in proxy mode"
+	remoteClusterExternalCAMsg   = "Remote cluster certificateAuthorities can only be set for clusters running outside of Kubernetes"
+	maintenanceScheduleMsg       = "Maintenance window schedule must be a valid cron expression"
+	maintenanceNeverMsg          = "Maintenance window schedule never matches"
+	maintenanceDurationMsg       = "Maintenance window duration must be positive"
+	credentialsMaxAgeMsg         = "Credentials rotation maxAge must be positive"
+	clientAuthenticationTLSMsg   = "Client authentication requires TLS to be enabled on the HTTP layer"
+	zoneAwarenessMixedMsg        = "Zone awareness must be enabled on all master and data NodeSets, or on none of them"
 )
 
+// snapshotRepositoryCredentialSettings are repository settings holding credentials, which should be stored in the keystore.
+var snapshotRepositoryCredentialSettings = []string{"access_key", "secret_key", "session_token", "sas_token", "credentials_file"}
+
+// snapshotLifecycleMinVersion is the first Elasticsearch version supporting snapshot lifecycle management.
+var snapshotLifecycleMinVersion = version.MustParse("7.4.0")
+
+// remoteClusterProxyModeMinVersion is the first Elasticsearch version supporting the proxy mode for remote clusters.
+var remoteClusterProxyModeMinVersion = version.MustParse("7.7.0")
+
 type validation func(*Elasticsearch) field.ErrorList
 
 // validations are the validation funcs that apply to creates or updates
@@ -41,6 +72,14 @@ var validations = []validation{
 	hasMaster,
 	supportedVersion,
 	validSanIP,
+	validAutoscalingPolicies,
+	validSnapshots,
+	validMonitoring,
+	validRemoteClusters,
+	validMaintenanceWindows,
+	validCredentialsRotation,
+	validClientAuthentication,
+	validZoneAwareness,
 }
 
 type updateValidation func(*Elasticsearch, *Elasticsearch) field.ErrorList
@@ -110,15 +149,201 @@ func validSanIP(es *Elasticsearch) field.ErrorList {
 	var errs field.ErrorList
 	selfSignedCerts := es.Spec.HTTP.TLS.SelfSignedCertificate
 	if selfSignedCerts != nil {
-		for _, san := range selfSignedCerts.SubjectAlternativeNames {
-			if san.IP != "" {
-				ip := netutil.MaybeIPTo4(net.ParseIP(san.IP))
-				if ip == nil {
-					errs = append(errs, field.Invalid(field.NewPath("spec").Child("http", "tls", "selfSignedCertificate", "subjectAlternativeNames"), san.IP, invalidSanIPErrMsg))
-				}
+		errs = append(errs, checkSanIPs(selfSignedCerts.SubjectAlternativeNames, field.NewPath("spec").Child("http", "tls", "selfSignedCertificate", "subjectAlternativeNames"))...)
+	}
+	errs = append(errs, checkSanIPs(es.Spec.Transport.TLS.SubjectAlternativeNames, field.NewPath("spec").Child("transport", "tls", "subjectAltNames"))...)
+	return errs
+}
+
+func checkSanIPs(sans []commonv1.SubjectAlternativeName, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, san := range sans {
+		if san.IP != "" {
+			ip := netutil.MaybeIPTo4(net.ParseIP(san.IP))
+			if ip == nil {
+				errs = append(errs, field.Invalid(path, san.IP, invalidSanIPErrMsg))
+			}
+		}
+	}
+	return errs
+}
+
+// validAutoscalingPolicies checks that autoscaling policies reference distinct existing NodeSets, with consistent settings.
+// Master-eligible NodeSets cannot be autoscaled: master nodes are created one at a time, which would turn a scale up
+// in reaction to the disk usage into a long series of cluster membership changes.
+func validAutoscalingPolicies(es *Elasticsearch) field.ErrorList {
+	var errs field.ErrorList
+	if es.Spec.Autoscaling == nil {
+		return errs
+	}
+	seen := make(map[string]struct{})
+	for i, policy := range es.Spec.Autoscaling.Policies {
+		path := field.NewPath("spec").Child("autoscaling", "policies").Index(i)
+		nodeSet := getNode(policy.NodeSet, es)
+		if nodeSet == nil {
+			errs = append(errs, field.Invalid(path.Child("nodeSet"), policy.NodeSet, autoscalingNodeSetMsg))
+		} else if cfg, err := UnpackConfig(nodeSet.Config); err == nil && cfg.Node.Master {
+			// invalid configurations are reported by hasMaster
+			errs = append(errs, field.Invalid(path.Child("nodeSet"), policy.NodeSet, autoscalingMasterMsg))
+		}
+		if _, exists := seen[policy.NodeSet]; exists {
+			errs = append(errs, field.Invalid(path.Child("nodeSet"), policy.NodeSet, autoscalingDuplicateMsg))
+		}
+		seen[policy.NodeSet] = struct{}{}
+		if policy.MinCount < 1 || policy.MinCount > policy.MaxCount {
+			errs = append(errs, field.Invalid(path, policy, autoscalingCountsMsg))
+		}
+		if policy.TargetDiskUsagePercent < 1 || policy.TargetDiskUsagePercent > 100 {
+			errs = append(errs, field.Invalid(path.Child("targetDiskUsagePercent"), policy.TargetDiskUsagePercent, autoscalingTargetMsg))
+		}
+	}
+	return errs
+}
+
+// validSnapshots checks that snapshot repositories and policies have unique names, that repositories do not hold
+// credentials, and that snapshot lifecycle policies are supported by the Elasticsearch version.
+func validSnapshots(es *Elasticsearch) field.ErrorList {
+	var errs field.ErrorList
+	if es.Spec.Snapshots == nil {
+		return errs
+	}
+	path := field.NewPath("spec").Child("snapshots")
+
+	repositories := make(map[string]struct{})
+	for i, repository := range es.Spec.Snapshots.Repositories {
+		if _, exists := repositories[repository.Name]; exists {
+			errs = append(errs, field.Invalid(path.Child("repositories").Index(i).Child("name"), repository.Name, snapshotDuplicateMsg))
+		}
+		repositories[repository.Name] = struct{}{}
+		if repository.Settings == nil {
+			continue
+		}
+		for _, setting := range snapshotRepositoryCredentialSettings {
+			if _, exists := repository.Settings.Data[setting]; exists {
+				errs = append(errs, field.Forbidden(path.Child("repositories").Index(i).Child("settings", setting), snapshotCredentialsMsg))
 			}
 		}
 	}
+
+	if len(es.Spec.Snapshots.Policies) == 0 {
+		return errs
+	}
+	if ver, err := version.Parse(es.Spec.Version); err == nil && !ver.IsSameOrAfter(snapshotLifecycleMinVersion) {
+		errs = append(errs, field.Invalid(path.Child("policies"), es.Spec.Version, snapshotPolicyVersionMsg))
+	}
+	policies := make(map[string]struct{})
+	for i, policy := range es.Spec.Snapshots.Policies {
+		if _, exists := policies[policy.Name]; exists {
+			errs = append(errs, field.Invalid(path.Child("policies").Index(i).Child("name"), policy.Name, snapshotDuplicateMsg))
+		}
+		policies[policy.Name] = struct{}{}
+	}
+	return errs
+}
+
+// validMonitoring checks the references to the Elasticsearch clusters receiving the metrics and logs of this cluster.
+func validMonitoring(es *Elasticsearch) field.ErrorList {
+	return commonv1.CheckMonitoring(es.Spec.Monitoring, es.Spec.Version)
+}
+
+// validRemoteClusters checks that remote clusters have unique names and a single connection mode supported by the
+// Elasticsearch version.
+func validRemoteClusters(es *Elasticsearch) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("spec").Child("remoteClusters")
+	names := make(map[string]struct{})
+	for i, remoteCluster := range es.Spec.RemoteClusters {
+		if _, exists := names[remoteCluster.Name]; exists {
+			errs = append(errs, field.Invalid(path.Index(i).Child("name"), remoteCluster.Name, remoteClusterDuplicateMsg))
+		}
+		names[remoteCluster.Name] = struct{}{}
+
+		modes := 0
+		for _, defined := range []bool{
+			remoteCluster.ElasticsearchRef.IsDefined(), len(remoteCluster.Seeds) > 0, remoteCluster.ProxyAddress != "",
+		} {
+			if defined {
+				modes++
+			}
+		}
+		if modes != 1 {
+			errs = append(errs, field.Invalid(path.Index(i), remoteCluster.Name, remoteClusterModeMsg))
+		}
+		if remoteCluster.IsProxyMode() {
+			if ver, err := version.Parse(es.Spec.Version); err == nil && !ver.IsSameOrAfter(remoteClusterProxyModeMinVersion) {
+				errs = append(errs, field.Invalid(path.Index(i).Child("proxyAddress"), remoteCluster.ProxyAddress, remoteClusterProxyVersionMsg))
+			}
+		} else if remoteCluster.ServerName != "" {
+			errs = append(errs, field.Invalid(path.Index(i).Child("serverName"), remoteCluster.ServerName, remoteClusterServerNameMsg))
+		}
+		if remoteCluster.ElasticsearchRef.IsDefined() && remoteCluster.CertificateAuthorities.SecretName != "" {
+			errs = append(errs, field.Invalid(path.Index(i).Child("certificateAuthorities"), remoteCluster.CertificateAuthorities.SecretName, remoteClusterExternalCAMsg))
+		}
+	}
+	return errs
+}
+
+// validMaintenanceWindows checks that the maintenance windows have a valid schedule and a positive duration.
+func validMaintenanceWindows(es *Elasticsearch) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("spec").Child("updateStrategy").Child("maintenanceWindows")
+	for i, window := range es.Spec.UpdateStrategy.MaintenanceWindows {
+		schedule, err := chrono.ParseCronSchedule(window.Schedule)
+		if err != nil {
+			errs = append(errs, field.Invalid(path.Index(i).Child("schedule"), window.Schedule, fmt.Sprintf("%s: %s", maintenanceScheduleMsg, err)))
+		} else if schedule.Next(time.Now()).IsZero() {
+			errs = append(errs, field.Invalid(path.Index(i).Child("schedule"), window.Schedule, maintenanceNeverMsg))
+		}
+		if window.Duration.Duration <= 0 {
+			errs = append(errs, field.Invalid(path.Index(i).Child("duration"), window.Duration.String(), maintenanceDurationMsg))
+		}
+	}
+	return errs
+}
+
+func validCredentialsRotation(es *Elasticsearch) field.ErrorList {
+	rotation := es.Spec.Auth.Rotation
+	if rotation == nil || rotation.MaxAge == nil || rotation.MaxAge.Duration > 0 {
+		return nil
+	}
+	path := field.NewPath("spec").Child("auth").Child("rotation").Child("maxAge")
+	return field.ErrorList{field.Invalid(path, rotation.MaxAge.String(), credentialsMaxAgeMsg)}
+}
+
+// validClientAuthentication checks that clients can only authenticate with certificates over TLS.
+func validClientAuthentication(es *Elasticsearch) field.ErrorList {
+	tls := es.Spec.HTTP.TLS
+	if tls.Client == nil || tls.Enabled() {
+		return nil
+	}
+	path := field.NewPath("spec").Child("http").Child("tls").Child("client")
+	return field.ErrorList{field.Forbidden(path, clientAuthenticationTLSMsg)}
+}
+
+// validZoneAwareness checks that zone awareness is either enabled or disabled on all the master and data NodeSets:
+// the shard allocation awareness settings of the elected master must match the zone attributes of the data nodes.
+func validZoneAwareness(es *Elasticsearch) field.ErrorList {
+	var enabled, disabled []int
+	for i, nodeSet := range es.Spec.NodeSets {
+		cfg, err := UnpackConfig(nodeSet.Config)
+		if err != nil || !(cfg.Node.Master || cfg.Node.Data) {
+			// invalid configurations are reported by hasMaster
+			continue
+		}
+		if nodeSet.ZoneAwareness != nil {
+			enabled = append(enabled, i)
+		} else {
+			disabled = append(disabled, i)
+		}
+	}
+	if len(enabled) == 0 || len(disabled) == 0 {
+		return nil
+	}
+	var errs field.ErrorList
+	for _, i := range disabled {
+		path := field.NewPath("spec").Child("nodeSets").Index(i).Child("zoneAwareness")
+		errs = append(errs, field.Required(path, zoneAwarenessMixedMsg))
+	}
 	return errs
 }
 
@@ -139,7 +364,10 @@ func checkNodeSetNameUniqueness(es *Elasticsearch) field.ErrorList {
 	return errs
 }
 
-// pvcModification ensures no PVCs are changed, as volume claim templates are immutable in stateful sets
+// pvcModification ensures no PVCs are changed, as volume claim templates are immutable in stateful sets.
+// The only exception is an increase of the storage request, which the operator handles through volume expansion.
+// Storage classes cannot be retrieved here: the operator ignores the increase, and emits a warning event, if the
+// storage class does not allow volume expansion.
 func pvcModification(current, proposed *Elasticsearch) field.ErrorList {
 	var errs field.ErrorList
 	if current == nil || proposed == nil {
@@ -151,16 +379,46 @@ func pvcModification(current, proposed *Elasticsearch) field.ErrorList {
 			// this is a new sset, so there is nothing to check
 			continue
 		}
+		if node.AllowsStorageMigration() {
+			// any change is applied by migrating the data to new nodes
+			continue
+		}
 
 		// ssets do not allow modifications to fields other than 'replicas', 'template', and 'updateStrategy'
 		// reflection isn't ideal, but okay here since the ES object does not have the status of the claims
-		if !reflect.DeepEqual(node.VolumeClaimTemplates, currNode.VolumeClaimTemplates) {
+		if !claimsStorageIncreaseOnly(currNode.VolumeClaimTemplates, node.VolumeClaimTemplates) {
 			errs = append(errs, field.Invalid(field.NewPath("spec").Child("nodeSet").Index(i).Child("volumeClaimTemplates"), node.VolumeClaimTemplates, pvcImmutableMsg))
 		}
 	}
 	return errs
 }
 
+// claimsStorageIncreaseOnly returns true if the proposed claims are identical to the current ones,
+// except for storage requests that may have been increased.
+func claimsStorageIncreaseOnly(current, proposed []corev1.PersistentVolumeClaim) bool {
+	if len(current) != len(proposed) {
+		return false
+	}
+	for i := range proposed {
+		currClaim, propClaim := current[i].DeepCopy(), proposed[i].DeepCopy()
+		currStorage, currExists := currClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+		propStorage, propExists := propClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if currExists && propExists {
+			if propStorage.Cmp(currStorage) < 0 {
+				// storage decrease is not supported
+				return false
+			}
+			// ignore the storage request in the comparison below
+			delete(currClaim.Spec.Resources.Requests, corev1.ResourceStorage)
+			delete(propClaim.Spec.Resources.Requests, corev1.ResourceStorage)
+		}
+		if !reflect.DeepEqual(currClaim, propClaim) {
+			return false
+		}
+	}
+	return true
+}
+
 func noDowngrades(current, proposed *Elasticsearch) field.ErrorList {
 	var errs field.ErrorList
 	if current == nil || proposed == nil {
@@ -6,6 +6,7 @@ package v1
 
 import (
 	"testing"
+	"time"
 
 	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -238,6 +239,32 @@ func Test_validName(t *testing.T) {
 			},
 			expectErrors: false,
 		},
+		{
+			name: "no room left for the storage migration suffix",
+			es: &Elasticsearch{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "that-is-a-very-long-name-with-36char",
+				},
+				Spec: ElasticsearchSpec{
+					NodeSets: []NodeSet{{Name: "data-nodes", Count: 3, VolumeClaimTemplatesUpdateStrategy: VolumeClaimTemplatesMigrate}},
+				},
+			},
+			expectErrors: true,
+		},
+		{
+			name: "room left for the storage migration suffix",
+			es: &Elasticsearch{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "that-is-a-very-long-name-with-36char",
+				},
+				Spec: ElasticsearchSpec{
+					NodeSets: []NodeSet{{Name: "data", Count: 3, VolumeClaimTemplatesUpdateStrategy: VolumeClaimTemplatesMigrate}},
+				},
+			},
+			expectErrors: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -315,6 +342,32 @@ func Test_validSanIP(t *testing.T) {
 			},
 			expectErrors: true,
 		},
+		{
+			name: "valid transport SAN IPs: OK",
+			es: &Elasticsearch{
+				Spec: ElasticsearchSpec{
+					Transport: TransportConfig{
+						TLS: TransportTLSOptions{
+							SubjectAlternativeNames: []commonv1.SubjectAlternativeName{{IP: validIP}, {IP: validIPv6}, {DNS: "es.example.com"}},
+						},
+					},
+				},
+			},
+			expectErrors: false,
+		},
+		{
+			name: "invalid transport SAN IPs: NOT OK",
+			es: &Elasticsearch{
+				Spec: ElasticsearchSpec{
+					Transport: TransportConfig{
+						TLS: TransportTLSOptions{
+							SubjectAlternativeNames: []commonv1.SubjectAlternativeName{{IP: invalidIP}},
+						},
+					},
+				},
+			},
+			expectErrors: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -327,8 +380,405 @@ func Test_validSanIP(t *testing.T) {
 	}
 }
 
+func Test_validAutoscalingPolicies(t *testing.T) {
+	nodeSets := []NodeSet{
+		{Name: "master"},
+		{Name: "data", Config: &commonv1.Config{Data: map[string]interface{}{NodeMaster: false}}},
+	}
+	validPolicy := StorageAutoscalingPolicy{NodeSet: "data", MinCount: 1, MaxCount: 5, TargetDiskUsagePercent: 80}
+	withPolicies := func(policies ...StorageAutoscalingPolicy) *Elasticsearch {
+		return &Elasticsearch{Spec: ElasticsearchSpec{NodeSets: nodeSets, Autoscaling: &AutoscalingSpec{Policies: policies}}}
+	}
+	tests := []struct {
+		name         string
+		es           *Elasticsearch
+		expectErrors bool
+	}{
+		{
+			name:         "no autoscaling: OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{NodeSets: nodeSets}},
+			expectErrors: false,
+		},
+		{
+			name:         "valid policy: OK",
+			es:           withPolicies(validPolicy),
+			expectErrors: false,
+		},
+		{
+			name:         "unknown NodeSet: NOT OK",
+			es:           withPolicies(StorageAutoscalingPolicy{NodeSet: "unknown", MinCount: 1, MaxCount: 5, TargetDiskUsagePercent: 80}),
+			expectErrors: true,
+		},
+		{
+			name:         "master-eligible NodeSet: NOT OK",
+			es:           withPolicies(StorageAutoscalingPolicy{NodeSet: "master", MinCount: 1, MaxCount: 5, TargetDiskUsagePercent: 80}),
+			expectErrors: true,
+		},
+		{
+			name:         "duplicate NodeSet: NOT OK",
+			es:           withPolicies(validPolicy, validPolicy),
+			expectErrors: true,
+		},
+		{
+			name:         "minCount higher than maxCount: NOT OK",
+			es:           withPolicies(StorageAutoscalingPolicy{NodeSet: "data", MinCount: 6, MaxCount: 5, TargetDiskUsagePercent: 80}),
+			expectErrors: true,
+		},
+		{
+			name:         "minCount set to 0: NOT OK",
+			es:           withPolicies(StorageAutoscalingPolicy{NodeSet: "data", MinCount: 0, MaxCount: 5, TargetDiskUsagePercent: 80}),
+			expectErrors: true,
+		},
+		{
+			name:         "invalid target: NOT OK",
+			es:           withPolicies(StorageAutoscalingPolicy{NodeSet: "data", MinCount: 1, MaxCount: 5, TargetDiskUsagePercent: 120}),
+			expectErrors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := validAutoscalingPolicies(tt.es)
+			actualErrors := len(actual) > 0
+			if tt.expectErrors != actualErrors {
+				t.Errorf("failed validAutoscalingPolicies(). Name: %v, actual %v, wanted: %v, value: %v", tt.name, actual, tt.expectErrors, tt.es.Spec)
+			}
+		})
+	}
+}
+
+func Test_validSnapshots(t *testing.T) {
+	repository := SnapshotRepository{Name: "repo", Type: "s3", Settings: &commonv1.Config{Data: map[string]interface{}{"bucket": "snapshots"}}}
+	policy := SnapshotPolicy{Name: "nightly", Schedule: "0 30 1 * * ?", Repository: "repo"}
+	tests := []struct {
+		name         string
+		es           *Elasticsearch
+		expectErrors bool
+	}{
+		{
+			name:         "no snapshots: OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.4.0"}},
+			expectErrors: false,
+		},
+		{
+			name: "repositories and policies: OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.4.0", Snapshots: &SnapshotsSpec{
+				Repositories: []SnapshotRepository{repository},
+				Policies:     []SnapshotPolicy{policy},
+			}}},
+			expectErrors: false,
+		},
+		{
+			name: "repositories with 6.x: OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "6.8.0", Snapshots: &SnapshotsSpec{
+				Repositories: []SnapshotRepository{repository},
+			}}},
+			expectErrors: false,
+		},
+		{
+			name: "policies before 7.4.0: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.3.2", Snapshots: &SnapshotsSpec{
+				Repositories: []SnapshotRepository{repository},
+				Policies:     []SnapshotPolicy{policy},
+			}}},
+			expectErrors: true,
+		},
+		{
+			name: "duplicate repositories: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.4.0", Snapshots: &SnapshotsSpec{
+				Repositories: []SnapshotRepository{repository, repository},
+			}}},
+			expectErrors: true,
+		},
+		{
+			name: "duplicate policies: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.4.0", Snapshots: &SnapshotsSpec{
+				Policies: []SnapshotPolicy{policy, policy},
+			}}},
+			expectErrors: true,
+		},
+		{
+			name: "credentials in repository settings: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.4.0", Snapshots: &SnapshotsSpec{
+				Repositories: []SnapshotRepository{{
+					Name:     "repo",
+					Type:     "s3",
+					Settings: &commonv1.Config{Data: map[string]interface{}{"bucket": "snapshots", "secret_key": "changeme"}},
+				}},
+			}}},
+			expectErrors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := validSnapshots(tt.es)
+			actualErrors := len(actual) > 0
+			if tt.expectErrors != actualErrors {
+				t.Errorf("failed validSnapshots(). Name: %v, actual %v, wanted: %v, value: %v", tt.name, actual, tt.expectErrors, tt.es.Spec)
+			}
+		})
+	}
+}
+
+func Test_validRemoteClusters(t *testing.T) {
+	internal := RemoteCluster{Name: "internal", ElasticsearchRef: commonv1.ObjectSelector{Name: "es2", Namespace: "ns2"}}
+	sniff := RemoteCluster{
+		Name:                   "sniff",
+		Seeds:                  []string{"10.0.0.1:9300", "10.0.0.2:9300"},
+		CertificateAuthorities: commonv1.SecretRef{SecretName: "remote-ca"},
+	}
+	proxy := RemoteCluster{Name: "proxy", ProxyAddress: "proxy.example.com:9300", ServerName: "es.example.com"}
+	tests := []struct {
+		name         string
+		es           *Elasticsearch
+		expectErrors bool
+	}{
+		{
+			name:         "no remote clusters: OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.7.0"}},
+			expectErrors: false,
+		},
+		{
+			name:         "internal, sniff and proxy remote clusters: OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.7.0", RemoteClusters: []RemoteCluster{internal, sniff, proxy}}},
+			expectErrors: false,
+		},
+		{
+			name:         "sniff remote cluster with 6.x: OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{Version: "6.8.0", RemoteClusters: []RemoteCluster{sniff}}},
+			expectErrors: false,
+		},
+		{
+			name:         "proxy remote cluster before 7.7.0: NOT OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.6.2", RemoteClusters: []RemoteCluster{proxy}}},
+			expectErrors: true,
+		},
+		{
+			name:         "duplicate names: NOT OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.7.0", RemoteClusters: []RemoteCluster{sniff, sniff}}},
+			expectErrors: true,
+		},
+		{
+			name:         "no connection mode: NOT OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.7.0", RemoteClusters: []RemoteCluster{{Name: "none"}}}},
+			expectErrors: true,
+		},
+		{
+			name: "seeds and proxy address: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.7.0", RemoteClusters: []RemoteCluster{{
+				Name:         "both",
+				Seeds:        []string{"10.0.0.1:9300"},
+				ProxyAddress: "proxy.example.com:9300",
+			}}}},
+			expectErrors: true,
+		},
+		{
+			name: "server name in sniff mode: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.7.0", RemoteClusters: []RemoteCluster{{
+				Name:       "sniff",
+				Seeds:      []string{"10.0.0.1:9300"},
+				ServerName: "es.example.com",
+			}}}},
+			expectErrors: true,
+		},
+		{
+			name: "certificate authorities of an internal cluster: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.7.0", RemoteClusters: []RemoteCluster{{
+				Name:                   "internal",
+				ElasticsearchRef:       commonv1.ObjectSelector{Name: "es2"},
+				CertificateAuthorities: commonv1.SecretRef{SecretName: "remote-ca"},
+			}}}},
+			expectErrors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := validRemoteClusters(tt.es)
+			actualErrors := len(actual) > 0
+			if tt.expectErrors != actualErrors {
+				t.Errorf("failed validRemoteClusters(). Name: %v, actual %v, wanted: %v, value: %v", tt.name, actual, tt.expectErrors, tt.es.Spec)
+			}
+		})
+	}
+}
+
+func Test_validMonitoring(t *testing.T) {
+	monitoringRef := []commonv1.ObjectSelector{{Name: "monitoring"}}
+	tests := []struct {
+		name         string
+		es           *Elasticsearch
+		expectErrors bool
+	}{
+		{
+			name:         "no monitoring: OK",
+			es:           &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.6.0"}},
+			expectErrors: false,
+		},
+		{
+			name: "metrics and logs: OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.14.0", Monitoring: commonv1.Monitoring{
+				Metrics: commonv1.MetricsMonitoring{ElasticsearchRefs: monitoringRef},
+				Logs:    commonv1.LogsMonitoring{ElasticsearchRefs: monitoringRef},
+			}}},
+			expectErrors: false,
+		},
+		{
+			name: "version before 7.14.0: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.13.4", Monitoring: commonv1.Monitoring{
+				Logs: commonv1.LogsMonitoring{ElasticsearchRefs: monitoringRef},
+			}}},
+			expectErrors: true,
+		},
+		{
+			name: "several monitoring clusters: NOT OK",
+			es: &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.14.0", Monitoring: commonv1.Monitoring{
+				Metrics: commonv1.MetricsMonitoring{ElasticsearchRefs: []commonv1.ObjectSelector{{Name: "m1"}, {Name: "m2"}}},
+			}}},
+			expectErrors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := validMonitoring(tt.es)
+			actualErrors := len(actual) > 0
+			if tt.expectErrors != actualErrors {
+				t.Errorf("failed validMonitoring(). Name: %v, actual %v, wanted: %v, value: %v", tt.name, actual, tt.expectErrors, tt.es.Spec)
+			}
+		})
+	}
+}
+
+func Test_validMaintenanceWindows(t *testing.T) {
+	esWithWindows := func(windows ...MaintenanceWindow) *Elasticsearch {
+		return &Elasticsearch{Spec: ElasticsearchSpec{UpdateStrategy: UpdateStrategy{MaintenanceWindows: windows}}}
+	}
+	threeHours := metav1.Duration{Duration: 3 * time.Hour}
+	tests := []struct {
+		name         string
+		es           *Elasticsearch
+		expectErrors bool
+	}{
+		{
+			name:         "no maintenance window: OK",
+			es:           esWithWindows(),
+			expectErrors: false,
+		},
+		{
+			name:         "weekend nights: OK",
+			es:           esWithWindows(MaintenanceWindow{Schedule: "0 2 * * sat,sun", Duration: threeHours}),
+			expectErrors: false,
+		},
+		{
+			name:         "invalid cron expression: NOT OK",
+			es:           esWithWindows(MaintenanceWindow{Schedule: "0 25 * * *", Duration: threeHours}),
+			expectErrors: true,
+		},
+		{
+			name:         "schedule never matching: NOT OK",
+			es:           esWithWindows(MaintenanceWindow{Schedule: "0 0 31 2 *", Duration: threeHours}),
+			expectErrors: true,
+		},
+		{
+			name:         "no duration: NOT OK",
+			es:           esWithWindows(MaintenanceWindow{Schedule: "0 2 * * *"}),
+			expectErrors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := validMaintenanceWindows(tt.es)
+			actualErrors := len(actual) > 0
+			if tt.expectErrors != actualErrors {
+				t.Errorf("failed validMaintenanceWindows(). Name: %v, actual %v, wanted: %v, value: %v", tt.name, actual, tt.expectErrors, tt.es.Spec)
+			}
+		})
+	}
+}
+
+func Test_validCredentialsRotation(t *testing.T) {
+	esWithRotation := func(rotation *CredentialsRotation) *Elasticsearch {
+		return &Elasticsearch{Spec: ElasticsearchSpec{Auth: Auth{Rotation: rotation}}}
+	}
+	tests := []struct {
+		name         string
+		es           *Elasticsearch
+		expectErrors bool
+	}{
+		{
+			name:         "no rotation: OK",
+			es:           esWithRotation(nil),
+			expectErrors: false,
+		},
+		{
+			name:         "no max age: OK",
+			es:           esWithRotation(&CredentialsRotation{}),
+			expectErrors: false,
+		},
+		{
+			name:         "30 days: OK",
+			es:           esWithRotation(&CredentialsRotation{MaxAge: &metav1.Duration{Duration: 720 * time.Hour}}),
+			expectErrors: false,
+		},
+		{
+			name:         "zero max age: NOT OK",
+			es:           esWithRotation(&CredentialsRotation{MaxAge: &metav1.Duration{}}),
+			expectErrors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := validCredentialsRotation(tt.es)
+			actualErrors := len(actual) > 0
+			if tt.expectErrors != actualErrors {
+				t.Errorf("failed validCredentialsRotation(). Name: %v, actual %v, wanted: %v, value: %v", tt.name, actual, tt.expectErrors, tt.es.Spec)
+			}
+		})
+	}
+}
+
+func Test_validClientAuthentication(t *testing.T) {
+	esWithTLS := func(tls commonv1.TLSOptions) *Elasticsearch {
+		return &Elasticsearch{Spec: ElasticsearchSpec{HTTP: commonv1.HTTPConfig{TLS: tls}}}
+	}
+	tests := []struct {
+		name         string
+		es           *Elasticsearch
+		expectErrors bool
+	}{
+		{
+			name:         "no client authentication: OK",
+			es:           esWithTLS(commonv1.TLSOptions{}),
+			expectErrors: false,
+		},
+		{
+			name: "client authentication with TLS: OK",
+			es: esWithTLS(commonv1.TLSOptions{
+				Client: &commonv1.ClientAuthentication{Mode: commonv1.ClientAuthenticationRequired},
+			}),
+			expectErrors: false,
+		},
+		{
+			name: "client authentication without TLS: NOT OK",
+			es: esWithTLS(commonv1.TLSOptions{
+				SelfSignedCertificate: &commonv1.SelfSignedCertificate{Disabled: true},
+				Client:                &commonv1.ClientAuthentication{},
+			}),
+			expectErrors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := validClientAuthentication(tt.es)
+			actualErrors := len(actual) > 0
+			if tt.expectErrors != actualErrors {
+				t.Errorf("failed validClientAuthentication(). Name: %v, actual %v, wanted: %v, value: %v", tt.name, actual, tt.expectErrors, tt.es.Spec)
+			}
+		})
+	}
+}
+
 func Test_pvcModified(t *testing.T) {
 	current := getEsCluster()
+	otherStorageClass := "other"
 
 	tests := []struct {
 		name         string
@@ -337,7 +787,67 @@ func Test_pvcModified(t *testing.T) {
 		expectErrors bool
 	}{
 		{
-			name:    "resize fails",
+			name:    "storage increase accepted",
+			current: current,
+			proposed: &Elasticsearch{
+				Spec: ElasticsearchSpec{
+					Version: "7.2.0",
+					NodeSets: []NodeSet{
+						{
+							Name: "master",
+							VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+								{
+									ObjectMeta: metav1.ObjectMeta{
+										Name: "elasticsearch-data",
+									},
+									Spec: corev1.PersistentVolumeClaimSpec{
+										Resources: corev1.ResourceRequirements{
+											Requests: corev1.ResourceList{
+												corev1.ResourceStorage: resource.MustParse("10Gi"),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectErrors: false,
+		},
+
+		{
+			name:    "storage decrease rejected",
+			current: current,
+			proposed: &Elasticsearch{
+				Spec: ElasticsearchSpec{
+					Version: "7.2.0",
+					NodeSets: []NodeSet{
+						{
+							Name: "master",
+							VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+								{
+									ObjectMeta: metav1.ObjectMeta{
+										Name: "elasticsearch-data",
+									},
+									Spec: corev1.PersistentVolumeClaimSpec{
+										Resources: corev1.ResourceRequirements{
+											Requests: corev1.ResourceList{
+												corev1.ResourceStorage: resource.MustParse("1Gi"),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectErrors: true,
+		},
+
+		{
+			name:    "storage increase with other changes rejected",
 			current: current,
 			proposed: &Elasticsearch{
 				Spec: ElasticsearchSpec{
@@ -351,6 +861,7 @@ func Test_pvcModified(t *testing.T) {
 										Name: "elasticsearch-data",
 									},
 									Spec: corev1.PersistentVolumeClaimSpec{
+										StorageClassName: &otherStorageClass,
 										Resources: corev1.ResourceRequirements{
 											Requests: corev1.ResourceList{
 												corev1.ResourceStorage: resource.MustParse("10Gi"),
@@ -468,6 +979,37 @@ func Test_pvcModified(t *testing.T) {
 			expectErrors: true,
 		},
 
+		{
+			name:    "name change accepted with the Migrate strategy",
+			current: current,
+			proposed: &Elasticsearch{
+				Spec: ElasticsearchSpec{
+					Version: "7.2.0",
+					NodeSets: []NodeSet{
+						{
+							Name:                               "master",
+							VolumeClaimTemplatesUpdateStrategy: VolumeClaimTemplatesMigrate,
+							VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+								{
+									ObjectMeta: metav1.ObjectMeta{
+										Name: "elasticsearch-data1",
+									},
+									Spec: corev1.PersistentVolumeClaimSpec{
+										Resources: corev1.ResourceRequirements{
+											Requests: corev1.ResourceList{
+												corev1.ResourceStorage: resource.MustParse("5Gi"),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectErrors: false,
+		},
+
 		{
 			name:    "add new node set accepted",
 			current: current,
@@ -688,6 +1230,57 @@ func es(v string) *Elasticsearch {
 	}
 }
 
+func Test_validZoneAwareness(t *testing.T) {
+	ingestOnly := &commonv1.Config{Data: map[string]interface{}{NodeMaster: "false", NodeData: "false"}}
+	esWithNodeSets := func(nodeSets ...NodeSet) *Elasticsearch {
+		return &Elasticsearch{Spec: ElasticsearchSpec{Version: "7.10.0", NodeSets: nodeSets}}
+	}
+	tests := []struct {
+		name         string
+		es           *Elasticsearch
+		expectErrors bool
+	}{
+		{
+			name:         "no zone awareness: OK",
+			es:           esWithNodeSets(NodeSet{Name: "a"}, NodeSet{Name: "b"}),
+			expectErrors: false,
+		},
+		{
+			name: "zone awareness on all NodeSets: OK",
+			es: esWithNodeSets(
+				NodeSet{Name: "a", ZoneAwareness: &ZoneAwareness{}},
+				NodeSet{Name: "b", ZoneAwareness: &ZoneAwareness{}},
+			),
+			expectErrors: false,
+		},
+		{
+			name: "zone awareness on master and data NodeSets only: OK",
+			es: esWithNodeSets(
+				NodeSet{Name: "a", ZoneAwareness: &ZoneAwareness{}},
+				NodeSet{Name: "ingest", Config: ingestOnly},
+			),
+			expectErrors: false,
+		},
+		{
+			name: "zone awareness on some master and data NodeSets: NOT OK",
+			es: esWithNodeSets(
+				NodeSet{Name: "a", ZoneAwareness: &ZoneAwareness{}},
+				NodeSet{Name: "b"},
+			),
+			expectErrors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := validZoneAwareness(tt.es)
+			actualErrors := len(actual) > 0
+			if tt.expectErrors != actualErrors {
+				t.Errorf("failed validZoneAwareness(). Name: %v, actual %v, wanted: %v, value: %v", tt.name, actual, tt.expectErrors, tt.es.Spec)
+			}
+		})
+	}
+}
+
 // // getEsCluster returns a ES cluster test fixture
 func getEsCluster() *Elasticsearch {
 	return &Elasticsearch{
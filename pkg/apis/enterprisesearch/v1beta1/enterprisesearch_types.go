@@ -47,6 +47,11 @@ type EnterpriseSearchSpec struct {
 	// Can only be used if ECK is enforcing RBAC on references.
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// NetworkPolicy controls the NetworkPolicy reconciled by the operator to allow the ingress traffic it knows about
+	// to the Enterprise Search Pods.
+	// +kubebuilder:validation:Optional
+	NetworkPolicy commonv1.NetworkPolicyOptions `json:"networkPolicy,omitempty"`
 }
 
 // ConfigSource references configuration settings to include in the Enterprise Search configuration.
@@ -35,7 +35,7 @@ func (in *EnterpriseSearch) DeepCopyInto(out *EnterpriseSearch) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	if in.assocConf != nil {
 		in, out := &in.assocConf, &out.assocConf
 		*out = new(v1.AssociationConf)
@@ -108,6 +108,7 @@ func (in *EnterpriseSearchSpec) DeepCopyInto(out *EnterpriseSearchSpec) {
 	in.HTTP.DeepCopyInto(&out.HTTP)
 	out.ElasticsearchRef = in.ElasticsearchRef
 	in.PodTemplate.DeepCopyInto(&out.PodTemplate)
+	in.NetworkPolicy.DeepCopyInto(&out.NetworkPolicy)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnterpriseSearchSpec.
@@ -123,7 +124,7 @@ func (in *EnterpriseSearchSpec) DeepCopy() *EnterpriseSearchSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EnterpriseSearchStatus) DeepCopyInto(out *EnterpriseSearchStatus) {
 	*out = *in
-	out.ReconcilerStatus = in.ReconcilerStatus
+	in.ReconcilerStatus.DeepCopyInto(&out.ReconcilerStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnterpriseSearchStatus.
@@ -45,6 +45,18 @@ type KibanaSpec struct {
 	// Can only be used if ECK is enforcing RBAC on references.
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Monitoring enables you to collect and ship log and monitoring data of this Kibana.
+	// See https://www.elastic.co/guide/en/kibana/current/xpack-monitoring.html.
+	// Metricbeat and Filebeat are deployed in the same Pod as sidecars and each one sends data to one or two different
+	// Elasticsearch monitoring clusters running in the same Kubernetes cluster.
+	// +kubebuilder:validation:Optional
+	Monitoring commonv1.Monitoring `json:"monitoring,omitempty"`
+
+	// NetworkPolicy controls the NetworkPolicy reconciled by the operator to allow the ingress traffic it knows about
+	// to the Kibana Pods.
+	// +kubebuilder:validation:Optional
+	NetworkPolicy commonv1.NetworkPolicyOptions `json:"networkPolicy,omitempty"`
 }
 
 // KibanaHealth expresses the status of the Kibana instances.
@@ -86,6 +98,11 @@ func (k *Kibana) ServiceAccountName() string {
 	return k.Spec.ServiceAccountName
 }
 
+// MonitoringSpec returns the references to the Elasticsearch clusters receiving the metrics and logs of Kibana.
+func (k *Kibana) MonitoringSpec() commonv1.Monitoring {
+	return k.Spec.Monitoring
+}
+
 func (k *Kibana) AssociationConf() *commonv1.AssociationConf {
 	return k.assocConf
 }
@@ -26,6 +26,7 @@ var (
 		checkNoUnknownFields,
 		checkNameLength,
 		checkSupportedVersion,
+		checkMonitoring,
 	}
 
 	updateChecks = []func(old, curr *Kibana) field.ErrorList{
@@ -101,6 +102,10 @@ func checkSupportedVersion(k *Kibana) field.ErrorList {
 	return commonv1.CheckSupportedStackVersion(k.Spec.Version, version.SupportedKibanaVersions)
 }
 
+func checkMonitoring(k *Kibana) field.ErrorList {
+	return commonv1.CheckMonitoring(k.Spec.Monitoring, k.Spec.Version)
+}
+
 func checkNoDowngrade(prev, curr *Kibana) field.ErrorList {
 	return commonv1.CheckNoDowngrade(prev.Spec.Version, curr.Spec.Version)
 }
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/test"
 	"github.com/stretchr/testify/require"
@@ -91,6 +92,43 @@ func TestWebhook(t *testing.T) {
 				`spec.version: Invalid value: "300.1.2": Unsupported version: version 300.1.2 is higher than the highest supported version`,
 			),
 		},
+		{
+			Name:      "monitoring-unsupported-version",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				k := mkKibana(uid)
+				k.Spec.Monitoring.Metrics.ElasticsearchRefs = []commonv1.ObjectSelector{{Name: "monitoring"}}
+				return serialize(t, k)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.monitoring: Invalid value: "7.6.1": Stack monitoring requires version 7.14.0 or above`,
+			),
+		},
+		{
+			Name:      "monitoring-too-many-refs",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				k := mkKibana(uid)
+				k.Spec.Version = "7.14.0"
+				k.Spec.Monitoring.Logs.ElasticsearchRefs = []commonv1.ObjectSelector{{Name: "monitoring"}, {Name: "other"}}
+				return serialize(t, k)
+			},
+			Check: test.ValidationWebhookFailed(
+				`spec.monitoring.logs.elasticsearchRefs: Too many: 2: must have at most 1 items`,
+			),
+		},
+		{
+			Name:      "monitoring-valid",
+			Operation: admissionv1beta1.Create,
+			Object: func(t *testing.T, uid string) []byte {
+				k := mkKibana(uid)
+				k.Spec.Version = "7.14.0"
+				k.Spec.Monitoring.Metrics.ElasticsearchRefs = []commonv1.ObjectSelector{{Name: "monitoring"}}
+				k.Spec.Monitoring.Logs.ElasticsearchRefs = []commonv1.ObjectSelector{{Name: "monitoring"}}
+				return serialize(t, k)
+			},
+			Check: test.ValidationWebhookSucceeded,
+		},
 		{
 			Name:      "update-valid",
 			Operation: admissionv1beta1.Update,
@@ -0,0 +1,480 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"reflect"
+	"sync/atomic"
+
+	"go.elastic.co/apm"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	agentname "github.com/elastic/cloud-on-k8s/pkg/controller/agent/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	controllerName = "agent-controller"
+)
+
+var (
+	log = logf.Log.WithName(controllerName)
+)
+
+// Add creates a new Agent Controller and adds it to the Manager with default RBAC.
+// The Manager will set fields on the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, params operator.Parameters) error {
+	reconciler := newReconciler(mgr, params)
+	c, err := common.NewController(mgr, controllerName, reconciler, params)
+	if err != nil {
+		return err
+	}
+	return addWatches(c, reconciler)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, params operator.Parameters) *ReconcileAgent {
+	client := k8s.WrapClient(mgr.GetClient())
+	return &ReconcileAgent{
+		Client:         client,
+		recorder:       mgr.GetEventRecorderFor(controllerName),
+		dynamicWatches: watches.NewDynamicWatches(),
+		Parameters:     params,
+	}
+}
+
+func podsToReconcileRequest(object handler.MapObject) []reconcile.Request {
+	labels := object.Meta.GetLabels()
+	agentName, isSet := labels[NameLabelName]
+	if !isSet {
+		return nil
+	}
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{
+				Namespace: object.Meta.GetNamespace(),
+				Name:      agentName,
+			},
+		},
+	}
+}
+
+func addWatches(c controller.Controller, r *ReconcileAgent) error {
+	// Watch for changes to Agent
+	err := c.Watch(&source.Kind{Type: &agentv1alpha1.Agent{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch DaemonSets
+	if err := c.Watch(&source.Kind{Type: &appsv1.DaemonSet{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &agentv1alpha1.Agent{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch Deployments
+	if err := c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &agentv1alpha1.Agent{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch Pods, to ensure `status.availableNodes` is kept up to date.
+	if err := c.Watch(&source.Kind{Type: &corev1.Pod{}},
+		&handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(podsToReconcileRequest),
+		}); err != nil {
+		return err
+	}
+
+	// Watch services
+	if err := c.Watch(&source.Kind{Type: &corev1.Service{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &agentv1alpha1.Agent{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch secrets
+	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &agentv1alpha1.Agent{},
+	}); err != nil {
+		return err
+	}
+
+	// Dynamically watch referenced secrets: CA certificates, secure settings and user-provided HTTP certificates
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, r.dynamicWatches.Secrets)
+}
+
+var _ reconcile.Reconciler = &ReconcileAgent{}
+
+// ReconcileAgent reconciles an Agent object
+type ReconcileAgent struct {
+	k8s.Client
+	recorder       record.EventRecorder
+	dynamicWatches watches.DynamicWatches
+	operator.Parameters
+	// iteration is the number of times this controller has run its Reconcile method
+	iteration uint64
+}
+
+func (r *ReconcileAgent) K8sClient() k8s.Client {
+	return r.Client
+}
+
+func (r *ReconcileAgent) DynamicWatches() watches.DynamicWatches {
+	return r.dynamicWatches
+}
+
+func (r *ReconcileAgent) Recorder() record.EventRecorder {
+	return r.recorder
+}
+
+// Reconcile reads that state of the cluster for an Agent object and makes changes based on the state read
+// and what is in the Agent.Spec.
+func (r *ReconcileAgent) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	defer common.LogReconciliationRun(log, request, "agent_name", &r.iteration)()
+	tx, ctx := tracing.NewTransaction(r.Tracer, request.NamespacedName, "agent")
+	defer tracing.EndTransaction(tx)
+
+	var agent agentv1alpha1.Agent
+	if err := association.FetchWithAssociation(ctx, r.Client, request, &agent); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.onDelete(request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if err := LoadAssociationConfs(&agent); err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if common.IsUnmanaged(agent.ObjectMeta) {
+		log.Info("Object is currently not managed by this controller. Skipping reconciliation", "namespace", agent.Namespace, "agent_name", agent.Name)
+		return reconcile.Result{}, nil
+	}
+
+	if compatible, err := r.isCompatible(ctx, &agent); err != nil || !compatible {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if agent.IsMarkedForDeletion() {
+		// Agent will be deleted, clean up resources
+		r.onDelete(k8s.ExtractNamespacedName(&agent))
+		return reconcile.Result{}, nil
+	}
+
+	if err := annotation.UpdateControllerVersion(ctx, r.Client, &agent, r.OperatorInfo.BuildInfo.Version); err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if !association.IsConfiguredIfSet(&agent, r.recorder) || !r.areOtherAssociationsConfiguredIfSet(&agent) {
+		return reconcile.Result{}, nil
+	}
+
+	return r.doReconcile(ctx, request, agent)
+}
+
+// LoadAssociationConfs sets the Kibana and Fleet Server association configurations stored in the annotations
+// of the given Agent.
+func LoadAssociationConfs(agent *agentv1alpha1.Agent) error {
+	kbAssocConf, err := association.GetAssociationConfFromAnnotation(agent, annotation.KibanaAssociationConfAnnotation)
+	if err != nil {
+		return err
+	}
+	agent.SetKibanaAssociationConf(kbAssocConf)
+	fsAssocConf, err := association.GetAssociationConfFromAnnotation(agent, annotation.FleetServerAssociationConfAnnotation)
+	if err != nil {
+		return err
+	}
+	agent.SetFleetServerAssociationConf(fsAssocConf)
+	return nil
+}
+
+// areOtherAssociationsConfiguredIfSet checks if the Kibana and Fleet Server associations are set in the spec and if
+// they have been configured by the association controller.
+func (r *ReconcileAgent) areOtherAssociationsConfiguredIfSet(agent *agentv1alpha1.Agent) bool {
+	kbRef := agent.KibanaRef()
+	if (&kbRef).IsDefined() && !agent.KibanaAssociationConf().IsConfigured() {
+		r.recorder.Event(agent, corev1.EventTypeWarning, events.EventAssociationError, "Kibana backend is not configured")
+		log.Info("Kibana association not established: skipping Agent reconciliation",
+			"namespace", agent.Namespace,
+			"agent_name", agent.Name,
+		)
+		return false
+	}
+	fsRef := agent.FleetServerRef()
+	if (&fsRef).IsDefined() && !agent.FleetServerAssociationConf().URLIsConfigured() {
+		r.recorder.Event(agent, corev1.EventTypeWarning, events.EventAssociationError, "Fleet Server is not configured")
+		log.Info("Fleet Server association not established: skipping Agent reconciliation",
+			"namespace", agent.Namespace,
+			"agent_name", agent.Name,
+		)
+		return false
+	}
+	return true
+}
+
+func (r *ReconcileAgent) onDelete(obj types.NamespacedName) {
+	// Clean up watches
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(esCASecretWatchName(obj))
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(kbCASecretWatchName(obj))
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(fsCASecretWatchName(obj))
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(keystore.SecureSettingsWatchName(obj))
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(certificates.CertificateWatchKey(agentname.AgentNamer, obj.Name))
+	certificates.DeleteExpirationMetrics(&agentv1alpha1.Agent{}, obj)
+}
+
+func (r *ReconcileAgent) isCompatible(ctx context.Context, agent *agentv1alpha1.Agent) (bool, error) {
+	selector := map[string]string{NameLabelName: agent.Name}
+	compat, err := annotation.ReconcileCompatibility(ctx, r.Client, agent, selector, r.OperatorInfo.BuildInfo.Version)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, agent, events.EventCompatCheckError, "Error during compatibility check: %v", err)
+	}
+	return compat, err
+}
+
+func (r *ReconcileAgent) doReconcile(ctx context.Context, request reconcile.Request, agent agentv1alpha1.Agent) (reconcile.Result, error) {
+	// Run validation in case the webhook is disabled
+	if err := r.validate(ctx, &agent); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	state := NewState(request, &agent)
+
+	if err := r.watchCASecrets(agent); err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	results := r.reconcileFleetServerHTTP(ctx, agent)
+	if results.HasError() {
+		res, err := results.Aggregate()
+		k8s.EmitErrorEvent(r.recorder, err, &agent, events.EventReconciliationError, "Fleet Server HTTP reconciliation error: %v", err)
+		return res, err
+	}
+
+	configSecret, err := reconcileConfig(r.K8sClient(), agent)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, &agent, events.EventReconciliationError, "Configuration reconciliation error: %v", err)
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	keystoreResources, err := keystore.NewResources(
+		r,
+		&agent,
+		agentname.AgentNamer,
+		Labels(agent.Name),
+		initContainerParameters,
+	)
+	if err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	// build a hash of various inputs to rotate Pods on any change
+	configHash, err := buildConfigHash(r.K8sClient(), agent, configSecret, keystoreResources)
+	if err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	state, err = r.reconcileWorkload(ctx, state, agent, newPodTemplate(agent, configHash, keystoreResources))
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			log.V(1).Info("Conflict while updating workload")
+			return reconcile.Result{Requeue: true}, nil
+		}
+		k8s.EmitErrorEvent(r.recorder, err, &agent, events.EventReconciliationError, "Workload reconciliation error: %v", err)
+		return state.Result, tracing.CaptureError(ctx, err)
+	}
+
+	err = r.updateStatus(ctx, state)
+	if err != nil && apierrors.IsConflict(err) {
+		log.V(1).Info("Conflict while updating status", "namespace", agent.Namespace, "agent_name", agent.Name)
+		return reconcile.Result{Requeue: true}, nil
+	}
+	res, err := results.WithResult(state.Result).WithError(err).Aggregate()
+	k8s.EmitErrorEvent(r.recorder, err, &agent, events.EventReconciliationError, "Reconciliation error: %v", err)
+	return res, err
+}
+
+// reconcileFleetServerHTTP reconciles the Service and the HTTP certificates of the Fleet Server, if enabled.
+// The Service is deleted if the Fleet Server is not enabled.
+func (r *ReconcileAgent) reconcileFleetServerHTTP(ctx context.Context, agent agentv1alpha1.Agent) *reconciler.Results {
+	results := reconciler.NewResult(ctx)
+	if !agent.FleetServerEnabled() {
+		return results.WithError(deleteIfExists(r.K8sClient(), &corev1.Service{}, agent.Namespace, agentname.HTTPService(agent.Name)))
+	}
+
+	svc, err := common.ReconcileService(ctx, r.Client, NewFleetServerService(agent), &agent)
+	if err != nil {
+		return results.WithError(err)
+	}
+
+	_, certResults := certificates.Reconciler{
+		K8sClient:             r.K8sClient(),
+		DynamicWatches:        r.DynamicWatches(),
+		Object:                &agent,
+		TLSOptions:            agent.Spec.HTTP.TLS,
+		Namer:                 agentname.AgentNamer,
+		Labels:                Labels(agent.Name),
+		Services:              []corev1.Service{*svc},
+		CACertRotation:        r.CACertRotation,
+		CertRotation:          r.CertRotation,
+		CertManagerIssuer:     r.CertManagerIssuer,
+		Recorder:              r.recorder,
+		GarbageCollectSecrets: true,
+	}.ReconcileCAAndHTTPCerts(ctx)
+	return results.WithResults(certResults)
+}
+
+func (r *ReconcileAgent) validate(ctx context.Context, agent *agentv1alpha1.Agent) error {
+	span, vctx := apm.StartSpan(ctx, "validate", tracing.SpanTypeApp)
+	defer span.End()
+
+	if err := agent.ValidateCreate(); err != nil {
+		log.Error(err, "Validation failed")
+		k8s.EmitErrorEvent(r.recorder, err, agent, events.EventReasonValidation, err.Error())
+		return tracing.CaptureError(vctx, err)
+	}
+
+	return nil
+}
+
+func (r *ReconcileAgent) updateStatus(ctx context.Context, state State) error {
+	span, _ := apm.StartSpan(ctx, "update_status", tracing.SpanTypeApp)
+	defer span.End()
+
+	current := state.originalAgent
+	if reflect.DeepEqual(current.Status, state.Agent.Status) {
+		return nil
+	}
+	if state.Agent.Status.IsDegraded(current.Status) {
+		r.recorder.Event(current, corev1.EventTypeWarning, events.EventReasonUnhealthy, "Agent health degraded")
+	}
+	log.V(1).Info("Updating status",
+		"iteration", atomic.LoadUint64(&r.iteration),
+		"namespace", state.Agent.Namespace,
+		"agent_name", state.Agent.Name,
+		"status", state.Agent.Status,
+	)
+	return common.UpdateStatus(r.Client, state.Agent)
+}
+
+func buildConfigHash(c k8s.Client, agent agentv1alpha1.Agent, configSecret *corev1.Secret, keystoreResources *keystore.Resources) (string, error) {
+	// build a hash of various settings to rotate the Pod on any change
+	configHash := sha256.New224()
+
+	// - in the Elastic Agent configuration file content
+	if configSecret != nil {
+		_, _ = configHash.Write(configSecret.Data[ConfigFileName])
+	}
+
+	// - in the secure settings
+	if keystoreResources != nil {
+		_, _ = configHash.Write([]byte(keystoreResources.Version))
+	}
+
+	// - in the Elasticsearch, Kibana and Fleet Server TLS certificates
+	for _, assocConf := range []*commonv1.AssociationConf{
+		agent.AssociationConf(),
+		agent.KibanaAssociationConf(),
+		agent.FleetServerAssociationConf(),
+	} {
+		if !assocConf.CAIsConfigured() {
+			continue
+		}
+		if err := writeCertHash(c, configHash, types.NamespacedName{Namespace: agent.Namespace, Name: assocConf.GetCASecretName()}); err != nil {
+			return "", err
+		}
+	}
+
+	// - in the Fleet Server HTTP certificate
+	if agent.FleetServerEnabled() && agent.Spec.HTTP.TLS.Enabled() {
+		key := types.NamespacedName{Namespace: agent.Namespace, Name: certificates.InternalCertsSecretName(agentname.AgentNamer, agent.Name)}
+		if err := writeCertHash(c, configHash, key); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%x", configHash.Sum(nil)), nil
+}
+
+// writeCertHash writes the certificate stored in the given Secret to the given hash.
+func writeCertHash(c k8s.Client, configHash hash.Hash, key types.NamespacedName) error {
+	var secret corev1.Secret
+	if err := c.Get(key, &secret); err != nil {
+		return err
+	}
+	if certPem, ok := secret.Data[certificates.CertFileName]; ok {
+		_, _ = configHash.Write(certPem)
+	}
+	return nil
+}
+
+func esCASecretWatchName(agent types.NamespacedName) string {
+	return fmt.Sprintf("%s-%s-es-ca-secret", agent.Namespace, agent.Name)
+}
+
+func kbCASecretWatchName(agent types.NamespacedName) string {
+	return fmt.Sprintf("%s-%s-kb-ca-secret", agent.Namespace, agent.Name)
+}
+
+func fsCASecretWatchName(agent types.NamespacedName) string {
+	return fmt.Sprintf("%s-%s-fs-ca-secret", agent.Namespace, agent.Name)
+}
+
+// watchCASecrets sets up dynamic watches for the Secrets containing the associated Elasticsearch, Kibana and
+// Fleet Server CA certs.
+func (r *ReconcileAgent) watchCASecrets(agent agentv1alpha1.Agent) error {
+	agentKey := k8s.ExtractNamespacedName(&agent)
+	for watchName, assocConf := range map[string]*commonv1.AssociationConf{
+		esCASecretWatchName(agentKey): agent.AssociationConf(),
+		kbCASecretWatchName(agentKey): agent.KibanaAssociationConf(),
+		fsCASecretWatchName(agentKey): agent.FleetServerAssociationConf(),
+	} {
+		if !assocConf.CAIsConfigured() {
+			r.dynamicWatches.Secrets.RemoveHandlerForKey(watchName)
+			continue
+		}
+		if err := r.dynamicWatches.Secrets.AddHandler(watches.NamedWatch{
+			Name:    watchName,
+			Watched: []types.NamespacedName{{Namespace: agent.Namespace, Name: assocConf.GetCASecretName()}},
+			Watcher: agentKey,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	agentname "github.com/elastic/cloud-on-k8s/pkg/controller/agent/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// ConfigFileName is the name of the Elastic Agent configuration file.
+	ConfigFileName = "agent.yml"
+	// ConfigMountPath is the directory where the Elastic Agent configuration file is mounted.
+	ConfigMountPath = "/etc/agent"
+	// ESCertsPath is the directory where the Elasticsearch CA certificates are mounted.
+	ESCertsPath = "/mnt/elastic-internal/elasticsearch-certs"
+	// KibanaCertsPath is the directory where the Kibana CA certificates are mounted.
+	KibanaCertsPath = "/mnt/elastic-internal/kibana-certs"
+	// FleetServerCertsPath is the directory where the CA certificates of the referenced Fleet Server are mounted.
+	FleetServerCertsPath = "/mnt/elastic-internal/fleetserver-certs"
+)
+
+// buildOutputConfig returns the configuration for the default Elasticsearch output, if an association is configured.
+func buildOutputConfig(client k8s.Client, agent agentv1alpha1.Agent) (*settings.CanonicalConfig, error) {
+	if !agent.AssociationConf().IsConfigured() {
+		return settings.NewCanonicalConfig(), nil
+	}
+	username, password, err := association.ElasticsearchAuthSettings(client, &agent)
+	if err != nil {
+		return nil, err
+	}
+	output := map[string]interface{}{
+		"type":     "elasticsearch",
+		"hosts":    []string{agent.AssociationConf().GetURL()},
+		"username": username,
+		"password": password,
+	}
+	if agent.AssociationConf().GetCACertProvided() {
+		output["ssl.certificate_authorities"] = []string{filepath.Join(ESCertsPath, certificates.CAFileName)}
+	}
+	return settings.NewCanonicalConfigFrom(map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"default": output,
+		},
+	})
+}
+
+// buildAgentConfig builds the configuration of an Elastic Agent running in standalone mode, merging the
+// user-provided configuration on top of the output settings derived from the Elasticsearch association.
+func buildAgentConfig(client k8s.Client, agent agentv1alpha1.Agent) ([]byte, error) {
+	outputCfg, err := buildOutputConfig(client, agent)
+	if err != nil {
+		return nil, err
+	}
+
+	specConfig := agent.Spec.Config
+	if specConfig == nil {
+		specConfig = &commonv1.Config{}
+	}
+	userCfg, err := settings.NewCanonicalConfigFrom(specConfig.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := settings.NewCanonicalConfig()
+	// merge the user configuration last so it takes precedence
+	if err := cfg.MergeWith(outputCfg, userCfg); err != nil {
+		return nil, err
+	}
+	return cfg.Render()
+}
+
+// reconcileConfig builds the Elastic Agent configuration and reconciles the Secret holding it.
+// In Fleet mode, the configuration is managed by Fleet: any leftover Secret is deleted and nil is returned.
+func reconcileConfig(client k8s.Client, agent agentv1alpha1.Agent) (*corev1.Secret, error) {
+	if agent.FleetModeEnabled() {
+		return nil, deleteIfExists(client, &corev1.Secret{}, agent.Namespace, agentname.ConfigSecret(agent.Name))
+	}
+
+	cfgBytes, err := buildAgentConfig(client, agent)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: agent.Namespace,
+			Name:      agentname.ConfigSecret(agent.Name),
+			Labels:    Labels(agent.Name),
+		},
+		Data: map[string][]byte{
+			ConfigFileName: cfgBytes,
+		},
+	}
+	reconciled, err := reconciler.ReconcileSecret(client, expected, &agent)
+	if err != nil {
+		return nil, err
+	}
+	return &reconciled, nil
+}
@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func testAgent() agentv1alpha1.Agent {
+	cfg := commonv1.NewConfig(map[string]interface{}{"inputs": []interface{}{}})
+	return agentv1alpha1.Agent{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "agent",
+		},
+		Spec: agentv1alpha1.AgentSpec{
+			Version:   "7.13.0",
+			Config:    &cfg,
+			DaemonSet: &agentv1alpha1.DaemonSetSpec{},
+		},
+	}
+}
+
+func authSecret(name, key, password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+		},
+		Data: map[string][]byte{
+			key: []byte(password),
+		},
+	}
+}
+
+func withES(a agentv1alpha1.Agent) agentv1alpha1.Agent {
+	a.SetAssociationConf(&commonv1.AssociationConf{
+		AuthSecretName: "agent-es-user",
+		AuthSecretKey:  "ns-agent-es-user",
+		CACertProvided: true,
+		CASecretName:   "agent-es-ca",
+		URL:            "https://es-es-http.ns.svc:9200",
+	})
+	return a
+}
+
+func Test_buildAgentConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		agent   agentv1alpha1.Agent
+		objs    []runtime.Object
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:  "no association",
+			agent: testAgent(),
+			want: map[string]interface{}{
+				"inputs": []interface{}{},
+			},
+		},
+		{
+			name:  "elasticsearch association",
+			agent: withES(testAgent()),
+			objs:  []runtime.Object{authSecret("agent-es-user", "ns-agent-es-user", "es-password")},
+			want: map[string]interface{}{
+				"inputs":                                      []interface{}{},
+				"outputs.default.type":                        "elasticsearch",
+				"outputs.default.hosts":                       []string{"https://es-es-http.ns.svc:9200"},
+				"outputs.default.username":                    "ns-agent-es-user",
+				"outputs.default.password":                    "es-password",
+				"outputs.default.ssl.certificate_authorities": []string{"/mnt/elastic-internal/elasticsearch-certs/ca.crt"},
+			},
+		},
+		{
+			name: "user configuration takes precedence",
+			agent: func() agentv1alpha1.Agent {
+				a := withES(testAgent())
+				cfg := commonv1.NewConfig(map[string]interface{}{"outputs.default.username": "custom"})
+				a.Spec.Config = &cfg
+				return a
+			}(),
+			objs: []runtime.Object{authSecret("agent-es-user", "ns-agent-es-user", "es-password")},
+			want: map[string]interface{}{
+				"outputs.default.type":                        "elasticsearch",
+				"outputs.default.hosts":                       []string{"https://es-es-http.ns.svc:9200"},
+				"outputs.default.username":                    "custom",
+				"outputs.default.password":                    "es-password",
+				"outputs.default.ssl.certificate_authorities": []string{"/mnt/elastic-internal/elasticsearch-certs/ca.crt"},
+			},
+		},
+		{
+			name:    "missing auth secret",
+			agent:   withES(testAgent()),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildAgentConfig(k8s.WrappedFakeClient(tt.objs...), tt.agent)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			gotCfg, err := settings.ParseConfig(got)
+			require.NoError(t, err)
+			wantCfg, err := settings.NewCanonicalConfigFrom(tt.want)
+			require.NoError(t, err)
+			require.Empty(t, gotCfg.Diff(wantCfg, nil))
+		})
+	}
+}
+
+func Test_reconcileConfig_FleetMode(t *testing.T) {
+	a := testAgent()
+	a.Spec.Mode = agentv1alpha1.AgentFleetMode
+	a.Spec.Config = nil
+	leftover := authSecret("agent-agent-config", "agent.yml", "")
+	c := k8s.WrappedFakeClient(leftover)
+
+	secret, err := reconcileConfig(c, a)
+	require.NoError(t, err)
+	require.Nil(t, secret)
+
+	var s corev1.Secret
+	err = c.Get(k8s.ExtractNamespacedName(leftover), &s)
+	require.Error(t, err)
+}
@@ -0,0 +1,145 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	"path/filepath"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	agentname "github.com/elastic/cloud-on-k8s/pkg/controller/agent/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+const (
+	// FleetServerPort is the port the Fleet Server listens on.
+	FleetServerPort = 8220
+
+	// environment variables read by the Elastic Agent container to enroll into Fleet
+	fleetEnrollEnv         = "FLEET_ENROLL"
+	fleetURLEnv            = "FLEET_URL"
+	fleetCAEnv             = "FLEET_CA"
+	kibanaFleetHostEnv     = "KIBANA_FLEET_HOST"
+	kibanaFleetUsernameEnv = "KIBANA_FLEET_USERNAME"
+	kibanaFleetPasswordEnv = "KIBANA_FLEET_PASSWORD" // nolint
+	kibanaFleetCAEnv       = "KIBANA_FLEET_CA"
+	kibanaFleetSetupEnv    = "KIBANA_FLEET_SETUP"
+
+	// environment variables read by the Elastic Agent container to run a Fleet Server
+	fleetServerEnableEnv       = "FLEET_SERVER_ENABLE"
+	fleetServerESHostEnv       = "FLEET_SERVER_ELASTICSEARCH_HOST"
+	fleetServerESUsernameEnv   = "FLEET_SERVER_ELASTICSEARCH_USERNAME"
+	fleetServerESPasswordEnv   = "FLEET_SERVER_ELASTICSEARCH_PASSWORD" // nolint
+	fleetServerESCAEnv         = "FLEET_SERVER_ELASTICSEARCH_CA"
+	fleetServerCertEnv         = "FLEET_SERVER_CERT"
+	fleetServerCertKeyEnv      = "FLEET_SERVER_CERT_KEY"
+	fleetServerInsecureHTTPEnv = "FLEET_SERVER_INSECURE_HTTP"
+)
+
+// FleetServerURL returns the URL used to reach the Fleet Server run by the given Elastic Agent.
+func FleetServerURL(agent agentv1alpha1.Agent) string {
+	return stringsutil.Concat(agent.Spec.HTTP.Protocol(), "://", agentname.HTTPService(agent.Name), ".", agent.Namespace, ".svc:", strconv.Itoa(FleetServerPort))
+}
+
+// NewFleetServerService returns the Service exposing the Fleet Server run by the given Elastic Agent.
+func NewFleetServerService(agent agentv1alpha1.Agent) *corev1.Service {
+	svc := corev1.Service{
+		ObjectMeta: agent.Spec.HTTP.Service.ObjectMeta,
+		Spec:       agent.Spec.HTTP.Service.Spec,
+	}
+
+	svc.ObjectMeta.Namespace = agent.Namespace
+	svc.ObjectMeta.Name = agentname.HTTPService(agent.Name)
+
+	labels := Labels(agent.Name)
+	ports := []corev1.ServicePort{
+		{
+			Name:     agent.Spec.HTTP.Protocol(),
+			Protocol: corev1.ProtocolTCP,
+			Port:     FleetServerPort,
+		},
+	}
+
+	return defaults.SetServiceDefaults(&svc, labels, labels, ports)
+}
+
+// fleetModeEnv returns the environment variables used by the Elastic Agent container to set up Fleet, enroll into it,
+// and optionally run a Fleet Server.
+func fleetModeEnv(agent agentv1alpha1.Agent) []corev1.EnvVar {
+	if !agent.FleetModeEnabled() {
+		return nil
+	}
+
+	env := []corev1.EnvVar{{Name: fleetEnrollEnv, Value: "true"}}
+
+	// Kibana is used to retrieve the enrollment token, and to set up Fleet if this agent runs the Fleet Server
+	if kbConf := agent.KibanaAssociationConf(); kbConf.IsConfigured() {
+		env = append(env,
+			corev1.EnvVar{Name: kibanaFleetHostEnv, Value: kbConf.GetURL()},
+			corev1.EnvVar{Name: kibanaFleetUsernameEnv, Value: kbConf.GetAuthSecretKey()},
+			authSecretEnvVar(kibanaFleetPasswordEnv, kbConf),
+		)
+		if kbConf.GetCACertProvided() {
+			env = append(env, corev1.EnvVar{Name: kibanaFleetCAEnv, Value: filepath.Join(KibanaCertsPath, certificates.CAFileName)})
+		}
+	}
+
+	if !agent.FleetServerEnabled() {
+		// enroll into the referenced Fleet Server
+		if fsConf := agent.FleetServerAssociationConf(); fsConf.URLIsConfigured() {
+			env = append(env, corev1.EnvVar{Name: fleetURLEnv, Value: fsConf.GetURL()})
+			if fsConf.GetCACertProvided() {
+				env = append(env, corev1.EnvVar{Name: fleetCAEnv, Value: filepath.Join(FleetServerCertsPath, certificates.CAFileName)})
+			}
+		}
+		return env
+	}
+
+	// run the Fleet Server, and enroll into it
+	env = append(env,
+		corev1.EnvVar{Name: fleetServerEnableEnv, Value: "true"},
+		corev1.EnvVar{Name: kibanaFleetSetupEnv, Value: "true"},
+		corev1.EnvVar{Name: fleetURLEnv, Value: FleetServerURL(agent)},
+	)
+	if esConf := agent.AssociationConf(); esConf.IsConfigured() {
+		env = append(env,
+			corev1.EnvVar{Name: fleetServerESHostEnv, Value: esConf.GetURL()},
+			corev1.EnvVar{Name: fleetServerESUsernameEnv, Value: esConf.GetAuthSecretKey()},
+			authSecretEnvVar(fleetServerESPasswordEnv, esConf),
+		)
+		if esConf.GetCACertProvided() {
+			env = append(env, corev1.EnvVar{Name: fleetServerESCAEnv, Value: filepath.Join(ESCertsPath, certificates.CAFileName)})
+		}
+	}
+	if agent.Spec.HTTP.TLS.Enabled() {
+		env = append(env,
+			corev1.EnvVar{Name: fleetServerCertEnv, Value: filepath.Join(certificates.HTTPCertificatesSecretVolumeMountPath, certificates.CertFileName)},
+			corev1.EnvVar{Name: fleetServerCertKeyEnv, Value: filepath.Join(certificates.HTTPCertificatesSecretVolumeMountPath, certificates.KeyFileName)},
+			corev1.EnvVar{Name: fleetCAEnv, Value: filepath.Join(certificates.HTTPCertificatesSecretVolumeMountPath, certificates.CAFileName)},
+		)
+	} else {
+		env = append(env, corev1.EnvVar{Name: fleetServerInsecureHTTPEnv, Value: "true"})
+	}
+	return env
+}
+
+// authSecretEnvVar returns an environment variable referencing the password stored in the auth secret of the
+// given association.
+func authSecretEnvVar(name string, assocConf *commonv1.AssociationConf) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: assocConf.GetAuthSecretName()},
+				Key:                  assocConf.GetAuthSecretKey(),
+			},
+		},
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+)
+
+func fleetAgent(fleetServerEnabled bool) agentv1alpha1.Agent {
+	a := testAgent()
+	a.Spec.Mode = agentv1alpha1.AgentFleetMode
+	a.Spec.Config = nil
+	a.Spec.FleetServerEnabled = fleetServerEnabled
+	a.SetKibanaAssociationConf(&commonv1.AssociationConf{
+		AuthSecretName: "agent-kb-user",
+		AuthSecretKey:  "ns-agent-kb-user",
+		CACertProvided: true,
+		CASecretName:   "agent-kb-ca",
+		URL:            "https://kb-kb-http.ns.svc:5601",
+	})
+	return a
+}
+
+// envValues returns the plain values of the given environment variables, indexed by name.
+func envValues(env []corev1.EnvVar) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, e := range env {
+		values[e.Name] = e.Value
+	}
+	return values
+}
+
+func Test_FleetServerURL(t *testing.T) {
+	a := fleetAgent(true)
+	require.Equal(t, "https://agent-agent-http.ns.svc:8220", FleetServerURL(a))
+	a.Spec.HTTP.TLS.SelfSignedCertificate = &commonv1.SelfSignedCertificate{Disabled: true}
+	require.Equal(t, "http://agent-agent-http.ns.svc:8220", FleetServerURL(a))
+}
+
+func Test_fleetModeEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent agentv1alpha1.Agent
+		want  map[string]string
+	}{
+		{
+			name:  "standalone mode",
+			agent: testAgent(),
+			want:  map[string]string{},
+		},
+		{
+			name:  "fleet server with TLS",
+			agent: withES(fleetAgent(true)),
+			want: map[string]string{
+				"FLEET_ENROLL":                        "true",
+				"KIBANA_FLEET_HOST":                   "https://kb-kb-http.ns.svc:5601",
+				"KIBANA_FLEET_USERNAME":               "ns-agent-kb-user",
+				"KIBANA_FLEET_PASSWORD":               "",
+				"KIBANA_FLEET_CA":                     "/mnt/elastic-internal/kibana-certs/ca.crt",
+				"FLEET_SERVER_ENABLE":                 "true",
+				"KIBANA_FLEET_SETUP":                  "true",
+				"FLEET_URL":                           "https://agent-agent-http.ns.svc:8220",
+				"FLEET_SERVER_ELASTICSEARCH_HOST":     "https://es-es-http.ns.svc:9200",
+				"FLEET_SERVER_ELASTICSEARCH_USERNAME": "ns-agent-es-user",
+				"FLEET_SERVER_ELASTICSEARCH_PASSWORD": "",
+				"FLEET_SERVER_ELASTICSEARCH_CA":       "/mnt/elastic-internal/elasticsearch-certs/ca.crt",
+				"FLEET_SERVER_CERT":                   "/mnt/elastic-internal/http-certs/tls.crt",
+				"FLEET_SERVER_CERT_KEY":               "/mnt/elastic-internal/http-certs/tls.key",
+				"FLEET_CA":                            "/mnt/elastic-internal/http-certs/ca.crt",
+			},
+		},
+		{
+			name: "fleet server without TLS",
+			agent: func() agentv1alpha1.Agent {
+				a := fleetAgent(true)
+				a.Spec.HTTP.TLS.SelfSignedCertificate = &commonv1.SelfSignedCertificate{Disabled: true}
+				return a
+			}(),
+			want: map[string]string{
+				"FLEET_ENROLL":               "true",
+				"KIBANA_FLEET_HOST":          "https://kb-kb-http.ns.svc:5601",
+				"KIBANA_FLEET_USERNAME":      "ns-agent-kb-user",
+				"KIBANA_FLEET_PASSWORD":      "",
+				"KIBANA_FLEET_CA":            "/mnt/elastic-internal/kibana-certs/ca.crt",
+				"FLEET_SERVER_ENABLE":        "true",
+				"KIBANA_FLEET_SETUP":         "true",
+				"FLEET_URL":                  "http://agent-agent-http.ns.svc:8220",
+				"FLEET_SERVER_INSECURE_HTTP": "true",
+			},
+		},
+		{
+			name: "agent enrolling into a fleet server",
+			agent: func() agentv1alpha1.Agent {
+				a := fleetAgent(false)
+				a.SetFleetServerAssociationConf(&commonv1.AssociationConf{
+					CACertProvided: true,
+					CASecretName:   "agent-fs-ca",
+					URL:            "https://fleet-server-agent-http.ns.svc:8220",
+				})
+				return a
+			}(),
+			want: map[string]string{
+				"FLEET_ENROLL":          "true",
+				"KIBANA_FLEET_HOST":     "https://kb-kb-http.ns.svc:5601",
+				"KIBANA_FLEET_USERNAME": "ns-agent-kb-user",
+				"KIBANA_FLEET_PASSWORD": "",
+				"KIBANA_FLEET_CA":       "/mnt/elastic-internal/kibana-certs/ca.crt",
+				"FLEET_URL":             "https://fleet-server-agent-http.ns.svc:8220",
+				"FLEET_CA":              "/mnt/elastic-internal/fleetserver-certs/ca.crt",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, envValues(fleetModeEnv(tt.agent)))
+		})
+	}
+}
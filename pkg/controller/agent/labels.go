@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+)
+
+const (
+	// Type represents the Elastic Agent type.
+	Type = "agent"
+	// NameLabelName is used to represent an Elastic Agent in k8s resources.
+	NameLabelName = "agent.k8s.elastic.co/name"
+	// VersionLabelName is a label used to track the version of an Elastic Agent Pod.
+	VersionLabelName = "agent.k8s.elastic.co/version"
+)
+
+// Labels returns labels that identify the given Elastic Agent resource.
+func Labels(agentName string) map[string]string {
+	return map[string]string{
+		NameLabelName:        agentName,
+		common.TypeLabelName: Type,
+	}
+}
+
+// VersionLabels returns labels identifying the version of the given Elastic Agent.
+func VersionLabels(agent agentv1alpha1.Agent) map[string]string {
+	return map[string]string{
+		VersionLabelName: agent.Spec.Version,
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package name
+
+import (
+	common_name "github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+)
+
+const (
+	configSuffix      = "config"
+	httpServiceSuffix = "http"
+)
+
+// AgentNamer is a Namer that is configured with the defaults for resources related to an Elastic Agent resource.
+var AgentNamer = common_name.NewNamer("agent")
+
+// Workload returns the name of the DaemonSet or Deployment running the Elastic Agent pods.
+func Workload(agentName string) string {
+	return AgentNamer.Suffix(agentName)
+}
+
+// ConfigSecret returns the name of the Secret holding the Elastic Agent configuration.
+func ConfigSecret(agentName string) string {
+	return AgentNamer.Suffix(agentName, configSuffix)
+}
+
+// HTTPService returns the name of the Service exposing the Fleet Server.
+func HTTPService(agentName string) string {
+	return AgentNamer.Suffix(agentName, httpServiceSuffix)
+}
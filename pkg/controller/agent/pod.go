@@ -0,0 +1,133 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	agentname "github.com/elastic/cloud-on-k8s/pkg/controller/agent/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/container"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
+)
+
+const (
+	// ContainerName is the name of the main container running the Elastic Agent.
+	ContainerName = "agent"
+	// ConfigHashAnnotationName is an annotation used to rotate the Elastic Agent pods on configuration changes.
+	ConfigHashAnnotationName = "agent.k8s.elastic.co/config-hash"
+
+	// BinPath is the path of the Elastic Agent binary in the Docker image.
+	BinPath = "/usr/share/elastic-agent/elastic-agent"
+	// DataVolumePath is the path of the volume holding the Elastic Agent keystore.
+	DataVolumePath = "/usr/share/elastic-agent/state"
+
+	configVolumeName   = "config"
+	esCertsVolumeName  = "es-certs"
+	kbCertsVolumeName  = "kibana-certs"
+	fsCertsVolumeName  = "fleetserver-certs"
+	nodeNameEnvVarName = "NODE_NAME"
+)
+
+var (
+	defaultResources = corev1.ResourceRequirements{
+		Requests: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceMemory: resource.MustParse("350Mi"),
+			corev1.ResourceCPU:    resource.MustParse("200m"),
+		},
+		Limits: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceMemory: resource.MustParse("350Mi"),
+			corev1.ResourceCPU:    resource.MustParse("200m"),
+		},
+	}
+
+	initContainerParameters = keystore.InitContainerParameters{
+		KeystoreCreateCommand:         BinPath + " keystore create --force",
+		KeystoreAddCommand:            BinPath + ` keystore add "$key" --stdin < "$filename"`,
+		SecureSettingsVolumeMountPath: keystore.SecureSettingsVolumeMountPath,
+		DataVolumePath:                DataVolumePath,
+	}
+)
+
+// userPodTemplate returns the pod template provided by the user, depending on the Elastic Agent deployment mode.
+func userPodTemplate(agent agentv1alpha1.Agent) corev1.PodTemplateSpec {
+	switch {
+	case agent.Spec.DaemonSet != nil:
+		return agent.Spec.DaemonSet.PodTemplate
+	case agent.Spec.Deployment != nil:
+		return agent.Spec.Deployment.PodTemplate
+	default:
+		return corev1.PodTemplateSpec{}
+	}
+}
+
+func newPodTemplate(agent agentv1alpha1.Agent, configHash string, keystoreResources *keystore.Resources) corev1.PodTemplateSpec {
+	builder := defaults.NewPodTemplateBuilder(userPodTemplate(agent), ContainerName).
+		WithResources(defaultResources).
+		WithDockerImage(agent.Spec.Image, container.ImageRepository(container.AgentImage, agent.Spec.Version)).
+		WithEnv(corev1.EnvVar{
+			Name: nodeNameEnvVarName,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "spec.nodeName"},
+			},
+		}).
+		WithLabels(maps.Merge(Labels(agent.Name), VersionLabels(agent))).
+		// ensure the Pods get rotated on config change
+		WithAnnotations(map[string]string{ConfigHashAnnotationName: configHash})
+
+	if agent.FleetModeEnabled() {
+		builder = builder.WithEnv(fleetModeEnv(agent)...)
+	} else {
+		cfgVolume := volume.NewSecretVolumeWithMountPath(agentname.ConfigSecret(agent.Name), configVolumeName, ConfigMountPath)
+		builder = builder.
+			WithArgs("-c", filepath.Join(ConfigMountPath, ConfigFileName), "-e").
+			WithVolumes(cfgVolume.Volume()).
+			WithVolumeMounts(cfgVolume.VolumeMount())
+	}
+
+	if agent.FleetServerEnabled() {
+		builder = builder.WithPorts([]corev1.ContainerPort{
+			{Name: agent.Spec.HTTP.Protocol(), ContainerPort: int32(FleetServerPort), Protocol: corev1.ProtocolTCP},
+		})
+		if agent.Spec.HTTP.TLS.Enabled() {
+			httpCertsVolume := certificates.HTTPCertSecretVolume(agentname.AgentNamer, agent.Name)
+			builder = builder.WithVolumes(httpCertsVolume.Volume()).WithVolumeMounts(httpCertsVolume.VolumeMount())
+		}
+	}
+
+	for _, caVolume := range []struct {
+		secretName string
+		name       string
+		mountPath  string
+	}{
+		{secretName: agent.AssociationConf().GetCASecretName(), name: esCertsVolumeName, mountPath: ESCertsPath},
+		{secretName: agent.KibanaAssociationConf().GetCASecretName(), name: kbCertsVolumeName, mountPath: KibanaCertsPath},
+		{secretName: agent.FleetServerAssociationConf().GetCASecretName(), name: fsCertsVolumeName, mountPath: FleetServerCertsPath},
+	} {
+		if caVolume.secretName == "" {
+			continue
+		}
+		v := volume.NewSecretVolumeWithMountPath(caVolume.secretName, caVolume.name, caVolume.mountPath)
+		builder = builder.WithVolumes(v.Volume()).WithVolumeMounts(v.VolumeMount())
+	}
+
+	if keystoreResources != nil {
+		dataVolume := keystore.DataVolume(strings.ToLower(agent.Kind), DataVolumePath)
+		builder = builder.WithInitContainers(keystoreResources.InitContainer).
+			WithVolumes(keystoreResources.Volume, dataVolume.Volume()).
+			WithVolumeMounts(dataVolume.VolumeMount()).
+			WithInitContainerDefaults()
+	}
+
+	return builder.PodTemplate
+}
@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+)
+
+// State holds the accumulated state during the reconcile loop including the response and a pointer to an Elastic Agent
+// resource for status updates.
+type State struct {
+	Agent   *agentv1alpha1.Agent
+	Result  reconcile.Result
+	Request reconcile.Request
+
+	originalAgent *agentv1alpha1.Agent
+}
+
+// NewState creates a new reconcile state based on the given request and Elastic Agent resource with the resource
+// state reset to empty.
+func NewState(request reconcile.Request, agent *agentv1alpha1.Agent) State {
+	return State{Request: request, Agent: agent, originalAgent: agent.DeepCopy()}
+}
+
+// UpdateAgentState updates the Elastic Agent status based on the number of available and expected pods.
+func (s State) UpdateAgentState(available, expected int32) {
+	s.Agent.Status.AvailableNodes = available
+	s.Agent.Status.ExpectedNodes = expected
+	s.Agent.Status.Health = calculateHealth(available, expected)
+}
+
+func calculateHealth(available, expected int32) agentv1alpha1.AgentHealth {
+	switch {
+	case available == 0:
+		return agentv1alpha1.AgentRedHealth
+	case available < expected:
+		return agentv1alpha1.AgentYellowHealth
+	default:
+		return agentv1alpha1.AgentGreenHealth
+	}
+}
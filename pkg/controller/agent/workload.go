@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agent
+
+import (
+	"context"
+
+	"go.elastic.co/apm"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	agentname "github.com/elastic/cloud-on-k8s/pkg/controller/agent/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/daemonset"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/deployment"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const defaultReplicas int32 = 1
+
+// reconcileWorkload reconciles the DaemonSet or the Deployment running the Elastic Agent pods, and deletes the other kind
+// of workload if the deployment mode was changed.
+func (r *ReconcileAgent) reconcileWorkload(ctx context.Context, state State, agent agentv1alpha1.Agent, podTemplate corev1.PodTemplateSpec) (State, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_workload", tracing.SpanTypeApp)
+	defer span.End()
+
+	name := agentname.Workload(agent.Name)
+	labels := Labels(agent.Name)
+
+	if agent.Spec.DaemonSet != nil {
+		if err := deleteIfExists(r.K8sClient(), &appsv1.Deployment{}, agent.Namespace, name); err != nil {
+			return state, err
+		}
+		ds := daemonset.New(daemonset.Params{
+			Name:            name,
+			Namespace:       agent.Namespace,
+			Selector:        labels,
+			Labels:          labels,
+			PodTemplateSpec: podTemplate,
+			UpdateStrategy:  agent.Spec.DaemonSet.UpdateStrategy,
+		})
+		reconciled, err := daemonset.Reconcile(r.K8sClient(), ds, &agent)
+		if err != nil {
+			return state, err
+		}
+		state.UpdateAgentState(reconciled.Status.NumberAvailable, reconciled.Status.DesiredNumberScheduled)
+		return state, nil
+	}
+
+	if err := deleteIfExists(r.K8sClient(), &appsv1.DaemonSet{}, agent.Namespace, name); err != nil {
+		return state, err
+	}
+	replicas := defaultReplicas
+	if agent.Spec.Deployment.Replicas != nil {
+		replicas = *agent.Spec.Deployment.Replicas
+	}
+	strategy := agent.Spec.Deployment.Strategy.Type
+	if strategy == "" {
+		strategy = appsv1.RollingUpdateDeploymentStrategyType
+	}
+	deploy := deployment.New(deployment.Params{
+		Name:            name,
+		Namespace:       agent.Namespace,
+		Selector:        labels,
+		Labels:          labels,
+		PodTemplateSpec: podTemplate,
+		Replicas:        replicas,
+		Strategy:        strategy,
+	})
+	reconciled, err := deployment.Reconcile(r.K8sClient(), deploy, &agent)
+	if err != nil {
+		return state, err
+	}
+	state.UpdateAgentState(reconciled.Status.AvailableReplicas, replicas)
+	return state, nil
+}
+
+// deleteIfExists deletes the resource with the given name and namespace, if it exists.
+func deleteIfExists(c k8s.Client, obj runtime.Object, namespace, name string) error {
+	if err := c.Get(types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := c.Delete(obj); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
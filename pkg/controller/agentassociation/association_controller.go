@@ -0,0 +1,718 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agentassociation
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"time"
+
+	"go.elastic.co/apm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/agent"
+	agentname "github.com/elastic/cloud-on-k8s/pkg/controller/agent/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	kbpod "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/rbac"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+const (
+	name = "agent-association-controller"
+
+	esUserSuffix     = "agent-es-user"
+	esCASecretSuffix = "agent-es-ca" // nolint
+	kbUserSuffix     = "agent-kb-user"
+	kbCASecretSuffix = "agent-kb-ca" // nolint
+
+	fsCASecretSuffix = "agent-fs-ca" // nolint
+
+	// kibanaFleetRole is the role of the user setting up Fleet in Kibana, which requires superuser privileges.
+	kibanaFleetRole = user.SuperUserBuiltinRole
+)
+
+var (
+	log            = logf.Log.WithName(name)
+	defaultRequeue = reconcile.Result{Requeue: true, RequeueAfter: 10 * time.Second}
+)
+
+// Add creates a new Agent association controller and adds it to the Manager.
+func Add(mgr manager.Manager, accessReviewer rbac.AccessReviewer, params operator.Parameters) error {
+	r := newReconciler(mgr, accessReviewer, params)
+	c, err := common.NewController(mgr, name, r, params)
+	if err != nil {
+		return err
+	}
+	return addWatches(c, r)
+}
+
+func newReconciler(mgr manager.Manager, accessReviewer rbac.AccessReviewer, params operator.Parameters) *ReconcileAgentAssociation {
+	client := k8s.WrapClient(mgr.GetClient())
+	return &ReconcileAgentAssociation{
+		Client:         client,
+		accessReviewer: accessReviewer,
+		watches:        watches.NewDynamicWatches(),
+		fleetServers:   watches.NewDynamicEnqueueRequest(),
+		recorder:       mgr.GetEventRecorderFor(name),
+		Parameters:     params,
+	}
+}
+
+func addWatches(c controller.Controller, r *ReconcileAgentAssociation) error {
+	// Watch for changes to Agent
+	if err := c.Watch(&source.Kind{Type: &agentv1alpha1.Agent{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// Dynamically watch referenced Elasticsearch clusters
+	if err := c.Watch(&source.Kind{Type: &esv1.Elasticsearch{}}, r.watches.ElasticsearchClusters); err != nil {
+		return err
+	}
+
+	// Dynamically watch referenced Kibana instances
+	if err := c.Watch(&source.Kind{Type: &kbv1.Kibana{}}, r.watches.Kibanas); err != nil {
+		return err
+	}
+
+	// Dynamically watch the Agents running referenced Fleet Servers
+	if err := c.Watch(&source.Kind{Type: &agentv1alpha1.Agent{}}, r.fleetServers); err != nil {
+		return err
+	}
+
+	// Dynamically watch Elasticsearch, Kibana and Fleet Server public CA secrets
+	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, r.watches.Secrets); err != nil {
+		return err
+	}
+
+	// Watch Secrets owned by an Agent resource
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &agentv1alpha1.Agent{},
+		IsController: true,
+	})
+}
+
+var _ reconcile.Reconciler = &ReconcileAgentAssociation{}
+
+// ReconcileAgentAssociation reconciles the associations between an Elastic Agent and Elasticsearch, Kibana and a Fleet Server.
+type ReconcileAgentAssociation struct {
+	k8s.Client
+	accessReviewer rbac.AccessReviewer
+	recorder       record.EventRecorder
+	watches        watches.DynamicWatches
+	// fleetServers dynamically watches the Agents running referenced Fleet Servers
+	fleetServers *watches.DynamicEnqueueRequest
+	operator.Parameters
+	// iteration is the number of times this controller has run its Reconcile method
+	iteration uint64
+}
+
+// unbinderFunc allows a function to be used as an association.Unbinder.
+type unbinderFunc func(associated commonv1.Associated) error
+
+func (f unbinderFunc) Unbind(associated commonv1.Associated) error {
+	return f(associated)
+}
+
+func elasticsearchWatchName(agentKey types.NamespacedName) string {
+	return agentKey.Namespace + "-" + agentKey.Name + "-es-watch"
+}
+
+func esCAWatchName(agentKey types.NamespacedName) string {
+	return agentKey.Namespace + "-" + agentKey.Name + "-es-ca-watch"
+}
+
+func kibanaWatchName(agentKey types.NamespacedName) string {
+	return agentKey.Namespace + "-" + agentKey.Name + "-kb-watch"
+}
+
+func kbCAWatchName(agentKey types.NamespacedName) string {
+	return agentKey.Namespace + "-" + agentKey.Name + "-kb-ca-watch"
+}
+
+func fleetServerWatchName(agentKey types.NamespacedName) string {
+	return agentKey.Namespace + "-" + agentKey.Name + "-fs-watch"
+}
+
+func fsCAWatchName(agentKey types.NamespacedName) string {
+	return agentKey.Namespace + "-" + agentKey.Name + "-fs-ca-watch"
+}
+
+func (r *ReconcileAgentAssociation) onDelete(obj types.NamespacedName) error {
+	// Clean up memory
+	r.watches.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(obj))
+	r.watches.Secrets.RemoveHandlerForKey(esCAWatchName(obj))
+	r.watches.Kibanas.RemoveHandlerForKey(kibanaWatchName(obj))
+	r.watches.Secrets.RemoveHandlerForKey(kbCAWatchName(obj))
+	r.fleetServers.RemoveHandlerForKey(fleetServerWatchName(obj))
+	r.watches.Secrets.RemoveHandlerForKey(fsCAWatchName(obj))
+	// Delete users
+	return k8s.DeleteSecretMatching(r.Client, NewUserLabelSelector(obj))
+}
+
+// Reconcile reads the state of the cluster for an Agent object and makes changes to its associations based on the
+// state read and what is in the Agent.Spec.
+func (r *ReconcileAgentAssociation) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	defer common.LogReconciliationRun(log, request, "agent_name", &r.iteration)()
+	tx, ctx := tracing.NewTransaction(r.Tracer, request.NamespacedName, "agent-association")
+	defer tracing.EndTransaction(tx)
+
+	var agentObj agentv1alpha1.Agent
+	if err := association.FetchWithAssociation(ctx, r.Client, request, &agentObj); err != nil {
+		if apierrors.IsNotFound(err) {
+			// Agent has been deleted, remove artifacts related to the associations.
+			return reconcile.Result{}, r.onDelete(request.NamespacedName)
+		}
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+	if err := agent.LoadAssociationConfs(&agentObj); err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if common.IsUnmanaged(agentObj.ObjectMeta) {
+		log.Info("Object is currently not managed by this controller. Skipping reconciliation", "namespace", agentObj.Namespace, "agent_name", agentObj.Name)
+		return reconcile.Result{}, nil
+	}
+
+	// Agent is being deleted, short-circuit reconciliation and remove artifacts related to the associations.
+	if agentObj.IsMarkedForDeletion() {
+		return reconcile.Result{}, tracing.CaptureError(ctx, r.onDelete(k8s.ExtractNamespacedName(&agentObj)))
+	}
+
+	if compatible, err := r.isCompatible(ctx, &agentObj); err != nil || !compatible {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if err := annotation.UpdateControllerVersion(ctx, r.Client, &agentObj, r.OperatorInfo.BuildInfo.Version); err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	results := reconciler.NewResult(ctx)
+	esStatus, err := r.reconcileElasticsearch(ctx, &agentObj)
+	if err != nil {
+		results.WithError(err)
+	}
+	kbStatus, kbESNamespace, err := r.reconcileKibana(ctx, &agentObj)
+	if err != nil {
+		results.WithError(err)
+	}
+	fsStatus, err := r.reconcileFleetServer(ctx, &agentObj)
+	if err != nil {
+		results.WithError(err)
+	}
+
+	if err := deleteOrphanedResources(ctx, r.Client, &agentObj, kbESNamespace); err != nil {
+		log.Error(err, "Error while trying to delete orphaned resources. Continuing.", "namespace", agentObj.Namespace, "agent_name", agentObj.Name)
+	}
+
+	// we want to attempt a status update even in the presence of errors
+	if err := r.updateStatus(ctx, agentObj, esStatus, kbStatus, fsStatus); err != nil {
+		return defaultRequeue, tracing.CaptureError(ctx, err)
+	}
+	return results.
+		WithResult(association.RequeueRbacCheck(r.accessReviewer)).
+		WithResult(resultFromStatus(esStatus)).
+		WithResult(resultFromStatus(kbStatus)).
+		WithResult(resultFromStatus(fsStatus)).
+		Aggregate()
+}
+
+func (r *ReconcileAgentAssociation) isCompatible(ctx context.Context, agentObj *agentv1alpha1.Agent) (bool, error) {
+	selector := map[string]string{agent.NameLabelName: agentObj.Name}
+	compat, err := annotation.ReconcileCompatibility(ctx, r.Client, agentObj, selector, r.OperatorInfo.BuildInfo.Version)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, agentObj, events.EventCompatCheckError, "Error during compatibility check: %v", err)
+	}
+	return compat, err
+}
+
+// reconcileElasticsearch reconciles the association between the Elastic Agent and the Elasticsearch cluster it sends
+// data to, or the Fleet Server connects to.
+func (r *ReconcileAgentAssociation) reconcileElasticsearch(ctx context.Context, agentObj *agentv1alpha1.Agent) (commonv1.AssociationStatus, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_es_association", tracing.SpanTypeApp)
+	defer span.End()
+
+	agentKey := k8s.ExtractNamespacedName(agentObj)
+	esRef := agentObj.Spec.ElasticsearchRef.WithDefaultNamespace(agentObj.Namespace)
+	if !esRef.IsDefined() {
+		// no auto-association, remove any leftover configuration
+		r.watches.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(agentKey))
+		r.watches.Secrets.RemoveHandlerForKey(esCAWatchName(agentKey))
+		return commonv1.AssociationUnknown, association.RemoveAssociationConf(r.Client, agentObj)
+	}
+
+	// Make sure we see events from Elasticsearch using a dynamic watch
+	if err := r.watches.ElasticsearchClusters.AddHandler(watches.NamedWatch{
+		Name:    elasticsearchWatchName(agentKey),
+		Watched: []types.NamespacedName{esRef.NamespacedName()},
+		Watcher: agentKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, err
+	}
+
+	var es esv1.Elasticsearch
+	if err := r.Get(esRef.NamespacedName(), &es); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, agentObj, events.EventAssociationError,
+			"Failed to find referenced backend %s: %v", esRef.NamespacedName(), err)
+		if apierrors.IsNotFound(err) {
+			// ES is not found, remove any existing backend configuration and retry in a bit.
+			if err := association.RemoveAssociationConf(r.Client, agentObj); err != nil && !apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, err
+			}
+			return commonv1.AssociationPending, nil
+		}
+		return commonv1.AssociationFailed, err
+	}
+
+	// Check if reference to Elasticsearch is allowed to be established
+	if allowed, err := association.CheckAndUnbind(
+		r.accessReviewer,
+		agentObj,
+		&es,
+		unbinderFunc(r.unbindElasticsearch),
+		r.recorder,
+	); err != nil || !allowed {
+		return commonv1.AssociationPending, err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
+		ctx,
+		r.Client,
+		agentObj,
+		associationLabels(agentKey, esTarget),
+		esRole(agentObj),
+		esUserSuffix,
+		es,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, err
+	}
+
+	// watch ES CA secret to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    esCAWatchName(agentKey),
+		Watched: []types.NamespacedName{certificates.PublicCertsSecretRef(esv1.ESNamer, esRef.NamespacedName())},
+		Watcher: agentKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, err
+	}
+	caSecret, err := association.ReconcileCASecret(
+		r.Client,
+		agentObj,
+		esRef.NamespacedName(),
+		caSecretLabels(agentKey, esTarget),
+		esCASecretSuffix,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, err // maybe not created yet
+	}
+
+	expectedAssocConf := &commonv1.AssociationConf{
+		AuthSecretName: authSecretRef.Name,
+		AuthSecretKey:  authSecretRef.Key,
+		CACertProvided: caSecret.CACertProvided,
+		CASecretName:   caSecret.Name,
+		URL:            services.ExternalServiceURL(es),
+	}
+	if !reflect.DeepEqual(expectedAssocConf, agentObj.AssociationConf()) {
+		log.Info("Updating Agent with Elasticsearch association configuration", "namespace", agentObj.Namespace, "agent_name", agentObj.Name)
+		if err := association.UpdateAssociationConf(r.Client, agentObj, expectedAssocConf); err != nil {
+			if apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, nil
+			}
+			return commonv1.AssociationPending, err
+		}
+		agentObj.SetAssociationConf(expectedAssocConf)
+	}
+
+	return commonv1.AssociationEstablished, nil
+}
+
+// reconcileKibana reconciles the association between the Elastic Agent and the Kibana instance used to set up Fleet
+// and retrieve enrollment tokens.
+// It also returns the namespace of the Elasticsearch cluster backing Kibana, in which the Kibana user is created.
+func (r *ReconcileAgentAssociation) reconcileKibana(ctx context.Context, agentObj *agentv1alpha1.Agent) (commonv1.AssociationStatus, string, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_kb_association", tracing.SpanTypeApp)
+	defer span.End()
+
+	agentKey := k8s.ExtractNamespacedName(agentObj)
+	kbRef := agentObj.Spec.KibanaRef.WithDefaultNamespace(agentObj.Namespace)
+	if !kbRef.IsDefined() {
+		// no auto-association, remove any leftover configuration
+		r.watches.Kibanas.RemoveHandlerForKey(kibanaWatchName(agentKey))
+		r.watches.Secrets.RemoveHandlerForKey(kbCAWatchName(agentKey))
+		return commonv1.AssociationUnknown, "", association.RemoveAssociationConfAnnotation(r.Client, agentObj, annotation.KibanaAssociationConfAnnotation)
+	}
+
+	// Make sure we see events from Kibana using a dynamic watch
+	if err := r.watches.Kibanas.AddHandler(watches.NamedWatch{
+		Name:    kibanaWatchName(agentKey),
+		Watched: []types.NamespacedName{kbRef.NamespacedName()},
+		Watcher: agentKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, "", err
+	}
+
+	var kb kbv1.Kibana
+	if err := r.Get(kbRef.NamespacedName(), &kb); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, agentObj, events.EventAssociationError,
+			"Failed to find referenced Kibana %s: %v", kbRef.NamespacedName(), err)
+		if apierrors.IsNotFound(err) {
+			// Kibana is not found, remove any existing configuration and retry in a bit.
+			if err := association.RemoveAssociationConfAnnotation(r.Client, agentObj, annotation.KibanaAssociationConfAnnotation); err != nil && !apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, "", err
+			}
+			return commonv1.AssociationPending, "", nil
+		}
+		return commonv1.AssociationFailed, "", err
+	}
+
+	// Check if reference to Kibana is allowed to be established
+	if allowed, err := association.CheckAndUnbind(
+		r.accessReviewer,
+		agentObj,
+		&kb,
+		unbinderFunc(r.unbindKibana),
+		r.recorder,
+	); err != nil || !allowed {
+		return commonv1.AssociationPending, "", err
+	}
+
+	// the user used to set up Fleet must exist in the Elasticsearch cluster backing Kibana
+	kbESRef := kb.Spec.ElasticsearchRef.WithDefaultNamespace(kb.Namespace)
+	if !kbESRef.IsDefined() {
+		r.recorder.Eventf(agentObj, corev1.EventTypeWarning, events.EventAssociationError,
+			"Kibana %s is not associated with an Elasticsearch cluster", kbRef.NamespacedName())
+		return commonv1.AssociationPending, "", nil
+	}
+	var kbES esv1.Elasticsearch
+	if err := r.Get(kbESRef.NamespacedName(), &kbES); err != nil {
+		if apierrors.IsNotFound(err) {
+			return commonv1.AssociationPending, "", nil
+		}
+		return commonv1.AssociationFailed, "", err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
+		ctx,
+		r.Client,
+		agentObj,
+		associationLabels(agentKey, kbTarget),
+		kibanaFleetRole,
+		kbUserSuffix,
+		kbES,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, kbES.Namespace, err
+	}
+
+	// watch Kibana CA secret to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    kbCAWatchName(agentKey),
+		Watched: []types.NamespacedName{certificates.PublicCertsSecretRef(kbname.KBNamer, kbRef.NamespacedName())},
+		Watcher: agentKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, kbES.Namespace, err
+	}
+	caSecret, err := association.ReconcileCASecretWithNamer(
+		r.Client,
+		agentObj,
+		kbname.KBNamer,
+		kbRef.NamespacedName(),
+		caSecretLabels(agentKey, kbTarget),
+		kbCASecretSuffix,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, kbES.Namespace, err // maybe not created yet
+	}
+
+	expectedAssocConf := &commonv1.AssociationConf{
+		AuthSecretName: authSecretRef.Name,
+		AuthSecretKey:  authSecretRef.Key,
+		CACertProvided: caSecret.CACertProvided,
+		CASecretName:   caSecret.Name,
+		URL:            kibanaURL(kb),
+	}
+	if !reflect.DeepEqual(expectedAssocConf, agentObj.KibanaAssociationConf()) {
+		log.Info("Updating Agent with Kibana association configuration", "namespace", agentObj.Namespace, "agent_name", agentObj.Name)
+		if err := association.UpdateAssociationConfAnnotation(r.Client, agentObj, annotation.KibanaAssociationConfAnnotation, expectedAssocConf); err != nil {
+			if apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, kbES.Namespace, nil
+			}
+			return commonv1.AssociationPending, kbES.Namespace, err
+		}
+		agentObj.SetKibanaAssociationConf(expectedAssocConf)
+	}
+
+	return commonv1.AssociationEstablished, kbES.Namespace, nil
+}
+
+// reconcileFleetServer reconciles the association between the Elastic Agent and the Fleet Server it enrolls into.
+func (r *ReconcileAgentAssociation) reconcileFleetServer(ctx context.Context, agentObj *agentv1alpha1.Agent) (commonv1.AssociationStatus, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_fs_association", tracing.SpanTypeApp)
+	defer span.End()
+
+	agentKey := k8s.ExtractNamespacedName(agentObj)
+	fsRef := agentObj.Spec.FleetServerRef.WithDefaultNamespace(agentObj.Namespace)
+	if !fsRef.IsDefined() {
+		// no auto-association, remove any leftover configuration
+		r.fleetServers.RemoveHandlerForKey(fleetServerWatchName(agentKey))
+		r.watches.Secrets.RemoveHandlerForKey(fsCAWatchName(agentKey))
+		return commonv1.AssociationUnknown, association.RemoveAssociationConfAnnotation(r.Client, agentObj, annotation.FleetServerAssociationConfAnnotation)
+	}
+
+	// Make sure we see events from the Fleet Server using a dynamic watch
+	if err := r.fleetServers.AddHandler(watches.NamedWatch{
+		Name:    fleetServerWatchName(agentKey),
+		Watched: []types.NamespacedName{fsRef.NamespacedName()},
+		Watcher: agentKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, err
+	}
+
+	var fs agentv1alpha1.Agent
+	if err := r.Get(fsRef.NamespacedName(), &fs); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, agentObj, events.EventAssociationError,
+			"Failed to find referenced Fleet Server %s: %v", fsRef.NamespacedName(), err)
+		if apierrors.IsNotFound(err) {
+			// Fleet Server is not found, remove any existing configuration and retry in a bit.
+			if err := association.RemoveAssociationConfAnnotation(r.Client, agentObj, annotation.FleetServerAssociationConfAnnotation); err != nil && !apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, err
+			}
+			return commonv1.AssociationPending, nil
+		}
+		return commonv1.AssociationFailed, err
+	}
+
+	// Check if reference to the Fleet Server is allowed to be established
+	if allowed, err := association.CheckAndUnbind(
+		r.accessReviewer,
+		agentObj,
+		&fs,
+		unbinderFunc(r.unbindFleetServer),
+		r.recorder,
+	); err != nil || !allowed {
+		return commonv1.AssociationPending, err
+	}
+
+	if !fs.FleetServerEnabled() {
+		r.recorder.Eventf(agentObj, corev1.EventTypeWarning, events.EventAssociationError,
+			"Agent %s does not run a Fleet Server", fsRef.NamespacedName())
+		return commonv1.AssociationFailed, nil
+	}
+
+	// watch Fleet Server CA secret to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    fsCAWatchName(agentKey),
+		Watched: []types.NamespacedName{certificates.PublicCertsSecretRef(agentname.AgentNamer, fsRef.NamespacedName())},
+		Watcher: agentKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, err
+	}
+	var caSecret association.CASecret
+	if fs.Spec.HTTP.TLS.Enabled() {
+		var err error
+		caSecret, err = association.ReconcileCASecretWithNamer(
+			r.Client,
+			agentObj,
+			agentname.AgentNamer,
+			fsRef.NamespacedName(),
+			caSecretLabels(agentKey, fsTarget),
+			fsCASecretSuffix,
+		)
+		if err != nil {
+			return commonv1.AssociationPending, err // maybe not created yet
+		}
+	}
+
+	// the Elastic Agent authenticates to the Fleet Server with an enrollment token, no user is needed
+	expectedAssocConf := &commonv1.AssociationConf{
+		CACertProvided: caSecret.CACertProvided,
+		CASecretName:   caSecret.Name,
+		URL:            agent.FleetServerURL(fs),
+	}
+	if !reflect.DeepEqual(expectedAssocConf, agentObj.FleetServerAssociationConf()) {
+		log.Info("Updating Agent with Fleet Server association configuration", "namespace", agentObj.Namespace, "agent_name", agentObj.Name)
+		if err := association.UpdateAssociationConfAnnotation(r.Client, agentObj, annotation.FleetServerAssociationConfAnnotation, expectedAssocConf); err != nil {
+			if apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, nil
+			}
+			return commonv1.AssociationPending, err
+		}
+		agentObj.SetFleetServerAssociationConf(expectedAssocConf)
+	}
+
+	return commonv1.AssociationEstablished, nil
+}
+
+// esRole returns the role of the user connecting to Elasticsearch, depending on whether the Elastic Agent runs
+// a Fleet Server.
+func esRole(agentObj *agentv1alpha1.Agent) string {
+	if agentObj.FleetServerEnabled() {
+		return user.FleetServerUserRoleV7
+	}
+	return user.AgentUserRoleV7
+}
+
+// kibanaURL returns the URL used to reach the Kibana HTTP service.
+func kibanaURL(kb kbv1.Kibana) string {
+	return stringsutil.Concat(kb.Spec.HTTP.Protocol(), "://", kbname.HTTPService(kb.Name), ".", kb.Namespace, ".svc:", strconv.Itoa(kbpod.HTTPPort))
+}
+
+func caSecretLabels(agentKey types.NamespacedName, target string) map[string]string {
+	labels := agent.Labels(agentKey.Name)
+	for k, v := range associationLabels(agentKey, target) {
+		labels[k] = v
+	}
+	return labels
+}
+
+func (r *ReconcileAgentAssociation) updateStatus(ctx context.Context, agentObj agentv1alpha1.Agent, esStatus, kbStatus, fsStatus commonv1.AssociationStatus) error {
+	span, _ := apm.StartSpan(ctx, "update_association", tracing.SpanTypeApp)
+	defer span.End()
+
+	oldESStatus := agentObj.Status.ElasticsearchAssociationStatus
+	oldKBStatus := agentObj.Status.KibanaAssociationStatus
+	oldFSStatus := agentObj.Status.FleetServerAssociationStatus
+	if oldESStatus == esStatus && oldKBStatus == kbStatus && oldFSStatus == fsStatus {
+		return nil
+	}
+	agentObj.Status.ElasticsearchAssociationStatus = esStatus
+	agentObj.Status.KibanaAssociationStatus = kbStatus
+	agentObj.Status.FleetServerAssociationStatus = fsStatus
+	if err := r.Status().Update(&agentObj); err != nil {
+		return err
+	}
+	if oldESStatus != esStatus {
+		r.recorder.AnnotatedEventf(&agentObj,
+			annotation.ForAssociationStatusChange(oldESStatus, esStatus),
+			corev1.EventTypeNormal,
+			events.EventAssociationStatusChange,
+			"Elasticsearch association status changed from [%s] to [%s]", oldESStatus, esStatus)
+	}
+	if oldKBStatus != kbStatus {
+		r.recorder.AnnotatedEventf(&agentObj,
+			annotation.ForAssociationStatusChange(oldKBStatus, kbStatus),
+			corev1.EventTypeNormal,
+			events.EventAssociationStatusChange,
+			"Kibana association status changed from [%s] to [%s]", oldKBStatus, kbStatus)
+	}
+	if oldFSStatus != fsStatus {
+		r.recorder.AnnotatedEventf(&agentObj,
+			annotation.ForAssociationStatusChange(oldFSStatus, fsStatus),
+			corev1.EventTypeNormal,
+			events.EventAssociationStatusChange,
+			"Fleet Server association status changed from [%s] to [%s]", oldFSStatus, fsStatus)
+	}
+	return nil
+}
+
+// deleteOrphanedResources deletes resources created by this controller that are left over from previous
+// reconciliation attempts: either because an association has been removed, or because the namespace of the
+// Elasticsearch cluster holding the user has changed.
+func deleteOrphanedResources(ctx context.Context, c k8s.Client, agentObj *agentv1alpha1.Agent, kbESNamespace string) error {
+	span, _ := apm.StartSpan(ctx, "delete_orphaned_resources", tracing.SpanTypeApp)
+	defer span.End()
+
+	agentKey := k8s.ExtractNamespacedName(agentObj)
+	var secrets corev1.SecretList
+	matchLabels := client.MatchingLabels{
+		AssociationLabelName:      agentKey.Name,
+		AssociationLabelNamespace: agentKey.Namespace,
+	}
+	if err := c.List(&secrets, matchLabels); err != nil {
+		return err
+	}
+
+	esRef := agentObj.Spec.ElasticsearchRef.WithDefaultNamespace(agentObj.Namespace)
+	kbRef := agentObj.Spec.KibanaRef.WithDefaultNamespace(agentObj.Namespace)
+	fsRef := agentObj.Spec.FleetServerRef.WithDefaultNamespace(agentObj.Namespace)
+	for _, s := range secrets.Items {
+		var refDefined bool
+		var userNamespace string
+		switch s.Labels[AssociationLabelTarget] {
+		case esTarget:
+			refDefined, userNamespace = esRef.IsDefined(), esRef.Namespace
+		case kbTarget:
+			refDefined, userNamespace = kbRef.IsDefined(), kbESNamespace
+		case fsTarget:
+			refDefined = fsRef.IsDefined()
+		default:
+			continue
+		}
+		isUser := s.Labels[common.TypeLabelName] == user.AssociatedUserType
+		if refDefined && (!isUser || userNamespace == "" || userNamespace == s.Namespace) {
+			continue
+		}
+		log.Info("Deleting secret", "namespace", s.Namespace, "secret_name", s.Name, "agent_name", agentObj.Name)
+		if err := c.Delete(&s); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func resultFromStatus(status commonv1.AssociationStatus) reconcile.Result {
+	switch status {
+	case commonv1.AssociationPending:
+		return defaultRequeue // retry
+	default:
+		return reconcile.Result{} // we are done or there is not much we can do
+	}
+}
+
+// unbindElasticsearch removes the resources related to the Elasticsearch association.
+func (r *ReconcileAgentAssociation) unbindElasticsearch(associated commonv1.Associated) error {
+	key := k8s.ExtractNamespacedName(associated)
+	// Ensure that user in Elasticsearch is deleted to prevent illegitimate access
+	if err := k8s.DeleteSecretMatching(r.Client, newTargetUserLabelSelector(key, esTarget)); err != nil {
+		return err
+	}
+	// Also remove the association configuration
+	return association.RemoveAssociationConf(r.Client, associated)
+}
+
+// unbindKibana removes the resources related to the Kibana association.
+func (r *ReconcileAgentAssociation) unbindKibana(associated commonv1.Associated) error {
+	key := k8s.ExtractNamespacedName(associated)
+	// Ensure that user in Elasticsearch is deleted to prevent illegitimate access
+	if err := k8s.DeleteSecretMatching(r.Client, newTargetUserLabelSelector(key, kbTarget)); err != nil {
+		return err
+	}
+	// Also remove the association configuration
+	return association.RemoveAssociationConfAnnotation(r.Client, associated, annotation.KibanaAssociationConfAnnotation)
+}
+
+// unbindFleetServer removes the resources related to the Fleet Server association.
+func (r *ReconcileAgentAssociation) unbindFleetServer(associated commonv1.Associated) error {
+	return association.RemoveAssociationConfAnnotation(r.Client, associated, annotation.FleetServerAssociationConfAnnotation)
+}
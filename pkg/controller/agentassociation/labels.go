@@ -0,0 +1,56 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package agentassociation
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+)
+
+const (
+	// AssociationLabelName marks resources created by this controller for easier retrieval.
+	AssociationLabelName = "agentassociation.k8s.elastic.co/name"
+	// AssociationLabelNamespace marks resources created by this controller for easier retrieval.
+	AssociationLabelNamespace = "agentassociation.k8s.elastic.co/namespace"
+	// AssociationLabelTarget marks resources created by this controller with the kind of the association target
+	// (Elasticsearch, Kibana or Fleet Server).
+	AssociationLabelTarget = "agentassociation.k8s.elastic.co/target"
+
+	esTarget = "elasticsearch"
+	kbTarget = "kibana"
+	fsTarget = "fleet-server"
+)
+
+// NewResourceLabels returns the labels to identify an Elastic Agent association.
+func NewResourceLabels(name string) map[string]string {
+	return map[string]string{AssociationLabelName: name}
+}
+
+// NewUserLabelSelector returns labels matching the users created for the given Elastic Agent, for all association targets.
+func NewUserLabelSelector(namespacedName types.NamespacedName) client.MatchingLabels {
+	return map[string]string{
+		AssociationLabelName:      namespacedName.Name,
+		AssociationLabelNamespace: namespacedName.Namespace,
+		common.TypeLabelName:      user.AssociatedUserType,
+	}
+}
+
+// newTargetUserLabelSelector returns labels matching the users created for the given Elastic Agent and association target.
+func newTargetUserLabelSelector(namespacedName types.NamespacedName, target string) client.MatchingLabels {
+	labels := NewUserLabelSelector(namespacedName)
+	labels[AssociationLabelTarget] = target
+	return labels
+}
+
+func associationLabels(namespacedName types.NamespacedName, target string) map[string]string {
+	return map[string]string{
+		AssociationLabelName:      namespacedName.Name,
+		AssociationLabelNamespace: namespacedName.Namespace,
+		AssociationLabelTarget:    target,
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"go.elastic.co/apm"
 
 	apmv1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/config"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
 	apmname "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/name"
@@ -31,6 +32,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/pod"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
@@ -241,6 +243,8 @@ func (r *ReconcileApmServer) doReconcile(ctx context.Context, request reconcile.
 		Services:              []corev1.Service{*svc},
 		CACertRotation:        r.CACertRotation,
 		CertRotation:          r.CertRotation,
+		CertManagerIssuer:     r.CertManagerIssuer,
+		Recorder:              r.recorder,
 		GarbageCollectSecrets: true,
 	}.ReconcileCAAndHTTPCerts(ctx)
 	if results.HasError() {
@@ -256,10 +260,16 @@ func (r *ReconcileApmServer) doReconcile(ctx context.Context, request reconcile.
 			return reconcile.Result{Requeue: true}, nil
 		}
 		k8s.EmitErrorEvent(r.recorder, err, as, events.EventReconciliationError, "Deployment reconciliation error: %v", err)
+		state.UpdateReconciliationStatus(err)
+		if statusErr := r.updateStatus(ctx, state); statusErr != nil && !apierrors.IsConflict(statusErr) {
+			log.Error(statusErr, "Failed to update status", "namespace", as.Namespace, "as_name", as.Name)
+		}
 		return state.Result, tracing.CaptureError(ctx, err)
 	}
 
 	state.UpdateApmServerExternalService(*svc)
+	_, reconcileErr := results.Aggregate()
+	state.UpdateReconciliationStatus(reconcileErr)
 
 	// update status
 	err = r.updateStatus(ctx, state)
@@ -288,6 +298,7 @@ func (r *ReconcileApmServer) validate(ctx context.Context, as *apmv1.ApmServer)
 func (r *ReconcileApmServer) onDelete(obj types.NamespacedName) {
 	// Clean up watches set on secure settings
 	r.dynamicWatches.Secrets.RemoveHandlerForKey(keystore.SecureSettingsWatchName(obj))
+	certificates.DeleteExpirationMetrics(&apmv1.ApmServer{}, obj)
 }
 
 // reconcileApmServerToken reconciles a Secret containing the APM Server token.
@@ -369,6 +380,25 @@ func (r *ReconcileApmServer) deploymentParams(
 		}
 	}
 
+	if as.AssociationConf().GetAuthType() == commonv1.ClientCertificateAuth {
+		// include the client certificate in the checksum, since APM Server does not reload it
+		var clientCerts corev1.Secret
+		key := types.NamespacedName{Namespace: as.Namespace, Name: as.AssociationConf().GetAuthSecretName()}
+		if err := r.Get(key, &clientCerts); err != nil {
+			return deployment.Params{}, err
+		}
+		_, _ = configChecksum.Write(clientCerts.Data[certificates.CertFileName])
+
+		clientCertsVolume := volume.NewSecretVolumeWithMountPath(
+			as.AssociationConf().GetAuthSecretName(),
+			"elasticsearch-client-certs",
+			filepath.Join(ApmBaseDir, config.ClientCertificatesDir),
+		)
+		podSpec.Spec.Volumes = append(podSpec.Spec.Volumes, clientCertsVolume.Volume())
+		apmServerContainer := pod.ContainerByName(podSpec.Spec, apmv1.ApmServerContainerName)
+		apmServerContainer.VolumeMounts = append(apmServerContainer.VolumeMounts, clientCertsVolume.VolumeMount())
+	}
+
 	if as.Spec.HTTP.TLS.Enabled() {
 		// fetch the secret to calculate the checksum
 		var httpCerts corev1.Secret
@@ -393,12 +423,16 @@ func (r *ReconcileApmServer) deploymentParams(
 
 	podSpec.Labels = maps.MergePreservingExistingKeys(podSpec.Labels, podLabels)
 
+	// the version is set on the Deployment only: adding it to the Pod template would restart existing APM Servers
+	deploymentLabels := labels.NewLabels(as.Name)
+	deploymentLabels[labels.ApmVersionLabelName] = as.Spec.Version
+
 	return deployment.Params{
 		Name:            apmname.Deployment(as.Name),
 		Namespace:       as.Namespace,
 		Replicas:        as.Spec.Count,
 		Selector:        labels.NewLabels(as.Name),
-		Labels:          labels.NewLabels(as.Name),
+		Labels:          deploymentLabels,
 		PodTemplateSpec: podSpec,
 		Strategy:        appsv1.RollingUpdateDeploymentStrategyType,
 	}, nil
@@ -453,10 +487,28 @@ func (r *ReconcileApmServer) reconcileApmServerDeployment(
 	if err != nil {
 		return state, err
 	}
-	state.UpdateApmServerState(result, tokenSecret)
+	lowestVersion, err := runningVersion(result, as.Status.Version)
+	if err != nil {
+		return state, err
+	}
+	state.UpdateApmServerState(result, lowestVersion, tokenSecret)
 	return state, nil
 }
 
+// runningVersion returns the lowest version running in the Pods of the given Deployment. The version is not set on
+// the Pods, it is read from the Deployment once its rollout is over. Until then, the previously reported version is
+// still the lowest one.
+func runningVersion(d appsv1.Deployment, previous string) (*version.Version, error) {
+	if d.Status.Replicas == 0 {
+		return nil, nil
+	}
+	rolledOut := d.Status.ObservedGeneration >= d.Generation && d.Status.UpdatedReplicas == d.Status.Replicas
+	if !rolledOut && previous != "" {
+		return version.Parse(previous)
+	}
+	return version.FromLabels(d.Labels, labels.ApmVersionLabelName)
+}
+
 func (r *ReconcileApmServer) updateStatus(ctx context.Context, state State) error {
 	span, _ := apm.StartSpan(ctx, "update_status", tracing.SpanTypeApp)
 	defer span.End()
@@ -87,7 +87,7 @@ func expectedDeploymentParams() testParams {
 			Name:      "test-apm-server-apm-server",
 			Namespace: "",
 			Selector:  map[string]string{"apm.k8s.elastic.co/name": "test-apm-server", "common.k8s.elastic.co/type": "apm-server"},
-			Labels:    map[string]string{"apm.k8s.elastic.co/name": "test-apm-server", "common.k8s.elastic.co/type": "apm-server"},
+			Labels:    map[string]string{"apm.k8s.elastic.co/name": "test-apm-server", "common.k8s.elastic.co/type": "apm-server", "apm.k8s.elastic.co/version": ""},
 			Strategy:  appsv1.RollingUpdateDeploymentStrategyType,
 			PodTemplateSpec: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
@@ -480,3 +480,67 @@ func Test_reconcileApmServerToken(t *testing.T) {
 		})
 	}
 }
+
+func Test_runningVersion(t *testing.T) {
+	deploymentWithStatus := func(generation, observedGeneration int64, replicas, updatedReplicas int32) appsv1.Deployment {
+		return appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Generation: generation,
+				Labels:     map[string]string{"apm.k8s.elastic.co/version": "7.10.0"},
+			},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: observedGeneration,
+				Replicas:           replicas,
+				UpdatedReplicas:    updatedReplicas,
+			},
+		}
+	}
+	tests := []struct {
+		name       string
+		deployment appsv1.Deployment
+		previous   string
+		want       string
+	}{
+		{
+			name:       "no Pod running",
+			deployment: deploymentWithStatus(1, 1, 0, 0),
+			previous:   "7.9.0",
+			want:       "",
+		},
+		{
+			name:       "rollout over",
+			deployment: deploymentWithStatus(2, 2, 3, 3),
+			previous:   "7.9.0",
+			want:       "7.10.0",
+		},
+		{
+			name:       "rollout not observed yet",
+			deployment: deploymentWithStatus(2, 1, 3, 3),
+			previous:   "7.9.0",
+			want:       "7.9.0",
+		},
+		{
+			name:       "rollout in progress",
+			deployment: deploymentWithStatus(2, 2, 4, 1),
+			previous:   "7.9.0",
+			want:       "7.9.0",
+		},
+		{
+			name:       "no previous version",
+			deployment: deploymentWithStatus(2, 2, 4, 1),
+			previous:   "",
+			want:       "7.10.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runningVersion(tt.deployment, tt.previous)
+			require.NoError(t, err)
+			if tt.want == "" {
+				require.Nil(t, got)
+				return
+			}
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
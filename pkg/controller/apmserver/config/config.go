@@ -23,6 +23,8 @@ const (
 
 	// Certificates
 	CertificatesDir = "config/elasticsearch-certs"
+	// ClientCertificatesDir holds the client certificate APM Server authenticates against Elasticsearch with
+	ClientCertificatesDir = "config/elasticsearch-client-certs"
 
 	APMServerHost        = "apm-server.host"
 	APMServerSecretToken = "apm-server.secret_token"
@@ -45,16 +47,24 @@ func NewConfigFromSpec(c k8s.Client, as *apmv1.ApmServer) (*settings.CanonicalCo
 
 	outputCfg := settings.NewCanonicalConfig()
 	if as.AssociationConf().IsConfigured() {
-		// Get username and password
+		// Get username and password, or the API key, unused with a client certificate
 		username, password, err := association.ElasticsearchAuthSettings(c, as)
 		if err != nil {
 			return nil, err
 		}
 
 		tmpOutputCfg := map[string]interface{}{
-			"output.elasticsearch.hosts":    []string{as.AssociationConf().GetURL()},
-			"output.elasticsearch.username": username,
-			"output.elasticsearch.password": password,
+			"output.elasticsearch.hosts": []string{as.AssociationConf().GetURL()},
+		}
+		switch as.AssociationConf().GetAuthType() {
+		case commonv1.APIKeyAuth:
+			tmpOutputCfg["output.elasticsearch.api_key"] = password
+		case commonv1.ClientCertificateAuth:
+			tmpOutputCfg["output.elasticsearch.ssl.certificate"] = filepath.Join(ClientCertificatesDir, certificates.CertFileName)
+			tmpOutputCfg["output.elasticsearch.ssl.key"] = filepath.Join(ClientCertificatesDir, certificates.KeyFileName)
+		default:
+			tmpOutputCfg["output.elasticsearch.username"] = username
+			tmpOutputCfg["output.elasticsearch.password"] = password
 		}
 		if as.AssociationConf().GetCACertProvided() {
 			tmpOutputCfg["output.elasticsearch.ssl.certificate_authorities"] = []string{filepath.Join(CertificatesDir, certificates.CAFileName)}
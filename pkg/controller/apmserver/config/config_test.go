@@ -68,6 +68,39 @@ func TestNewConfigFromSpec(t *testing.T) {
 				"output.elasticsearch.ssl.certificate_authorities": []string{"config/elasticsearch-certs/ca.crt"},
 			},
 		},
+		{
+			name: "with API key",
+			assocConf: &commonv1.AssociationConf{
+				AuthSecretName: "test-es-elastic-user",
+				AuthSecretKey:  "api-key",
+				AuthType:       commonv1.APIKeyAuth,
+				CASecretName:   "test-es-http-ca-public",
+				CACertProvided: true,
+				URL:            "https://test-es-http.default.svc:9200",
+			},
+			wantConf: map[string]interface{}{
+				"output.elasticsearch.hosts":                       []string{"https://test-es-http.default.svc:9200"},
+				"output.elasticsearch.api_key":                     "VuaCfGcBCdbkQm-e5aOx:ui2lp2axTNmsyakw9tvNnw",
+				"output.elasticsearch.ssl.certificate_authorities": []string{"config/elasticsearch-certs/ca.crt"},
+			},
+		},
+		{
+			name: "with a client certificate",
+			assocConf: &commonv1.AssociationConf{
+				AuthSecretName: "test-es-elastic-user",
+				AuthSecretKey:  "tls.crt",
+				AuthType:       commonv1.ClientCertificateAuth,
+				CASecretName:   "test-es-http-ca-public",
+				CACertProvided: true,
+				URL:            "https://test-es-http.default.svc:9200",
+			},
+			wantConf: map[string]interface{}{
+				"output.elasticsearch.hosts":                       []string{"https://test-es-http.default.svc:9200"},
+				"output.elasticsearch.ssl.certificate":             "config/elasticsearch-client-certs/tls.crt",
+				"output.elasticsearch.ssl.key":                     "config/elasticsearch-client-certs/tls.key",
+				"output.elasticsearch.ssl.certificate_authorities": []string{"config/elasticsearch-certs/ca.crt"},
+			},
+		},
 		{
 			name: "missing auth secret",
 			assocConf: &commonv1.AssociationConf{
@@ -121,6 +154,7 @@ func mkAuthSecret() *v1.Secret {
 		},
 		Data: map[string][]byte{
 			"elastic": []byte("password"),
+			"api-key": []byte("VuaCfGcBCdbkQm-e5aOx:ui2lp2axTNmsyakw9tvNnw"),
 		},
 	}
 }
@@ -9,6 +9,8 @@ import "github.com/elastic/cloud-on-k8s/pkg/controller/common"
 const (
 	// ApmServerNameLabelName used to represent an ApmServer in k8s resources
 	ApmServerNameLabelName = "apm.k8s.elastic.co/name"
+	// ApmVersionLabelName used to propagate the ApmServer version from the spec to the deployment
+	ApmVersionLabelName = "apm.k8s.elastic.co/version"
 	// Type represents the apm server type
 	Type = "apm-server"
 )
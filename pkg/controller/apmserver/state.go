@@ -10,6 +10,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	apmv1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 )
 
 // State holds the accumulated state during the reconcile loop including the response and a pointer to an ApmServer
@@ -28,8 +30,9 @@ func NewState(request reconcile.Request, as *apmv1.ApmServer) State {
 	return State{Request: request, ApmServer: as, originalApmServer: as.DeepCopy()}
 }
 
-// UpdateApmServerState updates the ApmServer status based on the given deployment.
-func (s State) UpdateApmServerState(deployment v1.Deployment, apmServerSecret corev1.Secret) {
+// UpdateApmServerState updates the ApmServer status based on the given deployment and the lowest version running in
+// its Pods.
+func (s State) UpdateApmServerState(deployment v1.Deployment, lowestVersion *version.Version, apmServerSecret corev1.Secret) {
 	s.ApmServer.Status.SecretTokenSecretName = apmServerSecret.Name
 	s.ApmServer.Status.AvailableNodes = deployment.Status.AvailableReplicas
 	s.ApmServer.Status.Health = apmv1.ApmServerRed
@@ -38,6 +41,15 @@ func (s State) UpdateApmServerState(deployment v1.Deployment, apmServerSecret co
 			s.ApmServer.Status.Health = apmv1.ApmServerGreen
 		}
 	}
+	common.ReportRunningVersion(&s.ApmServer.Status.ReconcilerStatus, lowestVersion, s.ApmServer.Spec.Version)
+}
+
+// UpdateReconciliationStatus reports the reconciliation of the current APM Server generation, see common.UpdateReconciliationStatus.
+func (s State) UpdateReconciliationStatus(err error) {
+	common.UpdateReconciliationStatus(
+		&s.ApmServer.Status.ReconcilerStatus, s.ApmServer.Generation, err,
+		s.ApmServer.Status.Health == apmv1.ApmServerGreen, "APM Server is not available",
+	)
 }
 
 // UpdateApmServerExternalService updates the ApmServer ExternalService status.
@@ -100,6 +100,7 @@ func newReconciler(mgr manager.Manager, accessReviewer rbac.AccessReviewer, para
 		accessReviewer: accessReviewer,
 		watches:        watches.NewDynamicWatches(),
 		recorder:       mgr.GetEventRecorderFor(name),
+		newESClient:    association.NewESClientProvider(params.Dialer),
 		Parameters:     params,
 	}
 }
@@ -139,6 +140,7 @@ type ReconcileApmServerElasticsearchAssociation struct {
 	accessReviewer rbac.AccessReviewer
 	recorder       record.EventRecorder
 	watches        watches.DynamicWatches
+	newESClient    association.ESClientProvider
 	operator.Parameters
 	// iteration is the number of times this controller has run its Reconcile method
 	iteration uint64
@@ -194,7 +196,7 @@ func (r *ReconcileApmServerElasticsearchAssociation) Reconcile(request reconcile
 	}
 
 	results := reconciler.NewResult(ctx)
-	newStatus, err := r.reconcileInternal(ctx, &apmServer)
+	newStatus, requeueAfter, err := r.reconcileInternal(ctx, &apmServer)
 	if err != nil {
 		results.WithError(err)
 	}
@@ -207,6 +209,7 @@ func (r *ReconcileApmServerElasticsearchAssociation) Reconcile(request reconcile
 		WithError(err).
 		WithResult(association.RequeueRbacCheck(r.accessReviewer)).
 		WithResult(resultFromStatus(newStatus)).
+		WithResult(reconcile.Result{RequeueAfter: requeueAfter}).
 		Aggregate()
 }
 
@@ -258,7 +261,7 @@ func (r *ReconcileApmServerElasticsearchAssociation) isCompatible(ctx context.Co
 	return compat, err
 }
 
-func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(ctx context.Context, apmServer *apmv1.ApmServer) (commonv1.AssociationStatus, error) {
+func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(ctx context.Context, apmServer *apmv1.ApmServer) (commonv1.AssociationStatus, time.Duration, error) {
 	// garbage collect leftover resources that are not required anymore
 	if err := deleteOrphanedResources(ctx, r, apmServer); err != nil {
 		log.Error(err, "Error while trying to delete orphaned resources. Continuing.", "namespace", apmServer.Namespace, "as_name", apmServer.Name)
@@ -269,10 +272,10 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(ctx conte
 	if !elasticsearchRef.IsDefined() {
 		// clean up watchers and remove artifacts related to the association
 		if err := r.onDelete(apmServerKey); err != nil {
-			return commonv1.AssociationFailed, err
+			return commonv1.AssociationFailed, 0, err
 		}
 		// remove the configuration in the annotation, other leftover resources are already garbage-collected
-		return commonv1.AssociationUnknown, association.RemoveAssociationConf(r.Client, apmServer)
+		return commonv1.AssociationUnknown, 0, association.RemoveAssociationConf(r.Client, apmServer)
 	}
 	if elasticsearchRef.Namespace == "" {
 		// no namespace provided: default to the APM server namespace
@@ -286,7 +289,7 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(ctx conte
 		Watcher: apmServerKey,
 	})
 	if err != nil {
-		return commonv1.AssociationFailed, err
+		return commonv1.AssociationFailed, 0, err
 	}
 
 	userSecretKey := association.UserKey(apmServer, apmUserSuffix)
@@ -296,13 +299,13 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(ctx conte
 		Watched: []types.NamespacedName{userSecretKey},
 		Watcher: apmServerKey,
 	}); err != nil {
-		return commonv1.AssociationFailed, err
+		return commonv1.AssociationFailed, 0, err
 	}
 
 	var es esv1.Elasticsearch
 	associationStatus, err := r.getElasticsearch(ctx, apmServer, elasticsearchRef, &es)
 	if associationStatus != "" || err != nil {
-		return associationStatus, err
+		return associationStatus, 0, err
 	}
 
 	// Check if reference to Elasticsearch is allowed to be established
@@ -313,31 +316,24 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(ctx conte
 		r,
 		r.recorder,
 	); err != nil || !allowed {
-		return commonv1.AssociationPending, err
+		return commonv1.AssociationPending, 0, err
 	}
 
-	if err := association.ReconcileEsUser(
-		ctx,
-		r.Client,
-		apmServer,
-		associationLabels(apmServer),
-		getRoles(version.MustParse(apmServer.Spec.Version)),
-		apmUserSuffix,
-		es,
-	); err != nil { // TODO distinguish conflicts and non-recoverable errors here
-		return commonv1.AssociationPending, err
+	authSecretRef, authType, requeueAfter, err := r.reconcileAuth(ctx, apmServer, es)
+	if err != nil { // TODO distinguish conflicts and non-recoverable errors here
+		return commonv1.AssociationPending, 0, err
 	}
 
 	caSecret, err := r.reconcileElasticsearchCA(ctx, apmServer, elasticsearchRef.NamespacedName())
 	if err != nil {
-		return commonv1.AssociationPending, err // maybe not created yet
+		return commonv1.AssociationPending, 0, err // maybe not created yet
 	}
 
 	// construct the expected ES output configuration
-	authSecretRef := association.ClearTextSecretKeySelector(apmServer, apmUserSuffix)
 	expectedAssocConf := &commonv1.AssociationConf{
 		AuthSecretName: authSecretRef.Name,
 		AuthSecretKey:  authSecretRef.Key,
+		AuthType:       authType,
 		CACertProvided: caSecret.CACertProvided,
 		CASecretName:   caSecret.Name,
 		URL:            services.ExternalServiceURL(es),
@@ -346,10 +342,83 @@ func (r *ReconcileApmServerElasticsearchAssociation) reconcileInternal(ctx conte
 	var status commonv1.AssociationStatus
 	status, err = r.updateAssocConf(ctx, expectedAssocConf, apmServer)
 	if err != nil || status != "" {
-		return status, err
+		return status, 0, err
 	}
 
-	return commonv1.AssociationEstablished, nil
+	return commonv1.AssociationEstablished, requeueAfter, nil
+}
+
+// reconcileAuth reconciles the credentials APM Server uses to authenticate against Elasticsearch: a client
+// certificate if client authentication is enabled on the HTTP layer of Elasticsearch, other credentials otherwise.
+func (r *ReconcileApmServerElasticsearchAssociation) reconcileAuth(
+	ctx context.Context,
+	apmServer *apmv1.ApmServer,
+	es esv1.Elasticsearch,
+) (*corev1.SecretKeySelector, commonv1.AuthType, time.Duration, error) {
+	if !es.Spec.HTTP.TLS.ClientAuthenticationEnabled() {
+		return r.reconcileCredentials(ctx, apmServer, es)
+	}
+	ready, err := association.ClientCertificateAuthReady(r.Client, es)
+	if err != nil {
+		return nil, commonv1.ClientCertificateAuth, 0, err
+	}
+	if ready {
+		authSecretRef, requeueAfter, err := association.ReconcileClientCertificate(
+			ctx,
+			r.Client,
+			apmServer,
+			associationLabels(apmServer),
+			getRoles(version.MustParse(apmServer.Spec.Version)),
+			apmUserSuffix,
+			es,
+			r.CertRotation,
+			time.Now(),
+		)
+		return authSecretRef, commonv1.ClientCertificateAuth, requeueAfter, err
+	}
+	// keep the current credentials until all the Elasticsearch Pods request client certificates
+	authSecretRef, authType, requeueAfter, err := r.reconcileCredentials(ctx, apmServer, es)
+	if requeueAfter == 0 || requeueAfter > association.ClientCertificateCheckInterval {
+		requeueAfter = association.ClientCertificateCheckInterval
+	}
+	return authSecretRef, authType, requeueAfter, err
+}
+
+// reconcileCredentials reconciles the credentials APM Server uses to authenticate against Elasticsearch: an API key
+// if supported, a file realm user otherwise.
+func (r *ReconcileApmServerElasticsearchAssociation) reconcileCredentials(
+	ctx context.Context,
+	apmServer *apmv1.ApmServer,
+	es esv1.Elasticsearch,
+) (*corev1.SecretKeySelector, commonv1.AuthType, time.Duration, error) {
+	if association.SupportsCredentials(es, apmServer.Spec.Version) {
+		authSecretRef, requeueAfter, err := association.ReconcileCredentials(
+			ctx,
+			r.Client,
+			r.newESClient,
+			apmServer,
+			associationLabels(apmServer),
+			apmUserSuffix,
+			es,
+			association.CredentialsSpec{AuthType: commonv1.APIKeyAuth, Role: user.ApmAPIKeyRole},
+			time.Now(),
+		)
+		return authSecretRef, commonv1.APIKeyAuth, requeueAfter, err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
+		ctx,
+		r.Client,
+		apmServer,
+		associationLabels(apmServer),
+		getRoles(version.MustParse(apmServer.Spec.Version)),
+		apmUserSuffix,
+		es,
+	)
+	if err != nil {
+		return nil, commonv1.BasicAuth, 0, err
+	}
+	return authSecretRef, commonv1.BasicAuth, 0, nil
 }
 
 func (r *ReconcileApmServerElasticsearchAssociation) getElasticsearch(ctx context.Context, apmServer *apmv1.ApmServer, elasticsearchRef commonv1.ObjectSelector, es *esv1.Elasticsearch) (commonv1.AssociationStatus, error) {
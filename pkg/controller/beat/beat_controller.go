@@ -0,0 +1,362 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package beat
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"go.elastic.co/apm"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	controllerName = "beat-controller"
+)
+
+var (
+	log = logf.Log.WithName(controllerName)
+)
+
+// Add creates a new Beat Controller and adds it to the Manager with default RBAC.
+// The Manager will set fields on the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, params operator.Parameters) error {
+	reconciler := newReconciler(mgr, params)
+	c, err := common.NewController(mgr, controllerName, reconciler, params)
+	if err != nil {
+		return err
+	}
+	return addWatches(c, reconciler)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, params operator.Parameters) *ReconcileBeat {
+	client := k8s.WrapClient(mgr.GetClient())
+	return &ReconcileBeat{
+		Client:         client,
+		recorder:       mgr.GetEventRecorderFor(controllerName),
+		dynamicWatches: watches.NewDynamicWatches(),
+		Parameters:     params,
+	}
+}
+
+func podsToReconcileRequest(object handler.MapObject) []reconcile.Request {
+	labels := object.Meta.GetLabels()
+	beatName, isSet := labels[NameLabelName]
+	if !isSet {
+		return nil
+	}
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{
+				Namespace: object.Meta.GetNamespace(),
+				Name:      beatName,
+			},
+		},
+	}
+}
+
+func addWatches(c controller.Controller, r *ReconcileBeat) error {
+	// Watch for changes to Beat
+	err := c.Watch(&source.Kind{Type: &beatv1beta1.Beat{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch DaemonSets
+	if err := c.Watch(&source.Kind{Type: &appsv1.DaemonSet{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &beatv1beta1.Beat{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch Deployments
+	if err := c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &beatv1beta1.Beat{},
+	}); err != nil {
+		return err
+	}
+
+	// Watch Pods, to ensure `status.availableNodes` is kept up to date.
+	if err := c.Watch(&source.Kind{Type: &corev1.Pod{}},
+		&handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(podsToReconcileRequest),
+		}); err != nil {
+		return err
+	}
+
+	// Watch secrets
+	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &beatv1beta1.Beat{},
+	}); err != nil {
+		return err
+	}
+
+	// Dynamically watch referenced secrets to connect to Elasticsearch and Kibana
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, r.dynamicWatches.Secrets)
+}
+
+var _ reconcile.Reconciler = &ReconcileBeat{}
+
+// ReconcileBeat reconciles a Beat object
+type ReconcileBeat struct {
+	k8s.Client
+	recorder       record.EventRecorder
+	dynamicWatches watches.DynamicWatches
+	operator.Parameters
+	// iteration is the number of times this controller has run its Reconcile method
+	iteration uint64
+}
+
+func (r *ReconcileBeat) K8sClient() k8s.Client {
+	return r.Client
+}
+
+func (r *ReconcileBeat) DynamicWatches() watches.DynamicWatches {
+	return r.dynamicWatches
+}
+
+func (r *ReconcileBeat) Recorder() record.EventRecorder {
+	return r.recorder
+}
+
+// Reconcile reads that state of the cluster for a Beat object and makes changes based on the state read
+// and what is in the Beat.Spec.
+func (r *ReconcileBeat) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	defer common.LogReconciliationRun(log, request, "beat_name", &r.iteration)()
+	tx, ctx := tracing.NewTransaction(r.Tracer, request.NamespacedName, "beat")
+	defer tracing.EndTransaction(tx)
+
+	var beat beatv1beta1.Beat
+	if err := association.FetchWithAssociation(ctx, r.Client, request, &beat); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.onDelete(request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	kbAssocConf, err := association.GetAssociationConfFromAnnotation(&beat, annotation.KibanaAssociationConfAnnotation)
+	if err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+	beat.SetKibanaAssociationConf(kbAssocConf)
+
+	if common.IsUnmanaged(beat.ObjectMeta) {
+		log.Info("Object is currently not managed by this controller. Skipping reconciliation", "namespace", beat.Namespace, "beat_name", beat.Name)
+		return reconcile.Result{}, nil
+	}
+
+	if compatible, err := r.isCompatible(ctx, &beat); err != nil || !compatible {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if beat.IsMarkedForDeletion() {
+		// Beat will be deleted, clean up resources
+		r.onDelete(k8s.ExtractNamespacedName(&beat))
+		return reconcile.Result{}, nil
+	}
+
+	if err := annotation.UpdateControllerVersion(ctx, r.Client, &beat, r.OperatorInfo.BuildInfo.Version); err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if !association.IsConfiguredIfSet(&beat, r.recorder) || !isKibanaConfiguredIfSet(&beat, r.recorder) {
+		return reconcile.Result{}, nil
+	}
+
+	return r.doReconcile(ctx, request, beat)
+}
+
+// isKibanaConfiguredIfSet checks if the Kibana association is set in the spec and if it has been configured
+// by the association controller.
+func isKibanaConfiguredIfSet(beat *beatv1beta1.Beat, r record.EventRecorder) bool {
+	kbRef := beat.KibanaRef()
+	if (&kbRef).IsDefined() && !beat.KibanaAssociationConf().IsConfigured() {
+		r.Event(beat, corev1.EventTypeWarning, events.EventAssociationError, "Kibana backend is not configured")
+		log.Info("Kibana association not established: skipping Beat reconciliation",
+			"namespace", beat.Namespace,
+			"beat_name", beat.Name,
+		)
+		return false
+	}
+	return true
+}
+
+func (r *ReconcileBeat) onDelete(obj types.NamespacedName) {
+	// Clean up watches
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(esCASecretWatchName(obj))
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(kbCASecretWatchName(obj))
+}
+
+func (r *ReconcileBeat) isCompatible(ctx context.Context, beat *beatv1beta1.Beat) (bool, error) {
+	selector := map[string]string{NameLabelName: beat.Name}
+	compat, err := annotation.ReconcileCompatibility(ctx, r.Client, beat, selector, r.OperatorInfo.BuildInfo.Version)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, beat, events.EventCompatCheckError, "Error during compatibility check: %v", err)
+	}
+	return compat, err
+}
+
+func (r *ReconcileBeat) doReconcile(ctx context.Context, request reconcile.Request, beat beatv1beta1.Beat) (reconcile.Result, error) {
+	// Run validation in case the webhook is disabled
+	if err := r.validate(ctx, &beat); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	state := NewState(request, &beat)
+
+	if err := r.watchCASecrets(beat); err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	configSecret, err := reconcileConfig(r.K8sClient(), beat)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, &beat, events.EventReconciliationError, "Configuration reconciliation error: %v", err)
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	// build a hash of various inputs to rotate Pods on any change
+	configHash, err := buildConfigHash(r.K8sClient(), beat, configSecret)
+	if err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	state, err = r.reconcileWorkload(ctx, state, beat, newPodTemplate(beat, configHash))
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			log.V(1).Info("Conflict while updating workload")
+			return reconcile.Result{Requeue: true}, nil
+		}
+		k8s.EmitErrorEvent(r.recorder, err, &beat, events.EventReconciliationError, "Workload reconciliation error: %v", err)
+		return state.Result, tracing.CaptureError(ctx, err)
+	}
+
+	if err := r.updateStatus(ctx, state); err != nil {
+		if apierrors.IsConflict(err) {
+			log.V(1).Info("Conflict while updating status")
+			return reconcile.Result{Requeue: true}, nil
+		}
+		k8s.EmitErrorEvent(r.recorder, err, &beat, events.EventReconciliationError, "Status update error: %v", err)
+		return state.Result, tracing.CaptureError(ctx, err)
+	}
+	return state.Result, nil
+}
+
+func (r *ReconcileBeat) validate(ctx context.Context, beat *beatv1beta1.Beat) error {
+	span, vctx := apm.StartSpan(ctx, "validate", tracing.SpanTypeApp)
+	defer span.End()
+
+	if err := beat.ValidateCreate(); err != nil {
+		log.Error(err, "Validation failed")
+		k8s.EmitErrorEvent(r.recorder, err, beat, events.EventReasonValidation, err.Error())
+		return tracing.CaptureError(vctx, err)
+	}
+
+	return nil
+}
+
+func (r *ReconcileBeat) updateStatus(ctx context.Context, state State) error {
+	span, _ := apm.StartSpan(ctx, "update_status", tracing.SpanTypeApp)
+	defer span.End()
+
+	current := state.originalBeat
+	if reflect.DeepEqual(current.Status, state.Beat.Status) {
+		return nil
+	}
+	if state.Beat.Status.IsDegraded(current.Status) {
+		r.recorder.Event(current, corev1.EventTypeWarning, events.EventReasonUnhealthy, "Beat health degraded")
+	}
+	log.V(1).Info("Updating status",
+		"iteration", atomic.LoadUint64(&r.iteration),
+		"namespace", state.Beat.Namespace,
+		"beat_name", state.Beat.Name,
+		"status", state.Beat.Status,
+	)
+	return common.UpdateStatus(r.Client, state.Beat)
+}
+
+func buildConfigHash(c k8s.Client, beat beatv1beta1.Beat, configSecret corev1.Secret) (string, error) {
+	// build a hash of various settings to rotate the Pod on any change
+	configHash := sha256.New224()
+
+	// - in the Beat configuration file content
+	_, _ = configHash.Write(configSecret.Data[ConfigFileName])
+
+	// - in the Elasticsearch and Kibana TLS certificates
+	for _, assocConf := range []*commonv1.AssociationConf{beat.AssociationConf(), beat.KibanaAssociationConf()} {
+		if !assocConf.CAIsConfigured() {
+			continue
+		}
+		var caSecret corev1.Secret
+		key := types.NamespacedName{Namespace: beat.Namespace, Name: assocConf.GetCASecretName()}
+		if err := c.Get(key, &caSecret); err != nil {
+			return "", err
+		}
+		if certPem, ok := caSecret.Data[certificates.CertFileName]; ok {
+			_, _ = configHash.Write(certPem)
+		}
+	}
+
+	return fmt.Sprintf("%x", configHash.Sum(nil)), nil
+}
+
+func esCASecretWatchName(beat types.NamespacedName) string {
+	return fmt.Sprintf("%s-%s-es-ca-secret", beat.Namespace, beat.Name)
+}
+
+func kbCASecretWatchName(beat types.NamespacedName) string {
+	return fmt.Sprintf("%s-%s-kb-ca-secret", beat.Namespace, beat.Name)
+}
+
+// watchCASecrets sets up dynamic watches for the Secrets containing the associated Elasticsearch and Kibana CA certs.
+func (r *ReconcileBeat) watchCASecrets(beat beatv1beta1.Beat) error {
+	beatKey := k8s.ExtractNamespacedName(&beat)
+	for watchName, assocConf := range map[string]*commonv1.AssociationConf{
+		esCASecretWatchName(beatKey): beat.AssociationConf(),
+		kbCASecretWatchName(beatKey): beat.KibanaAssociationConf(),
+	} {
+		if !assocConf.CAIsConfigured() {
+			r.dynamicWatches.Secrets.RemoveHandlerForKey(watchName)
+			continue
+		}
+		if err := r.dynamicWatches.Secrets.AddHandler(watches.NamedWatch{
+			Name:    watchName,
+			Watched: []types.NamespacedName{{Namespace: beat.Namespace, Name: assocConf.GetCASecretName()}},
+			Watcher: beatKey,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package beat
+
+import (
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	beatname "github.com/elastic/cloud-on-k8s/pkg/controller/beat/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// ConfigFileName is the name of the Beat configuration file.
+	ConfigFileName = "beat.yml"
+	// ConfigMountPath is the directory where the Beat configuration file is mounted.
+	ConfigMountPath = "/etc/beat"
+	// ESCertsPath is the directory where the Elasticsearch CA certificates are mounted.
+	ESCertsPath = "/mnt/elastic-internal/elasticsearch-certs"
+	// KibanaCertsPath is the directory where the Kibana CA certificates are mounted.
+	KibanaCertsPath = "/mnt/elastic-internal/kibana-certs"
+)
+
+// buildOutputConfig returns the configuration for the Elasticsearch output, if an association is configured.
+func buildOutputConfig(client k8s.Client, beat beatv1beta1.Beat) (*settings.CanonicalConfig, error) {
+	if !beat.AssociationConf().IsConfigured() {
+		return settings.NewCanonicalConfig(), nil
+	}
+	username, password, err := association.ElasticsearchAuthSettings(client, &beat)
+	if err != nil {
+		return nil, err
+	}
+	cfg := map[string]interface{}{
+		"output.elasticsearch.hosts":    []string{beat.AssociationConf().GetURL()},
+		"output.elasticsearch.username": username,
+		"output.elasticsearch.password": password,
+	}
+	if beat.AssociationConf().GetCACertProvided() {
+		cfg["output.elasticsearch.ssl.certificate_authorities"] = []string{filepath.Join(ESCertsPath, certificates.CAFileName)}
+	}
+	return settings.NewCanonicalConfigFrom(cfg)
+}
+
+// buildKibanaConfig returns the configuration for the Kibana dashboards setup, if an association is configured.
+func buildKibanaConfig(client k8s.Client, beat beatv1beta1.Beat) (*settings.CanonicalConfig, error) {
+	if !beat.KibanaAssociationConf().IsConfigured() {
+		return settings.NewCanonicalConfig(), nil
+	}
+	username, password, err := association.AuthSettings(client, beat.Namespace, beat.KibanaAssociationConf())
+	if err != nil {
+		return nil, err
+	}
+	cfg := map[string]interface{}{
+		"setup.dashboards.enabled": true,
+		"setup.kibana.host":        beat.KibanaAssociationConf().GetURL(),
+		"setup.kibana.username":    username,
+		"setup.kibana.password":    password,
+	}
+	if beat.KibanaAssociationConf().GetCACertProvided() {
+		cfg["setup.kibana.ssl.certificate_authorities"] = []string{filepath.Join(KibanaCertsPath, certificates.CAFileName)}
+	}
+	return settings.NewCanonicalConfigFrom(cfg)
+}
+
+// buildBeatConfig builds the Beat configuration, merging the user-provided configuration on top of the
+// connection settings derived from the associations.
+func buildBeatConfig(client k8s.Client, beat beatv1beta1.Beat) ([]byte, error) {
+	outputCfg, err := buildOutputConfig(client, beat)
+	if err != nil {
+		return nil, err
+	}
+	kibanaCfg, err := buildKibanaConfig(client, beat)
+	if err != nil {
+		return nil, err
+	}
+
+	specConfig := beat.Spec.Config
+	if specConfig == nil {
+		specConfig = &commonv1.Config{}
+	}
+	userCfg, err := settings.NewCanonicalConfigFrom(specConfig.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := settings.NewCanonicalConfig()
+	// merge the user configuration last so it takes precedence
+	if err := cfg.MergeWith(outputCfg, kibanaCfg, userCfg); err != nil {
+		return nil, err
+	}
+	return cfg.Render()
+}
+
+// reconcileConfig builds the Beat configuration and reconciles the Secret holding it.
+func reconcileConfig(client k8s.Client, beat beatv1beta1.Beat) (corev1.Secret, error) {
+	cfgBytes, err := buildBeatConfig(client, beat)
+	if err != nil {
+		return corev1.Secret{}, err
+	}
+
+	expected := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: beat.Namespace,
+			Name:      beatname.ConfigSecret(beat.Name),
+			Labels:    Labels(beat.Name),
+		},
+		Data: map[string][]byte{
+			ConfigFileName: cfgBytes,
+		},
+	}
+	return reconciler.ReconcileSecret(client, expected, &beat)
+}
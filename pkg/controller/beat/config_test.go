@@ -0,0 +1,136 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package beat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func testBeat() beatv1beta1.Beat {
+	cfg := commonv1.NewConfig(map[string]interface{}{"metricbeat.modules": []interface{}{}})
+	return beatv1beta1.Beat{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "beat",
+		},
+		Spec: beatv1beta1.BeatSpec{
+			Type:      "metricbeat",
+			Version:   "7.8.0",
+			Config:    &cfg,
+			DaemonSet: &beatv1beta1.DaemonSetSpec{},
+		},
+	}
+}
+
+func authSecret(name, key, password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+		},
+		Data: map[string][]byte{
+			key: []byte(password),
+		},
+	}
+}
+
+func Test_buildBeatConfig(t *testing.T) {
+	withES := func(b beatv1beta1.Beat) beatv1beta1.Beat {
+		b.SetAssociationConf(&commonv1.AssociationConf{
+			AuthSecretName: "beat-es-user",
+			AuthSecretKey:  "ns-beat-es-user",
+			CACertProvided: true,
+			CASecretName:   "beat-es-ca",
+			URL:            "https://es-es-http.ns.svc:9200",
+		})
+		return b
+	}
+	withKibana := func(b beatv1beta1.Beat) beatv1beta1.Beat {
+		b.SetKibanaAssociationConf(&commonv1.AssociationConf{
+			AuthSecretName: "beat-kb-user",
+			AuthSecretKey:  "ns-beat-kb-user",
+			CASecretName:   "beat-kb-ca",
+			URL:            "http://kb-kb-http.ns.svc:5601",
+		})
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		beat    beatv1beta1.Beat
+		objs    []runtime.Object
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "no association",
+			beat: testBeat(),
+			want: map[string]interface{}{
+				"metricbeat.modules": []interface{}{},
+			},
+		},
+		{
+			name: "elasticsearch association",
+			beat: withES(testBeat()),
+			objs: []runtime.Object{authSecret("beat-es-user", "ns-beat-es-user", "es-password")},
+			want: map[string]interface{}{
+				"metricbeat.modules":                               []interface{}{},
+				"output.elasticsearch.hosts":                       []string{"https://es-es-http.ns.svc:9200"},
+				"output.elasticsearch.username":                    "ns-beat-es-user",
+				"output.elasticsearch.password":                    "es-password",
+				"output.elasticsearch.ssl.certificate_authorities": []string{"/mnt/elastic-internal/elasticsearch-certs/ca.crt"},
+			},
+		},
+		{
+			name: "elasticsearch and kibana associations",
+			beat: withKibana(withES(testBeat())),
+			objs: []runtime.Object{
+				authSecret("beat-es-user", "ns-beat-es-user", "es-password"),
+				authSecret("beat-kb-user", "ns-beat-kb-user", "kb-password"),
+			},
+			want: map[string]interface{}{
+				"metricbeat.modules":                               []interface{}{},
+				"output.elasticsearch.hosts":                       []string{"https://es-es-http.ns.svc:9200"},
+				"output.elasticsearch.username":                    "ns-beat-es-user",
+				"output.elasticsearch.password":                    "es-password",
+				"output.elasticsearch.ssl.certificate_authorities": []string{"/mnt/elastic-internal/elasticsearch-certs/ca.crt"},
+				"setup.dashboards.enabled":                         true,
+				"setup.kibana.host":                                "http://kb-kb-http.ns.svc:5601",
+				"setup.kibana.username":                            "ns-beat-kb-user",
+				"setup.kibana.password":                            "kb-password",
+			},
+		},
+		{
+			name:    "missing auth secret",
+			beat:    withES(testBeat()),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildBeatConfig(k8s.WrappedFakeClient(tt.objs...), tt.beat)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			gotCfg, err := settings.ParseConfig(got)
+			require.NoError(t, err)
+			wantCfg, err := settings.NewCanonicalConfigFrom(tt.want)
+			require.NoError(t, err)
+			require.Empty(t, gotCfg.Diff(wantCfg, nil))
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package beat
+
+import (
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+)
+
+const (
+	// Type represents the Beat type.
+	Type = "beat"
+	// NameLabelName is used to represent a Beat in k8s resources.
+	NameLabelName = "beat.k8s.elastic.co/name"
+	// VersionLabelName is a label used to track the version of a Beat Pod.
+	VersionLabelName = "beat.k8s.elastic.co/version"
+)
+
+// Labels returns labels that identify the given Beat resource.
+func Labels(beatName string) map[string]string {
+	return map[string]string{
+		NameLabelName:        beatName,
+		common.TypeLabelName: Type,
+	}
+}
+
+// VersionLabels returns labels identifying the version of the given Beat.
+func VersionLabels(beat beatv1beta1.Beat) map[string]string {
+	return map[string]string{
+		VersionLabelName: beat.Spec.Version,
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package name
+
+import (
+	common_name "github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+)
+
+const (
+	configSuffix = "config"
+)
+
+// BeatNamer is a Namer that is configured with the defaults for resources related to a Beat resource.
+var BeatNamer = common_name.NewNamer("beat")
+
+// Workload returns the name of the DaemonSet or Deployment running the Beat pods.
+func Workload(beatName string) string {
+	return BeatNamer.Suffix(beatName)
+}
+
+// ConfigSecret returns the name of the Secret holding the Beat configuration.
+func ConfigSecret(beatName string) string {
+	return BeatNamer.Suffix(beatName, configSuffix)
+}
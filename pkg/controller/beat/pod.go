@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package beat
+
+import (
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	beatname "github.com/elastic/cloud-on-k8s/pkg/controller/beat/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/container"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
+)
+
+const (
+	// ConfigHashAnnotationName is an annotation used to rotate the Beat pods on configuration changes.
+	ConfigHashAnnotationName = "beat.k8s.elastic.co/config-hash"
+
+	configVolumeName   = "config"
+	esCertsVolumeName  = "es-certs"
+	kbCertsVolumeName  = "kibana-certs"
+	nodeNameEnvVarName = "NODE_NAME"
+)
+
+var (
+	defaultResources = corev1.ResourceRequirements{
+		Requests: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceMemory: resource.MustParse("200Mi"),
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+		},
+		Limits: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceMemory: resource.MustParse("200Mi"),
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+		},
+	}
+)
+
+// userPodTemplate returns the pod template provided by the user, depending on the Beat deployment mode.
+func userPodTemplate(beat beatv1beta1.Beat) corev1.PodTemplateSpec {
+	switch {
+	case beat.Spec.DaemonSet != nil:
+		return beat.Spec.DaemonSet.PodTemplate
+	case beat.Spec.Deployment != nil:
+		return beat.Spec.Deployment.PodTemplate
+	default:
+		return corev1.PodTemplateSpec{}
+	}
+}
+
+func newPodTemplate(beat beatv1beta1.Beat, configHash string) corev1.PodTemplateSpec {
+	cfgVolume := volume.NewSecretVolumeWithMountPath(beatname.ConfigSecret(beat.Name), configVolumeName, ConfigMountPath)
+
+	builder := defaults.NewPodTemplateBuilder(userPodTemplate(beat), beat.Spec.Type).
+		WithResources(defaultResources).
+		WithDockerImage(beat.Spec.Image, container.ImageRepository(container.BeatImage(beat.Spec.Type), beat.Spec.Version)).
+		WithArgs("-e", "-c", filepath.Join(ConfigMountPath, ConfigFileName)).
+		WithEnv(corev1.EnvVar{
+			Name: nodeNameEnvVarName,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "spec.nodeName"},
+			},
+		}).
+		WithVolumes(cfgVolume.Volume()).
+		WithVolumeMounts(cfgVolume.VolumeMount()).
+		WithLabels(maps.Merge(Labels(beat.Name), VersionLabels(beat))).
+		// ensure the Pods get rotated on config change
+		WithAnnotations(map[string]string{ConfigHashAnnotationName: configHash})
+
+	if beat.AssociationConf().CAIsConfigured() {
+		esCerts := volume.NewSecretVolumeWithMountPath(beat.AssociationConf().GetCASecretName(), esCertsVolumeName, ESCertsPath)
+		builder = builder.WithVolumes(esCerts.Volume()).WithVolumeMounts(esCerts.VolumeMount())
+	}
+	if beat.KibanaAssociationConf().CAIsConfigured() {
+		kbCerts := volume.NewSecretVolumeWithMountPath(beat.KibanaAssociationConf().GetCASecretName(), kbCertsVolumeName, KibanaCertsPath)
+		builder = builder.WithVolumes(kbCerts.Volume()).WithVolumeMounts(kbCerts.VolumeMount())
+	}
+
+	return builder.PodTemplate
+}
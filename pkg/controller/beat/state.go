@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package beat
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+)
+
+// State holds the accumulated state during the reconcile loop including the response and a pointer to a Beat
+// resource for status updates.
+type State struct {
+	Beat    *beatv1beta1.Beat
+	Result  reconcile.Result
+	Request reconcile.Request
+
+	originalBeat *beatv1beta1.Beat
+}
+
+// NewState creates a new reconcile state based on the given request and Beat resource with the resource
+// state reset to empty.
+func NewState(request reconcile.Request, beat *beatv1beta1.Beat) State {
+	return State{Request: request, Beat: beat, originalBeat: beat.DeepCopy()}
+}
+
+// UpdateBeatState updates the Beat status based on the number of available and expected pods.
+func (s State) UpdateBeatState(available, expected int32) {
+	s.Beat.Status.AvailableNodes = available
+	s.Beat.Status.ExpectedNodes = expected
+	s.Beat.Status.Health = calculateHealth(available, expected)
+}
+
+func calculateHealth(available, expected int32) beatv1beta1.BeatHealth {
+	switch {
+	case available == 0:
+		return beatv1beta1.BeatRedHealth
+	case available < expected:
+		return beatv1beta1.BeatYellowHealth
+	default:
+		return beatv1beta1.BeatGreenHealth
+	}
+}
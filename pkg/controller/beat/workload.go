@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package beat
+
+import (
+	"context"
+
+	"go.elastic.co/apm"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	beatname "github.com/elastic/cloud-on-k8s/pkg/controller/beat/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/daemonset"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/deployment"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const defaultReplicas int32 = 1
+
+// reconcileWorkload reconciles the DaemonSet or the Deployment running the Beat pods, and deletes the other kind
+// of workload if the deployment mode was changed.
+func (r *ReconcileBeat) reconcileWorkload(ctx context.Context, state State, beat beatv1beta1.Beat, podTemplate corev1.PodTemplateSpec) (State, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_workload", tracing.SpanTypeApp)
+	defer span.End()
+
+	name := beatname.Workload(beat.Name)
+	labels := Labels(beat.Name)
+
+	if beat.Spec.DaemonSet != nil {
+		if err := deleteIfExists(r.K8sClient(), &appsv1.Deployment{}, beat.Namespace, name); err != nil {
+			return state, err
+		}
+		ds := daemonset.New(daemonset.Params{
+			Name:            name,
+			Namespace:       beat.Namespace,
+			Selector:        labels,
+			Labels:          labels,
+			PodTemplateSpec: podTemplate,
+			UpdateStrategy:  beat.Spec.DaemonSet.UpdateStrategy,
+		})
+		reconciled, err := daemonset.Reconcile(r.K8sClient(), ds, &beat)
+		if err != nil {
+			return state, err
+		}
+		state.UpdateBeatState(reconciled.Status.NumberAvailable, reconciled.Status.DesiredNumberScheduled)
+		return state, nil
+	}
+
+	if err := deleteIfExists(r.K8sClient(), &appsv1.DaemonSet{}, beat.Namespace, name); err != nil {
+		return state, err
+	}
+	replicas := defaultReplicas
+	if beat.Spec.Deployment.Replicas != nil {
+		replicas = *beat.Spec.Deployment.Replicas
+	}
+	strategy := beat.Spec.Deployment.Strategy.Type
+	if strategy == "" {
+		strategy = appsv1.RollingUpdateDeploymentStrategyType
+	}
+	deploy := deployment.New(deployment.Params{
+		Name:            name,
+		Namespace:       beat.Namespace,
+		Selector:        labels,
+		Labels:          labels,
+		PodTemplateSpec: podTemplate,
+		Replicas:        replicas,
+		Strategy:        strategy,
+	})
+	reconciled, err := deployment.Reconcile(r.K8sClient(), deploy, &beat)
+	if err != nil {
+		return state, err
+	}
+	state.UpdateBeatState(reconciled.Status.AvailableReplicas, replicas)
+	return state, nil
+}
+
+// deleteIfExists deletes the resource with the given name and namespace, if it exists.
+func deleteIfExists(c k8s.Client, obj runtime.Object, namespace, name string) error {
+	if err := c.Get(types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := c.Delete(obj); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,580 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package beatassociation
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"time"
+
+	"go.elastic.co/apm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/beat"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	kbpod "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/rbac"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+const (
+	name = "beat-association-controller"
+
+	esUserSuffix     = "beat-es-user"
+	esCASecretSuffix = "beat-es-ca" // nolint
+	kbUserSuffix     = "beat-kb-user"
+	kbCASecretSuffix = "beat-kb-ca" // nolint
+
+	// kibanaAdminRole is the built-in role allowing to set up dashboards in Kibana, starting with 7.8.0.
+	kibanaAdminRole = "kibana_admin"
+	// kibanaUserRole is the built-in role allowing to set up dashboards in Kibana, before 7.8.0.
+	kibanaUserRole = "kibana_user"
+)
+
+var (
+	log            = logf.Log.WithName(name)
+	defaultRequeue = reconcile.Result{Requeue: true, RequeueAfter: 10 * time.Second}
+)
+
+// Add creates a new Beat association controller and adds it to the Manager.
+func Add(mgr manager.Manager, accessReviewer rbac.AccessReviewer, params operator.Parameters) error {
+	r := newReconciler(mgr, accessReviewer, params)
+	c, err := common.NewController(mgr, name, r, params)
+	if err != nil {
+		return err
+	}
+	return addWatches(c, r)
+}
+
+func newReconciler(mgr manager.Manager, accessReviewer rbac.AccessReviewer, params operator.Parameters) *ReconcileBeatAssociation {
+	client := k8s.WrapClient(mgr.GetClient())
+	return &ReconcileBeatAssociation{
+		Client:         client,
+		accessReviewer: accessReviewer,
+		watches:        watches.NewDynamicWatches(),
+		recorder:       mgr.GetEventRecorderFor(name),
+		Parameters:     params,
+	}
+}
+
+func addWatches(c controller.Controller, r *ReconcileBeatAssociation) error {
+	// Watch for changes to Beat
+	if err := c.Watch(&source.Kind{Type: &beatv1beta1.Beat{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// Dynamically watch referenced Elasticsearch clusters
+	if err := c.Watch(&source.Kind{Type: &esv1.Elasticsearch{}}, r.watches.ElasticsearchClusters); err != nil {
+		return err
+	}
+
+	// Dynamically watch referenced Kibana instances
+	if err := c.Watch(&source.Kind{Type: &kbv1.Kibana{}}, r.watches.Kibanas); err != nil {
+		return err
+	}
+
+	// Dynamically watch Elasticsearch and Kibana public CA secrets
+	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, r.watches.Secrets); err != nil {
+		return err
+	}
+
+	// Watch Secrets owned by a Beat resource
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &beatv1beta1.Beat{},
+		IsController: true,
+	})
+}
+
+var _ reconcile.Reconciler = &ReconcileBeatAssociation{}
+
+// ReconcileBeatAssociation reconciles the associations between a Beat and Elasticsearch, and between a Beat and Kibana.
+type ReconcileBeatAssociation struct {
+	k8s.Client
+	accessReviewer rbac.AccessReviewer
+	recorder       record.EventRecorder
+	watches        watches.DynamicWatches
+	operator.Parameters
+	// iteration is the number of times this controller has run its Reconcile method
+	iteration uint64
+}
+
+// unbinderFunc allows a function to be used as an association.Unbinder.
+type unbinderFunc func(associated commonv1.Associated) error
+
+func (f unbinderFunc) Unbind(associated commonv1.Associated) error {
+	return f(associated)
+}
+
+func elasticsearchWatchName(beat types.NamespacedName) string {
+	return beat.Namespace + "-" + beat.Name + "-es-watch"
+}
+
+func esCAWatchName(beat types.NamespacedName) string {
+	return beat.Namespace + "-" + beat.Name + "-es-ca-watch"
+}
+
+func kibanaWatchName(beat types.NamespacedName) string {
+	return beat.Namespace + "-" + beat.Name + "-kb-watch"
+}
+
+func kbCAWatchName(beat types.NamespacedName) string {
+	return beat.Namespace + "-" + beat.Name + "-kb-ca-watch"
+}
+
+func (r *ReconcileBeatAssociation) onDelete(obj types.NamespacedName) error {
+	// Clean up memory
+	r.watches.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(obj))
+	r.watches.Secrets.RemoveHandlerForKey(esCAWatchName(obj))
+	r.watches.Kibanas.RemoveHandlerForKey(kibanaWatchName(obj))
+	r.watches.Secrets.RemoveHandlerForKey(kbCAWatchName(obj))
+	// Delete users
+	return k8s.DeleteSecretMatching(r.Client, NewUserLabelSelector(obj))
+}
+
+// Reconcile reads the state of the cluster for a Beat object and makes changes to its associations based on the
+// state read and what is in the Beat.Spec.
+func (r *ReconcileBeatAssociation) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	defer common.LogReconciliationRun(log, request, "beat_name", &r.iteration)()
+	tx, ctx := tracing.NewTransaction(r.Tracer, request.NamespacedName, "beat-association")
+	defer tracing.EndTransaction(tx)
+
+	var beat beatv1beta1.Beat
+	if err := association.FetchWithAssociation(ctx, r.Client, request, &beat); err != nil {
+		if apierrors.IsNotFound(err) {
+			// Beat has been deleted, remove artifacts related to the associations.
+			return reconcile.Result{}, r.onDelete(request.NamespacedName)
+		}
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+	kbAssocConf, err := association.GetAssociationConfFromAnnotation(&beat, annotation.KibanaAssociationConfAnnotation)
+	if err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+	beat.SetKibanaAssociationConf(kbAssocConf)
+
+	if common.IsUnmanaged(beat.ObjectMeta) {
+		log.Info("Object is currently not managed by this controller. Skipping reconciliation", "namespace", beat.Namespace, "beat_name", beat.Name)
+		return reconcile.Result{}, nil
+	}
+
+	// Beat is being deleted, short-circuit reconciliation and remove artifacts related to the associations.
+	if beat.IsMarkedForDeletion() {
+		return reconcile.Result{}, tracing.CaptureError(ctx, r.onDelete(k8s.ExtractNamespacedName(&beat)))
+	}
+
+	if compatible, err := r.isCompatible(ctx, &beat); err != nil || !compatible {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if err := annotation.UpdateControllerVersion(ctx, r.Client, &beat, r.OperatorInfo.BuildInfo.Version); err != nil {
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	results := reconciler.NewResult(ctx)
+	esStatus, err := r.reconcileElasticsearch(ctx, &beat)
+	if err != nil {
+		results.WithError(err)
+	}
+	kbStatus, kbESNamespace, err := r.reconcileKibana(ctx, &beat)
+	if err != nil {
+		results.WithError(err)
+	}
+
+	if err := deleteOrphanedResources(ctx, r.Client, &beat, kbESNamespace); err != nil {
+		log.Error(err, "Error while trying to delete orphaned resources. Continuing.", "namespace", beat.Namespace, "beat_name", beat.Name)
+	}
+
+	// we want to attempt a status update even in the presence of errors
+	if err := r.updateStatus(ctx, beat, esStatus, kbStatus); err != nil {
+		return defaultRequeue, tracing.CaptureError(ctx, err)
+	}
+	return results.
+		WithResult(association.RequeueRbacCheck(r.accessReviewer)).
+		WithResult(resultFromStatus(esStatus)).
+		WithResult(resultFromStatus(kbStatus)).
+		Aggregate()
+}
+
+func (r *ReconcileBeatAssociation) isCompatible(ctx context.Context, beatObj *beatv1beta1.Beat) (bool, error) {
+	selector := map[string]string{beat.NameLabelName: beatObj.Name}
+	compat, err := annotation.ReconcileCompatibility(ctx, r.Client, beatObj, selector, r.OperatorInfo.BuildInfo.Version)
+	if err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, beatObj, events.EventCompatCheckError, "Error during compatibility check: %v", err)
+	}
+	return compat, err
+}
+
+// reconcileElasticsearch reconciles the association between the Beat and the Elasticsearch cluster it sends data to.
+func (r *ReconcileBeatAssociation) reconcileElasticsearch(ctx context.Context, beatObj *beatv1beta1.Beat) (commonv1.AssociationStatus, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_es_association", tracing.SpanTypeApp)
+	defer span.End()
+
+	beatKey := k8s.ExtractNamespacedName(beatObj)
+	esRef := beatObj.Spec.ElasticsearchRef.WithDefaultNamespace(beatObj.Namespace)
+	if !esRef.IsDefined() {
+		// no auto-association, remove any leftover configuration
+		r.watches.ElasticsearchClusters.RemoveHandlerForKey(elasticsearchWatchName(beatKey))
+		r.watches.Secrets.RemoveHandlerForKey(esCAWatchName(beatKey))
+		return commonv1.AssociationUnknown, association.RemoveAssociationConf(r.Client, beatObj)
+	}
+
+	// Make sure we see events from Elasticsearch using a dynamic watch
+	if err := r.watches.ElasticsearchClusters.AddHandler(watches.NamedWatch{
+		Name:    elasticsearchWatchName(beatKey),
+		Watched: []types.NamespacedName{esRef.NamespacedName()},
+		Watcher: beatKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, err
+	}
+
+	var es esv1.Elasticsearch
+	if err := r.Get(esRef.NamespacedName(), &es); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, beatObj, events.EventAssociationError,
+			"Failed to find referenced backend %s: %v", esRef.NamespacedName(), err)
+		if apierrors.IsNotFound(err) {
+			// ES is not found, remove any existing backend configuration and retry in a bit.
+			if err := association.RemoveAssociationConf(r.Client, beatObj); err != nil && !apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, err
+			}
+			return commonv1.AssociationPending, nil
+		}
+		return commonv1.AssociationFailed, err
+	}
+
+	// Check if reference to Elasticsearch is allowed to be established
+	if allowed, err := association.CheckAndUnbind(
+		r.accessReviewer,
+		beatObj,
+		&es,
+		unbinderFunc(r.unbindElasticsearch),
+		r.recorder,
+	); err != nil || !allowed {
+		return commonv1.AssociationPending, err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
+		ctx,
+		r.Client,
+		beatObj,
+		associationLabels(beatKey, esTarget),
+		user.BeatUserRoleV7,
+		esUserSuffix,
+		es,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, err
+	}
+
+	// watch ES CA secret to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    esCAWatchName(beatKey),
+		Watched: []types.NamespacedName{certificates.PublicCertsSecretRef(esv1.ESNamer, esRef.NamespacedName())},
+		Watcher: beatKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, err
+	}
+	caSecret, err := association.ReconcileCASecret(
+		r.Client,
+		beatObj,
+		esRef.NamespacedName(),
+		caSecretLabels(beatKey, esTarget),
+		esCASecretSuffix,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, err // maybe not created yet
+	}
+
+	expectedAssocConf := &commonv1.AssociationConf{
+		AuthSecretName: authSecretRef.Name,
+		AuthSecretKey:  authSecretRef.Key,
+		CACertProvided: caSecret.CACertProvided,
+		CASecretName:   caSecret.Name,
+		URL:            services.ExternalServiceURL(es),
+	}
+	if !reflect.DeepEqual(expectedAssocConf, beatObj.AssociationConf()) {
+		log.Info("Updating Beat with Elasticsearch association configuration", "namespace", beatObj.Namespace, "beat_name", beatObj.Name)
+		if err := association.UpdateAssociationConf(r.Client, beatObj, expectedAssocConf); err != nil {
+			if apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, nil
+			}
+			return commonv1.AssociationPending, err
+		}
+		beatObj.SetAssociationConf(expectedAssocConf)
+	}
+
+	return commonv1.AssociationEstablished, nil
+}
+
+// reconcileKibana reconciles the association between the Beat and the Kibana instance used to set up dashboards.
+// It also returns the namespace of the Elasticsearch cluster backing Kibana, in which the Kibana user is created.
+func (r *ReconcileBeatAssociation) reconcileKibana(ctx context.Context, beatObj *beatv1beta1.Beat) (commonv1.AssociationStatus, string, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_kb_association", tracing.SpanTypeApp)
+	defer span.End()
+
+	beatKey := k8s.ExtractNamespacedName(beatObj)
+	kbRef := beatObj.Spec.KibanaRef.WithDefaultNamespace(beatObj.Namespace)
+	if !kbRef.IsDefined() {
+		// no auto-association, remove any leftover configuration
+		r.watches.Kibanas.RemoveHandlerForKey(kibanaWatchName(beatKey))
+		r.watches.Secrets.RemoveHandlerForKey(kbCAWatchName(beatKey))
+		return commonv1.AssociationUnknown, "", association.RemoveAssociationConfAnnotation(r.Client, beatObj, annotation.KibanaAssociationConfAnnotation)
+	}
+
+	// Make sure we see events from Kibana using a dynamic watch
+	if err := r.watches.Kibanas.AddHandler(watches.NamedWatch{
+		Name:    kibanaWatchName(beatKey),
+		Watched: []types.NamespacedName{kbRef.NamespacedName()},
+		Watcher: beatKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, "", err
+	}
+
+	var kb kbv1.Kibana
+	if err := r.Get(kbRef.NamespacedName(), &kb); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, beatObj, events.EventAssociationError,
+			"Failed to find referenced Kibana %s: %v", kbRef.NamespacedName(), err)
+		if apierrors.IsNotFound(err) {
+			// Kibana is not found, remove any existing configuration and retry in a bit.
+			if err := association.RemoveAssociationConfAnnotation(r.Client, beatObj, annotation.KibanaAssociationConfAnnotation); err != nil && !apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, "", err
+			}
+			return commonv1.AssociationPending, "", nil
+		}
+		return commonv1.AssociationFailed, "", err
+	}
+
+	// Check if reference to Kibana is allowed to be established
+	if allowed, err := association.CheckAndUnbind(
+		r.accessReviewer,
+		beatObj,
+		&kb,
+		unbinderFunc(r.unbindKibana),
+		r.recorder,
+	); err != nil || !allowed {
+		return commonv1.AssociationPending, "", err
+	}
+
+	// the user used to set up dashboards must exist in the Elasticsearch cluster backing Kibana
+	kbESRef := kb.Spec.ElasticsearchRef.WithDefaultNamespace(kb.Namespace)
+	if !kbESRef.IsDefined() {
+		r.recorder.Eventf(beatObj, corev1.EventTypeWarning, events.EventAssociationError,
+			"Kibana %s is not associated with an Elasticsearch cluster", kbRef.NamespacedName())
+		return commonv1.AssociationPending, "", nil
+	}
+	var kbES esv1.Elasticsearch
+	if err := r.Get(kbESRef.NamespacedName(), &kbES); err != nil {
+		if apierrors.IsNotFound(err) {
+			return commonv1.AssociationPending, "", nil
+		}
+		return commonv1.AssociationFailed, "", err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
+		ctx,
+		r.Client,
+		beatObj,
+		associationLabels(beatKey, kbTarget),
+		kibanaRole(beatObj.Spec.Version),
+		kbUserSuffix,
+		kbES,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, kbES.Namespace, err
+	}
+
+	// watch Kibana CA secret to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    kbCAWatchName(beatKey),
+		Watched: []types.NamespacedName{certificates.PublicCertsSecretRef(kbname.KBNamer, kbRef.NamespacedName())},
+		Watcher: beatKey,
+	}); err != nil {
+		return commonv1.AssociationFailed, kbES.Namespace, err
+	}
+	caSecret, err := association.ReconcileCASecretWithNamer(
+		r.Client,
+		beatObj,
+		kbname.KBNamer,
+		kbRef.NamespacedName(),
+		caSecretLabels(beatKey, kbTarget),
+		kbCASecretSuffix,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, kbES.Namespace, err // maybe not created yet
+	}
+
+	expectedAssocConf := &commonv1.AssociationConf{
+		AuthSecretName: authSecretRef.Name,
+		AuthSecretKey:  authSecretRef.Key,
+		CACertProvided: caSecret.CACertProvided,
+		CASecretName:   caSecret.Name,
+		URL:            kibanaURL(kb),
+	}
+	if !reflect.DeepEqual(expectedAssocConf, beatObj.KibanaAssociationConf()) {
+		log.Info("Updating Beat with Kibana association configuration", "namespace", beatObj.Namespace, "beat_name", beatObj.Name)
+		if err := association.UpdateAssociationConfAnnotation(r.Client, beatObj, annotation.KibanaAssociationConfAnnotation, expectedAssocConf); err != nil {
+			if apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, kbES.Namespace, nil
+			}
+			return commonv1.AssociationPending, kbES.Namespace, err
+		}
+		beatObj.SetKibanaAssociationConf(expectedAssocConf)
+	}
+
+	return commonv1.AssociationEstablished, kbES.Namespace, nil
+}
+
+// kibanaRole returns the built-in role allowing the Beat to set up dashboards in Kibana.
+func kibanaRole(beatVersion string) string {
+	v, err := version.Parse(beatVersion)
+	if err != nil || v.IsSameOrAfter(version.From(7, 8, 0)) {
+		return kibanaAdminRole
+	}
+	return kibanaUserRole
+}
+
+// kibanaURL returns the URL used to reach the Kibana HTTP service.
+func kibanaURL(kb kbv1.Kibana) string {
+	return stringsutil.Concat(kb.Spec.HTTP.Protocol(), "://", kbname.HTTPService(kb.Name), ".", kb.Namespace, ".svc:", strconv.Itoa(kbpod.HTTPPort))
+}
+
+func caSecretLabels(beatKey types.NamespacedName, target string) map[string]string {
+	labels := beat.Labels(beatKey.Name)
+	for k, v := range associationLabels(beatKey, target) {
+		labels[k] = v
+	}
+	return labels
+}
+
+func (r *ReconcileBeatAssociation) updateStatus(ctx context.Context, beatObj beatv1beta1.Beat, esStatus, kbStatus commonv1.AssociationStatus) error {
+	span, _ := apm.StartSpan(ctx, "update_association", tracing.SpanTypeApp)
+	defer span.End()
+
+	oldESStatus := beatObj.Status.ElasticsearchAssociationStatus
+	oldKBStatus := beatObj.Status.KibanaAssociationStatus
+	if oldESStatus == esStatus && oldKBStatus == kbStatus {
+		return nil
+	}
+	beatObj.Status.ElasticsearchAssociationStatus = esStatus
+	beatObj.Status.KibanaAssociationStatus = kbStatus
+	if err := r.Status().Update(&beatObj); err != nil {
+		return err
+	}
+	if oldESStatus != esStatus {
+		r.recorder.AnnotatedEventf(&beatObj,
+			annotation.ForAssociationStatusChange(oldESStatus, esStatus),
+			corev1.EventTypeNormal,
+			events.EventAssociationStatusChange,
+			"Elasticsearch association status changed from [%s] to [%s]", oldESStatus, esStatus)
+	}
+	if oldKBStatus != kbStatus {
+		r.recorder.AnnotatedEventf(&beatObj,
+			annotation.ForAssociationStatusChange(oldKBStatus, kbStatus),
+			corev1.EventTypeNormal,
+			events.EventAssociationStatusChange,
+			"Kibana association status changed from [%s] to [%s]", oldKBStatus, kbStatus)
+	}
+	return nil
+}
+
+// deleteOrphanedResources deletes resources created by this controller that are left over from previous
+// reconciliation attempts: either because an association has been removed, or because the namespace of the
+// Elasticsearch cluster holding the user has changed.
+func deleteOrphanedResources(ctx context.Context, c k8s.Client, beatObj *beatv1beta1.Beat, kbESNamespace string) error {
+	span, _ := apm.StartSpan(ctx, "delete_orphaned_resources", tracing.SpanTypeApp)
+	defer span.End()
+
+	beatKey := k8s.ExtractNamespacedName(beatObj)
+	var secrets corev1.SecretList
+	matchLabels := client.MatchingLabels{
+		AssociationLabelName:      beatKey.Name,
+		AssociationLabelNamespace: beatKey.Namespace,
+	}
+	if err := c.List(&secrets, matchLabels); err != nil {
+		return err
+	}
+
+	esRef := beatObj.Spec.ElasticsearchRef.WithDefaultNamespace(beatObj.Namespace)
+	kbRef := beatObj.Spec.KibanaRef.WithDefaultNamespace(beatObj.Namespace)
+	for _, s := range secrets.Items {
+		var refDefined bool
+		var userNamespace string
+		switch s.Labels[AssociationLabelTarget] {
+		case esTarget:
+			refDefined, userNamespace = esRef.IsDefined(), esRef.Namespace
+		case kbTarget:
+			refDefined, userNamespace = kbRef.IsDefined(), kbESNamespace
+		default:
+			continue
+		}
+		isUser := s.Labels[common.TypeLabelName] == user.AssociatedUserType
+		if refDefined && (!isUser || userNamespace == "" || userNamespace == s.Namespace) {
+			continue
+		}
+		log.Info("Deleting secret", "namespace", s.Namespace, "secret_name", s.Name, "beat_name", beatObj.Name)
+		if err := c.Delete(&s); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func resultFromStatus(status commonv1.AssociationStatus) reconcile.Result {
+	switch status {
+	case commonv1.AssociationPending:
+		return defaultRequeue // retry
+	default:
+		return reconcile.Result{} // we are done or there is not much we can do
+	}
+}
+
+// unbindElasticsearch removes the resources related to the Elasticsearch association.
+func (r *ReconcileBeatAssociation) unbindElasticsearch(associated commonv1.Associated) error {
+	key := k8s.ExtractNamespacedName(associated)
+	// Ensure that user in Elasticsearch is deleted to prevent illegitimate access
+	if err := k8s.DeleteSecretMatching(r.Client, newTargetUserLabelSelector(key, esTarget)); err != nil {
+		return err
+	}
+	// Also remove the association configuration
+	return association.RemoveAssociationConf(r.Client, associated)
+}
+
+// unbindKibana removes the resources related to the Kibana association.
+func (r *ReconcileBeatAssociation) unbindKibana(associated commonv1.Associated) error {
+	key := k8s.ExtractNamespacedName(associated)
+	// Ensure that user in Elasticsearch is deleted to prevent illegitimate access
+	if err := k8s.DeleteSecretMatching(r.Client, newTargetUserLabelSelector(key, kbTarget)); err != nil {
+		return err
+	}
+	// Also remove the association configuration
+	return association.RemoveAssociationConfAnnotation(r.Client, associated, annotation.KibanaAssociationConfAnnotation)
+}
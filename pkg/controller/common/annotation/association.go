@@ -17,6 +17,16 @@ const (
 	PrevAssocStatusAnnotation = "association.k8s.elastic.co/previous-status"
 	// AssociationConfAnnotation is the annotation used to define the config for associated Elasticsearch cluster.
 	AssociationConfAnnotation = "association.k8s.elastic.co/es-conf"
+	// KibanaAssociationConfAnnotation is the annotation used to define the config for an associated Kibana instance.
+	KibanaAssociationConfAnnotation = "association.k8s.elastic.co/kb-conf"
+	// FleetServerAssociationConfAnnotation is the annotation used to define the config for an associated Fleet Server.
+	FleetServerAssociationConfAnnotation = "association.k8s.elastic.co/fs-conf"
+	// MonitoringMetricsAssociationConfAnnotation is the annotation used to define the config for the Elasticsearch
+	// cluster receiving the metrics of a monitored resource.
+	MonitoringMetricsAssociationConfAnnotation = "association.k8s.elastic.co/monitoring-metrics-es-conf"
+	// MonitoringLogsAssociationConfAnnotation is the annotation used to define the config for the Elasticsearch
+	// cluster receiving the logs of a monitored resource.
+	MonitoringLogsAssociationConfAnnotation = "association.k8s.elastic.co/monitoring-logs-es-conf"
 )
 
 // ForAssociationStatusChange constructs the annotation map for an association status change event.
@@ -9,22 +9,56 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 )
 
+// Wrapper is implemented by associated objects wrapping another resource, for example to represent one of the
+// several associations of a resource with Elasticsearch clusters. The wrapped resource owns the resources created
+// for the association, and is the object of the related events.
+type Wrapper interface {
+	Unwrap() runtime.Object
+}
+
+// unwrap returns the resource wrapped by the given associated object, or the associated object itself.
+func unwrap(associated commonv1.Associated) runtime.Object {
+	if wrapper, ok := associated.(Wrapper); ok {
+		return wrapper.Unwrap()
+	}
+	return associated
+}
+
+// owner returns the resource owning the resources created for the given association.
+func owner(associated commonv1.Associated) metav1.Object {
+	if obj, ok := unwrap(associated).(metav1.Object); ok {
+		return obj
+	}
+	return associated
+}
+
 // ElasticsearchAuthSettings returns the user and the password to be used by an associated object to authenticate
 // against an Elasticsearch cluster.
 func ElasticsearchAuthSettings(
 	c k8s.Client,
 	associated commonv1.Associated,
 ) (username, password string, err error) {
-	assocConf := associated.AssociationConf()
+	return AuthSettings(c, associated.GetNamespace(), associated.AssociationConf())
+}
+
+// AuthSettings returns the user and the password stored in the secret referenced by the given association
+// configuration, living in the given namespace.
+func AuthSettings(
+	c k8s.Client,
+	namespace string,
+	assocConf *commonv1.AssociationConf,
+) (username, password string, err error) {
 	if !assocConf.AuthIsConfigured() {
 		return "", "", nil
 	}
 
-	secretObjKey := types.NamespacedName{Namespace: associated.GetNamespace(), Name: assocConf.AuthSecretName}
+	secretObjKey := types.NamespacedName{Namespace: namespace, Name: assocConf.AuthSecretName}
 	var secret v1.Secret
 	if err := c.Get(secretObjKey, &secret); err != nil {
 		return "", "", err
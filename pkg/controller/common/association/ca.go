@@ -13,6 +13,7 @@ import (
 	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
@@ -38,11 +39,25 @@ func ReconcileCASecret(
 	labels map[string]string,
 	suffix string,
 ) (CASecret, error) {
-	publicESHTTPCertificatesNSN := certificates.PublicCertsSecretRef(esv1.ESNamer, es)
+	return ReconcileCASecretWithNamer(client, associated, esv1.ESNamer, es, labels, suffix)
+}
+
+// ReconcileCASecretWithNamer keeps in sync a copy of the public HTTP certificates of the referenced resource,
+// whose secret name is built using the given namer (eg. Kibana).
+// It is the responsibility of the controller to set a watch on the referenced CA.
+func ReconcileCASecretWithNamer(
+	client k8s.Client,
+	associated commonv1.Associated,
+	namer name.Namer,
+	referenced types.NamespacedName,
+	labels map[string]string,
+	suffix string,
+) (CASecret, error) {
+	publicHTTPCertificatesNSN := certificates.PublicCertsSecretRef(namer, referenced)
 
-	// retrieve the HTTP certificates from ES namespace
-	var publicESHTTPCertificatesSecret corev1.Secret
-	if err := client.Get(publicESHTTPCertificatesNSN, &publicESHTTPCertificatesSecret); err != nil {
+	// retrieve the HTTP certificates from the referenced resource namespace
+	var publicHTTPCertificatesSecret corev1.Secret
+	if err := client.Get(publicHTTPCertificatesNSN, &publicHTTPCertificatesSecret); err != nil {
 		if errors.IsNotFound(err) {
 			return CASecret{}, nil // probably not created yet, we'll be notified to reconcile later
 		}
@@ -56,9 +71,9 @@ func ReconcileCASecret(
 			Name:      ElasticsearchCACertSecretName(associated, suffix),
 			Labels:    labels,
 		},
-		Data: publicESHTTPCertificatesSecret.Data,
+		Data: publicHTTPCertificatesSecret.Data,
 	}
-	if _, err := reconciler.ReconcileSecret(client, expectedSecret, associated); err != nil {
+	if _, err := reconciler.ReconcileSecret(client, expectedSecret, owner(associated)); err != nil {
 		return CASecret{}, err
 	}
 
@@ -0,0 +1,148 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.elastic.co/apm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates/clientauth"
+	eslabel "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	esuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	esvolume "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// ClientCertificateCheckInterval is the interval at which an association checks whether the Elasticsearch Pods
+// have been restarted with client authentication enabled.
+const ClientCertificateCheckInterval = 30 * time.Second
+
+// ClientCertificateAuthReady returns true if the resources associated to the given Elasticsearch cluster can
+// authenticate with a client certificate: client authentication is enabled, and all the Elasticsearch Pods have
+// been restarted to request client certificates.
+func ClientCertificateAuthReady(c k8s.Client, es esv1.Elasticsearch) (bool, error) {
+	if !es.Spec.HTTP.TLS.ClientAuthenticationEnabled() {
+		return false, nil
+	}
+	var pods corev1.PodList
+	if err := c.List(&pods, client.InNamespace(es.Namespace), eslabel.NewLabelSelectorForElasticsearch(es)); err != nil {
+		return false, err
+	}
+	if len(pods.Items) == 0 {
+		return false, nil
+	}
+	for _, pod := range pods.Items {
+		mounted := false
+		for _, volume := range pod.Spec.Volumes {
+			if volume.Name == esvolume.HTTPClientCertificatesSecretVolumeName {
+				mounted = true
+				break
+			}
+		}
+		if !mounted {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ReconcileClientCertificate issues a client certificate for the associated resource from the client CA of the given
+// Elasticsearch cluster, and maps the given comma separated roles to it in the PKI realm. The certificate and its
+// private key are stored in a secret in the namespace of the associated resource, selected by the returned
+// SecretKeySelector. The returned duration is the time after which the certificate must be renewed.
+func ReconcileClientCertificate(
+	ctx context.Context,
+	c k8s.Client,
+	associated commonv1.Associated,
+	labels map[string]string,
+	userRoles string,
+	userObjectSuffix string,
+	es esv1.Elasticsearch,
+	rotation certificates.RotationParams,
+	now time.Time,
+) (*corev1.SecretKeySelector, time.Duration, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_es_client_certificate", tracing.SpanTypeApp)
+	defer span.End()
+
+	// Add the Elasticsearch name, this is only intended to help the user to filter on these resources
+	labels[eslabel.ClusterNameLabelName] = es.Name
+
+	var caSecret corev1.Secret
+	if err := c.Get(clientauth.CASecretKey(k8s.ExtractNamespacedName(&es)), &caSecret); err != nil {
+		// not created yet by the Elasticsearch controller
+		return nil, 0, err
+	}
+	ca := certificates.BuildCAFromSecret(caSecret)
+	if ca == nil {
+		return nil, 0, fmt.Errorf("cannot parse the client CA in secret %s/%s", caSecret.Namespace, caSecret.Name)
+	}
+
+	secKey := secretKey(associated, userObjectSuffix)
+	userName := elasticsearchUserName(associated, userObjectSuffix)
+	var existingSecret corev1.Secret
+	if err := c.Get(secKey, &existingSecret); err != nil && !apierrors.IsNotFound(err) {
+		return nil, 0, err
+	}
+	clientCert, err := certificates.EnsureClientCertificate(ca, userName, existingSecret.Data, rotation, now)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	expectedSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secKey.Name,
+			Namespace: secKey.Namespace,
+			Labels:    common.AddCredentialsLabel(labels),
+		},
+		Data: map[string][]byte{
+			certificates.CertFileName: clientCert.CertPem,
+			certificates.KeyFileName:  clientCert.KeyPem,
+		},
+	}
+	if _, err := reconciler.ReconcileSecret(c, expectedSecret, owner(associated)); err != nil {
+		return nil, 0, err
+	}
+
+	// merge the association labels provided by the controller with the one needed for a user, so that the secret
+	// is garbage collected along with the association
+	userLabels := esuser.AssociatedUserLabels(es)
+	for key, value := range labels {
+		userLabels[key] = value
+	}
+	userSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      userName,
+			Namespace: es.Namespace,
+			Labels:    userLabels,
+		},
+		Data: map[string][]byte{
+			esuser.UserNameField:      []byte(userName),
+			esuser.UserRolesField:     []byte(userRoles),
+			esuser.CertificateDNField: []byte(certificates.ClientCertificateDN(userName)),
+		},
+	}
+	esOwner := es // secret is owned by the es resource in es namespace
+	if _, err := reconciler.ReconcileSecret(c, userSecret, &esOwner); err != nil {
+		return nil, 0, err
+	}
+
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: secKey.Name},
+		Key:                  certificates.CertFileName,
+	}, certificates.ShouldRotateIn(now, clientCert.Cert.NotAfter, rotation.RotateBefore), nil
+}
@@ -0,0 +1,137 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates/clientauth"
+	eslabel "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	esuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	esvolume "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func esPod(name string, clientAuthentication bool) *corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: esFixture.Namespace,
+			Name:      name,
+			Labels:    map[string]string{eslabel.ClusterNameLabelName: esFixture.Name},
+		},
+		Spec: corev1.PodSpec{Volumes: []corev1.Volume{{Name: esvolume.HTTPCertificatesSecretVolumeName}}},
+	}
+	if clientAuthentication {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{Name: esvolume.HTTPClientCertificatesSecretVolumeName})
+	}
+	return &pod
+}
+
+func TestClientCertificateAuthReady(t *testing.T) {
+	esWithClientAuth := esFixture
+	esWithClientAuth.Spec.HTTP.TLS.Client = &commonv1.ClientAuthentication{}
+	tests := []struct {
+		name string
+		pods []runtime.Object
+		es   bool
+		want bool
+	}{
+		{name: "client authentication disabled", pods: []runtime.Object{esPod("es-0", true)}, es: false, want: false},
+		{name: "no Pods yet", es: true, want: false},
+		{name: "Pods not restarted yet", pods: []runtime.Object{esPod("es-0", true), esPod("es-1", false)}, es: true, want: false},
+		{name: "all Pods restarted", pods: []runtime.Object{esPod("es-0", true), esPod("es-1", true)}, es: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := esFixture
+			if tt.es {
+				es = esWithClientAuth
+			}
+			got, err := ClientCertificateAuthReady(k8s.WrappedFakeClient(tt.pods...), es)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReconcileClientCertificate(t *testing.T) {
+	now := time.Now()
+	es := esFixture
+	kibana := kibanaFixture
+	ca, err := certificates.NewSelfSignedCA(certificates.CABuilderOptions{Subject: pkix.Name{CommonName: "client-ca"}})
+	require.NoError(t, err)
+	caSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: es.Namespace,
+			Name:      clientauth.CASecretKey(k8s.ExtractNamespacedName(&es)).Name,
+		},
+		Data: map[string][]byte{
+			certificates.CertFileName: certificates.EncodePEMCert(ca.Cert.Raw),
+			certificates.KeyFileName:  certificates.EncodePEMPrivateKey(*ca.PrivateKey),
+		},
+	}
+	// the file realm user Kibana relied on before
+	userSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: userName, Labels: esuser.AssociatedUserLabels(es)},
+		Data: map[string][]byte{
+			esuser.UserNameField:     []byte(userName),
+			esuser.PasswordHashField: []byte("hash"),
+			esuser.UserRolesField:    []byte("kibana_system"),
+		},
+	}
+	c := k8s.WrappedFakeClient(&caSecret, &userSecret)
+	rotation := certificates.RotationParams{Validity: 10 * time.Hour, RotateBefore: time.Hour}
+	reconcile := func(at time.Time) (*corev1.SecretKeySelector, time.Duration) {
+		selector, requeueAfter, err := ReconcileClientCertificate(
+			context.Background(), c, &kibana, map[string]string{}, "kibana_system", "kibana-user", es, rotation, at,
+		)
+		require.NoError(t, err)
+		return selector, requeueAfter
+	}
+	secretData := func(name string) map[string][]byte {
+		var secret corev1.Secret
+		require.NoError(t, c.Get(types.NamespacedName{Namespace: "default", Name: name}, &secret))
+		return secret.Data
+	}
+
+	selector, requeueAfter := reconcile(now)
+	require.Equal(t, &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: userSecretName},
+		Key:                  certificates.CertFileName,
+	}, selector)
+	require.Equal(t, 9*time.Hour, requeueAfter.Round(time.Hour))
+	// the certificate is issued to the user by the client CA
+	cert, err := certificates.GetPrimaryCertificate(secretData(userSecretName)[certificates.CertFileName])
+	require.NoError(t, err)
+	require.Equal(t, userName, cert.Subject.CommonName)
+	require.Equal(t, ca.Cert.Subject, cert.Issuer)
+	require.NotEmpty(t, secretData(userSecretName)[certificates.KeyFileName])
+	// the user is mapped to its roles by distinguished name instead of authenticating with a password
+	require.Equal(t, map[string][]byte{
+		esuser.UserNameField:      []byte(userName),
+		esuser.UserRolesField:     []byte("kibana_system"),
+		esuser.CertificateDNField: []byte("CN=" + userName),
+	}, secretData(userName))
+
+	// the certificate is reused while valid
+	certPem := secretData(userSecretName)[certificates.CertFileName]
+	reconcile(now.Add(time.Hour))
+	require.Equal(t, certPem, secretData(userSecretName)[certificates.CertFileName])
+
+	// the certificate is renewed before it expires
+	reconcile(now.Add(9 * time.Hour))
+	require.NotEqual(t, certPem, secretData(userSecretName)[certificates.CertFileName])
+}
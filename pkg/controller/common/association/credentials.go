@@ -0,0 +1,323 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.elastic.co/apm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates/clientauth"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	eslabel "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
+	esuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/net"
+)
+
+const (
+	// serviceAccountTokenSecretKey is the key of the service account token in the auth secret.
+	serviceAccountTokenSecretKey = "token"
+	// apiKeySecretKey is the key of the API key in the auth secret.
+	apiKeySecretKey = "api-key"
+
+	credentialsNameAnnotation            = "association.k8s.elastic.co/credentials-name"
+	previousCredentialsNameAnnotation    = "association.k8s.elastic.co/previous-credentials-name"
+	credentialsCreatedAtAnnotation       = "association.k8s.elastic.co/credentials-created-at"
+	credentialsRotationTriggerAnnotation = "association.k8s.elastic.co/credentials-rotation-trigger"
+)
+
+// CredentialsSpec describes the credentials an associated resource authenticates with instead of a file realm user.
+type CredentialsSpec struct {
+	// AuthType is either commonv1.ServiceAccountTokenAuth or commonv1.APIKeyAuth.
+	AuthType commonv1.AuthType
+	// ServiceAccount is the service account tokens are created for.
+	ServiceAccount string
+	// Role is the predefined role API keys are restricted to.
+	Role string
+}
+
+// ESClientProvider returns a client for the given Elasticsearch cluster, authenticated as the operator user and
+// presenting the client certificate of the operator if client authentication is enabled.
+type ESClientProvider func(c k8s.Client, es esv1.Elasticsearch) (esclient.Client, error)
+
+// NewESClientProvider returns an ESClientProvider creating TCP connections with the given dialer.
+func NewESClientProvider(dialer net.Dialer) ESClientProvider {
+	return func(c k8s.Client, es esv1.Elasticsearch) (esclient.Client, error) {
+		var usersSecret corev1.Secret
+		usersKey := types.NamespacedName{Namespace: es.Namespace, Name: esv1.InternalUsersSecret(es.Name)}
+		if err := c.Get(usersKey, &usersSecret); err != nil {
+			return nil, err
+		}
+		// the controller user may have been renamed by a rotation of the credentials
+		user, exists := esuser.UserCredentials(usersSecret, esuser.ControllerUserName)
+		if !exists {
+			return nil, fmt.Errorf("no password for user %s in secret %s", esuser.ControllerUserName, usersKey)
+		}
+
+		var certsSecret corev1.Secret
+		if err := c.Get(certificates.PublicCertsSecretRef(esv1.ESNamer, k8s.ExtractNamespacedName(&es)), &certsSecret); err != nil {
+			return nil, err
+		}
+		caPEM := certsSecret.Data[certificates.CAFileName]
+		if len(caPEM) == 0 {
+			// user-provided certificate without CA
+			caPEM = certsSecret.Data[certificates.CertFileName]
+		}
+		caCerts, err := certificates.ParsePEMCerts(caPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := version.Parse(es.Spec.Version)
+		if err != nil {
+			return nil, err
+		}
+		clientCert, err := clientauth.OperatorCertificate(c, es)
+		if err != nil {
+			return nil, err
+		}
+		return esclient.NewElasticsearchClientWithCertificate(dialer, services.ExternalServiceURL(es), user, *v, caCerts, clientCert), nil
+	}
+}
+
+// SupportsCredentials returns true if an associated resource running the given version can authenticate against
+// the given Elasticsearch cluster with a service account token or an API key instead of a file realm user.
+func SupportsCredentials(es esv1.Elasticsearch, associatedVersion string) bool {
+	// API keys are disabled by default if TLS is disabled on the HTTP layer
+	if !es.Spec.HTTP.TLS.Enabled() {
+		return false
+	}
+	esVersion, err := version.Parse(es.Spec.Version)
+	if err != nil {
+		return false
+	}
+	v, err := version.Parse(associatedVersion)
+	if err != nil {
+		return false
+	}
+	return esVersion.IsSameOrAfter(esuser.MinAssociationCredentialsVersion) &&
+		v.IsSameOrAfter(esuser.MinAssociationCredentialsVersion)
+}
+
+// ReconcileCredentials creates a service account token or an API key for the associated resource, and rotates it
+// as the file realm users of the given Elasticsearch cluster. The credentials are stored in a secret in the
+// namespace of the associated resource, selected by the returned SecretKeySelector.
+// The credentials in use are referenced by a secret in the Elasticsearch namespace, the Elasticsearch controller
+// revokes the ones which are not referenced anymore.
+// A non-zero duration is returned if the credentials have to be reconciled again after that time.
+func ReconcileCredentials(
+	ctx context.Context,
+	c k8s.Client,
+	newESClient ESClientProvider,
+	associated commonv1.Associated,
+	labels map[string]string,
+	userObjectSuffix string,
+	es esv1.Elasticsearch,
+	spec CredentialsSpec,
+	now time.Time,
+) (*corev1.SecretKeySelector, time.Duration, error) {
+	span, ctx := apm.StartSpan(ctx, "reconcile_es_credentials", tracing.SpanTypeApp)
+	defer span.End()
+
+	// Add the Elasticsearch name, this is only intended to help the user to filter on these resources
+	labels[eslabel.ClusterNameLabelName] = es.Name
+
+	secKey := secretKey(associated, userObjectSuffix)
+	userName := elasticsearchUserName(associated, userObjectSuffix)
+	dataKey := apiKeySecretKey
+	if spec.AuthType == commonv1.ServiceAccountTokenAuth {
+		dataKey = serviceAccountTokenSecretKey
+	}
+
+	var existingSecret corev1.Secret
+	if err := c.Get(secKey, &existingSecret); err != nil && !apierrors.IsNotFound(err) {
+		return nil, 0, err
+	}
+	credentials := existingSecret.Data[dataKey]
+	name := existingSecret.Annotations[credentialsNameAnnotation]
+	previousName := existingSecret.Annotations[previousCredentialsNameAnnotation]
+	trigger := existingSecret.Annotations[credentialsRotationTriggerAnnotation]
+	createdAt, err := time.Parse(time.RFC3339, existingSecret.Annotations[credentialsCreatedAtAnnotation])
+
+	if len(credentials) == 0 || name == "" || err != nil || credentialsRotationDue(es, trigger, createdAt, now) {
+		newName := esuser.AssociationCredentialsName(userName, now)
+		// reference the new credentials before creating them, so they are not revoked by the Elasticsearch controller
+		if err := reconcileCredentialsUserSecret(c, associated, labels, userName, es, true, newName, name); err != nil {
+			return nil, 0, err
+		}
+		credentials, err = createCredentials(ctx, c, newESClient, es, spec, newName)
+		if err != nil {
+			return nil, 0, err
+		}
+		log.Info("Created Elasticsearch credentials", "namespace", associated.GetNamespace(),
+			"associated_name", associated.GetName(), "credentials_name", newName, "previous_credentials_name", name)
+		name, previousName, createdAt = newName, name, now
+		trigger = es.Annotations[esuser.RotateCredentialsAnnotation]
+	}
+
+	// the previous credentials, or the file realm user the associated resource relied on before, remain valid
+	// while the associated resource is restarted with the new credentials
+	requeueAfter := time.Duration(0)
+	inGracePeriod := false
+	if remaining := createdAt.Add(esuser.CredentialsGracePeriod).Sub(now); remaining > 0 {
+		inGracePeriod = true
+		requeueAfter = remaining
+	} else {
+		// the previous credentials are not needed anymore
+		previousName = ""
+	}
+	if maxAge := es.Spec.Auth.Rotation.MaxAgeOrZero(); maxAge > 0 {
+		if remaining := createdAt.Add(maxAge).Sub(now); requeueAfter == 0 || remaining < requeueAfter {
+			requeueAfter = remaining
+		}
+	}
+
+	expectedSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secKey.Name,
+			Namespace: secKey.Namespace,
+			Labels:    common.AddCredentialsLabel(labels),
+			Annotations: map[string]string{
+				credentialsNameAnnotation:            name,
+				previousCredentialsNameAnnotation:    previousName,
+				credentialsCreatedAtAnnotation:       createdAt.UTC().Format(time.RFC3339),
+				credentialsRotationTriggerAnnotation: trigger,
+			},
+		},
+		Data: map[string][]byte{dataKey: credentials},
+	}
+	if _, err := reconciler.ReconcileSecret(c, expectedSecret, owner(associated)); err != nil {
+		return nil, 0, err
+	}
+
+	if err := reconcileCredentialsUserSecret(c, associated, labels, userName, es, inGracePeriod, name, previousName); err != nil {
+		return nil, 0, err
+	}
+
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: secKey.Name},
+		Key:                  dataKey,
+	}, requeueAfter, nil
+}
+
+// credentialsRotationDue returns true if the credentials of an associated resource, created at the given time
+// after the given rotation request, must be rotated as requested on the Elasticsearch resource.
+func credentialsRotationDue(es esv1.Elasticsearch, trigger string, createdAt, now time.Time) bool {
+	requested := es.Annotations[esuser.RotateCredentialsAnnotation]
+	if requested != "" && requested != trigger {
+		return true
+	}
+	maxAge := es.Spec.Auth.Rotation.MaxAgeOrZero()
+	return maxAge > 0 && !now.Before(createdAt.Add(maxAge))
+}
+
+// reconcileCredentialsUserSecret reconciles the secret referencing the credentials in use by the associated resource
+// in the Elasticsearch namespace. It replaces the secret of the file realm user the associated resource relied on,
+// unless keepUser is true: the file realm user is kept during the grace period following the creation of the
+// credentials, since the associated resource uses it until restarted.
+func reconcileCredentialsUserSecret(
+	c k8s.Client,
+	associated commonv1.Associated,
+	labels map[string]string,
+	userName string,
+	es esv1.Elasticsearch,
+	keepUser bool,
+	credentialsNames ...string,
+) error {
+	// merge the association labels provided by the controller with the one needed for a user, so that the secret
+	// is garbage collected along with the association
+	userLabels := esuser.AssociatedUserLabels(es)
+	for key, value := range labels {
+		userLabels[key] = value
+	}
+
+	expected := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      userName,
+			Namespace: es.Namespace,
+			Labels:    userLabels,
+		},
+		Data: map[string][]byte{},
+	}
+	if keepUser {
+		// the associated resource may rely on the file realm user until the new credentials are created
+		var existing corev1.Secret
+		if err := c.Get(k8s.ExtractNamespacedName(&expected), &existing); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		for key, value := range existing.Data {
+			expected.Data[key] = value
+		}
+	}
+	var names []string
+	for _, name := range credentialsNames {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	expected.Data[esuser.CredentialsField] = []byte(strings.Join(names, ","))
+
+	owner := es // secret is owned by the es resource in es namespace
+	_, err := reconciler.ReconcileSecret(c, expected, &owner)
+	return err
+}
+
+// createCredentials creates a service account token or an API key with the given name.
+func createCredentials(
+	ctx context.Context,
+	c k8s.Client,
+	newESClient ESClientProvider,
+	es esv1.Elasticsearch,
+	spec CredentialsSpec,
+	name string,
+) ([]byte, error) {
+	esClient, err := newESClient(c, es)
+	if err != nil {
+		return nil, err
+	}
+	defer esClient.Close()
+	ctx, cancel := context.WithTimeout(ctx, esclient.DefaultReqTimeout)
+	defer cancel()
+
+	switch spec.AuthType {
+	case commonv1.ServiceAccountTokenAuth:
+		token, err := esClient.CreateServiceAccountToken(ctx, spec.ServiceAccount, name)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(token.Value), nil
+	case commonv1.APIKeyAuth:
+		roleDescriptor, err := esuser.RoleDescriptor(spec.Role)
+		if err != nil {
+			return nil, err
+		}
+		key, err := esClient.CreateAPIKey(ctx, esclient.CreateAPIKeyRequest{
+			Name:            name,
+			RoleDescriptors: map[string]esclient.RoleDescriptor{spec.Role: roleDescriptor},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []byte(key.Credentials()), nil
+	default:
+		return nil, fmt.Errorf("unsupported auth type %q", spec.AuthType)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package association
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	esuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func TestSupportsCredentials(t *testing.T) {
+	es := func(v string, tlsDisabled bool) esv1.Elasticsearch {
+		es := esv1.Elasticsearch{Spec: esv1.ElasticsearchSpec{Version: v}}
+		if tlsDisabled {
+			es.Spec.HTTP.TLS.SelfSignedCertificate = &commonv1.SelfSignedCertificate{Disabled: true}
+		}
+		return es
+	}
+	tests := []struct {
+		name              string
+		es                esv1.Elasticsearch
+		associatedVersion string
+		want              bool
+	}{
+		{name: "supported versions", es: es("7.13.0", false), associatedVersion: "7.14.1", want: true},
+		{name: "Elasticsearch too old", es: es("7.12.1", false), associatedVersion: "7.13.0", want: false},
+		{name: "associated resource too old", es: es("7.13.0", false), associatedVersion: "7.12.1", want: false},
+		{name: "TLS disabled", es: es("7.13.0", true), associatedVersion: "7.13.0", want: false},
+		{name: "invalid version", es: es("7.13.0", false), associatedVersion: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, SupportsCredentials(tt.es, tt.associatedVersion))
+		})
+	}
+}
+
+func TestReconcileCredentials(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	es := esFixture
+	es.Spec.Version = "7.13.0"
+	kibana := kibanaFixture
+	// the file realm user Kibana relies on before the token is created
+	userSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: userName, Labels: esuser.AssociatedUserLabels(es)},
+		Data: map[string][]byte{
+			esuser.UserNameField:     []byte(userName),
+			esuser.PasswordHashField: []byte("hash"),
+			esuser.UserRolesField:    []byte("kibana_system"),
+		},
+	}
+	c := k8s.WrappedFakeClient(&userSecret)
+
+	var createErr error
+	var createdTokens []string
+	newESClient := func(k8s.Client, esv1.Elasticsearch) (esclient.Client, error) {
+		return esclient.NewMockClient(version.MustParse("7.13.0"), func(req *http.Request) *http.Response {
+			if createErr != nil {
+				return esclient.NewMockResponse(500, req, `{}`)
+			}
+			name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+			require.Equal(t, "/_security/service/elastic/kibana/credential/token/"+name, req.URL.Path)
+			createdTokens = append(createdTokens, name)
+			return esclient.NewMockResponse(200, req, `{"created":true,"token":{"name":"`+name+`","value":"value-`+name+`"}}`)
+		}), nil
+	}
+	spec := CredentialsSpec{AuthType: commonv1.ServiceAccountTokenAuth, ServiceAccount: esuser.KibanaServiceAccount}
+	reconcile := func(at time.Time) (*corev1.SecretKeySelector, time.Duration, error) {
+		return ReconcileCredentials(context.Background(), c, newESClient, &kibana, map[string]string{}, "kibana-user", es, spec, at)
+	}
+	secretData := func(key types.NamespacedName) map[string][]byte {
+		var secret corev1.Secret
+		require.NoError(t, c.Get(key, &secret))
+		return secret.Data
+	}
+	userSecretKey := types.NamespacedName{Namespace: "default", Name: userName}
+	tokenSecretKey := types.NamespacedName{Namespace: "default", Name: userSecretName}
+
+	// Elasticsearch does not accept the request yet: the file realm user is kept
+	createErr = errors.New("unavailable")
+	_, _, err := reconcile(now)
+	require.Error(t, err)
+	require.Equal(t, []byte("hash"), secretData(userSecretKey)[esuser.PasswordHashField])
+	require.Equal(t, "eck-default-kibana-foo-kibana-user-1622505600", string(secretData(userSecretKey)[esuser.CredentialsField]))
+
+	// the token is created, the file realm user is kept while Kibana is restarted with the token
+	createErr = nil
+	selector, requeueAfter, err := reconcile(now)
+	require.NoError(t, err)
+	require.Equal(t, esuser.CredentialsGracePeriod, requeueAfter)
+	require.Equal(t, &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: userSecretName},
+		Key:                  "token",
+	}, selector)
+	firstToken := "eck-default-kibana-foo-kibana-user-1622505600"
+	require.Equal(t, map[string][]byte{"token": []byte("value-" + firstToken)}, secretData(tokenSecretKey))
+	require.Equal(t, []byte("hash"), secretData(userSecretKey)[esuser.PasswordHashField])
+	require.Equal(t, firstToken, string(secretData(userSecretKey)[esuser.CredentialsField]))
+
+	// the token is reused, and replaces the file realm user after the grace period
+	_, requeueAfter, err = reconcile(now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), requeueAfter)
+	require.Equal(t, []string{firstToken}, createdTokens)
+	require.Equal(t, map[string][]byte{esuser.CredentialsField: []byte(firstToken)}, secretData(userSecretKey))
+
+	// rotation requested: the previous token remains valid during the grace period
+	es.Annotations = map[string]string{esuser.RotateCredentialsAnnotation: "2021-06-01"}
+	rotatedAt := now.Add(2 * time.Hour)
+	_, requeueAfter, err = reconcile(rotatedAt)
+	require.NoError(t, err)
+	require.Equal(t, esuser.CredentialsGracePeriod, requeueAfter)
+	secondToken := "eck-default-kibana-foo-kibana-user-1622512800"
+	require.Equal(t, []string{firstToken, secondToken}, createdTokens)
+	require.Equal(t, map[string][]byte{"token": []byte("value-" + secondToken)}, secretData(tokenSecretKey))
+	require.Equal(t, secondToken+","+firstToken, string(secretData(userSecretKey)[esuser.CredentialsField]))
+	// the rotation is not requested again
+	_, _, err = reconcile(rotatedAt.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, createdTokens, 2)
+
+	// the previous token is not referenced anymore after the grace period
+	_, requeueAfter, err = reconcile(rotatedAt.Add(esuser.CredentialsGracePeriod))
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), requeueAfter)
+	require.Equal(t, secondToken, string(secretData(userSecretKey)[esuser.CredentialsField]))
+
+	// the token is rotated once it reaches the max age
+	es.Spec.Auth.Rotation = &esv1.CredentialsRotation{MaxAge: &metav1.Duration{Duration: 24 * time.Hour}}
+	_, requeueAfter, err = reconcile(rotatedAt.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 23*time.Hour, requeueAfter)
+	require.Len(t, createdTokens, 2)
+	_, _, err = reconcile(rotatedAt.Add(24 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, createdTokens, 3)
+}
@@ -48,7 +48,7 @@ func CheckAndUnbind(
 			"remote_name", metaObject.GetName(),
 		)
 		eventRecorder.Eventf(
-			associated,
+			unwrap(associated),
 			corev1.EventTypeWarning,
 			events.EventAssociationError,
 			"Association not allowed: %s/%s to %s/%s",
@@ -6,6 +6,7 @@ package association
 
 import (
 	"context"
+	"time"
 
 	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
@@ -60,17 +61,8 @@ func secretKey(associated commonv1.Associated, userSuffix string) types.Namespac
 	}
 }
 
-// ClearTextSecretKeySelector creates a SecretKeySelector for the associated user secret
-func ClearTextSecretKeySelector(associated commonv1.Associated, userSuffix string) *corev1.SecretKeySelector {
-	return &corev1.SecretKeySelector{
-		LocalObjectReference: corev1.LocalObjectReference{
-			Name: userSecretObjectName(associated, userSuffix),
-		},
-		Key: elasticsearchUserName(associated, userSuffix),
-	}
-}
-
 // ReconcileEsUser creates a User resource and a corresponding secret or updates those as appropriate.
+// It returns a SecretKeySelector for the secret holding the password of the user, under the user name key.
 func ReconcileEsUser(
 	ctx context.Context,
 	c k8s.Client,
@@ -79,7 +71,7 @@ func ReconcileEsUser(
 	userRoles string,
 	userObjectSuffix string,
 	es esv1.Elasticsearch,
-) error {
+) (*corev1.SecretKeySelector, error) {
 	span, _ := apm.StartSpan(ctx, "reconcile_es_user", tracing.SpanTypeApp)
 	defer span.End()
 
@@ -87,7 +79,19 @@ func ReconcileEsUser(
 	labels[eslabel.ClusterNameLabelName] = es.Name
 
 	secKey := secretKey(associated, userObjectSuffix)
-	usrKey := UserKey(associated, userObjectSuffix)
+	// the user lives in the namespace of the given Elasticsearch cluster, which may not be the one referenced
+	// through ElasticsearchRef() (eg. the cluster backing a referenced Kibana)
+	usrKey := types.NamespacedName{Namespace: es.Namespace, Name: elasticsearchUserName(associated, userObjectSuffix)}
+	var existingUserSecret corev1.Secret
+	if err := c.Get(usrKey, &existingUserSecret); err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	// the user is renamed by the Elasticsearch controller when the credentials are rotated
+	userName := usrKey.Name
+	if existingName := string(existingUserSecret.Data[esuser.UserNameField]); esuser.IsUserName(existingName, usrKey.Name) {
+		userName = existingName
+	}
+
 	expectedSecret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      secKey.Name,
@@ -102,17 +106,26 @@ func ReconcileEsUser(
 	var existingSecret corev1.Secret
 	err := c.Get(k8s.ExtractNamespacedName(&expectedSecret), &existingSecret)
 	if err != nil && !apierrors.IsNotFound(err) {
-		return err
+		return nil, err
 	}
-	if existingPassword, exists := existingSecret.Data[usrKey.Name]; exists {
+	if existingPassword, exists := existingSecret.Data[userName]; exists {
 		password = existingPassword
 	} else {
 		password = common.FixedLengthRandomPasswordBytes()
 	}
-	expectedSecret.Data[usrKey.Name] = password
+	expectedSecret.Data[userName] = password
+	// keep the password of the user replaced by the last rotation during the grace period, the associated resource
+	// reads it until its configuration references the new user
+	if rotatedAt, rotated := esuser.UserRotatedAt(userName, usrKey.Name); rotated && time.Now().Before(rotatedAt.Add(esuser.CredentialsGracePeriod)) {
+		for name, previousPassword := range existingSecret.Data {
+			if name != userName && esuser.IsUserName(name, usrKey.Name) {
+				expectedSecret.Data[name] = previousPassword
+			}
+		}
+	}
 
-	if _, err := reconciler.ReconcileSecret(c, expectedSecret, associated); err != nil {
-		return err
+	if _, err := reconciler.ReconcileSecret(c, expectedSecret, owner(associated)); err != nil {
+		return nil, err
 	}
 
 	// analogous to the associated secret: a user Secret goes on the Elasticsearch side of the association
@@ -130,9 +143,11 @@ func ReconcileEsUser(
 			Name:      usrKey.Name,
 			Namespace: usrKey.Namespace,
 			Labels:    userLabels,
+			// allows the Elasticsearch controller to update the password when the credentials are rotated
+			Annotations: map[string]string{esuser.PasswordSecretAnnotation: secKey.String()},
 		},
 		Data: map[string][]byte{
-			esuser.UserNameField:  []byte(usrKey.Name),
+			esuser.UserNameField:  []byte(userName),
 			esuser.UserRolesField: []byte(userRoles),
 		},
 	}
@@ -140,11 +155,7 @@ func ReconcileEsUser(
 	// reuse the existing hash if valid
 	bcryptHash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
 	if err != nil {
-		return err
-	}
-	var existingUserSecret corev1.Secret
-	if err := c.Get(k8s.ExtractNamespacedName(&expectedEsUser), &existingUserSecret); err != nil && !apierrors.IsNotFound(err) {
-		return err
+		return nil, err
 	}
 	if existingHash, exists := existingUserSecret.Data[esuser.PasswordHashField]; exists {
 		if bcrypt.CompareHashAndPassword(existingHash, password) == nil {
@@ -154,6 +165,11 @@ func ReconcileEsUser(
 	expectedEsUser.Data[esuser.PasswordHashField] = bcryptHash
 
 	owner := es // user is owned by the es resource in es namespace
-	_, err = reconciler.ReconcileSecret(c, expectedEsUser, &owner)
-	return err
+	if _, err := reconciler.ReconcileSecret(c, expectedEsUser, &owner); err != nil {
+		return nil, err
+	}
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: secKey.Name},
+		Key:                  userName,
+	}, nil
 }
@@ -8,6 +8,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,6 +34,9 @@ const (
 	associationLabelNamespace = "association.k8s.elastic.co/namespace"
 )
 
+// rotatedUserName is the name of the user after a rotation of the credentials started a minute ago.
+var rotatedUserName = esuser.RotatedUserName(userName, time.Now().Add(-time.Minute))
+
 var esFixture = esv1.Elasticsearch{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "es-foo",
@@ -121,6 +125,37 @@ func Test_reconcileEsUser(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "User renamed by a rotation of the credentials: keep its name and the previous password",
+			args: args{
+				initialObjects: []runtime.Object{
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: userName, Namespace: "default"},
+						Data:       map[string][]byte{esuser.UserNameField: []byte(rotatedUserName)},
+					},
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: userSecretName, Namespace: "default"},
+						Data: map[string][]byte{
+							userName:        []byte("previous-password"),
+							rotatedUserName: []byte("new-password"),
+						},
+					},
+				},
+				kibana: kibanaFixture,
+				es:     esFixture,
+			},
+			postCondition: func(c k8s.Client) {
+				var esUser corev1.Secret
+				assert.NoError(t, c.Get(types.NamespacedName{Name: userName, Namespace: "default"}, &esUser))
+				ChecksUser(t, &esUser, rotatedUserName, []string{"kibana_system"})
+				var secret corev1.Secret
+				assert.NoError(t, c.Get(types.NamespacedName{Name: userSecretName, Namespace: "default"}, &secret))
+				assert.Equal(t, map[string][]byte{
+					userName:        []byte("previous-password"),
+					rotatedUserName: []byte("new-password"),
+				}, secret.Data)
+			},
+		},
 		{
 			name: "Existing secret but different namespace: create new",
 			args: args{
@@ -251,7 +286,7 @@ func Test_reconcileEsUser(t *testing.T) {
 	for _, tt := range tests {
 		c := k8s.WrappedFakeClient(tt.args.initialObjects...)
 		t.Run(tt.name, func(t *testing.T) {
-			if err := ReconcileEsUser(
+			if _, err := ReconcileEsUser(
 				context.Background(),
 				c,
 				&tt.args.kibana,
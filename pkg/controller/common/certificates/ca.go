@@ -26,6 +26,9 @@ type CA struct {
 	PrivateKey *rsa.PrivateKey
 	// Cert is the certificate used to issue new certificates
 	Cert *x509.Certificate
+	// Chain holds the certificates of the authorities which issued Cert, up to the root one, if Cert is an
+	// intermediate CA provided by the user
+	Chain []*x509.Certificate
 }
 
 // ValidatedCertificateTemplate is a type alias used to convey that the certificate template has been validated and
@@ -13,6 +13,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
@@ -27,6 +28,8 @@ const (
 	TransportCAType CAType = "transport"
 	// HTTPCAType is the CA used for HTTP certificates
 	HTTPCAType CAType = "http"
+	// ClientCAType is the CA used for the client certificates of the ES HTTP layer
+	ClientCAType CAType = "client"
 )
 
 const (
@@ -43,7 +46,8 @@ func CAInternalSecretName(namer name.Namer, ownerName string, caType CAType) str
 // The CA is persisted across operator restarts in the apiserver as a Secret for the CA certificate and private key:
 // `<clusterName>-<caType>-ca-internal`
 //
-// The CA cert and private key are rotated if they become invalid (or soon to expire).
+// The CA cert and private key are rotated if they become invalid (or soon to expire). An event is emitted on the owner
+// through the given recorder, if not nil, when the CA is rotated.
 func ReconcileCAForOwner(
 	cl k8s.Client,
 	namer name.Namer,
@@ -51,6 +55,7 @@ func ReconcileCAForOwner(
 	labels map[string]string,
 	caType CAType,
 	rotationParams RotationParams,
+	recorder record.EventRecorder,
 ) (*CA, error) {
 
 	// retrieve current CA secret
@@ -72,19 +77,37 @@ func ReconcileCAForOwner(
 	ca := BuildCAFromSecret(caInternalSecret)
 	if ca == nil {
 		log.Info("Cannot build CA from secret, creating a new one", "owner_namespace", owner.GetNamespace(), "owner_name", owner.GetName(), "ca_type", caType)
-		return renewCA(cl, namer, owner, labels, rotationParams.Validity, caType)
+		return rotateCA(cl, namer, owner, labels, rotationParams.Validity, caType, recorder)
 	}
 
 	// renew if cannot reuse
 	if !CanReuseCA(ca, rotationParams.RotateBefore) {
 		log.Info("Cannot reuse existing CA, creating a new one", "owner_namespace", owner.GetNamespace(), "owner_name", owner.GetName(), "ca_type", caType)
-		return renewCA(cl, namer, owner, labels, rotationParams.Validity, caType)
+		return rotateCA(cl, namer, owner, labels, rotationParams.Validity, caType, recorder)
 	}
 
 	// reuse existing CA
 	return ca, nil
 }
 
+// rotateCA replaces an existing CA by a new one, and emits an event on the owner.
+func rotateCA(
+	client k8s.Client,
+	namer name.Namer,
+	owner v1.Object,
+	labels map[string]string,
+	expireIn time.Duration,
+	caType CAType,
+	recorder record.EventRecorder,
+) (*CA, error) {
+	ca, err := renewCA(client, namer, owner, labels, expireIn, caType)
+	if err != nil {
+		return nil, err
+	}
+	RecordRotation(recorder, owner, CertificateType(string(caType)+"-ca"), "")
+	return ca, nil
+}
+
 // renewCA creates and stores a new CA to replace one that might exist
 func renewCA(
 	client k8s.Client,
@@ -288,7 +288,7 @@ func TestReconcileCAForCluster(t *testing.T) {
 				tt.cl, testNamer, &testCluster, nil, TransportCAType, RotationParams{
 					Validity:     tt.caCertValidity,
 					RotateBefore: DefaultRotateBefore,
-				},
+				}, nil,
 			)
 			require.NoError(t, err)
 			require.NotNil(t, ca)
@@ -0,0 +1,201 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
+)
+
+const (
+	// CertManagerGroup is the API group of the cert-manager resources.
+	CertManagerGroup = "cert-manager.io"
+	// IssuerKind and ClusterIssuerKind are the kinds of the cert-manager issuers.
+	IssuerKind        = "Issuer"
+	ClusterIssuerKind = "ClusterIssuer"
+)
+
+// CertManagerCertificateGVK is the GroupVersionKind of the cert-manager Certificate resource.
+var CertManagerCertificateGVK = schema.GroupVersionKind{Group: CertManagerGroup, Version: "v1", Kind: "Certificate"}
+
+// IssuerRef references the cert-manager Issuer or ClusterIssuer issuing the certificates of the operator.
+type IssuerRef struct {
+	Kind string
+	Name string
+}
+
+// ParseIssuerRef parses an issuer reference formatted as <kind>/<name>, where kind is Issuer or ClusterIssuer.
+// A nil reference is returned for an empty string.
+func ParseIssuerRef(ref string) (*IssuerRef, error) {
+	if ref == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid cert-manager issuer %q, expected <kind>/<name>", ref)
+	}
+	if parts[0] != IssuerKind && parts[0] != ClusterIssuerKind {
+		return nil, fmt.Errorf("invalid cert-manager issuer kind %q, expected %s or %s", parts[0], IssuerKind, ClusterIssuerKind)
+	}
+	return &IssuerRef{Kind: parts[0], Name: parts[1]}, nil
+}
+
+func (i IssuerRef) String() string {
+	return i.Kind + "/" + i.Name
+}
+
+// CertManagerCertificate describes a certificate issued by cert-manager.
+type CertManagerCertificate struct {
+	// Name of the cert-manager Certificate, also used for the Secret holding the issued certificate.
+	Name      string
+	Namespace string
+	Labels    map[string]string
+
+	Issuer      IssuerRef
+	CommonName  string
+	DNSNames    []string
+	IPAddresses []string
+	// Rotation defines the validity of the certificate and how long before its expiration cert-manager renews it.
+	Rotation RotationParams
+}
+
+// Unstructured returns the cert-manager Certificate resource.
+func (c CertManagerCertificate) Unstructured() *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"secretName": c.Name,
+		"commonName": c.CommonName,
+		"issuerRef": map[string]interface{}{
+			"group": CertManagerGroup,
+			"kind":  c.Issuer.Kind,
+			"name":  c.Issuer.Name,
+		},
+		"usages": []interface{}{"digital signature", "key encipherment", "server auth", "client auth"},
+		"privateKey": map[string]interface{}{
+			"algorithm": "RSA",
+			"encoding":  "PKCS1",
+			"size":      int64(2048),
+		},
+	}
+	if len(c.Labels) > 0 {
+		// labels of the Secret holding the issued certificate, used to find it with the other resources of the owner
+		labels := make(map[string]interface{}, len(c.Labels))
+		for k, v := range c.Labels {
+			labels[k] = v
+		}
+		spec["secretTemplate"] = map[string]interface{}{"labels": labels}
+	}
+	if len(c.DNSNames) > 0 {
+		spec["dnsNames"] = toInterfaces(c.DNSNames)
+	}
+	if len(c.IPAddresses) > 0 {
+		spec["ipAddresses"] = toInterfaces(c.IPAddresses)
+	}
+	if c.Rotation.Validity > 0 {
+		spec["duration"] = c.Rotation.Validity.String()
+	}
+	if c.Rotation.RotateBefore > 0 {
+		spec["renewBefore"] = c.Rotation.RotateBefore.String()
+	}
+
+	cert := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	cert.SetGroupVersionKind(CertManagerCertificateGVK)
+	cert.SetNamespace(c.Namespace)
+	cert.SetName(c.Name)
+	cert.SetLabels(c.Labels)
+	return cert
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// ReconcileCertManagerCertificate reconciles the cert-manager Certificate owned by the given object, and returns the
+// Secret holding the issued certificate. A nil Secret is returned if the certificate has not been issued yet.
+// cert-manager does not set owner references on the Secrets it creates by default: the given object is set as the
+// owner of the Secret, so that it is garbage collected with the owner.
+func ReconcileCertManagerCertificate(c k8s.Client, owner metav1.Object, cert CertManagerCertificate) (*corev1.Secret, error) {
+	expected := cert.Unstructured()
+	reconciled := &unstructured.Unstructured{}
+	reconciled.SetGroupVersionKind(CertManagerCertificateGVK)
+	if err := reconciler.ReconcileResource(reconciler.Params{
+		Client:     c,
+		Owner:      owner,
+		Expected:   expected,
+		Reconciled: reconciled,
+		NeedsUpdate: func() bool {
+			return !maps.IsSubset(expected.GetLabels(), reconciled.GetLabels()) ||
+				// ignore the fields defaulted by cert-manager
+				!equality.Semantic.DeepDerivative(expected.Object["spec"], reconciled.Object["spec"])
+		},
+		UpdateReconciled: func() {
+			reconciled.SetLabels(maps.Merge(reconciled.GetLabels(), expected.GetLabels()))
+			reconciled.Object["spec"] = expected.Object["spec"]
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	var secret corev1.Secret
+	err := c.Get(types.NamespacedName{Namespace: cert.Namespace, Name: cert.Name}, &secret)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := setSecretOwner(c, owner, &secret); err != nil {
+		return nil, err
+	}
+	if len(secret.Data[CertFileName]) == 0 || len(secret.Data[KeyFileName]) == 0 {
+		return nil, nil
+	}
+	return &secret, nil
+}
+
+// setSecretOwner sets the given owner as the controller of the Secret created by cert-manager, unless the Secret
+// already has a controller: cert-manager may be configured to set the Certificate as the owner of the Secret, in which
+// case the Secret is garbage collected with the Certificate.
+func setSecretOwner(c k8s.Client, owner metav1.Object, secret *corev1.Secret) error {
+	if metav1.GetControllerOf(secret) != nil {
+		return nil
+	}
+	if err := controllerutil.SetControllerReference(owner, secret, scheme.Scheme); err != nil {
+		return err
+	}
+	log.V(1).Info("Setting owner of the Secret issued by cert-manager",
+		"namespace", secret.Namespace, "secret_name", secret.Name, "owner_name", owner.GetName())
+	return c.Update(secret)
+}
+
+// DeleteCertManagerCertificate deletes the cert-manager Certificate with the given name, and the Secret holding the
+// certificate it issued.
+func DeleteCertManagerCertificate(c k8s.Client, ref types.NamespacedName) error {
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(CertManagerCertificateGVK)
+	cert.SetNamespace(ref.Namespace)
+	cert.SetName(ref.Name)
+	if err := c.Delete(cert); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return deleteIfExists(c, ref)
+}
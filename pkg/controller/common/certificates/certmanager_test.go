@@ -0,0 +1,112 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func TestParseIssuerRef(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    *IssuerRef
+		wantErr bool
+	}{
+		{ref: "", want: nil},
+		{ref: "Issuer/my-issuer", want: &IssuerRef{Kind: IssuerKind, Name: "my-issuer"}},
+		{ref: "ClusterIssuer/my-issuer", want: &IssuerRef{Kind: ClusterIssuerKind, Name: "my-issuer"}},
+		{ref: "my-issuer", wantErr: true},
+		{ref: "Issuer/", wantErr: true},
+		{ref: "Secret/my-issuer", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseIssuerRef(tt.ref)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReconcileCertManagerCertificate(t *testing.T) {
+	owner := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "owner"}}
+	cert := CertManagerCertificate{
+		Name:        "es-http-certs-issued",
+		Namespace:   "ns",
+		Labels:      map[string]string{"a": "b"},
+		Issuer:      IssuerRef{Kind: IssuerKind, Name: "my-issuer"},
+		CommonName:  "es.local",
+		DNSNames:    []string{"es.local", "es-http.ns.svc"},
+		IPAddresses: []string{"10.0.0.1"},
+		Rotation:    RotationParams{Validity: 48 * time.Hour, RotateBefore: time.Hour},
+	}
+	certRef := types.NamespacedName{Namespace: "ns", Name: "es-http-certs-issued"}
+	c := k8s.WrappedFakeClient()
+
+	getCertificate := func() *unstructured.Unstructured {
+		var reconciled unstructured.Unstructured
+		reconciled.SetGroupVersionKind(CertManagerCertificateGVK)
+		require.NoError(t, c.Get(certRef, &reconciled))
+		return &reconciled
+	}
+
+	// the Certificate is created, nothing is issued yet
+	issued, err := ReconcileCertManagerCertificate(c, owner, cert)
+	require.NoError(t, err)
+	require.Nil(t, issued)
+	reconciled := getCertificate()
+	require.Equal(t, "b", reconciled.GetLabels()["a"])
+	require.Equal(t, "owner", reconciled.GetOwnerReferences()[0].Name)
+	spec := reconciled.Object["spec"].(map[string]interface{})
+	require.Equal(t, "es-http-certs-issued", spec["secretName"])
+	require.Equal(t, []interface{}{"es.local", "es-http.ns.svc"}, spec["dnsNames"])
+	require.Equal(t, []interface{}{"10.0.0.1"}, spec["ipAddresses"])
+	require.Equal(t, "48h0m0s", spec["duration"])
+	require.Equal(t, "1h0m0s", spec["renewBefore"])
+	require.Equal(t, map[string]interface{}{"group": CertManagerGroup, "kind": IssuerKind, "name": "my-issuer"}, spec["issuerRef"])
+	require.Equal(t, map[string]interface{}{"labels": map[string]interface{}{"a": "b"}}, spec["secretTemplate"])
+
+	// the Certificate is updated on changes
+	cert.IPAddresses = []string{"10.0.0.2"}
+	_, err = ReconcileCertManagerCertificate(c, owner, cert)
+	require.NoError(t, err)
+	spec = getCertificate().Object["spec"].(map[string]interface{})
+	require.Equal(t, []interface{}{"10.0.0.2"}, spec["ipAddresses"])
+
+	// the issued certificate is returned once the Secret is populated
+	issuedSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es-http-certs-issued"},
+		Data:       map[string][]byte{CertFileName: []byte("cert"), KeyFileName: []byte("key")},
+	}
+	require.NoError(t, c.Create(&issuedSecret))
+	issued, err = ReconcileCertManagerCertificate(c, owner, cert)
+	require.NoError(t, err)
+	require.Equal(t, issuedSecret.Data, issued.Data)
+	// the owner is set on the Secret, to garbage collect it with the owner
+	var secretWithOwner corev1.Secret
+	require.NoError(t, c.Get(certRef, &secretWithOwner))
+	require.Equal(t, "owner", metav1.GetControllerOf(&secretWithOwner).Name)
+
+	// both the Certificate and the Secret are deleted
+	require.NoError(t, DeleteCertManagerCertificate(c, certRef))
+	var secret corev1.Secret
+	require.Error(t, c.Get(certRef, &secret))
+	var deleted unstructured.Unstructured
+	deleted.SetGroupVersionKind(CertManagerCertificateGVK)
+	require.Error(t, c.Get(certRef, &deleted))
+}
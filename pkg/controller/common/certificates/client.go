@@ -0,0 +1,113 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ClientCertificate is a certificate authenticating a client, along with its private key.
+type ClientCertificate struct {
+	// CertPem is the PEM-encoded certificate.
+	CertPem []byte
+	// KeyPem is the PEM-encoded private key.
+	KeyPem []byte
+	// Cert is the parsed certificate.
+	Cert *x509.Certificate
+}
+
+// ClientCertificateDN returns the distinguished name of the client certificates issued for the given common name,
+// as expected in a role mapping of a PKI realm.
+func ClientCertificateDN(commonName string) string {
+	return pkix.Name{CommonName: commonName}.String()
+}
+
+// EnsureClientCertificate returns the client certificate found in the given secret data under the tls.crt and
+// tls.key keys if it was issued by the given CA for the given common name and is not about to expire.
+// Otherwise, a new client certificate is issued by the CA.
+func EnsureClientCertificate(
+	ca *CA,
+	commonName string,
+	secretData map[string][]byte,
+	rotation RotationParams,
+	now time.Time,
+) (ClientCertificate, error) {
+	if existing, ok := validClientCertificate(ca, commonName, secretData, rotation, now); ok {
+		return existing, nil
+	}
+
+	privateKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+	if err != nil {
+		return ClientCertificate{}, errors.Wrap(err, "unable to generate the private key")
+	}
+	notAfter := now.Add(rotation.Validity)
+	if notAfter.After(ca.Cert.NotAfter) {
+		// the certificate cannot outlive its CA
+		notAfter = ca.Cert.NotAfter
+	}
+	certData, err := ca.CreateCertificate(ValidatedCertificateTemplate(x509.Certificate{
+		Subject:            pkix.Name{CommonName: commonName},
+		NotBefore:          now.Add(-10 * time.Minute),
+		NotAfter:           notAfter,
+		PublicKeyAlgorithm: x509.RSA,
+		PublicKey:          privateKey.Public(),
+		SignatureAlgorithm: x509.SHA256WithRSA,
+		KeyUsage:           x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:        []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}))
+	if err != nil {
+		return ClientCertificate{}, err
+	}
+	cert, err := x509.ParseCertificate(certData)
+	if err != nil {
+		return ClientCertificate{}, err
+	}
+	return ClientCertificate{
+		CertPem: EncodePEMCert(certData),
+		KeyPem:  EncodePEMPrivateKey(*privateKey),
+		Cert:    cert,
+	}, nil
+}
+
+// validClientCertificate parses the client certificate in the given secret data and returns true if it can be reused.
+func validClientCertificate(
+	ca *CA,
+	commonName string,
+	secretData map[string][]byte,
+	rotation RotationParams,
+	now time.Time,
+) (ClientCertificate, bool) {
+	certPem, keyPem := secretData[CertFileName], secretData[KeyFileName]
+	if len(certPem) == 0 || len(keyPem) == 0 {
+		return ClientCertificate{}, false
+	}
+	cert, err := GetPrimaryCertificate(certPem)
+	if err != nil || cert.Subject.CommonName != commonName {
+		return ClientCertificate{}, false
+	}
+	privateKey, err := ParsePEMPrivateKey(keyPem)
+	if err != nil || !PrivateMatchesPublicKey(cert.PublicKey, *privateKey) {
+		return ClientCertificate{}, false
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:       pool,
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return ClientCertificate{}, false
+	}
+	if !now.Before(cert.NotAfter.Add(-rotation.RotateBefore)) {
+		return ClientCertificate{}, false
+	}
+	return ClientCertificate{CertPem: certPem, KeyPem: keyPem, Cert: cert}, true
+}
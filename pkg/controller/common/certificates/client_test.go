@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCertificateDN(t *testing.T) {
+	require.Equal(t, "CN=ns-kb-kibana-user", ClientCertificateDN("ns-kb-kibana-user"))
+}
+
+func TestEnsureClientCertificate(t *testing.T) {
+	now := time.Now()
+	rotation := RotationParams{Validity: 10 * time.Hour, RotateBefore: time.Hour}
+	secretData := func(cert ClientCertificate) map[string][]byte {
+		return map[string][]byte{CertFileName: cert.CertPem, KeyFileName: cert.KeyPem}
+	}
+
+	// a new certificate is issued by the CA
+	cert, err := EnsureClientCertificate(testCA, "user", nil, rotation, now)
+	require.NoError(t, err)
+	require.Equal(t, "user", cert.Cert.Subject.CommonName)
+	require.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, cert.Cert.ExtKeyUsage)
+	pool := x509.NewCertPool()
+	pool.AddCert(testCA.Cert)
+	_, err = cert.Cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	require.NoError(t, err)
+
+	// the certificate is reused while valid
+	reused, err := EnsureClientCertificate(testCA, "user", secretData(cert), rotation, now.Add(8*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, cert.CertPem, reused.CertPem)
+
+	// another certificate is issued for another user
+	other, err := EnsureClientCertificate(testCA, "other", secretData(cert), rotation, now)
+	require.NoError(t, err)
+	require.NotEqual(t, cert.CertPem, other.CertPem)
+
+	// the certificate is renewed before it expires
+	renewed, err := EnsureClientCertificate(testCA, "user", secretData(cert), rotation, now.Add(9*time.Hour))
+	require.NoError(t, err)
+	require.NotEqual(t, cert.CertPem, renewed.CertPem)
+
+	// the certificate is renewed if the CA changes
+	otherCA, err := NewSelfSignedCA(CABuilderOptions{Subject: pkix.Name{CommonName: "other"}})
+	require.NoError(t, err)
+	renewed, err = EnsureClientCertificate(otherCA, "user", secretData(cert), rotation, now)
+	require.NoError(t, err)
+	require.NotEqual(t, cert.CertPem, renewed.CertPem)
+	require.Equal(t, otherCA.Cert.Subject, renewed.Cert.Issuer)
+}
@@ -17,19 +17,30 @@ func CertificateWatchKey(namer name.Namer, ownerName string) string {
 }
 
 // reconcileDynamicWatches reconciles the dynamic watches needed by the HTTP certificates.
-func reconcileDynamicWatches(dynamicWatches watches.DynamicWatches, owner types.NamespacedName, namer name.Namer, tls commonv1.TLSOptions) error {
+func reconcileDynamicWatches(
+	dynamicWatches watches.DynamicWatches,
+	owner types.NamespacedName,
+	namer name.Namer,
+	tls commonv1.TLSOptions,
+	certManager bool,
+) error {
+	var watched []types.NamespacedName
 	// watch the Secret specified in es.Spec.HTTP.TLS.Certificate because if it changes we should reconcile the new
 	// user provided certificates.
+	if tls.Certificate.SecretName != "" {
+		watched = append(watched, types.NamespacedName{Namespace: owner.Namespace, Name: tls.Certificate.SecretName})
+	}
+	// watch the Secret of the certificate issued by cert-manager to reconcile its renewals.
+	if certManager {
+		watched = append(watched, types.NamespacedName{Namespace: owner.Namespace, Name: CertManagerHTTPCertsName(namer, owner.Name)})
+	}
 	httpCertificateWatch := watches.NamedWatch{
-		Name: CertificateWatchKey(namer, owner.Name),
-		Watched: []types.NamespacedName{{
-			Namespace: owner.Namespace,
-			Name:      tls.Certificate.SecretName,
-		}},
+		Name:    CertificateWatchKey(namer, owner.Name),
+		Watched: watched,
 		Watcher: owner,
 	}
 
-	if tls.Certificate.SecretName != "" {
+	if len(watched) > 0 {
 		if err := dynamicWatches.Secrets.AddHandler(httpCertificateWatch); err != nil {
 			return err
 		}
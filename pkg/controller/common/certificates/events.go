@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+)
+
+// RecordRotation emits an event on the given owner when one of its certificates was replaced by a new one.
+// Nothing is emitted if the recorder is nil.
+func RecordRotation(recorder record.EventRecorder, owner metav1.Object, certType CertificateType, details string) {
+	obj, ok := owner.(runtime.Object)
+	if recorder == nil || !ok {
+		return
+	}
+	message := "Rotated the " + string(certType) + " certificate"
+	if details != "" {
+		message += " " + details
+	}
+	recorder.Event(obj, corev1.EventTypeNormal, events.EventReasonCertificateRotated, message)
+}
+
+// ExpirationWarningAnnotation is set on the internal certificates secret once a warning was emitted about the
+// expiration of the user-provided certificate. Its value is the expiration date of the certificate.
+const ExpirationWarningAnnotation = "certificates.k8s.elastic.co/expiration-warning"
+
+// warnIfExpiring emits a warning event on the given owner if the given user-provided certificate expires within
+// the given margin. The warning is emitted once per certificate: its expiration date is recorded in an annotation of
+// the given internal secret, and true is returned if the secret must be updated. Nothing is emitted if the recorder
+// is nil.
+func warnIfExpiring(
+	recorder record.EventRecorder,
+	owner metav1.Object,
+	internalSecret *corev1.Secret,
+	secretName string,
+	notAfter time.Time,
+	margin time.Duration,
+) bool {
+	obj, ok := owner.(runtime.Object)
+	if recorder == nil || !ok || time.Now().Before(notAfter.Add(-margin)) {
+		return false
+	}
+	expiration := notAfter.UTC().Format(time.RFC3339)
+	if internalSecret.Annotations[ExpirationWarningAnnotation] == expiration {
+		// already warned about this certificate
+		return false
+	}
+	recorder.Eventf(obj, corev1.EventTypeWarning, events.EventReasonCertificateExpiring,
+		"The certificate provided in secret %s expires on %s", secretName, expiration)
+	if internalSecret.Annotations == nil {
+		internalSecret.Annotations = map[string]string{}
+	}
+	internalSecret.Annotations[ExpirationWarningAnnotation] = expiration
+	return true
+}
@@ -0,0 +1,63 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func Test_warnIfExpiring(t *testing.T) {
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		margin   time.Duration
+		wantWarn bool
+	}{
+		{
+			name:     "valid beyond the rotation margin",
+			notAfter: time.Now().Add(48 * time.Hour),
+			margin:   24 * time.Hour,
+		},
+		{
+			name:     "expires within the rotation margin",
+			notAfter: time.Now().Add(12 * time.Hour).Truncate(time.Second),
+			margin:   24 * time.Hour,
+			wantWarn: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			var internalSecret corev1.Secret
+			require.Equal(t, tt.wantWarn, warnIfExpiring(recorder, &testES, &internalSecret, "my-cert", tt.notAfter, tt.margin))
+			// the warning is only emitted once for the same certificate
+			require.False(t, warnIfExpiring(recorder, &testES, &internalSecret, "my-cert", tt.notAfter, tt.margin))
+			close(recorder.Events)
+			var got []string
+			for event := range recorder.Events {
+				got = append(got, event)
+			}
+			var want []string
+			if tt.wantWarn {
+				want = []string{"Warning CertificateExpiring The certificate provided in secret my-cert expires on " +
+					tt.notAfter.UTC().Format(time.RFC3339)}
+			}
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestRecordRotation(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	RecordRotation(recorder, &testES, TransportCertificate, "of nodes a, b")
+	require.Equal(t, "Normal CertificateRotated Rotated the transport certificate of nodes a, b", <-recorder.Events)
+	// no recorder
+	RecordRotation(nil, &testES, HTTPCertificate, "")
+}
@@ -5,10 +5,12 @@
 package certificates
 
 import (
+	"bytes"
 	cryptorand "crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"fmt"
 	"net"
 	"reflect"
 	"strings"
@@ -58,9 +60,17 @@ func (r Reconciler) ReconcileInternalHTTPCerts(ca *CA) (*CertificatesSecret, err
 		return nil, err
 	}
 
-	if err := reconcileDynamicWatches(r.DynamicWatches, ownerNSN, r.Namer, r.TLSOptions); err != nil {
+	useCertManager := customCertificates == nil && r.CertManagerIssuer != nil && r.TLSOptions.Enabled()
+	if err := reconcileDynamicWatches(r.DynamicWatches, ownerNSN, r.Namer, r.TLSOptions, useCertManager); err != nil {
 		return nil, err
 	}
+	if useCertManager {
+		// certificates issued by cert-manager are handled like user-provided certificates
+		customCertificates, err = r.reconcileCertManagerHTTPCerts()
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	secret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -100,10 +110,23 @@ func (r Reconciler) ReconcileInternalHTTPCerts(ca *CA) (*CertificatesSecret, err
 		secret.Data = make(map[string][]byte)
 	}
 
+	previousCert := secret.Data[CertFileName]
 	if customCertificates != nil {
 		if err := customCertificates.Validate(); err != nil {
 			return nil, err
 		}
+		if !useCertManager {
+			// warn about user-provided certificates that the operator cannot rotate
+			primaryCert, err := GetPrimaryCertificate(customCertificates.CertPem())
+			if err != nil {
+				return nil, err
+			}
+			if warnIfExpiring(
+				r.Recorder, r.Object, &secret, r.TLSOptions.Certificate.SecretName, primaryCert.NotAfter, r.CertRotation.RotateBefore,
+			) {
+				needsUpdate = true
+			}
+		}
 		expectedSecretData := make(map[string][]byte)
 		expectedSecretData[CertFileName] = customCertificates.CertPem()
 		expectedSecretData[KeyFileName] = customCertificates.KeyPem()
@@ -141,6 +164,9 @@ func (r Reconciler) ReconcileInternalHTTPCerts(ca *CA) (*CertificatesSecret, err
 			if err := r.K8sClient.Update(&secret); err != nil {
 				return nil, err
 			}
+			if len(previousCert) > 0 && !bytes.Equal(previousCert, secret.Data[CertFileName]) {
+				RecordRotation(r.Recorder, r.Object, HTTPCertificate, "")
+			}
 		}
 	}
 
@@ -148,6 +174,39 @@ func (r Reconciler) ReconcileInternalHTTPCerts(ca *CA) (*CertificatesSecret, err
 	return &internalCerts, nil
 }
 
+// reconcileCertManagerHTTPCerts reconciles the cert-manager Certificate for the HTTP layer, and returns the issued
+// certificate. An error is returned until the certificate is issued, the reconciliation being retried once the Secret
+// holding the certificate is created.
+func (r Reconciler) reconcileCertManagerHTTPCerts() (*CertificatesSecret, error) {
+	ownerNSN := k8s.ExtractNamespacedName(r.Object)
+	template := createValidatedHTTPCertificateTemplate(
+		ownerNSN, r.Namer, r.TLSOptions, r.Services, &x509.CertificateRequest{}, r.CertRotation.Validity,
+	)
+	ipAddresses := make([]string, len(template.IPAddresses))
+	for i, ip := range template.IPAddresses {
+		ipAddresses[i] = ip.String()
+	}
+	issued, err := ReconcileCertManagerCertificate(r.K8sClient, r.Object, CertManagerCertificate{
+		Name:        CertManagerHTTPCertsName(r.Namer, r.Object.GetName()),
+		Namespace:   r.Object.GetNamespace(),
+		Labels:      r.Labels,
+		Issuer:      *r.CertManagerIssuer,
+		CommonName:  template.Subject.CommonName,
+		DNSNames:    template.DNSNames,
+		IPAddresses: ipAddresses,
+		Rotation:    r.CertRotation,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if issued == nil {
+		return nil, fmt.Errorf("HTTP certificate %s/%s not issued yet by cert-manager %s",
+			r.Object.GetNamespace(), CertManagerHTTPCertsName(r.Namer, r.Object.GetName()), r.CertManagerIssuer)
+	}
+	certificates := CertificatesSecret(*issued)
+	return &certificates, nil
+}
+
 // ensureInternalSelfSignedCertificateSecretContents ensures that contents of a secret containing self-signed
 // certificates is valid. The provided secret is updated in-place.
 //
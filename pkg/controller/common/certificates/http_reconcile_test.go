@@ -235,6 +235,7 @@ func TestReconcileInternalHTTPCerts(t *testing.T) {
 		es       esv1.Elasticsearch
 		ca       *CA
 		services []corev1.Service
+		issuer   *IssuerRef
 	}
 	tests := []struct {
 		name    string
@@ -286,6 +287,37 @@ func TestReconcileInternalHTTPCerts(t *testing.T) {
 				assert.Equal(t, cs.Data[CAFileName], EncodePEMCert(testCA.Cert.Raw))
 			},
 		},
+		{
+			name: "should return an error until cert-manager issues the certificate",
+			args: args{
+				c:      k8s.WrappedFakeClient(),
+				es:     testES,
+				ca:     testCA,
+				issuer: &IssuerRef{Kind: ClusterIssuerKind, Name: "my-issuer"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "should use the certificate issued by cert-manager",
+			args: args{
+				c: k8s.WrappedFakeClient(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-es-name-es-http-certs-issued", Namespace: "test-namespace"},
+					Data: map[string][]byte{
+						CertFileName: tls,
+						KeyFileName:  key,
+						CAFileName:   loadFileBytes("ca.crt"),
+					},
+				}),
+				es:     testES,
+				ca:     testCA,
+				issuer: &IssuerRef{Kind: ClusterIssuerKind, Name: "my-issuer"},
+			},
+			want: func(t *testing.T, cs *CertificatesSecret) {
+				assert.Equal(t, cs.Data[KeyFileName], key)
+				assert.Equal(t, cs.Data[CertFileName], tls)
+				assert.Equal(t, cs.Data[CAFileName], loadFileBytes("ca.crt"))
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -302,11 +334,15 @@ func TestReconcileInternalHTTPCerts(t *testing.T) {
 					Validity:     DefaultCertValidity,
 					RotateBefore: DefaultRotateBefore,
 				},
+				CertManagerIssuer: tt.args.issuer,
 			}.ReconcileInternalHTTPCerts(tt.args.ca)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ReconcileInternalHTTPCerts() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 			tt.want(t, got)
 		})
 	}
@@ -0,0 +1,69 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// CertificateType is the type of a certificate managed by the operator, reported in metrics and events.
+type CertificateType string
+
+const (
+	HTTPCACertificate      CertificateType = "http-ca"
+	HTTPCertificate        CertificateType = "http"
+	TransportCACertificate CertificateType = "transport-ca"
+	TransportCertificate   CertificateType = "transport"
+	WebhookCertificate     CertificateType = "webhook"
+	ClientCACertificate    CertificateType = "client-ca"
+)
+
+var certificateTypes = []CertificateType{
+	HTTPCACertificate, HTTPCertificate, TransportCACertificate, TransportCertificate, WebhookCertificate, ClientCACertificate,
+}
+
+// expirationGauge is the expiration time of the certificates, per resource and certificate type.
+// For the transport certificates of the Elasticsearch nodes, this is the expiration time of the certificate expiring first.
+var expirationGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "eck_certificate_expiration_timestamp_seconds",
+		Help: "Expiration time of the certificates used by the resources managed by the operator, in seconds since the epoch",
+	},
+	[]string{"namespace", "name", "kind", "type"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(expirationGauge)
+}
+
+// ReportExpiration updates the expiration time of the certificate of the given type used by the given resource.
+func ReportExpiration(kind string, owner types.NamespacedName, certType CertificateType, notAfter time.Time) {
+	expirationGauge.WithLabelValues(owner.Namespace, owner.Name, kind, string(certType)).Set(float64(notAfter.Unix()))
+}
+
+// DeleteExpirationMetrics removes the expiration times of the certificates of a deleted resource, whose kind is
+// the kind of the given object.
+func DeleteExpirationMetrics(obj runtime.Object, owner types.NamespacedName) {
+	kind := KindOf(obj)
+	for _, certType := range certificateTypes {
+		expirationGauge.DeleteLabelValues(owner.Namespace, owner.Name, kind, string(certType))
+	}
+}
+
+// KindOf returns the kind of the given object, or an empty string if it is not registered in the scheme.
+func KindOf(obj runtime.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, scheme.Scheme)
+	if err != nil {
+		return ""
+	}
+	return gvk.Kind
+}
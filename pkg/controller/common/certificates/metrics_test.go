@@ -0,0 +1,36 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certificates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func TestReportExpiration(t *testing.T) {
+	// registers the Elastic resources in the scheme
+	_ = k8s.WrappedFakeClient()
+	es := types.NamespacedName{Namespace: "ns", Name: "es"}
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	kind := KindOf(&esv1.Elasticsearch{})
+	require.Equal(t, "Elasticsearch", kind)
+
+	ReportExpiration(kind, es, HTTPCertificate, notAfter)
+	ReportExpiration(kind, es, TransportCACertificate, notAfter.Add(time.Hour))
+	require.Equal(t, float64(notAfter.Unix()), testutil.ToFloat64(expirationGauge.WithLabelValues("ns", "es", kind, "http")))
+	require.Equal(t, float64(notAfter.Add(time.Hour).Unix()), testutil.ToFloat64(expirationGauge.WithLabelValues("ns", "es", kind, "transport-ca")))
+
+	DeleteExpirationMetrics(&esv1.Elasticsearch{}, es)
+	// nothing left to delete
+	require.False(t, expirationGauge.DeleteLabelValues("ns", "es", kind, "http"))
+	require.False(t, expirationGauge.DeleteLabelValues("ns", "es", kind, "transport-ca"))
+}
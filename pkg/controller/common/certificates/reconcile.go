@@ -12,7 +12,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -43,6 +45,10 @@ type Reconciler struct {
 	CertRotation   RotationParams // to requeue a reconciliation before cert expiration
 
 	GarbageCollectSecrets bool // if true, delete secrets if TLS is disabled
+
+	Recorder record.EventRecorder // to emit events on certificate rotations and expirations, may be nil
+
+	CertManagerIssuer *IssuerRef // if set, the HTTP certificate is issued by cert-manager instead of being self-signed
 }
 
 // ReconcileCAAndHTTPCerts reconciles 3 TLS-related secrets for the given object:
@@ -50,6 +56,7 @@ type Reconciler struct {
 // - a Secret containing the HTTP certificates and key (for internal use by the object), returned by this function
 // - a Secret containing the public-facing HTTP certificates (same as the internal one, but without the key)
 // If TLS is disabled, self-signed certificates are still reconciled, for simplicity/consistency, but not used.
+// If a cert-manager issuer is set, the HTTP certificate is issued by cert-manager unless provided by the user.
 func (r Reconciler) ReconcileCAAndHTTPCerts(ctx context.Context) (*CertificatesSecret, *reconciler.Results) {
 	span, _ := apm.StartSpan(ctx, "reconcile_certs", tracing.SpanTypeApp)
 	defer span.End()
@@ -68,10 +75,14 @@ func (r Reconciler) ReconcileCAAndHTTPCerts(ctx context.Context) (*CertificatesS
 		r.Labels,
 		HTTPCAType,
 		r.CACertRotation,
+		r.Recorder,
 	)
 	if err != nil {
 		return nil, results.WithError(err)
 	}
+	kind := KindOf(r.Object.(runtime.Object))
+	ownerNSN := k8s.ExtractNamespacedName(r.Object)
+	ReportExpiration(kind, ownerNSN, HTTPCACertificate, httpCa.Cert.NotAfter)
 	// handle CA expiry via requeue
 	results.WithResult(reconcile.Result{
 		RequeueAfter: ShouldRotateIn(time.Now(), httpCa.Cert.NotAfter, r.CACertRotation.RotateBefore),
@@ -86,6 +97,7 @@ func (r Reconciler) ReconcileCAAndHTTPCerts(ctx context.Context) (*CertificatesS
 	if err != nil {
 		return nil, results.WithError(err)
 	}
+	ReportExpiration(kind, ownerNSN, HTTPCertificate, primaryCert.NotAfter)
 	results.WithResult(reconcile.Result{
 		RequeueAfter: ShouldRotateIn(time.Now(), primaryCert.NotAfter, r.CertRotation.RotateBefore),
 	})
@@ -115,6 +127,15 @@ func (r *Reconciler) removeCAAndHTTPCertsSecrets() error {
 		return err
 	}
 
+	// remove the certificate issued by cert-manager
+	if r.CertManagerIssuer != nil {
+		if err := DeleteCertManagerCertificate(r.K8sClient,
+			types.NamespacedName{Namespace: r.Object.GetNamespace(), Name: CertManagerHTTPCertsName(r.Namer, r.Object.GetName())},
+		); err != nil {
+			return err
+		}
+	}
+
 	// remove watches on user-provided certs secret
 	r.DynamicWatches.Secrets.RemoveHandlerForKey(CertificateWatchKey(r.Namer, r.Object.GetName()))
 
@@ -18,6 +18,8 @@ import (
 const (
 	// CAFileName is used for the CA Certificates inside a secret
 	CAFileName = "ca.crt"
+	// CAKeyFileName is used for the CA Private Key inside a secret
+	CAKeyFileName = "ca.key"
 	// CertFileName is used for Certificates inside a secret
 	CertFileName = "tls.crt"
 	// KeyFileName is used for Private Keys inside a secret
@@ -26,6 +28,7 @@ const (
 	// certificate secrets suffixes
 	certsPublicSecretName   = "certs-public"
 	certsInternalSecretName = "certs-internal"
+	certsIssuedSecretName   = "certs-issued"
 
 	// http certs volume
 	HTTPCertificatesSecretVolumeName      = "elastic-internal-http-certificates"
@@ -40,6 +43,12 @@ func PublicCertsSecretName(namer name.Namer, ownerName string) string {
 	return namer.Suffix(ownerName, "http", certsPublicSecretName)
 }
 
+// CertManagerHTTPCertsName returns the name of the cert-manager Certificate issuing the HTTP certificate, and of the
+// Secret it is stored in.
+func CertManagerHTTPCertsName(namer name.Namer, ownerName string) string {
+	return namer.Suffix(ownerName, "http", certsIssuedSecretName)
+}
+
 func PublicCASecretName(namer name.Namer, ownerName string) string {
 	return namer.Suffix(ownerName, "ca", certsPublicSecretName)
 }
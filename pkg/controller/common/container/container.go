@@ -20,6 +20,7 @@ func SetContainerRegistry(registry string) {
 type Image string
 
 const (
+	AgentImage         Image = "beats/elastic-agent"
 	APMServerImage     Image = "apm/apm-server"
 	ElasticsearchImage Image = "elasticsearch/elasticsearch"
 	KibanaImage        Image = "kibana/kibana"
@@ -27,6 +28,11 @@ const (
 	EnterpriseSearchImage Image = "TODO"
 )
 
+// BeatImage returns the image path of the Beat of the given type (eg. filebeat).
+func BeatImage(beatType string) Image {
+	return Image(fmt.Sprintf("beats/%s", beatType))
+}
+
 // ImageRepository returns the full container image name by concatenating the current container registry and the image path with the given version.
 func ImageRepository(img Image, version string) string {
 	return fmt.Sprintf("%s/%s:%s", containerRegistry, img, version)
@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package daemonset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/comparison"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
+	commonscheme "github.com/elastic/cloud-on-k8s/pkg/controller/common/scheme"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func TestReconcile(t *testing.T) {
+	require.NoError(t, commonscheme.SetupScheme())
+	k8sClient := k8s.WrappedFakeClient()
+	expected := New(Params{
+		Name:      "ds",
+		Namespace: "ns",
+		Labels:    map[string]string{"a": "b"},
+		Selector:  map[string]string{"a": "b"},
+		UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+			Type: appsv1.OnDeleteDaemonSetStrategyType,
+		},
+	})
+	owner := esv1.Elasticsearch{} // can be any type
+
+	// should create a new DaemonSet
+	reconciled, err := Reconcile(k8sClient, expected, &owner)
+	require.NoError(t, err)
+	require.Equal(t, appsv1.OnDeleteDaemonSetStrategyType, reconciled.Spec.UpdateStrategy.Type)
+	require.Equal(t, "b", reconciled.Labels["a"])
+	require.NotEmpty(t, reconciled.Labels[hash.TemplateHashLabelName])
+	// resource should exist in the apiserver
+	var retrieved appsv1.DaemonSet
+	err = k8sClient.Get(k8s.ExtractNamespacedName(&expected), &retrieved)
+	require.NoError(t, err)
+	comparison.RequireEqual(t, &reconciled, &retrieved)
+
+	// reconciling the same should be a no-op
+	reconciledAgain, err := Reconcile(k8sClient, expected, &owner)
+	require.NoError(t, err)
+	comparison.RequireEqual(t, &reconciled, &reconciledAgain)
+
+	// update with a new spec
+	expected.Spec.UpdateStrategy.Type = appsv1.RollingUpdateDaemonSetStrategyType
+	reconciled, err = Reconcile(k8sClient, expected, &owner)
+	require.NoError(t, err)
+	require.Equal(t, appsv1.RollingUpdateDaemonSetStrategyType, reconciled.Spec.UpdateStrategy.Type)
+	require.NotEqual(t, reconciled.Labels[hash.TemplateHashLabelName], reconciledAgain.Labels[hash.TemplateHashLabelName])
+	err = k8sClient.Get(k8s.ExtractNamespacedName(&expected), &retrieved)
+	require.NoError(t, err)
+	comparison.RequireEqual(t, &reconciled, &retrieved)
+}
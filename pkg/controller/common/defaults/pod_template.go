@@ -152,6 +152,14 @@ func (b *PodTemplateBuilder) WithCommand(command []string) *PodTemplateBuilder {
 	return b
 }
 
+// WithArgs sets the given args to the Container, unless already provided in the template.
+func (b *PodTemplateBuilder) WithArgs(args ...string) *PodTemplateBuilder {
+	if len(b.Container.Args) == 0 {
+		b.Container.Args = args
+	}
+	return b
+}
+
 // volumeExists checks if a volume with the given name already exists in the Container.
 func (b *PodTemplateBuilder) volumeExists(name string) bool {
 	for _, v := range b.PodTemplate.Spec.Volumes {
@@ -314,6 +322,70 @@ func (b *PodTemplateBuilder) WithInitContainers(initContainers ...corev1.Contain
 	return b
 }
 
+// findContainerByName attempts to find a container with the given name in the template
+// Returns the index of the container or -1 if no container by that name was found.
+func (b *PodTemplateBuilder) findContainerByName(name string) int {
+	for i, c := range b.PodTemplate.Spec.Containers {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// WithSidecars includes the given sidecar containers to the pod template.
+//
+// If a container by the same name already exists in the template, the container in the template takes precedence:
+// - its image, command, args and resources are only set if not specified in the template
+// - env vars and volume mounts of the provided container are appended, unless they conflict (by name, or by mount path)
+// with the ones specified in the template
+func (b *PodTemplateBuilder) WithSidecars(sidecars ...corev1.Container) *PodTemplateBuilder {
+	for _, sidecar := range sidecars {
+		index := b.findContainerByName(sidecar.Name)
+		if index == -1 {
+			b.PodTemplate.Spec.Containers = append(b.PodTemplate.Spec.Containers, sidecar)
+			continue
+		}
+
+		c := &b.PodTemplate.Spec.Containers[index]
+		if c.Image == "" {
+			c.Image = sidecar.Image
+		}
+		if len(c.Command) == 0 && len(c.Args) == 0 {
+			c.Command = sidecar.Command
+			c.Args = sidecar.Args
+		}
+		if c.Resources.Requests == nil && c.Resources.Limits == nil {
+			c.Resources = sidecar.Resources
+		}
+		providedEnv := c.Env
+		for _, v := range sidecar.Env {
+			if !envVarExists(v.Name, providedEnv) {
+				c.Env = append(c.Env, v)
+			}
+		}
+		providedMounts := c.VolumeMounts
+		for _, volumeMount := range sidecar.VolumeMounts {
+			if b.findVolumeMountByNameOrMountPath(volumeMount, providedMounts) == -1 {
+				c.VolumeMounts = append(c.VolumeMounts, volumeMount)
+			}
+		}
+	}
+
+	// the main container may have moved in memory when appending sidecars
+	b.Container = &b.PodTemplate.Spec.Containers[b.findContainerByName(b.containerName)]
+	return b
+}
+
+func envVarExists(name string, vars []corev1.EnvVar) bool {
+	for _, v := range vars {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // WithResources sets up the given resource requirements if both resources limits and requests
 // are nil in the main container.
 // If a zero-value (empty map) for at least one of limits or request is provided, the given resource requirements
@@ -907,6 +907,62 @@ func TestPodTemplateBuilder_WithInitContainers(t *testing.T) {
 	}
 }
 
+func TestPodTemplateBuilder_WithSidecars(t *testing.T) {
+	sidecar := corev1.Container{
+		Name:         "sidecar",
+		Image:        "sidecar-image",
+		Args:         []string{"-e"},
+		Env:          []corev1.EnvVar{{Name: "VAR1", Value: "default"}, {Name: "VAR2", Value: "default"}},
+		VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/config"}, {Name: "data", MountPath: "/data"}},
+	}
+	tests := []struct {
+		name        string
+		PodTemplate corev1.PodTemplateSpec
+		want        []corev1.Container
+	}{
+		{
+			name:        "append sidecars",
+			PodTemplate: corev1.PodTemplateSpec{},
+			want:        []corev1.Container{{Name: "main"}, sidecar},
+		},
+		{
+			name: "user-provided sidecar fields take precedence",
+			PodTemplate: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:         "sidecar",
+							Env:          []corev1.EnvVar{{Name: "VAR1", Value: "user"}},
+							VolumeMounts: []corev1.VolumeMount{{Name: "user-data", MountPath: "/data"}},
+						},
+						{Name: "main"},
+					},
+				},
+			},
+			want: []corev1.Container{
+				{
+					Name:         "sidecar",
+					Image:        "sidecar-image",
+					Args:         []string{"-e"},
+					Env:          []corev1.EnvVar{{Name: "VAR1", Value: "user"}, {Name: "VAR2", Value: "default"}},
+					VolumeMounts: []corev1.VolumeMount{{Name: "user-data", MountPath: "/data"}, {Name: "config", MountPath: "/etc/config"}},
+				},
+				{Name: "main"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewPodTemplateBuilder(tt.PodTemplate, "main").WithSidecars(sidecar)
+			require.Equal(t, tt.want, b.PodTemplate.Spec.Containers)
+			// the main container should still be modifiable through the builder
+			b.WithArgs("main-arg")
+			require.Equal(t, []string{"main-arg"}, b.PodTemplate.Spec.Containers[b.findContainerByName("main")].Args)
+		})
+	}
+}
+
 func TestPodTemplateBuilder_WithDefaultResources(t *testing.T) {
 	containerName := "default-container"
 	tests := []struct {
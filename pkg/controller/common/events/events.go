@@ -24,6 +24,18 @@ const (
 	EventReasonStateChange = "StateChange"
 	// EventReasonRestart describes events where one or multiple Elasticsearch nodes are scheduled for a restart.
 	EventReasonRestart = "Restart"
+	// EventReasonAutoscaled describes events where the number of nodes was adjusted by an autoscaling policy.
+	EventReasonAutoscaled = "Autoscaled"
+	// EventReasonCertificateRotated describes events where a certificate was replaced by a new one.
+	EventReasonCertificateRotated = "CertificateRotated"
+	// EventReasonCertificateExpiring describes events where a user-provided certificate is about to expire.
+	EventReasonCertificateExpiring = "CertificateExpiring"
+	// EventReasonCredentialsRotated describes events where the passwords managed by the operator were replaced by new ones.
+	EventReasonCredentialsRotated = "CredentialsRotated"
+	// EventReasonNodeTuningRequired describes events where the kernel settings of a Kubernetes node do not suit Elasticsearch.
+	EventReasonNodeTuningRequired = "NodeTuningRequired"
+	// EventReasonUnsupported describes events where a requested feature is not supported by the Kubernetes cluster.
+	EventReasonUnsupported = "Unsupported"
 )
 
 // Event reasons for Association controllers
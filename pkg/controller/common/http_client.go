@@ -24,6 +24,12 @@ import (
 // match Kubernetes internal service name, but only the user-facing public endpoint
 // - set APM spans with each request
 func HTTPClient(dialer net.Dialer, caCerts []*x509.Certificate) *http.Client {
+	return HTTPClientWithCertificate(dialer, caCerts, nil)
+}
+
+// HTTPClientWithCertificate returns an http.Client configured as HTTPClient, presenting the given client certificate,
+// if not nil, to the servers requesting one.
+func HTTPClientWithCertificate(dialer net.Dialer, caCerts []*x509.Certificate, clientCert *tls.Certificate) *http.Client {
 	certPool := x509.NewCertPool()
 	for _, c := range caCerts {
 		certPool.AddCert(c)
@@ -55,6 +61,10 @@ func HTTPClient(dialer net.Dialer, caCerts []*x509.Certificate) *http.Client {
 		return err
 	}
 
+	if clientCert != nil {
+		transportConfig.TLSClientConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+
 	// use the custom dialer if provided
 	if dialer != nil {
 		transportConfig.DialContext = dialer.DialContext
@@ -0,0 +1,180 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package networkpolicy builds and reconciles the NetworkPolicies allowing the ingress traffic the operator knows
+// about to the Pods it manages: from the operator itself, and from the associated resources.
+package networkpolicy
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/version"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/tools/record"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
+)
+
+// NamespaceNameLabel is the label Kubernetes sets on every namespace to its name, starting with Kubernetes 1.21.
+const NamespaceNameLabel = "kubernetes.io/metadata.name"
+
+// minKubernetesVersion is the first Kubernetes version setting the NamespaceNameLabel, which the NetworkPolicies rely
+// on to select the Pods of other namespaces.
+var minKubernetesVersion = version.MustParseGeneric("1.21.0")
+
+// Supported returns true if the NetworkPolicies can be reconciled on a Kubernetes cluster of the given version.
+func Supported(serverVersion *k8sversion.Info) (bool, error) {
+	v, err := version.ParseGeneric(serverVersion.GitVersion)
+	if err != nil {
+		return false, err
+	}
+	return v.AtLeast(minKubernetesVersion), nil
+}
+
+// IsEnabled returns true if the NetworkPolicy of the given owner must be reconciled according to its options and the
+// operator parameters. If it is enabled on a Kubernetes cluster not supporting NetworkPolicies, a warning event is
+// emitted and false is returned, for any existing NetworkPolicy to be deleted.
+func IsEnabled(recorder record.EventRecorder, owner runtime.Object, options commonv1.NetworkPolicyOptions, params operator.Parameters) bool {
+	if !options.IsEnabled(params.ManageNetworkPolicies) {
+		return false
+	}
+	if !params.NetworkPoliciesSupported {
+		recorder.Eventf(owner, corev1.EventTypeWarning, events.EventReasonUnsupported,
+			"NetworkPolicies are not reconciled before Kubernetes %s, which labels the namespaces with %s", minKubernetesVersion, NamespaceNameLabel)
+		return false
+	}
+	return true
+}
+
+// Rule allows the ingress traffic from the given peers to the given TCP port.
+type Rule struct {
+	Port  int
+	Peers []networkingv1.NetworkPolicyPeer
+}
+
+// NamespacePeer returns a peer selecting all the Pods of the given namespace.
+func NamespacePeer(namespace string) networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{NamespaceNameLabel: namespace}},
+	}
+}
+
+// PodsPeer returns a peer selecting the Pods with the given labels in the given namespace, as seen from a
+// NetworkPolicy living in policyNamespace.
+func PodsPeer(policyNamespace string, namespace string, podLabels map[string]string) networkingv1.NetworkPolicyPeer {
+	peer := networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: podLabels}}
+	if namespace != policyNamespace {
+		peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{NamespaceNameLabel: namespace}}
+	}
+	return peer
+}
+
+// New returns a NetworkPolicy selecting the Pods with the given labels, and only allowing the ingress traffic
+// matching the given rules. Rules without peers are ignored rather than allowing the traffic from anywhere.
+func New(meta metav1.ObjectMeta, podLabels map[string]string, rules ...Rule) networkingv1.NetworkPolicy {
+	tcp := corev1.ProtocolTCP
+	var ingress []networkingv1.NetworkPolicyIngressRule
+	for _, rule := range rules {
+		peers := uniquePeers(rule.Peers)
+		if len(peers) == 0 {
+			continue
+		}
+		port := intstr.FromInt(rule.Port)
+		ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}},
+			From:  peers,
+		})
+	}
+	return networkingv1.NetworkPolicy{
+		ObjectMeta: meta,
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+			Ingress:     ingress,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+}
+
+// uniquePeers returns the given peers deduplicated and sorted, so that the order in which resources are listed does
+// not lead to unnecessary updates.
+func uniquePeers(peers []networkingv1.NetworkPolicyPeer) []networkingv1.NetworkPolicyPeer {
+	byKey := make(map[string]networkingv1.NetworkPolicyPeer, len(peers))
+	keys := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		key := peerKey(peer)
+		if _, exists := byKey[key]; exists {
+			continue
+		}
+		byKey[key] = peer
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]networkingv1.NetworkPolicyPeer, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, byKey[key])
+	}
+	return result
+}
+
+func peerKey(peer networkingv1.NetworkPolicyPeer) string {
+	var namespaceSelector, podSelector string
+	if peer.NamespaceSelector != nil {
+		namespaceSelector = labels.Set(peer.NamespaceSelector.MatchLabels).String()
+	}
+	if peer.PodSelector != nil {
+		podSelector = labels.Set(peer.PodSelector.MatchLabels).String()
+	}
+	return namespaceSelector + "/" + podSelector
+}
+
+// Reconcile creates or updates the expected NetworkPolicy for the given owner if enabled, or deletes it otherwise.
+func Reconcile(c k8s.Client, owner metav1.Object, expected networkingv1.NetworkPolicy, enabled bool) error {
+	if !enabled {
+		return deleteIfExists(c, expected)
+	}
+	reconciled := &networkingv1.NetworkPolicy{}
+	return reconciler.ReconcileResource(reconciler.Params{
+		Client:     c,
+		Owner:      owner,
+		Expected:   &expected,
+		Reconciled: reconciled,
+		NeedsUpdate: func() bool {
+			return !maps.IsSubset(expected.Labels, reconciled.Labels) ||
+				!equality.Semantic.DeepEqual(expected.Spec, reconciled.Spec)
+		},
+		UpdateReconciled: func() {
+			reconciled.Labels = maps.Merge(reconciled.Labels, expected.Labels)
+			reconciled.Spec = expected.Spec
+		},
+	})
+}
+
+// deleteIfExists deletes the given NetworkPolicy if it exists.
+func deleteIfExists(c k8s.Client, policy networkingv1.NetworkPolicy) error {
+	// get first from the cache, to save a Delete call to the API server in most cases
+	var existing networkingv1.NetworkPolicy
+	if err := c.Get(k8s.ExtractNamespacedName(&policy), &existing); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := c.Delete(&existing); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
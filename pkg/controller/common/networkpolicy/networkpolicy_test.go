@@ -0,0 +1,184 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/tools/record"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var policyMeta = metav1.ObjectMeta{Namespace: "ns", Name: "policy", Labels: map[string]string{"a": "b"}}
+
+func podsPeer(namespace string, podLabels map[string]string) networkingv1.NetworkPolicyPeer {
+	return PodsPeer("ns", namespace, podLabels)
+}
+
+func TestPodsPeer(t *testing.T) {
+	podLabels := map[string]string{"app": "kb"}
+	require.Equal(t,
+		networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: podLabels}},
+		PodsPeer("ns", "ns", podLabels),
+	)
+	require.Equal(t,
+		networkingv1.NetworkPolicyPeer{
+			PodSelector:       &metav1.LabelSelector{MatchLabels: podLabels},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{NamespaceNameLabel: "other"}},
+		},
+		PodsPeer("ns", "other", podLabels),
+	)
+}
+
+func TestNew(t *testing.T) {
+	tcp := corev1.ProtocolTCP
+	port := intstr.FromInt(9200)
+	policy := New(
+		policyMeta,
+		map[string]string{"app": "es"},
+		Rule{Port: 9200, Peers: []networkingv1.NetworkPolicyPeer{
+			podsPeer("ns", map[string]string{"app": "kb"}),
+			NamespacePeer("operator"),
+			podsPeer("ns", map[string]string{"app": "kb"}),
+			podsPeer("other", map[string]string{"app": "apm"}),
+		}},
+		// no peers: must not allow the traffic from anywhere
+		Rule{Port: 9300},
+	)
+	require.Equal(t, networkingv1.NetworkPolicy{
+		ObjectMeta: policyMeta,
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "es"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}},
+				// deduplicated and sorted
+				From: []networkingv1.NetworkPolicyPeer{
+					podsPeer("ns", map[string]string{"app": "kb"}),
+					NamespacePeer("operator"),
+					podsPeer("other", map[string]string{"app": "apm"}),
+				},
+			}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}, policy)
+
+	// the order of the peers does not matter
+	reordered := New(
+		policyMeta,
+		map[string]string{"app": "es"},
+		Rule{Port: 9200, Peers: []networkingv1.NetworkPolicyPeer{
+			podsPeer("other", map[string]string{"app": "apm"}),
+			NamespacePeer("operator"),
+			podsPeer("ns", map[string]string{"app": "kb"}),
+		}},
+	)
+	require.Equal(t, policy, reordered)
+}
+
+func TestReconcile(t *testing.T) {
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "owner"}}
+	c := k8s.WrappedFakeClient(owner)
+	key := types.NamespacedName{Namespace: "ns", Name: "policy"}
+	get := func() (networkingv1.NetworkPolicy, error) {
+		var policy networkingv1.NetworkPolicy
+		err := c.Get(key, &policy)
+		return policy, err
+	}
+
+	// disabled and not created: nothing to do
+	disabled := networkingv1.NetworkPolicy{ObjectMeta: policyMeta}
+	require.NoError(t, Reconcile(c, owner, disabled, false))
+	_, err := get()
+	require.True(t, apierrors.IsNotFound(err))
+
+	// created when enabled
+	expected := New(policyMeta, map[string]string{"app": "es"}, Rule{Port: 9200, Peers: []networkingv1.NetworkPolicyPeer{NamespacePeer("operator")}})
+	require.NoError(t, Reconcile(c, owner, expected, true))
+	policy, err := get()
+	require.NoError(t, err)
+	require.Equal(t, expected.Spec, policy.Spec)
+	require.Equal(t, "owner", policy.OwnerReferences[0].Name)
+
+	// updated when the allowed traffic changes
+	expected = New(policyMeta, map[string]string{"app": "es"}, Rule{Port: 9200, Peers: []networkingv1.NetworkPolicyPeer{NamespacePeer("elastic-system")}})
+	require.NoError(t, Reconcile(c, owner, expected, true))
+	policy, err = get()
+	require.NoError(t, err)
+	require.Equal(t, expected.Spec, policy.Spec)
+
+	// deleted when disabled
+	require.NoError(t, Reconcile(c, owner, disabled, false))
+	_, err = get()
+	require.True(t, apierrors.IsNotFound(err))
+}
+
+func TestSupported(t *testing.T) {
+	for version, want := range map[string]bool{
+		"v1.20.7":          false,
+		"v1.20.4-gke.2200": false,
+		"v1.21.0":          true,
+		"v1.22.1-eks-1":    true,
+	} {
+		supported, err := Supported(&k8sversion.Info{GitVersion: version})
+		require.NoError(t, err)
+		require.Equal(t, want, supported, version)
+	}
+	_, err := Supported(&k8sversion.Info{GitVersion: "invalid"})
+	require.Error(t, err)
+}
+
+func TestIsEnabled(t *testing.T) {
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "owner"}}
+	enabled := true
+	tests := []struct {
+		name       string
+		options    commonv1.NetworkPolicyOptions
+		params     operator.Parameters
+		want       bool
+		wantEvents int
+	}{
+		{
+			name:   "disabled by default",
+			params: operator.Parameters{NetworkPoliciesSupported: true},
+			want:   false,
+		},
+		{
+			name:   "enabled by the operator",
+			params: operator.Parameters{ManageNetworkPolicies: true, NetworkPoliciesSupported: true},
+			want:   true,
+		},
+		{
+			name:    "enabled on the resource",
+			options: commonv1.NetworkPolicyOptions{Enabled: &enabled},
+			params:  operator.Parameters{NetworkPoliciesSupported: true},
+			want:    true,
+		},
+		{
+			name:       "enabled but not supported by the Kubernetes cluster",
+			options:    commonv1.NetworkPolicyOptions{Enabled: &enabled},
+			params:     operator.Parameters{},
+			want:       false,
+			wantEvents: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			require.Equal(t, tt.want, IsEnabled(recorder, owner, tt.options, tt.params))
+			require.Len(t, recorder.Events, tt.wantEvents)
+		})
+	}
+}
@@ -5,21 +5,32 @@
 package operator
 
 const (
-	AutoPortForwardFlag         = "auto-port-forward"
-	CACertRotateBeforeFlag      = "ca-cert-rotate-before"
-	CACertValidityFlag          = "ca-cert-validity"
-	CertRotateBeforeFlag        = "cert-rotate-before"
-	CertValidityFlag            = "cert-validity"
-	ContainerRegistryFlag       = "container-registry"
-	DebugHTTPListenFlag         = "debug-http-listen"
-	EnableTracingFlag           = "enable-tracing"
-	EnableWebhookFlag           = "enable-webhook"
-	EnforceRBACOnRefsFlag       = "enforce-rbac-on-refs"
-	ManageWebhookCertsFlag      = "manage-webhook-certs"
-	MaxConcurrentReconcilesFlag = "max-concurrent-reconciles"
-	MetricsPortFlag             = "metrics-port"
-	NamespacesFlag              = "namespaces"
-	OperatorNamespaceFlag       = "operator-namespace"
-	WebhookCertDirFlag          = "webhook-cert-dir"
-	WebhookSecretFlag           = "webhook-secret"
+	AutoPortForwardFlag             = "auto-port-forward"
+	CACertRotateBeforeFlag          = "ca-cert-rotate-before"
+	CACertValidityFlag              = "ca-cert-validity"
+	CertManagerIssuerFlag           = "cert-manager-issuer"
+	CertRotateBeforeFlag            = "cert-rotate-before"
+	CertValidityFlag                = "cert-validity"
+	ContainerRegistryFlag           = "container-registry"
+	DebugHTTPListenFlag             = "debug-http-listen"
+	EnableLeaderElectionFlag        = "enable-leader-election"
+	EnableTracingFlag               = "enable-tracing"
+	EnableWebhookFlag               = "enable-webhook"
+	EnforceRBACOnRefsFlag           = "enforce-rbac-on-refs"
+	LeaderElectionIDFlag            = "leader-election-id"
+	LeaderElectionLeaseDurationFlag = "leader-election-lease-duration"
+	LeaderElectionNamespaceFlag     = "leader-election-namespace"
+	LeaderElectionRenewDeadlineFlag = "leader-election-renew-deadline"
+	LeaderElectionRetryPeriodFlag   = "leader-election-retry-period"
+	ManageNetworkPoliciesFlag       = "manage-network-policies"
+	ManageNodeTuningFlag            = "manage-node-tuning"
+	ManageWebhookCertsFlag          = "manage-webhook-certs"
+	MaxConcurrentReconcilesFlag     = "max-concurrent-reconciles"
+	MetricsPortFlag                 = "metrics-port"
+	NamespacesFlag                  = "namespaces"
+	NodeTuningImageFlag             = "node-tuning-image"
+	OperatorNamespaceFlag           = "operator-namespace"
+	ValidateStorageClassFlag        = "validate-storage-class"
+	WebhookCertDirFlag              = "webhook-cert-dir"
+	WebhookSecretFlag               = "webhook-secret"
 )
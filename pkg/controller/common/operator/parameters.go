@@ -24,8 +24,23 @@ type Parameters struct {
 	CACertRotation certificates.RotationParams
 	// CertRotation defines the rotation params for non-CA certificates.
 	CertRotation certificates.RotationParams
+	// CertManagerIssuer is the cert-manager issuer of the certificates, nil if certificates are self-signed.
+	CertManagerIssuer *certificates.IssuerRef
+	// ManageNetworkPolicies is the default for reconciling NetworkPolicies, unless set on a per-resource basis.
+	ManageNetworkPolicies bool
+	// NetworkPoliciesSupported is true if the Kubernetes cluster labels the namespaces with their name, which the
+	// NetworkPolicies rely on.
+	NetworkPoliciesSupported bool
+	// ManageNodeTuning is true if the operator raises the vm.max_map_count kernel setting of the Kubernetes nodes
+	// hosting Elasticsearch Pods.
+	ManageNodeTuning bool
+	// NodeTuningImage is the container image of the node tuning Pods.
+	NodeTuningImage string
 	// MaxConcurrentReconciles controls the number of goroutines per controller.
 	MaxConcurrentReconciles int
 	// Tracer is a shared APM tracer instance or nil
 	Tracer *apm.Tracer
+	// ValidateStorageClass specifies whether storage classes volume expansion support should be verified.
+	// Can be disabled if cluster-wide storage class RBAC access is not available.
+	ValidateStorageClass bool
 }
@@ -7,8 +7,10 @@ package scheme
 import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
 	apmv1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1"
 	apmv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1beta1"
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
 	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	commonv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1beta1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
@@ -42,6 +44,14 @@ func SetupScheme() error {
 		return err
 	}
 	err = entsv1beta1.AddToScheme(clientgoscheme.Scheme)
+	if err != nil {
+		return err
+	}
+	err = beatv1beta1.AddToScheme(clientgoscheme.Scheme)
+	if err != nil {
+		return err
+	}
+	err = agentv1alpha1.AddToScheme(clientgoscheme.Scheme)
 	return err
 }
 
@@ -70,5 +80,13 @@ func SetupV1beta1Scheme() error {
 		return err
 	}
 	err = entsv1beta1.AddToScheme(clientgoscheme.Scheme)
+	if err != nil {
+		return err
+	}
+	err = beatv1beta1.AddToScheme(clientgoscheme.Scheme)
+	if err != nil {
+		return err
+	}
+	err = agentv1alpha1.AddToScheme(clientgoscheme.Scheme)
 	return err
 }
@@ -0,0 +1,215 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package stackmon
+
+import (
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/container"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// MetricbeatType is the type of the Beat shipping the metrics of a monitored resource.
+	MetricbeatType = "metricbeat"
+	// FilebeatType is the type of the Beat shipping the logs of a monitored resource.
+	FilebeatType = "filebeat"
+
+	// ConfigHashAnnotationName is an annotation used to rotate the Pods on changes of the monitoring sidecars configuration.
+	ConfigHashAnnotationName = "monitoring.k8s.elastic.co/config-hash"
+
+	// outputPasswordEnvVarName is the environment variable holding the password used to ship data to the
+	// monitoring Elasticsearch cluster.
+	outputPasswordEnvVarName = "MONITORING_OUTPUT_PASSWORD"
+	configFileSuffix         = ".yml"
+)
+
+var (
+	defaultResources = corev1.ResourceRequirements{
+		Requests: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceMemory: resource.MustParse("200Mi"),
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+		},
+		Limits: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceMemory: resource.MustParse("200Mi"),
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+		},
+	}
+)
+
+// BeatSidecar is a Beat container shipping the metrics or the logs of the resource running in the same Pod to a
+// monitoring Elasticsearch cluster, along with its configuration.
+type BeatSidecar struct {
+	Container    corev1.Container
+	ConfigSecret corev1.Secret
+	ConfigHash   string
+	Volumes      []corev1.Volume
+}
+
+// BeatSidecarParams holds the parameters of a Beat sidecar specific to the monitored resource.
+type BeatSidecarParams struct {
+	// Monitored is the resource whose metrics or logs are shipped.
+	Monitored metav1.Object
+	// Namer is used to name the configuration Secret of the sidecar.
+	Namer name.Namer
+	// Version is the version of the Beat image, matching the version of the monitored resource.
+	Version string
+	// Labels are applied to the configuration Secret of the sidecar.
+	Labels map[string]string
+	// AssociationConf is the configuration of the association with the monitoring Elasticsearch cluster.
+	AssociationConf *commonv1.AssociationConf
+	// Modules is the list of Beat modules collecting the metrics or the logs.
+	Modules []map[string]interface{}
+	// VolumeMounts are mounted in the sidecar container, in addition to its configuration and data volumes.
+	// They must reference volumes of the Pod.
+	VolumeMounts []corev1.VolumeMount
+	// Env holds additional environment variables, typically the credentials used to collect metrics.
+	Env []corev1.EnvVar
+}
+
+// ConfigSecretName returns the name of the Secret holding the configuration of a Beat sidecar.
+func ConfigSecretName(namer name.Namer, monitoredName string, beatType string) string {
+	return namer.Suffix(monitoredName, "monitoring", beatType)
+}
+
+// NewMetricbeatSidecar returns a Metricbeat sidecar shipping the metrics of the monitored resource.
+func NewMetricbeatSidecar(params BeatSidecarParams) (BeatSidecar, error) {
+	return newBeatSidecar(MetricbeatType, params)
+}
+
+// NewFilebeatSidecar returns a Filebeat sidecar shipping the logs of the monitored resource.
+func NewFilebeatSidecar(params BeatSidecarParams) (BeatSidecar, error) {
+	return newBeatSidecar(FilebeatType, params)
+}
+
+func newBeatSidecar(beatType string, params BeatSidecarParams) (BeatSidecar, error) {
+	configSecretName := ConfigSecretName(params.Namer, params.Monitored.GetName(), beatType)
+	configVolume := volume.NewSecretVolumeWithMountPath(
+		configSecretName,
+		beatType+"-config",
+		filepath.Join("/etc", beatType+"-config"),
+	)
+	dataVolume := volume.NewEmptyDirVolume(beatType+"-data", filepath.Join("/usr/share", beatType, "data"))
+	volumes := []corev1.Volume{configVolume.Volume(), dataVolume.Volume()}
+	volumeMounts := append([]corev1.VolumeMount{configVolume.VolumeMount(), dataVolume.VolumeMount()}, params.VolumeMounts...)
+
+	output := map[string]interface{}{
+		"hosts":    []string{params.AssociationConf.GetURL()},
+		"username": params.AssociationConf.GetAuthSecretKey(),
+		"password": "${" + outputPasswordEnvVarName + "}",
+	}
+	if params.AssociationConf.GetCACertProvided() {
+		caVolume := volume.NewSecretVolumeWithMountPath(
+			params.AssociationConf.GetCASecretName(),
+			beatType+"-monitoring-es-ca",
+			filepath.Join("/mnt/elastic-internal", beatType+"-monitoring-es-ca"),
+		)
+		volumes = append(volumes, caVolume.Volume())
+		volumeMounts = append(volumeMounts, caVolume.VolumeMount())
+		output["ssl.certificate_authorities"] = []string{filepath.Join(caVolume.VolumeMount().MountPath, certificates.CAFileName)}
+	}
+
+	cfg, err := settings.NewCanonicalConfigFrom(map[string]interface{}{
+		beatType + ".modules":  params.Modules,
+		"output.elasticsearch": output,
+	})
+	if err != nil {
+		return BeatSidecar{}, err
+	}
+	cfgBytes, err := cfg.Render()
+	if err != nil {
+		return BeatSidecar{}, err
+	}
+
+	configSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: params.Monitored.GetNamespace(),
+			Name:      configSecretName,
+			Labels:    params.Labels,
+		},
+		Data: map[string][]byte{
+			beatType + configFileSuffix: cfgBytes,
+		},
+	}
+
+	env := append(defaults.PodDownwardEnvVars(), corev1.EnvVar{
+		Name: outputPasswordEnvVarName,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: params.AssociationConf.GetAuthSecretName()},
+				Key:                  params.AssociationConf.GetAuthSecretKey(),
+			},
+		},
+	})
+
+	return BeatSidecar{
+		Container: corev1.Container{
+			Name:         beatType,
+			Image:        container.ImageRepository(container.BeatImage(beatType), params.Version),
+			Args:         []string{"-e", "-c", filepath.Join(configVolume.VolumeMount().MountPath, beatType+configFileSuffix)},
+			Env:          append(env, params.Env...),
+			VolumeMounts: volumeMounts,
+			Resources:    defaultResources,
+		},
+		ConfigSecret: configSecret,
+		ConfigHash:   hash.HashObject(cfgBytes),
+		Volumes:      volumes,
+	}, nil
+}
+
+// WithBeatSidecars adds the given sidecars to the Pod template, along with their volumes, and annotates the Pod
+// template with the hash of their configuration to rotate the Pods on configuration changes.
+func WithBeatSidecars(builder *defaults.PodTemplateBuilder, sidecars ...BeatSidecar) *defaults.PodTemplateBuilder {
+	if len(sidecars) == 0 {
+		return builder
+	}
+	containers := make([]corev1.Container, 0, len(sidecars))
+	configHashes := make([]string, 0, len(sidecars))
+	for _, sidecar := range sidecars {
+		containers = append(containers, sidecar.Container)
+		configHashes = append(configHashes, sidecar.ConfigHash)
+		builder = builder.WithVolumes(sidecar.Volumes...)
+	}
+	return builder.
+		WithSidecars(containers...).
+		WithAnnotations(map[string]string{ConfigHashAnnotationName: hash.HashObject(configHashes)})
+}
+
+// ReconcileConfigSecrets creates or updates the configuration Secrets of the given sidecars, owned by the monitored
+// resource, and deletes the configuration Secrets of the sidecars which are not enabled anymore.
+func ReconcileConfigSecrets(c k8s.Client, monitored metav1.Object, namer name.Namer, sidecars ...BeatSidecar) error {
+	expected := make(map[string]struct{}, len(sidecars))
+	for _, sidecar := range sidecars {
+		if _, err := reconciler.ReconcileSecret(c, sidecar.ConfigSecret, monitored); err != nil {
+			return err
+		}
+		expected[sidecar.ConfigSecret.Name] = struct{}{}
+	}
+
+	for _, beatType := range []string{MetricbeatType, FilebeatType} {
+		secretName := ConfigSecretName(namer, monitored.GetName(), beatType)
+		if _, exists := expected[secretName]; exists {
+			continue
+		}
+		secret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: monitored.GetNamespace(), Name: secretName}}
+		if err := c.Delete(&secret); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
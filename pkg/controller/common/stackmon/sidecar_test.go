@@ -0,0 +1,182 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package stackmon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var (
+	testES = esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+	}
+	testAssocConf = &commonv1.AssociationConf{
+		AuthSecretName: "es-es-monitoring-metrics-user",
+		AuthSecretKey:  "ns-es-es-monitoring-metrics-user",
+		CACertProvided: true,
+		CASecretName:   "es-es-monitoring-metrics-ca",
+		URL:            "https://monitoring-es-http.observability.svc:9200",
+	}
+)
+
+func testParams() BeatSidecarParams {
+	return BeatSidecarParams{
+		Monitored:       &testES,
+		Namer:           esv1.ESNamer,
+		Version:         "7.14.0",
+		Labels:          map[string]string{"a": "b"},
+		AssociationConf: testAssocConf,
+		Modules:         []map[string]interface{}{{"module": "elasticsearch"}},
+		VolumeMounts:    []corev1.VolumeMount{{Name: "extra", MountPath: "/mnt/extra"}},
+		Env:             []corev1.EnvVar{{Name: "EXTRA", Value: "extra"}},
+	}
+}
+
+func TestNewMetricbeatSidecar(t *testing.T) {
+	sidecar, err := NewMetricbeatSidecar(testParams())
+	require.NoError(t, err)
+
+	// configuration
+	require.Equal(t, "es-es-monitoring-metricbeat", sidecar.ConfigSecret.Name)
+	require.Equal(t, "ns", sidecar.ConfigSecret.Namespace)
+	require.Equal(t, map[string]string{"a": "b"}, sidecar.ConfigSecret.Labels)
+	cfg, err := settings.ParseConfig(sidecar.ConfigSecret.Data["metricbeat.yml"])
+	require.NoError(t, err)
+	var got map[string]interface{}
+	require.NoError(t, cfg.Unpack(&got))
+	expectedCfg, err := settings.NewCanonicalConfigFrom(map[string]interface{}{
+		"metricbeat.modules": []map[string]interface{}{{"module": "elasticsearch"}},
+		"output.elasticsearch": map[string]interface{}{
+			"hosts":                       []string{"https://monitoring-es-http.observability.svc:9200"},
+			"username":                    "ns-es-es-monitoring-metrics-user",
+			"password":                    "${MONITORING_OUTPUT_PASSWORD}",
+			"ssl.certificate_authorities": []string{"/mnt/elastic-internal/metricbeat-monitoring-es-ca/ca.crt"},
+		},
+	})
+	require.NoError(t, err)
+	var expected map[string]interface{}
+	require.NoError(t, expectedCfg.Unpack(&expected))
+	require.Equal(t, expected, got)
+	require.NotEmpty(t, sidecar.ConfigHash)
+
+	// container
+	require.Equal(t, "metricbeat", sidecar.Container.Name)
+	require.Equal(t, "docker.elastic.co/beats/metricbeat:7.14.0", sidecar.Container.Image)
+	require.Equal(t, []string{"-e", "-c", "/etc/metricbeat-config/metricbeat.yml"}, sidecar.Container.Args)
+	require.Equal(t, defaultResources, sidecar.Container.Resources)
+	mountPaths := make([]string, 0, len(sidecar.Container.VolumeMounts))
+	for _, m := range sidecar.Container.VolumeMounts {
+		mountPaths = append(mountPaths, m.MountPath)
+	}
+	require.Equal(t, []string{
+		"/etc/metricbeat-config",
+		"/usr/share/metricbeat/data",
+		"/mnt/extra",
+		"/mnt/elastic-internal/metricbeat-monitoring-es-ca",
+	}, mountPaths)
+	envVars := make(map[string]corev1.EnvVar, len(sidecar.Container.Env))
+	for _, e := range sidecar.Container.Env {
+		envVars[e.Name] = e
+	}
+	require.Equal(t, "extra", envVars["EXTRA"].Value)
+	require.Equal(t, &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "es-es-monitoring-metrics-user"},
+		Key:                  "ns-es-es-monitoring-metrics-user",
+	}, envVars["MONITORING_OUTPUT_PASSWORD"].ValueFrom.SecretKeyRef)
+
+	// volumes
+	volumeNames := make([]string, 0, len(sidecar.Volumes))
+	for _, v := range sidecar.Volumes {
+		volumeNames = append(volumeNames, v.Name)
+	}
+	require.Equal(t, []string{"metricbeat-config", "metricbeat-data", "metricbeat-monitoring-es-ca"}, volumeNames)
+}
+
+func TestNewFilebeatSidecar_WithoutCA(t *testing.T) {
+	params := testParams()
+	params.AssociationConf = &commonv1.AssociationConf{
+		AuthSecretName: "user",
+		AuthSecretKey:  "key",
+		URL:            "http://monitoring-es-http.observability.svc:9200",
+	}
+	sidecar, err := NewFilebeatSidecar(params)
+	require.NoError(t, err)
+	require.Equal(t, "es-es-monitoring-filebeat", sidecar.ConfigSecret.Name)
+	require.Equal(t, "filebeat", sidecar.Container.Name)
+	require.Len(t, sidecar.Volumes, 2)
+	cfg, err := settings.ParseConfig(sidecar.ConfigSecret.Data["filebeat.yml"])
+	require.NoError(t, err)
+	require.Equal(t, []string{"filebeat.modules"}, cfg.HasKeys([]string{"filebeat.modules"}))
+	var output struct {
+		Output struct {
+			Elasticsearch map[string]interface{} `config:"elasticsearch"`
+		} `config:"output"`
+	}
+	require.NoError(t, cfg.Unpack(&output))
+	require.NotContains(t, output.Output.Elasticsearch, "ssl")
+}
+
+func TestWithBeatSidecars(t *testing.T) {
+	metricbeat, err := NewMetricbeatSidecar(testParams())
+	require.NoError(t, err)
+	filebeat, err := NewFilebeatSidecar(testParams())
+	require.NoError(t, err)
+
+	// no sidecars: the Pod template is left untouched
+	builder := WithBeatSidecars(defaults.NewPodTemplateBuilder(corev1.PodTemplateSpec{}, "elasticsearch"))
+	require.Len(t, builder.PodTemplate.Spec.Containers, 1)
+	require.NotContains(t, builder.PodTemplate.Annotations, ConfigHashAnnotationName)
+
+	builder = WithBeatSidecars(defaults.NewPodTemplateBuilder(corev1.PodTemplateSpec{}, "elasticsearch"), metricbeat, filebeat)
+	podSpec := builder.PodTemplate.Spec
+	require.Len(t, podSpec.Containers, 3)
+	require.Equal(t, "metricbeat", podSpec.Containers[1].Name)
+	require.Equal(t, "filebeat", podSpec.Containers[2].Name)
+	require.Len(t, podSpec.Volumes, 6)
+	hash := builder.PodTemplate.Annotations[ConfigHashAnnotationName]
+	require.NotEmpty(t, hash)
+
+	// the hash changes with the configuration of the sidecars
+	params := testParams()
+	params.Modules = []map[string]interface{}{{"module": "elasticsearch", "period": "20s"}}
+	updated, err := NewMetricbeatSidecar(params)
+	require.NoError(t, err)
+	builder = WithBeatSidecars(defaults.NewPodTemplateBuilder(corev1.PodTemplateSpec{}, "elasticsearch"), updated, filebeat)
+	require.NotEqual(t, hash, builder.PodTemplate.Annotations[ConfigHashAnnotationName])
+}
+
+func TestReconcileConfigSecrets(t *testing.T) {
+	metricbeat, err := NewMetricbeatSidecar(testParams())
+	require.NoError(t, err)
+	filebeat, err := NewFilebeatSidecar(testParams())
+	require.NoError(t, err)
+
+	c := k8s.WrappedFakeClient()
+	require.NoError(t, ReconcileConfigSecrets(c, &testES, esv1.ESNamer, metricbeat, filebeat))
+	for _, name := range []string{"es-es-monitoring-metricbeat", "es-es-monitoring-filebeat"} {
+		var secret corev1.Secret
+		require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: name}, &secret))
+		require.Len(t, secret.OwnerReferences, 1)
+	}
+
+	// disabling logs monitoring removes the Filebeat configuration
+	require.NoError(t, ReconcileConfigSecrets(c, &testES, esv1.ESNamer, metricbeat))
+	var secret corev1.Secret
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: "es-es-monitoring-metricbeat"}, &secret))
+	err = c.Get(types.NamespacedName{Namespace: "ns", Name: "es-es-monitoring-filebeat"}, &secret)
+	require.True(t, apierrors.IsNotFound(err))
+}
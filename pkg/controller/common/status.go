@@ -5,9 +5,14 @@
 package common
 
 import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
@@ -39,3 +44,58 @@ func workaroundStatusUpdateError(err error, client k8s.Client, obj runtime.Objec
 	)
 	return client.Update(obj)
 }
+
+// ReportRunningVersion records the lowest version running in the Pods of a resource in its status, along with the
+// RunningDesiredVersion condition. A nil lowest version means no Pod is running, in which case the condition status
+// is unknown.
+func ReportRunningVersion(status *commonv1.ReconcilerStatus, lowest *version.Version, desired string) {
+	condition := commonv1.Condition{Type: commonv1.RunningDesiredVersion, Status: corev1.ConditionTrue}
+	status.Version = ""
+	if lowest != nil {
+		status.Version = lowest.String()
+	}
+	desiredVersion, err := version.Parse(desired)
+	switch {
+	case lowest == nil:
+		condition.Status = corev1.ConditionUnknown
+		condition.Message = "No Pod is running"
+	case err != nil:
+		condition.Status = corev1.ConditionUnknown
+		condition.Message = fmt.Sprintf("Invalid desired version %s", desired)
+	case !lowest.IsSame(*desiredVersion) || lowest.Label != desiredVersion.Label:
+		condition.Status = corev1.ConditionFalse
+		condition.Message = fmt.Sprintf("Version %s is running, %s is desired", lowest, desiredVersion)
+	}
+	status.Conditions = status.Conditions.MergeWith(condition)
+}
+
+// ReportReconciliation records the generation of a resource observed by its last reconciliation in its status,
+// along with the ReconciliationComplete condition. The reconciliation is complete if it did not fail, no change is
+// pending, and the resource does not run a version other than its desired version.
+func ReportReconciliation(status *commonv1.ReconcilerStatus, generation int64, err error, pending string) {
+	status.ObservedGeneration = generation
+	condition := commonv1.Condition{Type: commonv1.ReconciliationComplete, Status: corev1.ConditionFalse}
+	versionIdx := status.Conditions.Index(commonv1.RunningDesiredVersion)
+	switch {
+	case err != nil:
+		condition.Message = err.Error()
+	case pending != "":
+		condition.Message = pending
+	case versionIdx >= 0 && status.Conditions[versionIdx].Status == corev1.ConditionFalse:
+		condition.Message = status.Conditions[versionIdx].Message
+	default:
+		condition.Status = corev1.ConditionTrue
+	}
+	status.Conditions = status.Conditions.MergeWith(condition)
+}
+
+// UpdateReconciliationStatus reports the reconciliation of the given generation of a resource in its status. The
+// reconciliation is complete once it returned no error and the resource is ready: notReadyMsg explains what is
+// pending otherwise.
+func UpdateReconciliationStatus(status *commonv1.ReconcilerStatus, generation int64, err error, ready bool, notReadyMsg string) {
+	var pending string
+	if !ready {
+		pending = notReadyMsg
+	}
+	ReportReconciliation(status, generation, err, pending)
+}
@@ -14,6 +14,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
 
@@ -57,3 +59,153 @@ func Test_workaroundStatusUpdateError(t *testing.T) {
 		})
 	}
 }
+
+func conditionStatus(status commonv1.ReconcilerStatus, conditionType commonv1.ConditionType) corev1.ConditionStatus {
+	i := status.Conditions.Index(conditionType)
+	if i < 0 {
+		return ""
+	}
+	return status.Conditions[i].Status
+}
+
+func TestReportRunningVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		lowest        *version.Version
+		desired       string
+		wantVersion   string
+		wantCondition corev1.ConditionStatus
+	}{
+		{
+			name:          "no pod running",
+			lowest:        nil,
+			desired:       "7.8.0",
+			wantVersion:   "",
+			wantCondition: corev1.ConditionUnknown,
+		},
+		{
+			name:          "desired version running",
+			lowest:        &version.Version{Major: 7, Minor: 8, Patch: 0},
+			desired:       "7.8.0",
+			wantVersion:   "7.8.0",
+			wantCondition: corev1.ConditionTrue,
+		},
+		{
+			name:          "upgrade in progress",
+			lowest:        &version.Version{Major: 7, Minor: 7, Patch: 1},
+			desired:       "7.8.0",
+			wantVersion:   "7.7.1",
+			wantCondition: corev1.ConditionFalse,
+		},
+		{
+			name:          "different label",
+			lowest:        &version.Version{Major: 7, Minor: 8, Patch: 0},
+			desired:       "7.8.0-SNAPSHOT",
+			wantVersion:   "7.8.0",
+			wantCondition: corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := commonv1.ReconcilerStatus{Version: "7.6.0"}
+			ReportRunningVersion(&status, tt.lowest, tt.desired)
+			require.Equal(t, tt.wantVersion, status.Version)
+			require.Equal(t, tt.wantCondition, conditionStatus(status, commonv1.RunningDesiredVersion))
+		})
+	}
+}
+
+func TestReportReconciliation(t *testing.T) {
+	runningVersion := func(status corev1.ConditionStatus) commonv1.Conditions {
+		return commonv1.Conditions{{Type: commonv1.RunningDesiredVersion, Status: status, Message: "version message"}}
+	}
+	tests := []struct {
+		name          string
+		conditions    commonv1.Conditions
+		err           error
+		pending       string
+		wantCondition corev1.ConditionStatus
+		wantMessage   string
+	}{
+		{
+			name:          "complete",
+			conditions:    runningVersion(corev1.ConditionTrue),
+			wantCondition: corev1.ConditionTrue,
+		},
+		{
+			name:          "complete, no pod running",
+			conditions:    runningVersion(corev1.ConditionUnknown),
+			wantCondition: corev1.ConditionTrue,
+		},
+		{
+			name:          "error",
+			conditions:    runningVersion(corev1.ConditionTrue),
+			err:           errors.New("something failed"),
+			wantCondition: corev1.ConditionFalse,
+			wantMessage:   "something failed",
+		},
+		{
+			name:          "pending change",
+			conditions:    runningVersion(corev1.ConditionTrue),
+			pending:       "not available",
+			wantCondition: corev1.ConditionFalse,
+			wantMessage:   "not available",
+		},
+		{
+			name:          "not running the desired version",
+			conditions:    runningVersion(corev1.ConditionFalse),
+			wantCondition: corev1.ConditionFalse,
+			wantMessage:   "version message",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := commonv1.ReconcilerStatus{Conditions: tt.conditions}
+			ReportReconciliation(&status, 3, tt.err, tt.pending)
+			require.Equal(t, int64(3), status.ObservedGeneration)
+			i := status.Conditions.Index(commonv1.ReconciliationComplete)
+			require.True(t, i >= 0)
+			require.Equal(t, tt.wantCondition, status.Conditions[i].Status)
+			require.Equal(t, tt.wantMessage, status.Conditions[i].Message)
+		})
+	}
+}
+
+func TestUpdateReconciliationStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		ready         bool
+		wantCondition corev1.ConditionStatus
+		wantMessage   string
+	}{
+		{
+			name:          "ready",
+			ready:         true,
+			wantCondition: corev1.ConditionTrue,
+		},
+		{
+			name:          "not ready",
+			wantCondition: corev1.ConditionFalse,
+			wantMessage:   "not available",
+		},
+		{
+			name:          "error",
+			err:           errors.New("something failed"),
+			ready:         true,
+			wantCondition: corev1.ConditionFalse,
+			wantMessage:   "something failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status commonv1.ReconcilerStatus
+			UpdateReconciliationStatus(&status, 3, tt.err, tt.ready, "not available")
+			require.Equal(t, int64(3), status.ObservedGeneration)
+			i := status.Conditions.Index(commonv1.ReconciliationComplete)
+			require.True(t, i >= 0)
+			require.Equal(t, tt.wantCondition, status.Conditions[i].Status)
+			require.Equal(t, tt.wantMessage, status.Conditions[i].Message)
+		})
+	}
+}
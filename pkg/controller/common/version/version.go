@@ -11,11 +11,14 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // supported Stack versions. See https://www.elastic.co/support/matrix#matrix_compatibility
 var (
+	SupportedAgentVersions            = MinMaxVersion{Min: From(7, 10, 0), Max: From(8, 99, 99)}
 	SupportedAPMServerVersions        = MinMaxVersion{Min: From(6, 2, 0), Max: From(8, 99, 99)}
+	SupportedBeatVersions             = MinMaxVersion{Min: From(7, 0, 0), Max: From(8, 99, 99)}
 	SupportedEnterpriseSearchVersions = MinMaxVersion{Min: From(7, 7, 0), Max: From(8, 99, 99)}
 	SupportedKibanaVersions           = MinMaxVersion{Min: From(6, 8, 0), Max: From(8, 99, 99)}
 )
@@ -152,3 +155,20 @@ func FromLabels(labels map[string]string, labelName string) (*Version, error) {
 	}
 	return v, nil
 }
+
+// MinInPods returns the lowest version specified in the given label of the given Pods, or nil if no Pod has the label.
+// Pods without the label, for example created by a previous operator version, are ignored.
+func MinInPods(pods []corev1.Pod, labelName string) (*Version, error) {
+	vs := make([]Version, 0, len(pods))
+	for _, pod := range pods {
+		if _, exists := pod.Labels[labelName]; !exists {
+			continue
+		}
+		v, err := FromLabels(pod.Labels, labelName)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, *v)
+	}
+	return Min(vs), nil
+}
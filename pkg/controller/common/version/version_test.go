@@ -10,6 +10,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestParse(t *testing.T) {
@@ -373,3 +375,60 @@ func TestFromLabels(t *testing.T) {
 		})
 	}
 }
+
+func TestMinInPods(t *testing.T) {
+	labelName := "label-with-version"
+	podWithLabels := func(labels map[string]string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: labels}}
+	}
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		want    *Version
+		wantErr bool
+	}{
+		{
+			name: "no pods",
+			pods: nil,
+			want: nil,
+		},
+		{
+			name: "lowest version",
+			pods: []corev1.Pod{
+				podWithLabels(map[string]string{labelName: "7.8.0"}),
+				podWithLabels(map[string]string{labelName: "7.7.1"}),
+				podWithLabels(map[string]string{labelName: "7.8.0"}),
+			},
+			want: &Version{Major: 7, Minor: 7, Patch: 1},
+		},
+		{
+			name: "pods without the label are ignored",
+			pods: []corev1.Pod{
+				podWithLabels(nil),
+				podWithLabels(map[string]string{labelName: "7.8.0"}),
+			},
+			want: &Version{Major: 7, Minor: 8, Patch: 0},
+		},
+		{
+			name: "no pod with the label",
+			pods: []corev1.Pod{podWithLabels(nil)},
+			want: nil,
+		},
+		{
+			name:    "invalid version",
+			pods:    []corev1.Pod{podWithLabels(map[string]string{labelName: "invalid"})},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MinInPods(tt.pods, labelName)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
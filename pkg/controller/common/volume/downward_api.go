@@ -31,16 +31,37 @@ var downwardAPIVolumeMount = corev1.VolumeMount{
 	ReadOnly:  true,
 }
 
-type DownwardAPI struct{}
+var downwardAPIAnnotationsFile = corev1.DownwardAPIVolumeFile{
+	Path: volume.AnnotationsFile,
+	FieldRef: &corev1.ObjectFieldSelector{
+		FieldPath: "metadata.annotations",
+	},
+}
+
+type DownwardAPI struct {
+	// withAnnotations exposes the Pod annotations in addition to the Pod labels
+	withAnnotations bool
+}
 
 var _ VolumeLike = DownwardAPI{}
 
+// WithAnnotations returns a copy of this DownwardAPI volume that also exposes the Pod annotations if withAnnotations is true.
+func (d DownwardAPI) WithAnnotations(withAnnotations bool) DownwardAPI {
+	d.withAnnotations = withAnnotations
+	return d
+}
+
 func (DownwardAPI) Name() string {
 	return volume.DownwardAPIVolumeName
 }
 
-func (DownwardAPI) Volume() corev1.Volume {
-	return downwardAPIVolume
+func (d DownwardAPI) Volume() corev1.Volume {
+	if !d.withAnnotations {
+		return downwardAPIVolume
+	}
+	vol := *downwardAPIVolume.DeepCopy()
+	vol.DownwardAPI.Items = append(vol.DownwardAPI.Items, downwardAPIAnnotationsFile)
+	return vol
 }
 
 func (DownwardAPI) VolumeMount() corev1.VolumeMount {
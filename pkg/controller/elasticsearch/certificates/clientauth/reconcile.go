@@ -0,0 +1,194 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package clientauth manages the authentication of clients with certificates on the HTTP layer of Elasticsearch.
+//
+// The operator issues client certificates from a dedicated CA, trusted by Elasticsearch along with the CAs provided
+// by the user. It presents its own client certificate to Elasticsearch, and so do the readiness probe and the Metricbeat
+// sidecar collecting the metrics of Elasticsearch.
+package clientauth
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var log = logf.Log.WithName("clientauth")
+
+const (
+	// OperatorCommonName is the common name of the client certificate presented by the operator and the readiness
+	// probe. No role is mapped to it: they still authenticate with a file realm user.
+	OperatorCommonName = "elastic-internal-client"
+	// OperatorCAFileName is the key of the CA issuing the client certificates of the operator and the associated
+	// resources in the secret mounted in the Elasticsearch Pods. Only the certificates it issues are mapped to roles
+	// by the PKI realm, the user-provided CAs are only trusted on the HTTP layer.
+	OperatorCAFileName = "operator-ca.crt"
+)
+
+// CustomCAWatchKey returns the key used by the dynamic watch registration for the user-provided client CAs.
+func CustomCAWatchKey(es types.NamespacedName) string {
+	return esv1.ESNamer.Suffix(es.Name, "client-ca")
+}
+
+// CASecretKey returns a reference to the secret holding the CA issuing the client certificates of the given cluster.
+func CASecretKey(es types.NamespacedName) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: es.Namespace,
+		Name:      certificates.CAInternalSecretName(esv1.ESNamer, es.Name, certificates.ClientCAType),
+	}
+}
+
+// Reconcile reconciles the CA issuing the client certificates of the given cluster, and the secret mounted in the
+// Elasticsearch Pods holding the trusted client CAs and the client certificate of the operator.
+// It returns the client certificate of the operator, nil if client authentication is disabled, along with the time
+// after which the certificates must be reconciled again.
+func Reconcile(
+	c k8s.Client,
+	dynamicWatches watches.DynamicWatches,
+	recorder record.EventRecorder,
+	es esv1.Elasticsearch,
+	labels map[string]string,
+	caRotation certificates.RotationParams,
+	certRotation certificates.RotationParams,
+) (*tls.Certificate, time.Duration, error) {
+	esNSN := k8s.ExtractNamespacedName(&es)
+	tlsOptions := es.Spec.HTTP.TLS
+	var customCASecrets []string
+	if tlsOptions.ClientAuthenticationEnabled() && tlsOptions.Client.CertificateAuthorities.SecretName != "" {
+		customCASecrets = []string{tlsOptions.Client.CertificateAuthorities.SecretName}
+	}
+	// watch the user-provided CAs to update the trusted CAs if they change
+	if err := watches.WatchUserProvidedSecrets(esNSN, dynamicWatches, CustomCAWatchKey(esNSN), customCASecrets); err != nil {
+		return nil, 0, err
+	}
+	if !tlsOptions.ClientAuthenticationEnabled() {
+		return nil, 0, garbageCollectSecrets(c, es)
+	}
+
+	ca, err := certificates.ReconcileCAForOwner(c, esv1.ESNamer, &es, labels, certificates.ClientCAType, caRotation, recorder)
+	if err != nil {
+		return nil, 0, err
+	}
+	certificates.ReportExpiration(certificates.KindOf(&es), esNSN, certificates.ClientCACertificate, ca.Cert.NotAfter)
+
+	trustedCAs := certificates.EncodePEMCert(ca.Cert.Raw)
+	for _, secretName := range customCASecrets {
+		var secret corev1.Secret
+		if err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: secretName}, &secret); err != nil {
+			return nil, 0, err
+		}
+		customCAs, exists := secret.Data[certificates.CAFileName]
+		if !exists {
+			return nil, 0, errors.Errorf("can't find client CA certificates %s in %s/%s", certificates.CAFileName, secret.Namespace, secret.Name)
+		}
+		if _, err := certificates.ParsePEMCerts(customCAs); err != nil {
+			return nil, 0, errors.Wrapf(err, "invalid client CA certificates in %s/%s", secret.Namespace, secret.Name)
+		}
+		trustedCAs = append(trustedCAs, customCAs...)
+	}
+
+	var existing corev1.Secret
+	secretKey := types.NamespacedName{Namespace: es.Namespace, Name: esv1.HTTPClientCertsSecret(es.Name)}
+	if err := c.Get(secretKey, &existing); err != nil && !apierrors.IsNotFound(err) {
+		return nil, 0, err
+	}
+	now := time.Now()
+	clientCert, err := certificates.EnsureClientCertificate(ca, OperatorCommonName, existing.Data, certRotation, now)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	expected := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: secretKey.Namespace,
+			Name:      secretKey.Name,
+			Labels:    labels,
+		},
+		Data: map[string][]byte{
+			certificates.CAFileName:   trustedCAs,
+			OperatorCAFileName:        certificates.EncodePEMCert(ca.Cert.Raw),
+			certificates.CertFileName: clientCert.CertPem,
+			certificates.KeyFileName:  clientCert.KeyPem,
+		},
+	}
+	if _, err := reconciler.ReconcileSecret(c, expected, &es); err != nil {
+		return nil, 0, err
+	}
+
+	operatorCert, err := tls.X509KeyPair(clientCert.CertPem, clientCert.KeyPem)
+	if err != nil {
+		return nil, 0, err
+	}
+	requeueAfter := certificates.ShouldRotateIn(now, clientCert.Cert.NotAfter, certRotation.RotateBefore)
+	if caRequeueAfter := certificates.ShouldRotateIn(now, ca.Cert.NotAfter, caRotation.RotateBefore); caRequeueAfter < requeueAfter {
+		requeueAfter = caRequeueAfter
+	}
+	return &operatorCert, requeueAfter, nil
+}
+
+// OperatorCertificate returns the client certificate the operator presents to the given cluster, nil if client
+// authentication is disabled.
+func OperatorCertificate(c k8s.Client, es esv1.Elasticsearch) (*tls.Certificate, error) {
+	if !es.Spec.HTTP.TLS.ClientAuthenticationEnabled() {
+		return nil, nil
+	}
+	var secret corev1.Secret
+	if err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: esv1.HTTPClientCertsSecret(es.Name)}, &secret); err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(secret.Data[certificates.CertFileName], secret.Data[certificates.KeyFileName])
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// garbageCollectSecrets deletes the CA issuing the client certificates and the secret holding the trusted client CAs
+// once client authentication is disabled. They are kept around as long as the secret is mounted in Pods not restarted
+// yet, the restart of the last one triggering a new reconciliation.
+func garbageCollectSecrets(c k8s.Client, es esv1.Elasticsearch) error {
+	secretName := esv1.HTTPClientCertsSecret(es.Name)
+	var pods corev1.PodList
+	if err := c.List(&pods, client.InNamespace(es.Namespace), label.NewLabelSelectorForElasticsearch(es)); err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.Secret != nil && volume.Secret.SecretName == secretName {
+				return nil
+			}
+		}
+	}
+	esNSN := k8s.ExtractNamespacedName(&es)
+	for _, secretKey := range []types.NamespacedName{{Namespace: es.Namespace, Name: secretName}, CASecretKey(esNSN)} {
+		var secret corev1.Secret
+		if err := c.Get(secretKey, &secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		log.Info("Deleting client authentication secret", "namespace", secret.Namespace, "secret_name", secret.Name)
+		if err := c.Delete(&secret); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package clientauth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func TestReconcile_DeletesSecretsOnceClientAuthenticationIsDisabled(t *testing.T) {
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	esNSN := k8s.ExtractNamespacedName(&es)
+	clientCertsKey := types.NamespacedName{Namespace: "ns", Name: "es-es-http-client-certs"}
+	secrets := []runtime.Object{
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: clientCertsKey.Name}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: CASecretKey(esNSN).Name}},
+	}
+	podMountingSecret := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es-es-default-0", Labels: label.NewLabels(esNSN)},
+		Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+			Name:         "elastic-internal-http-client-certificates",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: clientCertsKey.Name}},
+		}}},
+	}
+	podRestarted := podMountingSecret.DeepCopy()
+	podRestarted.Spec.Volumes = nil
+
+	tests := []struct {
+		name            string
+		pod             *corev1.Pod
+		wantSecretsLeft bool
+	}{
+		{
+			name:            "secrets kept while mounted in a Pod",
+			pod:             podMountingSecret,
+			wantSecretsLeft: true,
+		},
+		{
+			name:            "secrets deleted once no Pod mounts them",
+			pod:             podRestarted,
+			wantSecretsLeft: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := append([]runtime.Object{tt.pod}, secrets...)
+			c := k8s.WrappedFakeClient(objs...)
+			cert, _, err := Reconcile(
+				c, watches.NewDynamicWatches(), record.NewFakeRecorder(10), es, label.NewLabels(esNSN),
+				certificates.RotationParams{}, certificates.RotationParams{},
+			)
+			require.NoError(t, err)
+			require.Nil(t, cert)
+			for _, key := range []types.NamespacedName{clientCertsKey, CASecretKey(esNSN)} {
+				err := c.Get(key, &corev1.Secret{})
+				if tt.wantSecretsLeft {
+					require.NoError(t, err)
+				} else {
+					require.True(t, apierrors.IsNotFound(err))
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_OnlyOperatorCAIsMappedByThePKIRealm(t *testing.T) {
+	userCA, err := certificates.NewSelfSignedCA(certificates.CABuilderOptions{Subject: pkix.Name{CommonName: "user-ca"}})
+	require.NoError(t, err)
+	userCASecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "user-ca"},
+		Data:       map[string][]byte{certificates.CAFileName: certificates.EncodePEMCert(userCA.Cert.Raw)},
+	}
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec: esv1.ElasticsearchSpec{HTTP: commonv1.HTTPConfig{TLS: commonv1.TLSOptions{
+			Client: &commonv1.ClientAuthentication{CertificateAuthorities: commonv1.SecretRef{SecretName: "user-ca"}},
+		}}},
+	}
+	esNSN := k8s.ExtractNamespacedName(&es)
+	rotation := certificates.RotationParams{Validity: certificates.DefaultCertValidity, RotateBefore: certificates.DefaultRotateBefore}
+	c := k8s.WrappedFakeClient(userCASecret)
+	operatorCert, _, err := Reconcile(c, watches.NewDynamicWatches(), record.NewFakeRecorder(10), es, label.NewLabels(esNSN), rotation, rotation)
+	require.NoError(t, err)
+	require.NotNil(t, operatorCert)
+
+	var secret corev1.Secret
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: esv1.HTTPClientCertsSecret("es")}, &secret))
+	poolOf := func(pem []byte) *x509.CertPool {
+		cas, err := certificates.ParsePEMCerts(pem)
+		require.NoError(t, err)
+		pool := x509.NewCertPool()
+		for _, ca := range cas {
+			pool.AddCert(ca)
+		}
+		return pool
+	}
+	verify := func(cert *x509.Certificate, pool *x509.CertPool) error {
+		_, err := cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+		return err
+	}
+	trustedCAs := poolOf(secret.Data[certificates.CAFileName])
+	operatorCAs := poolOf(secret.Data[OperatorCAFileName])
+
+	// a certificate issued by the user CA for the name of an associated resource is trusted on the HTTP layer,
+	// but not by the PKI realm mapping the names of the associated resources to roles
+	userCert, err := certificates.EnsureClientCertificate(userCA, "ns-kibana-kb-user", nil, rotation, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, verify(userCert.Cert, trustedCAs))
+	require.Error(t, verify(userCert.Cert, operatorCAs))
+
+	// the certificate of the operator is trusted by both
+	cert, err := x509.ParseCertificate(operatorCert.Certificate[0])
+	require.NoError(t, err)
+	require.NoError(t, verify(cert, trustedCAs))
+	require.NoError(t, verify(cert, operatorCAs))
+}
@@ -6,6 +6,7 @@ package certificates
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/driver"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates/clientauth"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates/remoteca"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates/transport"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
@@ -30,6 +32,10 @@ type CertificateResources struct {
 
 	// TransportCA is the CA used for Transport certificates
 	TransportCA *certificates.CA
+
+	// OperatorClientCertificate is the certificate presented by the operator to Elasticsearch, nil if client
+	// authentication is disabled on the HTTP layer.
+	OperatorClientCertificate *tls.Certificate
 }
 
 // Reconcile reconciles the certificates of a cluster.
@@ -40,6 +46,7 @@ func Reconcile(
 	services []corev1.Service,
 	caRotation certificates.RotationParams,
 	certRotation certificates.RotationParams,
+	certManagerIssuer *certificates.IssuerRef,
 ) (*CertificateResources, *reconciler.Results) {
 	span, _ := apm.StartSpan(ctx, "reconcile_certs", tracing.SpanTypeApp)
 	defer span.End()
@@ -57,15 +64,17 @@ func Reconcile(
 	// reconcile HTTP CA and cert
 	var httpCerts *certificates.CertificatesSecret
 	httpCerts, results = certificates.Reconciler{
-		K8sClient:      driver.K8sClient(),
-		DynamicWatches: driver.DynamicWatches(),
-		Object:         &es,
-		TLSOptions:     es.Spec.HTTP.TLS,
-		Namer:          esv1.ESNamer,
-		Labels:         certsLabels,
-		Services:       services,
-		CACertRotation: caRotation,
-		CertRotation:   certRotation,
+		K8sClient:         driver.K8sClient(),
+		DynamicWatches:    driver.DynamicWatches(),
+		Object:            &es,
+		TLSOptions:        es.Spec.HTTP.TLS,
+		Namer:             esv1.ESNamer,
+		Labels:            certsLabels,
+		Services:          services,
+		CACertRotation:    caRotation,
+		CertRotation:      certRotation,
+		CertManagerIssuer: certManagerIssuer,
+		Recorder:          driver.Recorder(),
 		// ES is able to hot-reload TLS certificates: let's keep secrets around even though TLS is disabled.
 		// In case TLS is toggled on/off/on quickly enough, removing the secret would prevent future certs to be available.
 		GarbageCollectSecrets: false,
@@ -74,47 +83,77 @@ func Reconcile(
 		return nil, results
 	}
 
-	// reconcile transport CA and certs
-	transportCA, err := certificates.ReconcileCAForOwner(
+	// reconcile the client certificates of the HTTP layer
+	operatorClientCert, requeueAfter, err := clientauth.Reconcile(
 		driver.K8sClient(),
-		esv1.ESNamer,
-		&es,
+		driver.DynamicWatches(),
+		driver.Recorder(),
+		es,
 		certsLabels,
-		certificates.TransportCAType,
 		caRotation,
+		certRotation,
 	)
 	if err != nil {
 		return nil, results.WithError(err)
 	}
-	// make sure to requeue before the CA cert expires
-	results.WithResult(reconcile.Result{
-		RequeueAfter: certificates.ShouldRotateIn(time.Now(), transportCA.Cert.NotAfter, caRotation.RotateBefore),
-	})
+	results.WithResult(reconcile.Result{RequeueAfter: requeueAfter})
 
-	// reconcile transport public certs secret
-	if err := transport.ReconcileTransportCertsPublicSecret(driver.K8sClient(), es, transportCA); err != nil {
+	// reconcile transport CA and certs
+	transportCA, err := transport.ReconcileOrRetrieveCA(
+		driver.K8sClient(),
+		driver.DynamicWatches(),
+		driver.Recorder(),
+		es,
+		certsLabels,
+		caRotation,
+	)
+	if err != nil {
 		return nil, results.WithError(err)
 	}
+	certificates.ReportExpiration(
+		certificates.KindOf(&es), k8s.ExtractNamespacedName(&es), certificates.TransportCACertificate, transportCA.Cert.NotAfter,
+	)
+	if !es.Spec.Transport.TLS.UserDefinedCA() {
+		// make sure to requeue before the CA cert expires
+		results.WithResult(reconcile.Result{
+			RequeueAfter: certificates.ShouldRotateIn(time.Now(), transportCA.Cert.NotAfter, caRotation.RotateBefore),
+		})
+	}
+
+	// user-provided transport CAs take precedence over cert-manager
+	transportIssuer := certManagerIssuer
+	if es.Spec.Transport.TLS.UserDefinedCA() {
+		transportIssuer = nil
+	}
 
 	// reconcile transport certificates
-	transportResults := transport.ReconcileTransportCertificatesSecrets(
+	trustedTransportCAs, transportResults := transport.ReconcileTransportCertificatesSecrets(
 		driver.K8sClient(),
+		driver.DynamicWatches(),
+		driver.Recorder(),
 		transportCA,
 		es,
 		certRotation,
+		transportIssuer,
 	)
 
 	if results.WithResults(transportResults).HasError() {
 		return nil, results
 	}
 
+	// reconcile transport public certs secret
+	if err := transport.ReconcileTransportCertsPublicSecret(driver.K8sClient(), es, trustedTransportCAs); err != nil {
+		return nil, results.WithError(err)
+	}
+
 	trustedHTTPCertificates, err := certificates.ParsePEMCerts(httpCerts.CertPem())
 	if err != nil {
 		return nil, results.WithError(err)
 	}
 
 	return &CertificateResources{
-		TrustedHTTPCertificates: trustedHTTPCertificates,
-		TransportCA:             transportCA,
+		TrustedHTTPCertificates:   trustedHTTPCertificates,
+		TransportCA:               transportCA,
+		OperatorClientCertificate: operatorClientCert,
 	}, results
 }
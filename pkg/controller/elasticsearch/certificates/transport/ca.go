@@ -0,0 +1,114 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package transport
+
+import (
+	"crypto/rsa"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// CustomCAWatchKey returns the key used by the dynamic watch registration for the user-provided transport CA.
+func CustomCAWatchKey(es types.NamespacedName) string {
+	return esv1.ESNamer.Suffix(es.Name, "transport-ca")
+}
+
+// ReconcileOrRetrieveCA returns the CA issuing the transport certificates of the given cluster:
+// - the CA provided by the user in the secret referenced by spec.transport.tls.certificate, if any,
+// - otherwise a self-signed CA, generated and rotated by the operator.
+func ReconcileOrRetrieveCA(
+	c k8s.Client,
+	dynamicWatches watches.DynamicWatches,
+	recorder record.EventRecorder,
+	es esv1.Elasticsearch,
+	labels map[string]string,
+	rotationParams certificates.RotationParams,
+) (*certificates.CA, error) {
+	esNSN := k8s.ExtractNamespacedName(&es)
+
+	if !es.Spec.Transport.TLS.UserDefinedCA() {
+		// remove any watch on a previously provided CA
+		if err := watches.WatchUserProvidedSecrets(esNSN, dynamicWatches, CustomCAWatchKey(esNSN), nil); err != nil {
+			return nil, err
+		}
+		return certificates.ReconcileCAForOwner(c, esv1.ESNamer, &es, labels, certificates.TransportCAType, rotationParams, recorder)
+	}
+
+	// watch the user-provided CA to reissue the certificates if it changes
+	secretName := es.Spec.Transport.TLS.Certificate.SecretName
+	if err := watches.WatchUserProvidedSecrets(esNSN, dynamicWatches, CustomCAWatchKey(esNSN), []string{secretName}); err != nil {
+		return nil, err
+	}
+	var secret corev1.Secret
+	if err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: secretName}, &secret); err != nil {
+		return nil, err
+	}
+	return buildCustomCA(secret)
+}
+
+// buildCustomCA parses and validates the CA certificate and private key of the given user-provided secret.
+func buildCustomCA(secret corev1.Secret) (*certificates.CA, error) {
+	certData, exists := secret.Data[certificates.CAFileName]
+	if !exists {
+		return nil, errors.Errorf("can't find CA certificate %s in %s/%s", certificates.CAFileName, secret.Namespace, secret.Name)
+	}
+	certs, err := certificates.ParsePEMCerts(certData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid CA certificate in %s/%s", secret.Namespace, secret.Name)
+	}
+	if len(certs) == 0 {
+		return nil, errors.Errorf("can't find any CA certificate in %s/%s", secret.Namespace, secret.Name)
+	}
+	// the first certificate issues the transport certificates, it may be followed by the chain of an intermediate CA
+	cert := certs[0]
+	for i := 1; i < len(certs); i++ {
+		if err := certs[i-1].CheckSignatureFrom(certs[i]); err != nil {
+			return nil, errors.Wrapf(err, "invalid CA certificate chain in %s/%s", secret.Namespace, secret.Name)
+		}
+	}
+
+	keyData, exists := secret.Data[certificates.CAKeyFileName]
+	if !exists {
+		return nil, errors.Errorf("can't find CA private key %s in %s/%s", certificates.CAKeyFileName, secret.Namespace, secret.Name)
+	}
+	privateKey, err := certificates.ParsePEMPrivateKey(keyData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid CA private key in %s/%s", secret.Namespace, secret.Name)
+	}
+
+	if !cert.IsCA {
+		return nil, errors.Errorf("certificate in %s/%s is not a CA certificate", secret.Namespace, secret.Name)
+	}
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, errors.Errorf("CA certificate in %s/%s is not valid at the current time", secret.Namespace, secret.Name)
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok || publicKey.N.Cmp(privateKey.PublicKey.N) != 0 || publicKey.E != privateKey.PublicKey.E {
+		return nil, errors.Errorf("CA private key in %s/%s does not match the CA certificate", secret.Namespace, secret.Name)
+	}
+
+	ca := certificates.NewCA(privateKey, cert)
+	ca.Chain = certs[1:]
+	return ca, nil
+}
+
+// caCertificates returns the DER-encoded certificate of the given CA, followed by its chain.
+func caCertificates(ca *certificates.CA) [][]byte {
+	certs := [][]byte{ca.Cert.Raw}
+	for _, cert := range ca.Chain {
+		certs = append(certs, cert.Raw)
+	}
+	return certs
+}
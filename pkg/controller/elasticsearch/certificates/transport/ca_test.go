@@ -0,0 +1,170 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package transport
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func customCASecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testES.Namespace, Name: "corporate-ca"},
+		Data:       data,
+	}
+}
+
+func Test_buildCustomCA(t *testing.T) {
+	validData := map[string][]byte{
+		certificates.CAFileName:    certificates.EncodePEMCert(testCA.Cert.Raw),
+		certificates.CAKeyFileName: certificates.EncodePEMPrivateKey(*testCA.PrivateKey),
+	}
+	otherCA, err := certificates.NewSelfSignedCA(certificates.CABuilderOptions{Subject: pkix.Name{CommonName: "other"}})
+	require.NoError(t, err)
+	expiredValidity := -time.Hour
+	expiredCA, err := certificates.NewSelfSignedCA(certificates.CABuilderOptions{
+		Subject:    pkix.Name{CommonName: "expired"},
+		PrivateKey: testRSAPrivateKey,
+		ExpireIn:   &expiredValidity,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		data    map[string][]byte
+		wantErr bool
+	}{
+		{
+			name: "valid CA",
+			data: validData,
+		},
+		{
+			name:    "missing certificate",
+			data:    map[string][]byte{certificates.CAKeyFileName: validData[certificates.CAKeyFileName]},
+			wantErr: true,
+		},
+		{
+			name:    "missing private key",
+			data:    map[string][]byte{certificates.CAFileName: validData[certificates.CAFileName]},
+			wantErr: true,
+		},
+		{
+			name: "private key does not match the certificate",
+			data: map[string][]byte{
+				certificates.CAFileName:    validData[certificates.CAFileName],
+				certificates.CAKeyFileName: certificates.EncodePEMPrivateKey(*otherCA.PrivateKey),
+			},
+			wantErr: true,
+		},
+		{
+			name: "not a CA certificate",
+			data: map[string][]byte{
+				certificates.CAFileName:    certificates.EncodePEMCert(certData),
+				certificates.CAKeyFileName: validData[certificates.CAKeyFileName],
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired CA",
+			data: map[string][]byte{
+				certificates.CAFileName:    certificates.EncodePEMCert(expiredCA.Cert.Raw),
+				certificates.CAKeyFileName: validData[certificates.CAKeyFileName],
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca, err := buildCustomCA(*customCASecret(tt.data))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ca.Cert.Equal(testCA.Cert))
+		})
+	}
+}
+
+func Test_buildCustomCA_IntermediateCA(t *testing.T) {
+	rootCA, err := certificates.NewSelfSignedCA(certificates.CABuilderOptions{Subject: pkix.Name{CommonName: "root"}})
+	require.NoError(t, err)
+	intermediateData, err := rootCA.CreateCertificate(certificates.ValidatedCertificateTemplate(x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		PublicKey:             testRSAPrivateKey.Public(),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}))
+	require.NoError(t, err)
+	intermediate, err := x509.ParseCertificate(intermediateData)
+	require.NoError(t, err)
+	key := certificates.EncodePEMPrivateKey(*testRSAPrivateKey)
+
+	// the intermediate CA issues the certificates, the whole chain is trusted
+	ca, err := buildCustomCA(*customCASecret(map[string][]byte{
+		certificates.CAFileName:    certificates.EncodePEMCert(intermediateData, rootCA.Cert.Raw),
+		certificates.CAKeyFileName: key,
+	}))
+	require.NoError(t, err)
+	require.True(t, ca.Cert.Equal(intermediate))
+	require.Len(t, ca.Chain, 1)
+	require.True(t, ca.Chain[0].Equal(rootCA.Cert))
+	require.Equal(t, [][]byte{intermediateData, rootCA.Cert.Raw}, caCertificates(ca))
+
+	// the chain must be ordered from the intermediate CA to the root CA
+	_, err = buildCustomCA(*customCASecret(map[string][]byte{
+		certificates.CAFileName:    certificates.EncodePEMCert(intermediateData, testCA.Cert.Raw),
+		certificates.CAKeyFileName: key,
+	}))
+	require.Error(t, err)
+}
+
+func TestReconcileOrRetrieveCA(t *testing.T) {
+	es := *testES.DeepCopy()
+	es.Spec.Transport.TLS.Certificate = commonv1.SecretRef{SecretName: "corporate-ca"}
+	c := k8s.WrappedFakeClient(&es, customCASecret(map[string][]byte{
+		certificates.CAFileName:    certificates.EncodePEMCert(testCA.Cert.Raw),
+		certificates.CAKeyFileName: certificates.EncodePEMPrivateKey(*testCA.PrivateKey),
+	}))
+	w := watches.NewDynamicWatches()
+	rotation := certificates.RotationParams{Validity: certificates.DefaultCertValidity, RotateBefore: certificates.DefaultRotateBefore}
+
+	// the user-provided CA is used, and watched
+	ca, err := ReconcileOrRetrieveCA(c, w, nil, es, nil, rotation)
+	require.NoError(t, err)
+	require.True(t, ca.Cert.Equal(testCA.Cert))
+	require.Contains(t, w.Secrets.Registrations(), CustomCAWatchKey(k8s.ExtractNamespacedName(&es)))
+	// no self-signed CA is generated
+	var selfSigned corev1.Secret
+	err = c.Get(k8s.ExtractNamespacedName(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace: es.Namespace,
+		Name:      certificates.CAInternalSecretName(esv1.ESNamer, es.Name, certificates.TransportCAType),
+	}}), &selfSigned)
+	require.Error(t, err)
+
+	// without a user-provided CA, a self-signed one is generated, and the watch removed
+	es.Spec.Transport.TLS.Certificate = commonv1.SecretRef{}
+	ca, err = ReconcileOrRetrieveCA(c, w, nil, es, nil, rotation)
+	require.NoError(t, err)
+	require.False(t, ca.Cert.Equal(testCA.Cert))
+	require.NotContains(t, w.Secrets.Registrations(), CustomCAWatchKey(k8s.ExtractNamespacedName(&es)))
+}
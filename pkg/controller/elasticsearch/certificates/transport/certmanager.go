@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package transport
+
+import (
+	"fmt"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// CertManagerCertificateName returns the name of the cert-manager Certificate issuing the transport certificate of
+// the given Pod, and of the Secret it is stored in.
+func CertManagerCertificateName(podName string) string {
+	return podName + "-transport-certs-issued"
+}
+
+// CertManagerWatchKey returns the key of the dynamic watch on the transport certificates issued by cert-manager.
+func CertManagerWatchKey(es types.NamespacedName) string {
+	return esv1.ESNamer.Suffix(es.Name, "transport-certificates")
+}
+
+// reconcileCertManagerCertificate reconciles the cert-manager Certificate for the transport layer of the given Pod,
+// and returns the issued certificate, nil if not issued yet.
+func reconcileCertManagerCertificate(
+	c k8s.Client,
+	es esv1.Elasticsearch,
+	pod corev1.Pod,
+	issuer certificates.IssuerRef,
+	rotationParams certificates.RotationParams,
+) (*corev1.Secret, error) {
+	generalNames, err := buildGeneralNames(es, pod)
+	if err != nil {
+		return nil, err
+	}
+	// the ES-customized othername SAN is not needed: Elasticsearch verifies the hostname or IP address of the nodes
+	var dnsNames, ipAddresses []string
+	for _, generalName := range generalNames {
+		if generalName.DNSName != "" {
+			dnsNames = append(dnsNames, generalName.DNSName)
+		}
+		if generalName.IPAddress != nil {
+			ipAddresses = append(ipAddresses, net.IP(generalName.IPAddress).String())
+		}
+	}
+	issued, err := certificates.ReconcileCertManagerCertificate(c, &es, certificates.CertManagerCertificate{
+		Name:        CertManagerCertificateName(pod.Name),
+		Namespace:   es.Namespace,
+		Labels:      label.NewLabels(k8s.ExtractNamespacedName(&es)),
+		Issuer:      issuer,
+		CommonName:  buildCertificateCommonName(pod, es.Name, es.Namespace),
+		DNSNames:    dnsNames,
+		IPAddresses: ipAddresses,
+		Rotation:    rotationParams,
+	})
+	if err != nil || issued == nil {
+		return nil, err
+	}
+	if len(issued.Data[certificates.CAFileName]) == 0 {
+		return nil, fmt.Errorf("no CA certificate in the transport certificate issued by cert-manager %s for pod %s/%s", issuer, pod.Namespace, pod.Name)
+	}
+	return issued, nil
+}
+
+// reconcileCertManagerWatch watches the Secrets of the transport certificates issued by cert-manager for the given
+// Pods, to update the transport certificates of the nodes when cert-manager renews them.
+func reconcileCertManagerWatch(
+	dynamicWatches watches.DynamicWatches,
+	es esv1.Elasticsearch,
+	pods []corev1.Pod,
+	issuer *certificates.IssuerRef,
+) error {
+	key := CertManagerWatchKey(k8s.ExtractNamespacedName(&es))
+	if issuer == nil {
+		dynamicWatches.Secrets.RemoveHandlerForKey(key)
+		return nil
+	}
+	watched := make([]types.NamespacedName, 0, len(pods))
+	for _, pod := range pods {
+		watched = append(watched, types.NamespacedName{Namespace: es.Namespace, Name: CertManagerCertificateName(pod.Name)})
+	}
+	return dynamicWatches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    key,
+		Watched: watched,
+		Watcher: k8s.ExtractNamespacedName(&es),
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package transport
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func TestReconcileTransportCertificatesSecrets_certManager(t *testing.T) {
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	pod := func(name, ip string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      name,
+				Labels:    map[string]string{label.ClusterNameLabelName: "es"},
+			},
+			Status: corev1.PodStatus{PodIP: ip},
+		}
+	}
+	issuer := &certificates.IssuerRef{Kind: certificates.ClusterIssuerKind, Name: "my-issuer"}
+	issuedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es-es-default-0-transport-certs-issued"},
+		Data: map[string][]byte{
+			certificates.CertFileName: []byte("issued-cert"),
+			certificates.KeyFileName:  []byte("issued-key"),
+			certificates.CAFileName:   []byte("issued-ca"),
+		},
+	}
+	// certificates of a pod that does not exist anymore
+	transportSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: esv1.TransportCertificatesSecret("es")},
+		Data: map[string][]byte{
+			PodCertFileName("es-es-default-2"): []byte("removed-cert"),
+			PodKeyFileName("es-es-default-2"):  []byte("removed-key"),
+		},
+	}
+	removedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es-es-default-2-transport-certs-issued"},
+	}
+	c := k8s.WrappedFakeClient(
+		pod("es-es-default-0", "10.0.0.1"), pod("es-es-default-1", "10.0.0.2"),
+		issuedSecret, transportSecret, removedSecret,
+	)
+	w := watches.NewDynamicWatches()
+
+	trustedCAs, results := ReconcileTransportCertificatesSecrets(c, w, nil, testCA, es, certificates.RotationParams{
+		Validity:     certificates.DefaultCertValidity,
+		RotateBefore: certificates.DefaultRotateBefore,
+	}, issuer)
+	require.False(t, results.HasError())
+
+	// a Certificate is requested for each Pod
+	for _, name := range []string{"es-es-default-0-transport-certs-issued", "es-es-default-1-transport-certs-issued"} {
+		var cert unstructured.Unstructured
+		cert.SetGroupVersionKind(certificates.CertManagerCertificateGVK)
+		require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: name}, &cert))
+	}
+	// the certificate issued by cert-manager is used, the other Pod keeps a self-signed certificate until issued
+	var secret corev1.Secret
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: esv1.TransportCertificatesSecret("es")}, &secret))
+	require.Equal(t, []byte("issued-cert"), secret.Data[PodCertFileName("es-es-default-0")])
+	require.Equal(t, []byte("issued-key"), secret.Data[PodKeyFileName("es-es-default-0")])
+	require.NotNil(t, extractTransportCert(secret, *pod("es-es-default-1", "10.0.0.2"), "es-es-default-1.node.es.ns.es.local"))
+	// both CAs are trusted
+	expectedCAs := append(certificates.EncodePEMCert(testCA.Cert.Raw), []byte("issued-ca")...)
+	require.Equal(t, expectedCAs, secret.Data[certificates.CAFileName])
+	require.Equal(t, expectedCAs, trustedCAs)
+	// the certificates of the removed Pod are deleted
+	require.NotContains(t, secret.Data, PodCertFileName("es-es-default-2"))
+	require.Error(t, c.Get(k8s.ExtractNamespacedName(removedSecret), &corev1.Secret{}))
+	// the issued certificates are watched
+	require.Contains(t, w.Secrets.Registrations(), CertManagerWatchKey(k8s.ExtractNamespacedName(&es)))
+}
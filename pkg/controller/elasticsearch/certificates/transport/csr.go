@@ -94,6 +94,16 @@ func buildGeneralNames(
 		{IPAddress: net.ParseIP("127.0.0.1").To4()},
 	}
 
+	// add the user-provided SANs, eg. for nodes of remote clusters connecting through a load balancer
+	for _, san := range cluster.Spec.Transport.TLS.SubjectAlternativeNames {
+		if san.DNS != "" {
+			generalNames = append(generalNames, certificates.GeneralName{DNSName: san.DNS})
+		}
+		if san.IP != "" {
+			generalNames = append(generalNames, certificates.GeneralName{IPAddress: netutil.MaybeIPTo4(net.ParseIP(san.IP))})
+		}
+	}
+
 	return generalNames, nil
 }
 
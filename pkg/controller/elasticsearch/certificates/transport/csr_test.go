@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 )
@@ -97,6 +98,31 @@ func Test_buildGeneralNames(t *testing.T) {
 				{IPAddress: net.ParseIP("127.0.0.1").To4()},
 			},
 		},
+		{
+			name: "with user-provided SANs",
+			args: args{
+				cluster: func() esv1.Elasticsearch {
+					es := *testES.DeepCopy()
+					es.Spec.Transport.TLS.SubjectAlternativeNames = []commonv1.SubjectAlternativeName{
+						{DNS: "es.example.com"},
+						{IP: "10.0.0.1"},
+						{IP: "2001:db8::1"},
+					}
+					return es
+				}(),
+				pod: testPod,
+			},
+			want: []certificates.GeneralName{
+				{OtherName: *otherName},
+				{DNSName: expectedCommonName},
+				{DNSName: expectedTransportSvcName},
+				{IPAddress: net.ParseIP(testIP).To4()},
+				{IPAddress: net.ParseIP("127.0.0.1").To4()},
+				{DNSName: "es.example.com"},
+				{IPAddress: net.ParseIP("10.0.0.1").To4()},
+				{IPAddress: net.ParseIP("2001:db8::1")},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
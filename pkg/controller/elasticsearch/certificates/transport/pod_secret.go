@@ -93,7 +93,7 @@ func ensureTransportCertificatesSecretContentsForPod(
 		}
 
 		// store the issued certificate in a secret mounted into the pod
-		secret.Data[PodCertFileName(pod.Name)] = certificates.EncodePEMCert(certData, ca.Cert.Raw)
+		secret.Data[PodCertFileName(pod.Name)] = certificates.EncodePEMCert(append([][]byte{certData}, caCertificates(ca)...)...)
 	}
 
 	return nil
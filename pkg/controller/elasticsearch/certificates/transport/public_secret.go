@@ -16,11 +16,11 @@ import (
 )
 
 // ReconcileTransportCertsPublicSecret reconciles the Secret containing the publicly available transport CA
-// information.
+// information, made of the given PEM encoded CA certificates.
 func ReconcileTransportCertsPublicSecret(
 	c k8s.Client,
 	es esv1.Elasticsearch,
-	ca *certificates.CA,
+	caPem []byte,
 ) error {
 	esNSN := k8s.ExtractNamespacedName(&es)
 	meta := k8s.ToObjectMeta(PublicCertsSecretRef(esNSN))
@@ -29,7 +29,7 @@ func ReconcileTransportCertsPublicSecret(
 	expected := corev1.Secret{
 		ObjectMeta: meta,
 		Data: map[string][]byte{
-			certificates.CAFileName: certificates.EncodePEMCert(ca.Cert.Raw),
+			certificates.CAFileName: caPem,
 		},
 	}
 	_, err := reconciler.ReconcileSecret(c, expected, &es)
@@ -126,7 +126,7 @@ func TestReconcileTransportCertsPublicSecret(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			client := tt.client(t)
-			err := ReconcileTransportCertsPublicSecret(client, *owner, ca)
+			err := ReconcileTransportCertsPublicSecret(client, *owner, certificates.EncodePEMCert(ca.Cert.Raw))
 			if tt.wantErr {
 				require.Error(t, err, "Failed to reconcile")
 				return
@@ -14,6 +14,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -22,6 +24,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
@@ -30,43 +33,76 @@ import (
 var log = logf.Log.WithName("transport")
 
 // ReconcileTransportCertificatesSecrets reconciles the secret containing transport certificates for all nodes in the
-// cluster.
+// cluster, and returns the CA certificates trusted by the nodes.
+// If a cert-manager issuer is set, the certificates of the nodes are issued by cert-manager. Self-signed certificates
+// are used for the nodes whose certificate is not issued yet.
 func ReconcileTransportCertificatesSecrets(
 	c k8s.Client,
+	dynamicWatches watches.DynamicWatches,
+	recorder record.EventRecorder,
 	ca *certificates.CA,
 	es esv1.Elasticsearch,
 	rotationParams certificates.RotationParams,
-) *reconciler.Results {
+	certManagerIssuer *certificates.IssuerRef,
+) ([]byte, *reconciler.Results) {
 	results := &reconciler.Results{}
 	var pods corev1.PodList
 	matchLabels := label.NewLabelSelectorForElasticsearch(es)
 	ns := client.InNamespace(es.Namespace)
 	if err := c.List(&pods, matchLabels, ns); err != nil {
-		return results.WithError(errors.WithStack(err))
+		return nil, results.WithError(errors.WithStack(err))
+	}
+
+	if err := reconcileCertManagerWatch(dynamicWatches, es, pods.Items, certManagerIssuer); err != nil {
+		return nil, results.WithError(err)
 	}
 
 	secret, err := ensureTransportCertificatesSecretExists(c, es)
 	if err != nil {
-		return results.WithError(err)
+		return nil, results.WithError(err)
 	}
 	// defensive copy of the current secret so we can check whether we need to update later on
 	currentTransportCertificatesSecret := secret.DeepCopy()
+	selfSigned := false
+	var issuedCAs [][]byte
+	var earliestExpiration time.Time
 	for _, pod := range pods.Items {
 		if pod.Status.PodIP == "" {
 			log.Info("Skipping pod because it has no IP yet", "namespace", pod.Namespace, "pod_name", pod.Name)
 			continue
 		}
 
+		if certManagerIssuer != nil {
+			issued, err := reconcileCertManagerCertificate(c, es, pod, *certManagerIssuer, rotationParams)
+			if err != nil {
+				return nil, results.WithError(err)
+			}
+			if issued != nil {
+				// renewals are handled by cert-manager
+				secret.Data[PodKeyFileName(pod.Name)] = issued.Data[certificates.KeyFileName]
+				secret.Data[PodCertFileName(pod.Name)] = issued.Data[certificates.CertFileName]
+				issuedCAs = appendIfMissing(issuedCAs, issued.Data[certificates.CAFileName])
+				if cert, err := certificates.GetPrimaryCertificate(issued.Data[certificates.CertFileName]); err == nil {
+					earliestExpiration = earliest(earliestExpiration, cert.NotAfter)
+				}
+				continue
+			}
+			log.Info("Transport certificate not issued yet by cert-manager, using a self-signed certificate",
+				"namespace", pod.Namespace, "pod_name", pod.Name)
+		}
+
+		selfSigned = true
 		if err := ensureTransportCertificatesSecretContentsForPod(
 			es, secret, pod, ca, rotationParams,
 		); err != nil {
-			return results.WithError(err)
+			return nil, results.WithError(err)
 		}
 		certCommonName := buildCertificateCommonName(pod, es.Name, es.Namespace)
 		cert := extractTransportCert(*secret, pod, certCommonName)
 		if cert == nil {
-			return results.WithError(errors.New("No certificate found for pod"))
+			return nil, results.WithError(errors.New("No certificate found for pod"))
 		}
+		earliestExpiration = earliest(earliestExpiration, cert.NotAfter)
 		// handle cert expiry via requeue
 		results.WithResult(reconcile.Result{
 			RequeueAfter: certificates.ShouldRotateIn(time.Now(), cert.NotAfter, rotationParams.RotateBefore),
@@ -95,10 +131,26 @@ func ReconcileTransportCertificatesSecrets(
 
 		for _, keyToRemove := range keysToPrune {
 			delete(secret.Data, keyToRemove)
+			if certManagerIssuer == nil {
+				continue
+			}
+			podName := strings.SplitN(keyToRemove, ".", 2)[0]
+			if err := certificates.DeleteCertManagerCertificate(
+				c, types.NamespacedName{Namespace: es.Namespace, Name: CertManagerCertificateName(podName)},
+			); err != nil {
+				return nil, results.WithError(err)
+			}
 		}
 	}
 
-	caBytes := certificates.EncodePEMCert(ca.Cert.Raw)
+	// trust the self-signed CA as long as some nodes use it, and the CAs of the certificates issued by cert-manager
+	var caBytes []byte
+	if selfSigned || len(issuedCAs) == 0 {
+		caBytes = certificates.EncodePEMCert(caCertificates(ca)...)
+	}
+	for _, issuedCA := range issuedCAs {
+		caBytes = append(caBytes, issuedCA...)
+	}
 
 	// compare with current trusted CA certs.
 	if !bytes.Equal(caBytes, secret.Data[certificates.CAFileName]) {
@@ -107,14 +159,46 @@ func ReconcileTransportCertificatesSecrets(
 
 	if !reflect.DeepEqual(secret, currentTransportCertificatesSecret) {
 		if err := c.Update(secret); err != nil {
-			return results.WithError(err)
+			return nil, results.WithError(err)
+		}
+		var rotated []string
+		for _, pod := range pods.Items {
+			previous := currentTransportCertificatesSecret.Data[PodCertFileName(pod.Name)]
+			if len(previous) > 0 && !bytes.Equal(previous, secret.Data[PodCertFileName(pod.Name)]) {
+				rotated = append(rotated, pod.Name)
+			}
+		}
+		if len(rotated) > 0 {
+			certificates.RecordRotation(recorder, &es, certificates.TransportCertificate, "of nodes "+strings.Join(rotated, ", "))
 		}
 		for _, pod := range pods.Items {
 			annotation.MarkPodAsUpdated(c, pod)
 		}
 	}
 
-	return results
+	if !earliestExpiration.IsZero() {
+		certificates.ReportExpiration(
+			certificates.KindOf(&es), k8s.ExtractNamespacedName(&es), certificates.TransportCertificate, earliestExpiration,
+		)
+	}
+
+	return caBytes, results
+}
+
+func earliest(current, t time.Time) time.Time {
+	if current.IsZero() || t.Before(current) {
+		return t
+	}
+	return current
+}
+
+func appendIfMissing(values [][]byte, value []byte) [][]byte {
+	for _, v := range values {
+		if bytes.Equal(v, value) {
+			return values
+		}
+	}
+	return append(values, value)
 }
 
 // ensureTransportCertificatesSecretExists ensures the existence and Labels of the Secret that at a later point
@@ -7,10 +7,12 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"io"
 	"net/http"
+	"reflect"
 
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
@@ -21,6 +23,14 @@ type baseClient struct {
 	HTTP     *http.Client
 	Endpoint string
 	caCerts  []*x509.Certificate
+	// clientCert is the certificate presented to Elasticsearch, if any
+	clientCert *tls.Certificate
+	version    version.Version
+}
+
+// Version returns the Elasticsearch version this client is meant to be used with.
+func (c *baseClient) Version() version.Version {
+	return c.version
 }
 
 // Close idle connections in the underlying http client.
@@ -49,6 +59,11 @@ func (c *baseClient) equal(c2 *baseClient) bool {
 			return false
 		}
 	}
+	// compare client certs
+	if (c.clientCert == nil) != (c2.clientCert == nil) ||
+		(c.clientCert != nil && !reflect.DeepEqual(c.clientCert.Certificate, c2.clientCert.Certificate)) {
+		return false
+	}
 	// compare endpoint and user creds
 	return c.Endpoint == c2.Endpoint &&
 		c.User == c2.User
@@ -129,6 +144,7 @@ func (c *baseClient) request(
 }
 
 func versioned(b *baseClient, v version.Version) Client {
+	b.version = v
 	v6 := clientV6{
 		baseClient: *b,
 	}
@@ -6,6 +6,7 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
@@ -70,12 +71,68 @@ type Client interface {
 	ReloadSecureSettings(ctx context.Context) error
 	// GetNodes calls the _nodes api to return a map(nodeName -> Node)
 	GetNodes(ctx context.Context) (Nodes, error)
-	// GetNodesStats calls the _nodes/stats api to return a map(nodeName -> NodeStats)
+	// GetNodesStats calls the _nodes/stats api to return a map(nodeName -> NodeStats), restricted to os and fs stats
 	GetNodesStats(ctx context.Context) (NodesStats, error)
 	// ClusterBootstrappedForZen2 returns true if the cluster is relying on zen2 orchestration.
 	ClusterBootstrappedForZen2(ctx context.Context) (bool, error)
 	// UpdateRemoteClusterSettings updates the remote clusters of a cluster.
 	UpdateRemoteClusterSettings(ctx context.Context, settings RemoteClustersSettings) error
+	// GetSnapshotRepository returns the snapshot repository with the given name.
+	GetSnapshotRepository(ctx context.Context, name string) (SnapshotRepository, error)
+	// UpsertSnapshotRepository creates or updates the snapshot repository with the given name.
+	UpsertSnapshotRepository(ctx context.Context, name string, repository SnapshotRepository) error
+	// DeleteSnapshotRepository deletes the snapshot repository with the given name.
+	DeleteSnapshotRepository(ctx context.Context, name string) error
+	// GetSnapshotLifecyclePolicy returns the snapshot lifecycle management policy with the given id.
+	//
+	// Introduced in: Elasticsearch 7.4.0
+	GetSnapshotLifecyclePolicy(ctx context.Context, id string) (SnapshotLifecyclePolicyInfo, error)
+	// UpsertSnapshotLifecyclePolicy creates or updates the snapshot lifecycle management policy with the given id.
+	//
+	// Introduced in: Elasticsearch 7.4.0
+	UpsertSnapshotLifecyclePolicy(ctx context.Context, id string, policy SnapshotLifecyclePolicy) error
+	// DeleteSnapshotLifecyclePolicy deletes the snapshot lifecycle management policy with the given id.
+	//
+	// Introduced in: Elasticsearch 7.4.0
+	DeleteSnapshotLifecyclePolicy(ctx context.Context, id string) error
+	// Authenticate checks that the credentials of the client are accepted by Elasticsearch.
+	Authenticate(ctx context.Context) error
+	// UpsertUser creates or updates the user of the native realm with the given name.
+	UpsertUser(ctx context.Context, name string, user User) error
+	// DeleteUser deletes the user of the native realm with the given name.
+	DeleteUser(ctx context.Context, name string) error
+	// UpsertRole creates or updates the role with the given name.
+	UpsertRole(ctx context.Context, name string, role RoleDescriptor) error
+	// DeleteRole deletes the role with the given name.
+	DeleteRole(ctx context.Context, name string) error
+	// UpsertRoleMapping creates or updates the role mapping with the given name.
+	UpsertRoleMapping(ctx context.Context, name string, mapping RoleMapping) error
+	// DeleteRoleMapping deletes the role mapping with the given name.
+	DeleteRoleMapping(ctx context.Context, name string) error
+	// CreateServiceAccountToken creates a token with the given name for the given service account (eg. elastic/kibana).
+	//
+	// Introduced in: Elasticsearch 7.13.0
+	CreateServiceAccountToken(ctx context.Context, serviceAccount, name string) (ServiceAccountToken, error)
+	// GetServiceAccountTokens returns the names of the tokens of the given service account created through the API.
+	//
+	// Introduced in: Elasticsearch 7.13.0
+	GetServiceAccountTokens(ctx context.Context, serviceAccount string) ([]string, error)
+	// DeleteServiceAccountToken deletes the token with the given name of the given service account.
+	//
+	// Introduced in: Elasticsearch 7.13.0
+	DeleteServiceAccountToken(ctx context.Context, serviceAccount, name string) error
+	// CreateAPIKey creates an API key owned by the user of the client.
+	//
+	// Introduced in: Elasticsearch 7.0.0
+	CreateAPIKey(ctx context.Context, request CreateAPIKeyRequest) (CreateAPIKeyResponse, error)
+	// GetAPIKeys returns the API keys owned by the user of the client.
+	//
+	// Introduced in: Elasticsearch 7.7.0
+	GetAPIKeys(ctx context.Context) ([]APIKey, error)
+	// InvalidateAPIKeys invalidates the API keys with the given ids.
+	//
+	// Introduced in: Elasticsearch 7.10.0
+	InvalidateAPIKeys(ctx context.Context, ids []string) error
 	// AddVotingConfigExclusions sets the transient and persistent setting of the same name in cluster settings.
 	//
 	// If timeout is the empty string, the default is used.
@@ -86,6 +143,20 @@ type Client interface {
 	//
 	// Introduced in: Elasticsearch 7.0.0
 	DeleteVotingConfigExclusions(ctx context.Context, waitForRemoval bool) error
+	// GetShutdowns returns the shutdown records of all the nodes.
+	//
+	// Introduced in: Elasticsearch 7.15.0
+	GetShutdowns(ctx context.Context) (ShutdownResponse, error)
+	// PutShutdown registers a shutdown record of the given type for the node with the given id.
+	//
+	// Introduced in: Elasticsearch 7.15.0
+	PutShutdown(ctx context.Context, nodeID string, shutdownType ShutdownType, reason string) error
+	// DeleteShutdown deletes the shutdown record of the node with the given id.
+	//
+	// Introduced in: Elasticsearch 7.15.0
+	DeleteShutdown(ctx context.Context, nodeID string) error
+	// Version returns the Elasticsearch version this client is meant to be used with.
+	Version() version.Version
 	// Request exposes a low level interface to the underlying HTTP client e.g. for testing purposes.
 	// The Elasticsearch endpoint will be added automatically to the request URL which should therefore just be the path
 	// with a leading /
@@ -101,12 +172,26 @@ func NewElasticsearchClient(
 	esUser BasicAuth,
 	v version.Version,
 	caCerts []*x509.Certificate,
+) Client {
+	return NewElasticsearchClientWithCertificate(dialer, esURL, esUser, v, caCerts, nil)
+}
+
+// NewElasticsearchClientWithCertificate creates a new client for the target cluster, presenting the given client
+// certificate if not nil.
+func NewElasticsearchClientWithCertificate(
+	dialer net.Dialer,
+	esURL string,
+	esUser BasicAuth,
+	v version.Version,
+	caCerts []*x509.Certificate,
+	clientCert *tls.Certificate,
 ) Client {
 	base := &baseClient{
-		Endpoint: esURL,
-		User:     esUser,
-		caCerts:  caCerts,
-		HTTP:     common.HTTPClient(dialer, caCerts),
+		Endpoint:   esURL,
+		User:       esUser,
+		caCerts:    caCerts,
+		clientCert: clientCert,
+		HTTP:       common.HTTPClientWithCertificate(dialer, caCerts, clientCert),
 	}
 	return versioned(base, v)
 }
@@ -320,7 +320,7 @@ func TestClientGetNodes(t *testing.T) {
 }
 
 func TestClientGetNodesStats(t *testing.T) {
-	expectedPath := "/_nodes/_all/stats/os"
+	expectedPath := "/_nodes/_all/stats/os,fs"
 	testClient := NewMockClient(version.MustParse("6.8.0"), func(req *http.Request) *http.Response {
 		require.Equal(t, expectedPath, req.URL.Path)
 		return &http.Response{
@@ -335,6 +335,8 @@ func TestClientGetNodesStats(t *testing.T) {
 	require.Equal(t, 1, len(resp.Nodes))
 	require.Contains(t, resp.Nodes, "Rt-o5-ZBQaq-Nkhhy0p7JA")
 	require.Equal(t, "3221225472", resp.Nodes["Rt-o5-ZBQaq-Nkhhy0p7JA"].OS.CGroup.Memory.LimitInBytes)
+	require.Equal(t, int64(10498871296), resp.Nodes["Rt-o5-ZBQaq-Nkhhy0p7JA"].FS.Total.TotalInBytes)
+	require.Equal(t, int64(9346543616), resp.Nodes["Rt-o5-ZBQaq-Nkhhy0p7JA"].FS.Total.AvailableInBytes)
 }
 
 func TestGetInfo(t *testing.T) {
@@ -653,3 +655,172 @@ func TestClient_ClusterBootstrappedForZen2(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetSnapshotRepository(t *testing.T) {
+	testClient := NewMockClient(version.MustParse("6.8.0"), func(req *http.Request) *http.Response {
+		require.Equal(t, "/_snapshot/my-repo", req.URL.Path)
+		return NewMockResponse(200, req, `{"my-repo":{"type":"s3","settings":{"bucket":"snapshots","compress":"true"}}}`)
+	})
+	repository, err := testClient.GetSnapshotRepository(context.Background(), "my-repo")
+	require.NoError(t, err)
+	require.Equal(t, SnapshotRepository{Type: "s3", Settings: map[string]interface{}{"bucket": "snapshots", "compress": "true"}}, repository)
+
+	testClient = NewMockClient(version.MustParse("6.8.0"), func(req *http.Request) *http.Response {
+		return NewMockResponse(404, req, `{"error":{"type":"repository_missing_exception"},"status":404}`)
+	})
+	_, err = testClient.GetSnapshotRepository(context.Background(), "my-repo")
+	require.True(t, IsNotFound(err))
+}
+
+func TestClient_GetSnapshotLifecyclePolicy(t *testing.T) {
+	tests := []struct {
+		version version.Version
+		wantErr bool
+	}{
+		{
+			version: version.MustParse("6.8.0"),
+			wantErr: true,
+		},
+		{
+			version: version.MustParse("7.4.0"),
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		client := NewMockClient(tt.version, func(req *http.Request) *http.Response {
+			require.Equal(t, "/_slm/policy/nightly", req.URL.Path)
+			return NewMockResponse(200, req, `{"nightly":{"version":1,"modified_date_millis":1570000000000,`+
+				`"policy":{"name":"<nightly-{now/d}>","schedule":"0 30 1 * * ?","repository":"my-repo","retention":{"max_count":10}},`+
+				`"last_success":{"snapshot_name":"nightly-2019.10.02-abc","time":1570000000000}}}`)
+		})
+		policy, err := client.GetSnapshotLifecyclePolicy(context.Background(), "nightly")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Client.GetSnapshotLifecyclePolicy() error = %v, wantErr %v", err, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
+		}
+		require.Equal(t, "my-repo", policy.Policy.Repository)
+		require.Equal(t, int32(10), *policy.Policy.Retention.MaxCount)
+		require.Equal(t, &SnapshotInvocationRecord{SnapshotName: "nightly-2019.10.02-abc", Time: 1570000000000}, policy.LastSuccess)
+		require.Nil(t, policy.LastFailure)
+	}
+}
+
+func TestClient_SecurityAPIPaths(t *testing.T) {
+	tests := []struct {
+		version      string
+		expectedPath string
+		authPath     string
+	}{
+		{version: "6.8.0", expectedPath: "/_xpack/security/user/jdoe", authPath: "/_xpack/security/_authenticate"},
+		{version: "7.5.0", expectedPath: "/_security/user/jdoe", authPath: "/_security/_authenticate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			expectedPath := tt.expectedPath
+			testClient := NewMockClient(version.MustParse(tt.version), func(req *http.Request) *http.Response {
+				require.Equal(t, expectedPath, req.URL.Path)
+				return NewMockResponse(200, req, `{"created":true}`)
+			})
+			require.NoError(t, testClient.UpsertUser(context.Background(), "jdoe", User{Password: "changeme", Roles: []string{}}))
+			require.NoError(t, testClient.DeleteUser(context.Background(), "jdoe"))
+			expectedPath = tt.authPath
+			require.NoError(t, testClient.Authenticate(context.Background()))
+		})
+	}
+}
+
+func TestClient_NodeShutdown(t *testing.T) {
+	var requests []string
+	testClient := NewMockClient(version.MustParse("7.15.2"), func(req *http.Request) *http.Response {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Method == http.MethodPut {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"type":"remove","reason":"downscale"}`, string(body))
+		}
+		return NewMockResponse(200, req, `{"nodes":[{"node_id":"abc","type":"REMOVE","reason":"downscale",`+
+			`"shutdown_startedmillis":1630000000000,"status":"IN_PROGRESS",`+
+			`"shard_migration":{"status":"IN_PROGRESS","shard_migrations_remaining":3,"explanation":""}}]}`)
+	})
+	require.Equal(t, version.MustParse("7.15.2"), testClient.Version())
+
+	shutdowns, err := testClient.GetShutdowns(context.Background())
+	require.NoError(t, err)
+	require.Len(t, shutdowns.Nodes, 1)
+	require.True(t, shutdowns.Nodes[0].Is(Remove))
+	require.False(t, shutdowns.Nodes[0].Is(Restart))
+	require.Equal(t, ShutdownInProgress, shutdowns.Nodes[0].Status)
+	require.Equal(t, 3, shutdowns.Nodes[0].ShardMigration.ShardMigrationsRemaining)
+
+	require.NoError(t, testClient.PutShutdown(context.Background(), "abc", Remove, "downscale"))
+	require.NoError(t, testClient.DeleteShutdown(context.Background(), "abc"))
+	require.Equal(t, []string{
+		"GET /_nodes/shutdown",
+		"PUT /_nodes/abc/shutdown",
+		"DELETE /_nodes/abc/shutdown",
+	}, requests)
+
+	require.Error(t, NewMockClient(version.MustParse("6.8.0"), nil).PutShutdown(context.Background(), "abc", Restart, ""))
+}
+
+func TestClient_AssociationCredentials(t *testing.T) {
+	var requests []string
+	testClient := NewMockClient(version.MustParse("7.13.0"), func(req *http.Request) *http.Response {
+		requests = append(requests, req.Method+" "+req.URL.RequestURI())
+		switch {
+		case req.Method == http.MethodPost && strings.HasPrefix(req.URL.Path, "/_security/service/"):
+			return NewMockResponse(200, req, `{"created":true,"token":{"name":"eck-token","value":"AAEAAWVsYXN0aWM"}}`)
+		case req.Method == http.MethodGet && strings.HasPrefix(req.URL.Path, "/_security/service/"):
+			return NewMockResponse(200, req, `{"service_account":"elastic/kibana","count":2,`+
+				`"tokens":{"token-b":{},"token-a":{}},"nodes_credentials":{"_nodes":{"total":1,"successful":1,"failed":0},"file_tokens":{}}}`)
+		case req.Method == http.MethodPost:
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"name":"eck-key","role_descriptors":{"apm":{"cluster":["monitor"]}}}`, string(body))
+			return NewMockResponse(200, req, `{"id":"VuaCfGcBCdbkQm-e5aOx","name":"eck-key","api_key":"ui2lp2axTNmsyakw9tvNnw"}`)
+		case req.Method == http.MethodGet:
+			return NewMockResponse(200, req, `{"api_keys":[{"id":"VuaCfGcBCdbkQm-e5aOx","name":"eck-key","creation":1548550550158,`+
+				`"invalidated":false,"username":"elastic-internal","realm":"file1"}]}`)
+		case req.Method == http.MethodDelete && req.URL.Path == "/_security/api_key":
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"ids":["VuaCfGcBCdbkQm-e5aOx"]}`, string(body))
+		}
+		return NewMockResponse(200, req, `{}`)
+	})
+	ctx := context.Background()
+
+	token, err := testClient.CreateServiceAccountToken(ctx, "elastic/kibana", "eck-token")
+	require.NoError(t, err)
+	require.Equal(t, ServiceAccountToken{Name: "eck-token", Value: "AAEAAWVsYXN0aWM"}, token)
+	tokens, err := testClient.GetServiceAccountTokens(ctx, "elastic/kibana")
+	require.NoError(t, err)
+	require.Equal(t, []string{"token-a", "token-b"}, tokens)
+	require.NoError(t, testClient.DeleteServiceAccountToken(ctx, "elastic/kibana", "eck-token"))
+
+	key, err := testClient.CreateAPIKey(ctx, CreateAPIKeyRequest{
+		Name:            "eck-key",
+		RoleDescriptors: map[string]RoleDescriptor{"apm": {"cluster": []string{"monitor"}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "VuaCfGcBCdbkQm-e5aOx:ui2lp2axTNmsyakw9tvNnw", key.Credentials())
+	keys, err := testClient.GetAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []APIKey{{ID: "VuaCfGcBCdbkQm-e5aOx", Name: "eck-key", Username: "elastic-internal"}}, keys)
+	require.NoError(t, testClient.InvalidateAPIKeys(ctx, []string{"VuaCfGcBCdbkQm-e5aOx"}))
+
+	require.Equal(t, []string{
+		"POST /_security/service/elastic/kibana/credential/token/eck-token",
+		"GET /_security/service/elastic/kibana/credential",
+		"DELETE /_security/service/elastic/kibana/credential/token/eck-token",
+		"POST /_security/api_key",
+		"GET /_security/api_key?owner=true",
+		"DELETE /_security/api_key",
+	}, requests)
+
+	_, err = NewMockClient(version.MustParse("6.8.0"), nil).CreateServiceAccountToken(ctx, "elastic/kibana", "eck-token")
+	require.Error(t, err)
+}
@@ -110,6 +110,12 @@ type NodeStats struct {
 			} `json:"memory"`
 		} `json:"cgroup"`
 	} `json:"os"`
+	FS struct {
+		Total struct {
+			TotalInBytes     int64 `json:"total_in_bytes"`
+			AvailableInBytes int64 `json:"available_in_bytes"`
+		} `json:"total"`
+	} `json:"fs"`
 }
 
 // ClusterStateNode represents an element in the `node` structure in
@@ -379,9 +385,67 @@ type RemoteClusters struct {
 	RemoteClusters map[string]RemoteCluster `json:"remote,omitempty"`
 }
 
-// RemoteClusterSeeds is the set of seeds to use in a remote cluster setting.
+// RemoteCluster is the set of settings of a remote cluster.
+// Nil values are serialized as null, which removes the corresponding persistent setting.
 type RemoteCluster struct {
-	Seeds []string `json:"seeds"`
+	Seeds           []string               `json:"seeds"`
+	SkipUnavailable *bool                  `json:"skip_unavailable"`
+	Transport       RemoteClusterTransport `json:"transport"`
+	// RemoteClusterMode is omitted if nil, since the connection mode settings do not exist before Elasticsearch 7.7.0.
+	*RemoteClusterMode
+}
+
+// RemoteClusterTransport holds the transport settings of a remote cluster connection.
+type RemoteClusterTransport struct {
+	Compress     *bool   `json:"compress"`
+	PingSchedule *string `json:"ping_schedule"`
+}
+
+// RemoteClusterMode holds the connection mode settings of a remote cluster.
+//
+// Introduced in: Elasticsearch 7.7.0
+type RemoteClusterMode struct {
+	Mode         *string `json:"mode"`
+	ProxyAddress *string `json:"proxy_address"`
+	ServerName   *string `json:"server_name"`
+}
+
+// SnapshotRepository is the definition of a snapshot repository, as returned by the _snapshot API.
+type SnapshotRepository struct {
+	Type     string                 `json:"type"`
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// SnapshotLifecyclePolicy is the definition of a snapshot lifecycle management (SLM) policy.
+type SnapshotLifecyclePolicy struct {
+	Schedule   string                      `json:"schedule"`
+	Name       string                      `json:"name"`
+	Repository string                      `json:"repository"`
+	Config     map[string]interface{}      `json:"config,omitempty"`
+	Retention  *SnapshotLifecycleRetention `json:"retention,omitempty"`
+}
+
+// SnapshotLifecycleRetention is the retention configuration of a snapshot lifecycle management policy.
+type SnapshotLifecycleRetention struct {
+	ExpireAfter string `json:"expire_after,omitempty"`
+	MinCount    *int32 `json:"min_count,omitempty"`
+	MaxCount    *int32 `json:"max_count,omitempty"`
+}
+
+// SnapshotLifecyclePolicyInfo is a snapshot lifecycle management policy along with the result of its last executions,
+// as returned by the _slm/policy API.
+type SnapshotLifecyclePolicyInfo struct {
+	Policy      SnapshotLifecyclePolicy   `json:"policy"`
+	LastSuccess *SnapshotInvocationRecord `json:"last_success,omitempty"`
+	LastFailure *SnapshotInvocationRecord `json:"last_failure,omitempty"`
+}
+
+// SnapshotInvocationRecord is the result of a snapshot taken by a snapshot lifecycle management policy.
+type SnapshotInvocationRecord struct {
+	SnapshotName string `json:"snapshot_name"`
+	// Time is the time of the snapshot, in milliseconds since epoch.
+	Time    int64  `json:"time"`
+	Details string `json:"details,omitempty"`
 }
 
 // Hit represents a single search hit.
@@ -406,3 +470,130 @@ type SearchResults struct {
 	Shards json.RawMessage            // model when needed
 	Aggs   map[string]json.RawMessage // model when needed
 }
+
+// User is a user of the native realm, as expected by the security users API.
+type User struct {
+	Password string   `json:"password,omitempty"`
+	Roles    []string `json:"roles"`
+	FullName string   `json:"full_name,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	Enabled  *bool    `json:"enabled,omitempty"`
+}
+
+// RoleDescriptor is the definition of a role, as expected by the security roles API.
+type RoleDescriptor map[string]interface{}
+
+// RoleMapping is the definition of a role mapping, as expected by the security role mapping API.
+type RoleMapping struct {
+	Enabled bool                   `json:"enabled"`
+	Roles   []string               `json:"roles"`
+	Rules   map[string]interface{} `json:"rules"`
+}
+
+// ShutdownType is the type of a node shutdown.
+type ShutdownType string
+
+const (
+	// Restart prepares a node to be restarted: its shards are not reallocated while it is temporarily down.
+	Restart ShutdownType = "restart"
+	// Remove prepares a node to be removed from the cluster: its shards are migrated to other nodes.
+	Remove ShutdownType = "remove"
+)
+
+// ShutdownStatus is the status of the preparation of a node shutdown.
+type ShutdownStatus string
+
+// Statuses of the preparation of a node shutdown.
+const (
+	ShutdownNotStarted ShutdownStatus = "NOT_STARTED"
+	ShutdownInProgress ShutdownStatus = "IN_PROGRESS"
+	ShutdownStalled    ShutdownStatus = "STALLED"
+	ShutdownComplete   ShutdownStatus = "COMPLETE"
+)
+
+// ShutdownRequest is the request to register a node shutdown, as expected by the node shutdown API.
+type ShutdownRequest struct {
+	Type   ShutdownType `json:"type"`
+	Reason string       `json:"reason"`
+}
+
+// NodeShutdown is the shutdown record of a node, as returned by the node shutdown API.
+type NodeShutdown struct {
+	NodeID                string         `json:"node_id"`
+	Type                  string         `json:"type"`
+	Reason                string         `json:"reason"`
+	ShutdownStartedMillis int64          `json:"shutdown_startedmillis"`
+	Status                ShutdownStatus `json:"status"`
+	ShardMigration        struct {
+		Status                   ShutdownStatus `json:"status"`
+		ShardMigrationsRemaining int            `json:"shard_migrations_remaining"`
+		Explanation              string         `json:"explanation"`
+	} `json:"shard_migration"`
+}
+
+// Is returns true if the shutdown record is of the given type.
+func (ns NodeShutdown) Is(shutdownType ShutdownType) bool {
+	// types are returned upper case by the API
+	return strings.EqualFold(ns.Type, string(shutdownType))
+}
+
+// ShutdownResponse is the response of the node shutdown API.
+type ShutdownResponse struct {
+	Nodes []NodeShutdown `json:"nodes"`
+}
+
+// ServiceAccountToken is a token of a service account, as returned by the service account token API.
+type ServiceAccountToken struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CreateServiceAccountTokenResponse is the response of the create service account token API.
+type CreateServiceAccountTokenResponse struct {
+	Created bool                `json:"created"`
+	Token   ServiceAccountToken `json:"token"`
+}
+
+// ServiceAccountCredentialsResponse is the response of the get service account credentials API.
+type ServiceAccountCredentialsResponse struct {
+	ServiceAccount string `json:"service_account"`
+	Count          int    `json:"count"`
+	// Tokens are the tokens created through the API, indexed by name.
+	Tokens map[string]json.RawMessage `json:"tokens"`
+}
+
+// CreateAPIKeyRequest is the request to create an API key, as expected by the create API key API.
+type CreateAPIKeyRequest struct {
+	Name            string                    `json:"name"`
+	RoleDescriptors map[string]RoleDescriptor `json:"role_descriptors,omitempty"`
+}
+
+// CreateAPIKeyResponse is the response of the create API key API.
+type CreateAPIKeyResponse struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	APIKey string `json:"api_key"`
+}
+
+// Credentials returns the API key credentials, in the "id:api_key" format expected by the Beats and the APM Server.
+func (r CreateAPIKeyResponse) Credentials() string {
+	return r.ID + ":" + r.APIKey
+}
+
+// APIKey is the information about an API key, as returned by the get API key API.
+type APIKey struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Invalidated bool   `json:"invalidated"`
+	Username    string `json:"username"`
+}
+
+// APIKeysResponse is the response of the get API key API.
+type APIKeysResponse struct {
+	APIKeys []APIKey `json:"api_keys"`
+}
+
+// InvalidateAPIKeysRequest is the request to invalidate API keys, as expected by the invalidate API key API.
+type InvalidateAPIKeysRequest struct {
+	IDs []string `json:"ids"`
+}
@@ -13,6 +13,9 @@ import (
 )
 
 func TestModel_RemoteCluster(t *testing.T) {
+	skipUnavailable := true
+	mode := "proxy"
+	proxyAddress := "proxy.example.com:9300"
 	tests := []struct {
 		name string
 		arg  RemoteClustersSettings
@@ -31,7 +34,7 @@ func TestModel_RemoteCluster(t *testing.T) {
 					},
 				},
 			},
-			want: `{"persistent":{"cluster":{"remote":{"leader":{"seeds":["127.0.0.1:9300"]}}}}}`,
+			want: `{"persistent":{"cluster":{"remote":{"leader":{"seeds":["127.0.0.1:9300"],"skip_unavailable":null,"transport":{"compress":null,"ping_schedule":null}}}}}}`,
 		},
 		{
 			name: "Deleted remote cluster",
@@ -46,7 +49,26 @@ func TestModel_RemoteCluster(t *testing.T) {
 					},
 				},
 			},
-			want: `{"persistent":{"cluster":{"remote":{"leader":{"seeds":null}}}}}`,
+			want: `{"persistent":{"cluster":{"remote":{"leader":{"seeds":null,"skip_unavailable":null,"transport":{"compress":null,"ping_schedule":null}}}}}}`,
+		},
+		{
+			name: "Remote cluster in proxy mode",
+			arg: RemoteClustersSettings{
+				PersistentSettings: &SettingsGroup{
+					Cluster: RemoteClusters{
+						RemoteClusters: map[string]RemoteCluster{
+							"leader": {
+								SkipUnavailable: &skipUnavailable,
+								RemoteClusterMode: &RemoteClusterMode{
+									Mode:         &mode,
+									ProxyAddress: &proxyAddress,
+								},
+							},
+						},
+					},
+				},
+			},
+			want: `{"persistent":{"cluster":{"remote":{"leader":{"seeds":null,"skip_unavailable":true,"transport":{"compress":null,"ping_schedule":null},"mode":"proxy","proxy_address":"proxy.example.com:9300","server_name":null}}}}}`,
 		},
 	}
 	for _, tt := range tests {
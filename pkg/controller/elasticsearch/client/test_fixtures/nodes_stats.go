@@ -73,6 +73,24 @@ const (
             "usage_in_bytes" : "2926161920"
           }
         }
+      },
+      "fs" : {
+        "timestamp" : 1560016895152,
+        "total" : {
+          "total_in_bytes" : 10498871296,
+          "free_in_bytes" : 9363320832,
+          "available_in_bytes" : 9346543616
+        },
+        "data" : [
+          {
+            "path" : "/usr/share/elasticsearch/data/nodes/0",
+            "mount" : "/usr/share/elasticsearch/data (/dev/sdb)",
+            "type" : "ext4",
+            "total_in_bytes" : 10498871296,
+            "free_in_bytes" : 9363320832,
+            "available_in_bytes" : 9346543616
+          }
+        ]
       }
     }
   }
@@ -80,8 +80,8 @@ func (c *clientV6) GetNodes(ctx context.Context) (Nodes, error) {
 
 func (c *clientV6) GetNodesStats(ctx context.Context) (NodesStats, error) {
 	var nodesStats NodesStats
-	// restrict call to basic node info only
-	return nodesStats, c.get(ctx, "/_nodes/_all/stats/os", &nodesStats)
+	// restrict call to os and file system stats only
+	return nodesStats, c.get(ctx, "/_nodes/_all/stats/os,fs", &nodesStats)
 }
 
 func (c *clientV6) UpdateRemoteClusterSettings(ctx context.Context, settings RemoteClustersSettings) error {
@@ -108,6 +108,90 @@ func (c *clientV6) StartBasic(ctx context.Context) (StartBasicResponse, error) {
 	return response, c.post(ctx, "/_xpack/license/start_basic?acknowledge=true", nil, &response)
 }
 
+func (c *clientV6) GetSnapshotRepository(ctx context.Context, name string) (SnapshotRepository, error) {
+	var repositories map[string]SnapshotRepository
+	if err := c.get(ctx, "/_snapshot/"+url.PathEscape(name), &repositories); err != nil {
+		return SnapshotRepository{}, err
+	}
+	repository, exists := repositories[name]
+	if !exists {
+		return SnapshotRepository{}, errors.Errorf("snapshot repository %s not found in the response", name)
+	}
+	return repository, nil
+}
+
+func (c *clientV6) UpsertSnapshotRepository(ctx context.Context, name string, repository SnapshotRepository) error {
+	return c.put(ctx, "/_snapshot/"+url.PathEscape(name), &repository, nil)
+}
+
+func (c *clientV6) DeleteSnapshotRepository(ctx context.Context, name string) error {
+	return c.delete(ctx, "/_snapshot/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *clientV6) GetSnapshotLifecyclePolicy(ctx context.Context, id string) (SnapshotLifecyclePolicyInfo, error) {
+	return SnapshotLifecyclePolicyInfo{}, errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) UpsertSnapshotLifecyclePolicy(ctx context.Context, id string, policy SnapshotLifecyclePolicy) error {
+	return errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) DeleteSnapshotLifecyclePolicy(ctx context.Context, id string) error {
+	return errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) Authenticate(ctx context.Context) error {
+	return c.get(ctx, "/_xpack/security/_authenticate", nil)
+}
+
+func (c *clientV6) UpsertUser(ctx context.Context, name string, user User) error {
+	return c.put(ctx, "/_xpack/security/user/"+url.PathEscape(name), &user, nil)
+}
+
+func (c *clientV6) DeleteUser(ctx context.Context, name string) error {
+	return c.delete(ctx, "/_xpack/security/user/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *clientV6) UpsertRole(ctx context.Context, name string, role RoleDescriptor) error {
+	return c.put(ctx, "/_xpack/security/role/"+url.PathEscape(name), &role, nil)
+}
+
+func (c *clientV6) DeleteRole(ctx context.Context, name string) error {
+	return c.delete(ctx, "/_xpack/security/role/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *clientV6) UpsertRoleMapping(ctx context.Context, name string, mapping RoleMapping) error {
+	return c.put(ctx, "/_xpack/security/role_mapping/"+url.PathEscape(name), &mapping, nil)
+}
+
+func (c *clientV6) DeleteRoleMapping(ctx context.Context, name string) error {
+	return c.delete(ctx, "/_xpack/security/role_mapping/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *clientV6) CreateServiceAccountToken(ctx context.Context, serviceAccount, name string) (ServiceAccountToken, error) {
+	return ServiceAccountToken{}, errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) GetServiceAccountTokens(ctx context.Context, serviceAccount string) ([]string, error) {
+	return nil, errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) DeleteServiceAccountToken(ctx context.Context, serviceAccount, name string) error {
+	return errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) CreateAPIKey(ctx context.Context, request CreateAPIKeyRequest) (CreateAPIKeyResponse, error) {
+	return CreateAPIKeyResponse{}, errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	return nil, errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) InvalidateAPIKeys(ctx context.Context, ids []string) error {
+	return errors.New("Not supported in Elasticsearch 6.x")
+}
+
 func (c *clientV6) AddVotingConfigExclusions(ctx context.Context, nodeNames []string, timeout string) error {
 	return errors.New("Not supported in Elasticsearch 6.x")
 }
@@ -116,6 +200,18 @@ func (c *clientV6) DeleteVotingConfigExclusions(ctx context.Context, waitForRemo
 	return errors.New("Not supported in Elasticsearch 6.x")
 }
 
+func (c *clientV6) GetShutdowns(ctx context.Context) (ShutdownResponse, error) {
+	return ShutdownResponse{}, errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) PutShutdown(ctx context.Context, nodeID string, shutdownType ShutdownType, reason string) error {
+	return errors.New("Not supported in Elasticsearch 6.x")
+}
+
+func (c *clientV6) DeleteShutdown(ctx context.Context, nodeID string) error {
+	return errors.New("Not supported in Elasticsearch 6.x")
+}
+
 func (c *clientV6) ClusterBootstrappedForZen2(ctx context.Context) (bool, error) {
 	// Look at the current master node of the cluster: if it's running version 7.x.x or above,
 	// the cluster has been bootstrapped.
@@ -7,6 +7,8 @@ package client
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -65,6 +67,106 @@ func (c *clientV7) DeleteVotingConfigExclusions(ctx context.Context, waitForRemo
 	return nil
 }
 
+func (c *clientV7) GetSnapshotLifecyclePolicy(ctx context.Context, id string) (SnapshotLifecyclePolicyInfo, error) {
+	var policies map[string]SnapshotLifecyclePolicyInfo
+	if err := c.get(ctx, "/_slm/policy/"+url.PathEscape(id), &policies); err != nil {
+		return SnapshotLifecyclePolicyInfo{}, err
+	}
+	policy, exists := policies[id]
+	if !exists {
+		return SnapshotLifecyclePolicyInfo{}, fmt.Errorf("snapshot lifecycle policy %s not found in the response", id)
+	}
+	return policy, nil
+}
+
+func (c *clientV7) UpsertSnapshotLifecyclePolicy(ctx context.Context, id string, policy SnapshotLifecyclePolicy) error {
+	return c.put(ctx, "/_slm/policy/"+url.PathEscape(id), &policy, nil)
+}
+
+func (c *clientV7) DeleteSnapshotLifecyclePolicy(ctx context.Context, id string) error {
+	return c.delete(ctx, "/_slm/policy/"+url.PathEscape(id), nil, nil)
+}
+
+func (c *clientV7) Authenticate(ctx context.Context) error {
+	return c.get(ctx, "/_security/_authenticate", nil)
+}
+
+func (c *clientV7) UpsertUser(ctx context.Context, name string, user User) error {
+	return c.put(ctx, "/_security/user/"+url.PathEscape(name), &user, nil)
+}
+
+func (c *clientV7) DeleteUser(ctx context.Context, name string) error {
+	return c.delete(ctx, "/_security/user/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *clientV7) UpsertRole(ctx context.Context, name string, role RoleDescriptor) error {
+	return c.put(ctx, "/_security/role/"+url.PathEscape(name), &role, nil)
+}
+
+func (c *clientV7) DeleteRole(ctx context.Context, name string) error {
+	return c.delete(ctx, "/_security/role/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *clientV7) UpsertRoleMapping(ctx context.Context, name string, mapping RoleMapping) error {
+	return c.put(ctx, "/_security/role_mapping/"+url.PathEscape(name), &mapping, nil)
+}
+
+func (c *clientV7) DeleteRoleMapping(ctx context.Context, name string) error {
+	return c.delete(ctx, "/_security/role_mapping/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *clientV7) CreateServiceAccountToken(ctx context.Context, serviceAccount, name string) (ServiceAccountToken, error) {
+	var response CreateServiceAccountTokenResponse
+	path := fmt.Sprintf("/_security/service/%s/credential/token/%s", serviceAccount, url.PathEscape(name))
+	return response.Token, c.post(ctx, path, nil, &response)
+}
+
+func (c *clientV7) GetServiceAccountTokens(ctx context.Context, serviceAccount string) ([]string, error) {
+	var response ServiceAccountCredentialsResponse
+	if err := c.get(ctx, fmt.Sprintf("/_security/service/%s/credential", serviceAccount), &response); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(response.Tokens))
+	for name := range response.Tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (c *clientV7) DeleteServiceAccountToken(ctx context.Context, serviceAccount, name string) error {
+	path := fmt.Sprintf("/_security/service/%s/credential/token/%s", serviceAccount, url.PathEscape(name))
+	return c.delete(ctx, path, nil, nil)
+}
+
+func (c *clientV7) CreateAPIKey(ctx context.Context, request CreateAPIKeyRequest) (CreateAPIKeyResponse, error) {
+	var response CreateAPIKeyResponse
+	return response, c.post(ctx, "/_security/api_key", &request, &response)
+}
+
+func (c *clientV7) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var response APIKeysResponse
+	return response.APIKeys, c.get(ctx, "/_security/api_key?owner=true", &response)
+}
+
+func (c *clientV7) InvalidateAPIKeys(ctx context.Context, ids []string) error {
+	return c.delete(ctx, "/_security/api_key", &InvalidateAPIKeysRequest{IDs: ids}, nil)
+}
+
+func (c *clientV7) GetShutdowns(ctx context.Context) (ShutdownResponse, error) {
+	var response ShutdownResponse
+	return response, c.get(ctx, "/_nodes/shutdown", &response)
+}
+
+func (c *clientV7) PutShutdown(ctx context.Context, nodeID string, shutdownType ShutdownType, reason string) error {
+	request := ShutdownRequest{Type: shutdownType, Reason: reason}
+	return c.put(ctx, fmt.Sprintf("/_nodes/%s/shutdown", url.PathEscape(nodeID)), &request, nil)
+}
+
+func (c *clientV7) DeleteShutdown(ctx context.Context, nodeID string) error {
+	return c.delete(ctx, fmt.Sprintf("/_nodes/%s/shutdown", url.PathEscape(nodeID)), nil, nil)
+}
+
 func (c *clientV7) Equal(c2 Client) bool {
 	other, ok := c2.(*clientV7)
 	if !ok {
@@ -34,11 +34,11 @@ func NewConfigMapWithData(es types.NamespacedName, data map[string]string) corev
 
 // ReconcileScriptsConfigMap reconciles a configmap containing scripts used by
 // init containers and readiness probe.
-func ReconcileScriptsConfigMap(ctx context.Context, c k8s.Client, es esv1.Elasticsearch) error {
+func ReconcileScriptsConfigMap(ctx context.Context, c k8s.Client, es esv1.Elasticsearch, manageNodeTuning bool) error {
 	span, _ := apm.StartSpan(ctx, "reconcile_scripts", tracing.SpanTypeApp)
 	defer span.End()
 
-	fsScript, err := initcontainer.RenderPrepareFsScript()
+	fsScript, err := initcontainer.RenderPrepareFsScript(manageNodeTuning)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,172 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+)
+
+// applyStorageAutoscaling returns a copy of the given Elasticsearch resource, where the count of each autoscaled
+// NodeSet is replaced by the number of nodes required by its autoscaling policy, according to the disk usage
+// observed through the nodes stats API. The autoscaling status of the NodeSets is recorded in the reconcile state.
+// The resulting count is applied to the expected StatefulSets: upscales and downscales are then performed by the
+// regular orchestration steps, within the limits of the change budget.
+func applyStorageAutoscaling(
+	ctx context.Context,
+	es esv1.Elasticsearch,
+	esClient esclient.Client,
+	esReachable bool,
+	actualStatefulSets sset.StatefulSetList,
+	reconcileState *reconcile.State,
+) esv1.Elasticsearch {
+	if es.Spec.Autoscaling == nil || len(es.Spec.Autoscaling.Policies) == 0 {
+		reconcileState.UpdateAutoscalingStatus(nil)
+		return es
+	}
+
+	var nodesStats *esclient.NodesStats
+	if esReachable {
+		stats, err := esClient.GetNodesStats(ctx)
+		if err != nil {
+			// keep the current number of nodes until stats can be retrieved
+			log.Info("Unable to retrieve nodes stats, skipping storage autoscaling",
+				"namespace", es.Namespace, "es_name", es.Name, "error", err.Error())
+		} else {
+			nodesStats = &stats
+		}
+	}
+
+	autoscaled := *es.DeepCopy()
+	statuses := make([]esv1.NodeSetAutoscalingStatus, 0, len(es.Spec.Autoscaling.Policies))
+	now := time.Now()
+	for i, nodeSet := range autoscaled.Spec.NodeSets {
+		policy := es.Spec.Autoscaling.GetPolicy(nodeSet.Name)
+		if policy == nil {
+			continue
+		}
+		// the NodeSet is not scaled while its nodes are migrated to a new StatefulSet
+		nodeSetStatefulSets := nodespec.NodeSetStatefulSets(es, nodeSet.Name, actualStatefulSets)
+		actualExists := len(nodeSetStatefulSets) == 1
+		var actual appsv1.StatefulSet
+		if actualExists {
+			actual = nodeSetStatefulSets[0]
+		}
+		previous, previousExists := es.Status.GetAutoscalingStatus(nodeSet.Name)
+		if !previousExists {
+			// start from the current number of nodes
+			previous = esv1.NodeSetAutoscalingStatus{Name: nodeSet.Name, Count: nodeSet.Count}
+			if actualExists {
+				previous.Count = sset.GetReplicas(actual)
+			}
+		}
+
+		status := *previous.DeepCopy()
+		status.Count = boundedCount(*policy, status.Count)
+		if actualExists && nodesStats != nil {
+			status = nextAutoscalingStatus(*policy, status, actual, *nodesStats, now)
+		}
+		if status.Count != previous.Count {
+			log.Info("Scaling NodeSet according to its storage autoscaling policy",
+				"namespace", es.Namespace, "es_name", es.Name, "nodeset", nodeSet.Name,
+				"from", previous.Count, "to", status.Count)
+			reconcileState.AddEvent(corev1.EventTypeNormal, events.EventReasonAutoscaled,
+				fmt.Sprintf("Scaling NodeSet %s from %d to %d nodes", nodeSet.Name, previous.Count, status.Count))
+		}
+
+		autoscaled.Spec.NodeSets[i].Count = status.Count
+		statuses = append(statuses, status)
+	}
+	reconcileState.UpdateAutoscalingStatus(statuses)
+	return autoscaled
+}
+
+// nextAutoscalingStatus computes the autoscaling status of a NodeSet from the disk usage of its nodes.
+// The number of nodes is left unchanged if a scaling operation is still in progress, if the disk usage of some nodes
+// is unknown, or during the cooldown period following the last scaling operation.
+func nextAutoscalingStatus(
+	policy esv1.StorageAutoscalingPolicy,
+	status esv1.NodeSetAutoscalingStatus,
+	actual appsv1.StatefulSet,
+	nodesStats esclient.NodesStats,
+	now time.Time,
+) esv1.NodeSetAutoscalingStatus {
+	used, total, ok := diskUsage(actual, nodesStats)
+	if !ok {
+		// stats are not available for all nodes yet
+		return status
+	}
+	usage := int32(math.Round(float64(used) * 100 / float64(total)))
+	status.DiskUsagePercent = &usage
+
+	if sset.GetReplicas(actual) != status.Count {
+		// wait for the previous scaling operation to be over
+		return status
+	}
+	if status.LastScaleTime != nil && now.Sub(status.LastScaleTime.Time) < policy.CooldownPeriodOrDefault() {
+		return status
+	}
+
+	required := requiredNodeCount(policy, status.Count, used, total)
+	if required != status.Count {
+		status.Count = required
+		scaleTime := metav1.NewTime(now)
+		status.LastScaleTime = &scaleTime
+	}
+	return status
+}
+
+// diskUsage returns the used and total disk space of the nodes of the given StatefulSet.
+// It returns false if stats are not available for all of them.
+func diskUsage(statefulSet appsv1.StatefulSet, nodesStats esclient.NodesStats) (int64, int64, bool) {
+	statsByName := make(map[string]esclient.NodeStats, len(nodesStats.Nodes))
+	for _, stats := range nodesStats.Nodes {
+		statsByName[stats.Name] = stats
+	}
+	var used, total int64
+	for _, podName := range sset.PodNames(statefulSet) {
+		stats, exists := statsByName[podName]
+		if !exists {
+			return 0, 0, false
+		}
+		used += stats.FS.Total.TotalInBytes - stats.FS.Total.AvailableInBytes
+		total += stats.FS.Total.TotalInBytes
+	}
+	if total <= 0 {
+		return 0, 0, false
+	}
+	return used, total, true
+}
+
+// requiredNodeCount returns the number of nodes required to keep the disk usage below the policy target, assuming
+// data is evenly spread across nodes with the same storage capacity.
+func requiredNodeCount(policy esv1.StorageAutoscalingPolicy, current int32, used, total int64) int32 {
+	required := math.Ceil(float64(current) * float64(used) * 100 / (float64(total) * float64(policy.TargetDiskUsagePercent)))
+	return boundedCount(policy, int32(required))
+}
+
+// boundedCount returns the given count, bounded by the minimum and maximum counts of the policy.
+func boundedCount(policy esv1.StorageAutoscalingPolicy, count int32) int32 {
+	if count < policy.MinCount {
+		return policy.MinCount
+	}
+	if count > policy.MaxCount {
+		return policy.MaxCount
+	}
+	return count
+}
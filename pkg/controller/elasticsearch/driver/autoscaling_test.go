@@ -0,0 +1,167 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/pointer"
+)
+
+var testPolicy = esv1.StorageAutoscalingPolicy{NodeSet: "data", MinCount: 2, MaxCount: 6, TargetDiskUsagePercent: 80}
+
+// nodesStatsWithUsage returns stats for the given nodes, each with 100 bytes of disk space.
+func nodesStatsWithUsage(usedBytesByNode map[string]int64) esclient.NodesStats {
+	stats := esclient.NodesStats{Nodes: map[string]esclient.NodeStats{}}
+	for name, used := range usedBytesByNode {
+		nodeStats := esclient.NodeStats{Name: name}
+		nodeStats.FS.Total.TotalInBytes = 100
+		nodeStats.FS.Total.AvailableInBytes = 100 - used
+		stats.Nodes["id-"+name] = nodeStats
+	}
+	return stats
+}
+
+func Test_requiredNodeCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		current int32
+		used    int64
+		total   int64
+		want    int32
+	}{
+		{name: "below target", current: 3, used: 180, total: 300, want: 3},
+		{name: "at target", current: 3, used: 240, total: 300, want: 3},
+		{name: "above target", current: 3, used: 255, total: 300, want: 4},
+		{name: "far above target", current: 3, used: 297, total: 300, want: 4},
+		{name: "fewer nodes can hold the data", current: 4, used: 150, total: 400, want: 2},
+		{name: "bounded by maxCount", current: 6, used: 570, total: 600, want: 6},
+		{name: "bounded by minCount", current: 3, used: 0, total: 300, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, requiredNodeCount(testPolicy, tt.current, tt.used, tt.total))
+		})
+	}
+}
+
+func Test_nextAutoscalingStatus(t *testing.T) {
+	now := time.Now()
+	recently := metav1.NewTime(now.Add(-1 * time.Minute))
+	longAgo := metav1.NewTime(now.Add(-1 * time.Hour))
+	actual := sset.TestSset{Namespace: "ns", Name: "es-es-data", Replicas: 3}.Build()
+	highUsage := nodesStatsWithUsage(map[string]int64{"es-es-data-0": 90, "es-es-data-1": 90, "es-es-data-2": 90})
+
+	tests := []struct {
+		name       string
+		status     esv1.NodeSetAutoscalingStatus
+		actual     int32
+		nodesStats esclient.NodesStats
+		want       esv1.NodeSetAutoscalingStatus
+	}{
+		{
+			name:       "missing stats: no change",
+			status:     esv1.NodeSetAutoscalingStatus{Name: "data", Count: 3},
+			nodesStats: nodesStatsWithUsage(map[string]int64{"es-es-data-0": 90, "es-es-data-1": 90}),
+			want:       esv1.NodeSetAutoscalingStatus{Name: "data", Count: 3},
+		},
+		{
+			name:       "usage below target: no change",
+			status:     esv1.NodeSetAutoscalingStatus{Name: "data", Count: 3},
+			nodesStats: nodesStatsWithUsage(map[string]int64{"es-es-data-0": 60, "es-es-data-1": 70, "es-es-data-2": 80}),
+			want:       esv1.NodeSetAutoscalingStatus{Name: "data", Count: 3, DiskUsagePercent: pointer.Int32(70)},
+		},
+		{
+			name:       "usage above target: scale up",
+			status:     esv1.NodeSetAutoscalingStatus{Name: "data", Count: 3, LastScaleTime: &longAgo},
+			nodesStats: highUsage,
+			want:       esv1.NodeSetAutoscalingStatus{Name: "data", Count: 4, DiskUsagePercent: pointer.Int32(90), LastScaleTime: &metav1.Time{Time: now}},
+		},
+		{
+			name:       "usage above target during the cooldown period: no change",
+			status:     esv1.NodeSetAutoscalingStatus{Name: "data", Count: 3, LastScaleTime: &recently},
+			nodesStats: highUsage,
+			want:       esv1.NodeSetAutoscalingStatus{Name: "data", Count: 3, DiskUsagePercent: pointer.Int32(90), LastScaleTime: &recently},
+		},
+		{
+			name:       "usage above target while a previous scaling is in progress: no change",
+			status:     esv1.NodeSetAutoscalingStatus{Name: "data", Count: 2},
+			nodesStats: highUsage,
+			want:       esv1.NodeSetAutoscalingStatus{Name: "data", Count: 2, DiskUsagePercent: pointer.Int32(90)},
+		},
+		{
+			name:       "low usage: scale down",
+			status:     esv1.NodeSetAutoscalingStatus{Name: "data", Count: 3},
+			nodesStats: nodesStatsWithUsage(map[string]int64{"es-es-data-0": 20, "es-es-data-1": 20, "es-es-data-2": 20}),
+			want:       esv1.NodeSetAutoscalingStatus{Name: "data", Count: 2, DiskUsagePercent: pointer.Int32(20), LastScaleTime: &metav1.Time{Time: now}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextAutoscalingStatus(testPolicy, tt.status, actual, tt.nodesStats, now)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_applyStorageAutoscaling(t *testing.T) {
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec: esv1.ElasticsearchSpec{
+			NodeSets: []esv1.NodeSet{{Name: "master", Count: 3}, {Name: "data", Count: 3}},
+			Autoscaling: &esv1.AutoscalingSpec{
+				Policies: []esv1.StorageAutoscalingPolicy{testPolicy},
+			},
+		},
+	}
+	actualStatefulSets := sset.StatefulSetList{
+		sset.TestSset{Namespace: "ns", Name: "es-es-master", Replicas: 3}.Build(),
+		sset.TestSset{Namespace: "ns", Name: "es-es-data", Replicas: 3}.Build(),
+	}
+	esClient := &fakeESClient{
+		nodesStats: nodesStatsWithUsage(map[string]int64{"es-es-data-0": 90, "es-es-data-1": 90, "es-es-data-2": 90}),
+	}
+
+	// ES not reachable: the current number of nodes is kept
+	state := reconcile.NewState(es)
+	autoscaled := applyStorageAutoscaling(context.Background(), es, esClient, false, actualStatefulSets, state)
+	require.Equal(t, int32(3), autoscaled.Spec.NodeSets[0].Count)
+	require.Equal(t, int32(3), autoscaled.Spec.NodeSets[1].Count)
+	_, updated := state.Apply()
+	require.NotNil(t, updated)
+	require.Equal(t, []esv1.NodeSetAutoscalingStatus{{Name: "data", Count: 3}}, updated.Status.Autoscaling)
+
+	// ES reachable: the data NodeSet should be scaled up, the spec of the original resource is left untouched
+	state = reconcile.NewState(es)
+	autoscaled = applyStorageAutoscaling(context.Background(), es, esClient, true, actualStatefulSets, state)
+	require.Equal(t, int32(3), autoscaled.Spec.NodeSets[0].Count)
+	require.Equal(t, int32(4), autoscaled.Spec.NodeSets[1].Count)
+	require.Equal(t, int32(3), es.Spec.NodeSets[1].Count)
+	_, updated = state.Apply()
+	require.NotNil(t, updated)
+	require.Len(t, updated.Status.Autoscaling, 1)
+	require.Equal(t, int32(4), updated.Status.Autoscaling[0].Count)
+	require.Equal(t, pointer.Int32(90), updated.Status.Autoscaling[0].DiskUsagePercent)
+	require.NotNil(t, updated.Status.Autoscaling[0].LastScaleTime)
+
+	// the autoscaling status should be cleared once autoscaling is disabled
+	es.Spec.Autoscaling = nil
+	es.Status.Autoscaling = updated.Status.Autoscaling
+	state = reconcile.NewState(es)
+	autoscaled = applyStorageAutoscaling(context.Background(), es, esClient, true, actualStatefulSets, state)
+	require.Equal(t, int32(3), autoscaled.Spec.NodeSets[1].Count)
+	_, updated = state.Apply()
+	require.NotNil(t, updated)
+	require.Nil(t, updated.Status.Autoscaling)
+}
@@ -6,6 +6,7 @@ package driver
 
 import (
 	"context"
+	"time"
 
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
@@ -34,7 +35,7 @@ func HandleDownscale(
 	results := &reconciler.Results{}
 
 	// make sure we only downscale nodes we're allowed to
-	downscaleState, err := newDownscaleState(downscaleCtx.k8sClient, downscaleCtx.es)
+	downscaleState, err := newDownscaleState(downscaleCtx.k8sClient, downscaleCtx.es, expectedStatefulSets.ExpectedNodeCount())
 	if err != nil {
 		return results.WithError(err)
 	}
@@ -48,12 +49,28 @@ func HandleDownscale(
 		return results.WithError(err)
 	}
 
-	// migrate data away from nodes that should be removed
-	// if leavingNodes is empty, it clears any existing settings
+	// nodes are removed once the next maintenance window opens
 	leavingNodes := leavingNodeNames(downscales)
-	if err := migration.MigrateData(downscaleCtx.parentCtx, downscaleCtx.k8sClient, downscaleCtx.es, downscaleCtx.esClient, leavingNodes); err != nil {
+	if window := downscaleCtx.maintenanceWindow; !window.IsOpen() && len(leavingNodes) > 0 {
+		log.Info("Postponing the removal of nodes until the next maintenance window",
+			"namespace", downscaleCtx.es.Namespace, "es_name", downscaleCtx.es.Name,
+			"nodes", leavingNodes, "next_window", window.Next)
+		downscaleCtx.reconcileState.UpdatePostponedRemovals(leavingNodes, window.Next)
+		downscaleCtx.reconcileState.UpdateDownscaleOperations(nil)
+		return results.WithResult(maintenanceRequeue(window))
+	}
+	downscaleCtx.reconcileState.UpdatePostponedRemovals(nil, time.Time{})
+
+	// migrate data away from nodes that should be removed
+	if err := migrateData(downscaleCtx, expectedStatefulSets, leavingNodes); err != nil {
 		return results.WithError(err)
 	}
+	// report the shards still to be migrated away from each leaving node
+	remainingShards, err := migration.RemainingShards(downscaleCtx.parentCtx, downscaleCtx.shardLister, leavingNodes...)
+	if err != nil {
+		return results.WithError(err)
+	}
+	downscaleCtx.reconcileState.UpdateDownscaleOperations(downscaleOperations(leavingNodes, remainingShards))
 
 	for _, downscale := range downscales {
 		// attempt the StatefulSet downscale (may or may not remove nodes)
@@ -181,7 +198,7 @@ func calculatePerformableDownscale(
 	}
 	// iterate on all leaving nodes (ordered by highest ordinal first)
 	for _, node := range downscale.leavingNodeNames() {
-		migrating, err := migration.IsMigratingData(ctx.parentCtx, ctx.shardLister, node)
+		migrating, err := isMigratingData(ctx, node)
 		if err != nil {
 			return performableDownscale, err
 		}
@@ -198,6 +215,46 @@ func calculatePerformableDownscale(
 	return performableDownscale, nil
 }
 
+// migrateData prepares the leaving nodes for their removal. If the node shutdown API is supported, it registers
+// remove shutdown records for them and deletes the records that are not relevant anymore. Otherwise it excludes
+// them from shards allocation.
+func migrateData(ctx downscaleContext, expectedStatefulSets sset.StatefulSetList, leavingNodes []string) error {
+	if ctx.nodeShutdown == nil {
+		// if leavingNodes is empty, it clears any existing settings
+		return migration.MigrateData(ctx.parentCtx, ctx.k8sClient, ctx.es, ctx.esClient, leavingNodes)
+	}
+	// clear the allocation exclusions that may have been set before the node shutdown API could be used
+	if err := migration.MigrateData(ctx.parentCtx, ctx.k8sClient, ctx.es, ctx.esClient, nil); err != nil {
+		return err
+	}
+	if err := ctx.nodeShutdown.Register(ctx.parentCtx, leavingNodes); err != nil {
+		return err
+	}
+	// clean up the records of the nodes that left the cluster, or that should stay in the cluster
+	// if a downscale was cancelled
+	if err := ctx.nodeShutdown.DeleteOrphans(ctx.parentCtx); err != nil {
+		return err
+	}
+	return ctx.nodeShutdown.Delete(ctx.parentCtx, expectedStatefulSets.PodNames())
+}
+
+// isMigratingData returns true if the given leaving node cannot be removed yet because its data is being migrated.
+func isMigratingData(ctx downscaleContext, node string) (bool, error) {
+	if ctx.nodeShutdown == nil {
+		return migration.IsMigratingData(ctx.parentCtx, ctx.shardLister, node)
+	}
+	inCluster, err := ctx.nodeShutdown.InCluster(ctx.parentCtx, node)
+	if err != nil {
+		return false, err
+	}
+	if !inCluster {
+		// no shutdown record can be registered for a node that is not in the cluster, only consider its shards
+		return migration.IsMigratingData(ctx.parentCtx, ctx.shardLister, node)
+	}
+	complete, err := ctx.nodeShutdown.IsComplete(ctx.parentCtx, node)
+	return !complete, err
+}
+
 // doDownscale schedules nodes removal for the given downscale, and updates zen settings accordingly.
 func doDownscale(downscaleCtx downscaleContext, downscale ssetDownscale, actualStatefulSets sset.StatefulSetList) error {
 	ssetLogger(downscale.statefulSet).Info(
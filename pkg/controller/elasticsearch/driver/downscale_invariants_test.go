@@ -127,7 +127,7 @@ func Test_newDownscaleState(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			k8sClient := k8s.WrappedFakeClient(tt.initialResources...)
-			got, err := newDownscaleState(k8sClient, es)
+			got, err := newDownscaleState(k8sClient, es, es.Spec.NodeCount())
 			require.NoError(t, err)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewDownscaleInvariants() got = %v, want %v", got, tt.want)
@@ -6,16 +6,22 @@ package driver
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/comparison"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	commonscheme "github.com/elastic/cloud-on-k8s/pkg/controller/common/scheme"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/maintenance"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/migration"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
@@ -275,6 +281,13 @@ func TestHandleDownscale(t *testing.T) {
 		Data:      true,
 	}.Build()
 	require.NoError(t, k8sClient.Create(&ssetToRemove))
+	podsSsetToRemove := []corev1.Pod{
+		sset.TestPod{Namespace: "ns", Name: "ssetToRemove-0", StatefulSetName: "ssetToRemove", ClusterName: clusterName, Version: "7.2.0", Data: true, Ready: true}.Build(),
+		sset.TestPod{Namespace: "ns", Name: "ssetToRemove-1", StatefulSetName: "ssetToRemove", ClusterName: clusterName, Version: "7.2.0", Data: true, Ready: true}.Build(),
+	}
+	for i := range podsSsetToRemove {
+		require.NoError(t, k8sClient.Create(&podsSsetToRemove[i]))
+	}
 	// do the downscale, that third StatefulSet is not part of the expected ones
 	err = k8sClient.List(&actual)
 	require.NoError(t, err)
@@ -284,6 +297,10 @@ func TestHandleDownscale(t *testing.T) {
 	err = k8sClient.Get(k8s.ExtractNamespacedName(&ssetToRemove), &ssetToRemove)
 	require.NoError(t, err)
 	require.Equal(t, int32(0), sset.GetReplicas(ssetToRemove))
+	// simulate pods deletion
+	for i := range podsSsetToRemove {
+		require.NoError(t, k8sClient.Delete(&podsSsetToRemove[i]))
+	}
 	// run downscale again: this time the StatefulSet should be removed
 	err = k8sClient.List(&actual)
 	require.NoError(t, err)
@@ -293,6 +310,50 @@ func TestHandleDownscale(t *testing.T) {
 	require.True(t, apierrors.IsNotFound(err))
 }
 
+func TestHandleDownscale_maintenanceWindow(t *testing.T) {
+	k8sClient := k8s.WrappedFakeClient(runtimeObjs...)
+	esClient := &fakeESClient{}
+	nextWindow := time.Now().Add(time.Hour).Truncate(time.Second)
+	downscaleCtx := downscaleContext{
+		k8sClient:         k8sClient,
+		expectations:      expectations.NewExpectations(k8sClient),
+		reconcileState:    reconcile.NewState(esv1.Elasticsearch{}),
+		shardLister:       migration.NewFakeShardLister(esclient.Shards{}),
+		esClient:          esClient,
+		es:                es,
+		maintenanceWindow: maintenance.Window{Next: nextWindow},
+		parentCtx:         context.Background(),
+	}
+	actualStatefulSets := sset.StatefulSetList{ssetMaster3Replicas, ssetData4Replicas}
+	ssetData4ReplicasDownscaled := *ssetData4Replicas.DeepCopy()
+	nodespec.UpdateReplicas(&ssetData4ReplicasDownscaled, pointer.Int32(2))
+	requestedStatefulSets := sset.StatefulSetList{ssetMaster3Replicas, ssetData4ReplicasDownscaled}
+
+	// outside of the maintenance window, the downscale is postponed
+	results := HandleDownscale(downscaleCtx, requestedStatefulSets, actualStatefulSets)
+	res, err := results.Aggregate()
+	require.NoError(t, err)
+	require.True(t, res.RequeueAfter > 0 && res.RequeueAfter <= time.Hour)
+	require.False(t, esClient.ExcludeFromShardAllocationCalled)
+	var actual appsv1.StatefulSet
+	require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&ssetData4Replicas), &actual))
+	require.Equal(t, int32(4), sset.GetReplicas(actual))
+	_, updated := downscaleCtx.reconcileState.Apply()
+	require.Equal(t, &esv1.PostponedOperations{
+		NextMaintenanceWindow: metav1.NewTime(nextWindow),
+		Removals:              []string{"ssetData4Replicas-3", "ssetData4Replicas-2"},
+	}, updated.Status.InProgressOperations.Postponed)
+
+	// once the window is open, data is migrated away from the leaving nodes
+	downscaleCtx.maintenanceWindow = maintenance.Always
+	downscaleCtx.reconcileState = reconcile.NewState(*updated)
+	results = HandleDownscale(downscaleCtx, requestedStatefulSets, actualStatefulSets)
+	require.False(t, results.HasError())
+	require.True(t, esClient.ExcludeFromShardAllocationCalled)
+	_, updated = downscaleCtx.reconcileState.Apply()
+	require.Nil(t, updated.Status.InProgressOperations.Postponed)
+}
+
 func Test_calculateDownscales(t *testing.T) {
 	ssets := sset.StatefulSetList{
 		{
@@ -1067,3 +1128,96 @@ func Test_deleteStatefulSets(t *testing.T) {
 		})
 	}
 }
+
+// fakeNodeShutdownAPI simulates the nodes, node shutdown and cluster settings APIs.
+type fakeNodeShutdownAPI struct {
+	// node names indexed by node id
+	nodes     map[string]string
+	shutdowns map[string]esclient.NodeShutdown
+	requests  []string
+}
+
+func (f *fakeNodeShutdownAPI) roundTrip(req *http.Request) *http.Response {
+	path := req.URL.Path
+	switch {
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "/_nodes/_all"):
+		nodes := esclient.Nodes{Nodes: map[string]esclient.Node{}}
+		for id, name := range f.nodes {
+			nodes.Nodes[id] = esclient.Node{Name: name}
+		}
+		body, _ := json.Marshal(nodes)
+		return esclient.NewMockResponse(200, req, string(body))
+	case req.Method == http.MethodGet && path == "/_nodes/shutdown":
+		response := esclient.ShutdownResponse{Nodes: []esclient.NodeShutdown{}}
+		for _, shutdown := range f.shutdowns {
+			response.Nodes = append(response.Nodes, shutdown)
+		}
+		body, _ := json.Marshal(response)
+		return esclient.NewMockResponse(200, req, string(body))
+	}
+	f.requests = append(f.requests, req.Method+" "+path)
+	return esclient.NewMockResponse(200, req, `{"acknowledged":true}`)
+}
+
+func newFakeNodeShutdownAPI(nodes ...string) *fakeNodeShutdownAPI {
+	api := &fakeNodeShutdownAPI{nodes: map[string]string{}, shutdowns: map[string]esclient.NodeShutdown{}}
+	for _, node := range nodes {
+		api.nodes["id-"+node] = node
+	}
+	return api
+}
+
+func (f *fakeNodeShutdownAPI) withShutdown(nodeID string, shutdownType esclient.ShutdownType, status esclient.ShutdownStatus) *fakeNodeShutdownAPI {
+	f.shutdowns[nodeID] = esclient.NodeShutdown{NodeID: nodeID, Type: strings.ToUpper(string(shutdownType)), Status: status}
+	return f
+}
+
+func Test_migrateData_nodeShutdown(t *testing.T) {
+	expected := sset.TestSset{Name: "ssetData4Replicas", Namespace: "ns", Replicas: 2}.Build()
+	api := newFakeNodeShutdownAPI("ssetData4Replicas-0", "ssetData4Replicas-1", "ssetData4Replicas-2", "ssetData4Replicas-3").
+		// the removal of ssetData4Replicas-1 was cancelled
+		withShutdown("id-ssetData4Replicas-1", esclient.Remove, esclient.ShutdownInProgress).
+		// ssetData4Replicas-2 is ready to be removed
+		withShutdown("id-ssetData4Replicas-2", esclient.Remove, esclient.ShutdownComplete).
+		// ssetData4Replicas-9 has left the cluster
+		withShutdown("id-ssetData4Replicas-9", esclient.Remove, esclient.ShutdownComplete)
+	esClient := esclient.NewMockClient(version.MustParse("7.15.2"), api.roundTrip)
+	es := es.DeepCopy()
+	k8sClient := k8s.WrappedFakeClient(es)
+	downscaleCtx := newDownscaleContext(
+		context.Background(),
+		k8sClient,
+		esClient,
+		reconcile.ResourcesState{},
+		observer.State{},
+		reconcile.NewState(*es),
+		expectations.NewExpectations(k8sClient),
+		*es,
+		maintenance.Always,
+	)
+	downscaleCtx.shardLister = migration.NewFakeShardLister(esclient.Shards{})
+	require.NotNil(t, downscaleCtx.nodeShutdown)
+
+	leavingNodes := []string{"ssetData4Replicas-3", "ssetData4Replicas-2", "ssetData4Replicas-4"}
+	require.NoError(t, migrateData(downscaleCtx, sset.StatefulSetList{expected}, leavingNodes))
+	require.ElementsMatch(t, []string{
+		// allocation exclusions are cleared
+		"PUT /_cluster/settings",
+		// a shutdown is registered for the leaving node in the cluster without any
+		"PUT /_nodes/id-ssetData4Replicas-3/shutdown",
+		// shutdowns of the nodes which are not leaving anymore are deleted
+		"DELETE /_nodes/id-ssetData4Replicas-9/shutdown",
+		"DELETE /_nodes/id-ssetData4Replicas-1/shutdown",
+	}, api.requests)
+
+	for node, wantMigrating := range map[string]bool{
+		"ssetData4Replicas-2": false,
+		"ssetData4Replicas-3": true,
+		// not in the cluster, and no shards
+		"ssetData4Replicas-4": false,
+	} {
+		migrating, err := isMigratingData(downscaleCtx, node)
+		require.NoError(t, err)
+		require.Equal(t, wantMigrating, migrating, node)
+	}
+}
@@ -10,13 +10,18 @@ import (
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
 	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/maintenance"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/shutdown"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	appsv1 "k8s.io/api/apps/v1"
 )
 
+// removeShutdownReason is the reason of the shutdown records registered for the nodes leaving the cluster.
+const removeShutdownReason = "ECK downscale"
+
 // downscaleContext holds the context of this downscale, including clients and states,
 // propagated from the main driver.
 type downscaleContext struct {
@@ -24,6 +29,8 @@ type downscaleContext struct {
 	k8sClient   k8s.Client
 	esClient    esclient.Client
 	shardLister esclient.ShardLister
+	// nodeShutdown prepares the removal of the leaving nodes, nil if the node shutdown API is not supported
+	nodeShutdown *shutdown.NodeShutdown
 	// driver states
 	resourcesState reconcile.ResourcesState
 	observedState  observer.State
@@ -31,6 +38,8 @@ type downscaleContext struct {
 	expectations   *expectations.Expectations
 	// ES cluster
 	es esv1.Elasticsearch
+	// maintenanceWindow postpones the removal of nodes while it is not open
+	maintenanceWindow maintenance.Window
 
 	parentCtx context.Context
 }
@@ -45,17 +54,24 @@ func newDownscaleContext(
 	expectations *expectations.Expectations,
 	// ES cluster
 	es esv1.Elasticsearch,
+	maintenanceWindow maintenance.Window,
 ) downscaleContext {
+	var nodeShutdown *shutdown.NodeShutdown
+	if shutdown.IsSupported(esClient.Version()) {
+		nodeShutdown = shutdown.NewNodeShutdown(esClient, k8s.ExtractNamespacedName(&es), esclient.Remove, removeShutdownReason)
+	}
 	return downscaleContext{
-		k8sClient:      k8sClient,
-		esClient:       esClient,
-		shardLister:    esClient,
-		resourcesState: resourcesState,
-		observedState:  observedState,
-		reconcileState: reconcileState,
-		es:             es,
-		expectations:   expectations,
-		parentCtx:      ctx,
+		k8sClient:         k8sClient,
+		nodeShutdown:      nodeShutdown,
+		esClient:          esClient,
+		shardLister:       esClient,
+		resourcesState:    resourcesState,
+		observedState:     observedState,
+		reconcileState:    reconcileState,
+		es:                es,
+		expectations:      expectations,
+		maintenanceWindow: maintenanceWindow,
+		parentCtx:         ctx,
 	}
 }
 
@@ -88,3 +104,15 @@ func leavingNodeNames(downscales []ssetDownscale) []string {
 	}
 	return leavingNodes
 }
+
+// downscaleOperations returns the leaving nodes along with the number of shards they still hold.
+func downscaleOperations(leavingNodes []string, remainingShards map[string]int) []esv1.DownscaleOperation {
+	if len(leavingNodes) == 0 {
+		return nil
+	}
+	operations := make([]esv1.DownscaleOperation, 0, len(leavingNodes))
+	for _, node := range leavingNodes {
+		operations = append(operations, esv1.DownscaleOperation{Node: node, RemainingShards: remainingShards[node]})
+	}
+	return operations
+}
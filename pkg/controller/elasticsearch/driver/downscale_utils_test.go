@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	appsv1 "k8s.io/api/apps/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 )
 
 func Test_ssetDownscale_leavingNodeNames(t *testing.T) {
@@ -112,3 +114,34 @@ func Test_leavingNodeNames(t *testing.T) {
 		})
 	}
 }
+
+func Test_downscaleOperations(t *testing.T) {
+	tests := []struct {
+		name            string
+		leavingNodes    []string
+		remainingShards map[string]int
+		want            []esv1.DownscaleOperation
+	}{
+		{
+			name:         "no leaving node",
+			leavingNodes: []string{},
+			want:         nil,
+		},
+		{
+			name:            "leaving nodes with and without shards",
+			leavingNodes:    []string{"ssetMaster3Replicas-1", "ssetData4Replicas-3"},
+			remainingShards: map[string]int{"ssetMaster3Replicas-1": 0, "ssetData4Replicas-3": 3},
+			want: []esv1.DownscaleOperation{
+				{Node: "ssetMaster3Replicas-1", RemainingShards: 0},
+				{Node: "ssetData4Replicas-3", RemainingShards: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := downscaleOperations(tt.leavingNodes, tt.remainingShards); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("downscaleOperations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,12 +6,15 @@ package driver
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	controller "sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
@@ -33,13 +36,19 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/initcontainer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/license"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/maintenance"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/networkpolicy"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/remotecluster"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/security"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/snapshot"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/stackmon"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
 	esversion "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/zone"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
 
@@ -47,6 +56,11 @@ var (
 	defaultRequeue = controller.Result{Requeue: true, RequeueAfter: 10 * time.Second}
 )
 
+// maintenanceRequeue returns the result requeuing the reconciliation when the next maintenance window opens.
+func maintenanceRequeue(window maintenance.Window) controller.Result {
+	return controller.Result{RequeueAfter: window.RequeueAfter(time.Now())}
+}
+
 // Driver orchestrates the reconciliation of an Elasticsearch resource.
 // Its lifecycle is bound to a single reconciliation attempt.
 type Driver interface {
@@ -72,8 +86,11 @@ type DefaultDriverParameters struct {
 	// Version is the version of Elasticsearch we want to reconcile towards.
 	Version version.Version
 	// Client is used to access the Kubernetes API.
-	Client   k8s.Client
-	Recorder record.EventRecorder
+	Client k8s.Client
+	// APIReader reads cluster-scoped resources from the Kubernetes API, bypassing the cache of the Client which may be
+	// restricted to the managed namespaces.
+	APIReader client.Reader
+	Recorder  record.EventRecorder
 
 	// LicenseChecker is used for some features to check if an appropriate license is setup
 	LicenseChecker commonlicense.Checker
@@ -117,7 +134,7 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 		return results.WithError(err)
 	}
 
-	if err := configmap.ReconcileScriptsConfigMap(ctx, d.Client, d.ES); err != nil {
+	if err := configmap.ReconcileScriptsConfigMap(ctx, d.Client, d.ES, d.OperatorParameters.ManageNodeTuning); err != nil {
 		return results.WithError(err)
 	}
 
@@ -131,6 +148,10 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 		return results.WithError(err)
 	}
 
+	if err := networkpolicy.Reconcile(d.Client, d.Recorder(), d.ES, d.OperatorParameters); err != nil {
+		return results.WithError(err)
+	}
+
 	certificateResources, res := certificates.Reconcile(
 		ctx,
 		d,
@@ -138,6 +159,7 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 		[]corev1.Service{*externalService},
 		d.OperatorParameters.CACertRotation,
 		d.OperatorParameters.CertRotation,
+		d.OperatorParameters.CertManagerIssuer,
 	)
 	if results.WithResults(res).HasError() {
 		return results
@@ -156,10 +178,32 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 	if err != nil {
 		return results.WithError(err)
 	}
+	d.ReconcileState.UpdateRunningVersion(min)
 	if min == nil {
 		min = &d.Version
 	}
 
+	// complete the rotation of the credentials in progress once all the nodes accept the new users
+	controllerUser, rotationRequeue, err := user.ReconcileCredentialsRotation(
+		d.Client,
+		d.ES,
+		d.Recorder(),
+		controllerUser,
+		d.authenticateNodes(
+			ctx,
+			resourcesState,
+			*min,
+			certificateResources.TrustedHTTPCertificates,
+			certificateResources.OperatorClientCertificate,
+		),
+	)
+	if err != nil {
+		return results.WithError(err)
+	}
+	if rotationRequeue > 0 {
+		results.WithResult(controller.Result{RequeueAfter: rotationRequeue})
+	}
+
 	warnUnsupportedDistro(resourcesState.AllPods, d.ReconcileState.Recorder)
 
 	observedState := d.Observers.ObservedStateResolver(
@@ -169,6 +213,7 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 			controllerUser,
 			*min,
 			certificateResources.TrustedHTTPCertificates,
+			certificateResources.OperatorClientCertificate,
 		),
 	)
 
@@ -185,6 +230,7 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 		controllerUser,
 		*min,
 		certificateResources.TrustedHTTPCertificates,
+		certificateResources.OperatorClientCertificate,
 	)
 	defer esClient.Close()
 
@@ -192,6 +238,7 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 	if err != nil {
 		return results.WithError(err)
 	}
+	d.ReconcileState.UpdateElasticsearchReachable(esReachable)
 
 	results.Apply(
 		"reconcile-cluster-license",
@@ -221,13 +268,51 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 	)
 
 	if esReachable {
-		err = remotecluster.UpdateSettings(ctx, d.Client, esClient, d.Recorder(), d.LicenseChecker, d.ES)
+		err = remotecluster.UpdateSettings(ctx, d.Client, esClient, d.Recorder(), d.LicenseChecker, d.ES, *min)
 		if err != nil {
 			msg := "Could not update remote clusters in Elasticsearch settings"
 			d.ReconcileState.AddEvent(corev1.EventTypeWarning, events.EventReasonUnexpected, msg)
 			log.Error(err, msg, "namespace", d.ES.Namespace, "es_name", d.ES.Name)
 			results.WithResult(defaultRequeue)
 		}
+
+		if err := snapshot.Reconcile(ctx, d.Client, esClient, &d.ES, d.ReconcileState); err != nil {
+			msg := "Could not reconcile snapshot repositories and policies"
+			d.ReconcileState.AddEvent(corev1.EventTypeWarning, events.EventReasonUnexpected, msg)
+			log.Error(err, msg, "namespace", d.ES.Namespace, "es_name", d.ES.Name)
+			results.WithResult(defaultRequeue)
+		} else if d.ES.Spec.Snapshots != nil && len(d.ES.Spec.Snapshots.Policies) > 0 {
+			// refresh the status of the snapshot lifecycle policies periodically
+			results.WithResult(controller.Result{RequeueAfter: snapshot.StatusRefreshInterval})
+		}
+
+		if err := security.Reconcile(ctx, d.Client, esClient, d.DynamicWatches(), &d.ES); err != nil {
+			msg := "Could not reconcile users, roles and role mappings"
+			d.ReconcileState.AddEvent(corev1.EventTypeWarning, events.EventReasonUnexpected, msg)
+			log.Error(err, msg, "namespace", d.ES.Namespace, "es_name", d.ES.Name)
+			results.WithResult(defaultRequeue)
+		}
+
+		if err := user.GarbageCollectAssociationCredentials(ctx, d.Client, esClient, d.ES, time.Now()); err != nil {
+			msg := "Could not revoke unused association credentials"
+			d.ReconcileState.AddEvent(corev1.EventTypeWarning, events.EventReasonUnexpected, msg)
+			log.Error(err, msg, "namespace", d.ES.Namespace, "es_name", d.ES.Name)
+			results.WithResult(defaultRequeue)
+		}
+	}
+
+	// annotate the scheduled Pods with the zone of their Kubernetes node, Elasticsearch waits for it to start
+	if err := zone.AnnotatePods(d.Client, d.APIReader, resourcesState.AllPods); err != nil {
+		return results.WithError(err)
+	}
+
+	// report the Kubernetes nodes whose vm.max_map_count is too low, as checked by the prepare-fs init container
+	vmMaxMapCountWarnings, err := initcontainer.VMMaxMapCountWarnings(d.Client, d.ES, resourcesState.AllPods)
+	if err != nil {
+		return results.WithError(err)
+	}
+	for _, warning := range vmMaxMapCountWarnings {
+		d.ReconcileState.AddEvent(corev1.EventTypeWarning, events.EventReasonNodeTuningRequired, warning)
 	}
 
 	// Compute seed hosts based on current masters with a podIP
@@ -256,6 +341,11 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 		results = results.WithResult(defaultRequeue)
 	}
 
+	// reconcile the configuration of the monitoring sidecars, if any
+	if err := stackmon.ReconcileConfigSecrets(d.Client, d.ES); err != nil {
+		return results.WithError(err)
+	}
+
 	// reconcile StatefulSets and nodes configuration
 	res = d.reconcileNodeSpecs(ctx, esReachable, esClient, d.ReconcileState, observedState, *resourcesState, keystoreResources)
 	results = results.WithResults(res)
@@ -268,15 +358,56 @@ func (d *defaultDriver) Reconcile(ctx context.Context) *reconciler.Results {
 	return results
 }
 
-// newElasticsearchClient creates a new Elasticsearch HTTP client for this cluster using the provided user
+// newElasticsearchClient creates a new Elasticsearch HTTP client for this cluster using the provided user,
+// presenting the given client certificate if not nil
 func (d *defaultDriver) newElasticsearchClient(
 	state *reconcile.ResourcesState,
 	user esclient.BasicAuth,
 	v version.Version,
 	caCerts []*x509.Certificate,
+	clientCert *tls.Certificate,
 ) esclient.Client {
 	url := services.ElasticsearchURL(d.ES, state.CurrentPodsByPhase[corev1.PodRunning])
-	return esclient.NewElasticsearchClient(d.OperatorParameters.Dialer, url, user, v, caCerts)
+	return esclient.NewElasticsearchClientWithCertificate(d.OperatorParameters.Dialer, url, user, v, caCerts, clientCert)
+}
+
+// authenticateNodes returns a function checking that the given credentials are accepted by each of the ready
+// Elasticsearch nodes, which reload the file realm independently from each other.
+func (d *defaultDriver) authenticateNodes(
+	ctx context.Context,
+	state *reconcile.ResourcesState,
+	v version.Version,
+	caCerts []*x509.Certificate,
+	clientCert *tls.Certificate,
+) func(esclient.BasicAuth) error {
+	return func(credentials esclient.BasicAuth) error {
+		authenticated := 0
+		for _, pod := range state.CurrentPodsByPhase[corev1.PodRunning] {
+			if !k8s.IsPodReady(pod) {
+				// a node starting later reads the latest file realm
+				continue
+			}
+			url, ok := services.ElasticsearchPodURL(pod)
+			if !ok {
+				return fmt.Errorf("cannot compute the URL of pod %s/%s", pod.Namespace, pod.Name)
+			}
+			if err := authenticate(ctx, esclient.NewElasticsearchClientWithCertificate(d.OperatorParameters.Dialer, url, credentials, v, caCerts, clientCert)); err != nil {
+				return fmt.Errorf("pod %s/%s: %w", pod.Namespace, pod.Name, err)
+			}
+			authenticated++
+		}
+		if authenticated == 0 {
+			return errors.New("no ready Elasticsearch node")
+		}
+		return nil
+	}
+}
+
+func authenticate(ctx context.Context, esClient esclient.Client) error {
+	defer esClient.Close()
+	ctx, cancel := context.WithTimeout(ctx, esclient.DefaultReqTimeout)
+	defer cancel()
+	return esClient.Authenticate(ctx)
 }
 
 // warnUnsupportedDistro sends an event of type warning if the Elasticsearch Docker image is not a supported
@@ -44,6 +44,8 @@ type fakeESClient struct { //nolint:maligned
 
 	health                      esclient.Health
 	GetClusterHealthCalledCount int
+
+	nodesStats esclient.NodesStats
 }
 
 func (f *fakeESClient) SetMinimumMasterNodes(_ context.Context, n int) error {
@@ -94,6 +96,10 @@ func (f *fakeESClient) GetClusterHealth(_ context.Context) (esclient.Health, err
 	return f.health, nil
 }
 
+func (f *fakeESClient) GetNodesStats(_ context.Context) (esclient.NodesStats, error) {
+	return f.nodesStats, nil
+}
+
 // -- ESState tests
 
 func Test_memoizingNodes_NodesInCluster(t *testing.T) {
@@ -7,12 +7,14 @@ package driver
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
 	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/maintenance"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/pdb"
@@ -53,7 +55,10 @@ func (d *defaultDriver) reconcileNodeSpecs(
 		return results.WithError(err)
 	}
 
-	expectedResources, err := nodespec.BuildExpectedResources(d.ES, keystoreResources, actualStatefulSets)
+	// Adjust the number of nodes of NodeSets with a storage autoscaling policy.
+	autoscaledES := applyStorageAutoscaling(ctx, d.ES, esClient, esReachable, actualStatefulSets, reconcileState)
+
+	expectedResources, err := nodespec.BuildExpectedResources(autoscaledES, keystoreResources, actualStatefulSets)
 	if err != nil {
 		return results.WithError(err)
 	}
@@ -63,6 +68,27 @@ func (d *defaultDriver) reconcileNodeSpecs(
 	}
 
 	esState := NewMemoizingESState(ctx, esClient)
+	// Nodes are only restarted or removed during the maintenance windows.
+	maintenanceWindow := maintenance.Current(d.ES.Spec.UpdateStrategy.MaintenanceWindows, time.Now())
+
+	// In dry-run mode, the changes to the nodes are only written to the plan ConfigMap.
+	if IsDryRun(d.ES) {
+		log.Info("Dry-run mode enabled, writing the plan of the changes to the nodes instead of applying them",
+			"namespace", d.ES.Namespace, "es_name", d.ES.Name)
+		if err := d.reconcilePlan(actualStatefulSets, expectedResources.StatefulSets(), maintenanceWindow); err != nil {
+			return results.WithError(err)
+		}
+		if Reconciled(expectedResources.StatefulSets(), actualStatefulSets, d.Client) {
+			reconcileState.UpdateElasticsearchReady(resourcesState, observedState)
+		} else if reconcileState.IsElasticsearchReady(observedState) {
+			reconcileState.UpdateElasticsearchApplyingChanges(resourcesState.CurrentPods)
+		}
+		return results
+	}
+	// remove the plan written in dry-run mode, if any
+	if err := deletePlanConfigMap(d.Client, d.ES); err != nil {
+		return results.WithError(err)
+	}
 
 	// Phase 1: apply expected StatefulSets resources and scale up.
 	upscaleCtx := upscaleCtx{
@@ -72,12 +98,39 @@ func (d *defaultDriver) reconcileNodeSpecs(
 		observedState: observedState,
 		esState:       esState,
 		expectations:  d.Expectations,
+
+		validateStorageClass: d.OperatorParameters.ValidateStorageClass,
 	}
-	actualStatefulSets, err = HandleUpscaleAndSpecChanges(upscaleCtx, actualStatefulSets, expectedResources)
+	upscaleResults, err := HandleUpscaleAndSpecChanges(upscaleCtx, actualStatefulSets, expectedResources)
 	if err != nil {
 		reconcileState.AddEvent(corev1.EventTypeWarning, events.EventReconciliationError, fmt.Sprintf("Failed to apply spec change: %v", err))
 		return results.WithError(err)
 	}
+	// the Elasticsearch resource may have been annotated for StatefulSets to be recreated, keep its metadata up-to-date
+	// for the next updates and the status update not to conflict
+	d.ES.ObjectMeta = upscaleResults.ES.ObjectMeta
+	reconcileState.UpdateElasticsearchMetadata(upscaleResults.ES.ObjectMeta)
+	reportVolumeExpansionIgnored(reconcileState, upscaleResults.VolumeExpansionWarnings)
+	actualStatefulSets = upscaleResults.ActualStatefulSets
+	reconcileState.UpdateUpscaleOperations(upscaleResults.PendingUpscales)
+	reconcileState.UpdateStorageMigrationOperations(
+		storageMigrationOperations(d.ES, actualStatefulSets, expectedResources.StatefulSets()),
+	)
+
+	// Report the progress of any volume expansion.
+	expanding, err := reportVolumeExpansionStatus(d.K8sClient(), d.ES, actualStatefulSets, reconcileState)
+	if err != nil {
+		return results.WithError(err)
+	}
+	if expanding {
+		// PVCs are not watched, requeue to keep the status up-to-date
+		results.WithResult(defaultRequeue)
+	}
+	if upscaleResults.Requeue {
+		// some StatefulSets are being recreated to account for volume expansion, wait for them to be recreated
+		reconcileState.UpdateElasticsearchApplyingChanges(resourcesState.CurrentPods)
+		return results.WithResult(defaultRequeue)
+	}
 
 	// Update PDB to account for new replicas.
 	if err := pdb.Reconcile(d.Client, d.ES, actualStatefulSets); err != nil {
@@ -98,6 +151,7 @@ func (d *defaultDriver) reconcileNodeSpecs(
 	if !esReachable {
 		log.Info("ES cannot be reached yet, re-queuing", "namespace", d.ES.Namespace, "es_name", d.ES.Name)
 		reconcileState.UpdateElasticsearchApplyingChanges(resourcesState.CurrentPods)
+		reportFullClusterRestart(d.ES, reconcileState)
 		return results.WithResult(defaultRequeue)
 	}
 
@@ -138,6 +192,7 @@ func (d *defaultDriver) reconcileNodeSpecs(
 		reconcileState,
 		d.Expectations,
 		d.ES,
+		maintenanceWindow,
 	)
 	downscaleRes := HandleDownscale(downscaleCtx, expectedResources.StatefulSets(), actualStatefulSets)
 	results.WithResults(downscaleRes)
@@ -146,7 +201,7 @@ func (d *defaultDriver) reconcileNodeSpecs(
 	}
 
 	// Phase 3: handle rolling upgrades.
-	rollingUpgradesRes := d.handleRollingUpgrades(ctx, esClient, esState, expectedResources.MasterNodesNames())
+	rollingUpgradesRes := d.handleRollingUpgrades(ctx, esClient, esState, expectedResources.MasterNodesNames(), maintenanceWindow)
 	results.WithResults(rollingUpgradesRes)
 	if rollingUpgradesRes.HasError() {
 		return results
@@ -160,9 +215,6 @@ func (d *defaultDriver) reconcileNodeSpecs(
 		reconcileState.UpdateElasticsearchApplyingChanges(resourcesState.CurrentPods)
 	}
 
-	// TODO:
-	//  - change budget
-	//  - grow and shrink
 	return results
 }
 
@@ -0,0 +1,256 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"encoding/json"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/configmap"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/maintenance"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// DryRunAnnotation suspends the changes to the nodes of an Elasticsearch cluster when set to "true": StatefulSets
+	// are not updated, and nodes are neither restarted nor removed. These changes are written to the plan ConfigMap.
+	DryRunAnnotation = "elasticsearch.k8s.elastic.co/dry-run"
+	// PlanConfigKey is the key of the reconciliation plan in the plan ConfigMap.
+	PlanConfigKey = "plan.json"
+)
+
+// StatefulSetOperation is the operation applied to a StatefulSet.
+type StatefulSetOperation string
+
+const (
+	CreateStatefulSet StatefulSetOperation = "Create"
+	UpdateStatefulSet StatefulSetOperation = "Update"
+	DeleteStatefulSet StatefulSetOperation = "Delete"
+)
+
+// Plan describes the changes the operator would apply to the nodes of an Elasticsearch cluster.
+type Plan struct {
+	// ObservedGeneration is the generation of the Elasticsearch resource the plan was computed for.
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// StatefulSets lists the StatefulSets to create, update or delete.
+	StatefulSets []StatefulSetPlan `json:"statefulSets,omitempty"`
+	// Restarts lists the nodes to restart to apply a specification change, in the order they are restarted.
+	Restarts []string `json:"restarts,omitempty"`
+	// FullClusterRestart is true if the nodes to restart are restarted all together.
+	FullClusterRestart bool `json:"fullClusterRestart,omitempty"`
+	// Downscales lists the nodes removed next from the cluster, within the limits of the change budget.
+	Downscales []DownscalePlan `json:"downscales,omitempty"`
+	// MaintenanceWindow is the state of the maintenance windows: restarts and removals of nodes are postponed while
+	// no window is open.
+	MaintenanceWindow MaintenanceWindowPlan `json:"maintenanceWindow"`
+}
+
+// MaintenanceWindowPlan describes the state of the maintenance windows.
+type MaintenanceWindowPlan struct {
+	Open bool `json:"open"`
+	// Next is the start time of the next maintenance window, if none is open.
+	Next *metav1.Time `json:"next,omitempty"`
+}
+
+// StatefulSetPlan describes the change of a StatefulSet.
+type StatefulSetPlan struct {
+	Name             string               `json:"name"`
+	Operation        StatefulSetOperation `json:"operation"`
+	CurrentReplicas  int32                `json:"currentReplicas"`
+	ExpectedReplicas int32                `json:"expectedReplicas"`
+	// RestartsPods is true if the existing Pods of the StatefulSet are restarted to apply the change.
+	RestartsPods bool `json:"restartsPods,omitempty"`
+}
+
+// DownscalePlan describes the removal of nodes from a StatefulSet.
+type DownscalePlan struct {
+	StatefulSet string `json:"statefulSet"`
+	// Nodes lists the nodes to remove, in the order they are removed.
+	Nodes []string `json:"nodes"`
+	// MigrateData is true if the shards of the nodes are migrated away before the nodes are removed.
+	MigrateData bool `json:"migrateData"`
+}
+
+// IsDryRun returns true if the changes to the nodes of the given Elasticsearch cluster are replaced by the computation
+// of their plan.
+func IsDryRun(es esv1.Elasticsearch) bool {
+	return es.Annotations[DryRunAnnotation] == "true"
+}
+
+// reconcilePlan writes to the plan ConfigMap the changes the operator would apply to the nodes of the cluster to reach
+// the expected StatefulSets.
+func (d *defaultDriver) reconcilePlan(
+	actualStatefulSets sset.StatefulSetList,
+	expectedStatefulSets sset.StatefulSetList,
+	maintenanceWindow maintenance.Window,
+) error {
+	plan, err := buildPlan(d.Client, d.ES, actualStatefulSets, expectedStatefulSets, maintenanceWindow)
+	if err != nil {
+		return err
+	}
+	serialized, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	planConfigMap := configmap.NewConfigMapWithData(
+		types.NamespacedName{Namespace: d.ES.Namespace, Name: esv1.PlanConfigMap(d.ES.Name)},
+		map[string]string{PlanConfigKey: string(serialized)},
+	)
+	return configmap.ReconcileConfigMap(d.Client, d.ES, planConfigMap)
+}
+
+// deletePlanConfigMap deletes the plan ConfigMap once the dry-run mode is disabled.
+func deletePlanConfigMap(c k8s.Client, es esv1.Elasticsearch) error {
+	var planConfigMap corev1.ConfigMap
+	err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: esv1.PlanConfigMap(es.Name)}, &planConfigMap)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = c.Delete(&planConfigMap)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// buildPlan compares the expected StatefulSets to the actual ones. The nodes to remove are computed as the downscale
+// phase does, with the same invariants.
+func buildPlan(
+	c k8s.Client,
+	es esv1.Elasticsearch,
+	actualStatefulSets sset.StatefulSetList,
+	expectedStatefulSets sset.StatefulSetList,
+	maintenanceWindow maintenance.Window,
+) (Plan, error) {
+	plan := Plan{
+		ObservedGeneration: es.Generation,
+		FullClusterRestart: es.Spec.UpdateStrategy.IsFullClusterRestart(),
+		MaintenanceWindow:  MaintenanceWindowPlan{Open: maintenanceWindow.IsOpen()},
+	}
+	if !maintenanceWindow.IsOpen() {
+		plan.MaintenanceWindow.Next = &metav1.Time{Time: maintenanceWindow.Next}
+	}
+
+	// nodes to remove
+	downscaleState, err := newDownscaleState(c, es, expectedStatefulSets.ExpectedNodeCount())
+	if err != nil {
+		return Plan{}, err
+	}
+	downscales, _ := calculateDownscales(*downscaleState, expectedStatefulSets, actualStatefulSets)
+	leaving := map[string]struct{}{}
+	for _, downscale := range downscales {
+		nodes := downscale.leavingNodeNames()
+		for _, node := range nodes {
+			leaving[node] = struct{}{}
+		}
+		plan.Downscales = append(plan.Downscales, DownscalePlan{
+			StatefulSet: downscale.statefulSet.Name,
+			Nodes:       nodes,
+			MigrateData: label.IsDataNodeSet(downscale.statefulSet),
+		})
+	}
+
+	// StatefulSets to update, and their Pods to restart
+	var restarts []corev1.Pod
+	for _, expectedSset := range expectedStatefulSets {
+		actualSset, exists := actualStatefulSets.GetByName(expectedSset.Name)
+		if !exists {
+			plan.StatefulSets = append(plan.StatefulSets, StatefulSetPlan{
+				Name:             expectedSset.Name,
+				Operation:        CreateStatefulSet,
+				ExpectedReplicas: sset.GetReplicas(expectedSset),
+			})
+			continue
+		}
+		restart := restartsPods(expectedSset, actualSset)
+		if !restart && sset.GetReplicas(expectedSset) == sset.GetReplicas(actualSset) {
+			continue
+		}
+		plan.StatefulSets = append(plan.StatefulSets, StatefulSetPlan{
+			Name:             expectedSset.Name,
+			Operation:        UpdateStatefulSet,
+			CurrentReplicas:  sset.GetReplicas(actualSset),
+			ExpectedReplicas: sset.GetReplicas(expectedSset),
+			RestartsPods:     restart,
+		})
+		if !restart {
+			continue
+		}
+		pods, err := sset.GetActualPodsForStatefulSet(c, k8s.ExtractNamespacedName(&actualSset))
+		if err != nil {
+			return Plan{}, err
+		}
+		restarts = append(restarts, pods...)
+	}
+	for _, actualSset := range actualStatefulSets {
+		if _, shouldExist := expectedStatefulSets.GetByName(actualSset.Name); !shouldExist {
+			plan.StatefulSets = append(plan.StatefulSets, StatefulSetPlan{
+				Name:            actualSset.Name,
+				Operation:       DeleteStatefulSet,
+				CurrentReplicas: sset.GetReplicas(actualSset),
+			})
+		}
+	}
+
+	// Pods already scheduled for a restart by a previous specification change
+	pending, err := podsToUpgrade(c, actualStatefulSets)
+	if err != nil {
+		return Plan{}, err
+	}
+	restarts = append(restarts, pending...)
+
+	plan.Restarts = restartOrder(restarts, leaving, plan.FullClusterRestart)
+	return plan, nil
+}
+
+// restartsPods returns true if the change of the given StatefulSet restarts its Pods. Changes of the number of
+// replicas and of the storage size of the volume claim templates do not restart the Pods.
+func restartsPods(expected, actual appsv1.StatefulSet) bool {
+	candidate := *expected.DeepCopy()
+	candidate.Spec.Replicas = actual.Spec.Replicas
+	for i, claim := range candidate.Spec.VolumeClaimTemplates {
+		for _, actualClaim := range actual.Spec.VolumeClaimTemplates {
+			if actualClaim.Name == claim.Name {
+				candidate.Spec.VolumeClaimTemplates[i].Spec.Resources.Requests = actualClaim.Spec.Resources.Requests
+			}
+		}
+	}
+	return hash.HashObject(candidate.Spec) != hash.GetTemplateHashLabel(actual.Labels)
+}
+
+// restartOrder returns the names of the given Pods in the order they are restarted, excluding the nodes to remove.
+func restartOrder(pods []corev1.Pod, leaving map[string]struct{}, fullClusterRestart bool) []string {
+	unique := make(map[string]corev1.Pod, len(pods))
+	for _, pod := range pods {
+		if _, removed := leaving[pod.Name]; !removed {
+			unique[pod.Name] = pod
+		}
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	candidates := make([]corev1.Pod, 0, len(unique))
+	for _, pod := range unique {
+		candidates = append(candidates, pod)
+	}
+	if fullClusterRestart {
+		// all the nodes are restarted together
+		return sortedPodNames(candidates)
+	}
+	sortCandidates(candidates)
+	return k8s.PodNames(candidates)
+}
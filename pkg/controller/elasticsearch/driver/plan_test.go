@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/maintenance"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func Test_buildPlan(t *testing.T) {
+	masters := sset.TestSset{Namespace: "ns", Name: "masters", ClusterName: "es", Version: "7.10.0", Replicas: 3, Master: true}
+	data := sset.TestSset{Namespace: "ns", Name: "data", ClusterName: "es", Version: "7.10.0", Replicas: 3, Data: true}
+	old := sset.TestSset{Namespace: "ns", Name: "old", ClusterName: "es", Version: "7.10.0", Replicas: 2, Data: true}
+	pending := sset.TestSset{
+		Namespace: "ns", Name: "pending", ClusterName: "es", Version: "7.10.0", Replicas: 1,
+		Status: appsv1.StatefulSetStatus{CurrentRevision: "a", UpdateRevision: "b"},
+	}
+	actual := sset.StatefulSetList{masters.Build(), data.Build(), old.Build(), pending.Build()}
+	readyPods := func(s sset.TestSset) []runtime.Object {
+		var pods []runtime.Object
+		for _, name := range sset.PodNames(s.Build()) {
+			pods = append(pods, sset.TestPod{
+				Namespace: s.Namespace, Name: name, ClusterName: s.ClusterName, StatefulSetName: s.Name, Version: s.Version,
+				Master: s.Master, Data: s.Data, Ready: true,
+			}.BuildPtr())
+		}
+		return pods
+	}
+	var objs []runtime.Object
+	objs = append(objs, readyPods(masters)...)
+	objs = append(objs, readyPods(data)...)
+	objs = append(objs, readyPods(old)...)
+	objs = append(objs, sset.TestPod{
+		Namespace: "ns", Name: "pending-0", ClusterName: "es", StatefulSetName: "pending", Version: "7.10.0", Revision: "a",
+	}.BuildPtr())
+
+	// upgrade the masters and the data nodes, remove a data node, replace the old NodeSet by a new one
+	upgradedMasters := masters
+	upgradedMasters.Version = "7.11.0"
+	upgradedData := data
+	upgradedData.Version = "7.11.0"
+	upgradedData.Replicas = 2
+	expected := sset.StatefulSetList{
+		upgradedMasters.Build(),
+		upgradedData.Build(),
+		sset.TestSset{Namespace: "ns", Name: "new", ClusterName: "es", Version: "7.10.0", Replicas: 2, Data: true}.Build(),
+		pending.Build(),
+	}
+
+	wantStatefulSets := []StatefulSetPlan{
+		{Name: "masters", Operation: UpdateStatefulSet, CurrentReplicas: 3, ExpectedReplicas: 3, RestartsPods: true},
+		{Name: "data", Operation: UpdateStatefulSet, CurrentReplicas: 3, ExpectedReplicas: 2, RestartsPods: true},
+		{Name: "new", Operation: CreateStatefulSet, ExpectedReplicas: 2},
+		{Name: "old", Operation: DeleteStatefulSet, CurrentReplicas: 2},
+	}
+	// the change budget allows a single node to be unavailable: the old nodes are removed after the data node
+	wantDownscales := []DownscalePlan{
+		{StatefulSet: "data", Nodes: []string{"data-2"}, MigrateData: true},
+	}
+
+	tests := []struct {
+		name         string
+		strategy     esv1.UpdateStrategyType
+		wantRestarts []string
+	}{
+		{
+			name:     "rolling upgrade: data nodes first, then masters",
+			strategy: esv1.RollingUpgradeStrategyType,
+			wantRestarts: []string{
+				"data-1", "data-0", "pending-0", "masters-2", "masters-1", "masters-0",
+			},
+		},
+		{
+			name:     "full cluster restart: all nodes together",
+			strategy: esv1.FullClusterRestartStrategyType,
+			wantRestarts: []string{
+				"data-0", "data-1", "masters-0", "masters-1", "masters-2", "pending-0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := esv1.Elasticsearch{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es", Generation: 4},
+				Spec:       esv1.ElasticsearchSpec{UpdateStrategy: esv1.UpdateStrategy{Type: tt.strategy}},
+			}
+			plan, err := buildPlan(k8s.WrappedFakeClient(objs...), es, actual, expected, maintenance.Always)
+			require.NoError(t, err)
+			require.Equal(t, Plan{
+				ObservedGeneration: 4,
+				StatefulSets:       wantStatefulSets,
+				Restarts:           tt.wantRestarts,
+				FullClusterRestart: tt.strategy == esv1.FullClusterRestartStrategyType,
+				Downscales:         wantDownscales,
+				MaintenanceWindow:  MaintenanceWindowPlan{Open: true},
+			}, plan)
+		})
+	}
+}
+
+func Test_buildPlan_noChange(t *testing.T) {
+	data := sset.TestSset{Namespace: "ns", Name: "data", ClusterName: "es", Version: "7.10.0", Replicas: 3, Data: true}
+	actual := sset.StatefulSetList{data.Build()}
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es", Generation: 2}}
+	plan, err := buildPlan(k8s.WrappedFakeClient(data.Pods()...), es, actual, actual.DeepCopy(), maintenance.Always)
+	require.NoError(t, err)
+	require.Equal(t, Plan{ObservedGeneration: 2, MaintenanceWindow: MaintenanceWindowPlan{Open: true}}, plan)
+}
+
+func Test_buildPlan_maintenanceWindowClosed(t *testing.T) {
+	data := sset.TestSset{Namespace: "ns", Name: "data", ClusterName: "es", Version: "7.10.0", Replicas: 3, Data: true}
+	actual := sset.StatefulSetList{data.Build()}
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es", Generation: 2}}
+	next := time.Date(2021, 1, 2, 3, 0, 0, 0, time.UTC)
+	plan, err := buildPlan(k8s.WrappedFakeClient(data.Pods()...), es, actual, actual.DeepCopy(), maintenance.Window{Next: next})
+	require.NoError(t, err)
+	require.Equal(t, MaintenanceWindowPlan{Next: &metav1.Time{Time: next}}, plan.MaintenanceWindow)
+}
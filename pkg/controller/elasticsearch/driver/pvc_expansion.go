@@ -0,0 +1,384 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/pointer"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+const (
+	// defaultStorageClassAnnotation is set on the storage class used by claims that do not specify one.
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+	// betaDefaultStorageClassAnnotation is the deprecated version of defaultStorageClassAnnotation.
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+
+	// RecreateStatefulSetAnnotationPrefix is the prefix of the annotation set on the Elasticsearch resource for each
+	// StatefulSet deleted to account for resized PVCs. The annotation value holds the number of replicas of the
+	// StatefulSet before its deletion and the claims it is recreated with.
+	RecreateStatefulSetAnnotationPrefix = "elasticsearch.k8s.elastic.co/recreate-"
+)
+
+// statefulSetRecreation is stored in the recreation annotation of a StatefulSet deleted to account for resized PVCs.
+type statefulSetRecreation struct {
+	// Replicas is the number of replicas of the StatefulSet before its deletion.
+	Replicas int32 `json:"replicas"`
+	// VolumeClaimTemplates are the claims of the expected StatefulSet, without the storage increases ignored because
+	// the storage class does not allow them.
+	VolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"volumeClaimTemplates"`
+}
+
+// handleVolumeExpansion increases the storage request of the existing PVCs of the actual StatefulSet, if the
+// expected StatefulSet claims request more storage. Since the volumeClaimTemplates section of a StatefulSet is
+// immutable, the actual StatefulSet is then deleted with an orphan propagation policy: its Pods and PVCs are
+// left untouched, and the StatefulSet is recreated with the expected claims in a subsequent reconciliation.
+// Its replicas and the expected claims are stored beforehand in an annotation of the Elasticsearch resource, for the
+// StatefulSet to be recreated with the same replicas.
+// If validateStorageClass is true, the storage increase of claims whose storage class does not allow volume expansion
+// is ignored: the actual claims are kept in the expected StatefulSet, for it to be updated with any other change, and
+// a warning message is returned for each of them.
+// It returns true if the StatefulSet is being recreated, in which case it should not be updated.
+func handleVolumeExpansion(
+	k8sClient k8s.Client,
+	es *esv1.Elasticsearch,
+	expected *appsv1.StatefulSet,
+	actual appsv1.StatefulSet,
+	validateStorageClass bool,
+) (bool, []string, error) {
+	resized := claimsWithIncreasedStorage(actual.Spec.VolumeClaimTemplates, expected.Spec.VolumeClaimTemplates)
+	if len(resized) == 0 {
+		// nothing to expand, any other change is left to the StatefulSet update
+		return false, nil, nil
+	}
+
+	var warnings []string
+	recreate := false
+	for _, claim := range resized {
+		if validateStorageClass {
+			sc, err := getStorageClass(k8sClient, claim)
+			if err != nil {
+				return false, nil, err
+			}
+			if !allowsVolumeExpansion(sc) {
+				keepActualClaim(expected, actual, claim.Name)
+				warnings = append(warnings, fmt.Sprintf(
+					"Storage class %s of claim %s does not allow volume expansion, ignoring its storage increase in StatefulSet %s",
+					sc.Name, claim.Name, actual.Name,
+				))
+				continue
+			}
+		}
+		if err := resizePVCs(k8sClient, *es, actual, claim); err != nil {
+			return false, nil, err
+		}
+		recreate = true
+	}
+	if !recreate {
+		return false, warnings, nil
+	}
+
+	if err := annotateForRecreation(k8sClient, es, *expected, actual); err != nil {
+		return false, nil, err
+	}
+	log.Info("Deleting StatefulSet to account for resized PVCs, it will be recreated automatically",
+		"namespace", actual.Namespace, "es_name", es.Name, "statefulset_name", actual.Name)
+	return true, warnings, deleteStatefulSetOrphanDependents(k8sClient, actual)
+}
+
+// keepActualClaim replaces the claim with the given name in the expected StatefulSet by the actual one.
+func keepActualClaim(expected *appsv1.StatefulSet, actual appsv1.StatefulSet, claimName string) {
+	actualClaim := getClaimMatchingName(actual.Spec.VolumeClaimTemplates, claimName)
+	for i := range expected.Spec.VolumeClaimTemplates {
+		if expected.Spec.VolumeClaimTemplates[i].Name == claimName {
+			expected.Spec.VolumeClaimTemplates[i] = *actualClaim.DeepCopy()
+		}
+	}
+}
+
+// annotateForRecreation stores the replicas of the given actual StatefulSet and the claims of the expected one in an
+// annotation of the Elasticsearch resource, before the StatefulSet is deleted to be recreated.
+func annotateForRecreation(k8sClient k8s.Client, es *esv1.Elasticsearch, expected appsv1.StatefulSet, actual appsv1.StatefulSet) error {
+	annotation := RecreateStatefulSetAnnotationPrefix + actual.Name
+	value, err := json.Marshal(statefulSetRecreation{
+		Replicas:             sset.GetReplicas(actual),
+		VolumeClaimTemplates: expected.Spec.VolumeClaimTemplates,
+	})
+	if err != nil {
+		return err
+	}
+	if es.Annotations[annotation] == string(value) {
+		return nil
+	}
+	if es.Annotations == nil {
+		es.Annotations = make(map[string]string)
+	}
+	es.Annotations[annotation] = string(value)
+	return k8sClient.Update(es)
+}
+
+// removeRecreationAnnotation removes the annotation storing the replicas of the given StatefulSet, once recreated.
+func removeRecreationAnnotation(k8sClient k8s.Client, es *esv1.Elasticsearch, statefulSetName string) error {
+	annotation := RecreateStatefulSetAnnotationPrefix + statefulSetName
+	if _, exists := es.Annotations[annotation]; !exists {
+		return nil
+	}
+	delete(es.Annotations, annotation)
+	return k8sClient.Update(es)
+}
+
+// getRecreation returns the recreation stored in the annotation of the given StatefulSet, if any.
+func getRecreation(es esv1.Elasticsearch, statefulSetName string) (statefulSetRecreation, bool, error) {
+	annotation := RecreateStatefulSetAnnotationPrefix + statefulSetName
+	value, exists := es.Annotations[annotation]
+	if !exists {
+		return statefulSetRecreation{}, false, nil
+	}
+	var recreation statefulSetRecreation
+	if err := json.Unmarshal([]byte(value), &recreation); err != nil {
+		return statefulSetRecreation{}, false, fmt.Errorf("invalid annotation %s: %w", annotation, err)
+	}
+	return recreation, true, nil
+}
+
+// withStatefulSetsToRecreate returns the actual StatefulSets, along with the expected StatefulSets deleted to account
+// for resized PVCs and not recreated yet, with the replicas they had before their deletion. Their Pods still exist,
+// and they must not be considered as new StatefulSets whose nodes have to be created.
+func withStatefulSetsToRecreate(
+	es esv1.Elasticsearch,
+	actualStatefulSets sset.StatefulSetList,
+	expectedStatefulSets sset.StatefulSetList,
+) (sset.StatefulSetList, error) {
+	result := append(sset.StatefulSetList{}, actualStatefulSets...)
+	for _, expected := range expectedStatefulSets {
+		if _, alreadyRecreated := actualStatefulSets.GetByName(expected.Name); alreadyRecreated {
+			continue
+		}
+		recreation, exists, err := getRecreation(es, expected.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		toRecreate := expected.DeepCopy()
+		toRecreate.Spec.VolumeClaimTemplates = recreation.VolumeClaimTemplates
+		nodespec.UpdateReplicas(toRecreate, pointer.Int32(recreation.Replicas))
+		result = result.WithStatefulSet(*toRecreate)
+	}
+	return result, nil
+}
+
+// claimsWithIncreasedStorage returns the expected claims whose storage request is higher than the actual one.
+func claimsWithIncreasedStorage(actualClaims, expectedClaims []corev1.PersistentVolumeClaim) []corev1.PersistentVolumeClaim {
+	var resized []corev1.PersistentVolumeClaim
+	for _, expectedClaim := range expectedClaims {
+		actualClaim := getClaimMatchingName(actualClaims, expectedClaim.Name)
+		if actualClaim == nil {
+			continue
+		}
+		actualStorage, actualExists := actualClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+		expectedStorage, expectedExists := expectedClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if actualExists && expectedExists && expectedStorage.Cmp(actualStorage) > 0 {
+			resized = append(resized, expectedClaim)
+		}
+	}
+	return resized
+}
+
+// getClaimMatchingName returns a claim matching the given name.
+func getClaimMatchingName(claims []corev1.PersistentVolumeClaim, name string) *corev1.PersistentVolumeClaim {
+	for i, claim := range claims {
+		if claim.Name == name {
+			return &claims[i]
+		}
+	}
+	return nil
+}
+
+// resizePVCs updates the storage request of the existing PVCs matching the given claim template.
+func resizePVCs(k8sClient k8s.Client, es esv1.Elasticsearch, statefulSet appsv1.StatefulSet, claim corev1.PersistentVolumeClaim) error {
+	expectedStorage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	for _, podName := range sset.PodNames(statefulSet) {
+		var pvc corev1.PersistentVolumeClaim
+		pvcName := fmt.Sprintf("%s-%s", claim.Name, podName)
+		if err := k8sClient.Get(types.NamespacedName{Namespace: statefulSet.Namespace, Name: pvcName}, &pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				// the PVC will be created from the expected claim template
+				continue
+			}
+			return err
+		}
+		storage := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if storage.Cmp(expectedStorage) >= 0 {
+			continue
+		}
+		log.Info("Resizing PVC storage requests",
+			"namespace", pvc.Namespace, "es_name", es.Name, "pvc_name", pvc.Name,
+			"old_value", storage.String(), "new_value", expectedStorage.String())
+		if pvc.Spec.Resources.Requests == nil {
+			pvc.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = expectedStorage
+		if err := k8sClient.Update(&pvc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allowsVolumeExpansion returns true if the given storage class allows volume expansion.
+func allowsVolumeExpansion(sc storagev1.StorageClass) bool {
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion
+}
+
+// getStorageClass returns the storage class specified by the given claim,
+// or the default storage class if the claim does not specify any.
+// Storage classes are listed rather than retrieved by name, since getting a cluster-scoped resource
+// is not supported by the cache when the operator manages multiple namespaces.
+func getStorageClass(k8sClient k8s.Client, claim corev1.PersistentVolumeClaim) (storagev1.StorageClass, error) {
+	var storageClasses storagev1.StorageClassList
+	if err := k8sClient.List(&storageClasses); err != nil {
+		return storagev1.StorageClass{}, err
+	}
+	for _, sc := range storageClasses.Items {
+		if claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName == "" {
+			if isDefaultStorageClass(sc) {
+				return sc, nil
+			}
+			continue
+		}
+		if sc.Name == *claim.Spec.StorageClassName {
+			return sc, nil
+		}
+	}
+	if claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName == "" {
+		return storagev1.StorageClass{}, fmt.Errorf("no default storage class found for claim %s", claim.Name)
+	}
+	return storagev1.StorageClass{}, fmt.Errorf("storage class %s not found", *claim.Spec.StorageClassName)
+}
+
+// isDefaultStorageClass returns true if the given storage class is annotated as the default one.
+func isDefaultStorageClass(sc storagev1.StorageClass) bool {
+	return sc.Annotations[defaultStorageClassAnnotation] == "true" || sc.Annotations[betaDefaultStorageClassAnnotation] == "true"
+}
+
+// deleteStatefulSetOrphanDependents deletes the given StatefulSet but leaves its Pods and PVCs untouched.
+// A precondition on the UID ensures we don't delete a StatefulSet that has already been recreated.
+func deleteStatefulSetOrphanDependents(k8sClient k8s.Client, statefulSet appsv1.StatefulSet) error {
+	err := k8sClient.Delete(&statefulSet,
+		client.PropagationPolicy(metav1.DeletePropagationOrphan),
+		client.Preconditions{UID: &statefulSet.UID},
+	)
+	if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+		// already deleted, or already recreated
+		return nil
+	}
+	return err
+}
+
+// reportVolumeExpansionStatus inspects the PVCs of the given StatefulSets to report the volume expansion
+// progress in the Elasticsearch status conditions. It returns true if a volume expansion is still in progress.
+func reportVolumeExpansionStatus(
+	k8sClient k8s.Client,
+	es esv1.Elasticsearch,
+	statefulSets sset.StatefulSetList,
+	reconcileState *reconcile.State,
+) (bool, error) {
+	var pvcs corev1.PersistentVolumeClaimList
+	if err := k8sClient.List(&pvcs, client.InNamespace(es.Namespace), label.NewLabelSelectorForElasticsearch(es)); err != nil {
+		return false, err
+	}
+	expectedPVCs := stringsutil.SliceToMap(statefulSets.PVCNames())
+
+	var resizing, fsResizePending []string
+	for _, pvc := range pvcs.Items {
+		if _, exists := expectedPVCs[pvc.Name]; !exists {
+			continue
+		}
+		if isFileSystemResizePending(pvc) {
+			fsResizePending = append(fsResizePending, pvc.Name)
+			continue
+		}
+		if isVolumeResizing(pvc) {
+			resizing = append(resizing, pvc.Name)
+		}
+	}
+
+	reportPVCsCondition(reconcileState, esv1.VolumeExpansionInProgress, resizing, "Resizing volumes of PVCs: ")
+	reportPVCsCondition(reconcileState, esv1.FileSystemResizePending, fsResizePending, "Waiting for the file system of PVCs to be resized: ")
+	return len(resizing)+len(fsResizePending) > 0, nil
+}
+
+// reportPVCsCondition sets the given condition to true if pvcNames is not empty.
+// The condition is only set to false if it was previously reported, to avoid polluting the status.
+func reportPVCsCondition(reconcileState *reconcile.State, conditionType commonv1.ConditionType, pvcNames []string, messagePrefix string) {
+	if len(pvcNames) == 0 {
+		if reconcileState.HasCondition(conditionType) {
+			reconcileState.ReportCondition(conditionType, corev1.ConditionFalse, "")
+		}
+		return
+	}
+	sort.Strings(pvcNames)
+	reconcileState.ReportCondition(conditionType, corev1.ConditionTrue, messagePrefix+strings.Join(pvcNames, ", "))
+}
+
+// reportVolumeExpansionIgnored reports the storage increases ignored because the storage class does not allow them in
+// the Elasticsearch status conditions, rather than with an event repeated at each reconciliation.
+func reportVolumeExpansionIgnored(reconcileState *reconcile.State, warnings []string) {
+	if len(warnings) == 0 {
+		if reconcileState.HasCondition(esv1.VolumeExpansionIgnored) {
+			reconcileState.ReportCondition(esv1.VolumeExpansionIgnored, corev1.ConditionFalse, "")
+		}
+		return
+	}
+	sort.Strings(warnings)
+	reconcileState.ReportCondition(esv1.VolumeExpansionIgnored, corev1.ConditionTrue, strings.Join(warnings, ". "))
+}
+
+// isVolumeResizing returns true if the storage request of the given PVC is higher than its actual capacity.
+func isVolumeResizing(pvc corev1.PersistentVolumeClaim) bool {
+	requested, requestExists := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity, capacityExists := pvc.Status.Capacity[corev1.ResourceStorage]
+	if !requestExists || !capacityExists {
+		// not bound yet
+		return false
+	}
+	return requested.Cmp(capacity) > 0 || hasPVCCondition(pvc, corev1.PersistentVolumeClaimResizing)
+}
+
+// isFileSystemResizePending returns true if the volume of the given PVC has been resized,
+// but its file system still needs to be resized on the node.
+func isFileSystemResizePending(pvc corev1.PersistentVolumeClaim) bool {
+	return hasPVCCondition(pvc, corev1.PersistentVolumeClaimFileSystemResizePending)
+}
+
+func hasPVCCondition(pvc corev1.PersistentVolumeClaim, conditionType corev1.PersistentVolumeClaimConditionType) bool {
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == conditionType && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
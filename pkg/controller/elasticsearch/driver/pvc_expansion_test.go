@@ -0,0 +1,495 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/bootstrap"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var (
+	sampleES = esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+
+	sampleStorageClass = storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: "resizable"},
+		AllowVolumeExpansion: boolPtr(true),
+	}
+	defaultStorageClass = storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: "default", Annotations: map[string]string{defaultStorageClassAnnotation: "true"}},
+		AllowVolumeExpansion: boolPtr(true),
+	}
+	fixedStorageClass = storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fixed"}}
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func withStorageReq(claim corev1.PersistentVolumeClaim, size string) corev1.PersistentVolumeClaim {
+	c := claim.DeepCopy()
+	c.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)}
+	return *c
+}
+
+func withClaims(statefulSet appsv1.StatefulSet, claims ...corev1.PersistentVolumeClaim) appsv1.StatefulSet {
+	s := statefulSet.DeepCopy()
+	s.Spec.VolumeClaimTemplates = claims
+	return *s
+}
+
+func sampleClaim(storageClassName string) corev1.PersistentVolumeClaim {
+	claim := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "elasticsearch-data"}}
+	if storageClassName != "" {
+		claim.Spec.StorageClassName = &storageClassName
+	}
+	return claim
+}
+
+func samplePVC(name string, request string, capacity string, conditions ...corev1.PersistentVolumeClaimConditionType) corev1.PersistentVolumeClaim {
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+			Labels:    map[string]string{label.ClusterNameLabelName: "es"},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(request)},
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)},
+		},
+	}
+	for _, c := range conditions {
+		pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{Type: c, Status: corev1.ConditionTrue})
+	}
+	return pvc
+}
+
+func Test_claimsWithIncreasedStorage(t *testing.T) {
+	claim := sampleClaim("resizable")
+	otherClaim := claim.DeepCopy()
+	otherClaim.Name = "other"
+	tests := []struct {
+		name     string
+		actual   []corev1.PersistentVolumeClaim
+		expected []corev1.PersistentVolumeClaim
+		want     []corev1.PersistentVolumeClaim
+	}{
+		{
+			name:     "no claims",
+			actual:   nil,
+			expected: nil,
+			want:     nil,
+		},
+		{
+			name:     "same storage",
+			actual:   []corev1.PersistentVolumeClaim{withStorageReq(claim, "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{withStorageReq(claim, "1Gi")},
+			want:     nil,
+		},
+		{
+			name:     "storage decrease",
+			actual:   []corev1.PersistentVolumeClaim{withStorageReq(claim, "2Gi")},
+			expected: []corev1.PersistentVolumeClaim{withStorageReq(claim, "1Gi")},
+			want:     nil,
+		},
+		{
+			name:     "new claim",
+			actual:   []corev1.PersistentVolumeClaim{withStorageReq(claim, "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{withStorageReq(claim, "1Gi"), withStorageReq(*otherClaim, "2Gi")},
+			want:     nil,
+		},
+		{
+			name:     "storage increase",
+			actual:   []corev1.PersistentVolumeClaim{withStorageReq(claim, "1Gi"), withStorageReq(*otherClaim, "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{withStorageReq(claim, "1Gi"), withStorageReq(*otherClaim, "2Gi")},
+			want:     []corev1.PersistentVolumeClaim{withStorageReq(*otherClaim, "2Gi")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, claimsWithIncreasedStorage(tt.actual, tt.expected))
+		})
+	}
+}
+
+func Test_getStorageClass(t *testing.T) {
+	tests := []struct {
+		name    string
+		objs    []runtime.Object
+		claim   corev1.PersistentVolumeClaim
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "storage class specified in the claim",
+			objs:  []runtime.Object{&sampleStorageClass, &defaultStorageClass},
+			claim: sampleClaim("resizable"),
+			want:  "resizable",
+		},
+		{
+			name:    "storage class not found",
+			objs:    []runtime.Object{&defaultStorageClass},
+			claim:   sampleClaim("resizable"),
+			wantErr: true,
+		},
+		{
+			name:  "default storage class",
+			objs:  []runtime.Object{&sampleStorageClass, &defaultStorageClass},
+			claim: sampleClaim(""),
+			want:  "default",
+		},
+		{
+			name:    "no default storage class",
+			objs:    []runtime.Object{&sampleStorageClass},
+			claim:   sampleClaim(""),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := getStorageClass(k8s.WrappedFakeClient(tt.objs...), tt.claim)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, sc.Name)
+		})
+	}
+}
+
+func Test_handleVolumeExpansion(t *testing.T) {
+	actual := withClaims(sset.TestSset{Namespace: "ns", Name: "sset", Replicas: 2}.Build(), withStorageReq(sampleClaim("resizable"), "1Gi"))
+	actual.UID = "uid"
+	resized := withClaims(actual, withStorageReq(sampleClaim("resizable"), "3Gi"))
+	nonResizable := withClaims(actual, withStorageReq(sampleClaim("fixed"), "3Gi"))
+	pvcs := []corev1.PersistentVolumeClaim{
+		samplePVC("elasticsearch-data-sset-0", "1Gi", "1Gi"),
+		samplePVC("elasticsearch-data-sset-1", "1Gi", "1Gi"),
+	}
+
+	tests := []struct {
+		name                 string
+		expected             appsv1.StatefulSet
+		validateStorageClass bool
+		wantRecreate         bool
+		wantStorage          string
+		wantWarnings         int
+	}{
+		{
+			name:         "no storage change",
+			expected:     actual,
+			wantRecreate: false,
+			wantStorage:  "1Gi",
+		},
+		{
+			name:                 "storage increase",
+			expected:             resized,
+			validateStorageClass: true,
+			wantRecreate:         true,
+			wantStorage:          "3Gi",
+		},
+		{
+			name:                 "storage class does not allow volume expansion",
+			expected:             nonResizable,
+			validateStorageClass: true,
+			wantRecreate:         false,
+			wantStorage:          "1Gi",
+			wantWarnings:         1,
+		},
+		{
+			name:                 "storage class validation disabled",
+			expected:             nonResizable,
+			validateStorageClass: false,
+			wantRecreate:         true,
+			wantStorage:          "3Gi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := sampleES.DeepCopy()
+			k8sClient := k8s.WrappedFakeClient(
+				es.DeepCopy(), &sampleStorageClass, &fixedStorageClass, actual.DeepCopy(), pvcs[0].DeepCopy(), pvcs[1].DeepCopy(),
+			)
+			expected := tt.expected.DeepCopy()
+			recreate, warnings, err := handleVolumeExpansion(k8sClient, es, expected, actual, tt.validateStorageClass)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRecreate, recreate)
+			require.Len(t, warnings, tt.wantWarnings)
+
+			// the storage increase should be ignored in the expected StatefulSet if not supported
+			expectedStorage := expected.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[corev1.ResourceStorage]
+			if tt.wantWarnings > 0 {
+				require.Equal(t, "1Gi", expectedStorage.String())
+			} else {
+				require.Equal(t, tt.expected.Spec.VolumeClaimTemplates, expected.Spec.VolumeClaimTemplates)
+			}
+
+			// PVCs should have been resized accordingly
+			for _, pvc := range pvcs {
+				var retrieved corev1.PersistentVolumeClaim
+				require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&pvc), &retrieved))
+				storage := retrieved.Spec.Resources.Requests[corev1.ResourceStorage]
+				require.Equal(t, tt.wantStorage, storage.String())
+			}
+
+			// the StatefulSet should have been deleted if recreated
+			var retrieved appsv1.StatefulSet
+			err = k8sClient.Get(types.NamespacedName{Namespace: actual.Namespace, Name: actual.Name}, &retrieved)
+			if tt.wantRecreate {
+				require.True(t, apierrors.IsNotFound(err))
+			} else {
+				require.NoError(t, err)
+			}
+
+			// the replicas of the StatefulSet should have been stored for it to be recreated
+			var retrievedES esv1.Elasticsearch
+			require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&sampleES), &retrievedES))
+			recreation, annotated, err := getRecreation(retrievedES, actual.Name)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRecreate, annotated)
+			if tt.wantRecreate {
+				require.Equal(t, int32(2), recreation.Replicas)
+				require.Equal(t, expected.Spec.VolumeClaimTemplates, recreation.VolumeClaimTemplates)
+			}
+		})
+	}
+}
+
+func TestHandleUpscaleAndSpecChanges_VolumeExpansion(t *testing.T) {
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        "es",
+			Annotations: map[string]string{bootstrap.ClusterUUIDAnnotationName: "uuid"},
+		},
+		Spec: esv1.ElasticsearchSpec{Version: "7.5.0"},
+	}
+	masters := withClaims(
+		sset.TestSset{Namespace: "ns", Name: "masters", ClusterName: "es", Version: "7.5.0", Replicas: 3, Master: true}.Build(),
+		withStorageReq(sampleClaim("resizable"), "1Gi"),
+	)
+	masters.UID = "uid"
+	k8sClient := k8s.WrappedFakeClient(&es, &sampleStorageClass, masters.DeepCopy())
+	ctx := upscaleCtx{
+		k8sClient:            k8sClient,
+		es:                   es,
+		esState:              &fakeESState{},
+		expectations:         expectations.NewExpectations(k8sClient),
+		parentCtx:            context.Background(),
+		validateStorageClass: true,
+	}
+	expectedResources := nodespec.ResourcesList{{
+		StatefulSet:     withClaims(masters, withStorageReq(sampleClaim("resizable"), "3Gi")),
+		HeadlessService: corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "masters"}},
+		Config:          settings.CanonicalConfig{},
+	}}
+
+	// the StatefulSet should be deleted to account for the storage increase
+	res, err := HandleUpscaleAndSpecChanges(ctx, sset.StatefulSetList{masters}, expectedResources)
+	require.NoError(t, err)
+	require.True(t, res.Requeue)
+	var retrieved appsv1.StatefulSet
+	err = k8sClient.Get(k8s.ExtractNamespacedName(&masters), &retrieved)
+	require.True(t, apierrors.IsNotFound(err))
+
+	// it should be recreated with its 3 replicas, even though master nodes are created one at a time
+	ctx.es = res.ES
+	res, err = HandleUpscaleAndSpecChanges(ctx, sset.StatefulSetList{}, expectedResources)
+	require.NoError(t, err)
+	require.False(t, res.Requeue)
+	require.Empty(t, res.PendingUpscales)
+	require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&masters), &retrieved))
+	require.Equal(t, int32(3), sset.GetReplicas(retrieved))
+	storage := retrieved.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[corev1.ResourceStorage]
+	require.Equal(t, "3Gi", storage.String())
+
+	// the annotation should have been removed
+	var retrievedES esv1.Elasticsearch
+	require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&es), &retrievedES))
+	require.NotContains(t, retrievedES.Annotations, RecreateStatefulSetAnnotationPrefix+masters.Name)
+	// the returned resource is up-to-date
+	require.Equal(t, retrievedES.ObjectMeta, res.ES.ObjectMeta)
+}
+
+func TestHandleUpscaleAndSpecChanges_VolumeExpansionIgnored(t *testing.T) {
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        "es",
+			Annotations: map[string]string{bootstrap.ClusterUUIDAnnotationName: "uuid"},
+		},
+		Spec: esv1.ElasticsearchSpec{Version: "7.5.0"},
+	}
+	fixedClaim := withStorageReq(sampleClaim("fixed"), "1Gi")
+	fixedClaim.Name = "fixed"
+	data := withClaims(
+		sset.TestSset{Namespace: "ns", Name: "data", ClusterName: "es", Version: "7.5.0", Replicas: 2}.Build(),
+		withStorageReq(sampleClaim("resizable"), "1Gi"), fixedClaim,
+	)
+	data.UID = "uid"
+	k8sClient := k8s.WrappedFakeClient(&es, &sampleStorageClass, &fixedStorageClass, data.DeepCopy())
+	ctx := upscaleCtx{
+		k8sClient:            k8sClient,
+		es:                   es,
+		esState:              &fakeESState{},
+		expectations:         expectations.NewExpectations(k8sClient),
+		parentCtx:            context.Background(),
+		validateStorageClass: true,
+	}
+	// both claims request more storage, only one of them can be expanded
+	expectedResources := nodespec.ResourcesList{{
+		StatefulSet:     withClaims(data, withStorageReq(sampleClaim("resizable"), "3Gi"), withStorageReq(fixedClaim, "3Gi")),
+		HeadlessService: corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "data"}},
+		Config:          settings.CanonicalConfig{},
+	}}
+
+	res, err := HandleUpscaleAndSpecChanges(ctx, sset.StatefulSetList{data}, expectedResources)
+	require.NoError(t, err)
+	require.True(t, res.Requeue)
+	require.Len(t, res.VolumeExpansionWarnings, 1)
+
+	// the StatefulSet is recreated without the ignored storage increase
+	ctx.es = res.ES
+	res, err = HandleUpscaleAndSpecChanges(ctx, sset.StatefulSetList{}, expectedResources)
+	require.NoError(t, err)
+	require.False(t, res.Requeue)
+	var retrieved appsv1.StatefulSet
+	require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&data), &retrieved))
+	resizable := retrieved.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[corev1.ResourceStorage]
+	require.Equal(t, "3Gi", resizable.String())
+	fixed := retrieved.Spec.VolumeClaimTemplates[1].Spec.Resources.Requests[corev1.ResourceStorage]
+	require.Equal(t, "1Gi", fixed.String())
+}
+
+func Test_reportVolumeExpansionStatus(t *testing.T) {
+	statefulSets := sset.StatefulSetList{
+		withClaims(sset.TestSset{Namespace: "ns", Name: "sset", Replicas: 3}.Build(), sampleClaim("resizable")),
+	}
+	tests := []struct {
+		name           string
+		pvcs           []corev1.PersistentVolumeClaim
+		initialStatus  esv1.ElasticsearchStatus
+		wantExpanding  bool
+		wantConditions map[string]corev1.ConditionStatus
+	}{
+		{
+			name: "no expansion in progress",
+			pvcs: []corev1.PersistentVolumeClaim{
+				samplePVC("elasticsearch-data-sset-0", "1Gi", "1Gi"),
+			},
+			wantExpanding:  false,
+			wantConditions: map[string]corev1.ConditionStatus{},
+		},
+		{
+			name: "volume expansion and file system resize in progress",
+			pvcs: []corev1.PersistentVolumeClaim{
+				samplePVC("elasticsearch-data-sset-0", "2Gi", "1Gi"),
+				samplePVC("elasticsearch-data-sset-1", "2Gi", "2Gi", corev1.PersistentVolumeClaimFileSystemResizePending),
+				samplePVC("elasticsearch-data-sset-2", "2Gi", "2Gi"),
+				// does not belong to the expected StatefulSets
+				samplePVC("elasticsearch-data-sset-3", "2Gi", "1Gi"),
+			},
+			wantExpanding: true,
+			wantConditions: map[string]corev1.ConditionStatus{
+				string(esv1.VolumeExpansionInProgress): corev1.ConditionTrue,
+				string(esv1.FileSystemResizePending):   corev1.ConditionTrue,
+			},
+		},
+		{
+			name: "expansion over",
+			pvcs: []corev1.PersistentVolumeClaim{
+				samplePVC("elasticsearch-data-sset-0", "2Gi", "2Gi"),
+			},
+			initialStatus: esv1.ElasticsearchStatus{
+				ReconcilerStatus: commonv1.ReconcilerStatus{
+					Conditions: commonv1.Conditions{{Type: esv1.VolumeExpansionInProgress, Status: corev1.ConditionTrue}},
+				},
+			},
+			wantExpanding: false,
+			wantConditions: map[string]corev1.ConditionStatus{
+				string(esv1.VolumeExpansionInProgress): corev1.ConditionFalse,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := make([]runtime.Object, 0, len(tt.pvcs))
+			for i := range tt.pvcs {
+				objs = append(objs, &tt.pvcs[i])
+			}
+			es := sampleES.DeepCopy()
+			es.Status = tt.initialStatus
+			state := reconcile.NewState(*es)
+
+			expanding, err := reportVolumeExpansionStatus(k8s.WrappedFakeClient(objs...), *es, statefulSets, state)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantExpanding, expanding)
+
+			_, updated := state.Apply()
+			conditions := map[string]corev1.ConditionStatus{}
+			if updated != nil {
+				for _, c := range updated.Status.Conditions {
+					conditions[string(c.Type)] = c.Status
+				}
+			}
+			require.Equal(t, tt.wantConditions, conditions)
+		})
+	}
+}
+
+func Test_reportVolumeExpansionIgnored(t *testing.T) {
+	conditionStatus := func(s *reconcile.State) corev1.ConditionStatus {
+		_, es := s.Apply()
+		if es == nil {
+			return ""
+		}
+		i := es.Status.Conditions.Index(esv1.VolumeExpansionIgnored)
+		if i < 0 {
+			return ""
+		}
+		return es.Status.Conditions[i].Status
+	}
+
+	// nothing reported if no storage increase was ignored
+	state := reconcile.NewState(sampleES)
+	reportVolumeExpansionIgnored(state, nil)
+	require.Equal(t, corev1.ConditionStatus(""), conditionStatus(state))
+
+	state = reconcile.NewState(sampleES)
+	reportVolumeExpansionIgnored(state, []string{"ignored"})
+	require.Equal(t, corev1.ConditionTrue, conditionStatus(state))
+
+	// reset once previously reported
+	reported := *sampleES.DeepCopy()
+	reported.Status.Conditions = commonv1.Conditions{{Type: esv1.VolumeExpansionIgnored, Status: corev1.ConditionTrue}}
+	state = reconcile.NewState(reported)
+	reportVolumeExpansionIgnored(state, nil)
+	require.Equal(t, corev1.ConditionFalse, conditionStatus(state))
+}
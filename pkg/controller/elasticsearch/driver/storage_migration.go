@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+)
+
+// storageMigrationOperations returns the NodeSets whose nodes are being replaced by the nodes of a new StatefulSet,
+// to apply a change of their volume claim templates. The nodes of the replaced StatefulSets are removed through
+// the regular downscale process, once their data has been migrated.
+func storageMigrationOperations(
+	es esv1.Elasticsearch,
+	actualStatefulSets sset.StatefulSetList,
+	expectedStatefulSets sset.StatefulSetList,
+) []esv1.StorageMigrationOperation {
+	var operations []esv1.StorageMigrationOperation
+	for _, nodeSet := range es.Spec.NodeSets {
+		expected := nodespec.NodeSetStatefulSets(es, nodeSet.Name, expectedStatefulSets)
+		if len(expected) != 1 {
+			continue
+		}
+		operation := esv1.StorageMigrationOperation{NodeSet: nodeSet.Name, StatefulSet: expected[0].Name}
+		for _, actual := range nodespec.NodeSetStatefulSets(es, nodeSet.Name, actualStatefulSets) {
+			if actual.Name == operation.StatefulSet {
+				continue
+			}
+			operation.ReplacedStatefulSets = append(operation.ReplacedStatefulSets, actual.Name)
+			operation.RemainingNodes += sset.GetReplicas(actual)
+		}
+		if len(operation.ReplacedStatefulSets) > 0 {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+)
+
+func Test_storageMigrationOperations(t *testing.T) {
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec: esv1.ElasticsearchSpec{NodeSets: []esv1.NodeSet{
+			{Name: "masters"},
+			{Name: "data", VolumeClaimTemplatesUpdateStrategy: esv1.VolumeClaimTemplatesMigrate},
+		}},
+	}
+	migrated := sset.TestSset{Namespace: "ns", Name: "es-es-data-12345", ClusterName: "es", Replicas: 3}.Build()
+	migrated.Labels[label.NodeSetNameLabelName] = "data"
+	masters := sset.TestSset{Namespace: "ns", Name: "es-es-masters", ClusterName: "es", Replicas: 3}.Build()
+	data := sset.TestSset{Namespace: "ns", Name: "es-es-data", ClusterName: "es", Replicas: 2}.Build()
+
+	tests := []struct {
+		name     string
+		actual   sset.StatefulSetList
+		expected sset.StatefulSetList
+		want     []esv1.StorageMigrationOperation
+	}{
+		{
+			name:     "no migration",
+			actual:   sset.StatefulSetList{masters, data},
+			expected: sset.StatefulSetList{masters, data},
+			want:     nil,
+		},
+		{
+			name:     "migration in progress",
+			actual:   sset.StatefulSetList{masters, data, migrated},
+			expected: sset.StatefulSetList{masters, migrated},
+			want: []esv1.StorageMigrationOperation{
+				{NodeSet: "data", StatefulSet: "es-es-data-12345", ReplacedStatefulSets: []string{"es-es-data"}, RemainingNodes: 2},
+			},
+		},
+		{
+			name:     "new StatefulSet not created yet",
+			actual:   sset.StatefulSetList{masters, data},
+			expected: sset.StatefulSetList{masters, migrated},
+			want: []esv1.StorageMigrationOperation{
+				{NodeSet: "data", StatefulSet: "es-es-data-12345", ReplacedStatefulSets: []string{"es-es-data"}, RemainingNodes: 2},
+			},
+		},
+		{
+			name:     "migration over",
+			actual:   sset.StatefulSetList{masters, migrated},
+			expected: sset.StatefulSetList{masters, migrated},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, storageMigrationOperations(es, tt.actual, tt.expected))
+		})
+	}
+}
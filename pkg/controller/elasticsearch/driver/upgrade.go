@@ -6,12 +6,16 @@ package driver
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/maintenance"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/shutdown"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	corev1 "k8s.io/api/core/v1"
@@ -24,6 +28,7 @@ func (d *defaultDriver) handleRollingUpgrades(
 	esClient esclient.Client,
 	esState ESState,
 	expectedMaster []string,
+	maintenanceWindow maintenance.Window,
 ) *reconciler.Results {
 	results := &reconciler.Results{}
 
@@ -58,8 +63,27 @@ func (d *defaultDriver) handleRollingUpgrades(
 		return results.WithError(err)
 	}
 
+	// Nodes are restarted once the next maintenance window opens.
+	if !maintenanceWindow.IsOpen() && len(podsToUpgrade) > 0 {
+		log.Info("Postponing the restart of nodes until the next maintenance window",
+			"namespace", d.ES.Namespace, "es_name", d.ES.Name,
+			"pod_count", len(podsToUpgrade), "next_window", maintenanceWindow.Next)
+		d.ReconcileState.UpdatePostponedRestarts(sortedPodNames(podsToUpgrade), maintenanceWindow.Next)
+		results.WithResult(maintenanceRequeue(maintenanceWindow))
+		podsToUpgrade = nil
+	} else {
+		d.ReconcileState.UpdatePostponedRestarts(nil, time.Time{})
+	}
+
+	if d.ES.Spec.UpdateStrategy.IsFullClusterRestart() {
+		return results.WithResults(d.handleFullClusterRestart(
+			ctx, esClient, esState, statefulSets, expectedMaster, actualMasters, podsToUpgrade, healthyPods,
+		))
+	}
+	d.ReconcileState.UpdateFullClusterRestartOperation(nil)
+
 	// Maybe upgrade some of the nodes.
-	deletedPods, err := newRollingUpgrade(
+	rollingUpgrade := newRollingUpgrade(
 		ctx,
 		d,
 		statefulSets,
@@ -69,10 +93,19 @@ func (d *defaultDriver) handleRollingUpgrades(
 		actualMasters,
 		podsToUpgrade,
 		healthyPods,
-	).run()
+	)
+	deletedPods, failed, err := rollingUpgrade.run()
 	if err != nil {
 		return results.WithError(err)
 	}
+	d.ReconcileState.UpdateUpgradeOperations(upgradeOperations(podsToUpgrade, deletedPods, failed))
+
+	// Maybe clean up the shutdown records of the nodes that are back into the cluster after their restart.
+	if rollingUpgrade.nodeShutdown != nil {
+		if err := rollingUpgrade.nodeShutdown.Delete(ctx, restartedNodes(podsToUpgrade, healthyPods)); err != nil {
+			return results.WithError(err)
+		}
+	}
 	if len(deletedPods) > 0 {
 		// Some Pods have just been deleted, we don't need to try to enable shards allocation.
 		return results.WithResult(defaultRequeue)
@@ -89,6 +122,9 @@ func (d *defaultDriver) handleRollingUpgrades(
 	return results
 }
 
+// restartShutdownReason is the reason of the shutdown records registered for the nodes to restart.
+const restartShutdownReason = "ECK rolling upgrade"
+
 type rollingUpgradeCtx struct {
 	parentCtx       context.Context
 	client          k8s.Client
@@ -103,6 +139,8 @@ type rollingUpgradeCtx struct {
 	actualMasters   []corev1.Pod
 	podsToUpgrade   []corev1.Pod
 	healthyPods     map[string]corev1.Pod
+	// nodeShutdown prepares the restart of the nodes, nil if the node shutdown API is not supported
+	nodeShutdown *shutdown.NodeShutdown
 }
 
 func newRollingUpgrade(
@@ -116,6 +154,10 @@ func newRollingUpgrade(
 	podsToUpgrade []corev1.Pod,
 	healthyPods map[string]corev1.Pod,
 ) rollingUpgradeCtx {
+	var nodeShutdown *shutdown.NodeShutdown
+	if shutdown.IsSupported(esClient.Version()) {
+		nodeShutdown = shutdown.NewNodeShutdown(esClient, k8s.ExtractNamespacedName(&d.ES), esclient.Restart, restartShutdownReason)
+	}
 	return rollingUpgradeCtx{
 		parentCtx:       ctx,
 		client:          d.Client,
@@ -130,21 +172,63 @@ func newRollingUpgrade(
 		actualMasters:   actualMasters,
 		podsToUpgrade:   podsToUpgrade,
 		healthyPods:     healthyPods,
+		nodeShutdown:    nodeShutdown,
 	}
 }
 
-func (ctx rollingUpgradeCtx) run() ([]corev1.Pod, error) {
-	deletedPods, err := ctx.Delete()
+func (ctx rollingUpgradeCtx) run() ([]corev1.Pod, failedPredicates, error) {
+	deletedPods, failed, err := ctx.Delete()
 	if errors.IsConflict(err) || errors.IsNotFound(err) {
 		// Cache is not up to date or Pod has been deleted by someone else
 		// (could be the statefulset controller)
 		// TODO: should we at least log this one in debug mode ?
-		return deletedPods, nil
+		return deletedPods, failed, nil
 	}
 	if err != nil {
-		return deletedPods, err
+		return deletedPods, failed, err
+	}
+	return deletedPods, failed, nil
+}
+
+// upgradeOperations returns the status of the Pods to upgrade: Pods just deleted are restarting, Pods for which a
+// predicate failed are blocked by that predicate, other Pods are pending.
+func upgradeOperations(podsToUpgrade, deletedPods []corev1.Pod, failed failedPredicates) []esv1.UpgradeOperation {
+	if len(podsToUpgrade) == 0 {
+		return nil
+	}
+	deleted := k8s.PodsByName(deletedPods)
+	blockedBy := make(map[string]string, len(failed))
+	for _, f := range failed {
+		blockedBy[f.pod] = f.predicate
+	}
+	operations := make([]esv1.UpgradeOperation, 0, len(podsToUpgrade))
+	for _, pod := range podsToUpgrade {
+		operation := esv1.UpgradeOperation{Node: pod.Name, Status: esv1.UpgradePending}
+		if _, isDeleted := deleted[pod.Name]; isDeleted {
+			operation.Status = esv1.UpgradeRestarting
+		} else if predicate, isBlocked := blockedBy[pod.Name]; isBlocked {
+			operation.Status = esv1.UpgradeBlocked
+			operation.Predicate = predicate
+		}
+		operations = append(operations, operation)
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].Node < operations[j].Node
+	})
+	return operations
+}
+
+// restartedNodes returns the names of the healthy nodes that do not need to be upgraded anymore.
+func restartedNodes(podsToUpgrade []corev1.Pod, healthyPods map[string]corev1.Pod) []string {
+	toUpgrade := k8s.PodsByName(podsToUpgrade)
+	var restarted []string
+	for name := range healthyPods {
+		if _, exists := toUpgrade[name]; !exists {
+			restarted = append(restarted, name)
+		}
 	}
-	return deletedPods, nil
+	sort.Strings(restarted)
+	return restarted
 }
 
 func healthyPods(
@@ -0,0 +1,217 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+// fullClusterRestartAnnotation holds the names of the nodes restarted by the full cluster restart in progress. It is
+// set before the Pods are deleted and removed once shards allocation is enabled again: the transient setting disabling
+// shards allocation does not survive the restart of all the master nodes, and cannot tell whether a restart is ongoing.
+const fullClusterRestartAnnotation = "elasticsearch.k8s.elastic.co/full-cluster-restart"
+
+// handleFullClusterRestart restarts together all the nodes whose specification changed. Shards allocation is disabled
+// and indices are flushed before the Pods are deleted. Shards allocation is enabled again once all the nodes are back
+// in the cluster and the cluster health is at least yellow, so that primary shards are allocated to the nodes holding
+// their data. The change budget does not apply.
+func (d *defaultDriver) handleFullClusterRestart(
+	ctx context.Context,
+	esClient esclient.Client,
+	esState ESState,
+	statefulSets sset.StatefulSetList,
+	expectedMasters []string,
+	actualMasters []corev1.Pod,
+	podsToUpgrade []corev1.Pod,
+	healthyPods map[string]corev1.Pod,
+) *reconciler.Results {
+	results := &reconciler.Results{}
+
+	if len(podsToUpgrade) > 0 {
+		// record the restart before deleting any Pod, to track it until shards allocation is enabled again
+		if err := d.annotateFullClusterRestart(podsToUpgrade); err != nil {
+			return results.WithError(err)
+		}
+		// the node shutdown API is not used: all the nodes are restarted together
+		restart := rollingUpgradeCtx{
+			parentCtx:       ctx,
+			client:          d.Client,
+			ES:              d.ES,
+			statefulSets:    statefulSets,
+			esClient:        esClient,
+			shardLister:     esClient,
+			esState:         esState,
+			expectations:    d.Expectations,
+			reconcileState:  d.ReconcileState,
+			expectedMasters: expectedMasters,
+			actualMasters:   actualMasters,
+			podsToUpgrade:   podsToUpgrade,
+			healthyPods:     healthyPods,
+		}
+		if err := restart.restartAll(); err != nil {
+			d.ReconcileState.UpdateUpgradeOperations(upgradeOperations(podsToUpgrade, nil, nil))
+			return results.WithError(err)
+		}
+		d.ReconcileState.UpdateUpgradeOperations(upgradeOperations(podsToUpgrade, podsToUpgrade, nil))
+		d.ReconcileState.UpdateFullClusterRestartOperation(&esv1.FullClusterRestartOperation{
+			Phase: esv1.FullClusterRestartRestarting,
+			Nodes: sortedPodNames(podsToUpgrade),
+		})
+		return results.WithResult(defaultRequeue)
+	}
+
+	if len(fullClusterRestartNodes(d.ES)) == 0 {
+		// no full cluster restart in progress
+		d.ReconcileState.UpdateFullClusterRestartOperation(nil)
+		return results
+	}
+
+	var restarting []string
+	for _, name := range statefulSets.PodNames() {
+		if _, healthy := healthyPods[name]; !healthy {
+			restarting = append(restarting, name)
+		}
+	}
+	if len(restarting) > 0 {
+		sort.Strings(restarting)
+		d.ReconcileState.UpdateUpgradeOperations(restartingOperations(restarting))
+		d.ReconcileState.UpdateFullClusterRestartOperation(&esv1.FullClusterRestartOperation{
+			Phase: esv1.FullClusterRestartRestarting,
+			Nodes: restarting,
+		})
+		return results.WithResult(defaultRequeue)
+	}
+
+	health, err := esState.Health()
+	if err != nil {
+		return results.WithError(err)
+	}
+	if health != esv1.ElasticsearchGreenHealth && health != esv1.ElasticsearchYellowHealth {
+		log.V(1).Info("Waiting for primary shards to be allocated before enabling shards allocation",
+			"namespace", d.ES.Namespace, "es_name", d.ES.Name, "health", health)
+		d.ReconcileState.UpdateFullClusterRestartOperation(&esv1.FullClusterRestartOperation{
+			Phase: esv1.FullClusterRestartRecovering,
+		})
+		return results.WithResult(defaultRequeue)
+	}
+
+	res := d.MaybeEnableShardsAllocation(ctx, esClient, esState)
+	results.WithResults(res)
+	if result, err := res.Aggregate(); err != nil || result.Requeue || result.RequeueAfter > 0 {
+		return results
+	}
+	if err := d.removeFullClusterRestartAnnotation(); err != nil {
+		return results.WithError(err)
+	}
+	d.ReconcileState.UpdateFullClusterRestartOperation(nil)
+	return results
+}
+
+// reportFullClusterRestart reports the nodes of the full cluster restart in progress, if any, as restarting. It is used
+// while Elasticsearch cannot be reached, which is expected during a full cluster restart.
+func reportFullClusterRestart(es esv1.Elasticsearch, reconcileState *reconcile.State) {
+	nodes := fullClusterRestartNodes(es)
+	if len(nodes) == 0 {
+		return
+	}
+	reconcileState.UpdateUpgradeOperations(restartingOperations(nodes))
+	reconcileState.UpdateFullClusterRestartOperation(&esv1.FullClusterRestartOperation{
+		Phase: esv1.FullClusterRestartRestarting,
+		Nodes: nodes,
+	})
+}
+
+// fullClusterRestartNodes returns the names of the nodes restarted by the full cluster restart in progress, if any.
+func fullClusterRestartNodes(es esv1.Elasticsearch) []string {
+	value := es.Annotations[fullClusterRestartAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// annotateFullClusterRestart adds the given Pods to the nodes of the full cluster restart in progress.
+func (d *defaultDriver) annotateFullClusterRestart(pods []corev1.Pod) error {
+	nodes := fullClusterRestartNodes(d.ES)
+	for _, name := range k8s.PodNames(pods) {
+		if !stringsutil.StringInSlice(name, nodes) {
+			nodes = append(nodes, name)
+		}
+	}
+	sort.Strings(nodes)
+	value := strings.Join(nodes, ",")
+	if d.ES.Annotations[fullClusterRestartAnnotation] == value {
+		return nil
+	}
+	if d.ES.Annotations == nil {
+		d.ES.Annotations = map[string]string{}
+	}
+	d.ES.Annotations[fullClusterRestartAnnotation] = value
+	return d.updateElasticsearchMetadata()
+}
+
+// removeFullClusterRestartAnnotation marks the full cluster restart as complete.
+func (d *defaultDriver) removeFullClusterRestartAnnotation() error {
+	if _, exists := d.ES.Annotations[fullClusterRestartAnnotation]; !exists {
+		return nil
+	}
+	delete(d.ES.Annotations, fullClusterRestartAnnotation)
+	return d.updateElasticsearchMetadata()
+}
+
+// updateElasticsearchMetadata updates the Elasticsearch resource, and keeps its metadata up-to-date in the reconcile
+// state for the status update not to conflict.
+func (d *defaultDriver) updateElasticsearchMetadata() error {
+	if err := d.Client.Update(&d.ES); err != nil {
+		return err
+	}
+	d.ReconcileState.UpdateElasticsearchMetadata(d.ES.ObjectMeta)
+	return nil
+}
+
+// restartAll prepares the cluster for a full restart, then deletes all the Pods to upgrade.
+func (ctx *rollingUpgradeCtx) restartAll() error {
+	if err := ctx.prepareClusterForNodeRestart(ctx.esClient, ctx.esState); err != nil {
+		return err
+	}
+	log.Info("Performing a full cluster restart",
+		"namespace", ctx.ES.Namespace, "es_name", ctx.ES.Name, "pod_count", len(ctx.podsToUpgrade))
+	for _, pod := range ctx.podsToUpgrade {
+		if err := ctx.handleMasterScaleChange(pod); err != nil {
+			return err
+		}
+		if err := deletePod(ctx.client, ctx.ES, pod, ctx.expectations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restartingOperations returns the upgrade operations of the given nodes, restarted but not back in the cluster yet.
+func restartingOperations(nodes []string) []esv1.UpgradeOperation {
+	operations := make([]esv1.UpgradeOperation, 0, len(nodes))
+	for _, node := range nodes {
+		operations = append(operations, esv1.UpgradeOperation{Node: node, Status: esv1.UpgradeRestarting})
+	}
+	return operations
+}
+
+func sortedPodNames(pods []corev1.Pod) []string {
+	names := k8s.PodNames(pods)
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,212 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/expectations"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func Test_defaultDriver_handleFullClusterRestart(t *testing.T) {
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec: esv1.ElasticsearchSpec{
+			UpdateStrategy: esv1.UpdateStrategy{Type: esv1.FullClusterRestartStrategyType},
+		},
+	}
+	statefulSet := sset.TestSset{Namespace: "ns", Name: "es-es-data", ClusterName: "es", Replicas: 3}.Build()
+	pods := []corev1.Pod{
+		sset.TestPod{Namespace: "ns", Name: "es-es-data-0", ClusterName: "es", StatefulSetName: "es-es-data", Ready: true}.Build(),
+		sset.TestPod{Namespace: "ns", Name: "es-es-data-1", ClusterName: "es", StatefulSetName: "es-es-data", Ready: true}.Build(),
+		sset.TestPod{Namespace: "ns", Name: "es-es-data-2", ClusterName: "es", StatefulSetName: "es-es-data", Ready: true}.Build(),
+	}
+	allHealthy := k8s.PodsByName(pods)
+	allocationDisabled := esclient.ClusterRoutingAllocation{}
+	allocationDisabled.Transient.Cluster.Routing.Allocation.Enable = "primaries"
+	allNodes := esclient.Nodes{Nodes: map[string]esclient.Node{
+		"a": {Name: "es-es-data-0"}, "b": {Name: "es-es-data-1"}, "c": {Name: "es-es-data-2"},
+	}}
+
+	tests := []struct {
+		name                  string
+		esClient              *fakeESClient
+		podsToUpgrade         []corev1.Pod
+		healthyPods           map[string]corev1.Pod
+		restartInProgress     bool
+		wantRemainingPods     int
+		wantStatus            *esv1.FullClusterRestartOperation
+		wantUpgrade           []esv1.UpgradeOperation
+		wantAllocationEnabled bool
+		wantRestartInProgress bool
+		wantRequeue           bool
+	}{
+		{
+			name:              "restart all the nodes to upgrade together",
+			esClient:          &fakeESClient{},
+			podsToUpgrade:     pods[1:],
+			healthyPods:       allHealthy,
+			wantRemainingPods: 1,
+			wantStatus: &esv1.FullClusterRestartOperation{
+				Phase: esv1.FullClusterRestartRestarting,
+				Nodes: []string{"es-es-data-1", "es-es-data-2"},
+			},
+			wantUpgrade: []esv1.UpgradeOperation{
+				{Node: "es-es-data-1", Status: esv1.UpgradeRestarting},
+				{Node: "es-es-data-2", Status: esv1.UpgradeRestarting},
+			},
+			wantRestartInProgress: true,
+			wantRequeue:           true,
+		},
+		{
+			name:              "wait for the nodes to be back in the cluster",
+			esClient:          &fakeESClient{clusterRoutingAllocation: allocationDisabled},
+			healthyPods:       map[string]corev1.Pod{"es-es-data-0": pods[0]},
+			restartInProgress: true,
+			wantRemainingPods: 3,
+			wantStatus: &esv1.FullClusterRestartOperation{
+				Phase: esv1.FullClusterRestartRestarting,
+				Nodes: []string{"es-es-data-1", "es-es-data-2"},
+			},
+			wantUpgrade: []esv1.UpgradeOperation{
+				{Node: "es-es-data-1", Status: esv1.UpgradeRestarting},
+				{Node: "es-es-data-2", Status: esv1.UpgradeRestarting},
+			},
+			wantRestartInProgress: true,
+			wantRequeue:           true,
+		},
+		{
+			// the transient setting disabling shards allocation is lost once all the master nodes are restarted
+			name:              "wait for the nodes to be back in the cluster after the restart of the master nodes",
+			esClient:          &fakeESClient{},
+			healthyPods:       map[string]corev1.Pod{"es-es-data-0": pods[0]},
+			restartInProgress: true,
+			wantRemainingPods: 3,
+			wantStatus: &esv1.FullClusterRestartOperation{
+				Phase: esv1.FullClusterRestartRestarting,
+				Nodes: []string{"es-es-data-1", "es-es-data-2"},
+			},
+			wantUpgrade: []esv1.UpgradeOperation{
+				{Node: "es-es-data-1", Status: esv1.UpgradeRestarting},
+				{Node: "es-es-data-2", Status: esv1.UpgradeRestarting},
+			},
+			wantRestartInProgress: true,
+			wantRequeue:           true,
+		},
+		{
+			name: "wait for the cluster health to be yellow",
+			esClient: &fakeESClient{
+				clusterRoutingAllocation: allocationDisabled,
+				health:                   esclient.Health{Status: esv1.ElasticsearchRedHealth},
+			},
+			healthyPods:           allHealthy,
+			restartInProgress:     true,
+			wantRemainingPods:     3,
+			wantStatus:            &esv1.FullClusterRestartOperation{Phase: esv1.FullClusterRestartRecovering},
+			wantRestartInProgress: true,
+			wantRequeue:           true,
+		},
+		{
+			name: "enable shards allocation once the cluster health is yellow",
+			esClient: &fakeESClient{
+				clusterRoutingAllocation: allocationDisabled,
+				health:                   esclient.Health{Status: esv1.ElasticsearchYellowHealth},
+				nodes:                    allNodes,
+			},
+			healthyPods:           allHealthy,
+			restartInProgress:     true,
+			wantRemainingPods:     3,
+			wantAllocationEnabled: true,
+		},
+		{
+			name:              "no full cluster restart in progress",
+			esClient:          &fakeESClient{clusterRoutingAllocation: allocationDisabled},
+			healthyPods:       allHealthy,
+			wantRemainingPods: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// start from a status with a full cluster restart in progress, to check it is updated
+			esWithStatus := *es.DeepCopy()
+			if tt.restartInProgress {
+				esWithStatus.Annotations = map[string]string{fullClusterRestartAnnotation: "es-es-data-1,es-es-data-2"}
+			}
+			objs := []runtime.Object{statefulSet.DeepCopy(), esWithStatus.DeepCopy()}
+			for i := range pods {
+				objs = append(objs, pods[i].DeepCopy())
+			}
+			k8sClient := k8s.WrappedFakeClient(objs...)
+			esWithStatus.Status.InProgressOperations.FullClusterRestart = &esv1.FullClusterRestartOperation{Phase: "Unknown"}
+			d := &defaultDriver{DefaultDriverParameters: DefaultDriverParameters{
+				ES:             esWithStatus,
+				Client:         k8sClient,
+				Expectations:   expectations.NewExpectations(k8sClient),
+				ReconcileState: reconcile.NewState(esWithStatus),
+			}}
+			esState := NewMemoizingESState(context.Background(), tt.esClient)
+
+			results := d.handleFullClusterRestart(
+				context.Background(), tt.esClient, esState, sset.StatefulSetList{statefulSet},
+				nil, nil, tt.podsToUpgrade, tt.healthyPods,
+			)
+			res, err := results.Aggregate()
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRequeue, res.Requeue || res.RequeueAfter > 0)
+
+			var actualPods corev1.PodList
+			require.NoError(t, k8sClient.List(&actualPods))
+			require.Len(t, actualPods.Items, tt.wantRemainingPods)
+			if len(tt.podsToUpgrade) > 0 {
+				require.True(t, tt.esClient.DisableReplicaShardsAllocationCalled)
+				require.True(t, tt.esClient.SyncedFlushCalled)
+			}
+			require.Equal(t, tt.wantAllocationEnabled, tt.esClient.EnableShardAllocationCalled)
+
+			_, updated := d.ReconcileState.Apply()
+			require.NotNil(t, updated)
+			require.Equal(t, tt.wantStatus, updated.Status.InProgressOperations.FullClusterRestart)
+			require.Equal(t, tt.wantUpgrade, updated.Status.InProgressOperations.Upgrade)
+
+			var actualES esv1.Elasticsearch
+			require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(&es), &actualES))
+			_, restartInProgress := actualES.Annotations[fullClusterRestartAnnotation]
+			require.Equal(t, tt.wantRestartInProgress, restartInProgress)
+		})
+	}
+}
+
+func Test_reportFullClusterRestart(t *testing.T) {
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	state := reconcile.NewState(es)
+	reportFullClusterRestart(es, state)
+	_, updated := state.Apply()
+	require.Nil(t, updated)
+
+	es.Annotations = map[string]string{fullClusterRestartAnnotation: "es-es-data-0,es-es-data-1"}
+	state = reconcile.NewState(es)
+	reportFullClusterRestart(es, state)
+	_, updated = state.Apply()
+	require.NotNil(t, updated)
+	require.Equal(t, &esv1.FullClusterRestartOperation{
+		Phase: esv1.FullClusterRestartRestarting,
+		Nodes: []string{"es-es-data-0", "es-es-data-1"},
+	}, updated.Status.InProgressOperations.FullClusterRestart)
+	require.Equal(t, []esv1.UpgradeOperation{
+		{Node: "es-es-data-0", Status: esv1.UpgradeRestarting},
+		{Node: "es-es-data-1", Status: esv1.UpgradeRestarting},
+	}, updated.Status.InProgressOperations.Upgrade)
+}
@@ -19,10 +19,11 @@ import (
 )
 
 // Delete runs through a list of potential candidates and select the ones that can be deleted.
+// It also returns the predicates which prevented the other candidates from being deleted.
 // Do not run this function unless driver expectations are met.
-func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, error) {
+func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, failedPredicates, error) {
 	if len(ctx.podsToUpgrade) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	// Get allowed deletions and check if maxUnavailable has been reached.
@@ -48,9 +49,9 @@ func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, error) {
 		"maxUnavailableReached", maxUnavailableReached,
 		"allowedDeletions", allowedDeletions,
 	)
-	podsToDelete, err := applyPredicates(predicateContext, candidates, maxUnavailableReached, allowedDeletions)
+	podsToDelete, failed, err := applyPredicates(predicateContext, candidates, maxUnavailableReached, allowedDeletions)
 	if err != nil {
-		return podsToDelete, err
+		return podsToDelete, failed, err
 	}
 
 	if len(podsToDelete) == 0 {
@@ -59,25 +60,65 @@ func (ctx *rollingUpgradeCtx) Delete() ([]corev1.Pod, error) {
 			"es_name", ctx.ES.Name,
 			"namespace", ctx.ES.Namespace,
 		)
-		return podsToDelete, nil
+		return podsToDelete, failed, nil
 	}
 
-	// Disable shard allocation
-	if err := ctx.prepareClusterForNodeRestart(ctx.esClient, ctx.esState); err != nil {
-		return podsToDelete, err
+	// Prepare the nodes to be restarted
+	podsToDelete, err = ctx.readyToDelete(podsToDelete)
+	if err != nil {
+		return nil, failed, err
 	}
 	// TODO: If master is changed into a data node (or the opposite) it must be excluded or we should update m_m_n
 	deletedPods := []corev1.Pod{}
 	for _, podToDelete := range podsToDelete {
 		if err := ctx.handleMasterScaleChange(podToDelete); err != nil {
-			return deletedPods, err
+			return deletedPods, failed, err
 		}
 		if err := deletePod(ctx.client, ctx.ES, podToDelete, ctx.expectations); err != nil {
-			return deletedPods, err
+			return deletedPods, failed, err
 		}
 		deletedPods = append(deletedPods, podToDelete)
 	}
-	return deletedPods, nil
+	return deletedPods, failed, nil
+}
+
+// readyToDelete prepares the nodes of the given Pods to be restarted, and returns the Pods that can be deleted.
+// If the node shutdown API is supported, it registers restart shutdown records and only returns the Pods whose
+// shutdown preparation is complete. Otherwise it disables shards allocation and returns all the given Pods.
+func (ctx *rollingUpgradeCtx) readyToDelete(podsToDelete []corev1.Pod) ([]corev1.Pod, error) {
+	if ctx.nodeShutdown == nil {
+		// Disable shard allocation
+		if err := ctx.prepareClusterForNodeRestart(ctx.esClient, ctx.esState); err != nil {
+			return nil, err
+		}
+		return podsToDelete, nil
+	}
+	if err := ctx.nodeShutdown.Register(ctx.parentCtx, k8s.PodNames(podsToDelete)); err != nil {
+		return nil, err
+	}
+	ready := make([]corev1.Pod, 0, len(podsToDelete))
+	for _, pod := range podsToDelete {
+		inCluster, err := ctx.nodeShutdown.InCluster(ctx.parentCtx, pod.Name)
+		if err != nil {
+			return nil, err
+		}
+		complete, err := ctx.nodeShutdown.IsComplete(ctx.parentCtx, pod.Name)
+		if err != nil {
+			return nil, err
+		}
+		// a node that is not in the cluster does not need to be prepared
+		if !inCluster || complete {
+			ready = append(ready, pod)
+			continue
+		}
+		log.V(1).Info(
+			"Node not yet prepared for restart",
+			"es_name", ctx.ES.Name,
+			"namespace", ctx.ES.Namespace,
+			"pod_name", pod.Name,
+		)
+	}
+	return ready, nil
 }
 
 // getAllowedDeletions returns the number of deletions that can be done and if maxUnavailable has been reached.
@@ -72,14 +72,18 @@ func NewPredicateContext(
 	}
 }
 
-func applyPredicates(ctx PredicateContext, candidates []corev1.Pod, maxUnavailableReached bool, allowedDeletions int) (deletedPods []corev1.Pod, err error) {
-	var failedPredicates failedPredicates
+func applyPredicates(
+	ctx PredicateContext,
+	candidates []corev1.Pod,
+	maxUnavailableReached bool,
+	allowedDeletions int,
+) (deletedPods []corev1.Pod, failedPredicates failedPredicates, err error) {
 
 Loop:
 	for _, candidate := range candidates {
 		switch predicateErr, err := runPredicates(ctx, candidate, deletedPods, maxUnavailableReached); {
 		case err != nil:
-			return deletedPods, err
+			return deletedPods, failedPredicates, err
 		case predicateErr != nil:
 			// A predicate has failed on this Pod
 			failedPredicates = append(failedPredicates, *predicateErr)
@@ -109,7 +113,7 @@ Loop:
 			"es_name", ctx.es.Name,
 			"failed_predicates", groupByPredicates(failedPredicates))
 	}
-	return deletedPods, nil
+	return deletedPods, failedPredicates, nil
 }
 
 var predicates = [...]Predicate{
@@ -156,7 +156,7 @@ func TestUpgradePodsDeletion_WithNodeTypeMutations(t *testing.T) {
 			healthyPods:     tt.fields.upgradeTestPods.toHealthyPods(),
 		}
 
-		deleted, err := ctx.Delete()
+		deleted, _, err := ctx.Delete()
 		if (err != nil) != tt.wantErr {
 			t.Errorf("runPredicates error = %v, wantErr %v", err, tt.wantErr)
 			return
@@ -655,7 +655,7 @@ func TestUpgradePodsDeletion_Delete(t *testing.T) {
 			healthyPods:     tt.fields.upgradeTestPods.toHealthyPods(),
 		}
 
-		deleted, err := ctx.Delete()
+		deleted, _, err := ctx.Delete()
 		if (err != nil) != tt.wantErr {
 			t.Errorf("runPredicates error = %v, wantErr %v", err, tt.wantErr)
 			return
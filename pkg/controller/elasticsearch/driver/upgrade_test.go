@@ -5,15 +5,22 @@
 package driver
 
 import (
+	"context"
 	"testing"
 
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/shutdown"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func podWithRevision(name, revision string) *corev1.Pod {
@@ -196,3 +203,70 @@ func Test_healthyPods(t *testing.T) {
 		})
 	}
 }
+
+func Test_upgradeOperations(t *testing.T) {
+	pod := func(name string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	tests := []struct {
+		name          string
+		podsToUpgrade []corev1.Pod
+		deletedPods   []corev1.Pod
+		failed        failedPredicates
+		want          []esv1.UpgradeOperation
+	}{
+		{
+			name: "no Pod to upgrade",
+			want: nil,
+		},
+		{
+			name:          "Pods restarting, blocked by a predicate, or pending",
+			podsToUpgrade: []corev1.Pod{pod("es-2"), pod("es-1"), pod("es-0")},
+			deletedPods:   []corev1.Pod{pod("es-2")},
+			failed:        failedPredicates{{pod: "es-1", predicate: "require_started_replica"}},
+			want: []esv1.UpgradeOperation{
+				{Node: "es-0", Status: esv1.UpgradePending},
+				{Node: "es-1", Status: esv1.UpgradeBlocked, Predicate: "require_started_replica"},
+				{Node: "es-2", Status: esv1.UpgradeRestarting},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, upgradeOperations(tt.podsToUpgrade, tt.deletedPods, tt.failed))
+		})
+	}
+}
+
+func Test_rollingUpgradeCtx_readyToDelete_nodeShutdown(t *testing.T) {
+	api := newFakeNodeShutdownAPI("es-0", "es-1", "es-2").
+		// es-0 is ready to be restarted
+		withShutdown("id-es-0", esclient.Restart, esclient.ShutdownComplete).
+		// es-2 is being removed
+		withShutdown("id-es-2", esclient.Remove, esclient.ShutdownInProgress)
+	esClient := esclient.NewMockClient(version.MustParse("7.15.2"), api.roundTrip)
+	ctx := rollingUpgradeCtx{
+		parentCtx:    context.Background(),
+		ES:           esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}},
+		esClient:     esClient,
+		nodeShutdown: shutdown.NewNodeShutdown(esClient, types.NamespacedName{Namespace: "ns", Name: "es"}, esclient.Restart, restartShutdownReason),
+	}
+	pod := func(name string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name}}
+	}
+
+	ready, err := ctx.readyToDelete([]corev1.Pod{pod("es-0"), pod("es-1"), pod("es-2"), pod("es-3")})
+	require.NoError(t, err)
+	// es-1 is not prepared yet, es-2 is prepared for another shutdown type, es-3 is not in the cluster
+	require.Equal(t, []string{"es-0", "es-3"}, k8s.PodNames(ready))
+	require.Equal(t, []string{"PUT /_nodes/id-es-1/shutdown"}, api.requests)
+}
+
+func Test_restartedNodes(t *testing.T) {
+	pod := func(name string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	healthyPods := map[string]corev1.Pod{"es-0": pod("es-0"), "es-1": pod("es-1"), "es-2": pod("es-2")}
+	assert.Equal(t, []string{"es-0", "es-2"}, restartedNodes([]corev1.Pod{pod("es-1"), pod("es-3")}, healthyPods))
+	assert.Nil(t, restartedNodes([]corev1.Pod{pod("es-0"), pod("es-1"), pod("es-2")}, healthyPods))
+}
@@ -27,6 +27,23 @@ type upscaleCtx struct {
 	observedState observer.State
 	esState       ESState
 	expectations  *expectations.Expectations
+	// validateStorageClass specifies whether storage classes volume expansion support should be verified
+	validateStorageClass bool
+}
+
+// UpscaleResults contains the results of the upscale phase.
+type UpscaleResults struct {
+	// ActualStatefulSets are the StatefulSets updated with the reconciled ones.
+	ActualStatefulSets sset.StatefulSetList
+	// Requeue is true if some StatefulSets are being recreated, in which case other operations should be
+	// postponed until they are.
+	Requeue bool
+	// PendingUpscales are the StatefulSets for which some nodes creation is postponed.
+	PendingUpscales []esv1.UpscaleOperation
+	// VolumeExpansionWarnings describe the storage increases ignored because the storage class does not allow them.
+	VolumeExpansionWarnings []string
+	// ES is the Elasticsearch resource, updated if it was annotated for StatefulSets to be recreated.
+	ES esv1.Elasticsearch
 }
 
 // HandleUpscaleAndSpecChanges reconciles expected NodeSet resources.
@@ -35,6 +52,7 @@ type upscaleCtx struct {
 // - update existing StatefulSets specification, to be used for future pods rotation
 // - upscale StatefulSet for which we expect more replicas
 // - limit master node creation to one at a time
+// - resize existing PVCs whose storage request was increased, and recreate the corresponding StatefulSets
 // It does not:
 // - perform any StatefulSet downscale (left for downscale phase)
 // - perform any pod upgrade (left for rolling upgrade phase)
@@ -42,50 +60,83 @@ func HandleUpscaleAndSpecChanges(
 	ctx upscaleCtx,
 	actualStatefulSets sset.StatefulSetList,
 	expectedResources nodespec.ResourcesList,
-) (sset.StatefulSetList, error) {
+) (UpscaleResults, error) {
+	results := UpscaleResults{ES: ctx.es}
+	// StatefulSets deleted to account for resized PVCs are recreated with their former replicas: their Pods still exist
+	withRecreated, err := withStatefulSetsToRecreate(ctx.es, actualStatefulSets, expectedResources.StatefulSets())
+	if err != nil {
+		return results, err
+	}
 	// adjust expected replicas to control nodes creation and deletion
-	adjusted, err := adjustResources(ctx, actualStatefulSets, expectedResources)
+	adjusted, pendingUpscales, err := adjustResources(ctx, withRecreated, expectedResources)
 	if err != nil {
-		return nil, err
+		return results, err
 	}
+	results.PendingUpscales = pendingUpscales
 	// reconcile all resources
 	for _, res := range adjusted {
 		if err := settings.ReconcileConfig(ctx.k8sClient, ctx.es, res.StatefulSet.Name, res.Config); err != nil {
-			return nil, err
+			return results, err
 		}
 		if _, err := common.ReconcileService(ctx.parentCtx, ctx.k8sClient, &res.HeadlessService, &ctx.es); err != nil {
-			return nil, err
+			return results, err
+		}
+		if actual, exists := actualStatefulSets.GetByName(res.StatefulSet.Name); exists {
+			recreating, warnings, err := handleVolumeExpansion(ctx.k8sClient, &ctx.es, &res.StatefulSet, actual, ctx.validateStorageClass)
+			if err != nil {
+				return results, err
+			}
+			results.VolumeExpansionWarnings = append(results.VolumeExpansionWarnings, warnings...)
+			if recreating {
+				// the StatefulSet is being recreated with the expected claims, it cannot be updated for now
+				results.Requeue = true
+				continue
+			}
+		} else {
+			recreation, exists, err := getRecreation(ctx.es, res.StatefulSet.Name)
+			if err != nil {
+				return results, err
+			}
+			if exists {
+				// recreate the StatefulSet with the claims it was deleted for, which may differ from the expected ones
+				res.StatefulSet.Spec.VolumeClaimTemplates = recreation.VolumeClaimTemplates
+			}
 		}
 		reconciled, err := sset.ReconcileStatefulSet(ctx.k8sClient, ctx.es, res.StatefulSet, ctx.expectations)
 		if err != nil {
-			return nil, err
+			return results, err
+		}
+		if err := removeRecreationAnnotation(ctx.k8sClient, &ctx.es, res.StatefulSet.Name); err != nil {
+			return results, err
 		}
 		// update actual with the reconciled ones for next steps to work with up-to-date information
 		actualStatefulSets = actualStatefulSets.WithStatefulSet(reconciled)
 	}
-	return actualStatefulSets, nil
+	results.ActualStatefulSets = actualStatefulSets
+	results.ES = ctx.es
+	return results, nil
 }
 
 func adjustResources(
 	ctx upscaleCtx,
 	actualStatefulSets sset.StatefulSetList,
 	expectedResources nodespec.ResourcesList,
-) (nodespec.ResourcesList, error) {
+) (nodespec.ResourcesList, []esv1.UpscaleOperation, error) {
 	upscaleState := newUpscaleState(ctx, actualStatefulSets, expectedResources)
 	adjustedResources := make(nodespec.ResourcesList, 0, len(expectedResources))
 	for _, nodeSpecRes := range expectedResources {
 		adjusted, err := adjustStatefulSetReplicas(upscaleState, actualStatefulSets, *nodeSpecRes.StatefulSet.DeepCopy())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		nodeSpecRes.StatefulSet = adjusted
 		adjustedResources = append(adjustedResources, nodeSpecRes)
 	}
 	// adapt resources configuration to match adjusted replicas
 	if err := adjustZenConfig(ctx.k8sClient, ctx.es, adjustedResources); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return adjustedResources, nil
+	return adjustedResources, upscaleState.pendingUpscales, nil
 }
 
 func adjustZenConfig(k8sClient k8s.Client, es esv1.Elasticsearch, resources nodespec.ResourcesList) error {
@@ -7,6 +7,7 @@ package driver
 import (
 	"sync"
 
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/bootstrap"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/nodespec"
@@ -17,6 +18,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
+const (
+	maxSurgeReason         = "limited by changeBudget.maxSurge"
+	oneMasterAtATimeReason = "master nodes are created one at a time"
+)
+
 type upscaleState struct {
 	isBootstrapped      bool
 	allowMasterCreation bool
@@ -25,8 +31,10 @@ type upscaleState struct {
 	// indicates how many creates are allowed when taking into account maxSurge setting,
 	// nil indicates that any number of pods can be created, negative value is not expected.
 	createsAllowed *int32
-	ctx            upscaleCtx
-	once           *sync.Once
+	// StatefulSets for which some nodes creation is postponed
+	pendingUpscales []esv1.UpscaleOperation
+	ctx             upscaleCtx
+	once            *sync.Once
 }
 
 func newUpscaleState(
@@ -155,6 +163,19 @@ func (s *upscaleState) getMaxNodesToCreate(noMoreThan int32) int32 {
 	return noMoreThan
 }
 
+// recordPendingUpscale records that the StatefulSet to apply does not have the target replicas yet, for the given reason.
+func (s *upscaleState) recordPendingUpscale(toApply appsv1.StatefulSet, targetReplicas int32, reason string) {
+	if sset.GetReplicas(toApply) >= targetReplicas {
+		return
+	}
+	s.pendingUpscales = append(s.pendingUpscales, esv1.UpscaleOperation{
+		StatefulSet:     toApply.Name,
+		CurrentReplicas: sset.GetReplicas(toApply),
+		TargetReplicas:  targetReplicas,
+		Reason:          reason,
+	})
+}
+
 // limitNodesCreation decreases replica count in specs as needed, assumes an upscale is requested
 func (s *upscaleState) limitNodesCreation(
 	actual appsv1.StatefulSet,
@@ -190,6 +211,7 @@ func (s *upscaleState) limitNodesCreation(
 			"actual", actualReplicas,
 		)
 	}
+	s.recordPendingUpscale(toApply, targetReplicas, maxSurgeReason)
 
 	return toApply, nil
 }
@@ -209,6 +231,11 @@ func (s *upscaleState) limitMasterNodesCreation(
 				"target", targetReplicas,
 				"actual", actualReplicas,
 			)
+			reason := maxSurgeReason
+			if !s.allowMasterCreation {
+				reason = oneMasterAtATimeReason
+			}
+			s.recordPendingUpscale(toApply, targetReplicas, reason)
 			break
 		}
 		// allow one more master node to be created
@@ -59,7 +59,7 @@ func Test_upscaleState_limitNodesCreation(t *testing.T) {
 			actual:      sset.TestSset{Name: "sset", Replicas: 1, Master: false}.Build(),
 			ssetToApply: sset.TestSset{Name: "sset", Replicas: 4, Master: false}.Build(),
 			wantSset:    sset.TestSset{Name: "sset", Replicas: 3, Master: false}.Build(),
-			wantState:   &upscaleState{allowMasterCreation: true, isBootstrapped: true, createsAllowed: pointer.Int32(2), recordedCreates: 2},
+			wantState:   &upscaleState{allowMasterCreation: true, isBootstrapped: true, createsAllowed: pointer.Int32(2), recordedCreates: 2, pendingUpscales: []esv1.UpscaleOperation{{StatefulSet: "sset", CurrentReplicas: 3, TargetReplicas: 4, Reason: maxSurgeReason}}},
 		},
 		{
 			name:        "upscale master nodes from 1 to 3: should limit to 2",
@@ -67,7 +67,7 @@ func Test_upscaleState_limitNodesCreation(t *testing.T) {
 			actual:      sset.TestSset{Name: "sset", Replicas: 1, Master: true}.Build(),
 			ssetToApply: sset.TestSset{Name: "sset", Replicas: 3, Master: true}.Build(),
 			wantSset:    sset.TestSset{Name: "sset", Replicas: 2, Master: true}.Build(),
-			wantState:   &upscaleState{allowMasterCreation: false, isBootstrapped: true, createsAllowed: pointer.Int32(1), recordedCreates: 1},
+			wantState:   &upscaleState{allowMasterCreation: false, isBootstrapped: true, createsAllowed: pointer.Int32(1), recordedCreates: 1, pendingUpscales: []esv1.UpscaleOperation{{StatefulSet: "sset", CurrentReplicas: 2, TargetReplicas: 3, Reason: oneMasterAtATimeReason}}},
 		},
 		{
 			name:        "upscale master nodes from 1 to 3 when cluster not yet bootstrapped: should go through",
@@ -83,7 +83,7 @@ func Test_upscaleState_limitNodesCreation(t *testing.T) {
 			actual:      sset.TestSset{Name: "sset", Replicas: 3, Master: true}.Build(),
 			ssetToApply: sset.TestSset{Name: "sset", Replicas: 4, Master: true}.Build(),
 			wantSset:    sset.TestSset{Name: "sset", Replicas: 3, Master: true}.Build(),
-			wantState:   &upscaleState{allowMasterCreation: true, isBootstrapped: true, createsAllowed: pointer.Int32(0), recordedCreates: 0},
+			wantState:   &upscaleState{allowMasterCreation: true, isBootstrapped: true, createsAllowed: pointer.Int32(0), recordedCreates: 0, pendingUpscales: []esv1.UpscaleOperation{{StatefulSet: "sset", CurrentReplicas: 3, TargetReplicas: 4, Reason: maxSurgeReason}}},
 		},
 		{
 			name:        "upscale data nodes from 3 to 4, but no creates allowed: should limit to 0",
@@ -91,7 +91,7 @@ func Test_upscaleState_limitNodesCreation(t *testing.T) {
 			actual:      sset.TestSset{Name: "sset", Replicas: 3, Master: false}.Build(),
 			ssetToApply: sset.TestSset{Name: "sset", Replicas: 4, Master: false}.Build(),
 			wantSset:    sset.TestSset{Name: "sset", Replicas: 3, Master: false}.Build(),
-			wantState:   &upscaleState{allowMasterCreation: true, isBootstrapped: true, createsAllowed: pointer.Int32(0), recordedCreates: 0},
+			wantState:   &upscaleState{allowMasterCreation: true, isBootstrapped: true, createsAllowed: pointer.Int32(0), recordedCreates: 0, pendingUpscales: []esv1.UpscaleOperation{{StatefulSet: "sset", CurrentReplicas: 3, TargetReplicas: 4, Reason: maxSurgeReason}}},
 		},
 		{
 			name:        "new StatefulSet with 5 master nodes, cluster isn't bootstrapped yet: should go through",
@@ -107,7 +107,7 @@ func Test_upscaleState_limitNodesCreation(t *testing.T) {
 			actual:      appsv1.StatefulSet{},
 			ssetToApply: sset.TestSset{Name: "sset", Replicas: 3, Master: true}.Build(),
 			wantSset:    sset.TestSset{Name: "sset", Replicas: 1, Master: true}.Build(),
-			wantState:   &upscaleState{allowMasterCreation: false, isBootstrapped: true, createsAllowed: pointer.Int32(1), recordedCreates: 1},
+			wantState:   &upscaleState{allowMasterCreation: false, isBootstrapped: true, createsAllowed: pointer.Int32(1), recordedCreates: 1, pendingUpscales: []esv1.UpscaleOperation{{StatefulSet: "sset", CurrentReplicas: 1, TargetReplicas: 3, Reason: oneMasterAtATimeReason}}},
 		},
 	}
 	for _, tt := range tests {
@@ -112,8 +112,9 @@ func TestHandleUpscaleAndSpecChanges(t *testing.T) {
 
 	// when no StatefulSets already exists
 	actualStatefulSets := sset.StatefulSetList{}
-	updatedStatefulSets, err := HandleUpscaleAndSpecChanges(ctx, actualStatefulSets, expectedResources)
+	res, err := HandleUpscaleAndSpecChanges(ctx, actualStatefulSets, expectedResources)
 	require.NoError(t, err)
+	updatedStatefulSets := res.ActualStatefulSets
 	// StatefulSets should be created with their expected replicas
 	var sset1 appsv1.StatefulSet
 	require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "sset1"}, &sset1))
@@ -133,8 +134,9 @@ func TestHandleUpscaleAndSpecChanges(t *testing.T) {
 	// upscale data nodes
 	actualStatefulSets = sset.StatefulSetList{sset1, sset2}
 	expectedResources[1].StatefulSet.Spec.Replicas = pointer.Int32(10)
-	updatedStatefulSets, err = HandleUpscaleAndSpecChanges(ctx, actualStatefulSets, expectedResources)
+	res, err = HandleUpscaleAndSpecChanges(ctx, actualStatefulSets, expectedResources)
 	require.NoError(t, err)
+	updatedStatefulSets = res.ActualStatefulSets
 	require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "sset2"}, &sset2))
 	require.Equal(t, pointer.Int32(10), sset2.Spec.Replicas)
 	comparison.RequireEqual(t, &updatedStatefulSets[1], &sset2)
@@ -144,8 +146,9 @@ func TestHandleUpscaleAndSpecChanges(t *testing.T) {
 	// apply a spec change
 	actualStatefulSets = sset.StatefulSetList{sset1, sset2}
 	expectedResources[1].StatefulSet.Spec.Template.Labels = map[string]string{"a": "b"}
-	updatedStatefulSets, err = HandleUpscaleAndSpecChanges(ctx, actualStatefulSets, expectedResources)
+	res, err = HandleUpscaleAndSpecChanges(ctx, actualStatefulSets, expectedResources)
 	require.NoError(t, err)
+	updatedStatefulSets = res.ActualStatefulSets
 	require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "sset2"}, &sset2))
 	require.Equal(t, "b", sset2.Spec.Template.Labels["a"])
 	comparison.RequireEqual(t, &updatedStatefulSets[1], &sset2)
@@ -154,8 +157,9 @@ func TestHandleUpscaleAndSpecChanges(t *testing.T) {
 	actualStatefulSets = sset.StatefulSetList{sset1, sset2}
 	expectedResources[1].StatefulSet.Spec.Replicas = pointer.Int32(2)
 	expectedResources[1].StatefulSet.Spec.Template.Labels = map[string]string{"a": "c"}
-	updatedStatefulSets, err = HandleUpscaleAndSpecChanges(ctx, actualStatefulSets, expectedResources)
+	res, err = HandleUpscaleAndSpecChanges(ctx, actualStatefulSets, expectedResources)
 	require.NoError(t, err)
+	updatedStatefulSets = res.ActualStatefulSets
 	require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "sset2"}, &sset2))
 	// spec should be updated
 	require.Equal(t, "c", sset2.Spec.Template.Labels["a"])
@@ -259,7 +263,7 @@ func Test_adjustStatefulSetReplicas(t *testing.T) {
 				expected:           sset.TestSset{Name: "sset", Replicas: 5, Master: true, Data: true}.Build(),
 			},
 			want:             sset.TestSset{Name: "sset", Replicas: 4, Master: true, Data: true}.Build(),
-			wantUpscaleState: &upscaleState{recordedCreates: 1, isBootstrapped: true, allowMasterCreation: false, createsAllowed: pointer.Int32(3)},
+			wantUpscaleState: &upscaleState{recordedCreates: 1, isBootstrapped: true, allowMasterCreation: false, createsAllowed: pointer.Int32(3), pendingUpscales: []esv1.UpscaleOperation{{StatefulSet: "sset", CurrentReplicas: 4, TargetReplicas: 5, Reason: oneMasterAtATimeReason}}},
 		},
 		{
 			name: "upscale case: new additional master sset - one by one",
@@ -269,7 +273,7 @@ func Test_adjustStatefulSetReplicas(t *testing.T) {
 				expected:           sset.TestSset{Name: "sset-2", Replicas: 3, Master: true, Data: true}.Build(),
 			},
 			want:             sset.TestSset{Name: "sset-2", Replicas: 1, Master: true, Data: true}.Build(),
-			wantUpscaleState: &upscaleState{recordedCreates: 1, isBootstrapped: true, allowMasterCreation: false, createsAllowed: pointer.Int32(3)},
+			wantUpscaleState: &upscaleState{recordedCreates: 1, isBootstrapped: true, allowMasterCreation: false, createsAllowed: pointer.Int32(3), pendingUpscales: []esv1.UpscaleOperation{{StatefulSet: "sset-2", CurrentReplicas: 1, TargetReplicas: 3, Reason: oneMasterAtATimeReason}}},
 		},
 	}
 	for _, tt := range tests {
@@ -434,7 +438,7 @@ func Test_adjustResources(t *testing.T) {
 				k8sClient:    k8sClient,
 				expectations: expectations.NewExpectations(k8sClient),
 			}
-			got, err := adjustResources(ctx, tt.args.actualStatefulSets, tt.args.expectedResources)
+			got, _, err := adjustResources(ctx, tt.args.actualStatefulSets, tt.args.expectedResources)
 			require.NoError(t, err)
 			require.Nil(t, deep.Equal(got.StatefulSets(), tt.wantSsets))
 		})
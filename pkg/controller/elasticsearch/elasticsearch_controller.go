@@ -12,17 +12,26 @@ import (
 	"go.elastic.co/apm"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	apmv1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1"
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	entv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/enterprisesearch/v1beta1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
@@ -36,10 +45,13 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
 	commonversion "github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates/transport"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/driver"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/networkpolicy"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	esreconcile "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/security"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
 	esversion "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/version"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
@@ -68,6 +80,7 @@ func newReconciler(mgr manager.Manager, params operator.Parameters) *ReconcileEl
 	observerSettings.Tracer = params.Tracer
 	return &ReconcileElasticsearch{
 		Client:         client,
+		apiReader:      mgr.GetAPIReader(),
 		recorder:       mgr.GetEventRecorderFor(name),
 		licenseChecker: license.NewLicenseChecker(client, params.OperatorNamespace),
 		esObservers:    observer.NewManager(observerSettings),
@@ -141,6 +154,52 @@ func addWatches(c controller.Controller, r *ReconcileElasticsearch) error {
 		return err
 	}
 
+	// Watch the users, roles and role mappings referencing Elasticsearch clusters
+	for _, kind := range []runtime.Object{&esv1.ElasticsearchUser{}, &esv1.ElasticsearchRole{}, &esv1.ElasticsearchRoleMapping{}} {
+		if err := c.Watch(&source.Kind{Type: kind}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(
+				func(object handler.MapObject) []reconcile.Request {
+					resource, ok := object.Object.(esv1.SecurityResource)
+					if !ok {
+						return nil
+					}
+					if esv1.IsCrossNamespaceReference(resource) {
+						// the resource is never picked up by the referenced cluster, it is rejected by the validation webhook
+						return nil
+					}
+					return []reconcile.Request{{NamespacedName: resource.ElasticsearchRef().NamespacedName()}}
+				}),
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Watch the NetworkPolicies, and the resources referencing Elasticsearch clusters they allow traffic from. The
+	// referenced clusters are reconciled regardless of their NetworkPolicy settings, which are only known once the
+	// cluster is retrieved by the reconciliation: it deletes the NetworkPolicy if it is not managed.
+	if err := c.Watch(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &esv1.Elasticsearch{},
+	}); err != nil {
+		return err
+	}
+	for _, kind := range []runtime.Object{
+		&kbv1.Kibana{}, &apmv1.ApmServer{}, &entv1beta1.EnterpriseSearch{}, &beatv1beta1.Beat{}, &agentv1alpha1.Agent{}, &esv1.Elasticsearch{},
+	} {
+		if err := c.Watch(&source.Kind{Type: kind}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(
+				func(object handler.MapObject) []reconcile.Request {
+					var requests []reconcile.Request
+					for _, cluster := range networkpolicy.ReferencedClusters(object.Object) {
+						requests = append(requests, reconcile.Request{NamespacedName: cluster})
+					}
+					return requests
+				}),
+		}, predicate.GenerationChangedPredicate{}); err != nil {
+			return err
+		}
+	}
+
 	// Trigger a reconciliation when observers report a cluster health change
 	if err := c.Watch(observer.WatchClusterHealthChange(r.esObservers), reconciler.GenericEventHandler()); err != nil {
 		return err
@@ -155,6 +214,9 @@ var _ reconcile.Reconciler = &ReconcileElasticsearch{}
 type ReconcileElasticsearch struct {
 	k8s.Client
 	operator.Parameters
+	// apiReader reads resources directly from the API server, it is used for cluster-scoped resources which may not be
+	// in the cache if the operator is restricted to some namespaces.
+	apiReader      client.Reader
 	recorder       record.EventRecorder
 	licenseChecker license.Checker
 
@@ -213,6 +275,8 @@ func (r *ReconcileElasticsearch) Reconcile(request reconcile.Request) (reconcile
 
 	state := esreconcile.NewState(es)
 	results := r.internalReconcile(ctx, es, state)
+	_, reconcileErr := results.Aggregate()
+	state.UpdateReconciliationStatus(reconcileErr)
 	err = r.updateStatus(ctx, es, state)
 	if err != nil {
 		if apierrors.IsConflict(err) {
@@ -298,6 +362,7 @@ func (r *ReconcileElasticsearch) internalReconcile(
 		ES:                 es,
 		ReconcileState:     reconcileState,
 		Client:             r.Client,
+		APIReader:          r.apiReader,
 		Recorder:           r.recorder,
 		Version:            *ver,
 		Expectations:       r.expectations.ForCluster(k8s.ExtractNamespacedName(&es)),
@@ -341,4 +406,8 @@ func (r *ReconcileElasticsearch) onDelete(es types.NamespacedName) {
 	r.dynamicWatches.Secrets.RemoveHandlerForKey(certificates.CertificateWatchKey(esv1.ESNamer, es.Name))
 	r.dynamicWatches.Secrets.RemoveHandlerForKey(user.UserProvidedRolesWatchName(es))
 	r.dynamicWatches.Secrets.RemoveHandlerForKey(user.UserProvidedFileRealmWatchName(es))
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(transport.CustomCAWatchKey(es))
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(transport.CertManagerWatchKey(es))
+	r.dynamicWatches.Secrets.RemoveHandlerForKey(security.PasswordSecretsWatchName(es))
+	certificates.DeleteExpirationMetrics(&esv1.Elasticsearch{}, es)
 }
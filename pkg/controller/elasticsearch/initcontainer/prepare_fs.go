@@ -6,6 +6,7 @@ package initcontainer
 
 import (
 	"path"
+	"time"
 
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
@@ -14,6 +15,8 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user/filerealm"
 	esvolume "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/zone"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/nodetuning"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -21,6 +24,10 @@ import (
 
 const (
 	initContainerTransportCertificatesVolumeMountPath = "/mnt/elastic-internal/transport-certificates"
+
+	// vmMaxMapCountWaitTimeout is how long the init container waits for the node tuning to raise vm.max_map_count,
+	// before failing to be restarted by the kubelet.
+	vmMaxMapCountWaitTimeout = 30 * time.Second
 )
 
 // Volumes that are shared between the prepare-fs init container and the ES container
@@ -69,6 +76,10 @@ var (
 				Source: stringsutil.Concat(esvolume.XPackFileRealmVolumeMountPath, "/", filerealm.UsersRolesFile),
 				Target: stringsutil.Concat(EsConfigSharedVolume.EsContainerMountPath, "/", filerealm.UsersRolesFile),
 			},
+			{
+				Source: stringsutil.Concat(esvolume.XPackFileRealmVolumeMountPath, "/", user.PKIRoleMappingFile),
+				Target: stringsutil.Concat(EsConfigSharedVolume.EsContainerMountPath, "/", user.PKIRoleMappingFile),
+			},
 			{
 				Source: stringsutil.Concat(settings.ConfigVolumeMountPath, "/", settings.ConfigFileName),
 				Target: stringsutil.Concat(EsConfigSharedVolume.EsContainerMountPath, "/", settings.ConfigFileName),
@@ -137,7 +148,13 @@ func NewPrepareFSInitContainer(
 	return container, nil
 }
 
-func RenderPrepareFsScript() (string, error) {
+// RenderPrepareFsScript renders the prepare-fs script. If manageNodeTuning is true, the script does not let
+// Elasticsearch start until the node tuning raises the vm.max_map_count kernel setting.
+func RenderPrepareFsScript(manageNodeTuning bool) (string, error) {
+	var waitTimeout time.Duration
+	if manageNodeTuning {
+		waitTimeout = vmMaxMapCountWaitTimeout
+	}
 	return RenderScriptTemplate(TemplateParams{
 		PluginVolumes: PluginVolumes,
 		LinkedFiles:   linkedFiles,
@@ -157,5 +174,12 @@ func RenderPrepareFsScript() (string, error) {
 			esvolume.NodeTransportCertificateCertFile,
 		),
 		TransportCertificatesSecretVolumeMountPath: esvolume.TransportCertificatesSecretVolumeMountPath,
+		AnnotationsFile: path.Join(esvolume.DownwardAPIMountPath, esvolume.AnnotationsFile),
+		ZoneAnnotation:  zone.AnnotationName,
+
+		MinVMMaxMapCount:                 nodetuning.VMMaxMapCount,
+		VMMaxMapCountWaitTimeout:         int(waitTimeout.Seconds()),
+		VMMaxMapCountTooLowMessagePrefix: VMMaxMapCountTooLowMessagePrefix,
+		TerminationMessagePath:           corev1.TerminationMessagePathDefault,
 	})
 }
@@ -33,6 +33,23 @@ type TemplateParams struct {
 	// TransportCertificatesSecretVolumeMountPath is the path to the volume in the es container that contains the
 	// transport certificates.
 	TransportCertificatesSecretVolumeMountPath string
+
+	// AnnotationsFile is the path to the file exposing the Pod annotations through the Downward API.
+	// It only exists if zone awareness is enabled.
+	AnnotationsFile string
+	// ZoneAnnotation is the name of the Pod annotation holding the zone of the Kubernetes node.
+	ZoneAnnotation string
+
+	// MinVMMaxMapCount is the minimum value of the vm.max_map_count kernel setting for memory mapping.
+	MinVMMaxMapCount int
+	// VMMaxMapCountWaitTimeout is the number of seconds to wait for the node tuning to raise vm.max_map_count, before
+	// failing for the container to be restarted. It is 0 if the node tuning is not managed by the operator, in which
+	// case Elasticsearch starts anyway.
+	VMMaxMapCountWaitTimeout int
+	// VMMaxMapCountTooLowMessagePrefix prefixes the termination message reporting a too low vm.max_map_count.
+	VMMaxMapCountTooLowMessagePrefix string
+	// TerminationMessagePath is the path of the file holding the termination message of the container.
+	TerminationMessagePath string
 }
 
 // RenderScriptTemplate renders scriptTemplate using the given TemplateParams
@@ -163,6 +180,49 @@ var scriptTemplate = template.Must(template.New("").Parse(
 
 	echo "Certs linking duration: $(duration $ln_start) sec."
 
+	######################
+	#  Wait for zone     #
+	######################
+
+	# the Pod annotations are only exposed if zone awareness is enabled,
+	# wait for the operator to annotate the Pod with the zone of its Kubernetes node
+	if [[ -f {{ .AnnotationsFile }} ]]; then
+		echo "waiting for the zone annotation {{ .ZoneAnnotation }}"
+		wait_start=$(date +%s)
+		while ! grep -q "^{{ .ZoneAnnotation }}=" {{ .AnnotationsFile }}
+		do
+			sleep 0.2
+		done
+		echo "wait duration: $(duration $wait_start) sec."
+	fi
+
+	######################
+	#  vm.max_map_count  #
+	######################
+
+	# memory mapping requires the vm.max_map_count kernel setting of the Kubernetes node to be high enough,
+	# report a value too low in the termination message for the operator to emit an event. If the node tuning
+	# is managed by the operator, wait for it to raise the value, and fail for the kubelet to restart the
+	# container until it does
+	if [[ -r /proc/sys/vm/max_map_count ]]; then
+		echo "checking vm.max_map_count"
+		wait_start=$(date +%s)
+		while [[ $(cat /proc/sys/vm/max_map_count) -lt {{ .MinVMMaxMapCount }} && $(duration $wait_start) -lt {{ .VMMaxMapCountWaitTimeout }} ]]
+		do
+			sleep 1
+		done
+		max_map_count=$(cat /proc/sys/vm/max_map_count)
+		if [[ $max_map_count -lt {{ .MinVMMaxMapCount }} ]]; then
+			message="{{ .VMMaxMapCountTooLowMessagePrefix }} $max_map_count, expected at least {{ .MinVMMaxMapCount }}"
+			>&2 echo "$message"
+			echo "$message" > {{ .TerminationMessagePath }} || true
+			{{- if .VMMaxMapCountWaitTimeout }}
+			exit 1
+			{{- end }}
+		fi
+		echo "vm.max_map_count check duration: $(duration $wait_start) sec."
+	fi
+
 	######################
 	#         End        #
 	######################
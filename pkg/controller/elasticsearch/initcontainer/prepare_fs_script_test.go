@@ -34,6 +34,42 @@ func TestRenderScriptTemplate(t *testing.T) {
 				"ln -sf /secrets/users /usr/share/elasticsearch/users",
 			},
 		},
+		{
+			name: "Wait for the zone annotation",
+			params: TemplateParams{
+				AnnotationsFile: "/mnt/elastic-internal/downward-api/annotations",
+				ZoneAnnotation:  "elasticsearch.k8s.elastic.co/zone",
+			},
+			wantSubstr: []string{
+				"if [[ -f /mnt/elastic-internal/downward-api/annotations ]]; then",
+				`while ! grep -q "^elasticsearch.k8s.elastic.co/zone=" /mnt/elastic-internal/downward-api/annotations`,
+			},
+		},
+		{
+			name: "Wait for vm.max_map_count and fail until it is raised",
+			params: TemplateParams{
+				MinVMMaxMapCount:                 262144,
+				VMMaxMapCountWaitTimeout:         30,
+				VMMaxMapCountTooLowMessagePrefix: "vm.max_map_count is too low:",
+				TerminationMessagePath:           "/dev/termination-log",
+			},
+			wantSubstr: []string{
+				"while [[ $(cat /proc/sys/vm/max_map_count) -lt 262144 && $(duration $wait_start) -lt 30 ]]",
+				`message="vm.max_map_count is too low: $max_map_count, expected at least 262144"`,
+				`echo "$message" > /dev/termination-log || true` + "\n\t\t\texit 1\n\t\tfi",
+			},
+		},
+		{
+			name: "Report vm.max_map_count without failing if the node tuning is not managed",
+			params: TemplateParams{
+				MinVMMaxMapCount:                 262144,
+				VMMaxMapCountTooLowMessagePrefix: "vm.max_map_count is too low:",
+				TerminationMessagePath:           "/dev/termination-log",
+			},
+			wantSubstr: []string{
+				`echo "$message" > /dev/termination-log || true` + "\n\t\tfi",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
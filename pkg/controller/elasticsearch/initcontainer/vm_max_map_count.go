@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package initcontainer
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// VMMaxMapCountTooLowMessagePrefix prefixes the termination message of the prepare-fs init container when the
+// vm.max_map_count kernel setting of the Kubernetes node is too low for memory mapping.
+const VMMaxMapCountTooLowMessagePrefix = "vm.max_map_count is too low:"
+
+// VMMaxMapCountWarningAnnotationName is set on the Pods whose vm.max_map_count warning was already reported, so that
+// it is reported once per Pod.
+const VMMaxMapCountWarningAnnotationName = "elasticsearch.k8s.elastic.co/vm-max-map-count-warning"
+
+// VMMaxMapCountTooLow returns the message reported by the prepare-fs init container of the given Pod if the
+// vm.max_map_count kernel setting of its Kubernetes node is too low for memory mapping. The last termination of the
+// container is considered as well, since it is restarted until the setting is raised if the node tuning is managed
+// by the operator.
+func VMMaxMapCountTooLow(pod corev1.Pod) (string, bool) {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name != PrepareFilesystemContainerName {
+			continue
+		}
+		for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+			if terminated != nil && strings.HasPrefix(terminated.Message, VMMaxMapCountTooLowMessagePrefix) {
+				return strings.TrimSpace(terminated.Message), true
+			}
+		}
+	}
+	return "", false
+}
+
+// VMMaxMapCountWarnings returns a warning for each Pod of the given cluster whose Kubernetes node has a vm.max_map_count
+// too low for memory mapping. Pods of NodeSets with memory mapping disabled are ignored. Each warning is returned once
+// per Pod: the Pods are annotated with VMMaxMapCountWarningAnnotationName.
+func VMMaxMapCountWarnings(c k8s.Client, es esv1.Elasticsearch, pods []corev1.Pod) ([]string, error) {
+	mmapAllowed := make(map[string]bool, len(es.Spec.NodeSets))
+	for _, nodeSet := range es.Spec.NodeSets {
+		cfg, err := esv1.UnpackConfig(nodeSet.Config)
+		// an invalid configuration is reported by the validation, assume the default
+		mmapAllowed[esv1.StatefulSet(es.Name, nodeSet.Name)] = err != nil || cfg.Node.Store.AllowMmap
+	}
+	var warnings []string
+	for i := range pods {
+		pod := pods[i].DeepCopy()
+		if allowed, exists := mmapAllowed[pod.Labels[label.StatefulSetNameLabelName]]; exists && !allowed {
+			continue
+		}
+		if _, reported := pod.Annotations[VMMaxMapCountWarningAnnotationName]; reported {
+			continue
+		}
+		message, tooLow := VMMaxMapCountTooLow(*pod)
+		if !tooLow {
+			continue
+		}
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[VMMaxMapCountWarningAnnotationName] = "true"
+		if err := c.Update(pod); err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, fmt.Sprintf(
+			"Pod %s on Kubernetes node %s: %s. Raise it on the node, enable the operator node tuning, "+
+				"or disable memory mapping with node.store.allow_mmap: false",
+			pod.Name, pod.Spec.NodeName, message,
+		))
+	}
+	return warnings, nil
+}
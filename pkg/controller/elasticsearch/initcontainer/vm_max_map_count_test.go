@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package initcontainer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func podWithPrepareFsMessage(name string, statefulSetName string, message string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Labels: map[string]string{label.StatefulSetNameLabelName: statefulSetName}},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+			{
+				Name:  PrepareFilesystemContainerName,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}},
+			},
+		}},
+	}
+}
+
+func TestVMMaxMapCountWarnings(t *testing.T) {
+	tooLow := "vm.max_map_count is too low: 65530, expected at least 262144\n"
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Name: "es"},
+		Spec: esv1.ElasticsearchSpec{NodeSets: []esv1.NodeSet{
+			{Name: "default"},
+			{Name: "nommap", Config: &commonv1.Config{Data: map[string]interface{}{"node.store.allow_mmap": false}}},
+		}},
+	}
+	// restarted by the kubelet until the node tuning raises vm.max_map_count
+	restarting := podWithPrepareFsMessage("es-es-default-3", "es-es-default", "")
+	restarting.Status.InitContainerStatuses[0].LastTerminationState = restarting.Status.InitContainerStatuses[0].State
+	restarting.Status.InitContainerStatuses[0].LastTerminationState.Terminated.Message = tooLow
+	restarting.Status.InitContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	pods := []corev1.Pod{
+		podWithPrepareFsMessage("es-es-default-0", "es-es-default", tooLow),
+		restarting,
+		podWithPrepareFsMessage("es-es-default-1", "es-es-default", ""),
+		// memory mapping disabled
+		podWithPrepareFsMessage("es-es-nommap-0", "es-es-nommap", tooLow),
+		// not started yet
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es-es-default-2"}},
+	}
+	objs := make([]runtime.Object, len(pods))
+	for i := range pods {
+		objs[i] = &pods[i]
+	}
+	c := k8s.WrappedFakeClient(objs...)
+
+	warnings, err := VMMaxMapCountWarnings(c, es, pods)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"Pod es-es-default-0 on Kubernetes node node-1: vm.max_map_count is too low: 65530, expected at least 262144. " +
+			"Raise it on the node, enable the operator node tuning, or disable memory mapping with node.store.allow_mmap: false",
+		"Pod es-es-default-3 on Kubernetes node node-1: vm.max_map_count is too low: 65530, expected at least 262144. " +
+			"Raise it on the node, enable the operator node tuning, or disable memory mapping with node.store.allow_mmap: false",
+	}, warnings)
+
+	// the warning is reported once per Pod
+	var pod corev1.Pod
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: "es-es-default-0"}, &pod))
+	require.Equal(t, "true", pod.Annotations[VMMaxMapCountWarningAnnotationName])
+	warnings, err = VMMaxMapCountWarnings(c, es, []corev1.Pod{pod})
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+}
@@ -26,6 +26,8 @@ const (
 	PodNameLabelName = "elasticsearch.k8s.elastic.co/pod-name"
 	// StatefulSetNameLabelName used to store the name of the statefulset.
 	StatefulSetNameLabelName = "elasticsearch.k8s.elastic.co/statefulset-name"
+	// NodeSetNameLabelName is set on the StatefulSets created to migrate the storage of a NodeSet, to store its name.
+	NodeSetNameLabelName = "elasticsearch.k8s.elastic.co/node-set-name"
 
 	// ConfigHashLabelName is a label used to store a hash of the Elasticsearch configuration.
 	ConfigHashLabelName = "elasticsearch.k8s.elastic.co/config-hash"
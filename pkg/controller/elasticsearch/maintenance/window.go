@@ -0,0 +1,63 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package maintenance
+
+import (
+	"time"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/chrono"
+)
+
+// Window is the state of the maintenance windows of a cluster at a given time.
+// The zero value is an open window.
+type Window struct {
+	// Next is the start time of the next maintenance window, zero if a window is currently open.
+	Next time.Time
+}
+
+// Always is the window of clusters with no maintenance windows.
+var Always = Window{}
+
+// IsOpen returns true if changes that restart or remove nodes can be applied.
+func (w Window) IsOpen() bool {
+	return w.Next.IsZero()
+}
+
+// Current returns the state of the given maintenance windows at the given time.
+// Windows that never open, normally rejected by the validation webhook, are ignored: the window is considered open
+// if none of the given windows can open.
+func Current(windows []esv1.MaintenanceWindow, now time.Time) Window {
+	if len(windows) == 0 {
+		return Always
+	}
+	var current Window
+	for _, window := range windows {
+		schedule, err := chrono.ParseCronSchedule(window.Schedule)
+		if err != nil || window.Duration.Duration <= 0 {
+			continue
+		}
+		// first window start within the duration of the window before now
+		start := schedule.Next(now.Add(-window.Duration.Duration))
+		if start.IsZero() {
+			continue
+		}
+		if !start.After(now) {
+			return Always
+		}
+		if current.Next.IsZero() || start.Before(current.Next) {
+			current.Next = start
+		}
+	}
+	return current
+}
+
+// RequeueAfter returns the duration until the next window opens, zero if the window is open.
+func (w Window) RequeueAfter(now time.Time) time.Duration {
+	if w.IsOpen() {
+		return 0
+	}
+	return w.Next.Sub(now)
+}
@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+)
+
+func TestCurrent(t *testing.T) {
+	weekendNights := esv1.MaintenanceWindow{Schedule: "0 2 * * sat,sun", Duration: metav1.Duration{Duration: 3 * time.Hour}}
+	wednesdayNoon := esv1.MaintenanceWindow{Schedule: "0 12 * * wed", Duration: metav1.Duration{Duration: time.Hour}}
+	tests := []struct {
+		name    string
+		windows []esv1.MaintenanceWindow
+		now     time.Time
+		want    Window
+	}{
+		{
+			name: "no maintenance windows",
+			now:  time.Date(2021, 3, 10, 14, 0, 0, 0, time.UTC),
+			want: Always,
+		},
+		{
+			name:    "outside the window",
+			windows: []esv1.MaintenanceWindow{weekendNights},
+			now:     time.Date(2021, 3, 10, 14, 0, 0, 0, time.UTC),
+			want:    Window{Next: time.Date(2021, 3, 13, 2, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "start of the window",
+			windows: []esv1.MaintenanceWindow{weekendNights},
+			now:     time.Date(2021, 3, 13, 2, 0, 0, 0, time.UTC),
+			want:    Always,
+		},
+		{
+			name:    "within the window",
+			windows: []esv1.MaintenanceWindow{weekendNights},
+			now:     time.Date(2021, 3, 14, 4, 59, 0, 0, time.UTC),
+			want:    Always,
+		},
+		{
+			name:    "end of the window",
+			windows: []esv1.MaintenanceWindow{weekendNights},
+			now:     time.Date(2021, 3, 14, 5, 0, 0, 0, time.UTC),
+			want:    Window{Next: time.Date(2021, 3, 20, 2, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "earliest of several windows",
+			windows: []esv1.MaintenanceWindow{weekendNights, wednesdayNoon},
+			now:     time.Date(2021, 3, 10, 14, 0, 0, 0, time.UTC),
+			want:    Window{Next: time.Date(2021, 3, 13, 2, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "within one of several windows",
+			windows: []esv1.MaintenanceWindow{weekendNights, wednesdayNoon},
+			now:     time.Date(2021, 3, 10, 12, 30, 0, 0, time.UTC),
+			want:    Always,
+		},
+		{
+			name:    "invalid windows are ignored",
+			windows: []esv1.MaintenanceWindow{{Schedule: "invalid", Duration: metav1.Duration{Duration: time.Hour}}},
+			now:     time.Date(2021, 3, 10, 14, 0, 0, 0, time.UTC),
+			want:    Always,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Current(tt.windows, tt.now))
+		})
+	}
+}
+
+func TestWindow_RequeueAfter(t *testing.T) {
+	now := time.Date(2021, 3, 10, 14, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Duration(0), Always.RequeueAfter(now))
+	require.Equal(t, 2*time.Hour, Window{Next: now.Add(2 * time.Hour)}.RequeueAfter(now))
+}
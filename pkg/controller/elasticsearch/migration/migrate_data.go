@@ -27,17 +27,32 @@ const (
 // and checks if there is at least one other copy of the shard in the cluster
 // that is started and not relocating.
 func IsMigratingData(ctx context.Context, shardLister esclient.ShardLister, podName string) (bool, error) {
-	shards, err := shardLister.GetShards(ctx)
+	remaining, err := RemainingShards(ctx, shardLister, podName)
 	if err != nil {
 		return false, err
 	}
-	// filter shards affected by node removal
+	return remaining[podName] > 0, nil
+}
+
+// RemainingShards returns the number of shards still allocated to each of the given nodes.
+func RemainingShards(ctx context.Context, shardLister esclient.ShardLister, nodeNames ...string) (map[string]int, error) {
+	remaining := make(map[string]int, len(nodeNames))
+	if len(nodeNames) == 0 {
+		return remaining, nil
+	}
+	for _, nodeName := range nodeNames {
+		remaining[nodeName] = 0
+	}
+	shards, err := shardLister.GetShards(ctx)
+	if err != nil {
+		return nil, err
+	}
 	for _, shard := range shards {
-		if shard.NodeName == podName {
-			return true, nil
+		if count, isLeaving := remaining[shard.NodeName]; isLeaving {
+			remaining[shard.NodeName] = count + 1
 		}
 	}
-	return false, nil
+	return remaining, nil
 }
 
 // allocationExcludeFromAnnotation returns the allocation exclude value stored in an annotation.
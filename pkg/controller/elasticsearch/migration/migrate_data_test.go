@@ -113,6 +113,23 @@ func TestIsMigratingData(t *testing.T) {
 	}
 }
 
+func TestRemainingShards(t *testing.T) {
+	shardLister := NewFakeShardLister([]client.Shard{
+		{Index: "index-1", Shard: "0", State: client.STARTED, NodeName: "A"},
+		{Index: "index-1", Shard: "1", State: client.RELOCATING, NodeName: "A"},
+		{Index: "index-1", Shard: "1", State: client.INITIALIZING, NodeName: "B"},
+		{Index: "index-2", Shard: "0", State: client.STARTED, NodeName: "C"},
+	})
+	remaining, err := RemainingShards(context.Background(), shardLister, "A", "B", "D")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"A": 2, "B": 1, "D": 0}, remaining)
+
+	// no node: shards are not retrieved
+	remaining, err = RemainingShards(context.Background(), NewFakeShardListerWithError(nil, fmt.Errorf("error")))
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+}
+
 func TestMigrateData(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -0,0 +1,217 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	apmv1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1"
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	entv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/enterprisesearch/v1beta1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/agent"
+	apmlabels "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/beat"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/network"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/enterprisesearch"
+	kblabel "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// Reconcile reconciles the NetworkPolicy of the given cluster if enabled, and deletes it otherwise.
+// It allows the HTTP traffic from the operator namespace and from the Pods of the resources referencing the cluster,
+// and the transport traffic from the Pods of the cluster and of the clusters using it as a remote cluster.
+func Reconcile(c k8s.Client, recorder record.EventRecorder, es esv1.Elasticsearch, params operator.Parameters) error {
+	enabled := networkpolicy.IsEnabled(recorder, &es, es.Spec.NetworkPolicy, params)
+	expected := networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+		Namespace: es.Namespace,
+		Name:      esv1.NetworkPolicy(es.Name),
+	}}
+	if enabled {
+		var err error
+		expected, err = expectedNetworkPolicy(c, es, params.OperatorNamespace)
+		if err != nil {
+			return err
+		}
+	}
+	return networkpolicy.Reconcile(c, &es, expected, enabled)
+}
+
+func expectedNetworkPolicy(c k8s.Client, es esv1.Elasticsearch, operatorNamespace string) (networkingv1.NetworkPolicy, error) {
+	httpPeers, err := httpClients(c, es)
+	if err != nil {
+		return networkingv1.NetworkPolicy{}, err
+	}
+	transportPeers, err := transportClients(c, es)
+	if err != nil {
+		return networkingv1.NetworkPolicy{}, err
+	}
+	return networkpolicy.New(
+		metav1.ObjectMeta{
+			Namespace: es.Namespace,
+			Name:      esv1.NetworkPolicy(es.Name),
+			Labels:    label.NewLabels(k8s.ExtractNamespacedName(&es)),
+		},
+		clusterPodLabels(es.Name),
+		networkpolicy.Rule{
+			Port:  network.HTTPPort,
+			Peers: append(httpPeers, networkpolicy.NamespacePeer(operatorNamespace)),
+		},
+		networkpolicy.Rule{
+			Port:  network.TransportPort,
+			Peers: append(transportPeers, networkpolicy.PodsPeer(es.Namespace, es.Namespace, clusterPodLabels(es.Name))),
+		},
+	), nil
+}
+
+func clusterPodLabels(esName string) map[string]string {
+	return map[string]string{label.ClusterNameLabelName: esName}
+}
+
+// references returns true if one of the given references, defaulting to the given namespace, targets the cluster.
+func references(es types.NamespacedName, namespace string, refs ...commonv1.ObjectSelector) bool {
+	for _, ref := range refs {
+		if ref.IsDefined() && ref.WithDefaultNamespace(namespace).NamespacedName() == es {
+			return true
+		}
+	}
+	return false
+}
+
+// monitoringRefs returns the references to the monitoring clusters of the given monitoring specification.
+func monitoringRefs(monitoring commonv1.Monitoring) []commonv1.ObjectSelector {
+	return append(append([]commonv1.ObjectSelector{}, monitoring.Metrics.ElasticsearchRefs...), monitoring.Logs.ElasticsearchRefs...)
+}
+
+// httpClients returns the Pods of the resources connecting to the HTTP layer of the given cluster: the associated
+// Kibana, APM Server, Enterprise Search, Beats and Elastic Agent, and the monitored Elasticsearch and Kibana.
+func httpClients(c k8s.Client, es esv1.Elasticsearch) ([]networkingv1.NetworkPolicyPeer, error) {
+	esKey := k8s.ExtractNamespacedName(&es)
+	var peers []networkingv1.NetworkPolicyPeer
+	addPeer := func(namespace string, podLabels map[string]string) {
+		peers = append(peers, networkpolicy.PodsPeer(es.Namespace, namespace, podLabels))
+	}
+
+	var kibanas kbv1.KibanaList
+	if err := c.List(&kibanas); err != nil {
+		return nil, err
+	}
+	for _, kb := range kibanas.Items {
+		if references(esKey, kb.Namespace, append(monitoringRefs(kb.Spec.Monitoring), kb.Spec.ElasticsearchRef)...) {
+			addPeer(kb.Namespace, map[string]string{kblabel.KibanaNameLabelName: kb.Name})
+		}
+	}
+
+	var apmServers apmv1.ApmServerList
+	if err := c.List(&apmServers); err != nil {
+		return nil, err
+	}
+	for _, as := range apmServers.Items {
+		if references(esKey, as.Namespace, as.Spec.ElasticsearchRef) {
+			addPeer(as.Namespace, map[string]string{apmlabels.ApmServerNameLabelName: as.Name})
+		}
+	}
+
+	var entSearches entv1beta1.EnterpriseSearchList
+	if err := c.List(&entSearches); err != nil {
+		return nil, err
+	}
+	for _, ents := range entSearches.Items {
+		if references(esKey, ents.Namespace, ents.Spec.ElasticsearchRef) {
+			addPeer(ents.Namespace, map[string]string{enterprisesearch.EnterpriseSearchNameLabelName: ents.Name})
+		}
+	}
+
+	var beats beatv1beta1.BeatList
+	if err := c.List(&beats); err != nil {
+		return nil, err
+	}
+	for _, b := range beats.Items {
+		if references(esKey, b.Namespace, b.Spec.ElasticsearchRef) {
+			addPeer(b.Namespace, map[string]string{beat.NameLabelName: b.Name})
+		}
+	}
+
+	var agents agentv1alpha1.AgentList
+	if err := c.List(&agents); err != nil {
+		return nil, err
+	}
+	for _, a := range agents.Items {
+		if references(esKey, a.Namespace, a.Spec.ElasticsearchRef) {
+			addPeer(a.Namespace, map[string]string{agent.NameLabelName: a.Name})
+		}
+	}
+
+	var clusters esv1.ElasticsearchList
+	if err := c.List(&clusters); err != nil {
+		return nil, err
+	}
+	for _, cluster := range clusters.Items {
+		if references(esKey, cluster.Namespace, monitoringRefs(cluster.Spec.Monitoring)...) {
+			addPeer(cluster.Namespace, clusterPodLabels(cluster.Name))
+		}
+	}
+	return peers, nil
+}
+
+// transportClients returns the Pods of the clusters connecting to the transport layer of the given cluster as a
+// remote cluster.
+func transportClients(c k8s.Client, es esv1.Elasticsearch) ([]networkingv1.NetworkPolicyPeer, error) {
+	esKey := k8s.ExtractNamespacedName(&es)
+	var clusters esv1.ElasticsearchList
+	if err := c.List(&clusters); err != nil {
+		return nil, err
+	}
+	var peers []networkingv1.NetworkPolicyPeer
+	for _, cluster := range clusters.Items {
+		for _, remoteCluster := range cluster.Spec.RemoteClusters {
+			if references(esKey, cluster.Namespace, remoteCluster.ElasticsearchRef) {
+				peers = append(peers, networkpolicy.PodsPeer(es.Namespace, cluster.Namespace, clusterPodLabels(cluster.Name)))
+				break
+			}
+		}
+	}
+	return peers, nil
+}
+
+// ReferencedClusters returns the clusters referenced by the given object, for which the NetworkPolicy may need to be
+// updated when the object changes.
+func ReferencedClusters(obj interface{}) []types.NamespacedName {
+	var namespace string
+	var refs []commonv1.ObjectSelector
+	switch o := obj.(type) {
+	case *kbv1.Kibana:
+		namespace, refs = o.Namespace, append(monitoringRefs(o.Spec.Monitoring), o.Spec.ElasticsearchRef)
+	case *apmv1.ApmServer:
+		namespace, refs = o.Namespace, []commonv1.ObjectSelector{o.Spec.ElasticsearchRef}
+	case *entv1beta1.EnterpriseSearch:
+		namespace, refs = o.Namespace, []commonv1.ObjectSelector{o.Spec.ElasticsearchRef}
+	case *beatv1beta1.Beat:
+		namespace, refs = o.Namespace, []commonv1.ObjectSelector{o.Spec.ElasticsearchRef}
+	case *agentv1alpha1.Agent:
+		namespace, refs = o.Namespace, []commonv1.ObjectSelector{o.Spec.ElasticsearchRef}
+	case *esv1.Elasticsearch:
+		namespace, refs = o.Namespace, monitoringRefs(o.Spec.Monitoring)
+		for _, remoteCluster := range o.Spec.RemoteClusters {
+			refs = append(refs, remoteCluster.ElasticsearchRef)
+		}
+	}
+	var clusters []types.NamespacedName
+	for _, ref := range refs {
+		if ref.IsDefined() {
+			clusters = append(clusters, ref.WithDefaultNamespace(namespace).NamespacedName())
+		}
+	}
+	return clusters
+}
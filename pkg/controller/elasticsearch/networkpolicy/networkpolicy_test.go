@@ -0,0 +1,136 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	apmv1 "github.com/elastic/cloud-on-k8s/pkg/apis/apm/v1"
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	apmlabels "github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	commonscheme "github.com/elastic/cloud-on-k8s/pkg/controller/common/scheme"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/network"
+	kblabel "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var esFixture = esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+
+func esRef(namespace string) commonv1.ObjectSelector {
+	return commonv1.ObjectSelector{Namespace: namespace, Name: "es"}
+}
+
+func TestReconcile(t *testing.T) {
+	require.NoError(t, commonscheme.SetupScheme())
+	params := operator.Parameters{OperatorNamespace: "elastic-system", ManageNetworkPolicies: true, NetworkPoliciesSupported: true}
+	kb := kbv1.Kibana{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb"},
+		Spec:       kbv1.KibanaSpec{ElasticsearchRef: esRef("")},
+	}
+	apm := apmv1.ApmServer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "apm"},
+		Spec:       apmv1.ApmServerSpec{ElasticsearchRef: esRef("ns")},
+	}
+	// references a cluster with the same name in another namespace
+	unrelatedBeat := beatv1beta1.Beat{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "beat"},
+		Spec:       beatv1beta1.BeatSpec{ElasticsearchRef: esRef("")},
+	}
+	monitored := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "monitored"},
+		Spec: esv1.ElasticsearchSpec{Monitoring: commonv1.Monitoring{
+			Metrics: commonv1.MetricsMonitoring{ElasticsearchRefs: []commonv1.ObjectSelector{esRef("")}},
+		}},
+	}
+	remote := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "remote", Name: "ccs"},
+		Spec: esv1.ElasticsearchSpec{RemoteClusters: []esv1.RemoteCluster{
+			{Name: "es", ElasticsearchRef: esRef("ns")},
+		}},
+	}
+	es := esFixture
+	c := k8s.WrappedFakeClient(&es, &kb, &apm, &unrelatedBeat, &monitored, &remote)
+
+	require.NoError(t, Reconcile(c, record.NewFakeRecorder(10), es, params))
+	var policy networkingv1.NetworkPolicy
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: "es-es-network-policy"}, &policy))
+	expected := networkpolicy.New(
+		metav1.ObjectMeta{},
+		clusterPodLabels("es"),
+		networkpolicy.Rule{Port: network.HTTPPort, Peers: []networkingv1.NetworkPolicyPeer{
+			networkpolicy.NamespacePeer("elastic-system"),
+			networkpolicy.PodsPeer("ns", "ns", map[string]string{kblabel.KibanaNameLabelName: "kb"}),
+			networkpolicy.PodsPeer("ns", "other", map[string]string{apmlabels.ApmServerNameLabelName: "apm"}),
+			networkpolicy.PodsPeer("ns", "ns", clusterPodLabels("monitored")),
+		}},
+		networkpolicy.Rule{Port: network.TransportPort, Peers: []networkingv1.NetworkPolicyPeer{
+			networkpolicy.PodsPeer("ns", "ns", clusterPodLabels("es")),
+			networkpolicy.PodsPeer("ns", "remote", clusterPodLabels("ccs")),
+		}},
+	)
+	require.Equal(t, expected.Spec, policy.Spec)
+
+	// disabled on the resource
+	disabled := false
+	es.Spec.NetworkPolicy.Enabled = &disabled
+	require.NoError(t, Reconcile(c, record.NewFakeRecorder(10), es, params))
+	var policies networkingv1.NetworkPolicyList
+	require.NoError(t, c.List(&policies))
+	require.Empty(t, policies.Items)
+}
+
+func TestReferencedClusters(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  interface{}
+		want []types.NamespacedName
+	}{
+		{
+			name: "no reference",
+			obj:  &kbv1.Kibana{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb"}},
+			want: nil,
+		},
+		{
+			name: "Kibana association and monitoring",
+			obj: &kbv1.Kibana{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb"},
+				Spec: kbv1.KibanaSpec{
+					ElasticsearchRef: esRef(""),
+					Monitoring: commonv1.Monitoring{
+						Logs: commonv1.LogsMonitoring{ElasticsearchRefs: []commonv1.ObjectSelector{{Namespace: "monitoring", Name: "logs"}}},
+					},
+				},
+			},
+			want: []types.NamespacedName{{Namespace: "monitoring", Name: "logs"}, {Namespace: "ns", Name: "es"}},
+		},
+		{
+			name: "remote clusters",
+			obj: &esv1.Elasticsearch{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ccs"},
+				Spec: esv1.ElasticsearchSpec{RemoteClusters: []esv1.RemoteCluster{
+					{Name: "es", ElasticsearchRef: esRef("")},
+					{Name: "external"},
+				}},
+			},
+			want: []types.NamespacedName{{Namespace: "ns", Name: "es"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ReferencedClusters(tt.obj))
+		})
+	}
+}
@@ -21,7 +21,9 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/network"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/stackmon"
 	esvolume "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/zone"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
 
@@ -32,7 +34,9 @@ func BuildPodTemplateSpec(
 	cfg settings.CanonicalConfig,
 	keystoreResources *keystore.Resources,
 ) (corev1.PodTemplateSpec, error) {
-	volumes, volumeMounts := buildVolumes(es.Name, nodeSet, keystoreResources)
+	volumes, volumeMounts := buildVolumes(
+		es.Name, nodeSet, keystoreResources, nodeSet.ZoneAwareness != nil, es.Spec.HTTP.TLS.ClientAuthenticationEnabled(),
+	)
 	labels, err := buildLabels(es, cfg, nodeSet, keystoreResources)
 	if err != nil {
 		return corev1.PodTemplateSpec{}, err
@@ -67,6 +71,13 @@ func BuildPodTemplateSpec(
 		WithPreStopHook(*NewPreStopHook()).
 		WithInitContainerDefaults()
 
+	builder = zone.WithZoneAwareness(builder, es, nodeSet)
+
+	builder, err = stackmon.WithMonitoring(builder, es)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+
 	return builder.PodTemplate, nil
 }
 
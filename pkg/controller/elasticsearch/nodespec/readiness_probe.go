@@ -64,9 +64,17 @@ else
   BASIC_AUTH=''
 fi
 
+# present a client certificate if client authentication is enabled
+client_cert_dir="` + volume.HTTPClientCertificatesSecretVolumeMountPath + `"
+if [ -f "${client_cert_dir}/tls.crt" ] && [ -f "${client_cert_dir}/tls.key" ]; then
+  CLIENT_CERT="--cert ${client_cert_dir}/tls.crt --key ${client_cert_dir}/tls.key"
+else
+  CLIENT_CERT=''
+fi
+
 # request Elasticsearch on /
 ENDPOINT="${READINESS_PROBE_PROTOCOL:-https}://127.0.0.1:9200/"
-status=$(curl -o /dev/null -w "%{http_code}" --max-time ${READINESS_PROBE_TIMEOUT} -XGET -s -k ${BASIC_AUTH} $ENDPOINT)
+status=$(curl -o /dev/null -w "%{http_code}" --max-time ${READINESS_PROBE_TIMEOUT} -XGET -s -k ${BASIC_AUTH} ${CLIENT_CERT} $ENDPOINT)
 curl_rc=$?
 
 if [[ ${curl_rc} -ne 0 ]]; then
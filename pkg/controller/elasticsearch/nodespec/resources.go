@@ -53,16 +53,21 @@ func BuildExpectedResources(
 		if nodeSpec.Config != nil {
 			userCfg = *nodeSpec.Config
 		}
-		cfg, err := settings.NewMergedESConfig(es.Name, *ver, es.Spec.HTTP, userCfg)
+		cfg, err := settings.NewMergedESConfig(es.Name, *ver, es.Spec.HTTP, userCfg, nodeSpec.ZoneAwareness != nil)
 		if err != nil {
 			return nil, err
 		}
 
-		// build stateful set and associated headless service
+		// build stateful set and associated headless service, under another name if the NodeSet storage is migrated
+		nodeSetName := nodeSpec.Name
+		nodeSpec.Name = statefulSetNodeSetName(es, nodeSpec, existingStatefulSets)
 		statefulSet, err := BuildStatefulSet(es, nodeSpec, cfg, keystoreResources, existingStatefulSets)
 		if err != nil {
 			return nil, err
 		}
+		if nodeSpec.Name != nodeSetName {
+			statefulSet.Labels[label.NodeSetNameLabelName] = nodeSetName
+		}
 		headlessSvc := HeadlessService(k8s.ExtractNamespacedName(&es), statefulSet.Name)
 
 		nodesResources = append(nodesResources, Resources{
@@ -0,0 +1,116 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodespec
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+	esvolume "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
+)
+
+// NodeSetStatefulSets returns the existing StatefulSets of the given NodeSet: the one named after the NodeSet, and the
+// ones created to migrate its storage. The StatefulSet named after the NodeSet comes first.
+func NodeSetStatefulSets(es esv1.Elasticsearch, nodeSetName string, existing sset.StatefulSetList) sset.StatefulSetList {
+	defaultName := esv1.StatefulSet(es.Name, nodeSetName)
+	var statefulSets sset.StatefulSetList
+	for _, s := range existing {
+		if s.Name == defaultName || s.Labels[label.NodeSetNameLabelName] == nodeSetName {
+			statefulSets = append(statefulSets, s)
+		}
+	}
+	sort.SliceStable(statefulSets, func(i, j int) bool {
+		return statefulSets[i].Name == defaultName && statefulSets[j].Name != defaultName
+	})
+	return statefulSets
+}
+
+// statefulSetNodeSetName returns the name to use in place of the NodeSet name to build its StatefulSet.
+// The name of an existing StatefulSet of the NodeSet with volume claim templates compatible with the expected ones is
+// reused. If there is none and the NodeSet allows storage migration, a suffix derived from the expected claims is
+// appended to the NodeSet name, so that the nodes of the NodeSet are migrated to a new StatefulSet.
+func statefulSetNodeSetName(es esv1.Elasticsearch, nodeSet esv1.NodeSet, existing sset.StatefulSetList) string {
+	statefulSets := NodeSetStatefulSets(es, nodeSet.Name, existing)
+	if len(statefulSets) == 0 {
+		return nodeSet.Name
+	}
+	defaultName := esv1.StatefulSet(es.Name, nodeSet.Name)
+	nameOf := func(statefulSetName string) string {
+		return nodeSet.Name + strings.TrimPrefix(statefulSetName, defaultName)
+	}
+	expectedClaims := defaults.AppendDefaultPVCs(
+		nodeSet.VolumeClaimTemplates, nodeSet.PodTemplate.Spec, esvolume.DefaultVolumeClaimTemplates...,
+	)
+	for _, s := range statefulSets {
+		if claimsCompatible(s.Spec.VolumeClaimTemplates, expectedClaims) {
+			return nameOf(s.Name)
+		}
+	}
+	if nodeSet.AllowsStorageMigration() {
+		return nodeSet.Name + "-" + claimsSuffix(expectedClaims)
+	}
+	// the claims update is rejected when updating the StatefulSet
+	return nameOf(statefulSets[0].Name)
+}
+
+// claimsSuffix returns a suffix derived from the given claims.
+func claimsSuffix(claims []corev1.PersistentVolumeClaim) string {
+	specs := make(map[string]corev1.PersistentVolumeClaimSpec, len(claims))
+	for _, claim := range claims {
+		specs[claim.Name] = claim.Spec
+	}
+	suffix := hash.HashObject(specs)
+	if len(suffix) > esv1.StorageMigrationSuffixLength {
+		suffix = suffix[len(suffix)-esv1.StorageMigrationSuffixLength:]
+	}
+	return suffix
+}
+
+// claimsCompatible returns true if the actual claims can be used in place of the expected ones, possibly through
+// volume expansion. Fields defaulted by the API server are ignored.
+func claimsCompatible(actual, expected []corev1.PersistentVolumeClaim) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for _, expectedClaim := range expected {
+		actualClaim := getClaimMatchingName(actual, expectedClaim.Name)
+		if actualClaim == nil {
+			return false
+		}
+		if !claimSpecCompatible(actualClaim.Spec, expectedClaim.Spec) {
+			return false
+		}
+	}
+	return true
+}
+
+func claimSpecCompatible(actual, expected corev1.PersistentVolumeClaimSpec) bool {
+	actualStorage := actual.Resources.Requests[corev1.ResourceStorage]
+	expectedStorage := expected.Resources.Requests[corev1.ResourceStorage]
+	if expectedStorage.Cmp(actualStorage) < 0 {
+		// storage decrease
+		return false
+	}
+	return reflect.DeepEqual(actual.StorageClassName, expected.StorageClassName) &&
+		reflect.DeepEqual(actual.AccessModes, expected.AccessModes) &&
+		reflect.DeepEqual(actual.Selector, expected.Selector) &&
+		volumeMode(actual) == volumeMode(expected)
+}
+
+// volumeMode returns the volume mode of the given claim, which defaults to Filesystem.
+func volumeMode(claim corev1.PersistentVolumeClaimSpec) corev1.PersistentVolumeMode {
+	if claim.VolumeMode == nil {
+		return corev1.PersistentVolumeFilesystem
+	}
+	return *claim.VolumeMode
+}
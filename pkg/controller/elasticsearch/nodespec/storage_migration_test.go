@@ -0,0 +1,212 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodespec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/sset"
+)
+
+func claim(storageClass string, storage string) corev1.PersistentVolumeClaim {
+	return corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "elasticsearch-data"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(storage)},
+			},
+		},
+	}
+}
+
+func statefulSetWithClaims(name string, nodeSetLabel string, claims ...corev1.PersistentVolumeClaim) appsv1.StatefulSet {
+	s := appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+		Spec:       appsv1.StatefulSetSpec{VolumeClaimTemplates: claims},
+	}
+	if nodeSetLabel != "" {
+		s.Labels = map[string]string{label.NodeSetNameLabelName: nodeSetLabel}
+	}
+	return s
+}
+
+func Test_statefulSetNodeSetName(t *testing.T) {
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	standardClaim := claim("standard", "1Gi")
+	fastClaim := claim("fast", "1Gi")
+	fastSuffix := claimsSuffix([]corev1.PersistentVolumeClaim{fastClaim})
+	tests := []struct {
+		name     string
+		nodeSet  esv1.NodeSet
+		existing sset.StatefulSetList
+		want     string
+	}{
+		{
+			name:    "no existing StatefulSet",
+			nodeSet: esv1.NodeSet{Name: "data", VolumeClaimTemplates: []corev1.PersistentVolumeClaim{fastClaim}},
+			want:    "data",
+		},
+		{
+			name: "existing StatefulSet with the same claims",
+			nodeSet: esv1.NodeSet{
+				Name:                               "data",
+				VolumeClaimTemplates:               []corev1.PersistentVolumeClaim{standardClaim},
+				VolumeClaimTemplatesUpdateStrategy: esv1.VolumeClaimTemplatesMigrate,
+			},
+			existing: sset.StatefulSetList{statefulSetWithClaims("es-es-data", "", standardClaim)},
+			want:     "data",
+		},
+		{
+			name: "storage increase is applied through volume expansion",
+			nodeSet: esv1.NodeSet{
+				Name:                               "data",
+				VolumeClaimTemplates:               []corev1.PersistentVolumeClaim{claim("standard", "2Gi")},
+				VolumeClaimTemplatesUpdateStrategy: esv1.VolumeClaimTemplatesMigrate,
+			},
+			existing: sset.StatefulSetList{statefulSetWithClaims("es-es-data", "", standardClaim)},
+			want:     "data",
+		},
+		{
+			name:     "storage class change rejected with the default strategy",
+			nodeSet:  esv1.NodeSet{Name: "data", VolumeClaimTemplates: []corev1.PersistentVolumeClaim{fastClaim}},
+			existing: sset.StatefulSetList{statefulSetWithClaims("es-es-data", "", standardClaim)},
+			want:     "data",
+		},
+		{
+			name: "storage class change with the Migrate strategy: new StatefulSet",
+			nodeSet: esv1.NodeSet{
+				Name:                               "data",
+				VolumeClaimTemplates:               []corev1.PersistentVolumeClaim{fastClaim},
+				VolumeClaimTemplatesUpdateStrategy: esv1.VolumeClaimTemplatesMigrate,
+			},
+			existing: sset.StatefulSetList{statefulSetWithClaims("es-es-data", "", standardClaim)},
+			want:     "data-" + fastSuffix,
+		},
+		{
+			name: "migration in progress: reuse the new StatefulSet",
+			nodeSet: esv1.NodeSet{
+				Name:                               "data",
+				VolumeClaimTemplates:               []corev1.PersistentVolumeClaim{claim("fast", "2Gi")},
+				VolumeClaimTemplatesUpdateStrategy: esv1.VolumeClaimTemplatesMigrate,
+			},
+			existing: sset.StatefulSetList{
+				statefulSetWithClaims("es-es-data", "", standardClaim),
+				statefulSetWithClaims("es-es-data-"+fastSuffix, "data", fastClaim),
+			},
+			want: "data-" + fastSuffix,
+		},
+		{
+			name:    "migrated StatefulSet kept when switching back to the default strategy",
+			nodeSet: esv1.NodeSet{Name: "data", VolumeClaimTemplates: []corev1.PersistentVolumeClaim{fastClaim}},
+			existing: sset.StatefulSetList{
+				statefulSetWithClaims("es-es-data-"+fastSuffix, "data", fastClaim),
+			},
+			want: "data-" + fastSuffix,
+		},
+		{
+			name: "StatefulSets of other NodeSets are ignored",
+			nodeSet: esv1.NodeSet{
+				Name:                               "data",
+				VolumeClaimTemplates:               []corev1.PersistentVolumeClaim{fastClaim},
+				VolumeClaimTemplatesUpdateStrategy: esv1.VolumeClaimTemplatesMigrate,
+			},
+			existing: sset.StatefulSetList{
+				statefulSetWithClaims("es-es-data", "", standardClaim),
+				statefulSetWithClaims("es-es-hot-"+fastSuffix, "hot", fastClaim),
+			},
+			want: "data-" + fastSuffix,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, statefulSetNodeSetName(es, tt.nodeSet, tt.existing))
+		})
+	}
+}
+
+func Test_claimsCompatible(t *testing.T) {
+	block := corev1.PersistentVolumeBlock
+	filesystem := corev1.PersistentVolumeFilesystem
+	withVolumeMode := func(c corev1.PersistentVolumeClaim, mode *corev1.PersistentVolumeMode) corev1.PersistentVolumeClaim {
+		c.Spec.VolumeMode = mode
+		return c
+	}
+	withName := func(c corev1.PersistentVolumeClaim, name string) corev1.PersistentVolumeClaim {
+		c.Name = name
+		return c
+	}
+	tests := []struct {
+		name     string
+		actual   []corev1.PersistentVolumeClaim
+		expected []corev1.PersistentVolumeClaim
+		want     bool
+	}{
+		{
+			name:     "same claims",
+			actual:   []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			want:     true,
+		},
+		{
+			name:     "storage increase",
+			actual:   []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{claim("standard", "2Gi")},
+			want:     true,
+		},
+		{
+			name:     "storage decrease",
+			actual:   []corev1.PersistentVolumeClaim{claim("standard", "2Gi")},
+			expected: []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			want:     false,
+		},
+		{
+			name:     "storage class change",
+			actual:   []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{claim("fast", "1Gi")},
+			want:     false,
+		},
+		{
+			name:     "volume mode defaulted by the API server",
+			actual:   []corev1.PersistentVolumeClaim{withVolumeMode(claim("standard", "1Gi"), &filesystem)},
+			expected: []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			want:     true,
+		},
+		{
+			name:     "volume mode change",
+			actual:   []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{withVolumeMode(claim("standard", "1Gi"), &block)},
+			want:     false,
+		},
+		{
+			name:     "claim renamed",
+			actual:   []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{withName(claim("standard", "1Gi"), "other")},
+			want:     false,
+		},
+		{
+			name:   "claim added",
+			actual: []corev1.PersistentVolumeClaim{claim("standard", "1Gi")},
+			expected: []corev1.PersistentVolumeClaim{
+				claim("standard", "1Gi"), withName(claim("standard", "1Gi"), "other"),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, claimsCompatible(tt.actual, tt.expected))
+		})
+	}
+}
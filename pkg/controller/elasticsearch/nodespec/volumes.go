@@ -19,7 +19,13 @@ import (
 
 var downwardAPIVolume = volume.DownwardAPI{}
 
-func buildVolumes(esName string, nodeSpec esv1.NodeSet, keystoreResources *keystore.Resources) ([]corev1.Volume, []corev1.VolumeMount) {
+func buildVolumes(
+	esName string,
+	nodeSpec esv1.NodeSet,
+	keystoreResources *keystore.Resources,
+	zoneAwareness bool,
+	clientAuthentication bool,
+) ([]corev1.Volume, []corev1.VolumeMount) {
 
 	configVolume := settings.ConfigSecretVolume(esv1.StatefulSet(esName, nodeSpec.Name))
 	probeSecret := volume.NewSelectiveSecretVolumeWithMountPath(
@@ -78,7 +84,8 @@ func buildVolumes(esName string, nodeSpec esv1.NodeSet, keystoreResources *keyst
 			httpCertificatesVolume.Volume(),
 			scriptsVolume.Volume(),
 			configVolume.Volume(),
-			downwardAPIVolume.Volume(),
+			// the Pod annotations hold the zone of the Kubernetes node if zone awareness is enabled
+			downwardAPIVolume.WithAnnotations(zoneAwareness).Volume(),
 		)...)
 	if keystoreResources != nil {
 		volumes = append(volumes, keystoreResources.Volume)
@@ -99,5 +106,16 @@ func buildVolumes(esName string, nodeSpec esv1.NodeSet, keystoreResources *keyst
 		downwardAPIVolume.VolumeMount(),
 	)
 
+	// the trusted client CAs and the client certificate of the readiness probe
+	if clientAuthentication {
+		httpClientCertificatesVolume := volume.NewSecretVolumeWithMountPath(
+			esv1.HTTPClientCertsSecret(esName),
+			esvolume.HTTPClientCertificatesSecretVolumeName,
+			esvolume.HTTPClientCertificatesSecretVolumeMountPath,
+		)
+		volumes = append(volumes, httpClientCertificatesVolume.Volume())
+		volumeMounts = append(volumeMounts, httpClientCertificatesVolume.VolumeMount())
+	}
+
 	return volumes, volumeMounts
 }
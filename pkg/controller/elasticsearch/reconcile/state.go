@@ -5,12 +5,18 @@
 package reconcile
 
 import (
+	"fmt"
 	"reflect"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
@@ -23,9 +29,24 @@ type State struct {
 	status  esv1.ElasticsearchStatus
 }
 
-// NewState creates a new reconcile state based on the given cluster
+// NewState creates a new reconcile state based on the given cluster.
+// In progress upscale, downscale, upgrade and storage migration operations are computed again by each reconciliation
+// and are therefore not carried over from the current status, to not report stale operations if the reconciliation
+// returns before updating them.
 func NewState(c esv1.Elasticsearch) *State {
-	return &State{Recorder: events.NewRecorder(), cluster: c, status: *c.Status.DeepCopy()}
+	status := *c.Status.DeepCopy()
+	status.InProgressOperations.Upscale = nil
+	status.InProgressOperations.Downscale = nil
+	status.InProgressOperations.Upgrade = nil
+	status.InProgressOperations.StorageMigration = nil
+	return &State{Recorder: events.NewRecorder(), cluster: c, status: status}
+}
+
+// UpdateElasticsearchMetadata records the metadata of the Elasticsearch resource updated during the reconciliation,
+// for the status update not to conflict with it.
+func (s *State) UpdateElasticsearchMetadata(meta metav1.ObjectMeta) *State {
+	s.cluster.ObjectMeta = meta
+	return s
 }
 
 // AvailableElasticsearchNodes filters a slice of pods for the ones that are ready.
@@ -112,6 +133,118 @@ func (s *State) Apply() ([]events.Event, *esv1.Elasticsearch) {
 	return s.Events(), &s.cluster
 }
 
+// ReportCondition records the given condition in the Elasticsearch status.
+func (s *State) ReportCondition(conditionType commonv1.ConditionType, status corev1.ConditionStatus, message string) *State {
+	s.status.Conditions = s.status.Conditions.MergeWith(commonv1.Condition{
+		Type:    conditionType,
+		Status:  status,
+		Message: message,
+	})
+	return s
+}
+
+// HasCondition returns true if a condition with the given type is recorded in the Elasticsearch status.
+func (s *State) HasCondition(conditionType commonv1.ConditionType) bool {
+	return s.status.Conditions.Index(conditionType) >= 0
+}
+
+// UpdateAutoscalingStatus records the status of the autoscaled NodeSets.
+func (s *State) UpdateAutoscalingStatus(statuses []esv1.NodeSetAutoscalingStatus) *State {
+	s.status.Autoscaling = statuses
+	return s
+}
+
+// UpdateSnapshotsStatus sets the status of the snapshot lifecycle policies.
+func (s *State) UpdateSnapshotsStatus(statuses []esv1.SnapshotPolicyStatus) *State {
+	s.status.Snapshots = statuses
+	return s
+}
+
+// UpdateUpscaleOperations records the StatefulSets whose nodes creation is postponed.
+func (s *State) UpdateUpscaleOperations(operations []esv1.UpscaleOperation) *State {
+	s.status.InProgressOperations.Upscale = operations
+	return s
+}
+
+// UpdateDownscaleOperations records the nodes to be removed from the cluster.
+func (s *State) UpdateDownscaleOperations(operations []esv1.DownscaleOperation) *State {
+	s.status.InProgressOperations.Downscale = operations
+	return s
+}
+
+// UpdateUpgradeOperations records the nodes to be restarted during a rolling upgrade.
+func (s *State) UpdateUpgradeOperations(operations []esv1.UpgradeOperation) *State {
+	s.status.InProgressOperations.Upgrade = operations
+	return s
+}
+
+// UpdateFullClusterRestartOperation records the full cluster restart in progress, nil if there is none.
+func (s *State) UpdateFullClusterRestartOperation(operation *esv1.FullClusterRestartOperation) *State {
+	s.status.InProgressOperations.FullClusterRestart = operation
+	return s
+}
+
+// UpdatePostponedRestarts records the nodes whose restart waits for the maintenance window starting at nextWindow.
+func (s *State) UpdatePostponedRestarts(nodes []string, nextWindow time.Time) *State {
+	return s.updatePostponedOperations(nextWindow, func(postponed *esv1.PostponedOperations) {
+		postponed.Restarts = nodes
+	})
+}
+
+// UpdatePostponedRemovals records the nodes whose removal waits for the maintenance window starting at nextWindow.
+func (s *State) UpdatePostponedRemovals(nodes []string, nextWindow time.Time) *State {
+	return s.updatePostponedOperations(nextWindow, func(postponed *esv1.PostponedOperations) {
+		postponed.Removals = nodes
+	})
+}
+
+func (s *State) updatePostponedOperations(nextWindow time.Time, update func(*esv1.PostponedOperations)) *State {
+	postponed := &esv1.PostponedOperations{}
+	if s.status.InProgressOperations.Postponed != nil {
+		postponed = s.status.InProgressOperations.Postponed.DeepCopy()
+	}
+	update(postponed)
+	if len(postponed.Restarts) == 0 && len(postponed.Removals) == 0 {
+		s.status.InProgressOperations.Postponed = nil
+		return s
+	}
+	postponed.NextMaintenanceWindow = metav1.NewTime(nextWindow)
+	s.status.InProgressOperations.Postponed = postponed
+	return s
+}
+
+// UpdateStorageMigrationOperations records the NodeSets whose nodes are being replaced to apply a change of their
+// volume claim templates.
+func (s *State) UpdateStorageMigrationOperations(operations []esv1.StorageMigrationOperation) *State {
+	s.status.InProgressOperations.StorageMigration = operations
+	return s
+}
+
+// UpdateRunningVersion records the lowest version running in the Elasticsearch Pods, which is nil if no Pod is running,
+// and whether it matches the desired version.
+func (s *State) UpdateRunningVersion(lowest *version.Version) *State {
+	common.ReportRunningVersion(&s.status.ReconcilerStatus, lowest, s.cluster.Spec.Version)
+	return s
+}
+
+// UpdateElasticsearchReachable records whether the Elasticsearch HTTP API can be reached.
+func (s *State) UpdateElasticsearchReachable(reachable bool) *State {
+	if reachable {
+		return s.ReportCondition(esv1.ElasticsearchReachable, corev1.ConditionTrue, "")
+	}
+	return s.ReportCondition(esv1.ElasticsearchReachable, corev1.ConditionFalse, "Service is not ready")
+}
+
+// UpdateReconciliationStatus reports the reconciliation of the current Elasticsearch generation, see common.UpdateReconciliationStatus.
+// The cluster is ready once it reached the Ready phase, which requires all node changes to be applied.
+func (s *State) UpdateReconciliationStatus(err error) *State {
+	common.UpdateReconciliationStatus(
+		&s.status.ReconcilerStatus, s.cluster.Generation, err,
+		s.status.Phase == esv1.ElasticsearchReadyPhase, fmt.Sprintf("Elasticsearch is in phase %s", s.status.Phase),
+	)
+	return s
+}
+
 func (s *State) UpdateElasticsearchInvalid(err error) {
 	s.status.Phase = esv1.ElasticsearchResourceInvalid
 	s.AddEvent(corev1.EventTypeWarning, events.EventReasonValidation, err.Error())
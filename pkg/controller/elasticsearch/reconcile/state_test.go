@@ -5,17 +5,20 @@
 package reconcile
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/observer"
 )
@@ -200,6 +203,28 @@ func TestState_Apply(t *testing.T) {
 				Phase:  esv1.ElasticsearchApplyingChangesPhase,
 			},
 		},
+		{
+			name: "in progress operations not updated by the reconciliation are cleared",
+			cluster: esv1.Elasticsearch{
+				Status: esv1.ElasticsearchStatus{
+					InProgressOperations: esv1.InProgressOperations{
+						Downscale: []esv1.DownscaleOperation{{Node: "node-2"}},
+						Upgrade:   []esv1.UpgradeOperation{{Node: "node-1", Status: esv1.UpgradeRestarting}},
+						FullClusterRestart: &esv1.FullClusterRestartOperation{
+							Phase: esv1.FullClusterRestartRecovering,
+						},
+					},
+				},
+			},
+			wantEvents: []events.Event{},
+			wantStatus: &esv1.ElasticsearchStatus{
+				InProgressOperations: esv1.InProgressOperations{
+					FullClusterRestart: &esv1.FullClusterRestartOperation{
+						Phase: esv1.FullClusterRestartRecovering,
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -310,3 +335,66 @@ func TestState_UpdateElasticsearchMigrating(t *testing.T) {
 		})
 	}
 }
+
+func TestState_UpdateReconciliationStatus(t *testing.T) {
+	conditionStatus := func(s *State, conditionType commonv1.ConditionType) corev1.ConditionStatus {
+		i := s.status.Conditions.Index(conditionType)
+		if i < 0 {
+			return ""
+		}
+		return s.status.Conditions[i].Status
+	}
+	cluster := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Spec:       esv1.ElasticsearchSpec{Version: "7.8.0"},
+	}
+	tests := []struct {
+		name     string
+		update   func(s *State)
+		err      error
+		complete corev1.ConditionStatus
+	}{
+		{
+			name: "ready and running the desired version",
+			update: func(s *State) {
+				s.UpdateRunningVersion(&version.Version{Major: 7, Minor: 8, Patch: 0})
+				s.UpdateElasticsearchReady(ResourcesState{}, observer.State{})
+			},
+			complete: corev1.ConditionTrue,
+		},
+		{
+			name: "reconciliation error",
+			update: func(s *State) {
+				s.UpdateRunningVersion(&version.Version{Major: 7, Minor: 8, Patch: 0})
+				s.UpdateElasticsearchReady(ResourcesState{}, observer.State{})
+			},
+			err:      errors.New("failure"),
+			complete: corev1.ConditionFalse,
+		},
+		{
+			name: "applying changes",
+			update: func(s *State) {
+				s.UpdateRunningVersion(&version.Version{Major: 7, Minor: 8, Patch: 0})
+				s.UpdateElasticsearchApplyingChanges(nil)
+			},
+			complete: corev1.ConditionFalse,
+		},
+		{
+			name: "upgrade in progress",
+			update: func(s *State) {
+				s.UpdateRunningVersion(&version.Version{Major: 7, Minor: 7, Patch: 0})
+				s.UpdateElasticsearchReady(ResourcesState{}, observer.State{})
+			},
+			complete: corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewState(cluster)
+			tt.update(s)
+			s.UpdateReconciliationStatus(tt.err)
+			assert.Equal(t, int64(2), s.status.ObservedGeneration)
+			assert.Equal(t, tt.complete, conditionStatus(s, commonv1.ReconciliationComplete))
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/license"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
@@ -22,9 +23,18 @@ import (
 
 var log = logf.Log.WithName("remotecluster")
 
-const enterpriseFeaturesDisabledMsg = "Remote cluster is an enterprise feature. Enterprise features are disabled"
+const (
+	enterpriseFeaturesDisabledMsg = "Remote cluster is an enterprise feature. Enterprise features are disabled"
+
+	sniffMode = "sniff"
+	proxyMode = "proxy"
+)
+
+// proxyModeMinVersion is the first Elasticsearch version supporting the proxy mode for remote clusters.
+var proxyModeMinVersion = version.MustParse("7.7.0")
 
 // UpdateSettings updates the remote clusters in the persistent settings by calling the Elasticsearch API.
+// The given version is the lowest version of the Elasticsearch nodes, it is used to know which settings are supported.
 func UpdateSettings(
 	ctx context.Context,
 	c k8s.Client,
@@ -32,6 +42,7 @@ func UpdateSettings(
 	eventRecorder record.EventRecorder,
 	licenseChecker license.Checker,
 	es esv1.Elasticsearch,
+	esVersion version.Version,
 ) error {
 	span, _ := apm.StartSpan(ctx, "update_remote_clusters", tracing.SpanTypeApp)
 	defer span.End()
@@ -55,19 +66,21 @@ func UpdateSettings(
 		return err
 	}
 
+	modeSupported := esVersion.IsSameOrAfter(proxyModeMinVersion)
 	remoteClusters := make(map[string]esclient.RemoteCluster)
 	// RemoteClusters to add or update
 	for name, remoteCluster := range expectedRemoteClusters {
 		if currentConfigHash, ok := currentRemoteClusters[name]; !ok || currentConfigHash != remoteCluster.ConfigHash {
 			// Declare remote cluster in ES
-			seedHosts := []string{services.ExternalTransportServiceHost(remoteCluster.ElasticsearchRef.NamespacedName())}
+			settings := remoteClusterSettings(remoteCluster.RemoteCluster, modeSupported)
 			log.Info("Adding or updating remote cluster",
 				"namespace", es.Namespace,
 				"es_name", es.Name,
 				"remote_cluster", remoteCluster.Name,
-				"seeds", seedHosts,
+				"seeds", settings.Seeds,
+				"proxy_address", remoteCluster.ProxyAddress,
 			)
-			remoteClusters[name] = esclient.RemoteCluster{Seeds: seedHosts}
+			remoteClusters[name] = settings
 		}
 	}
 
@@ -79,7 +92,7 @@ func UpdateSettings(
 				"es_name", es.Name,
 				"remote_cluster", name,
 			)
-			remoteClusters[name] = esclient.RemoteCluster{Seeds: nil}
+			remoteClusters[name] = emptyRemoteClusterSettings(modeSupported)
 		}
 	}
 
@@ -99,10 +112,12 @@ func UpdateSettings(
 func getExpectedRemoteClusters(es esv1.Elasticsearch) map[string]expectedRemoteClusterConfiguration {
 	remoteClusters := make(map[string]expectedRemoteClusterConfiguration)
 	for _, remoteCluster := range es.Spec.RemoteClusters {
-		if !remoteCluster.ElasticsearchRef.IsDefined() {
+		switch {
+		case remoteCluster.ElasticsearchRef.IsDefined():
+			remoteCluster.ElasticsearchRef = remoteCluster.ElasticsearchRef.WithDefaultNamespace(es.Namespace)
+		case !remoteCluster.IsExternal():
 			continue
 		}
-		remoteCluster.ElasticsearchRef = remoteCluster.ElasticsearchRef.WithDefaultNamespace(es.Namespace)
 		remoteClusters[remoteCluster.Name] = expectedRemoteClusterConfiguration{
 			RemoteCluster: remoteCluster,
 			ConfigHash:    remoteCluster.ConfigHash(),
@@ -111,6 +126,47 @@ func getExpectedRemoteClusters(es esv1.Elasticsearch) map[string]expectedRemoteC
 	return remoteClusters
 }
 
+// emptyRemoteClusterSettings returns settings resetting all the settings of a remote cluster, which removes it.
+func emptyRemoteClusterSettings(modeSupported bool) esclient.RemoteCluster {
+	settings := esclient.RemoteCluster{}
+	if modeSupported {
+		settings.RemoteClusterMode = &esclient.RemoteClusterMode{}
+	}
+	return settings
+}
+
+// remoteClusterSettings returns the settings of the given remote cluster. Settings which are not set in the
+// specification are reset, so that options or a connection mode previously set are removed.
+func remoteClusterSettings(remoteCluster esv1.RemoteCluster, modeSupported bool) esclient.RemoteCluster {
+	settings := emptyRemoteClusterSettings(modeSupported)
+	settings.SkipUnavailable = remoteCluster.SkipUnavailable
+	settings.Transport.Compress = remoteCluster.Compress
+	if remoteCluster.PingSchedule != "" {
+		settings.Transport.PingSchedule = &remoteCluster.PingSchedule
+	}
+
+	if remoteCluster.IsProxyMode() {
+		// proxy mode is only accepted by the validation of supported versions
+		mode := proxyMode
+		settings.RemoteClusterMode = &esclient.RemoteClusterMode{Mode: &mode, ProxyAddress: &remoteCluster.ProxyAddress}
+		if remoteCluster.ServerName != "" {
+			settings.ServerName = &remoteCluster.ServerName
+		}
+		return settings
+	}
+
+	if remoteCluster.ElasticsearchRef.IsDefined() {
+		settings.Seeds = []string{services.ExternalTransportServiceHost(remoteCluster.ElasticsearchRef.NamespacedName())}
+	} else {
+		settings.Seeds = remoteCluster.Seeds
+	}
+	if modeSupported {
+		mode := sniffMode
+		settings.Mode = &mode
+	}
+	return settings
+}
+
 // updateSettings makes a call to an Elasticsearch cluster to apply a persistent setting.
 func updateSettings(esClient esclient.Client, remoteClusters map[string]esclient.RemoteCluster) error {
 	ctx, cancel := context.WithTimeout(context.Background(), esclient.DefaultReqTimeout)
@@ -13,6 +13,7 @@ import (
 	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/license"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/stretchr/testify/assert"
@@ -113,10 +114,26 @@ func (fakeLicenseChecker) Valid(_ license.EnterpriseLicense) (bool, error) {
 	return true, nil
 }
 
+func sniffSettings(seeds ...string) esclient.RemoteCluster {
+	mode := "sniff"
+	return esclient.RemoteCluster{Seeds: seeds, RemoteClusterMode: &esclient.RemoteClusterMode{Mode: &mode}}
+}
+
+func emptySettings() esclient.RemoteCluster {
+	return esclient.RemoteCluster{RemoteClusterMode: &esclient.RemoteClusterMode{}}
+}
+
 func TestUpdateSettings(t *testing.T) {
+	trueValue := true
+	pingSchedule := "30s"
+	sniffModeValue := "sniff"
+	proxyModeValue := "proxy"
+	proxyAddress := "proxy.example.com:9300"
+	serverName := "es.example.com"
 	type args struct {
 		esClient       *fakeESClient
 		es             *esv1.Elasticsearch
+		esVersion      string
 		licenseChecker license.Checker
 	}
 	tests := []struct {
@@ -130,6 +147,7 @@ func TestUpdateSettings(t *testing.T) {
 			name: "Create a new remote cluster",
 			args: args{
 				esClient:       &fakeESClient{},
+				esVersion:      "7.7.0",
 				licenseChecker: &fakeLicenseChecker{true},
 				es: newEsWithRemoteClusters(
 					"ns1",
@@ -146,7 +164,7 @@ func TestUpdateSettings(t *testing.T) {
 				PersistentSettings: &esclient.SettingsGroup{
 					Cluster: esclient.RemoteClusters{
 						RemoteClusters: map[string]esclient.RemoteCluster{
-							"ns2-es2": {Seeds: []string{"es2-es-transport.ns2.svc:9300"}},
+							"ns2-es2": sniffSettings("es2-es-transport.ns2.svc:9300"),
 						},
 					},
 				},
@@ -156,6 +174,7 @@ func TestUpdateSettings(t *testing.T) {
 			name: "Create a new remote cluster with no namespace",
 			args: args{
 				esClient:       &fakeESClient{},
+				esVersion:      "7.7.0",
 				licenseChecker: &fakeLicenseChecker{true},
 				es: newEsWithRemoteClusters(
 					"ns1",
@@ -171,7 +190,7 @@ func TestUpdateSettings(t *testing.T) {
 				PersistentSettings: &esclient.SettingsGroup{
 					Cluster: esclient.RemoteClusters{
 						RemoteClusters: map[string]esclient.RemoteCluster{
-							"ns1-es2": {Seeds: []string{"es2-es-transport.ns1.svc:9300"}},
+							"ns1-es2": sniffSettings("es2-es-transport.ns1.svc:9300"),
 						},
 					},
 				},
@@ -181,12 +200,13 @@ func TestUpdateSettings(t *testing.T) {
 			name: "Remote cluster already exists, do not make an API call",
 			args: args{
 				esClient:       &fakeESClient{},
+				esVersion:      "7.7.0",
 				licenseChecker: &fakeLicenseChecker{true},
 				es: newEsWithRemoteClusters(
 					"ns1",
 					"es1",
 					map[string]string{
-						"elasticsearch.k8s.elastic.co/remote-clusters": `{"ns1-es2":"2221154215"}`,
+						"elasticsearch.k8s.elastic.co/remote-clusters": `{"ns1-es2":"3939274378"}`,
 					},
 					esv1.RemoteCluster{
 						Name:             "ns1-es2",
@@ -199,6 +219,7 @@ func TestUpdateSettings(t *testing.T) {
 			name: "Remote cluster already exists but has been updated, we should make an API call",
 			args: args{
 				esClient:       &fakeESClient{},
+				esVersion:      "7.7.0",
 				licenseChecker: &fakeLicenseChecker{true},
 				es: newEsWithRemoteClusters(
 					"ns1",
@@ -216,7 +237,7 @@ func TestUpdateSettings(t *testing.T) {
 				PersistentSettings: &esclient.SettingsGroup{
 					Cluster: esclient.RemoteClusters{
 						RemoteClusters: map[string]esclient.RemoteCluster{
-							"ns1-es2": {Seeds: []string{"es2-es-transport.ns1.svc:9300"}},
+							"ns1-es2": sniffSettings("es2-es-transport.ns1.svc:9300"),
 						},
 					},
 				},
@@ -226,12 +247,13 @@ func TestUpdateSettings(t *testing.T) {
 			name: "Remove existing cluster",
 			args: args{
 				esClient:       &fakeESClient{},
+				esVersion:      "7.7.0",
 				licenseChecker: &fakeLicenseChecker{true},
 				es: newEsWithRemoteClusters(
 					"ns1",
 					"es1",
 					map[string]string{
-						"elasticsearch.k8s.elastic.co/remote-clusters": `{"to-be-deleted":"8538658922","ns1-es2":"2221154215"}`,
+						"elasticsearch.k8s.elastic.co/remote-clusters": `{"to-be-deleted":"8538658922","ns1-es2":"3939274378"}`,
 					},
 					esv1.RemoteCluster{
 						Name:             "ns1-es2",
@@ -243,7 +265,7 @@ func TestUpdateSettings(t *testing.T) {
 				PersistentSettings: &esclient.SettingsGroup{
 					Cluster: esclient.RemoteClusters{
 						RemoteClusters: map[string]esclient.RemoteCluster{
-							"to-be-deleted": {Seeds: nil},
+							"to-be-deleted": emptySettings(),
 						},
 					},
 				},
@@ -253,6 +275,7 @@ func TestUpdateSettings(t *testing.T) {
 			name: "No valid license to create a new remote cluster",
 			args: args{
 				esClient:       &fakeESClient{},
+				esVersion:      "7.7.0",
 				licenseChecker: &fakeLicenseChecker{false},
 				es: newEsWithRemoteClusters(
 					"ns1",
@@ -269,6 +292,7 @@ func TestUpdateSettings(t *testing.T) {
 			name: "Multiple changes in one call: remote cluster already exists but has been updated, one is added and a last one is removed.",
 			args: args{
 				esClient:       &fakeESClient{},
+				esVersion:      "7.7.0",
 				licenseChecker: &fakeLicenseChecker{true},
 				es: newEsWithRemoteClusters(
 					"ns1",
@@ -291,9 +315,87 @@ func TestUpdateSettings(t *testing.T) {
 				PersistentSettings: &esclient.SettingsGroup{
 					Cluster: esclient.RemoteClusters{
 						RemoteClusters: map[string]esclient.RemoteCluster{
-							"ns1-es2": {Seeds: []string{"es2-es-transport.ns1.svc:9300"}},
-							"ns1-es5": {Seeds: nil},
-							"ns1-es4": {Seeds: []string{"es4-es-transport.ns1.svc:9300"}},
+							"ns1-es2": sniffSettings("es2-es-transport.ns1.svc:9300"),
+							"ns1-es5": emptySettings(),
+							"ns1-es4": sniffSettings("es4-es-transport.ns1.svc:9300"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Create remote clusters running outside of Kubernetes, in sniff and proxy modes",
+			args: args{
+				esClient:       &fakeESClient{},
+				esVersion:      "7.7.0",
+				licenseChecker: &fakeLicenseChecker{true},
+				es: newEsWithRemoteClusters(
+					"ns1",
+					"es1",
+					nil,
+					esv1.RemoteCluster{
+						Name:                   "sniff",
+						Seeds:                  []string{"10.0.0.1:9300", "10.0.0.2:9300"},
+						CertificateAuthorities: commonv1.SecretRef{SecretName: "remote-ca"},
+						SkipUnavailable:        &trueValue,
+						Compress:               &trueValue,
+						PingSchedule:           "30s",
+					},
+					esv1.RemoteCluster{
+						Name:         "proxy",
+						ProxyAddress: "proxy.example.com:9300",
+						ServerName:   "es.example.com",
+					},
+				),
+			},
+			wantEsCalled: true,
+			wantSettings: esclient.RemoteClustersSettings{
+				PersistentSettings: &esclient.SettingsGroup{
+					Cluster: esclient.RemoteClusters{
+						RemoteClusters: map[string]esclient.RemoteCluster{
+							"sniff": {
+								Seeds:             []string{"10.0.0.1:9300", "10.0.0.2:9300"},
+								SkipUnavailable:   &trueValue,
+								Transport:         esclient.RemoteClusterTransport{Compress: &trueValue, PingSchedule: &pingSchedule},
+								RemoteClusterMode: &esclient.RemoteClusterMode{Mode: &sniffModeValue},
+							},
+							"proxy": {
+								RemoteClusterMode: &esclient.RemoteClusterMode{
+									Mode:         &proxyModeValue,
+									ProxyAddress: &proxyAddress,
+									ServerName:   &serverName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Connection mode settings are not supported before 7.7.0",
+			args: args{
+				esClient:       &fakeESClient{},
+				esVersion:      "6.8.0",
+				licenseChecker: &fakeLicenseChecker{true},
+				es: newEsWithRemoteClusters(
+					"ns1",
+					"es1",
+					map[string]string{
+						"elasticsearch.k8s.elastic.co/remote-clusters": `{"to-be-deleted":"8538658922"}`,
+					},
+					esv1.RemoteCluster{
+						Name:  "sniff",
+						Seeds: []string{"10.0.0.1:9300"},
+					},
+				),
+			},
+			wantEsCalled: true,
+			wantSettings: esclient.RemoteClustersSettings{
+				PersistentSettings: &esclient.SettingsGroup{
+					Cluster: esclient.RemoteClusters{
+						RemoteClusters: map[string]esclient.RemoteCluster{
+							"sniff":         {Seeds: []string{"10.0.0.1:9300"}},
+							"to-be-deleted": {},
 						},
 					},
 				},
@@ -310,6 +412,7 @@ func TestUpdateSettings(t *testing.T) {
 				record.NewFakeRecorder(100),
 				tt.args.licenseChecker,
 				*tt.args.es,
+				version.MustParse(tt.args.esVersion),
 			); (err != nil) != tt.wantErr {
 				t.Errorf("UpdateRemoteClusterSettings() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -108,6 +108,8 @@ func deleteAllRemoteCa(ctx context.Context, r *ReconcileRemoteCa, es types.Names
 			results.WithError(err)
 		}
 	}
+	// copies of the CAs of remote clusters running outside of Kubernetes are garbage collected with their owner
+	r.watches.Secrets.RemoveHandlerForKey(externalWatchName(es))
 	return results.Aggregate()
 }
 
@@ -127,7 +129,8 @@ func doReconcile(
 	if err != nil {
 		return defaultRequeue, err
 	}
-	if !enabled && len(expectedRemoteClusters) > 0 {
+	externalRemoteClusters := externalCertificateAuthorities(localEs)
+	if !enabled && (len(expectedRemoteClusters) > 0 || len(externalRemoteClusters) > 0) {
 		log.V(1).Info(
 			"Remote cluster controller is an enterprise feature. Enterprise features are disabled",
 			"namespace", localEs.Namespace, "es_name", localEs.Name,
@@ -170,6 +173,12 @@ func doReconcile(
 		}
 	}
 
+	// Create, update or delete the CAs of remote clusters running outside of Kubernetes
+	results.WithResults(reconcileExternalCertificateAuthorities(ctx, r, localEs))
+	if results.HasError() {
+		return results.Aggregate()
+	}
+
 	// Delete existing but not expected remote CA
 	for toDelete := range remoteClustersInvolved {
 		log.V(1).Info("Deleting remote CA",
@@ -255,6 +264,10 @@ func remoteClustersInvolvedWith(
 		return nil, err
 	}
 	for _, remoteCA := range remoteCAList.Items {
+		if _, external := remoteCA.Labels[RemoteClusterExternalLabelName]; external {
+			// CAs of remote clusters running outside of Kubernetes are not part of a trust relationship
+			continue
+		}
 		remoteNs := remoteCA.Labels[RemoteClusterNamespaceLabelName]
 		remoteEs := remoteCA.Labels[RemoteClusterNameLabelName]
 		currentRemoteClusters[types.NamespacedName{
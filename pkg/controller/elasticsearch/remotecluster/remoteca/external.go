@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remoteca
+
+import (
+	"context"
+	"fmt"
+
+	"go.elastic.co/apm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// EventReasonExternalCaCertNotFound is the reason of the event emitted when the CA of a remote cluster running
+	// outside of Kubernetes cannot be found.
+	EventReasonExternalCaCertNotFound = "ExternalCaCertNotFound"
+)
+
+// externalWatchName is the name of the watch on the secrets holding the CAs of the remote clusters running outside
+// of Kubernetes.
+func externalWatchName(es types.NamespacedName) string {
+	return fmt.Sprintf("%s-%s-external-remote-ca", es.Namespace, es.Name)
+}
+
+// externalCertificateAuthorities returns the remote clusters running outside of Kubernetes for which a CA is provided.
+func externalCertificateAuthorities(es *esv1.Elasticsearch) []esv1.RemoteCluster {
+	var remoteClusters []esv1.RemoteCluster
+	for _, remoteCluster := range es.Spec.RemoteClusters {
+		if remoteCluster.IsExternal() && remoteCluster.CertificateAuthorities.SecretName != "" {
+			remoteClusters = append(remoteClusters, remoteCluster)
+		}
+	}
+	return remoteClusters
+}
+
+// reconcileExternalCertificateAuthorities copies the CAs of the remote clusters running outside of Kubernetes from
+// the secrets provided by the user, so that they are trusted by the local cluster along with the other remote CAs.
+// Copies of CAs which are not expected anymore are deleted.
+func reconcileExternalCertificateAuthorities(
+	ctx context.Context,
+	r *ReconcileRemoteCa,
+	es *esv1.Elasticsearch,
+) *reconciler.Results {
+	span, _ := apm.StartSpan(ctx, "reconcile_external_remote_ca", tracing.SpanTypeApp)
+	defer span.End()
+	results := &reconciler.Results{}
+	esKey := k8s.ExtractNamespacedName(es)
+
+	remoteClusters := externalCertificateAuthorities(es)
+	userSecrets := make([]string, 0, len(remoteClusters))
+	for _, remoteCluster := range remoteClusters {
+		userSecrets = append(userSecrets, remoteCluster.CertificateAuthorities.SecretName)
+	}
+	// user-provided secrets are watched, changes to their content trigger a new reconciliation
+	if err := watches.WatchUserProvidedSecrets(esKey, r.watches, externalWatchName(esKey), userSecrets); err != nil {
+		return results.WithError(err)
+	}
+
+	expected := make(map[string]struct{}, len(remoteClusters))
+	for _, remoteCluster := range remoteClusters {
+		secretName := externalRemoteCASecretName(es.Name, remoteCluster.Name)
+		expected[secretName] = struct{}{}
+
+		var userSecret corev1.Secret
+		userSecretKey := types.NamespacedName{Namespace: es.Namespace, Name: remoteCluster.CertificateAuthorities.SecretName}
+		if err := r.Client.Get(userSecretKey, &userSecret); err != nil && !errors.IsNotFound(err) {
+			return results.WithError(err)
+		}
+		ca := userSecret.Data[certificates.CAFileName]
+		if len(ca) == 0 {
+			msg := fmt.Sprintf(
+				"Cannot find CA certificate %s of remote cluster %s in secret %s/%s",
+				certificates.CAFileName, remoteCluster.Name, userSecretKey.Namespace, userSecretKey.Name,
+			)
+			log.Info(msg, "namespace", es.Namespace, "es_name", es.Name)
+			r.recorder.Event(es, corev1.EventTypeWarning, EventReasonExternalCaCertNotFound, msg)
+			// keep any existing copy until the secret is fixed, it will trigger a new reconciliation
+			continue
+		}
+
+		expectedCopy := corev1.Secret{
+			ObjectMeta: externalRemoteCAObjectMeta(secretName, es, remoteCluster.Name),
+			Data: map[string][]byte{
+				certificates.CAFileName: ca,
+			},
+		}
+		if _, err := reconciler.ReconcileSecret(r.Client, expectedCopy, es); err != nil {
+			return results.WithError(err)
+		}
+	}
+
+	// delete the copies of CAs not expected anymore
+	var copies corev1.SecretList
+	if err := r.Client.List(&copies, client.InNamespace(es.Namespace), externalLabelSelector(es.Name)); err != nil {
+		return results.WithError(err)
+	}
+	for i := range copies.Items {
+		if _, exists := expected[copies.Items[i].Name]; exists {
+			continue
+		}
+		log.V(1).Info("Deleting external remote CA",
+			"namespace", es.Namespace,
+			"es_name", es.Name,
+			"remote_cluster", copies.Items[i].Labels[RemoteClusterNameLabelName],
+		)
+		if err := r.Client.Delete(&copies.Items[i]); err != nil && !errors.IsNotFound(err) {
+			results.WithError(err)
+		}
+	}
+	return results
+}
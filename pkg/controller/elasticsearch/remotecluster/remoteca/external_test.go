@@ -0,0 +1,158 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remoteca
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+func esWithExternalRemoteClusters(remoteClusters ...esv1.RemoteCluster) *esv1.Elasticsearch {
+	return &esv1.Elasticsearch{
+		ObjectMeta: v1.ObjectMeta{Namespace: "ns1", Name: "es1"},
+		Spec:       esv1.ElasticsearchSpec{RemoteClusters: remoteClusters},
+	}
+}
+
+func userCASecret(name string, ca string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "ns1", Name: name},
+		Data:       map[string][]byte{certificates.CAFileName: []byte(ca)},
+	}
+}
+
+func externalRemoteCa(remoteClusterName string, ca string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "ns1",
+			Name:      externalRemoteCASecretName("es1", remoteClusterName),
+			Labels: map[string]string{
+				"common.k8s.elastic.co/type":                           "remote-ca",
+				"elasticsearch.k8s.elastic.co/cluster-name":            "es1",
+				"elasticsearch.k8s.elastic.co/remote-cluster-name":     remoteClusterName,
+				"elasticsearch.k8s.elastic.co/remote-cluster-external": "true",
+			},
+		},
+		Data: map[string][]byte{certificates.CAFileName: []byte(ca)},
+	}
+}
+
+func TestReconcileRemoteCa_ExternalRemoteClusters(t *testing.T) {
+	sniff := esv1.RemoteCluster{
+		Name:                   "sniff",
+		Seeds:                  []string{"10.0.0.1:9300"},
+		CertificateAuthorities: commonv1.SecretRef{SecretName: "sniff-ca"},
+	}
+	proxy := esv1.RemoteCluster{
+		Name:                   "proxy",
+		ProxyAddress:           "proxy.example.com:9300",
+		CertificateAuthorities: commonv1.SecretRef{SecretName: "proxy-ca"},
+	}
+	tests := []struct {
+		name              string
+		objects           []runtime.Object
+		expectedSecrets   []*corev1.Secret
+		unexpectedSecrets []string
+		wantWatch         bool
+	}{
+		{
+			name: "Copy the CAs provided by the user",
+			objects: []runtime.Object{
+				esWithExternalRemoteClusters(sniff, proxy),
+				userCASecret("sniff-ca", "sniff-ca-cert"),
+				userCASecret("proxy-ca", "proxy-ca-cert"),
+			},
+			expectedSecrets: []*corev1.Secret{
+				externalRemoteCa("sniff", "sniff-ca-cert"),
+				externalRemoteCa("proxy", "proxy-ca-cert"),
+			},
+			wantWatch: true,
+		},
+		{
+			name: "Update the copy of a CA",
+			objects: []runtime.Object{
+				esWithExternalRemoteClusters(sniff),
+				userCASecret("sniff-ca", "new-sniff-ca-cert"),
+				externalRemoteCa("sniff", "sniff-ca-cert"),
+			},
+			expectedSecrets: []*corev1.Secret{
+				externalRemoteCa("sniff", "new-sniff-ca-cert"),
+			},
+			wantWatch: true,
+		},
+		{
+			name: "User secret does not exist yet",
+			objects: []runtime.Object{
+				esWithExternalRemoteClusters(sniff),
+			},
+			unexpectedSecrets: []string{externalRemoteCASecretName("es1", "sniff")},
+			wantWatch:         true,
+		},
+		{
+			name: "Remote cluster removed from the specification",
+			objects: []runtime.Object{
+				esWithExternalRemoteClusters(sniff),
+				userCASecret("sniff-ca", "sniff-ca-cert"),
+				externalRemoteCa("sniff", "sniff-ca-cert"),
+				externalRemoteCa("proxy", "proxy-ca-cert"),
+			},
+			expectedSecrets: []*corev1.Secret{
+				externalRemoteCa("sniff", "sniff-ca-cert"),
+			},
+			unexpectedSecrets: []string{externalRemoteCASecretName("es1", "proxy")},
+			wantWatch:         true,
+		},
+		{
+			name: "All remote clusters removed from the specification",
+			objects: []runtime.Object{
+				esWithExternalRemoteClusters(),
+				externalRemoteCa("sniff", "sniff-ca-cert"),
+			},
+			unexpectedSecrets: []string{externalRemoteCASecretName("es1", "sniff")},
+			wantWatch:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ReconcileRemoteCa{
+				Client:         k8s.WrappedFakeClient(tt.objects...),
+				accessReviewer: &fakeAccessReviewer{allowed: true},
+				watches:        watches.NewDynamicWatches(),
+				licenseChecker: &fakeLicenseChecker{enterpriseFeaturesEnabled: true},
+				recorder:       record.NewFakeRecorder(10),
+			}
+			_, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "es1"}})
+			require.NoError(t, err)
+
+			for _, expectedSecret := range tt.expectedSecrets {
+				var actualSecret corev1.Secret
+				require.NoError(t, r.Client.Get(k8s.ExtractNamespacedName(expectedSecret), &actualSecret))
+				require.Equal(t, expectedSecret.Data, actualSecret.Data)
+				require.Equal(t, expectedSecret.Labels, actualSecret.Labels)
+			}
+			for _, unexpectedSecret := range tt.unexpectedSecrets {
+				var actualSecret corev1.Secret
+				err := r.Client.Get(types.NamespacedName{Namespace: "ns1", Name: unexpectedSecret}, &actualSecret)
+				require.True(t, apierrors.IsNotFound(err))
+			}
+			require.Equal(t, tt.wantWatch, stringsutil.StringInSlice("ns1-es1-external-remote-ca", r.watches.Secrets.Registrations()))
+		})
+	}
+}
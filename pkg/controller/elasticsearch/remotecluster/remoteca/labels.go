@@ -22,8 +22,12 @@ const (
 	RemoteClusterNameLabelName = "elasticsearch.k8s.elastic.co/remote-cluster-name"
 	// TypeLabelValue is a type used to identify a Secret which contains the CA of a remote cluster.
 	TypeLabelValue = "remote-ca"
+	// RemoteClusterExternalLabelName marks a Secret which contains the CA of a remote cluster running outside of Kubernetes.
+	RemoteClusterExternalLabelName = "elasticsearch.k8s.elastic.co/remote-cluster-external"
 	// remoteCASecretSuffix is the suffix added to the aforementioned Secret.
 	remoteCASecretSuffix = "remote-ca"
+	// externalRemoteCASecretSuffix is the suffix added to the Secret which contains the CA of an external remote cluster.
+	externalRemoteCASecretSuffix = "ext-remote-ca"
 )
 
 func remoteCAObjectMeta(
@@ -43,6 +47,22 @@ func remoteCAObjectMeta(
 	}
 }
 
+// externalRemoteCAObjectMeta returns the metadata of the Secret that contains the CA of a remote cluster running
+// outside of Kubernetes. The Secret is not linked to a remote Elasticsearch resource, the name of the remote cluster
+// is used instead.
+func externalRemoteCAObjectMeta(name string, owner *esv1.Elasticsearch, remoteClusterName string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: owner.Namespace,
+		Labels: map[string]string{
+			RemoteClusterNameLabelName:     remoteClusterName,
+			RemoteClusterExternalLabelName: "true",
+			label.ClusterNameLabelName:     owner.Name,
+			common.TypeLabelName:           TypeLabelValue,
+		},
+	}
+}
+
 // RemoteCASecretName returns the name of the Secret that contains the transport CA of a remote cluster
 func remoteCASecretName(
 	localClusterName string,
@@ -54,6 +74,23 @@ func remoteCASecretName(
 	)
 }
 
+// externalRemoteCASecretName returns the name of the Secret that contains the CA of a remote cluster running outside
+// of Kubernetes.
+func externalRemoteCASecretName(localClusterName string, remoteClusterName string) string {
+	return esv1.ESNamer.Suffix(
+		fmt.Sprintf("%s-%s", localClusterName, remoteClusterName),
+		externalRemoteCASecretSuffix,
+	)
+}
+
+// externalLabelSelector returns labels matching the Secrets that contain the CAs of the remote clusters running
+// outside of Kubernetes.
+func externalLabelSelector(esName string) client.MatchingLabels {
+	labels := LabelSelector(esName)
+	labels[RemoteClusterExternalLabelName] = "true"
+	return labels
+}
+
 func LabelSelector(esName string) client.MatchingLabels {
 	return map[string]string{
 		label.ClusterNameLabelName: esName,
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package security
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// ManagedSecurityAnnotationName stores the names of the roles, users and role mappings created by the operator,
+	// along with a hash of their last applied definition, so they can be deleted from Elasticsearch once the
+	// corresponding resources are removed.
+	ManagedSecurityAnnotationName = "elasticsearch.k8s.elastic.co/managed-security"
+)
+
+// managedSecurity are the roles, users and role mappings managed by the operator, indexed by their name in
+// Elasticsearch. Values are the hash of their last applied definition.
+type managedSecurity struct {
+	Roles        map[string]string `json:"roles,omitempty"`
+	Users        map[string]string `json:"users,omitempty"`
+	RoleMappings map[string]string `json:"roleMappings,omitempty"`
+}
+
+func (m managedSecurity) isEmpty() bool {
+	return len(m.Roles) == 0 && len(m.Users) == 0 && len(m.RoleMappings) == 0
+}
+
+// getManagedSecurity returns the roles, users and role mappings previously created by the operator.
+func getManagedSecurity(es esv1.Elasticsearch) (managedSecurity, error) {
+	var managed managedSecurity
+	serialized, ok := es.Annotations[ManagedSecurityAnnotationName]
+	if !ok {
+		return managed, nil
+	}
+	if err := json.Unmarshal([]byte(serialized), &managed); err != nil {
+		return managed, err
+	}
+	return managed, nil
+}
+
+// annotateWithManagedSecurity stores the given managed roles, users and role mappings in an annotation of the
+// Elasticsearch resource. The resource is only updated if they changed.
+func annotateWithManagedSecurity(c k8s.Client, es *esv1.Elasticsearch, managed managedSecurity) error {
+	current, err := getManagedSecurity(*es)
+	if err == nil && reflect.DeepEqual(current, managed) {
+		return nil
+	}
+
+	if managed.isEmpty() {
+		if _, exists := es.Annotations[ManagedSecurityAnnotationName]; !exists {
+			return nil
+		}
+		delete(es.Annotations, ManagedSecurityAnnotationName)
+		return c.Update(es)
+	}
+
+	serialized, err := json.Marshal(managed)
+	if err != nil {
+		return errors.Wrapf(err, "failed to serialize managed security resources")
+	}
+	if es.Annotations == nil {
+		es.Annotations = make(map[string]string)
+	}
+	es.Annotations[ManagedSecurityAnnotationName] = string(serialized)
+	return c.Update(es)
+}
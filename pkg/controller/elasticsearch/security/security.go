@@ -0,0 +1,361 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.elastic.co/apm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/hash"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var log = logf.Log.WithName("security")
+
+// PasswordSecretsWatchName returns the name of the watch on the secrets holding the passwords of the users
+// of the given Elasticsearch cluster.
+func PasswordSecretsWatchName(es types.NamespacedName) string {
+	return fmt.Sprintf("%s-%s-security-user-passwords", es.Namespace, es.Name)
+}
+
+// expectedState is the expected state of a role, user or role mapping in Elasticsearch.
+type expectedState struct {
+	// hash of the definition, used to skip the API call if it did not change since it was last applied
+	hash string
+	// apply creates or updates the resource in Elasticsearch
+	apply func(ctx context.Context) error
+}
+
+// Reconcile creates, updates or deletes the roles, users and role mappings of the given Elasticsearch cluster
+// to match the ElasticsearchRole, ElasticsearchUser and ElasticsearchRoleMapping resources referencing it.
+// The result of the synchronization is reported in the status of each resource.
+// The names of the managed roles, users and role mappings are stored in an annotation of the Elasticsearch resource,
+// so they can be deleted once the corresponding resources are removed.
+func Reconcile(
+	ctx context.Context,
+	c k8s.Client,
+	esClient esclient.Client,
+	dynamicWatches watches.DynamicWatches,
+	es *esv1.Elasticsearch,
+) error {
+	span, ctx := apm.StartSpan(ctx, "reconcile_security", tracing.SpanTypeApp)
+	defer span.End()
+
+	previous, err := getManagedSecurity(*es)
+	if err != nil {
+		return err
+	}
+	roles, users, mappings, err := referencingResources(c, *es)
+	if err != nil {
+		return err
+	}
+
+	// user passwords are watched, changes to their content trigger a new reconciliation
+	passwordSecrets := make([]string, 0, len(users))
+	for _, user := range users {
+		passwordSecrets = append(passwordSecrets, user.Spec.PasswordSecret.SecretName)
+	}
+	if err := watches.WatchUserProvidedSecrets(
+		k8s.ExtractNamespacedName(es), dynamicWatches, PasswordSecretsWatchName(k8s.ExtractNamespacedName(es)), passwordSecrets,
+	); err != nil {
+		return err
+	}
+
+	var errs []error
+	managed := managedSecurity{}
+
+	// roles must exist before users and role mappings can reference them
+	managed.Roles, err = syncResources(ctx, c, rolesAsResources(roles), previous.Roles, func(resource esv1.SecurityResource) (expectedState, error) {
+		return expectedRole(esClient, resource.(*esv1.ElasticsearchRole)), nil
+	})
+	errs = append(errs, err)
+	managed.Users, err = syncResources(ctx, c, usersAsResources(users), previous.Users, func(resource esv1.SecurityResource) (expectedState, error) {
+		return expectedUser(c, esClient, resource.(*esv1.ElasticsearchUser))
+	})
+	errs = append(errs, err)
+	managed.RoleMappings, err = syncResources(ctx, c, mappingsAsResources(mappings), previous.RoleMappings, func(resource esv1.SecurityResource) (expectedState, error) {
+		return expectedRoleMapping(esClient, resource.(*esv1.ElasticsearchRoleMapping)), nil
+	})
+	errs = append(errs, err)
+
+	// role mappings and users must be deleted before the roles they reference
+	errs = append(errs, deleteRemoved(ctx, es, "role mapping", previous.RoleMappings, &managed.RoleMappings, esClient.DeleteRoleMapping))
+	errs = append(errs, deleteRemoved(ctx, es, "user", previous.Users, &managed.Users, esClient.DeleteUser))
+	errs = append(errs, deleteRemoved(ctx, es, "role", previous.Roles, &managed.Roles, esClient.DeleteRole))
+
+	errs = append(errs, annotateWithManagedSecurity(c, es, managed))
+	return utilerrors.NewAggregate(errs)
+}
+
+// referencingResources returns the roles, users and role mappings referencing the given Elasticsearch cluster.
+// Only resources in the namespace of the Elasticsearch cluster are considered.
+func referencingResources(c k8s.Client, es esv1.Elasticsearch) (
+	[]esv1.ElasticsearchRole, []esv1.ElasticsearchUser, []esv1.ElasticsearchRoleMapping, error,
+) {
+	esKey := k8s.ExtractNamespacedName(&es)
+	references := func(resource esv1.SecurityResource) bool {
+		return resource.ElasticsearchRef().NamespacedName() == esKey
+	}
+
+	var roleList esv1.ElasticsearchRoleList
+	if err := c.List(&roleList, client.InNamespace(es.Namespace)); err != nil {
+		return nil, nil, nil, err
+	}
+	var roles []esv1.ElasticsearchRole
+	for i := range roleList.Items {
+		if references(&roleList.Items[i]) {
+			roles = append(roles, roleList.Items[i])
+		}
+	}
+
+	var userList esv1.ElasticsearchUserList
+	if err := c.List(&userList, client.InNamespace(es.Namespace)); err != nil {
+		return nil, nil, nil, err
+	}
+	var users []esv1.ElasticsearchUser
+	for i := range userList.Items {
+		if references(&userList.Items[i]) {
+			users = append(users, userList.Items[i])
+		}
+	}
+
+	var mappingList esv1.ElasticsearchRoleMappingList
+	if err := c.List(&mappingList, client.InNamespace(es.Namespace)); err != nil {
+		return nil, nil, nil, err
+	}
+	var mappings []esv1.ElasticsearchRoleMapping
+	for i := range mappingList.Items {
+		if references(&mappingList.Items[i]) {
+			mappings = append(mappings, mappingList.Items[i])
+		}
+	}
+	return roles, users, mappings, nil
+}
+
+func rolesAsResources(roles []esv1.ElasticsearchRole) []esv1.SecurityResource {
+	resources := make([]esv1.SecurityResource, len(roles))
+	for i := range roles {
+		resources[i] = &roles[i]
+	}
+	return resources
+}
+
+func usersAsResources(users []esv1.ElasticsearchUser) []esv1.SecurityResource {
+	resources := make([]esv1.SecurityResource, len(users))
+	for i := range users {
+		resources[i] = &users[i]
+	}
+	return resources
+}
+
+func mappingsAsResources(mappings []esv1.ElasticsearchRoleMapping) []esv1.SecurityResource {
+	resources := make([]esv1.SecurityResource, len(mappings))
+	for i := range mappings {
+		resources[i] = &mappings[i]
+	}
+	return resources
+}
+
+// syncResources creates or updates the given resources in Elasticsearch if their definition changed since they were
+// last applied, and updates their status accordingly. It returns the hashes of the resources now managed by the
+// operator. If several resources have the same name in Elasticsearch, only the oldest one is applied.
+func syncResources(
+	ctx context.Context,
+	c k8s.Client,
+	resources []esv1.SecurityResource,
+	previous map[string]string,
+	expected func(resource esv1.SecurityResource) (expectedState, error),
+) (map[string]string, error) {
+	// the oldest resource wins in case of duplicates
+	sort.SliceStable(resources, func(i, j int) bool {
+		ti, tj := resources[i].GetCreationTimestamp(), resources[j].GetCreationTimestamp()
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return resources[i].GetName() < resources[j].GetName()
+	})
+
+	var errs []error
+	managed := make(map[string]string)
+	for _, resource := range resources {
+		name := resource.SecurityName()
+		if _, exists := managed[name]; exists {
+			errs = append(errs, updateStatus(c, resource, esv1.SecuritySyncError,
+				fmt.Sprintf("%s is already defined by another resource", name)))
+			continue
+		}
+
+		state, err := expected(resource)
+		if err != nil {
+			if _, wasManaged := previous[name]; wasManaged {
+				// keep track of it, but force the next update
+				managed[name] = ""
+			}
+			errs = append(errs, updateStatus(c, resource, esv1.SecuritySyncError, err.Error()))
+			continue
+		}
+		if previous[name] == state.hash {
+			managed[name] = state.hash
+			errs = append(errs, updateStatus(c, resource, esv1.SecuritySyncSynced, ""))
+			continue
+		}
+
+		log.Info("Creating or updating security resource",
+			"namespace", resource.GetNamespace(), "name", resource.GetName(), "security_name", name)
+		if err := withTimeout(ctx, state.apply); err != nil {
+			// the resource may have been created, keep track of it but force the next update
+			managed[name] = ""
+			errs = append(errs, err, updateStatus(c, resource, esv1.SecuritySyncError, err.Error()))
+			continue
+		}
+		managed[name] = state.hash
+		errs = append(errs, updateStatus(c, resource, esv1.SecuritySyncSynced, ""))
+	}
+	if len(managed) == 0 {
+		managed = nil
+	}
+	return managed, utilerrors.NewAggregate(errs)
+}
+
+// deleteRemoved deletes from Elasticsearch the resources which were previously managed but are not expected anymore.
+// Resources which cannot be deleted are kept in the managed resources, so the deletion is retried.
+func deleteRemoved(
+	ctx context.Context,
+	es *esv1.Elasticsearch,
+	kind string,
+	previous map[string]string,
+	managed *map[string]string,
+	deleteFunc func(ctx context.Context, name string) error,
+) error {
+	names := make([]string, 0, len(previous))
+	for name := range previous {
+		if _, exists := (*managed)[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		log.Info("Deleting security resource", "namespace", es.Namespace, "es_name", es.Name, "kind", kind, "security_name", name)
+		if err := withTimeout(ctx, func(ctx context.Context) error {
+			return deleteFunc(ctx, name)
+		}); err != nil && !esclient.IsNotFound(err) {
+			if *managed == nil {
+				*managed = make(map[string]string)
+			}
+			(*managed)[name] = previous[name]
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// expectedRole returns the expected state of the given role.
+func expectedRole(esClient esclient.Client, role *esv1.ElasticsearchRole) expectedState {
+	definition := esclient.RoleDescriptor(configData(role.Spec.Definition))
+	return expectedState{
+		hash: hash.HashObject(definition),
+		apply: func(ctx context.Context) error {
+			return esClient.UpsertRole(ctx, role.SecurityName(), definition)
+		},
+	}
+}
+
+// expectedUser returns the expected state of the given user, including its password.
+func expectedUser(c k8s.Client, esClient esclient.Client, user *esv1.ElasticsearchUser) (expectedState, error) {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: user.Namespace, Name: user.Spec.PasswordSecret.SecretName}
+	if err := c.Get(secretKey, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return expectedState{}, fmt.Errorf("password secret %s not found", secretKey.Name)
+		}
+		return expectedState{}, err
+	}
+	password := secret.Data[esv1.PasswordSecretKey]
+	if len(password) == 0 {
+		return expectedState{}, fmt.Errorf("no %s key in password secret %s", esv1.PasswordSecretKey, secretKey.Name)
+	}
+
+	roles := user.Spec.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	expected := esclient.User{
+		Password: string(password),
+		Roles:    roles,
+		FullName: user.Spec.FullName,
+		Email:    user.Spec.Email,
+		Enabled:  user.Spec.Enabled,
+	}
+	return expectedState{
+		// the password is not part of the hash, the version of the secret is used instead
+		hash: hash.HashObject([]interface{}{user.Spec, secret.ResourceVersion}),
+		apply: func(ctx context.Context) error {
+			return esClient.UpsertUser(ctx, user.SecurityName(), expected)
+		},
+	}, nil
+}
+
+// expectedRoleMapping returns the expected state of the given role mapping.
+func expectedRoleMapping(esClient esclient.Client, mapping *esv1.ElasticsearchRoleMapping) expectedState {
+	expected := esclient.RoleMapping{
+		Enabled: mapping.Spec.Enabled == nil || *mapping.Spec.Enabled,
+		Roles:   mapping.Spec.Roles,
+		Rules:   configData(mapping.Spec.Rules),
+	}
+	return expectedState{
+		hash: hash.HashObject(expected),
+		apply: func(ctx context.Context) error {
+			return esClient.UpsertRoleMapping(ctx, mapping.SecurityName(), expected)
+		},
+	}
+}
+
+// updateStatus updates the status of the given resource if it changed.
+func updateStatus(c k8s.Client, resource esv1.SecurityResource, phase esv1.SecuritySyncPhase, msg string) error {
+	expected := esv1.SecuritySyncStatus{
+		Phase:              phase,
+		Message:            msg,
+		ObservedGeneration: resource.GetGeneration(),
+	}
+	if *resource.SyncStatus() == expected {
+		return nil
+	}
+	*resource.SyncStatus() = expected
+	err := c.Status().Update(resource)
+	if apierrors.IsNotFound(err) {
+		// the resource was deleted in the meantime
+		return nil
+	}
+	return err
+}
+
+func withTimeout(ctx context.Context, f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, esclient.DefaultReqTimeout)
+	defer cancel()
+	return f(ctx)
+}
+
+func configData(config *commonv1.Config) map[string]interface{} {
+	if config == nil {
+		return nil
+	}
+	return config.Data
+}
@@ -0,0 +1,236 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+// fakeSecurityAPI simulates the Elasticsearch security APIs.
+type fakeSecurityAPI struct {
+	// resources indexed by their path
+	resources map[string]json.RawMessage
+	updates   []string
+	deletions []string
+	// paths for which requests fail
+	failures map[string]bool
+}
+
+func newFakeSecurityAPI() *fakeSecurityAPI {
+	return &fakeSecurityAPI{
+		resources: map[string]json.RawMessage{},
+		failures:  map[string]bool{},
+	}
+}
+
+func (f *fakeSecurityAPI) roundTrip(req *http.Request) *http.Response {
+	path := req.URL.Path
+	if f.failures[path] {
+		return esclient.NewMockResponse(400, req, `{"error":{"type":"illegal_argument_exception"},"status":400}`)
+	}
+	switch req.Method {
+	case http.MethodPut:
+		var body json.RawMessage
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return esclient.NewMockResponse(400, req, "{}")
+		}
+		f.resources[path] = body
+		f.updates = append(f.updates, path)
+		return esclient.NewMockResponse(200, req, "{}")
+	case http.MethodDelete:
+		f.deletions = append(f.deletions, path)
+		if _, exists := f.resources[path]; !exists {
+			return esclient.NewMockResponse(404, req, `{"found":false}`)
+		}
+		delete(f.resources, path)
+		return esclient.NewMockResponse(200, req, `{"found":true}`)
+	default:
+		return esclient.NewMockResponse(400, req, "{}")
+	}
+}
+
+func (f *fakeSecurityAPI) reset() {
+	f.updates = nil
+	f.deletions = nil
+}
+
+var (
+	testES = esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec:       esv1.ElasticsearchSpec{Version: "7.5.0"},
+	}
+	testRef = commonv1.ObjectSelector{Name: "es"}
+)
+
+func role(name string, created time.Time) *esv1.ElasticsearchRole {
+	return &esv1.ElasticsearchRole{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, CreationTimestamp: metav1.NewTime(created)},
+		Spec: esv1.ElasticsearchRoleSpec{
+			ElasticsearchRef: testRef,
+			Definition:       &commonv1.Config{Data: map[string]interface{}{"cluster": []interface{}{"monitor"}}},
+		},
+	}
+}
+
+func passwordSecret(password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "jdoe-password"},
+		Data:       map[string][]byte{esv1.PasswordSecretKey: []byte(password)},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	now := time.Now()
+	es := testES.DeepCopy()
+	user := &esv1.ElasticsearchUser{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "jdoe"},
+		Spec: esv1.ElasticsearchUserSpec{
+			ElasticsearchRef: testRef,
+			PasswordSecret:   commonv1.SecretRef{SecretName: "jdoe-password"},
+			Roles:            []string{"monitoring"},
+		},
+	}
+	mapping := &esv1.ElasticsearchRoleMapping{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "saml-admins"},
+		Spec: esv1.ElasticsearchRoleMappingSpec{
+			ElasticsearchRef: testRef,
+			Roles:            []string{"monitoring"},
+			Rules:            &commonv1.Config{Data: map[string]interface{}{"field": map[string]interface{}{"realm.name": "saml1"}}},
+		},
+	}
+	monitoring := role("monitoring", now)
+	// same name in Elasticsearch as the previous one, but created later
+	duplicate := role("monitoring-duplicate", now.Add(time.Minute))
+	duplicate.Spec.RoleName = "monitoring"
+	// references another cluster
+	other := role("other", now)
+	other.Spec.ElasticsearchRef = commonv1.ObjectSelector{Name: "other-es"}
+
+	k8sClient := k8s.WrappedFakeClient(es, monitoring, duplicate, other, user, mapping, passwordSecret("changeme"))
+	api := newFakeSecurityAPI()
+	esClient := esclient.NewMockClient(version.MustParse("7.5.0"), api.roundTrip)
+	dynamicWatches := watches.NewDynamicWatches()
+
+	reconcileAndGet := func() {
+		api.reset()
+		require.NoError(t, Reconcile(context.Background(), k8sClient, esClient, dynamicWatches, es))
+		require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "es"}, es))
+	}
+	requireStatus := func(obj esv1.SecurityResource, phase esv1.SecuritySyncPhase) {
+		require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(obj), obj))
+		require.Equal(t, phase, obj.SyncStatus().Phase, obj.SyncStatus().Message)
+	}
+
+	// roles, users and role mappings should be created in that order
+	reconcileAndGet()
+	require.Equal(t, []string{
+		"/_security/role/monitoring",
+		"/_security/user/jdoe",
+		"/_security/role_mapping/saml-admins",
+	}, api.updates)
+	require.JSONEq(t, `{"cluster":["monitor"]}`, string(api.resources["/_security/role/monitoring"]))
+	require.JSONEq(t, `{"password":"changeme","roles":["monitoring"]}`, string(api.resources["/_security/user/jdoe"]))
+	require.JSONEq(t,
+		`{"enabled":true,"roles":["monitoring"],"rules":{"field":{"realm.name":"saml1"}}}`,
+		string(api.resources["/_security/role_mapping/saml-admins"]),
+	)
+	requireStatus(monitoring, esv1.SecuritySyncSynced)
+	requireStatus(duplicate, esv1.SecuritySyncError)
+	requireStatus(user, esv1.SecuritySyncSynced)
+	requireStatus(mapping, esv1.SecuritySyncSynced)
+	requireStatus(other, "")
+	managed, err := getManagedSecurity(*es)
+	require.NoError(t, err)
+	require.Len(t, managed.Roles, 1)
+	require.Len(t, managed.Users, 1)
+	require.Len(t, managed.RoleMappings, 1)
+	require.True(t, stringsutil.StringInSlice(PasswordSecretsWatchName(k8s.ExtractNamespacedName(es)), dynamicWatches.Secrets.Registrations()))
+
+	// nothing changed: no API call
+	reconcileAndGet()
+	require.Empty(t, api.updates)
+	require.Empty(t, api.deletions)
+
+	// updating the password updates the user
+	require.NoError(t, k8sClient.Update(passwordSecret("new-password")))
+	reconcileAndGet()
+	require.Equal(t, []string{"/_security/user/jdoe"}, api.updates)
+	require.JSONEq(t, `{"password":"new-password","roles":["monitoring"]}`, string(api.resources["/_security/user/jdoe"]))
+
+	// an invalid role mapping is reported in the status, and retried
+	api.failures["/_security/role_mapping/saml-admins"] = true
+	require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(mapping), mapping))
+	mapping.Spec.Roles = []string{"superuser"}
+	require.NoError(t, k8sClient.Update(mapping))
+	api.reset()
+	require.Error(t, Reconcile(context.Background(), k8sClient, esClient, dynamicWatches, es))
+	requireStatus(mapping, esv1.SecuritySyncError)
+	require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "es"}, es))
+	delete(api.failures, "/_security/role_mapping/saml-admins")
+	reconcileAndGet()
+	require.Equal(t, []string{"/_security/role_mapping/saml-admins"}, api.updates)
+	requireStatus(mapping, esv1.SecuritySyncSynced)
+
+	// resources removed from Kubernetes are deleted in Elasticsearch, role mappings and users before roles
+	for _, obj := range []runtime.Object{monitoring, duplicate, user, mapping} {
+		require.NoError(t, k8sClient.Delete(obj))
+	}
+	reconcileAndGet()
+	require.Equal(t, []string{
+		"/_security/role_mapping/saml-admins",
+		"/_security/user/jdoe",
+		"/_security/role/monitoring",
+	}, api.deletions)
+	require.Empty(t, api.resources)
+	require.NotContains(t, es.Annotations, ManagedSecurityAnnotationName)
+	require.False(t, stringsutil.StringInSlice(PasswordSecretsWatchName(k8s.ExtractNamespacedName(es)), dynamicWatches.Secrets.Registrations()))
+}
+
+func TestReconcile_MissingPassword(t *testing.T) {
+	es := testES.DeepCopy()
+	user := &esv1.ElasticsearchUser{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "jdoe"},
+		Spec: esv1.ElasticsearchUserSpec{
+			ElasticsearchRef: testRef,
+			Username:         "john",
+			PasswordSecret:   commonv1.SecretRef{SecretName: "jdoe-password"},
+		},
+	}
+	k8sClient := k8s.WrappedFakeClient(es, user)
+	api := newFakeSecurityAPI()
+	esClient := esclient.NewMockClient(version.MustParse("6.8.0"), api.roundTrip)
+
+	// the user is not created until the secret exists
+	require.NoError(t, Reconcile(context.Background(), k8sClient, esClient, watches.NewDynamicWatches(), es))
+	require.Empty(t, api.updates)
+	require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(user), user))
+	require.Equal(t, esv1.SecuritySyncError, user.Status.Phase)
+	require.Equal(t, "password secret jdoe-password not found", user.Status.Message)
+
+	require.NoError(t, k8sClient.Create(passwordSecret("changeme")))
+	require.NoError(t, Reconcile(context.Background(), k8sClient, esClient, watches.NewDynamicWatches(), es))
+	require.Equal(t, []string{"/_xpack/security/user/john"}, api.updates)
+	require.JSONEq(t, `{"password":"changeme","roles":[]}`, string(api.resources["/_xpack/security/user/john"]))
+	require.NoError(t, k8sClient.Get(k8s.ExtractNamespacedName(user), user))
+	require.Equal(t, esv1.SecuritySyncSynced, user.Status.Phase)
+}
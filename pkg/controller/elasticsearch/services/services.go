@@ -146,11 +146,20 @@ func ElasticsearchURL(es esv1.Elasticsearch, pods []corev1.Pod) string {
 	if schemeChange {
 		// switch to sending requests directly to a random pod instead of going through the service
 		randomPod := pods[rand.Intn(len(pods))]
-		scheme, hasScheme := randomPod.Labels[label.HTTPSchemeLabelName]
-		sset, hasSset := randomPod.Labels[label.StatefulSetNameLabelName]
-		if hasScheme && hasSset {
-			return fmt.Sprintf("%s://%s.%s.%s:%d", scheme, randomPod.Name, sset, randomPod.Namespace, network.HTTPPort)
+		if url, ok := ElasticsearchPodURL(randomPod); ok {
+			return url
 		}
 	}
 	return ExternalServiceURL(es)
 }
+
+// ElasticsearchPodURL returns the URL of the given Elasticsearch Pod, reached through its StatefulSet headless
+// service, and false if it cannot be computed from the Pod labels.
+func ElasticsearchPodURL(pod corev1.Pod) (string, bool) {
+	scheme, hasScheme := pod.Labels[label.HTTPSchemeLabelName]
+	sset, hasSset := pod.Labels[label.StatefulSetNameLabelName]
+	if !hasScheme || !hasSset {
+		return "", false
+	}
+	return fmt.Sprintf("%s://%s.%s.%s:%d", scheme, pod.Name, sset, pod.Namespace, network.HTTPPort), true
+}
@@ -17,4 +17,8 @@ const (
 	// to be referenced in ES configuration file
 	EnvPodName = "POD_NAME"
 	EnvPodIP   = "POD_IP"
+
+	// EnvZone is injected as env var into the ES pod when zone awareness is enabled. Its value is the zone of the
+	// Kubernetes node the pod is scheduled on.
+	EnvZone = "ZONE"
 )
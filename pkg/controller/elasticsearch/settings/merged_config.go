@@ -12,7 +12,9 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	common "github.com/elastic/cloud-on-k8s/pkg/controller/common/settings"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/certificates/clientauth"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
 )
 
@@ -23,6 +25,7 @@ func NewMergedESConfig(
 	ver version.Version,
 	httpConfig commonv1.HTTPConfig,
 	userConfig commonv1.Config,
+	zoneAwareness bool,
 ) (CanonicalConfig, error) {
 	userCfg, err := common.NewCanonicalConfigFrom(userConfig.Data)
 	if err != nil {
@@ -31,6 +34,7 @@ func NewMergedESConfig(
 	config := baseConfig(clusterName, ver).CanonicalConfig
 	err = config.MergeWith(
 		xpackConfig(ver, httpConfig).CanonicalConfig,
+		zoneAwarenessConfig(zoneAwareness).CanonicalConfig,
 		userCfg,
 	)
 	if err != nil {
@@ -65,6 +69,17 @@ func baseConfig(clusterName string, ver version.Version) *CanonicalConfig {
 	return &CanonicalConfig{common.MustCanonicalConfig(cfg)}
 }
 
+// zoneAwarenessConfig exposes the zone of the node as a node attribute, and uses it for shard allocation awareness.
+// It is empty if zone awareness is disabled.
+func zoneAwarenessConfig(zoneAwareness bool) *CanonicalConfig {
+	cfg := map[string]interface{}{}
+	if zoneAwareness {
+		cfg[esv1.NodeAttrZone] = "${" + EnvZone + "}"
+		cfg[esv1.ClusterRoutingAllocationAwarenessAttributes] = "zone"
+	}
+	return &CanonicalConfig{common.MustCanonicalConfig(cfg)}
+}
+
 // xpackConfig returns the configuration bit related to XPack settings
 func xpackConfig(ver version.Version, httpCfg commonv1.HTTPConfig) *CanonicalConfig {
 	// enable x-pack security, including TLS
@@ -111,6 +126,10 @@ func xpackConfig(ver version.Version, httpCfg commonv1.HTTPConfig) *CanonicalCon
 		cfg[esv1.XPackSecurityAuthcRealmsNativeNative1Order] = -99
 	}
 
+	if httpCfg.TLS.ClientAuthenticationEnabled() {
+		clientAuthenticationConfig(cfg, ver, httpCfg.TLS.Client.ModeOrDefault())
+	}
+
 	if ver.IsSameOrAfter(version.MustParse("7.6.0")) {
 		cfg[esv1.XPackLicenseUploadTypes] = []string{
 			string(client.ElasticsearchLicenseTypeTrial), string(client.ElasticsearchLicenseTypeEnterprise),
@@ -119,3 +138,30 @@ func xpackConfig(ver version.Version, httpCfg commonv1.HTTPConfig) *CanonicalCon
 
 	return &CanonicalConfig{common.MustCanonicalConfig(cfg)}
 }
+
+// clientAuthenticationConfig requests client certificates on the HTTP layer, trusting the client CAs in addition to
+// the HTTP CA, and enables a PKI realm authenticating the clients with their certificate. The PKI realm only trusts
+// the CA of the operator, so that a certificate issued by a user-provided CA cannot be mapped to the roles of an
+// associated resource.
+func clientAuthenticationConfig(cfg map[string]interface{}, ver version.Version, mode commonv1.ClientAuthenticationMode) {
+	cfg[esv1.XPackSecurityHttpSslClientAuthentication] = string(mode)
+	cfg[esv1.XPackSecurityHttpSslCertificateAuthorities] = []string{
+		path.Join(volume.HTTPCertificatesSecretVolumeMountPath, certificates.CAFileName),
+		path.Join(volume.HTTPClientCertificatesSecretVolumeMountPath, certificates.CAFileName),
+	}
+	operatorCA := []string{path.Join(volume.HTTPClientCertificatesSecretVolumeMountPath, clientauth.OperatorCAFileName)}
+	// the PKI realm comes after the file and native realms, so that requests with credentials are not authenticated
+	// with the certificate
+	if ver.Major < 7 {
+		// 6.x syntax
+		cfg[esv1.XPackSecurityAuthcRealmsPki1Type] = "pki"
+		cfg[esv1.XPackSecurityAuthcRealmsPki1Order] = -98
+		cfg[esv1.XPackSecurityAuthcRealmsPki1RoleMapping] = user.PKIRoleMappingFile
+		cfg[esv1.XPackSecurityAuthcRealmsPki1CertificateAuthorities] = operatorCA
+	} else {
+		// 7.x syntax
+		cfg[esv1.XPackSecurityAuthcRealmsPkiPki1Order] = -98
+		cfg[esv1.XPackSecurityAuthcRealmsPkiPki1RoleMapping] = user.PKIRoleMappingFile
+		cfg[esv1.XPackSecurityAuthcRealmsPkiPki1CertificateAuthorities] = operatorCA
+	}
+}
@@ -22,10 +22,12 @@ func TestNewMergedESConfig(t *testing.T) {
 	xPackSecurityAuthcRealmsAD1Order := "xpack.security.authc.realms.ad1.order"
 
 	tests := []struct {
-		name    string
-		version string
-		cfgData map[string]interface{}
-		assert  func(cfg CanonicalConfig)
+		name          string
+		version       string
+		cfgData       map[string]interface{}
+		httpConfig    commonv1.HTTPConfig
+		zoneAwareness bool
+		assert        func(cfg CanonicalConfig)
 	}{
 		{
 			name:    "in 6.x, empty config should have the default file and native realm settings configured",
@@ -157,6 +159,106 @@ func TestNewMergedESConfig(t *testing.T) {
 				require.Equal(t, 1, bytes.Count(cfgBytes, []byte("seed_providers:")))
 			},
 		},
+		{
+			name:    "zone awareness is not configured by default",
+			version: "7.10.0",
+			cfgData: map[string]interface{}{},
+			assert: func(cfg CanonicalConfig) {
+				require.Equal(t, 0, len(cfg.HasKeys([]string{esv1.NodeAttrZone})))
+				require.Equal(t, 0, len(cfg.HasKeys([]string{esv1.ClusterRoutingAllocationAwarenessAttributes})))
+			},
+		},
+		{
+			name:          "zone awareness sets the zone attribute and uses it for allocation awareness",
+			version:       "7.10.0",
+			cfgData:       map[string]interface{}{},
+			zoneAwareness: true,
+			assert: func(cfg CanonicalConfig) {
+				cfgBytes, err := cfg.Render()
+				require.NoError(t, err)
+				require.True(t, bytes.Contains(cfgBytes, []byte("zone: ${ZONE}")))
+				require.True(t, bytes.Contains(cfgBytes, []byte("attributes: zone")))
+			},
+		},
+		{
+			name:    "zone awareness settings can be overridden",
+			version: "7.10.0",
+			cfgData: map[string]interface{}{
+				esv1.ClusterRoutingAllocationAwarenessAttributes: "zone,rack",
+			},
+			zoneAwareness: true,
+			assert: func(cfg CanonicalConfig) {
+				cfgBytes, err := cfg.Render()
+				require.NoError(t, err)
+				require.True(t, bytes.Contains(cfgBytes, []byte("zone: ${ZONE}")))
+				require.True(t, bytes.Contains(cfgBytes, []byte("attributes: zone,rack")))
+			},
+		},
+		{
+			name:    "client authentication is not configured by default",
+			version: "7.10.0",
+			cfgData: map[string]interface{}{},
+			assert: func(cfg CanonicalConfig) {
+				require.Equal(t, 0, len(cfg.HasKeys([]string{esv1.XPackSecurityHttpSslClientAuthentication})))
+				require.Equal(t, 0, len(cfg.HasKeys([]string{esv1.XPackSecurityAuthcRealmsPkiPki1Order})))
+			},
+		},
+		{
+			name:    "in 7.x, client authentication trusts the client CAs and enables a PKI realm",
+			version: "7.10.0",
+			cfgData: map[string]interface{}{},
+			httpConfig: commonv1.HTTPConfig{TLS: commonv1.TLSOptions{
+				Client: &commonv1.ClientAuthentication{Mode: commonv1.ClientAuthenticationRequired},
+			}},
+			assert: func(cfg CanonicalConfig) {
+				cfgBytes, err := cfg.Render()
+				require.NoError(t, err)
+				require.True(t, bytes.Contains(cfgBytes, []byte("client_authentication: required")))
+				require.True(t, bytes.Contains(cfgBytes, []byte("- /usr/share/elasticsearch/config/http-certs/ca.crt\n")))
+				require.True(t, bytes.Contains(cfgBytes, []byte("- /usr/share/elasticsearch/config/http-client-certs/ca.crt\n")))
+				require.Equal(t, 1, len(cfg.HasKeys([]string{esv1.XPackSecurityAuthcRealmsPkiPki1Order})))
+				require.Equal(t, 1, len(cfg.HasKeys([]string{esv1.XPackSecurityAuthcRealmsPkiPki1RoleMapping})))
+				// the PKI realm only trusts the CA of the operator
+				var realm struct {
+					CertificateAuthorities []string `config:"xpack.security.authc.realms.pki.pki1.certificate_authorities"`
+				}
+				require.NoError(t, cfg.CanonicalConfig.Unpack(&realm))
+				require.Equal(t, []string{"/usr/share/elasticsearch/config/http-client-certs/operator-ca.crt"}, realm.CertificateAuthorities)
+			},
+		},
+		{
+			name:    "in 6.x, client authentication is optional by default and enables a PKI realm",
+			version: "6.8.0",
+			cfgData: map[string]interface{}{},
+			httpConfig: commonv1.HTTPConfig{TLS: commonv1.TLSOptions{
+				Client: &commonv1.ClientAuthentication{},
+			}},
+			assert: func(cfg CanonicalConfig) {
+				cfgBytes, err := cfg.Render()
+				require.NoError(t, err)
+				require.True(t, bytes.Contains(cfgBytes, []byte("client_authentication: optional")))
+				require.Equal(t, 1, len(cfg.HasKeys([]string{esv1.XPackSecurityAuthcRealmsPki1Type})))
+				require.Equal(t, 1, len(cfg.HasKeys([]string{esv1.XPackSecurityAuthcRealmsPki1Order})))
+				require.Equal(t, 1, len(cfg.HasKeys([]string{esv1.XPackSecurityAuthcRealmsPki1RoleMapping})))
+				var realm struct {
+					CertificateAuthorities []string `config:"xpack.security.authc.realms.pki1.certificate_authorities"`
+				}
+				require.NoError(t, cfg.CanonicalConfig.Unpack(&realm))
+				require.Equal(t, []string{"/usr/share/elasticsearch/config/http-client-certs/operator-ca.crt"}, realm.CertificateAuthorities)
+			},
+		},
+		{
+			name:    "client authentication is not configured if TLS is disabled",
+			version: "7.10.0",
+			cfgData: map[string]interface{}{},
+			httpConfig: commonv1.HTTPConfig{TLS: commonv1.TLSOptions{
+				SelfSignedCertificate: &commonv1.SelfSignedCertificate{Disabled: true},
+				Client:                &commonv1.ClientAuthentication{},
+			}},
+			assert: func(cfg CanonicalConfig) {
+				require.Equal(t, 0, len(cfg.HasKeys([]string{esv1.XPackSecurityHttpSslClientAuthentication})))
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -165,8 +267,9 @@ func TestNewMergedESConfig(t *testing.T) {
 			cfg, err := NewMergedESConfig(
 				"clusterName",
 				*ver,
-				commonv1.HTTPConfig{},
+				tt.httpConfig,
 				commonv1.Config{Data: tt.cfgData},
+				tt.zoneAwareness,
 			)
 			require.NoError(t, err)
 			tt.assert(cfg)
@@ -0,0 +1,206 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package shutdown
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+)
+
+var log = logf.Log.WithName("node-shutdown")
+
+// MinVersion is the lowest Elasticsearch version for which the node shutdown API is used to prepare nodes
+// for restarts and removals. Prior versions rely on shard allocation settings instead.
+var MinVersion = version.MustParse("7.15.2")
+
+// IsSupported returns true if the node shutdown API can be used in a cluster whose lowest version is v.
+func IsSupported(v version.Version) bool {
+	return v.IsSameOrAfter(MinVersion)
+}
+
+// NodeShutdown manages the shutdown records of a given type through the node shutdown API.
+// It requests Elasticsearch for the nodes and their shutdown records only once, at first call.
+type NodeShutdown struct {
+	client       esclient.Client
+	shutdownType esclient.ShutdownType
+	reason       string
+	log          logr.Logger
+	loaded       bool
+	// nodeIDs are the ids of the nodes in the cluster, indexed by node name
+	nodeIDs map[string]string
+	// shutdowns are the shutdown records of all types, indexed by node id
+	shutdowns map[string]esclient.NodeShutdown
+}
+
+// NewNodeShutdown returns a NodeShutdown registering shutdown records of the given type, with the given reason,
+// for the nodes of the given Elasticsearch cluster.
+func NewNodeShutdown(
+	client esclient.Client,
+	es types.NamespacedName,
+	shutdownType esclient.ShutdownType,
+	reason string,
+) *NodeShutdown {
+	return &NodeShutdown{
+		client:       client,
+		shutdownType: shutdownType,
+		reason:       reason,
+		log:          log.WithValues("namespace", es.Namespace, "es_name", es.Name),
+	}
+}
+
+func (ns *NodeShutdown) load(ctx context.Context) error {
+	if ns.loaded {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, esclient.DefaultReqTimeout)
+	defer cancel()
+	nodes, err := ns.client.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+	shutdowns, err := ns.client.GetShutdowns(ctx)
+	if err != nil {
+		return err
+	}
+	ns.nodeIDs = make(map[string]string, len(nodes.Nodes))
+	for id, node := range nodes.Nodes {
+		ns.nodeIDs[node.Name] = id
+	}
+	ns.shutdowns = make(map[string]esclient.NodeShutdown, len(shutdowns.Nodes))
+	for _, shutdown := range shutdowns.Nodes {
+		ns.shutdowns[shutdown.NodeID] = shutdown
+	}
+	ns.loaded = true
+	return nil
+}
+
+// Register registers a shutdown record for each of the given nodes that does not have one yet.
+// Nodes that are not in the cluster are ignored. A restart shutdown record is replaced when registering removals,
+// since the node is leaving the cluster and its shards must be migrated. Other shutdown records of another type
+// are left untouched.
+func (ns *NodeShutdown) Register(ctx context.Context, nodeNames []string) error {
+	if err := ns.load(ctx); err != nil {
+		return err
+	}
+	for _, name := range nodeNames {
+		id, inCluster := ns.nodeIDs[name]
+		if !inCluster {
+			continue
+		}
+		if existing, exists := ns.shutdowns[id]; exists {
+			if existing.Is(ns.shutdownType) {
+				continue
+			}
+			if !(ns.shutdownType == esclient.Remove && existing.Is(esclient.Restart)) {
+				ns.log.Info("Node already has a shutdown record of another type", "node", name, "type", existing.Type)
+				continue
+			}
+			ns.log.Info("Replacing restart node shutdown", "node", name, "type", ns.shutdownType)
+		} else {
+			ns.log.Info("Registering node shutdown", "node", name, "type", ns.shutdownType)
+		}
+		if err := ns.client.PutShutdown(ctx, id, ns.shutdownType, ns.reason); err != nil {
+			return err
+		}
+		ns.shutdowns[id] = esclient.NodeShutdown{
+			NodeID: id,
+			Type:   string(ns.shutdownType),
+			Reason: ns.reason,
+			Status: esclient.ShutdownNotStarted,
+		}
+	}
+	return nil
+}
+
+// InCluster returns true if the given node is part of the cluster.
+func (ns *NodeShutdown) InCluster(ctx context.Context, nodeName string) (bool, error) {
+	if err := ns.load(ctx); err != nil {
+		return false, err
+	}
+	_, inCluster := ns.nodeIDs[nodeName]
+	return inCluster, nil
+}
+
+// Status returns the shutdown record of the given node, and false if the node is not in the cluster
+// or does not have a shutdown record of this type.
+func (ns *NodeShutdown) Status(ctx context.Context, nodeName string) (esclient.NodeShutdown, bool, error) {
+	if err := ns.load(ctx); err != nil {
+		return esclient.NodeShutdown{}, false, err
+	}
+	shutdown, exists := ns.shutdowns[ns.nodeIDs[nodeName]]
+	if !exists || !shutdown.Is(ns.shutdownType) {
+		return esclient.NodeShutdown{}, false, nil
+	}
+	return shutdown, true, nil
+}
+
+// IsComplete returns true if the given node is ready to be shut down.
+func (ns *NodeShutdown) IsComplete(ctx context.Context, nodeName string) (bool, error) {
+	shutdown, exists, err := ns.Status(ctx, nodeName)
+	if err != nil || !exists {
+		return false, err
+	}
+	if shutdown.Status == esclient.ShutdownStalled {
+		ns.log.Info("Node shutdown is stalled", "node", nodeName, "explanation", shutdown.ShardMigration.Explanation)
+	}
+	return shutdown.Status == esclient.ShutdownComplete, nil
+}
+
+// Delete deletes the shutdown records of this type of the given nodes.
+// Nodes that are not in the cluster are ignored.
+func (ns *NodeShutdown) Delete(ctx context.Context, nodeNames []string) error {
+	if err := ns.load(ctx); err != nil {
+		return err
+	}
+	for _, name := range nodeNames {
+		id, inCluster := ns.nodeIDs[name]
+		if !inCluster {
+			continue
+		}
+		if err := ns.delete(ctx, id, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteOrphans deletes the shutdown records of this type of the nodes that are no longer in the cluster.
+func (ns *NodeShutdown) DeleteOrphans(ctx context.Context) error {
+	if err := ns.load(ctx); err != nil {
+		return err
+	}
+	inCluster := make(map[string]bool, len(ns.nodeIDs))
+	for _, id := range ns.nodeIDs {
+		inCluster[id] = true
+	}
+	for id := range ns.shutdowns {
+		if inCluster[id] {
+			continue
+		}
+		if err := ns.delete(ctx, id, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ns *NodeShutdown) delete(ctx context.Context, id string, name string) error {
+	shutdown, exists := ns.shutdowns[id]
+	if !exists || !shutdown.Is(ns.shutdownType) {
+		return nil
+	}
+	ns.log.Info("Deleting node shutdown", "node", name, "node_id", id, "type", ns.shutdownType)
+	if err := ns.client.DeleteShutdown(ctx, id); err != nil && !esclient.IsNotFound(err) {
+		return err
+	}
+	delete(ns.shutdowns, id)
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package shutdown
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+)
+
+// fakeShutdownAPI simulates the nodes and node shutdown APIs.
+type fakeShutdownAPI struct {
+	// node names indexed by node id
+	nodes     map[string]string
+	shutdowns map[string]esclient.NodeShutdown
+	requests  []string
+}
+
+func (f *fakeShutdownAPI) roundTrip(req *http.Request) *http.Response {
+	path := req.URL.Path
+	switch {
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "/_nodes/_all"):
+		nodes := esclient.Nodes{Nodes: map[string]esclient.Node{}}
+		for id, name := range f.nodes {
+			nodes.Nodes[id] = esclient.Node{Name: name}
+		}
+		return jsonResponse(req, nodes)
+	case req.Method == http.MethodGet && path == "/_nodes/shutdown":
+		response := esclient.ShutdownResponse{Nodes: []esclient.NodeShutdown{}}
+		for _, shutdown := range f.shutdowns {
+			response.Nodes = append(response.Nodes, shutdown)
+		}
+		return jsonResponse(req, response)
+	}
+	f.requests = append(f.requests, req.Method+" "+path)
+	id := strings.TrimSuffix(strings.TrimPrefix(path, "/_nodes/"), "/shutdown")
+	switch req.Method {
+	case http.MethodPut:
+		var request esclient.ShutdownRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			return esclient.NewMockResponse(400, req, "{}")
+		}
+		f.shutdowns[id] = esclient.NodeShutdown{
+			NodeID: id,
+			Type:   strings.ToUpper(string(request.Type)),
+			Status: esclient.ShutdownInProgress,
+		}
+	case http.MethodDelete:
+		if _, exists := f.shutdowns[id]; !exists {
+			return esclient.NewMockResponse(404, req, "{}")
+		}
+		delete(f.shutdowns, id)
+	}
+	return esclient.NewMockResponse(200, req, `{"acknowledged":true}`)
+}
+
+func jsonResponse(req *http.Request, obj interface{}) *http.Response {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		panic(fmt.Sprintf("cannot marshal %v", obj))
+	}
+	return esclient.NewMockResponse(200, req, string(body))
+}
+
+func TestIsSupported(t *testing.T) {
+	require.False(t, IsSupported(version.MustParse("6.8.0")))
+	require.False(t, IsSupported(version.MustParse("7.15.1")))
+	require.True(t, IsSupported(version.MustParse("7.15.2")))
+	require.True(t, IsSupported(version.MustParse("8.0.0")))
+}
+
+func TestNodeShutdown(t *testing.T) {
+	api := &fakeShutdownAPI{
+		nodes: map[string]string{"id-0": "es-0", "id-1": "es-1", "id-2": "es-2"},
+		shutdowns: map[string]esclient.NodeShutdown{
+			// es-0 is already being removed
+			"id-0": {NodeID: "id-0", Type: "REMOVE", Status: esclient.ShutdownComplete},
+			// es-1 is being restarted
+			"id-1": {NodeID: "id-1", Type: "RESTART", Status: esclient.ShutdownComplete},
+			// the node with id-3 was removed from the cluster
+			"id-3": {NodeID: "id-3", Type: "REMOVE", Status: esclient.ShutdownComplete},
+		},
+	}
+	client := esclient.NewMockClient(version.MustParse("7.15.2"), api.roundTrip)
+	ns := NewNodeShutdown(client, types.NamespacedName{Namespace: "ns", Name: "es"}, esclient.Remove, "downscale")
+	ctx := context.Background()
+
+	// es-0 already has a record, the restart record of es-1 is replaced, es-4 is not in the cluster
+	require.NoError(t, ns.Register(ctx, []string{"es-0", "es-1", "es-2", "es-4"}))
+	require.Equal(t, []string{"PUT /_nodes/id-1/shutdown", "PUT /_nodes/id-2/shutdown"}, api.requests)
+	require.True(t, api.shutdowns["id-1"].Is(esclient.Remove))
+
+	complete, err := ns.IsComplete(ctx, "es-0")
+	require.NoError(t, err)
+	require.True(t, complete)
+	// the removal of es-1 just started
+	complete, err = ns.IsComplete(ctx, "es-1")
+	require.NoError(t, err)
+	require.False(t, complete)
+	_, exists, err := ns.Status(ctx, "es-2")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	api.requests = nil
+	require.NoError(t, ns.Delete(ctx, []string{"es-1", "es-2"}))
+	require.NoError(t, ns.DeleteOrphans(ctx))
+	require.Equal(t, []string{"DELETE /_nodes/id-1/shutdown", "DELETE /_nodes/id-2/shutdown", "DELETE /_nodes/id-3/shutdown"}, api.requests)
+	require.Len(t, api.shutdowns, 1)
+}
+
+func TestNodeShutdown_Register_KeepsRemoval(t *testing.T) {
+	api := &fakeShutdownAPI{
+		nodes: map[string]string{"id-0": "es-0"},
+		shutdowns: map[string]esclient.NodeShutdown{
+			"id-0": {NodeID: "id-0", Type: "REMOVE", Status: esclient.ShutdownInProgress},
+		},
+	}
+	client := esclient.NewMockClient(version.MustParse("7.15.2"), api.roundTrip)
+	ns := NewNodeShutdown(client, types.NamespacedName{Namespace: "ns", Name: "es"}, esclient.Restart, "upgrade")
+
+	// a removal is not replaced by a restart
+	require.NoError(t, ns.Register(context.Background(), []string{"es-0"}))
+	require.Empty(t, api.requests)
+	require.True(t, api.shutdowns["id-0"].Is(esclient.Remove))
+}
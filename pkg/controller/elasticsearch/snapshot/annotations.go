@@ -0,0 +1,67 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package snapshot
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// ManagedSnapshotsAnnotationName stores the names of the snapshot repositories and policies created by the operator,
+	// so they can be deleted from Elasticsearch once removed from the specification.
+	ManagedSnapshotsAnnotationName = "elasticsearch.k8s.elastic.co/managed-snapshots"
+)
+
+// managedSnapshots are the snapshot repositories and policies managed by the operator.
+type managedSnapshots struct {
+	Repositories []string `json:"repositories,omitempty"`
+	Policies     []string `json:"policies,omitempty"`
+}
+
+// getManagedSnapshots returns the snapshot repositories and policies previously created by the operator.
+func getManagedSnapshots(es esv1.Elasticsearch) (managedSnapshots, error) {
+	var managed managedSnapshots
+	serialized, ok := es.Annotations[ManagedSnapshotsAnnotationName]
+	if !ok {
+		return managed, nil
+	}
+	if err := json.Unmarshal([]byte(serialized), &managed); err != nil {
+		return managed, err
+	}
+	return managed, nil
+}
+
+// annotateWithManagedSnapshots stores the given managed repositories and policies in an annotation of the Elasticsearch
+// resource. The resource is only updated if they changed.
+func annotateWithManagedSnapshots(c k8s.Client, es *esv1.Elasticsearch, managed managedSnapshots) error {
+	current, err := getManagedSnapshots(*es)
+	if err == nil && reflect.DeepEqual(current, managed) {
+		return nil
+	}
+
+	if len(managed.Repositories) == 0 && len(managed.Policies) == 0 {
+		if _, exists := es.Annotations[ManagedSnapshotsAnnotationName]; !exists {
+			return nil
+		}
+		delete(es.Annotations, ManagedSnapshotsAnnotationName)
+		return c.Update(es)
+	}
+
+	serialized, err := json.Marshal(managed)
+	if err != nil {
+		return errors.Wrapf(err, "failed to serialize managed snapshots")
+	}
+	if es.Annotations == nil {
+		es.Annotations = make(map[string]string)
+	}
+	es.Annotations[ManagedSnapshotsAnnotationName] = string(serialized)
+	return c.Update(es)
+}
@@ -0,0 +1,297 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.elastic.co/apm"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
+)
+
+var log = logf.Log.WithName("snapshot")
+
+// StatusRefreshInterval is the interval at which the status of the snapshot lifecycle policies is refreshed.
+const StatusRefreshInterval = 5 * time.Minute
+
+// Reconcile creates, updates or deletes the snapshot repositories and snapshot lifecycle policies of the given
+// Elasticsearch cluster to match its specification. Repositories and policies modified through the Elasticsearch API
+// are restored to their expected configuration, and a warning event is emitted. The result of the last snapshots
+// taken by each policy is reported in the reconcile state.
+// The names of the managed repositories and policies are stored in an annotation of the Elasticsearch resource.
+func Reconcile(
+	ctx context.Context,
+	c k8s.Client,
+	esClient esclient.Client,
+	es *esv1.Elasticsearch,
+	reconcileState *reconcile.State,
+) error {
+	span, ctx := apm.StartSpan(ctx, "reconcile_snapshots", tracing.SpanTypeApp)
+	defer span.End()
+
+	var repositories []esv1.SnapshotRepository
+	var policies []esv1.SnapshotPolicy
+	if es.Spec.Snapshots != nil {
+		repositories = es.Spec.Snapshots.Repositories
+		policies = es.Spec.Snapshots.Policies
+	}
+
+	previous, err := getManagedSnapshots(*es)
+	if err != nil {
+		return err
+	}
+	managed := managedSnapshots{}
+
+	// repositories must exist before policies can reference them
+	for _, repository := range repositories {
+		if err := reconcileRepository(ctx, esClient, *es, repository, stringsutil.StringInSlice(repository.Name, previous.Repositories), reconcileState); err != nil {
+			return err
+		}
+		managed.Repositories = append(managed.Repositories, repository.Name)
+	}
+
+	var statuses []esv1.SnapshotPolicyStatus
+	for _, policy := range policies {
+		status, err := reconcilePolicy(ctx, esClient, *es, policy, stringsutil.StringInSlice(policy.Name, previous.Policies), reconcileState)
+		if err != nil {
+			return err
+		}
+		managed.Policies = append(managed.Policies, policy.Name)
+		statuses = append(statuses, status)
+	}
+	reconcileState.UpdateSnapshotsStatus(statuses)
+
+	// policies must be deleted before the repositories they reference
+	for _, name := range previous.Policies {
+		if stringsutil.StringInSlice(name, managed.Policies) {
+			continue
+		}
+		log.Info("Deleting snapshot lifecycle policy", "namespace", es.Namespace, "es_name", es.Name, "policy", name)
+		if err := withTimeout(ctx, func(ctx context.Context) error {
+			return esClient.DeleteSnapshotLifecyclePolicy(ctx, name)
+		}); err != nil && !esclient.IsNotFound(err) {
+			return err
+		}
+	}
+	for _, name := range previous.Repositories {
+		if stringsutil.StringInSlice(name, managed.Repositories) {
+			continue
+		}
+		log.Info("Deleting snapshot repository", "namespace", es.Namespace, "es_name", es.Name, "repository", name)
+		if err := withTimeout(ctx, func(ctx context.Context) error {
+			return esClient.DeleteSnapshotRepository(ctx, name)
+		}); err != nil && !esclient.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return annotateWithManagedSnapshots(c, es, managed)
+}
+
+// reconcileRepository creates or updates the given snapshot repository if it does not match its specification.
+func reconcileRepository(
+	ctx context.Context,
+	esClient esclient.Client,
+	es esv1.Elasticsearch,
+	repository esv1.SnapshotRepository,
+	managed bool,
+	reconcileState *reconcile.State,
+) error {
+	expected := expectedRepository(repository)
+	var actual esclient.SnapshotRepository
+	err := withTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		actual, err = esClient.GetSnapshotRepository(ctx, repository.Name)
+		return err
+	})
+	switch {
+	case esclient.IsNotFound(err):
+		if managed {
+			reportDrift(reconcileState, fmt.Sprintf("Snapshot repository %s was deleted outside of the operator, recreating it", repository.Name))
+		}
+	case err != nil:
+		return err
+	case actual.Type == expected.Type && settingsEqual(actual.Settings, expected.Settings):
+		return nil
+	case managed:
+		reportDrift(reconcileState, fmt.Sprintf("Snapshot repository %s was modified outside of the operator, restoring it", repository.Name))
+	}
+
+	log.Info("Creating or updating snapshot repository", "namespace", es.Namespace, "es_name", es.Name, "repository", repository.Name)
+	return withTimeout(ctx, func(ctx context.Context) error {
+		return esClient.UpsertSnapshotRepository(ctx, repository.Name, expected)
+	})
+}
+
+// reconcilePolicy creates or updates the given snapshot lifecycle policy if it does not match its specification,
+// and returns its status.
+func reconcilePolicy(
+	ctx context.Context,
+	esClient esclient.Client,
+	es esv1.Elasticsearch,
+	policy esv1.SnapshotPolicy,
+	managed bool,
+	reconcileState *reconcile.State,
+) (esv1.SnapshotPolicyStatus, error) {
+	expected := expectedPolicy(policy)
+	var actual esclient.SnapshotLifecyclePolicyInfo
+	err := withTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		actual, err = esClient.GetSnapshotLifecyclePolicy(ctx, policy.Name)
+		return err
+	})
+	switch {
+	case esclient.IsNotFound(err):
+		if managed {
+			reportDrift(reconcileState, fmt.Sprintf("Snapshot lifecycle policy %s was deleted outside of the operator, recreating it", policy.Name))
+		}
+	case err != nil:
+		return esv1.SnapshotPolicyStatus{}, err
+	case policiesEqual(actual.Policy, expected):
+		return policyStatus(policy.Name, actual), nil
+	case managed:
+		reportDrift(reconcileState, fmt.Sprintf("Snapshot lifecycle policy %s was modified outside of the operator, restoring it", policy.Name))
+	}
+
+	log.Info("Creating or updating snapshot lifecycle policy", "namespace", es.Namespace, "es_name", es.Name, "policy", policy.Name)
+	if err := withTimeout(ctx, func(ctx context.Context) error {
+		return esClient.UpsertSnapshotLifecyclePolicy(ctx, policy.Name, expected)
+	}); err != nil {
+		return esv1.SnapshotPolicyStatus{}, err
+	}
+	// the result of previous executions is kept when a policy is updated
+	return policyStatus(policy.Name, actual), nil
+}
+
+func reportDrift(reconcileState *reconcile.State, msg string) {
+	reconcileState.AddEvent(corev1.EventTypeWarning, events.EventReasonUnexpected, msg)
+}
+
+func withTimeout(ctx context.Context, f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, esclient.DefaultReqTimeout)
+	defer cancel()
+	return f(ctx)
+}
+
+// expectedRepository returns the repository to declare in Elasticsearch.
+func expectedRepository(repository esv1.SnapshotRepository) esclient.SnapshotRepository {
+	return esclient.SnapshotRepository{
+		Type:     repository.Type,
+		Settings: configData(repository.Settings),
+	}
+}
+
+// expectedPolicy returns the snapshot lifecycle policy to declare in Elasticsearch.
+func expectedPolicy(policy esv1.SnapshotPolicy) esclient.SnapshotLifecyclePolicy {
+	expected := esclient.SnapshotLifecyclePolicy{
+		Schedule:   policy.Schedule,
+		Name:       policy.SnapshotNameOrDefault(),
+		Repository: policy.Repository,
+		Config:     configData(policy.Config),
+	}
+	if policy.Retention != nil {
+		expected.Retention = &esclient.SnapshotLifecycleRetention{
+			ExpireAfter: policy.Retention.ExpireAfter,
+			MinCount:    policy.Retention.MinCount,
+			MaxCount:    policy.Retention.MaxCount,
+		}
+	}
+	return expected
+}
+
+func configData(config *commonv1.Config) map[string]interface{} {
+	if config == nil {
+		return nil
+	}
+	return config.Data
+}
+
+// policiesEqual returns true if both snapshot lifecycle policies have the same definition.
+func policiesEqual(actual, expected esclient.SnapshotLifecyclePolicy) bool {
+	emptyRetention := esclient.SnapshotLifecycleRetention{}
+	actualRetention, expectedRetention := emptyRetention, emptyRetention
+	if actual.Retention != nil {
+		actualRetention = *actual.Retention
+	}
+	if expected.Retention != nil {
+		expectedRetention = *expected.Retention
+	}
+	return actual.Schedule == expected.Schedule &&
+		actual.Name == expected.Name &&
+		actual.Repository == expected.Repository &&
+		reflect.DeepEqual(actualRetention, expectedRetention) &&
+		settingsEqual(actual.Config, expected.Config)
+}
+
+// settingsEqual compares settings regardless of their representation: nested or flattened keys, and scalar values
+// compared as strings, since Elasticsearch returns settings values as strings.
+func settingsEqual(actual, expected map[string]interface{}) bool {
+	return reflect.DeepEqual(flatten("", actual), flatten("", expected))
+}
+
+// flatten returns the given settings as a map of dotted keys to string values.
+func flatten(prefix string, settings map[string]interface{}) map[string]string {
+	flattened := make(map[string]string)
+	for key, value := range settings {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for k, val := range flatten(key, v) {
+				flattened[k] = val
+			}
+		case []interface{}:
+			values := make([]string, len(v))
+			for i, item := range v {
+				values[i] = fmt.Sprintf("%v", item)
+			}
+			sort.Strings(values)
+			flattened[key] = fmt.Sprintf("%v", values)
+		case float64:
+			flattened[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			flattened[key] = fmt.Sprintf("%v", v)
+		}
+	}
+	return flattened
+}
+
+// policyStatus returns the status of a snapshot lifecycle policy from the result of its last executions.
+func policyStatus(name string, info esclient.SnapshotLifecyclePolicyInfo) esv1.SnapshotPolicyStatus {
+	return esv1.SnapshotPolicyStatus{
+		Name:        name,
+		LastSuccess: snapshotResult(info.LastSuccess),
+		LastFailure: snapshotResult(info.LastFailure),
+	}
+}
+
+func snapshotResult(record *esclient.SnapshotInvocationRecord) *esv1.SnapshotResult {
+	if record == nil {
+		return nil
+	}
+	return &esv1.SnapshotResult{
+		SnapshotName: record.SnapshotName,
+		// truncated to the second, as stored in the resource status
+		Time:    metav1.NewTime(time.Unix(record.Time/1000, 0)),
+		Details: record.Details,
+	}
+}
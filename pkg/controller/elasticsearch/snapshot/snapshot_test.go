@@ -0,0 +1,229 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/reconcile"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/pointer"
+)
+
+// fakeSnapshotAPI simulates the Elasticsearch snapshot repository and snapshot lifecycle management APIs.
+type fakeSnapshotAPI struct {
+	repositories map[string]esclient.SnapshotRepository
+	policies     map[string]esclient.SnapshotLifecyclePolicyInfo
+	updates      int
+}
+
+func newFakeSnapshotAPI() *fakeSnapshotAPI {
+	return &fakeSnapshotAPI{
+		repositories: map[string]esclient.SnapshotRepository{},
+		policies:     map[string]esclient.SnapshotLifecyclePolicyInfo{},
+	}
+}
+
+func (f *fakeSnapshotAPI) roundTrip(req *http.Request) *http.Response {
+	var name string
+	var store func(body *json.Decoder) error
+	var get func() (interface{}, bool)
+	var remove func()
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/_snapshot/"):
+		name = strings.TrimPrefix(req.URL.Path, "/_snapshot/")
+		store = func(body *json.Decoder) error {
+			var repository esclient.SnapshotRepository
+			err := body.Decode(&repository)
+			f.repositories[name] = repository
+			return err
+		}
+		get = func() (interface{}, bool) {
+			repository, exists := f.repositories[name]
+			return map[string]esclient.SnapshotRepository{name: repository}, exists
+		}
+		remove = func() { delete(f.repositories, name) }
+	case strings.HasPrefix(req.URL.Path, "/_slm/policy/"):
+		name = strings.TrimPrefix(req.URL.Path, "/_slm/policy/")
+		store = func(body *json.Decoder) error {
+			info := f.policies[name]
+			err := body.Decode(&info.Policy)
+			f.policies[name] = info
+			return err
+		}
+		get = func() (interface{}, bool) {
+			info, exists := f.policies[name]
+			return map[string]esclient.SnapshotLifecyclePolicyInfo{name: info}, exists
+		}
+		remove = func() { delete(f.policies, name) }
+	default:
+		return esclient.NewMockResponse(400, req, "{}")
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		f.updates++
+		if err := store(json.NewDecoder(req.Body)); err != nil {
+			return esclient.NewMockResponse(400, req, "{}")
+		}
+		return esclient.NewMockResponse(200, req, `{"acknowledged":true}`)
+	case http.MethodDelete:
+		remove()
+		return esclient.NewMockResponse(200, req, `{"acknowledged":true}`)
+	default:
+		body, exists := get()
+		if !exists {
+			return esclient.NewMockResponse(404, req, `{"error":{"type":"resource_not_found_exception"},"status":404}`)
+		}
+		serialized, _ := json.Marshal(body)
+		return esclient.NewMockResponse(200, req, string(serialized))
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec: esv1.ElasticsearchSpec{
+			Version: "7.5.0",
+			Snapshots: &esv1.SnapshotsSpec{
+				Repositories: []esv1.SnapshotRepository{{
+					Name:     "my-repo",
+					Type:     "s3",
+					Settings: &commonv1.Config{Data: map[string]interface{}{"bucket": "snapshots", "compress": true}},
+				}},
+				Policies: []esv1.SnapshotPolicy{{
+					Name:       "nightly",
+					Schedule:   "0 30 1 * * ?",
+					Repository: "my-repo",
+					Retention:  &esv1.SnapshotRetention{MaxCount: pointer.Int32(10)},
+				}},
+			},
+		},
+	}
+	k8sClient := k8s.WrappedFakeClient(&es)
+	api := newFakeSnapshotAPI()
+	esClient := esclient.NewMockClient(version.MustParse("7.5.0"), api.roundTrip)
+
+	reconcileAndGet := func() *reconcile.State {
+		state := reconcile.NewState(es)
+		require.NoError(t, Reconcile(context.Background(), k8sClient, esClient, &es, state))
+		require.NoError(t, k8sClient.Get(types.NamespacedName{Namespace: "ns", Name: "es"}, &es))
+		return state
+	}
+
+	// repositories and policies should be created
+	state := reconcileAndGet()
+	require.Equal(t, 2, api.updates)
+	require.Equal(t, esclient.SnapshotRepository{Type: "s3", Settings: map[string]interface{}{"bucket": "snapshots", "compress": true}}, api.repositories["my-repo"])
+	require.Equal(t, "<nightly-{now/d}>", api.policies["nightly"].Policy.Name)
+	require.Equal(t, `{"repositories":["my-repo"],"policies":["nightly"]}`, es.Annotations[ManagedSnapshotsAnnotationName])
+	require.Empty(t, state.Events())
+	_, updated := state.Apply()
+	require.NotNil(t, updated)
+	require.Equal(t, []esv1.SnapshotPolicyStatus{{Name: "nightly"}}, updated.Status.Snapshots)
+
+	// Elasticsearch returns settings values as strings: nothing should be updated
+	api.repositories["my-repo"] = esclient.SnapshotRepository{Type: "s3", Settings: map[string]interface{}{"bucket": "snapshots", "compress": "true"}}
+	state = reconcileAndGet()
+	require.Equal(t, 2, api.updates)
+	require.Empty(t, state.Events())
+
+	// the result of the last snapshot should be reported in the status
+	info := api.policies["nightly"]
+	info.LastSuccess = &esclient.SnapshotInvocationRecord{SnapshotName: "nightly-2019.12.01-abc", Time: 1575165600123}
+	api.policies["nightly"] = info
+	state = reconcileAndGet()
+	_, updated = state.Apply()
+	require.NotNil(t, updated)
+	require.Equal(t, "nightly-2019.12.01-abc", updated.Status.Snapshots[0].LastSuccess.SnapshotName)
+	require.Equal(t, int64(1575165600), updated.Status.Snapshots[0].LastSuccess.Time.Unix())
+	require.Nil(t, updated.Status.Snapshots[0].LastFailure)
+	es.Status = updated.Status
+	require.NoError(t, k8sClient.Update(&es))
+
+	// changes made through the Elasticsearch API should be reverted and reported
+	api.repositories["my-repo"] = esclient.SnapshotRepository{Type: "s3", Settings: map[string]interface{}{"bucket": "other"}}
+	delete(api.policies, "nightly")
+	state = reconcileAndGet()
+	require.Equal(t, 4, api.updates)
+	require.Equal(t, "snapshots", api.repositories["my-repo"].Settings["bucket"])
+	require.Contains(t, api.policies, "nightly")
+	require.Len(t, state.Events(), 2)
+	for _, event := range state.Events() {
+		require.Equal(t, corev1.EventTypeWarning, event.EventType)
+	}
+
+	// repositories and policies removed from the specification should be deleted
+	es.Spec.Snapshots = nil
+	state = reconcileAndGet()
+	require.Empty(t, api.repositories)
+	require.Empty(t, api.policies)
+	require.NotContains(t, es.Annotations, ManagedSnapshotsAnnotationName)
+	_, updated = state.Apply()
+	require.NotNil(t, updated)
+	require.Nil(t, updated.Status.Snapshots)
+}
+
+func Test_settingsEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		actual   map[string]interface{}
+		expected map[string]interface{}
+		want     bool
+	}{
+		{
+			name: "both empty",
+			want: true,
+		},
+		{
+			name:     "same settings",
+			actual:   map[string]interface{}{"bucket": "snapshots"},
+			expected: map[string]interface{}{"bucket": "snapshots"},
+			want:     true,
+		},
+		{
+			name:     "values as strings",
+			actual:   map[string]interface{}{"compress": "true", "max_restore_bytes_per_sec": "40", "indices": []interface{}{"b", "a"}},
+			expected: map[string]interface{}{"compress": true, "max_restore_bytes_per_sec": float64(40), "indices": []interface{}{"a", "b"}},
+			want:     true,
+		},
+		{
+			name:     "nested and flattened keys",
+			actual:   map[string]interface{}{"client": map[string]interface{}{"name": "default"}},
+			expected: map[string]interface{}{"client.name": "default"},
+			want:     true,
+		},
+		{
+			name:     "different values",
+			actual:   map[string]interface{}{"bucket": "other"},
+			expected: map[string]interface{}{"bucket": "snapshots"},
+			want:     false,
+		},
+		{
+			name:     "missing setting",
+			actual:   map[string]interface{}{"bucket": "snapshots"},
+			expected: map[string]interface{}{"bucket": "snapshots", "compress": true},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, settingsEqual(tt.actual, tt.expected))
+		})
+	}
+}
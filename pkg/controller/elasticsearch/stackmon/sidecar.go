@@ -0,0 +1,182 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package stackmon
+
+import (
+	"fmt"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/stackmon"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/network"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	esvolume "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// monitoringPasswordEnvVarName is the environment variable holding the password of the user collecting the
+	// metrics of Elasticsearch.
+	monitoringPasswordEnvVarName = "ES_MONITORING_PASSWORD"
+	// logStyleEnvVarName is the environment variable used to make Elasticsearch write its logs to files, in the
+	// official Docker image.
+	logStyleEnvVarName = "ES_LOG_STYLE"
+
+	httpCertsMountPath       = "/mnt/elastic-internal/elasticsearch-http-certs"
+	httpClientCertsMountPath = "/mnt/elastic-internal/elasticsearch-http-client-certs"
+)
+
+// IsLogsMonitoringDefined returns true if the logs of the given Elasticsearch cluster are shipped to a monitoring cluster.
+func IsLogsMonitoringDefined(es esv1.Elasticsearch) bool {
+	return es.Spec.Monitoring.LogsRef().IsDefined()
+}
+
+// Sidecars returns the Metricbeat and Filebeat sidecars shipping the metrics and the logs of the given Elasticsearch
+// cluster, for the associations with the monitoring clusters which are configured.
+func Sidecars(es esv1.Elasticsearch) ([]stackmon.BeatSidecar, error) {
+	var sidecars []stackmon.BeatSidecar
+
+	metricsConf, err := association.GetAssociationConfFromAnnotation(&es, annotation.MonitoringMetricsAssociationConfAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if es.Spec.Monitoring.MetricsRef().IsDefined() && metricsConf.IsConfigured() {
+		volumeMounts := []corev1.VolumeMount{{
+			Name:      esvolume.HTTPCertificatesSecretVolumeName,
+			MountPath: httpCertsMountPath,
+			ReadOnly:  true,
+		}}
+		if es.Spec.HTTP.TLS.ClientAuthenticationEnabled() {
+			// Metricbeat presents the client certificate of the operator, mounted in the Pod along with the trusted
+			// client CAs, in case clients are required to present one
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{
+				Name:      esvolume.HTTPClientCertificatesSecretVolumeName,
+				MountPath: httpClientCertsMountPath,
+				ReadOnly:  true,
+			})
+		}
+		metricbeat, err := stackmon.NewMetricbeatSidecar(stackmon.BeatSidecarParams{
+			Monitored:       &es,
+			Namer:           esv1.ESNamer,
+			Version:         es.Spec.Version,
+			Labels:          label.NewLabels(k8s.ExtractNamespacedName(&es)),
+			AssociationConf: metricsConf,
+			Modules:         []map[string]interface{}{metricbeatModule(es)},
+			VolumeMounts:    volumeMounts,
+			Env: []corev1.EnvVar{{
+				Name: monitoringPasswordEnvVarName,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: esv1.InternalUsersSecret(es.Name)},
+						Key:                  user.MonitoringUserName,
+					},
+				},
+			}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		sidecars = append(sidecars, metricbeat)
+	}
+
+	logsConf, err := association.GetAssociationConfFromAnnotation(&es, annotation.MonitoringLogsAssociationConfAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if IsLogsMonitoringDefined(es) && logsConf.IsConfigured() {
+		filebeat, err := stackmon.NewFilebeatSidecar(stackmon.BeatSidecarParams{
+			Monitored:       &es,
+			Namer:           esv1.ESNamer,
+			Version:         es.Spec.Version,
+			Labels:          label.NewLabels(k8s.ExtractNamespacedName(&es)),
+			AssociationConf: logsConf,
+			Modules:         []map[string]interface{}{filebeatModule()},
+			VolumeMounts: []corev1.VolumeMount{{
+				Name:      esvolume.ElasticsearchLogsVolumeName,
+				MountPath: esvolume.ElasticsearchLogsMountPath,
+				ReadOnly:  true,
+			}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		sidecars = append(sidecars, filebeat)
+	}
+
+	return sidecars, nil
+}
+
+// metricbeatModule returns the configuration of the Metricbeat module collecting the metrics of the local
+// Elasticsearch node.
+func metricbeatModule(es esv1.Elasticsearch) map[string]interface{} {
+	module := map[string]interface{}{
+		"module":        "elasticsearch",
+		"xpack.enabled": true,
+		"period":        "10s",
+		"scope":         "node",
+		"hosts":         []string{fmt.Sprintf("%s://localhost:%d", es.Spec.HTTP.Protocol(), network.HTTPPort)},
+		"username":      user.MonitoringUserName,
+		"password":      "${" + monitoringPasswordEnvVarName + "}",
+	}
+	if es.Spec.HTTP.TLS.Enabled() {
+		module["ssl.certificate_authorities"] = []string{filepath.Join(httpCertsMountPath, certificates.CAFileName)}
+		// the certificate is not issued for localhost
+		module["ssl.verification_mode"] = "certificate"
+	}
+	if es.Spec.HTTP.TLS.ClientAuthenticationEnabled() {
+		module["ssl.certificate"] = filepath.Join(httpClientCertsMountPath, certificates.CertFileName)
+		module["ssl.key"] = filepath.Join(httpClientCertsMountPath, certificates.KeyFileName)
+	}
+	return module
+}
+
+// filebeatModule returns the configuration of the Filebeat module collecting the logs of the local Elasticsearch node.
+func filebeatModule() map[string]interface{} {
+	logPaths := func(patterns ...string) map[string]interface{} {
+		paths := make([]string, len(patterns))
+		for i, pattern := range patterns {
+			paths[i] = filepath.Join(esvolume.ElasticsearchLogsMountPath, pattern)
+		}
+		return map[string]interface{}{"enabled": true, "var.paths": paths}
+	}
+	return map[string]interface{}{
+		"module":      "elasticsearch",
+		"server":      logPaths("*_server.json"),
+		"gc":          logPaths("gc.log.[0-9]*", "gc.log"),
+		"audit":       logPaths("*_audit.json"),
+		"slowlog":     logPaths("*_index_search_slowlog.json", "*_index_indexing_slowlog.json"),
+		"deprecation": logPaths("*_deprecation.json"),
+	}
+}
+
+// WithMonitoring adds the monitoring sidecars of the given Elasticsearch cluster to the Pod template being built.
+// Elasticsearch writes its logs to files to be collected by Filebeat, as soon as logs monitoring is defined.
+func WithMonitoring(builder *defaults.PodTemplateBuilder, es esv1.Elasticsearch) (*defaults.PodTemplateBuilder, error) {
+	if IsLogsMonitoringDefined(es) {
+		builder = builder.WithEnv(corev1.EnvVar{Name: logStyleEnvVarName, Value: "file"})
+	}
+	sidecars, err := Sidecars(es)
+	if err != nil {
+		return nil, err
+	}
+	return stackmon.WithBeatSidecars(builder, sidecars...), nil
+}
+
+// ReconcileConfigSecrets reconciles the configuration Secrets of the monitoring sidecars of the given Elasticsearch
+// cluster.
+func ReconcileConfigSecrets(c k8s.Client, es esv1.Elasticsearch) error {
+	sidecars, err := Sidecars(es)
+	if err != nil {
+		return err
+	}
+	return stackmon.ReconcileConfigSecrets(c, &es, esv1.ESNamer, sidecars...)
+}
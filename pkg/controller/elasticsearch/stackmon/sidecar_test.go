@@ -0,0 +1,129 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package stackmon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/stackmon"
+)
+
+const assocConf = `{"authSecretName":"es-es-monitoring-metrics-user","authSecretKey":"ns-es-es-monitoring-metrics-user","caCertProvided":true,"caSecretName":"es-es-monitoring-metrics-ca","url":"https://monitoring-es-http.observability.svc:9200"}`
+
+func monitoredES(metrics, logs bool, annotations map[string]string) esv1.Elasticsearch {
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es", Annotations: annotations},
+		Spec:       esv1.ElasticsearchSpec{Version: "7.14.0"},
+	}
+	ref := []commonv1.ObjectSelector{{Namespace: "observability", Name: "monitoring"}}
+	if metrics {
+		es.Spec.Monitoring.Metrics.ElasticsearchRefs = ref
+	}
+	if logs {
+		es.Spec.Monitoring.Logs.ElasticsearchRefs = ref
+	}
+	return es
+}
+
+func TestSidecars(t *testing.T) {
+	bothConfigured := map[string]string{
+		annotation.MonitoringMetricsAssociationConfAnnotation: assocConf,
+		annotation.MonitoringLogsAssociationConfAnnotation:    assocConf,
+	}
+	tests := []struct {
+		name string
+		es   esv1.Elasticsearch
+		want []string
+	}{
+		{
+			name: "no monitoring",
+			es:   monitoredES(false, false, nil),
+			want: nil,
+		},
+		{
+			name: "association not configured yet",
+			es:   monitoredES(true, true, nil),
+			want: nil,
+		},
+		{
+			name: "metrics and logs",
+			es:   monitoredES(true, true, bothConfigured),
+			want: []string{stackmon.MetricbeatType, stackmon.FilebeatType},
+		},
+		{
+			name: "logs only",
+			es:   monitoredES(false, true, bothConfigured),
+			want: []string{stackmon.FilebeatType},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sidecars, err := Sidecars(tt.es)
+			require.NoError(t, err)
+			var got []string
+			for _, s := range sidecars {
+				got = append(got, s.Container.Name)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithMonitoring(t *testing.T) {
+	es := monitoredES(true, true, map[string]string{
+		annotation.MonitoringMetricsAssociationConfAnnotation: assocConf,
+		annotation.MonitoringLogsAssociationConfAnnotation:    assocConf,
+	})
+	builder, err := WithMonitoring(defaults.NewPodTemplateBuilder(corev1.PodTemplateSpec{}, esv1.ElasticsearchContainerName), es)
+	require.NoError(t, err)
+
+	containers := builder.PodTemplate.Spec.Containers
+	require.Len(t, containers, 3)
+	// Elasticsearch writes its logs to files
+	require.Contains(t, containers[0].Env, corev1.EnvVar{Name: "ES_LOG_STYLE", Value: "file"})
+	// Metricbeat can read the password of the monitoring user
+	require.Contains(t, containers[1].Env, corev1.EnvVar{
+		Name: "ES_MONITORING_PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "es-es-internal-users"},
+				Key:                  "elastic-internal-monitoring",
+			},
+		},
+	})
+	require.NotEmpty(t, builder.PodTemplate.Annotations[stackmon.ConfigHashAnnotationName])
+}
+
+func Test_metricbeatModule(t *testing.T) {
+	es := monitoredES(true, false, nil)
+	module := metricbeatModule(es)
+	require.Equal(t, []string{"https://localhost:9200"}, module["hosts"])
+	require.Equal(t, []string{"/mnt/elastic-internal/elasticsearch-http-certs/ca.crt"}, module["ssl.certificate_authorities"])
+	require.NotContains(t, module, "ssl.certificate")
+
+	// the client certificate of the operator is presented when client authentication is enabled
+	es.Spec.HTTP.TLS.Client = &commonv1.ClientAuthentication{Mode: commonv1.ClientAuthenticationRequired}
+	module = metricbeatModule(es)
+	require.Equal(t, "/mnt/elastic-internal/elasticsearch-http-client-certs/tls.crt", module["ssl.certificate"])
+	require.Equal(t, "/mnt/elastic-internal/elasticsearch-http-client-certs/tls.key", module["ssl.key"])
+
+	// and mounted in the Metricbeat sidecar
+	es.Annotations = map[string]string{annotation.MonitoringMetricsAssociationConfAnnotation: assocConf}
+	sidecars, err := Sidecars(es)
+	require.NoError(t, err)
+	require.Contains(t, sidecars[0].Container.VolumeMounts, corev1.VolumeMount{
+		Name:      "elastic-internal-http-client-certificates",
+		MountPath: "/mnt/elastic-internal/elasticsearch-http-client-certs",
+		ReadOnly:  true,
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/set"
 )
 
 const (
@@ -27,6 +28,17 @@ const (
 	PasswordHashField = "passwordHash"
 	// UserRolesField is the field in the secret that contains the roles for the user as a comma separated list of strings.
 	UserRolesField = "userRoles"
+	// CredentialsField is the field in the secret of an associated resource authenticating with a service account token
+	// or an API key instead of a file realm user. It contains the names of the tokens or keys in use as a comma
+	// separated list of strings.
+	CredentialsField = "credentials"
+	// CertificateDNField is the field in the secret of an associated resource authenticating with a client certificate
+	// instead of a file realm user. It contains the distinguished name of the certificate subject.
+	CertificateDNField = "certificateDN"
+
+	// PasswordSecretAnnotation is the annotation of an associated user secret referencing the secret, formatted as
+	// <namespace>/<name>, in which the associated resource reads the password of the user, under the user name key.
+	PasswordSecretAnnotation = "association.k8s.elastic.co/password-secret"
 
 	fieldNotFound = "field %s not found in secret %s/%s"
 )
@@ -46,22 +58,70 @@ func AssociatedUserLabels(es esv1.Elasticsearch) map[string]string {
 	}
 }
 
-// retrieveAssociatedUsers fetches users resulting from an association (eg. Kibana or APMServer users).
-// Those users are created by an association controller.
-func retrieveAssociatedUsers(c k8s.Client, es esv1.Elasticsearch) (users, error) {
-	// list all associated user secrets
-	var associatedUserSecrets corev1.SecretList
+// listAssociatedSecrets lists the secrets created in the namespace of the given Elasticsearch cluster for the
+// associated resources, either for file realm users, for service account tokens and API keys, or for client certificates.
+func listAssociatedSecrets(c k8s.Client, es esv1.Elasticsearch) ([]corev1.Secret, error) {
+	var associatedSecrets corev1.SecretList
 	if err := c.List(
-		&associatedUserSecrets,
+		&associatedSecrets,
 		client.InNamespace(es.Namespace),
 		client.MatchingLabels(AssociatedUserLabels(es)),
 	); err != nil {
 		return nil, err
 	}
+	return associatedSecrets.Items, nil
+}
+
+// listAssociatedUserSecrets lists the secrets of the users of the resources associated to the given Elasticsearch cluster.
+func listAssociatedUserSecrets(c k8s.Client, es esv1.Elasticsearch) ([]corev1.Secret, error) {
+	associatedSecrets, err := listAssociatedSecrets(c, es)
+	if err != nil {
+		return nil, err
+	}
+	userSecrets := make([]corev1.Secret, 0, len(associatedSecrets))
+	for _, secret := range associatedSecrets {
+		_, hasCredentials := secret.Data[CredentialsField]
+		_, hasCertificate := secret.Data[CertificateDNField]
+		_, hasPassword := secret.Data[PasswordHashField]
+		if (hasCredentials || hasCertificate) && !hasPassword {
+			// the associated resource does not rely on a file realm user
+			continue
+		}
+		userSecrets = append(userSecrets, secret)
+	}
+	return userSecrets, nil
+}
+
+// AssociatedCredentials returns the names of the service account tokens and API keys in use by the resources
+// associated to the given Elasticsearch cluster.
+func AssociatedCredentials(c k8s.Client, es esv1.Elasticsearch) (set.StringSet, error) {
+	associatedSecrets, err := listAssociatedSecrets(c, es)
+	if err != nil {
+		return nil, err
+	}
+	names := set.StringSet{}
+	for _, secret := range associatedSecrets {
+		for _, name := range strings.Split(string(secret.Data[CredentialsField]), ",") {
+			if name != "" {
+				names.Add(name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// retrieveAssociatedUsers fetches users resulting from an association (eg. Kibana or APMServer users).
+// Those users are created by an association controller.
+func retrieveAssociatedUsers(c k8s.Client, es esv1.Elasticsearch) (users, error) {
+	// list all associated user secrets
+	associatedUserSecrets, err := listAssociatedUserSecrets(c, es)
+	if err != nil {
+		return nil, err
+	}
 
 	// parse secrets content into users
-	users := make([]AssociatedUser, 0, len(associatedUserSecrets.Items))
-	for _, secret := range associatedUserSecrets.Items {
+	users := make([]AssociatedUser, 0, len(associatedUserSecrets))
+	for _, secret := range associatedUserSecrets {
 		u, err := parseAssociatedUserSecret(secret)
 		if err != nil {
 			return nil, err
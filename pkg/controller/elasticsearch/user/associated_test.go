@@ -65,6 +65,56 @@ func Test_retrieveAssociatedUsers(t *testing.T) {
 				{Name: "user2", PasswordHash: []byte("passwordHash2"), Roles: []string{"role1", "role2", "role3"}},
 			},
 		},
+		{
+			name: "associated resources authenticating with a service account token",
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: es.Namespace,
+						Name:      "user1",
+						Labels:    AssociatedUserLabels(es),
+					},
+					Data: map[string][]byte{
+						CredentialsField: []byte("eck-ns-kb-kibana-user-1622505600"),
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: es.Namespace,
+						Name:      "user2",
+						Labels:    AssociatedUserLabels(es),
+					},
+					// the file realm user is kept until the token is created
+					Data: map[string][]byte{
+						UserNameField:     []byte("user2"),
+						PasswordHashField: []byte("passwordHash2"),
+						UserRolesField:    []byte("role1"),
+						CredentialsField:  []byte("eck-ns-kb2-kibana-user-1622505600"),
+					},
+				},
+			},
+			want: users{
+				{Name: "user2", PasswordHash: []byte("passwordHash2"), Roles: []string{"role1"}},
+			},
+		},
+		{
+			name: "associated resource authenticating with a client certificate",
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: es.Namespace,
+						Name:      "user1",
+						Labels:    AssociatedUserLabels(es),
+					},
+					Data: map[string][]byte{
+						UserNameField:      []byte("user1"),
+						UserRolesField:     []byte("role1"),
+						CertificateDNField: []byte("CN=user1"),
+					},
+				},
+			},
+			want: users{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
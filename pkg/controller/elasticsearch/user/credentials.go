@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// KibanaServiceAccount is the service account Kibana authenticates with when it does not use a file realm user.
+	KibanaServiceAccount = "elastic/kibana"
+
+	// associationCredentialsPrefix prefixes the names of the service account tokens and API keys created by the
+	// operator, to tell them apart from the ones created by users.
+	associationCredentialsPrefix = "eck-"
+	// associationCredentialsGCDelay is the minimum age of unused association credentials before they are revoked.
+	// It leaves time for the cache of this controller to observe the secret referencing new credentials.
+	associationCredentialsGCDelay = 5 * time.Minute
+)
+
+var (
+	// MinAssociationCredentialsVersion is the minimum Elasticsearch version supporting the creation of service
+	// account tokens through the API.
+	MinAssociationCredentialsVersion = version.MustParse("7.13.0")
+
+	// serviceAccounts are the service accounts the operator creates tokens for.
+	serviceAccounts = []string{KibanaServiceAccount}
+)
+
+// AssociationCredentialsName returns the name of a service account token or an API key created at the given time
+// for the given associated user.
+func AssociationCredentialsName(userName string, createdAt time.Time) string {
+	// dots are not allowed in service account token names
+	return fmt.Sprintf("%s%s-%d", associationCredentialsPrefix, strings.ReplaceAll(userName, ".", "_"), createdAt.Unix())
+}
+
+// associationCredentialsCreatedAt returns the creation time of the service account token or the API key with the
+// given name, and false if it was not created by the operator.
+func associationCredentialsCreatedAt(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, associationCredentialsPrefix) {
+		return time.Time{}, false
+	}
+	timestamp, err := strconv.ParseInt(name[strings.LastIndex(name, "-")+1:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(timestamp, 0), true
+}
+
+// RoleDescriptor returns the descriptor of the given predefined role, as expected in the role descriptors of an API key.
+func RoleDescriptor(roleName string) (esclient.RoleDescriptor, error) {
+	role, ok := PredefinedRoles[roleName].(esclient.Role)
+	if !ok {
+		return nil, fmt.Errorf("unknown role %s", roleName)
+	}
+	indices := make([]esclient.RoleDescriptor, 0, len(role.Indices))
+	for _, index := range role.Indices {
+		indices = append(indices, esclient.RoleDescriptor{"names": index.Names, "privileges": index.Privileges})
+	}
+	return esclient.RoleDescriptor{"cluster": role.Cluster, "indices": indices}, nil
+}
+
+// GarbageCollectAssociationCredentials revokes the service account tokens and API keys created by the operator
+// that are not used anymore by any resource associated to the given Elasticsearch cluster.
+func GarbageCollectAssociationCredentials(
+	ctx context.Context,
+	c k8s.Client,
+	esClient esclient.Client,
+	es esv1.Elasticsearch,
+	now time.Time,
+) error {
+	esVersion := esClient.Version()
+	if !esVersion.IsSameOrAfter(MinAssociationCredentialsVersion) || !es.Spec.HTTP.TLS.Enabled() {
+		// associations rely on file realm users
+		return nil
+	}
+	inUse, err := AssociatedCredentials(c, es)
+	if err != nil {
+		return err
+	}
+	unused := func(name string) bool {
+		createdAt, created := associationCredentialsCreatedAt(name)
+		return created && !inUse.Has(name) && now.Sub(createdAt) >= associationCredentialsGCDelay
+	}
+
+	for _, serviceAccount := range serviceAccounts {
+		tokens, err := esClient.GetServiceAccountTokens(ctx, serviceAccount)
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if !unused(token) {
+				continue
+			}
+			log.Info("Deleting unused service account token",
+				"namespace", es.Namespace, "es_name", es.Name, "service_account", serviceAccount, "token_name", token)
+			if err := esClient.DeleteServiceAccountToken(ctx, serviceAccount, token); err != nil && !esclient.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	apiKeys, err := esClient.GetAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+	var unusedKeys []string
+	for _, key := range apiKeys {
+		if key.Invalidated || !unused(key.Name) {
+			continue
+		}
+		log.Info("Invalidating unused API key", "namespace", es.Namespace, "es_name", es.Name, "key_name", key.Name)
+		unusedKeys = append(unusedKeys, key.ID)
+	}
+	if len(unusedKeys) == 0 {
+		return nil
+	}
+	return esClient.InvalidateAPIKeys(ctx, unusedKeys)
+}
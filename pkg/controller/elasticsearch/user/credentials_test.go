@@ -0,0 +1,125 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func Test_associationCredentialsCreatedAt(t *testing.T) {
+	createdAt := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	name := AssociationCredentialsName("ns-kb.v2-kibana-user", createdAt)
+	require.Equal(t, "eck-ns-kb_v2-kibana-user-1622505600", name)
+
+	parsed, ok := associationCredentialsCreatedAt(name)
+	require.True(t, ok)
+	require.True(t, createdAt.Equal(parsed))
+
+	for _, name := range []string{"my-token", "eck-token", "eck-"} {
+		_, ok := associationCredentialsCreatedAt(name)
+		require.False(t, ok, name)
+	}
+}
+
+func TestRoleDescriptor(t *testing.T) {
+	descriptor, err := RoleDescriptor(ProbeUserRole)
+	require.NoError(t, err)
+	require.Equal(t, esclient.RoleDescriptor{"cluster": []string{"monitor"}, "indices": []esclient.RoleDescriptor{}}, descriptor)
+
+	_, err = RoleDescriptor("unknown")
+	require.Error(t, err)
+}
+
+func TestGarbageCollectAssociationCredentials(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	inUse := AssociationCredentialsName("ns-kb-kibana-user", now.Add(-time.Hour))
+	unused := AssociationCredentialsName("ns-kb-kibana-user", now.Add(-2*time.Hour))
+	tooRecent := AssociationCredentialsName("ns-kb2-kibana-user", now.Add(-time.Minute))
+	credentialsSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ns-kb-kibana-user", Labels: AssociatedUserLabels(es)},
+		Data:       map[string][]byte{CredentialsField: []byte(inUse)},
+	}
+
+	tests := []struct {
+		name         string
+		version      string
+		es           func() esv1.Elasticsearch
+		wantRequests []string
+	}{
+		{
+			name:    "revoke the unused credentials created by the operator",
+			version: "7.13.0",
+			es:      func() esv1.Elasticsearch { return es },
+			wantRequests: []string{
+				"GET /_security/service/elastic/kibana/credential",
+				"DELETE /_security/service/elastic/kibana/credential/token/" + unused,
+				"GET /_security/api_key",
+				`DELETE /_security/api_key {"ids":["unused-id"]}`,
+			},
+		},
+		{
+			name:         "version without association credentials",
+			version:      "7.12.1",
+			es:           func() esv1.Elasticsearch { return es },
+			wantRequests: nil,
+		},
+		{
+			name:    "TLS disabled",
+			version: "7.13.0",
+			es: func() esv1.Elasticsearch {
+				es := es
+				es.Spec.HTTP.TLS.SelfSignedCertificate = &commonv1.SelfSignedCertificate{Disabled: true}
+				return es
+			},
+			wantRequests: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			esClient := esclient.NewMockClient(version.MustParse(tt.version), func(req *http.Request) *http.Response {
+				request := req.Method + " " + req.URL.Path
+				if req.Body != nil {
+					body, err := ioutil.ReadAll(req.Body)
+					require.NoError(t, err)
+					if len(body) > 0 {
+						request += " " + string(body)
+					}
+				}
+				requests = append(requests, request)
+				switch request {
+				case "GET /_security/service/elastic/kibana/credential":
+					return esclient.NewMockResponse(200, req, `{"service_account":"elastic/kibana","count":4,"tokens":{`+
+						`"`+inUse+`":{},"`+unused+`":{},"`+tooRecent+`":{},"user-token":{}}}`)
+				case "GET /_security/api_key":
+					return esclient.NewMockResponse(200, req, `{"api_keys":[`+
+						`{"id":"in-use-id","name":"`+inUse+`","invalidated":false},`+
+						`{"id":"unused-id","name":"`+unused+`","invalidated":false},`+
+						`{"id":"invalidated-id","name":"`+unused+`","invalidated":true},`+
+						`{"id":"user-id","name":"user-key","invalidated":false}]}`)
+				}
+				return esclient.NewMockResponse(200, req, `{}`)
+			})
+			c := k8s.WrappedFakeClient(&credentialsSecret)
+			require.NoError(t, GarbageCollectAssociationCredentials(context.Background(), c, esClient, tt.es(), now))
+			require.Equal(t, tt.wantRequests, requests)
+		})
+	}
+}
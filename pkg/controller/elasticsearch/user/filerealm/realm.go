@@ -11,6 +11,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/stringsutil"
 )
 
 const (
@@ -78,6 +79,24 @@ func (f Realm) PasswordHashForUser(userName string) []byte {
 	return f.users[userName]
 }
 
+// ForUsers returns a file realm restricted to the given users and their roles.
+func (f Realm) ForUsers(names ...string) Realm {
+	result := New()
+	for _, name := range names {
+		hash, exists := f.users[name]
+		if !exists {
+			continue
+		}
+		result = result.WithUser(name, hash)
+		for role, users := range f.usersRoles {
+			if stringsutil.StringInSlice(name, users) {
+				result = result.WithRole(role, []string{name})
+			}
+		}
+	}
+	return result
+}
+
 // FileBytes returns a map with the content of the 2 file realm files.
 func (f Realm) FileBytes() map[string][]byte {
 	return map[string][]byte{
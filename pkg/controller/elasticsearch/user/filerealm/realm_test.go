@@ -41,6 +41,18 @@ func Test_FileRealm_PasswordHashForUser(t *testing.T) {
 	require.Equal(t, []byte(nil), r.PasswordHashForUser("unknown-user"))
 }
 
+func Test_FileRealm_ForUsers(t *testing.T) {
+	r := Realm{
+		users:      usersPasswordHashes{"user1": []byte("hash1"), "user2": []byte("hash2"), "user3": []byte("hash3")},
+		usersRoles: usersRoles{"role1": []string{"user1", "user2"}, "role2": []string{"user2", "user3"}, "role3": []string{"user3"}},
+	}
+	require.Equal(t, Realm{
+		users:      usersPasswordHashes{"user1": []byte("hash1"), "user2": []byte("hash2")},
+		usersRoles: usersRoles{"role1": []string{"user1", "user2"}, "role2": []string{"user2"}},
+	}, r.ForUsers("user1", "user2", "unknown-user"))
+	require.Equal(t, New(), r.ForUsers())
+}
+
 func Test_FromSecret(t *testing.T) {
 	secret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "fileRealmSecret"},
@@ -27,6 +27,8 @@ const (
 	ControllerUserName = "elastic-internal"
 	// ProbeUserName is used for the Elasticsearch readiness probe.
 	ProbeUserName = "elastic-internal-probe"
+	// MonitoringUserName is used by the Metricbeat sidecar to collect the metrics of Elasticsearch.
+	MonitoringUserName = "elastic-internal-monitoring"
 )
 
 // reconcileElasticUser reconciles a single secret holding the "elastic" user password.
@@ -51,6 +53,7 @@ func reconcileInternalUsers(c k8s.Client, es esv1.Elasticsearch, existingFileRea
 		users{
 			{Name: ControllerUserName, Roles: []string{SuperUserBuiltinRole}},
 			{Name: ProbeUserName, Roles: []string{ProbeUserRole}},
+			{Name: MonitoringUserName, Roles: []string{RemoteMonitoringCollectorBuiltinRole}},
 		},
 		esv1.InternalUsersSecret(es.Name))
 }
@@ -96,7 +99,7 @@ func reconcilePredefinedUsers(
 	return users, err
 }
 
-// reuseOrGeneratePassword updates the users with existing passwords reused from the existing K8s secret,
+// reuseOrGeneratePassword updates the users with existing names and passwords reused from the existing K8s secret,
 // or generates new passwords.
 func reuseOrGeneratePassword(c k8s.Client, users users, secretRef types.NamespacedName) (users, error) {
 	var secret corev1.Secret
@@ -111,10 +114,11 @@ func reuseOrGeneratePassword(c k8s.Client, users users, secretRef types.Namespac
 	if secret.Data == nil {
 		secret.Data = map[string][]byte{}
 	}
-	// either reuse the password or generate a new one
+	// either reuse the password, along with the name the user got in a rotation of the credentials, or generate a new one
 	for i, u := range users {
-		if password, exists := secret.Data[u.Name]; exists {
-			users[i].Password = password
+		if existing, exists := UserCredentials(secret, u.Name); exists {
+			users[i].Name = existing.Name
+			users[i].Password = []byte(existing.Password)
 		} else {
 			users[i].Password = common.FixedLengthRandomPasswordBytes()
 		}
@@ -219,23 +219,28 @@ func Test_reconcileInternalUsers(t *testing.T) {
 			got, err := reconcileInternalUsers(c, es, tt.existingFileRealm)
 			require.NoError(t, err)
 			// check returned users
-			require.Len(t, got, 2)
+			require.Len(t, got, 3)
 			controllerUser := got[0]
 			probeUser := got[1]
+			monitoringUser := got[2]
 			// names and roles are always the same
 			require.Equal(t, ControllerUserName, controllerUser.Name)
 			require.Equal(t, []string{SuperUserBuiltinRole}, controllerUser.Roles)
 			require.Equal(t, ProbeUserName, probeUser.Name)
 			require.Equal(t, []string{ProbeUserRole}, probeUser.Roles)
+			require.Equal(t, MonitoringUserName, monitoringUser.Name)
+			require.Equal(t, []string{RemoteMonitoringCollectorBuiltinRole}, monitoringUser.Roles)
 			// passwords and hash should always match
 			require.NoError(t, bcrypt.CompareHashAndPassword(controllerUser.PasswordHash, controllerUser.Password))
 			require.NoError(t, bcrypt.CompareHashAndPassword(probeUser.PasswordHash, probeUser.Password))
+			require.NoError(t, bcrypt.CompareHashAndPassword(monitoringUser.PasswordHash, monitoringUser.Password))
 			// reconciled secret should have the updated passwords
 			var secret corev1.Secret
 			err = c.Get(types.NamespacedName{Namespace: es.Namespace, Name: esv1.InternalUsersSecret(es.Name)}, &secret)
 			require.NoError(t, err)
 			require.Equal(t, controllerUser.Password, secret.Data[ControllerUserName])
 			require.Equal(t, probeUser.Password, secret.Data[ProbeUserName])
+			require.Equal(t, monitoringUser.Password, secret.Data[MonitoringUserName])
 		})
 	}
 }
@@ -7,6 +7,7 @@ package user
 import (
 	"context"
 	"reflect"
+	"time"
 
 	"go.elastic.co/apm"
 	corev1 "k8s.io/api/core/v1"
@@ -14,9 +15,11 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
@@ -31,7 +34,8 @@ var log = logf.Log.WithName("elasticsearch-user")
 
 // ReconcileUsersAndRoles fetches all users and roles and aggregates them into a single
 // Kubernetes secret mounted in the Elasticsearch Pods.
-// That secret contains the file realm files (`users` and `users_roles`) and the file roles (`roles.yml`).
+// That secret contains the file realm files (`users` and `users_roles`), the file roles (`roles.yml`) and the role
+// mapping of the PKI realm (`pki_role_mapping.yml`).
 // Users are aggregated from various sources:
 // - predefined users include the controller user, the probe user, and the public-facing elastic user
 // - associated users come from resource associations (eg. Kibana or APMServer)
@@ -54,15 +58,29 @@ func ReconcileUsersAndRoles(
 	if err != nil {
 		return esclient.BasicAuth{}, err
 	}
-	fileRealm, controllerUser, err := aggregateFileRealm(c, es, watched, recorder)
+	// start a rotation of the credentials if due, the new users are added to the file realm first
+	rotation, rotationStarted, err := reconcileCredentialsRotationState(c, es, time.Now())
+	if err != nil {
+		return esclient.BasicAuth{}, err
+	}
+	fileRealm, controllerUser, err := aggregateFileRealm(c, es, watched, recorder, rotation)
+	if err != nil {
+		return esclient.BasicAuth{}, err
+	}
+
+	roleMapping, err := associatedRoleMapping(c, es)
 	if err != nil {
 		return esclient.BasicAuth{}, err
 	}
 
 	// reconcile the aggregate secret
-	if err := reconcileRolesFileRealmSecret(c, es, roles, fileRealm); err != nil {
+	if err := reconcileRolesFileRealmSecret(c, es, roles, fileRealm, roleMapping); err != nil {
 		return esclient.BasicAuth{}, err
 	}
+	if rotationStarted {
+		// speed up the propagation of the new file realm to the Pods
+		annotation.MarkPodsAsUpdated(c, client.InNamespace(es.Namespace), label.NewLabelSelectorForElasticsearch(es))
+	}
 
 	// return the controller user for next reconciliation steps to interact with Elasticsearch
 	return controllerUser, nil
@@ -77,11 +95,15 @@ func getExistingFileRealm(c k8s.Client, es esv1.Elasticsearch) (filerealm.Realm,
 }
 
 // aggregateFileRealm builds a single file realm from multiple ones, and returns the controller user credentials.
+// The users created by the rotation of the credentials in progress are added to the file realm along with the ones
+// they replace, and the users replaced by the last rotation are kept during the grace period. The returned controller
+// user credentials keep the current ones until the rotation is completed.
 func aggregateFileRealm(
 	c k8s.Client,
 	es esv1.Elasticsearch,
 	watched watches.DynamicWatches,
 	recorder record.EventRecorder,
+	rotation credentialsRotation,
 ) (filerealm.Realm, esclient.BasicAuth, error) {
 	// retrieve existing file realm to reuse predefined users password hashes if possible
 	existingFileRealm, err := getExistingFileRealm(c, es)
@@ -114,11 +136,26 @@ func aggregateFileRealm(
 		return filerealm.Realm{}, esclient.BasicAuth{}, err
 	}
 
+	// add the users created by the rotation in progress
+	realmInternalUsers, err := withRotatedUsers(internalUsers, rotation, existingFileRealm)
+	if err != nil {
+		return filerealm.Realm{}, esclient.BasicAuth{}, err
+	}
+	realmElasticUser, err := withRotatedUsers(elasticUser, rotation, existingFileRealm)
+	if err != nil {
+		return filerealm.Realm{}, esclient.BasicAuth{}, err
+	}
+	realmAssociatedUsers, err := withRotatedUsers(associatedUsers, rotation, existingFileRealm)
+	if err != nil {
+		return filerealm.Realm{}, esclient.BasicAuth{}, err
+	}
+
 	// merge all file realms together, the last one having precedence
 	fileRealm := filerealm.MergedFrom(
-		internalUsers.fileRealm(),
-		elasticUser.fileRealm(),
-		associatedUsers.fileRealm(),
+		rotation.previousUsers,
+		realmInternalUsers.fileRealm(),
+		realmElasticUser.fileRealm(),
+		realmAssociatedUsers.fileRealm(),
 		userProvidedFileRealm,
 	)
 
@@ -149,14 +186,22 @@ func RolesFileRealmSecretKey(es esv1.Elasticsearch) types.NamespacedName {
 	return types.NamespacedName{Namespace: es.Namespace, Name: esv1.RolesAndFileRealmSecret(es.Name)}
 }
 
-// reconcileRolesFileRealmSecret creates or updates the single secret holding the file realm and the file-based roles.
-func reconcileRolesFileRealmSecret(c k8s.Client, es esv1.Elasticsearch, roles RolesFileContent, fileRealm filerealm.Realm) error {
+// reconcileRolesFileRealmSecret creates or updates the single secret holding the file realm, the file-based roles,
+// and the role mapping of the PKI realm.
+func reconcileRolesFileRealmSecret(
+	c k8s.Client,
+	es esv1.Elasticsearch,
+	roles RolesFileContent,
+	fileRealm filerealm.Realm,
+	roleMapping []byte,
+) error {
 	secretData := fileRealm.FileBytes()
 	rolesBytes, err := roles.FileBytes()
 	if err != nil {
 		return err
 	}
 	secretData[RolesFile] = rolesBytes
+	secretData[PKIRoleMappingFile] = roleMapping
 
 	expected := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
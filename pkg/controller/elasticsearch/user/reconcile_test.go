@@ -27,8 +27,9 @@ func TestReconcileUsersAndRoles(t *testing.T) {
 	var reconciledSecret corev1.Secret
 	err = c.Get(RolesFileRealmSecretKey(sampleEsWithAuth), &reconciledSecret)
 	require.NoError(t, err)
-	require.Len(t, reconciledSecret.Data, 3)
+	require.Len(t, reconciledSecret.Data, 4)
 	require.NotEmpty(t, reconciledSecret.Data[RolesFile])
+	require.NotEmpty(t, reconciledSecret.Data[PKIRoleMappingFile])
 	require.NotEmpty(t, reconciledSecret.Data[filerealm.UsersRolesFile])
 	require.NotEmpty(t, reconciledSecret.Data[filerealm.UsersFile])
 }
@@ -50,31 +51,34 @@ func Test_ReconcileRolesFileRealmSecret(t *testing.T) {
 		WithRole("role1", []string{"user1"}).
 		WithRole("role2", []string{"user2"})
 
-	err := reconcileRolesFileRealmSecret(c, es, roles, realm)
+	roleMapping := []byte("kibana_system:\n- CN=ns-kb-kibana-user\n")
+
+	err := reconcileRolesFileRealmSecret(c, es, roles, realm, roleMapping)
 	require.NoError(t, err)
 	// retrieve reconciled secret
 	var secret corev1.Secret
 	err = c.Get(types.NamespacedName{Namespace: es.Namespace, Name: esv1.RolesAndFileRealmSecret(es.Name)}, &secret)
 	require.NoError(t, err)
-	require.Len(t, secret.Data, 3)
+	require.Len(t, secret.Data, 4)
 	require.Contains(t, string(secret.Data[RolesFile]), "click_admins")
+	require.Equal(t, roleMapping, secret.Data[PKIRoleMappingFile])
 	require.Contains(t, string(secret.Data[filerealm.UsersRolesFile]), "role1:user1")
 	require.Contains(t, string(secret.Data[filerealm.UsersFile]), "user1:hash1")
 }
 
 func Test_aggregateFileRealm(t *testing.T) {
 	c := k8s.WrappedFakeClient(sampleUserProvidedFileRealmSecrets...)
-	fileRealm, controllerUser, err := aggregateFileRealm(c, sampleEsWithAuth, initDynamicWatches(), record.NewFakeRecorder(10))
+	fileRealm, controllerUser, err := aggregateFileRealm(c, sampleEsWithAuth, initDynamicWatches(), record.NewFakeRecorder(10), credentialsRotation{})
 	require.NoError(t, err)
 	require.NotEmpty(t, controllerUser.Password)
 	actualUsers := fileRealm.UserNames()
-	require.ElementsMatch(t, []string{"elastic", "elastic-internal", "elastic-internal-probe", "user1", "user2", "user3"}, actualUsers)
+	require.ElementsMatch(t, []string{"elastic", "elastic-internal", "elastic-internal-probe", "elastic-internal-monitoring", "user1", "user2", "user3"}, actualUsers)
 }
 
 func Test_aggregateRoles(t *testing.T) {
 	c := k8s.WrappedFakeClient(sampleUserProvidedRolesSecret...)
 	roles, err := aggregateRoles(c, sampleEsWithAuth, initDynamicWatches(), record.NewFakeRecorder(10))
 	require.NoError(t, err)
-	require.Len(t, roles, 6)
+	require.Len(t, roles, 10)
 	require.Contains(t, roles, ProbeUserRole, "role1", "role2")
 }
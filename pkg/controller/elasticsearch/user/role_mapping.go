@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// PKIRoleMappingFile is the name of the file in the ES config dir mapping the roles of the associated resources
+// authenticating with a client certificate to the distinguished name of their certificate, in the PKI realm.
+const PKIRoleMappingFile = "pki_role_mapping.yml"
+
+// associatedRoleMapping returns the content of the role mapping file of the PKI realm, built from the secrets of the
+// resources associated to the given Elasticsearch cluster which authenticate with a client certificate.
+func associatedRoleMapping(c k8s.Client, es esv1.Elasticsearch) ([]byte, error) {
+	associatedSecrets, err := listAssociatedSecrets(c, es)
+	if err != nil {
+		return nil, err
+	}
+	// role name -> distinguished names
+	mapping := map[string][]string{}
+	for _, secret := range associatedSecrets {
+		dn := string(secret.Data[CertificateDNField])
+		if dn == "" {
+			continue
+		}
+		for _, role := range strings.Split(string(secret.Data[UserRolesField]), ",") {
+			if role != "" {
+				mapping[role] = append(mapping[role], dn)
+			}
+		}
+	}
+	for _, dns := range mapping {
+		sort.Strings(dns)
+	}
+	return yaml.Marshal(mapping)
+}
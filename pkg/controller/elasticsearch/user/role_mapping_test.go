@@ -0,0 +1,65 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func Test_associatedRoleMapping(t *testing.T) {
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Name: "es", Namespace: "ns"}}
+	secret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: es.Namespace, Name: name, Labels: AssociatedUserLabels(es)},
+			Data:       data,
+		}
+	}
+
+	// no associated resource authenticating with a client certificate
+	roleMapping, err := associatedRoleMapping(k8s.WrappedFakeClient(), es)
+	require.NoError(t, err)
+	require.Equal(t, "{}\n", string(roleMapping))
+
+	c := k8s.WrappedFakeClient(
+		secret("kb2", map[string][]byte{
+			UserNameField:      []byte("ns-kb2-kibana-user"),
+			UserRolesField:     []byte("kibana_system"),
+			CertificateDNField: []byte("CN=ns-kb2-kibana-user"),
+		}),
+		secret("kb1", map[string][]byte{
+			UserNameField:      []byte("ns-kb1-kibana-user"),
+			UserRolesField:     []byte("kibana_system"),
+			CertificateDNField: []byte("CN=ns-kb1-kibana-user"),
+		}),
+		secret("apm", map[string][]byte{
+			UserNameField:      []byte("ns-apm-apm-user"),
+			UserRolesField:     []byte("eck_apm_user_role_v75,apm_system"),
+			CertificateDNField: []byte("CN=ns-apm-apm-user"),
+		}),
+		// file realm user
+		secret("kb3", map[string][]byte{
+			UserNameField:     []byte("ns-kb3-kibana-user"),
+			PasswordHashField: []byte("hash"),
+			UserRolesField:    []byte("kibana_system"),
+		}),
+	)
+	roleMapping, err = associatedRoleMapping(c, es)
+	require.NoError(t, err)
+	require.Equal(t, `apm_system:
+- CN=ns-apm-apm-user
+eck_apm_user_role_v75:
+- CN=ns-apm-apm-user
+kibana_system:
+- CN=ns-kb1-kibana-user
+- CN=ns-kb2-kibana-user
+`, string(roleMapping))
+}
@@ -16,6 +16,10 @@ const (
 
 	// SuperUserBuiltinRole is the name of the built-in superuser role.
 	SuperUserBuiltinRole = "superuser"
+	// RemoteMonitoringCollectorBuiltinRole is the name of the built-in role allowing to collect the metrics of a cluster.
+	RemoteMonitoringCollectorBuiltinRole = "remote_monitoring_collector"
+	// RemoteMonitoringAgentBuiltinRole is the name of the built-in role allowing to ship metrics to a monitoring cluster.
+	RemoteMonitoringAgentBuiltinRole = "remote_monitoring_agent"
 	// ProbeUserRole is the name of the role used by the internal probe user.
 	ProbeUserRole = "elastic_internal_probe_user"
 
@@ -25,6 +29,17 @@ const (
 	ApmUserRoleV7 = "eck_apm_user_role_v7"
 	// ApmUserRoleV75 is the name of the role used by APMServer instances to connect to Elasticsearch from version 7.5
 	ApmUserRoleV75 = "eck_apm_user_role_v75"
+	// ApmAPIKeyRole is the name of the role API keys of APMServer instances are restricted to. It includes the
+	// privileges of the ingest_admin and apm_system built-in roles, which cannot be referenced by API keys.
+	ApmAPIKeyRole = "eck_apm_api_key_role"
+
+	// BeatUserRoleV7 is the name of the role used by Beat instances to publish events to Elasticsearch from version 7.0.
+	BeatUserRoleV7 = "eck_beat_user_role_v7"
+
+	// AgentUserRoleV7 is the name of the role used by standalone Elastic Agent instances to publish events to Elasticsearch.
+	AgentUserRoleV7 = "eck_agent_user_role_v7"
+	// FleetServerUserRoleV7 is the name of the role used by Fleet Server instances to connect to Elasticsearch.
+	FleetServerUserRoleV7 = "eck_fleet_server_user_role_v7"
 )
 
 var (
@@ -58,6 +73,50 @@ var (
 				},
 			},
 		},
+		ApmAPIKeyRole: esclient.Role{
+			Cluster: []string{"monitor", "manage_ilm", "manage_api_key", "manage_index_templates", "manage_pipeline"},
+			Indices: []esclient.IndexRole{
+				{
+					Names:      []string{"apm-*"},
+					Privileges: []string{"manage", "create_doc", "create_index"},
+				},
+				{
+					Names:      []string{".monitoring-beats-*"},
+					Privileges: []string{"create_doc", "create_index"},
+				},
+			},
+		},
+		BeatUserRoleV7: esclient.Role{
+			Cluster: []string{"monitor", "manage_ilm", "manage_index_templates", "manage_pipeline"},
+			Indices: []esclient.IndexRole{
+				{
+					Names:      []string{"*beat-*"},
+					Privileges: []string{"manage", "write", "create_index"},
+				},
+			},
+		},
+		AgentUserRoleV7: esclient.Role{
+			Cluster: []string{"monitor"},
+			Indices: []esclient.IndexRole{
+				{
+					Names:      []string{"logs-*", "metrics-*", "traces-*", "synthetics-*"},
+					Privileges: []string{"auto_configure", "create_doc"},
+				},
+			},
+		},
+		FleetServerUserRoleV7: esclient.Role{
+			Cluster: []string{"monitor", "manage_api_key"},
+			Indices: []esclient.IndexRole{
+				{
+					Names:      []string{".fleet-*"},
+					Privileges: []string{"all"},
+				},
+				{
+					Names:      []string{"logs-*", "metrics-*", "traces-*", "synthetics-*"},
+					Privileges: []string{"auto_configure", "create_doc"},
+				},
+			},
+		},
 	}
 )
 
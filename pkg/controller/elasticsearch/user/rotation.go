@@ -0,0 +1,503 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user/filerealm"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
+)
+
+const (
+	// RotateCredentialsAnnotation rotates the passwords of the elastic user, the operator user and the users of the
+	// associated resources each time its value changes on an Elasticsearch resource. Any value can be used, for
+	// example the current date.
+	RotateCredentialsAnnotation = "elasticsearch.k8s.elastic.co/rotate-credentials"
+
+	// rotationTriggerAnnotation records the value of RotateCredentialsAnnotation handled by the last rotation.
+	rotationTriggerAnnotation = "elasticsearch.k8s.elastic.co/rotation-trigger"
+	// rotatedAtAnnotation records the time of the last rotation, or of the creation of the rotation state.
+	rotatedAtAnnotation = "elasticsearch.k8s.elastic.co/rotated-at"
+	// rotationStartedAtAnnotation records the start time of the rotation in progress, from which the names of the
+	// new users are derived.
+	rotationStartedAtAnnotation = "elasticsearch.k8s.elastic.co/rotation-started-at"
+
+	// RotationCheckInterval is the interval at which the propagation of the new users to the nodes is checked.
+	RotationCheckInterval = 10 * time.Second
+	// CredentialsGracePeriod is the time during which the previous credentials remain valid after a rotation, to let
+	// the associated resources be restarted and the clients read the new ones.
+	CredentialsGracePeriod = 15 * time.Minute
+)
+
+// rotatedPredefinedUsers are the predefined users whose passwords are rotated. The probe and monitoring users are
+// used from within the Elasticsearch Pods and are not rotated.
+var rotatedPredefinedUsers = []string{ElasticUserName, ControllerUserName}
+
+// RotatedUserName returns the name of the user replacing the user with the given base name in the rotation of the
+// credentials started at the given time.
+func RotatedUserName(baseName string, startedAt time.Time) string {
+	return fmt.Sprintf("%s-%d", baseName, startedAt.Unix())
+}
+
+// UserRotatedAt returns the start time of the rotation of the credentials in which the user with the given name
+// replaced the user with the given base name, and false if it was not created by a rotation.
+func UserRotatedAt(name, baseName string) (time.Time, bool) {
+	suffix := strings.TrimPrefix(name, baseName+"-")
+	if suffix == name {
+		return time.Time{}, false
+	}
+	timestamp, err := strconv.ParseInt(suffix, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(timestamp, 0), true
+}
+
+// IsUserName returns true if the given user name is the given base name, or the name of a user replacing it in a
+// rotation of the credentials.
+func IsUserName(name, baseName string) bool {
+	_, rotated := UserRotatedAt(name, baseName)
+	return name == baseName || rotated
+}
+
+// UserCredentials returns the credentials of the user with the given base name, or of the user replacing it in a
+// rotation of the credentials, from a secret holding passwords under the user name keys.
+func UserCredentials(secret corev1.Secret, baseName string) (esclient.BasicAuth, bool) {
+	for name, password := range secret.Data {
+		if IsUserName(name, baseName) {
+			return esclient.BasicAuth{Name: name, Password: string(password)}, true
+		}
+	}
+	return esclient.BasicAuth{}, false
+}
+
+// CredentialsRotationSecretKey returns a reference to the K8s secret holding the state of the rotation of the credentials.
+func CredentialsRotationSecretKey(es esv1.Elasticsearch) types.NamespacedName {
+	return types.NamespacedName{Namespace: es.Namespace, Name: esv1.CredentialsRotationSecret(es.Name)}
+}
+
+// reconcileCredentialsRotationSecret creates the secret holding the state of the rotation of the credentials if it
+// does not exist yet. While a rotation is in progress, the secret holds the passwords of the new users keyed by user
+// name. Once the secrets are updated with the new users, it holds the file realm of the previous users until the end
+// of the grace period. It is empty otherwise.
+func reconcileCredentialsRotationSecret(c k8s.Client, es esv1.Elasticsearch, now time.Time) (corev1.Secret, error) {
+	expected := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: CredentialsRotationSecretKey(es).Namespace,
+			Name:      CredentialsRotationSecretKey(es).Name,
+			Labels:    common.AddCredentialsLabel(label.NewLabels(k8s.ExtractNamespacedName(&es))),
+			Annotations: map[string]string{
+				// the age of existing passwords is not known, consider they were just created
+				rotatedAtAnnotation: now.Format(time.RFC3339),
+				// a value set at creation time does not trigger a rotation
+				rotationTriggerAnnotation: es.Annotations[RotateCredentialsAnnotation],
+			},
+		},
+	}
+	var reconciled corev1.Secret
+	err := reconciler.ReconcileResource(reconciler.Params{
+		Client:     c,
+		Owner:      &es,
+		Expected:   &expected,
+		Reconciled: &reconciled,
+		NeedsUpdate: func() bool {
+			return !maps.IsSubset(expected.Labels, reconciled.Labels)
+		},
+		UpdateReconciled: func() {
+			reconciled.Labels = maps.Merge(reconciled.Labels, expected.Labels)
+		},
+	})
+	return reconciled, err
+}
+
+// credentialsRotation is the state of the rotation of the credentials.
+type credentialsRotation struct {
+	// startedAt is the start time of the rotation in progress, zero if there is none.
+	startedAt time.Time
+	// newPasswords are the passwords of the users created by the rotation in progress, keyed by user name.
+	newPasswords map[string][]byte
+	// previousUsers are the users replaced by the last rotation, kept in the file realm during the grace period.
+	previousUsers filerealm.Realm
+}
+
+func (r credentialsRotation) inProgress() bool {
+	return !r.startedAt.IsZero()
+}
+
+// baseUserName returns the name of the user replaced by the given new user.
+func (r credentialsRotation) baseUserName(newName string) string {
+	return strings.TrimSuffix(newName, fmt.Sprintf("-%d", r.startedAt.Unix()))
+}
+
+// parseCredentialsRotation reads the state of the rotation of the credentials from its secret.
+func parseCredentialsRotation(rotationSecret corev1.Secret) (credentialsRotation, error) {
+	rotation := credentialsRotation{newPasswords: map[string][]byte{}}
+	previousUsers, err := filerealm.FromSecret(rotationSecret)
+	if err != nil {
+		return rotation, err
+	}
+	rotation.previousUsers = previousUsers
+	startedAt, inProgress := rotationSecret.Annotations[rotationStartedAtAnnotation]
+	if !inProgress {
+		return rotation, nil
+	}
+	if rotation.startedAt, err = time.Parse(time.RFC3339, startedAt); err != nil {
+		return rotation, err
+	}
+	for name, password := range rotationSecret.Data {
+		if name == filerealm.UsersFile || name == filerealm.UsersRolesFile {
+			continue
+		}
+		rotation.newPasswords[name] = password
+	}
+	return rotation, nil
+}
+
+// rotationDue returns true if the credentials must be rotated, because a rotation is requested through
+// RotateCredentialsAnnotation or because the passwords reached the maximum age of spec.auth.rotation.
+func rotationDue(es esv1.Elasticsearch, rotationSecret corev1.Secret, now time.Time) bool {
+	trigger := es.Annotations[RotateCredentialsAnnotation]
+	if trigger != "" && trigger != rotationSecret.Annotations[rotationTriggerAnnotation] {
+		return true
+	}
+	maxAge := es.Spec.Auth.Rotation.MaxAgeOrZero()
+	if maxAge == 0 {
+		return false
+	}
+	rotatedAt, err := time.Parse(time.RFC3339, rotationSecret.Annotations[rotatedAtAnnotation])
+	if err != nil {
+		// the age of the passwords is unknown
+		return true
+	}
+	return !now.Before(rotatedAt.Add(maxAge))
+}
+
+// gracePeriodRemaining returns the duration until the users replaced by the last rotation are removed.
+func gracePeriodRemaining(rotationSecret corev1.Secret, now time.Time) time.Duration {
+	rotatedAt, err := time.Parse(time.RFC3339, rotationSecret.Annotations[rotatedAtAnnotation])
+	if err != nil {
+		return 0
+	}
+	return rotatedAt.Add(CredentialsGracePeriod).Sub(now)
+}
+
+// reconcileCredentialsRotationState returns the state of the rotation of the credentials. The users replaced by the
+// last rotation are removed at the end of the grace period. If no rotation is in progress and one is due, new users
+// are created with new passwords and true is returned. A rotation due during the grace period is postponed until
+// the previous users are removed.
+func reconcileCredentialsRotationState(c k8s.Client, es esv1.Elasticsearch, now time.Time) (credentialsRotation, bool, error) {
+	rotationSecret, err := reconcileCredentialsRotationSecret(c, es, now)
+	if err != nil {
+		return credentialsRotation{}, false, err
+	}
+	rotation, err := parseCredentialsRotation(rotationSecret)
+	if err != nil || rotation.inProgress() {
+		return rotation, false, err
+	}
+	if len(rotationSecret.Data) > 0 {
+		if gracePeriodRemaining(rotationSecret, now) > 0 {
+			return rotation, false, nil
+		}
+		previousUsers := rotation.previousUsers.UserNames()
+		sort.Strings(previousUsers)
+		log.Info("Removing the users replaced by the last rotation of the credentials",
+			"namespace", es.Namespace, "es_name", es.Name, "users", previousUsers)
+		rotationSecret.Data = nil
+		if err := c.Update(&rotationSecret); err != nil {
+			return rotation, false, err
+		}
+		rotation.previousUsers = filerealm.New()
+	}
+	if !rotationDue(es, rotationSecret, now) {
+		return rotation, false, nil
+	}
+
+	passwords := make(map[string][]byte)
+	for _, name := range rotatedPredefinedUsers {
+		passwords[RotatedUserName(name, now)] = common.FixedLengthRandomPasswordBytes()
+	}
+	associatedUserSecrets, err := listAssociatedUserSecrets(c, es)
+	if err != nil {
+		return rotation, false, err
+	}
+	for _, secret := range associatedUserSecrets {
+		if _, exists := secret.Annotations[PasswordSecretAnnotation]; !exists || len(secret.Data[UserNameField]) == 0 {
+			// the password of this user cannot be updated until the association is reconciled again
+			log.Info("Not rotating the credentials of an associated user with an unknown password secret",
+				"namespace", secret.Namespace, "secret_name", secret.Name)
+			continue
+		}
+		// associated user secrets are named after the user before any rotation
+		passwords[RotatedUserName(secret.Name, now)] = common.FixedLengthRandomPasswordBytes()
+	}
+
+	log.Info("Starting the rotation of the credentials", "namespace", es.Namespace, "es_name", es.Name, "users", sortedNames(passwords))
+	rotationSecret.Data = passwords
+	if rotationSecret.Annotations == nil {
+		rotationSecret.Annotations = map[string]string{}
+	}
+	rotationSecret.Annotations[rotationTriggerAnnotation] = es.Annotations[RotateCredentialsAnnotation]
+	rotationSecret.Annotations[rotationStartedAtAnnotation] = now.Format(time.RFC3339)
+	if err := c.Update(&rotationSecret); err != nil {
+		return rotation, false, err
+	}
+	rotation, err = parseCredentialsRotation(rotationSecret)
+	return rotation, true, err
+}
+
+// withRotatedUsers returns a copy of the given users to which the users replacing them in the rotation in progress
+// are added, with the same roles, their new password and the matching hash, reused from the given file realm if
+// possible.
+func withRotatedUsers(all users, rotation credentialsRotation, fileRealm filerealm.Realm) (users, error) {
+	result := make(users, len(all))
+	copy(result, all)
+	for _, newName := range sortedNames(rotation.newPasswords) {
+		baseName := rotation.baseUserName(newName)
+		for _, u := range all {
+			if u.Name == newName || !IsUserName(u.Name, baseName) {
+				continue
+			}
+			password := rotation.newPasswords[newName]
+			hashed, err := reuseOrGenerateHash(users{{Name: newName, Password: password}}, fileRealm)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, user{Name: newName, Password: password, PasswordHash: hashed[0].PasswordHash, Roles: u.Roles})
+		}
+	}
+	return result, nil
+}
+
+// ReconcileCredentialsRotation completes the rotation of the credentials in progress, if any, once the given
+// authenticate function confirms that the new users are accepted by the Elasticsearch nodes. The new users are then
+// stored in the secrets of the associated resources and in the secrets of the predefined users, and the previous
+// users are kept in the file realm during CredentialsGracePeriod, so that the associated resources and the clients
+// can still authenticate until they use the new ones.
+// It returns the credentials of the controller user to use from now on, and the duration after which the rotation
+// must be reconciled again, zero if not needed.
+func ReconcileCredentialsRotation(
+	c k8s.Client,
+	es esv1.Elasticsearch,
+	recorder record.EventRecorder,
+	controllerUser esclient.BasicAuth,
+	authenticate func(esclient.BasicAuth) error,
+) (esclient.BasicAuth, time.Duration, error) {
+	now := time.Now()
+	var rotationSecret corev1.Secret
+	if err := c.Get(CredentialsRotationSecretKey(es), &rotationSecret); err != nil {
+		return controllerUser, 0, err
+	}
+	rotation, err := parseCredentialsRotation(rotationSecret)
+	if err != nil {
+		return controllerUser, 0, err
+	}
+	if !rotation.inProgress() {
+		return controllerUser, nextReconcileIn(es, rotationSecret, now), nil
+	}
+
+	// the file realm holds the new users, wait for all the nodes to reload it
+	newControllerName := RotatedUserName(ControllerUserName, rotation.startedAt)
+	newControllerUser := esclient.BasicAuth{Name: newControllerName, Password: string(rotation.newPasswords[newControllerName])}
+	if err := authenticate(newControllerUser); err != nil {
+		log.V(1).Info("New users not accepted by all the nodes yet", "namespace", es.Namespace, "es_name", es.Name, "error", err.Error())
+		return controllerUser, RotationCheckInterval, nil
+	}
+
+	fileRealm, err := getExistingFileRealm(c, es)
+	if err != nil {
+		return controllerUser, 0, err
+	}
+	if len(rotationSecret.Data[filerealm.UsersFile]) == 0 {
+		// keep the previous users in the file realm while the secrets they are read from are updated
+		previousUsers := fileRealm.ForUsers(replacedUserNames(fileRealm, rotation)...)
+		for name, data := range previousUsers.FileBytes() {
+			rotationSecret.Data[name] = data
+		}
+		if err := c.Update(&rotationSecret); err != nil {
+			return controllerUser, 0, err
+		}
+	}
+	if err := switchAssociatedUsers(c, es, rotation, fileRealm); err != nil {
+		return controllerUser, 0, err
+	}
+	if err := switchPredefinedUsers(c, es, rotation); err != nil {
+		return controllerUser, 0, err
+	}
+
+	users := sortedNames(rotation.newPasswords)
+	for _, name := range users {
+		delete(rotationSecret.Data, name)
+	}
+	delete(rotationSecret.Annotations, rotationStartedAtAnnotation)
+	rotationSecret.Annotations[rotatedAtAnnotation] = now.Format(time.RFC3339)
+	if err := c.Update(&rotationSecret); err != nil {
+		// the new controller user is already stored
+		return newControllerUser, 0, err
+	}
+	log.Info("Completed the rotation of the credentials", "namespace", es.Namespace, "es_name", es.Name, "users", users)
+	if recorder != nil {
+		recorder.Eventf(&es, corev1.EventTypeNormal, events.EventReasonCredentialsRotated,
+			"Rotated the credentials to users %s, the previous users remain valid for %s", strings.Join(users, ", "), CredentialsGracePeriod)
+	}
+	return newControllerUser, nextReconcileIn(es, rotationSecret, now), nil
+}
+
+// replacedUserNames returns the names of the users of the given file realm replaced by the rotation in progress.
+func replacedUserNames(fileRealm filerealm.Realm, rotation credentialsRotation) []string {
+	var names []string
+	for _, newName := range sortedNames(rotation.newPasswords) {
+		baseName := rotation.baseUserName(newName)
+		for _, name := range fileRealm.UserNames() {
+			if name != newName && IsUserName(name, baseName) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// nextReconcileIn returns the duration until the users replaced by the last rotation must be removed, or until the
+// passwords reach their maximum age, zero if none of them is expected.
+func nextReconcileIn(es esv1.Elasticsearch, rotationSecret corev1.Secret, now time.Time) time.Duration {
+	next := nextRotationIn(es, rotationSecret, now)
+	if len(rotationSecret.Data) == 0 {
+		return next
+	}
+	remaining := gracePeriodRemaining(rotationSecret, now)
+	if remaining <= 0 {
+		remaining = RotationCheckInterval
+	}
+	if next == 0 || remaining < next {
+		return remaining
+	}
+	return next
+}
+
+// nextRotationIn returns the duration until the passwords reach their maximum age, zero if they do not expire.
+func nextRotationIn(es esv1.Elasticsearch, rotationSecret corev1.Secret, now time.Time) time.Duration {
+	maxAge := es.Spec.Auth.Rotation.MaxAgeOrZero()
+	if maxAge == 0 {
+		return 0
+	}
+	rotatedAt, err := time.Parse(time.RFC3339, rotationSecret.Annotations[rotatedAtAnnotation])
+	if err != nil || !now.Before(rotatedAt.Add(maxAge)) {
+		return RotationCheckInterval
+	}
+	return rotatedAt.Add(maxAge).Sub(now)
+}
+
+// switchAssociatedUsers stores the names and passwords of the new associated users in the secrets read by the
+// associated resources, then in the associated user secrets so that the association controllers keep them and
+// update the configuration of the associated resources. The former passwords are left in the secrets of the
+// associated resources, which may read them until their configuration is updated.
+func switchAssociatedUsers(c k8s.Client, es esv1.Elasticsearch, rotation credentialsRotation, fileRealm filerealm.Realm) error {
+	associatedUserSecrets, err := listAssociatedUserSecrets(c, es)
+	if err != nil {
+		return err
+	}
+	for i := range associatedUserSecrets {
+		userSecret := associatedUserSecrets[i]
+		name := RotatedUserName(userSecret.Name, rotation.startedAt)
+		password, rotated := rotation.newPasswords[name]
+		if !rotated {
+			continue
+		}
+		ref := strings.SplitN(userSecret.Annotations[PasswordSecretAnnotation], "/", 2)
+		if len(ref) != 2 {
+			return fmt.Errorf("invalid annotation %s on secret %s/%s", PasswordSecretAnnotation, userSecret.Namespace, userSecret.Name)
+		}
+		var passwordSecret corev1.Secret
+		err := c.Get(types.NamespacedName{Namespace: ref[0], Name: ref[1]}, &passwordSecret)
+		if apierrors.IsNotFound(err) {
+			// the association is being removed
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(passwordSecret.Data[name], password) {
+			if passwordSecret.Data == nil {
+				passwordSecret.Data = map[string][]byte{}
+			}
+			passwordSecret.Data[name] = password
+			if err := c.Update(&passwordSecret); err != nil {
+				return err
+			}
+		}
+
+		hash := fileRealm.PasswordHashForUser(name)
+		if bcrypt.CompareHashAndPassword(hash, password) != nil {
+			if hash, err = bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost); err != nil {
+				return err
+			}
+		}
+		if string(userSecret.Data[UserNameField]) != name || !bytes.Equal(userSecret.Data[PasswordHashField], hash) {
+			userSecret.Data[UserNameField] = []byte(name)
+			userSecret.Data[PasswordHashField] = hash
+			if err := c.Update(&userSecret); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// switchPredefinedUsers replaces the rotated predefined users by the new ones in their secrets.
+func switchPredefinedUsers(c k8s.Client, es esv1.Elasticsearch, rotation credentialsRotation) error {
+	for _, secretName := range []string{esv1.ElasticUserSecret(es.Name), esv1.InternalUsersSecret(es.Name)} {
+		var secret corev1.Secret
+		if err := c.Get(types.NamespacedName{Namespace: es.Namespace, Name: secretName}, &secret); err != nil {
+			return err
+		}
+		updated := false
+		for _, baseName := range rotatedPredefinedUsers {
+			name := RotatedUserName(baseName, rotation.startedAt)
+			password, rotated := rotation.newPasswords[name]
+			current, exists := UserCredentials(secret, baseName)
+			if !exists || !rotated || current.Name == name {
+				continue
+			}
+			delete(secret.Data, current.Name)
+			secret.Data[name] = password
+			updated = true
+		}
+		if !updated {
+			continue
+		}
+		if err := c.Update(&secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedNames(passwords map[string][]byte) []string {
+	names := make([]string, 0, len(passwords))
+	for name := range passwords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,234 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	esclient "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/client"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user/filerealm"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func Test_rotationDue(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	rotationSecret := func(trigger string, rotatedAt time.Time) corev1.Secret {
+		return corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			rotationTriggerAnnotation: trigger,
+			rotatedAtAnnotation:       rotatedAt.Format(time.RFC3339),
+		}}}
+	}
+	es := func(trigger string, maxAge time.Duration) esv1.Elasticsearch {
+		es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+		if trigger != "" {
+			es.Annotations[RotateCredentialsAnnotation] = trigger
+		}
+		if maxAge > 0 {
+			es.Spec.Auth.Rotation = &esv1.CredentialsRotation{MaxAge: &metav1.Duration{Duration: maxAge}}
+		}
+		return es
+	}
+	tests := []struct {
+		name           string
+		es             esv1.Elasticsearch
+		rotationSecret corev1.Secret
+		want           bool
+	}{
+		{
+			name:           "no annotation nor max age",
+			es:             es("", 0),
+			rotationSecret: rotationSecret("", now.Add(-1000*time.Hour)),
+			want:           false,
+		},
+		{
+			name:           "annotation already handled",
+			es:             es("2021-05-01", 0),
+			rotationSecret: rotationSecret("2021-05-01", now.Add(-1000*time.Hour)),
+			want:           false,
+		},
+		{
+			name:           "new annotation value",
+			es:             es("2021-06-01", 0),
+			rotationSecret: rotationSecret("2021-05-01", now.Add(-time.Hour)),
+			want:           true,
+		},
+		{
+			name:           "passwords younger than max age",
+			es:             es("", 720*time.Hour),
+			rotationSecret: rotationSecret("", now.Add(-719*time.Hour)),
+			want:           false,
+		},
+		{
+			name:           "passwords reached max age",
+			es:             es("", 720*time.Hour),
+			rotationSecret: rotationSecret("", now.Add(-720*time.Hour)),
+			want:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, rotationDue(tt.es, tt.rotationSecret, now))
+		})
+	}
+}
+
+func TestIsUserName(t *testing.T) {
+	rotatedAt := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		userName string
+		baseName string
+		want     bool
+	}{
+		{name: "base name", userName: "elastic", baseName: "elastic", want: true},
+		{name: "rotated name", userName: RotatedUserName("elastic", rotatedAt), baseName: "elastic", want: true},
+		{name: "other user", userName: "elastic-internal", baseName: "elastic", want: false},
+		{name: "other user with the same prefix", userName: "elastic-internal-probe", baseName: "elastic-internal", want: false},
+		{name: "rotated name of another user", userName: RotatedUserName("elastic-internal", rotatedAt), baseName: "elastic", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsUserName(tt.userName, tt.baseName))
+		})
+	}
+	actualRotatedAt, rotated := UserRotatedAt(RotatedUserName("elastic", rotatedAt), "elastic")
+	require.True(t, rotated)
+	require.True(t, rotatedAt.Equal(actualRotatedAt))
+}
+
+func TestCredentialsRotation(t *testing.T) {
+	es := esv1.Elasticsearch{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"}}
+	// user of an associated Kibana
+	kibanaUserSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        "kbns-kb-kibana-user",
+			Labels:      AssociatedUserLabels(es),
+			Annotations: map[string]string{PasswordSecretAnnotation: "kbns/kb-kibana-user"},
+		},
+		Data: map[string][]byte{
+			UserNameField:     []byte("kbns-kb-kibana-user"),
+			PasswordHashField: []byte("old-hash"),
+			UserRolesField:    []byte("kibana_system"),
+		},
+	}
+	kibanaPasswordSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kbns", Name: "kb-kibana-user"},
+		Data:       map[string][]byte{"kbns-kb-kibana-user": []byte("old-password")},
+	}
+	c := k8s.WrappedFakeClient(&kibanaUserSecret, &kibanaPasswordSecret)
+	recorder := record.NewFakeRecorder(10)
+	reconcileUsers := func() esclient.BasicAuth {
+		controllerUser, err := ReconcileUsersAndRoles(context.Background(), c, es, watches.NewDynamicWatches(), recorder)
+		require.NoError(t, err)
+		return controllerUser
+	}
+	secretData := func(key types.NamespacedName) map[string][]byte {
+		var secret corev1.Secret
+		require.NoError(t, c.Get(key, &secret))
+		return secret.Data
+	}
+	fileRealmUsers := func() []string {
+		fileRealm, err := getExistingFileRealm(c, es)
+		require.NoError(t, err)
+		return fileRealm.UserNames()
+	}
+	elasticUserKey := types.NamespacedName{Namespace: "ns", Name: esv1.ElasticUserSecret("es")}
+	authErr := errors.New("not accepted yet")
+	authenticate := func(esclient.BasicAuth) error { return authErr }
+
+	// no rotation requested
+	controllerUser := reconcileUsers()
+	require.Equal(t, ControllerUserName, controllerUser.Name)
+	elasticPassword := secretData(elasticUserKey)[ElasticUserName]
+	controllerUser, requeue, err := ReconcileCredentialsRotation(c, es, recorder, controllerUser, authenticate)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), requeue)
+	require.Empty(t, secretData(CredentialsRotationSecretKey(es)))
+
+	// request a rotation: the file realm holds the current users and the new ones, the secrets keep the current ones
+	es.Annotations = map[string]string{RotateCredentialsAnnotation: "2021-06-01"}
+	require.Equal(t, controllerUser, reconcileUsers())
+	newPasswords := secretData(CredentialsRotationSecretKey(es))
+	require.Len(t, newPasswords, 3)
+	newName := func(baseName string) string {
+		for name := range newPasswords {
+			if name != baseName && IsUserName(name, baseName) {
+				return name
+			}
+		}
+		t.Fatalf("no new user for %s", baseName)
+		return ""
+	}
+	newElasticName, newControllerName, newKibanaName := newName(ElasticUserName), newName(ControllerUserName), newName("kbns-kb-kibana-user")
+	require.NotEqual(t, elasticPassword, newPasswords[newElasticName])
+	fileRealm, err := getExistingFileRealm(c, es)
+	require.NoError(t, err)
+	for _, name := range []string{newElasticName, newControllerName, newKibanaName} {
+		require.NoError(t, bcrypt.CompareHashAndPassword(fileRealm.PasswordHashForUser(name), newPasswords[name]))
+	}
+	currentUsers := []string{ElasticUserName, ControllerUserName, ProbeUserName, MonitoringUserName, "kbns-kb-kibana-user"}
+	require.ElementsMatch(t, append([]string{newElasticName, newControllerName, newKibanaName}, currentUsers...), fileRealmUsers())
+	require.Equal(t, map[string][]byte{ElasticUserName: elasticPassword}, secretData(elasticUserKey))
+
+	// the new users are not accepted by all the nodes yet
+	rotatedControllerUser, requeue, err := ReconcileCredentialsRotation(c, es, recorder, controllerUser, authenticate)
+	require.NoError(t, err)
+	require.Equal(t, RotationCheckInterval, requeue)
+	require.Equal(t, controllerUser, rotatedControllerUser)
+	// the rotation is not restarted by the next reconciliation
+	require.Equal(t, controllerUser, reconcileUsers())
+	require.Equal(t, newPasswords, secretData(CredentialsRotationSecretKey(es)))
+
+	// the new users are accepted: the secrets are updated, the previous users are kept during the grace period
+	authErr = nil
+	rotatedControllerUser, requeue, err = ReconcileCredentialsRotation(c, es, recorder, controllerUser, authenticate)
+	require.NoError(t, err)
+	require.InDelta(t, CredentialsGracePeriod, requeue, float64(time.Second))
+	require.Equal(t, esclient.BasicAuth{Name: newControllerName, Password: string(newPasswords[newControllerName])}, rotatedControllerUser)
+	require.Equal(t, map[string][]byte{newElasticName: newPasswords[newElasticName]}, secretData(elasticUserKey))
+	// the association controller switches Kibana over to the new user
+	require.Equal(t, map[string][]byte{
+		"kbns-kb-kibana-user": []byte("old-password"),
+		newKibanaName:         newPasswords[newKibanaName],
+	}, secretData(k8s.ExtractNamespacedName(&kibanaPasswordSecret)))
+	kibanaUser := secretData(k8s.ExtractNamespacedName(&kibanaUserSecret))
+	require.Equal(t, newKibanaName, string(kibanaUser[UserNameField]))
+	require.NoError(t, bcrypt.CompareHashAndPassword(kibanaUser[PasswordHashField], newPasswords[newKibanaName]))
+	previousUsers, err := filerealm.FromSecret(corev1.Secret{Data: secretData(CredentialsRotationSecretKey(es))})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{ElasticUserName, ControllerUserName, "kbns-kb-kibana-user"}, previousUsers.UserNames())
+	require.Equal(t, fmt.Sprintf("Normal CredentialsRotated Rotated the credentials to users %s, %s, %s, the previous users remain valid for 15m0s",
+		newElasticName, newControllerName, newKibanaName), <-recorder.Events)
+
+	// both the previous and the new users remain in the file realm
+	require.Equal(t, rotatedControllerUser, reconcileUsers())
+	require.ElementsMatch(t, append([]string{newElasticName, newControllerName, newKibanaName}, currentUsers...), fileRealmUsers())
+
+	// the previous users are removed at the end of the grace period
+	var rotationSecret corev1.Secret
+	require.NoError(t, c.Get(CredentialsRotationSecretKey(es), &rotationSecret))
+	rotationSecret.Annotations[rotatedAtAnnotation] = time.Now().Add(-CredentialsGracePeriod).Format(time.RFC3339)
+	require.NoError(t, c.Update(&rotationSecret))
+	require.Equal(t, rotatedControllerUser, reconcileUsers())
+	require.ElementsMatch(t, []string{newElasticName, newControllerName, ProbeUserName, MonitoringUserName, newKibanaName}, fileRealmUsers())
+	require.Empty(t, secretData(CredentialsRotationSecretKey(es)))
+	_, requeue, err = ReconcileCredentialsRotation(c, es, recorder, rotatedControllerUser, authenticate)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), requeue)
+}
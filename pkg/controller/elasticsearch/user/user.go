@@ -40,11 +40,12 @@ func (users users) fileRealm() filerealm.Realm {
 	return fileRealm
 }
 
-// credentialsFor returns basic auth credentials for the given user.
+// credentialsFor returns basic auth credentials for the given user, or for the user replacing it in a rotation of
+// the credentials.
 func (users users) credentialsFor(userName string) (client.BasicAuth, error) {
 	for _, u := range users {
-		if u.Name == userName {
-			return client.BasicAuth{Name: userName, Password: string(u.Password)}, nil
+		if IsUserName(u.Name, userName) {
+			return client.BasicAuth{Name: u.Name, Password: string(u.Password)}, nil
 		}
 	}
 	return client.BasicAuth{}, fmt.Errorf("user %s not found", userName)
@@ -23,6 +23,9 @@ const (
 	HTTPCertificatesSecretVolumeName      = "elastic-internal-http-certificates"
 	HTTPCertificatesSecretVolumeMountPath = "/usr/share/elasticsearch/config/http-certs"
 
+	HTTPClientCertificatesSecretVolumeName      = "elastic-internal-http-client-certificates"
+	HTTPClientCertificatesSecretVolumeMountPath = "/usr/share/elasticsearch/config/http-client-certs"
+
 	XPackFileRealmVolumeName      = "elastic-internal-xpack-file-realm"
 	XPackFileRealmVolumeMountPath = "/mnt/elastic-internal/xpack-file-realm"
 
@@ -42,4 +45,5 @@ const (
 	DownwardAPIVolumeName = "downward-api"
 	DownwardAPIMountPath  = "/mnt/elastic-internal/downward-api"
 	LabelsFile            = "labels"
+	AnnotationsFile       = "annotations"
 )
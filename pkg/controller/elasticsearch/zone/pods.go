@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package zone
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var log = logf.Log.WithName("zone-awareness")
+
+// AnnotatePods sets the zone annotation on the scheduled Pods of the NodeSets with zone awareness, using the
+// topology label of the Kubernetes node each Pod is scheduled on. Elasticsearch does not start until its Pod is
+// annotated. Kubernetes nodes are read through the given reader, which must not be restricted to some namespaces.
+func AnnotatePods(c k8s.Client, nodeReader client.Reader, pods []corev1.Pod) error {
+	nodes := make(map[string]corev1.Node)
+	for i := range pods {
+		pod := pods[i].DeepCopy()
+		topologyKey, zoneAware := pod.Annotations[TopologyKeyAnnotationName]
+		if !zoneAware {
+			continue
+		}
+		if pod.Spec.NodeName == "" {
+			// not scheduled yet, the Pod will be annotated once updated with its node name
+			continue
+		}
+		if _, exists := pod.Annotations[AnnotationName]; exists {
+			continue
+		}
+		node, cached := nodes[pod.Spec.NodeName]
+		if !cached {
+			if err := nodeReader.Get(context.Background(), types.NamespacedName{Name: pod.Spec.NodeName}, &node); err != nil {
+				return err
+			}
+			nodes[pod.Spec.NodeName] = node
+		}
+		zone, exists := node.Labels[topologyKey]
+		if !exists {
+			log.Info("Kubernetes node has no zone label, Elasticsearch will not start until it is set",
+				"namespace", pod.Namespace, "pod_name", pod.Name, "node_name", node.Name, "label", topologyKey)
+			continue
+		}
+		pod.Annotations[AnnotationName] = zone
+		log.V(1).Info("Setting zone annotation", "namespace", pod.Namespace, "pod_name", pod.Name, "zone", zone)
+		if err := c.Update(pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
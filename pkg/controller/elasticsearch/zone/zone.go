@@ -0,0 +1,115 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package zone
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
+)
+
+const (
+	// AnnotationName is set by the operator on the Elasticsearch Pods once scheduled. Its value is the zone of the
+	// Kubernetes node the Pod is scheduled on, exposed to the Elasticsearch container through the Downward API.
+	AnnotationName = "elasticsearch.k8s.elastic.co/zone"
+	// TopologyKeyAnnotationName is set on the Pod template of the NodeSets with zone awareness. Its value is the label
+	// of the Kubernetes nodes holding their zone.
+	TopologyKeyAnnotationName = "elasticsearch.k8s.elastic.co/zone-topology-key"
+)
+
+// EnvVar returns the environment variable holding the zone of the Kubernetes node, read from the Pod annotation.
+func EnvVar() corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: settings.EnvZone,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", AnnotationName)},
+		},
+	}
+}
+
+// TopologySpreadConstraint returns a constraint spreading evenly the Pods of the given NodeSet across zones.
+func TopologySpreadConstraint(esName string, nodeSet esv1.NodeSet, zoneAwareness esv1.ZoneAwareness) corev1.TopologySpreadConstraint {
+	return corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       zoneAwareness.TopologyKeyOrDefault(),
+		WhenUnsatisfiable: corev1.DoNotSchedule,
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				label.ClusterNameLabelName:     esName,
+				label.StatefulSetNameLabelName: esv1.StatefulSet(esName, nodeSet.Name),
+			},
+		},
+	}
+}
+
+// WithZoneAwareness sets up the Pod template of the given NodeSet for zone awareness, if enabled for this NodeSet:
+//   - the zone of the Kubernetes node is exposed to the Elasticsearch container
+//   - Pods are spread across zones, unless the Pod template specifies its own topology spread constraint for the same
+//     topology key
+//   - Pods are restricted to the specified zones, if any.
+func WithZoneAwareness(builder *defaults.PodTemplateBuilder, es esv1.Elasticsearch, nodeSet esv1.NodeSet) *defaults.PodTemplateBuilder {
+	if nodeSet.ZoneAwareness == nil {
+		return builder
+	}
+	builder = builder.WithEnv(EnvVar())
+	spec := &builder.PodTemplate.Spec
+	constraint := TopologySpreadConstraint(es.Name, nodeSet, *nodeSet.ZoneAwareness)
+	if builder.PodTemplate.Annotations == nil {
+		builder.PodTemplate.Annotations = map[string]string{}
+	}
+	builder.PodTemplate.Annotations[TopologyKeyAnnotationName] = constraint.TopologyKey
+	if !hasTopologySpreadConstraint(spec.TopologySpreadConstraints, constraint.TopologyKey) {
+		spec.TopologySpreadConstraints = append(spec.TopologySpreadConstraints, constraint)
+	}
+	if len(nodeSet.ZoneAwareness.Zones) > 0 {
+		spec.Affinity = withZones(spec.Affinity, constraint.TopologyKey, nodeSet.ZoneAwareness.Zones)
+	}
+	return builder
+}
+
+func hasTopologySpreadConstraint(constraints []corev1.TopologySpreadConstraint, topologyKey string) bool {
+	for _, c := range constraints {
+		if c.TopologyKey == topologyKey {
+			return true
+		}
+	}
+	return false
+}
+
+// withZones returns a copy of the given affinity that requires Pods to be scheduled on the Kubernetes nodes of
+// the given zones. The zones requirement is added to each of the existing node selector terms, if any.
+func withZones(affinity *corev1.Affinity, topologyKey string, zones []string) *corev1.Affinity {
+	requirement := corev1.NodeSelectorRequirement{
+		Key:      topologyKey,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   zones,
+	}
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	} else {
+		affinity = affinity.DeepCopy()
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil ||
+		len(nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) == 0 {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{}},
+		}
+	}
+	terms := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	for i := range terms {
+		terms[i].MatchExpressions = append(terms[i].MatchExpressions, requirement)
+	}
+	return affinity
+}
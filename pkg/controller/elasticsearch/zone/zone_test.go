@@ -0,0 +1,209 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package zone
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/settings"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func esWithNodeSets(nodeSets ...esv1.NodeSet) esv1.Elasticsearch {
+	return esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec:       esv1.ElasticsearchSpec{NodeSets: nodeSets},
+	}
+}
+
+func TestWithZoneAwareness(t *testing.T) {
+	zoneNodeSet := esv1.NodeSet{Name: "data", ZoneAwareness: &esv1.ZoneAwareness{}}
+	defaultConstraint := TopologySpreadConstraint("es", zoneNodeSet, esv1.ZoneAwareness{})
+	userAffinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "type", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}}}},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name            string
+		es              esv1.Elasticsearch
+		nodeSet         esv1.NodeSet
+		podTemplate     corev1.PodTemplateSpec
+		wantEnv         bool
+		wantConstraints []corev1.TopologySpreadConstraint
+		wantAffinity    *corev1.Affinity
+	}{
+		{
+			name:    "zone awareness disabled",
+			es:      esWithNodeSets(esv1.NodeSet{Name: "data"}),
+			nodeSet: esv1.NodeSet{Name: "data"},
+		},
+		{
+			name:    "zone awareness enabled for another NodeSet",
+			es:      esWithNodeSets(esv1.NodeSet{Name: "masters"}, zoneNodeSet),
+			nodeSet: esv1.NodeSet{Name: "masters"},
+		},
+		{
+			name:            "zone awareness enabled: spread Pods across zones",
+			es:              esWithNodeSets(zoneNodeSet),
+			nodeSet:         zoneNodeSet,
+			wantEnv:         true,
+			wantConstraints: []corev1.TopologySpreadConstraint{defaultConstraint},
+		},
+		{
+			name:    "topology spread constraint specified by the user for the same key",
+			es:      esWithNodeSets(zoneNodeSet),
+			nodeSet: zoneNodeSet,
+			podTemplate: corev1.PodTemplateSpec{Spec: corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 2, TopologyKey: esv1.DefaultZoneTopologyKey, WhenUnsatisfiable: corev1.ScheduleAnyway},
+			}}},
+			wantEnv: true,
+			wantConstraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 2, TopologyKey: esv1.DefaultZoneTopologyKey, WhenUnsatisfiable: corev1.ScheduleAnyway},
+			},
+		},
+		{
+			name: "restrict Pods to the given zones, merged with the user node affinity",
+			es:   esWithNodeSets(zoneNodeSet),
+			nodeSet: esv1.NodeSet{Name: "data", ZoneAwareness: &esv1.ZoneAwareness{
+				TopologyKey: "zone", Zones: []string{"a", "b"},
+			}},
+			podTemplate: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Affinity: userAffinity}},
+			wantEnv:     true,
+			wantConstraints: []corev1.TopologySpreadConstraint{
+				TopologySpreadConstraint("es", zoneNodeSet, esv1.ZoneAwareness{TopologyKey: "zone"}),
+			},
+			wantAffinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{MatchExpressions: []corev1.NodeSelectorRequirement{
+								{Key: "type", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}},
+								{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := defaults.NewPodTemplateBuilder(tt.podTemplate, esv1.ElasticsearchContainerName)
+			builder = WithZoneAwareness(builder, tt.es, tt.nodeSet)
+
+			var env []corev1.EnvVar
+			if tt.wantEnv {
+				env = []corev1.EnvVar{EnvVar()}
+			}
+			require.Equal(t, env, builder.Container.Env)
+			wantTopologyKey := ""
+			if tt.nodeSet.ZoneAwareness != nil {
+				wantTopologyKey = tt.nodeSet.ZoneAwareness.TopologyKeyOrDefault()
+			}
+			require.Equal(t, wantTopologyKey, builder.PodTemplate.Annotations[TopologyKeyAnnotationName])
+			require.Equal(t, tt.wantConstraints, builder.PodTemplate.Spec.TopologySpreadConstraints)
+			if tt.wantAffinity != nil {
+				require.Equal(t, tt.wantAffinity, builder.PodTemplate.Spec.Affinity)
+			}
+		})
+	}
+	// the user affinity is not mutated
+	require.Len(t, userAffinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions, 1)
+}
+
+func TestEnvVar(t *testing.T) {
+	env := EnvVar()
+	require.Equal(t, settings.EnvZone, env.Name)
+	require.Equal(t, "metadata.annotations['elasticsearch.k8s.elastic.co/zone']", env.ValueFrom.FieldRef.FieldPath)
+}
+
+func pod(name string, nodeName string, annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        name,
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+// namespacedReader simulates the cache of an operator restricted to some namespaces, which cannot read cluster-scoped
+// resources.
+type namespacedReader struct {
+	client.Reader
+}
+
+func (r namespacedReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if key.Namespace == "" {
+		return errors.New("cluster-scoped resources are not cached")
+	}
+	return r.Reader.Get(ctx, key, obj)
+}
+
+func TestAnnotatePods(t *testing.T) {
+	defaultKey := map[string]string{TopologyKeyAnnotationName: esv1.DefaultZoneTopologyKey}
+	customKey := map[string]string{TopologyKeyAnnotationName: "custom-zone"}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{
+		esv1.DefaultZoneTopologyKey: "zone-a",
+		"custom-zone":               "custom-a",
+	}}}
+	unlabeledNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}}
+	pods := []runtime.Object{
+		// no zone awareness
+		pod("es-es-masters-0", "node-1", nil),
+		// default topology key
+		pod("es-es-data-0", "node-1", defaultKey),
+		// custom topology key
+		pod("es-es-hot-0", "node-1", customKey),
+		// already annotated
+		pod("es-es-data-1", "node-1", map[string]string{TopologyKeyAnnotationName: esv1.DefaultZoneTopologyKey, AnnotationName: "zone-b"}),
+		// not scheduled yet
+		pod("es-es-data-2", "", defaultKey),
+		// no zone label on the Kubernetes node
+		pod("es-es-data-3", "node-2", defaultKey),
+	}
+	c := k8s.WrappedFakeClient(pods...)
+	nodeReader := fake.NewFakeClient(node, unlabeledNode)
+	var actualPods []corev1.Pod
+	for _, obj := range pods {
+		actualPods = append(actualPods, *obj.(*corev1.Pod))
+	}
+
+	require.NoError(t, AnnotatePods(c, nodeReader, actualPods))
+
+	for name, want := range map[string]string{
+		"es-es-masters-0": "",
+		"es-es-data-0":    "zone-a",
+		"es-es-hot-0":     "custom-a",
+		"es-es-data-1":    "zone-b",
+		"es-es-data-2":    "",
+		"es-es-data-3":    "",
+	} {
+		var p corev1.Pod
+		require.NoError(t, c.Get(types.NamespacedName{Namespace: "ns", Name: name}, &p))
+		require.Equal(t, want, p.Annotations[AnnotationName], name)
+	}
+
+	// Kubernetes nodes cannot be read through the cache of an operator restricted to some namespaces
+	require.Error(t, AnnotatePods(c, namespacedReader{Reader: nodeReader}, []corev1.Pod{*pod("es-es-data-4", "node-1", defaultKey)}))
+}
@@ -9,10 +9,13 @@ import (
 
 	"go.elastic.co/apm"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	entsv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/enterprisesearch/v1beta1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/deployment"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	entsname "github.com/elastic/cloud-on-k8s/pkg/controller/enterprisesearch/name"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
 )
@@ -31,7 +34,15 @@ func (r *ReconcileEnterpriseSearch) reconcileDeployment(
 	if err != nil {
 		return state, err
 	}
-	state.UpdateEnterpriseSearchState(result)
+	var pods corev1.PodList
+	if err := r.K8sClient().List(&pods, client.InNamespace(ents.Namespace), client.MatchingLabels(Labels(ents.Name))); err != nil {
+		return state, err
+	}
+	lowestVersion, err := version.MinInPods(pods.Items, VersionLabelName)
+	if err != nil {
+		return state, err
+	}
+	state.UpdateEnterpriseSearchState(result, lowestVersion)
 	return state, nil
 }
 
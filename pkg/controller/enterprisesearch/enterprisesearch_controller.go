@@ -8,10 +8,13 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"reflect"
+	"sync/atomic"
 
 	"go.elastic.co/apm"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -115,6 +118,14 @@ func addWatches(c controller.Controller, r *ReconcileEnterpriseSearch) error {
 		return err
 	}
 
+	// Watch NetworkPolicies
+	if err := c.Watch(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &entsv1beta1.EnterpriseSearch{},
+	}); err != nil {
+		return err
+	}
+
 	// Watch secrets
 	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
@@ -205,6 +216,7 @@ func (r *ReconcileEnterpriseSearch) Reconcile(request reconcile.Request) (reconc
 func (r *ReconcileEnterpriseSearch) onDelete(obj types.NamespacedName) {
 	// Clean up watches
 	r.dynamicWatches.Secrets.RemoveHandlerForKey(configRefWatchName(obj))
+	certificates.DeleteExpirationMetrics(&entsv1beta1.EnterpriseSearch{}, obj)
 }
 
 func (r *ReconcileEnterpriseSearch) isCompatible(ctx context.Context, ents *entsv1beta1.EnterpriseSearch) (bool, error) {
@@ -229,6 +241,10 @@ func (r *ReconcileEnterpriseSearch) doReconcile(ctx context.Context, request rec
 		return reconcile.Result{}, err
 	}
 
+	if err := ReconcileNetworkPolicy(r.Client, r.recorder, ents, r.Parameters); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	_, results := certificates.Reconciler{
 		K8sClient:             r.K8sClient(),
 		DynamicWatches:        r.DynamicWatches(),
@@ -239,6 +255,8 @@ func (r *ReconcileEnterpriseSearch) doReconcile(ctx context.Context, request rec
 		Services:              []corev1.Service{*svc},
 		CACertRotation:        r.CACertRotation,
 		CertRotation:          r.CertRotation,
+		CertManagerIssuer:     r.CertManagerIssuer,
+		Recorder:              r.recorder,
 		GarbageCollectSecrets: true,
 	}.ReconcileCAAndHTTPCerts(ctx)
 	if results.HasError() {
@@ -275,18 +293,49 @@ func (r *ReconcileEnterpriseSearch) doReconcile(ctx context.Context, request rec
 			return reconcile.Result{Requeue: true}, nil
 		}
 		k8s.EmitErrorEvent(r.recorder, err, &ents, events.EventReconciliationError, "Deployment reconciliation error: %v", err)
+		state.UpdateReconciliationStatus(err)
+		if statusErr := r.updateStatus(ctx, state); statusErr != nil && !apierrors.IsConflict(statusErr) {
+			log.Error(statusErr, "Failed to update status", "namespace", ents.Namespace, "ents_name", ents.Name)
+		}
 		return state.Result, tracing.CaptureError(ctx, err)
 	}
 
 	state.UpdateEnterpriseSearchExternalService(*svc)
+	_, reconcileErr := results.Aggregate()
+	state.UpdateReconciliationStatus(reconcileErr)
 
-	// TODO: update status
+	// update status
+	err = r.updateStatus(ctx, state)
+	if err != nil && apierrors.IsConflict(err) {
+		log.V(1).Info("Conflict while updating status", "namespace", ents.Namespace, "ents_name", ents.Name)
+		return reconcile.Result{Requeue: true}, nil
+	}
 
 	res, err := results.WithError(err).Aggregate()
 	k8s.EmitErrorEvent(r.recorder, err, &ents, events.EventReconciliationError, "Reconciliation error: %v", err)
 	return res, nil
 }
 
+func (r *ReconcileEnterpriseSearch) updateStatus(ctx context.Context, state State) error {
+	span, _ := apm.StartSpan(ctx, "update_status", tracing.SpanTypeApp)
+	defer span.End()
+
+	current := state.originalEnterpriseSearch
+	if reflect.DeepEqual(current.Status, state.EnterpriseSearch.Status) {
+		return nil
+	}
+	if state.EnterpriseSearch.Status.IsDegraded(current.Status) {
+		r.recorder.Event(current, corev1.EventTypeWarning, events.EventReasonUnhealthy, "Enterprise Search health degraded")
+	}
+	log.V(1).Info("Updating status",
+		"iteration", atomic.LoadUint64(&r.iteration),
+		"namespace", state.EnterpriseSearch.Namespace,
+		"ents_name", state.EnterpriseSearch.Name,
+		"status", state.EnterpriseSearch.Status,
+	)
+	return common.UpdateStatus(r.Client, state.EnterpriseSearch)
+}
+
 func (r *ReconcileEnterpriseSearch) validate(ctx context.Context, ents *entsv1beta1.EnterpriseSearch) error {
 	span, vctx := apm.StartSpan(ctx, "validate", tracing.SpanTypeApp)
 	defer span.End()
@@ -9,9 +9,10 @@ import (
 )
 
 const (
-	httpServiceSuffix = "http"
-	configSuffix      = "config"
-	deploymentSuffix  = "server"
+	httpServiceSuffix   = "http"
+	configSuffix        = "config"
+	deploymentSuffix    = "server"
+	networkPolicySuffix = "network-policy"
 )
 
 // EntSearchNamer is a Namer that is configured with the defaults for resources related to an EnterpriseSearch resource.
@@ -29,3 +30,7 @@ func Deployment(entsName string) string {
 func Config(entsName string) string {
 	return EntSearchNamer.Suffix(entsName, configSuffix)
 }
+
+func NetworkPolicy(entsName string) string {
+	return EntSearchNamer.Suffix(entsName, networkPolicySuffix)
+}
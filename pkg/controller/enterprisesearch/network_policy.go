@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package enterprisesearch
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	entsv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/enterprisesearch/v1beta1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	entsname "github.com/elastic/cloud-on-k8s/pkg/controller/enterprisesearch/name"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// ReconcileNetworkPolicy reconciles the NetworkPolicy allowing the traffic from the operator to the given
+// Enterprise Search if enabled, and deletes it otherwise.
+func ReconcileNetworkPolicy(c k8s.Client, recorder record.EventRecorder, ents entsv1beta1.EnterpriseSearch, params operator.Parameters) error {
+	enabled := networkpolicy.IsEnabled(recorder, &ents, ents.Spec.NetworkPolicy, params)
+	meta := metav1.ObjectMeta{
+		Namespace: ents.Namespace,
+		Name:      entsname.NetworkPolicy(ents.Name),
+		Labels:    Labels(ents.Name),
+	}
+	expected := networkingv1.NetworkPolicy{ObjectMeta: meta}
+	if enabled {
+		// the operator calls the Enterprise Search API to toggle the read-only mode during version upgrades
+		expected = networkpolicy.New(
+			meta,
+			map[string]string{EnterpriseSearchNameLabelName: ents.Name},
+			networkpolicy.Rule{Port: HTTPPort, Peers: []networkingv1.NetworkPolicyPeer{networkpolicy.NamespacePeer(params.OperatorNamespace)}},
+		)
+	}
+	return networkpolicy.Reconcile(c, &ents, expected, enabled)
+}
@@ -14,6 +14,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	entsv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/enterprisesearch/v1beta1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 )
 
 // State holds the accumulated state during the reconcile loop including the response and a pointer to an EnterpriseSearch
@@ -32,10 +34,25 @@ func NewState(request reconcile.Request, ents *entsv1beta1.EnterpriseSearch) Sta
 	return State{Request: request, EnterpriseSearch: ents, originalEnterpriseSearch: ents.DeepCopy()}
 }
 
-// UpdateApmServerState updates the ApmServer status based on the given deployment.
-func (s State) UpdateEnterpriseSearchState(deployment v1.Deployment) {
+// UpdateEnterpriseSearchState updates the EnterpriseSearch status based on the given deployment and the lowest version
+// running in its Pods.
+func (s State) UpdateEnterpriseSearchState(deployment v1.Deployment, lowestVersion *version.Version) {
 	s.EnterpriseSearch.Status.AvailableNodes = deployment.Status.AvailableReplicas
-	// TODO health
+	s.EnterpriseSearch.Status.Health = entsv1beta1.EnterpriseSearchRed
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == v1.DeploymentAvailable && c.Status == corev1.ConditionTrue {
+			s.EnterpriseSearch.Status.Health = entsv1beta1.EnterpriseSearchGreen
+		}
+	}
+	common.ReportRunningVersion(&s.EnterpriseSearch.Status.ReconcilerStatus, lowestVersion, s.EnterpriseSearch.Spec.Version)
+}
+
+// UpdateReconciliationStatus reports the reconciliation of the current Enterprise Search generation, see common.UpdateReconciliationStatus.
+func (s State) UpdateReconciliationStatus(err error) {
+	common.UpdateReconciliationStatus(
+		&s.EnterpriseSearch.Status.ReconcilerStatus, s.EnterpriseSearch.Generation, err,
+		s.EnterpriseSearch.Status.Health == entsv1beta1.EnterpriseSearchGreen, "Enterprise Search is not available",
+	)
 }
 
 // UpdateEnterpriseSearchExternalService updates the EnterpriseSearch ExternalService status.
@@ -239,7 +239,9 @@ func (r *ReconcileEnterpriseSearchElasticsearchAssociation) reconcileInternal(ct
 		return commonv1.AssociationPending, err
 	}
 
-	if err := association.ReconcileEsUser(
+	// Enterprise Search can only authenticate to Elasticsearch with a username and a password: unlike Kibana and
+	// APM Server, it cannot rely on a service account token or an API key and keeps using a file realm user.
+	authSecretRef, err := association.ReconcileEsUser(
 		ctx,
 		r.Client,
 		entSearch,
@@ -250,7 +252,8 @@ func (r *ReconcileEnterpriseSearchElasticsearchAssociation) reconcileInternal(ct
 		"superuser",
 		entSearchUserSuffix,
 		es,
-	); err != nil { // TODO distinguish conflicts and non-recoverable errors here
+	)
+	if err != nil { // TODO distinguish conflicts and non-recoverable errors here
 		return commonv1.AssociationPending, err
 	}
 
@@ -260,7 +263,6 @@ func (r *ReconcileEnterpriseSearchElasticsearchAssociation) reconcileInternal(ct
 	}
 
 	// construct the expected ES output configuration
-	authSecretRef := association.ClearTextSecretKeySelector(entSearch, entSearchUserSuffix)
 	expectedAssocConf := &commonv1.AssociationConf{
 		AuthSecretName: authSecretRef.Name,
 		AuthSecretKey:  authSecretRef.Key,
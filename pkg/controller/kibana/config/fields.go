@@ -11,12 +11,19 @@ const (
 	XpackMonitoringUiContainerElasticsearchEnabled = "xpack.monitoring.ui.container.elasticsearch.enabled"
 	XpackLicenseManagementUIEnabled                = "xpack.license_management.ui.enabled" // >= 7.6
 	XpackSecurityEncryptionKey                     = "xpack.security.encryptionKey"
+	XpackMonitoringKibanaCollectionEnabled         = "xpack.monitoring.kibana.collection.enabled"
+
+	LoggingDest = "logging.dest"
+	LoggingJSON = "logging.json"
 
 	ElasticsearchSslCertificateAuthorities = "elasticsearch.ssl.certificateAuthorities"
 	ElasticsearchSslVerificationMode       = "elasticsearch.ssl.verificationMode"
+	ElasticsearchSslCertificate            = "elasticsearch.ssl.certificate"
+	ElasticsearchSslKey                    = "elasticsearch.ssl.key"
 
-	ElasticsearchUsername = "elasticsearch.username"
-	ElasticsearchPassword = "elasticsearch.password"
+	ElasticsearchUsername            = "elasticsearch.username"
+	ElasticsearchPassword            = "elasticsearch.password"
+	ElasticsearchServiceAccountToken = "elasticsearch.serviceAccountToken"
 
 	ElasticsearchHosts = "elasticsearch.hosts"
 
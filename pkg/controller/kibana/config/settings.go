@@ -24,6 +24,8 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/es"
+	kbstackmon "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/stackmon"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/volume"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
 
@@ -72,6 +74,7 @@ func NewConfigSettings(ctx context.Context, client k8s.Client, kb kbv1.Kibana, v
 			reusableSettings,
 			versionSpecificCfg,
 			kibanaTLSCfg,
+			settings.MustCanonicalConfig(monitoringSettings(kb)),
 			userSettings); err != nil {
 			return CanonicalConfig{}, err
 		}
@@ -89,12 +92,8 @@ func NewConfigSettings(ctx context.Context, client k8s.Client, kb kbv1.Kibana, v
 		versionSpecificCfg,
 		kibanaTLSCfg,
 		settings.MustCanonicalConfig(elasticsearchTLSSettings(kb)),
-		settings.MustCanonicalConfig(
-			map[string]interface{}{
-				ElasticsearchUsername: username,
-				ElasticsearchPassword: password,
-			},
-		),
+		settings.MustCanonicalConfig(elasticsearchAuthSettings(kb, username, password)),
+		settings.MustCanonicalConfig(monitoringSettings(kb)),
 		userSettings,
 	)
 	if err != nil {
@@ -104,6 +103,25 @@ func NewConfigSettings(ctx context.Context, client k8s.Client, kb kbv1.Kibana, v
 	return CanonicalConfig{cfg}, nil
 }
 
+// elasticsearchAuthSettings returns the settings Kibana authenticates against Elasticsearch with, given the
+// content of the auth secret of the association.
+func elasticsearchAuthSettings(kb kbv1.Kibana, username, password string) map[string]interface{} {
+	switch kb.AssociationConf().GetAuthType() {
+	case commonv1.ServiceAccountTokenAuth:
+		return map[string]interface{}{ElasticsearchServiceAccountToken: password}
+	case commonv1.ClientCertificateAuth:
+		clientCertsVolumeMountPath := es.ClientCertSecretVolume(kb).VolumeMount().MountPath
+		return map[string]interface{}{
+			ElasticsearchSslCertificate: path.Join(clientCertsVolumeMountPath, certificates.CertFileName),
+			ElasticsearchSslKey:         path.Join(clientCertsVolumeMountPath, certificates.KeyFileName),
+		}
+	}
+	return map[string]interface{}{
+		ElasticsearchUsername: username,
+		ElasticsearchPassword: password,
+	}
+}
+
 // reusableSettings captures secrets settings in the Kibana configuration that we want to reuse.
 type reusableSettings struct {
 	EncryptionKey string `config:"xpack.security.encryptionKey"`
@@ -179,6 +197,21 @@ func kibanaTLSSettings(kb kbv1.Kibana) map[string]interface{} {
 	}
 }
 
+// monitoringSettings returns the settings required to collect the metrics and the logs of Kibana through the
+// monitoring sidecars.
+func monitoringSettings(kb kbv1.Kibana) map[string]interface{} {
+	cfg := map[string]interface{}{}
+	if kbstackmon.IsMetricsMonitoringDefined(kb) {
+		// metrics are collected by Metricbeat
+		cfg[XpackMonitoringKibanaCollectionEnabled] = false
+	}
+	if kbstackmon.IsLogsMonitoringDefined(kb) {
+		cfg[LoggingDest] = path.Join(volume.LogsVolumeMountPath, kbstackmon.LogsFile)
+		cfg[LoggingJSON] = true
+	}
+	return cfg
+}
+
 func elasticsearchTLSSettings(kb kbv1.Kibana) map[string]interface{} {
 	cfg := map[string]interface{}{
 		ElasticsearchSslVerificationMode: "certificate",
@@ -226,6 +226,110 @@ func TestNewConfigSettings(t *testing.T) {
 			}(),
 			wantErr: false,
 		},
+		{
+			name: "with Association authenticating with a service account token",
+			args: args{
+				kb: func() kbv1.Kibana {
+					kb := mkKibana()
+					kb.Spec = kbv1.KibanaSpec{
+						ElasticsearchRef: commonv1.ObjectSelector{Name: "test-es"},
+					}
+					kb.SetAssociationConf(&commonv1.AssociationConf{
+						AuthSecretName: "auth-secret",
+						AuthSecretKey:  "token",
+						AuthType:       commonv1.ServiceAccountTokenAuth,
+						CASecretName:   "ca-secret",
+						CACertProvided: true,
+						URL:            "https://es-url:9200",
+					})
+					return kb
+				},
+				client: k8s.WrapClient(fake.NewFakeClient(
+					existingSecret,
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "auth-secret",
+							Namespace: mkKibana().Namespace,
+						},
+						Data: map[string][]byte{
+							"token": []byte("AAEAAWVsYXN0aWM"),
+						},
+					},
+				)),
+			},
+			want: func() []byte {
+				cfg, err := settings.ParseConfig(defaultConfig)
+				require.NoError(t, err)
+				assocCfg, err := settings.ParseConfig([]byte(`
+elasticsearch:
+  hosts:
+    - "https://es-url:9200"
+  serviceAccountToken: "AAEAAWVsYXN0aWM"
+  ssl:
+    certificateAuthorities: /usr/share/kibana/config/elasticsearch-certs/ca.crt
+    verificationMode: certificate
+`))
+				require.NoError(t, err)
+				require.NoError(t, cfg.MergeWith(assocCfg))
+				bytes, err := cfg.Render()
+				require.NoError(t, err)
+				return bytes
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "with Association authenticating with a client certificate",
+			args: args{
+				kb: func() kbv1.Kibana {
+					kb := mkKibana()
+					kb.Spec = kbv1.KibanaSpec{
+						ElasticsearchRef: commonv1.ObjectSelector{Name: "test-es"},
+					}
+					kb.SetAssociationConf(&commonv1.AssociationConf{
+						AuthSecretName: "auth-secret",
+						AuthSecretKey:  "tls.crt",
+						AuthType:       commonv1.ClientCertificateAuth,
+						CASecretName:   "ca-secret",
+						CACertProvided: true,
+						URL:            "https://es-url:9200",
+					})
+					return kb
+				},
+				client: k8s.WrapClient(fake.NewFakeClient(
+					existingSecret,
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "auth-secret",
+							Namespace: mkKibana().Namespace,
+						},
+						Data: map[string][]byte{
+							"tls.crt": []byte("cert"),
+							"tls.key": []byte("key"),
+						},
+					},
+				)),
+			},
+			want: func() []byte {
+				cfg, err := settings.ParseConfig(defaultConfig)
+				require.NoError(t, err)
+				assocCfg, err := settings.ParseConfig([]byte(`
+elasticsearch:
+  hosts:
+    - "https://es-url:9200"
+  ssl:
+    certificate: /usr/share/kibana/config/elasticsearch-client-certs/tls.crt
+    key: /usr/share/kibana/config/elasticsearch-client-certs/tls.key
+    certificateAuthorities: /usr/share/kibana/config/elasticsearch-certs/ca.crt
+    verificationMode: certificate
+`))
+				require.NoError(t, err)
+				require.NoError(t, cfg.MergeWith(assocCfg))
+				bytes, err := cfg.Render()
+				require.NoError(t, err)
+				return bytes
+			}(),
+			wantErr: false,
+		},
 		{
 			name: "with user config",
 			args: args{
@@ -244,6 +348,26 @@ func TestNewConfigSettings(t *testing.T) {
 			},
 			want: append(defaultConfig, []byte(`foo: bar`)...),
 		},
+		{
+			name: "with monitoring",
+			args: args{
+				client: k8s.WrappedFakeClient(existingSecret),
+				kb: func() kbv1.Kibana {
+					kb := mkKibana()
+					kb.Spec = kbv1.KibanaSpec{
+						Monitoring: commonv1.Monitoring{
+							Metrics: commonv1.MetricsMonitoring{ElasticsearchRefs: []commonv1.ObjectSelector{{Name: "monitoring"}}},
+							Logs:    commonv1.LogsMonitoring{ElasticsearchRefs: []commonv1.ObjectSelector{{Name: "monitoring"}}},
+						},
+					}
+					return kb
+				},
+			},
+			want: append(defaultConfig, []byte(`
+xpack.monitoring.kibana.collection.enabled: false
+logging.dest: /usr/share/kibana/logs/kibana.json
+logging.json: true`)...),
+		},
 		{
 			name: "test existing secret does not prevent updates to config, e.g. spec takes precedence even if there is a secret indicating otherwise",
 			args: args{
@@ -17,6 +17,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
 	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/apmserver/labels"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
@@ -37,6 +38,7 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
 	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
+	kbstackmon "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/stackmon"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/volume"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
@@ -148,6 +150,11 @@ func (d *driver) deploymentParams(kb *kbv1.Kibana) (deployment.Params, error) {
 		}
 	}
 
+	if kb.AssociationConf().GetAuthType() == commonv1.ClientCertificateAuth {
+		// the client certificate is part of the config checksum through the auth secret
+		volumes = append(volumes, es.ClientCertSecretVolume(*kb))
+	}
+
 	if kb.Spec.HTTP.TLS.Enabled() {
 		// fetch the secret to calculate the checksum
 		var httpCerts corev1.Secret
@@ -173,6 +180,12 @@ func (d *driver) deploymentParams(kb *kbv1.Kibana) (deployment.Params, error) {
 		kibanaContainer.VolumeMounts = append(kibanaContainer.VolumeMounts, volume.VolumeMount())
 	}
 
+	// add the monitoring sidecars, if any
+	kibanaPodSpec, err = kbstackmon.WithMonitoring(kibanaPodSpec, *kb)
+	if err != nil {
+		return deployment.Params{}, err
+	}
+
 	// get config secret to add its content to the config checksum
 	configSecret := corev1.Secret{}
 	err = d.client.Get(types.NamespacedName{Name: config.SecretName(*kb), Namespace: kb.Namespace}, &configSecret)
@@ -219,6 +232,10 @@ func (d *driver) Reconcile(
 		return results.WithError(err)
 	}
 
+	if err := ReconcileNetworkPolicy(d.client, d.recorder, *kb, params); err != nil {
+		return results.WithError(err)
+	}
+
 	_, results = certificates.Reconciler{
 		K8sClient:             d.K8sClient(),
 		DynamicWatches:        d.DynamicWatches(),
@@ -229,6 +246,8 @@ func (d *driver) Reconcile(
 		Services:              []corev1.Service{*svc},
 		CACertRotation:        params.CACertRotation,
 		CertRotation:          params.CertRotation,
+		CertManagerIssuer:     params.CertManagerIssuer,
+		Recorder:              d.Recorder(),
 		GarbageCollectSecrets: true,
 	}.ReconcileCAAndHTTPCerts(ctx)
 	if results.HasError() {
@@ -245,6 +264,10 @@ func (d *driver) Reconcile(
 		return results.WithError(err)
 	}
 
+	if err := kbstackmon.ReconcileConfigSecrets(d.client, *kb); err != nil {
+		return results.WithError(err)
+	}
+
 	span, _ := apm.StartSpan(ctx, "reconcile_deployment", tracing.SpanTypeApp)
 	defer span.End()
 
@@ -258,7 +281,16 @@ func (d *driver) Reconcile(
 	if err != nil {
 		return results.WithError(err)
 	}
-	state.UpdateKibanaState(reconciledDp)
+	var pods corev1.PodList
+	var labels client.MatchingLabels = map[string]string{label.KibanaNameLabelName: kb.Name}
+	if err := d.client.List(&pods, client.InNamespace(kb.Namespace), labels); err != nil {
+		return results.WithError(err)
+	}
+	lowestVersion, err := version.MinInPods(pods.Items, label.KibanaVersionLabelName)
+	if err != nil {
+		return results.WithError(err)
+	}
+	state.UpdateKibanaState(reconciledDp, lowestVersion)
 	return results
 }
 
@@ -13,7 +13,10 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 )
 
-var eSCertsVolumeMountPath = "/usr/share/kibana/config/elasticsearch-certs"
+var (
+	eSCertsVolumeMountPath       = "/usr/share/kibana/config/elasticsearch-certs"
+	eSClientCertsVolumeMountPath = "/usr/share/kibana/config/elasticsearch-client-certs"
+)
 
 // CaCertSecretVolume returns a SecretVolume to hold the Elasticsearch CA certs for the given Kibana resource.
 func CaCertSecretVolume(kb kbv1.Kibana) volume.SecretVolume {
@@ -25,6 +28,16 @@ func CaCertSecretVolume(kb kbv1.Kibana) volume.SecretVolume {
 	)
 }
 
+// ClientCertSecretVolume returns a SecretVolume to hold the client certificate Kibana authenticates against
+// Elasticsearch with, for the given Kibana resource.
+func ClientCertSecretVolume(kb kbv1.Kibana) volume.SecretVolume {
+	return volume.NewSecretVolumeWithMountPath(
+		kb.AssociationConf().GetAuthSecretName(),
+		"elasticsearch-client-certs",
+		eSClientCertsVolumeMountPath,
+	)
+}
+
 // GetAuthSecret returns the Elasticsearch auth secret for the given Kibana resource.
 func GetAuthSecret(client k8s.Client, kb kbv1.Kibana) (*corev1.Secret, error) {
 	esAuthSecret := types.NamespacedName{
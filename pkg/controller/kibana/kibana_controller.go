@@ -9,10 +9,13 @@ import (
 	"reflect"
 	"sync/atomic"
 
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
 	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/finalizer"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/keystore"
@@ -24,13 +27,16 @@ import (
 	"go.elastic.co/apm"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -99,6 +105,28 @@ func addWatches(c controller.Controller, r *ReconcileKibana) error {
 		return err
 	}
 
+	// Watch the NetworkPolicies, and the Beats and Elastic Agents they allow traffic from
+	if err := c.Watch(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &kbv1.Kibana{},
+	}); err != nil {
+		return err
+	}
+	for _, kind := range []runtime.Object{&beatv1beta1.Beat{}, &agentv1alpha1.Agent{}} {
+		if err := c.Watch(&source.Kind{Type: kind}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(
+				func(object handler.MapObject) []reconcile.Request {
+					kb, referenced := referencedKibana(object.Object)
+					if !referenced {
+						return nil
+					}
+					return []reconcile.Request{{NamespacedName: kb}}
+				}),
+		}, predicate.GenerationChangedPredicate{}); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -191,6 +219,8 @@ func (r *ReconcileKibana) doReconcile(ctx context.Context, request reconcile.Req
 
 	state := NewState(request, kb)
 	results := driver.Reconcile(ctx, &state, kb, r.params)
+	_, reconcileErr := results.Aggregate()
+	state.UpdateReconciliationStatus(reconcileErr)
 
 	// update status
 	err = r.updateStatus(ctx, state)
@@ -240,4 +270,5 @@ func (r *ReconcileKibana) updateStatus(ctx context.Context, state State) error {
 func (r *ReconcileKibana) onDelete(obj types.NamespacedName) {
 	// Clean up watches set on secure settings
 	r.dynamicWatches.Secrets.RemoveHandlerForKey(keystore.SecureSettingsWatchName(obj))
+	certificates.DeleteExpirationMetrics(&kbv1.Kibana{}, obj)
 }
@@ -8,7 +8,10 @@ import (
 	common_name "github.com/elastic/cloud-on-k8s/pkg/controller/common/name"
 )
 
-const httpServiceSuffix = "http"
+const (
+	httpServiceSuffix   = "http"
+	networkPolicySuffix = "network-policy"
+)
 
 // KBNamer is a Namer that is configured with the defaults for resources related to a Kibana resource.
 var KBNamer = common_name.NewNamer("kb")
@@ -20,3 +23,7 @@ func HTTPService(kbName string) string {
 func Deployment(kbName string) string {
 	return KBNamer.Suffix(kbName)
 }
+
+func NetworkPolicy(kbName string) string {
+	return KBNamer.Suffix(kbName, networkPolicySuffix)
+}
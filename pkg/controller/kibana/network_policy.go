@@ -0,0 +1,98 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package kibana
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	agentv1alpha1 "github.com/elastic/cloud-on-k8s/pkg/apis/agent/v1alpha1"
+	beatv1beta1 "github.com/elastic/cloud-on-k8s/pkg/apis/beat/v1beta1"
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/agent"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/beat"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/networkpolicy"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+// ReconcileNetworkPolicy reconciles the NetworkPolicy allowing the traffic from the Beats and Elastic Agents
+// referencing the given Kibana if enabled, and deletes it otherwise.
+func ReconcileNetworkPolicy(c k8s.Client, recorder record.EventRecorder, kb kbv1.Kibana, params operator.Parameters) error {
+	enabled := networkpolicy.IsEnabled(recorder, &kb, kb.Spec.NetworkPolicy, params)
+	expected := networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+		Namespace: kb.Namespace,
+		Name:      kbname.NetworkPolicy(kb.Name),
+	}}
+	if enabled {
+		peers, err := kibanaClients(c, kb)
+		if err != nil {
+			return err
+		}
+		expected = networkpolicy.New(
+			metav1.ObjectMeta{
+				Namespace: kb.Namespace,
+				Name:      kbname.NetworkPolicy(kb.Name),
+				Labels:    label.NewLabels(kb.Name),
+			},
+			map[string]string{label.KibanaNameLabelName: kb.Name},
+			networkpolicy.Rule{Port: pod.HTTPPort, Peers: peers},
+		)
+	}
+	return networkpolicy.Reconcile(c, &kb, expected, enabled)
+}
+
+// kibanaClients returns the Pods of the Beats and Elastic Agents referencing the given Kibana.
+func kibanaClients(c k8s.Client, kb kbv1.Kibana) ([]networkingv1.NetworkPolicyPeer, error) {
+	kbKey := k8s.ExtractNamespacedName(&kb)
+	var peers []networkingv1.NetworkPolicyPeer
+
+	var beats beatv1beta1.BeatList
+	if err := c.List(&beats); err != nil {
+		return nil, err
+	}
+	for _, b := range beats.Items {
+		if references(kbKey, b.Namespace, b.Spec.KibanaRef) {
+			peers = append(peers, networkpolicy.PodsPeer(kb.Namespace, b.Namespace, map[string]string{beat.NameLabelName: b.Name}))
+		}
+	}
+
+	var agents agentv1alpha1.AgentList
+	if err := c.List(&agents); err != nil {
+		return nil, err
+	}
+	for _, a := range agents.Items {
+		if references(kbKey, a.Namespace, a.Spec.KibanaRef) {
+			peers = append(peers, networkpolicy.PodsPeer(kb.Namespace, a.Namespace, map[string]string{agent.NameLabelName: a.Name}))
+		}
+	}
+	return peers, nil
+}
+
+func references(kb types.NamespacedName, namespace string, ref commonv1.ObjectSelector) bool {
+	return ref.IsDefined() && ref.WithDefaultNamespace(namespace).NamespacedName() == kb
+}
+
+// referencedKibana returns the Kibana referenced by the given Beat or Elastic Agent, if any.
+func referencedKibana(obj interface{}) (types.NamespacedName, bool) {
+	var namespace string
+	var ref commonv1.ObjectSelector
+	switch o := obj.(type) {
+	case *beatv1beta1.Beat:
+		namespace, ref = o.Namespace, o.Spec.KibanaRef
+	case *agentv1alpha1.Agent:
+		namespace, ref = o.Namespace, o.Spec.KibanaRef
+	}
+	if !ref.IsDefined() {
+		return types.NamespacedName{}, false
+	}
+	return ref.WithDefaultNamespace(namespace).NamespacedName(), true
+}
@@ -0,0 +1,173 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package stackmon
+
+import (
+	"fmt"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/defaults"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/stackmon"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	kbname "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/name"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/pod"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/volume"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const (
+	// monitoringPasswordEnvVarName is the environment variable holding the password of the user collecting the
+	// metrics of Kibana.
+	monitoringPasswordEnvVarName = "KB_MONITORING_PASSWORD"
+
+	// LogsFile is the file Kibana writes its logs to, when logs monitoring is defined.
+	LogsFile = "kibana.json"
+
+	httpCertsMountPath = "/mnt/elastic-internal/kibana-http-certs"
+)
+
+// IsMetricsMonitoringDefined returns true if the metrics of the given Kibana are shipped to a monitoring cluster.
+func IsMetricsMonitoringDefined(kb kbv1.Kibana) bool {
+	return kb.Spec.Monitoring.MetricsRef().IsDefined()
+}
+
+// IsLogsMonitoringDefined returns true if the logs of the given Kibana are shipped to a monitoring cluster.
+func IsLogsMonitoringDefined(kb kbv1.Kibana) bool {
+	return kb.Spec.Monitoring.LogsRef().IsDefined()
+}
+
+// Sidecars returns the Metricbeat and Filebeat sidecars shipping the metrics and the logs of the given Kibana,
+// for the associations with the monitoring clusters which are configured.
+func Sidecars(kb kbv1.Kibana) ([]stackmon.BeatSidecar, error) {
+	var sidecars []stackmon.BeatSidecar
+
+	metricsConf, err := association.GetAssociationConfFromAnnotation(&kb, annotation.MonitoringMetricsAssociationConfAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if IsMetricsMonitoringDefined(kb) && metricsConf.IsConfigured() {
+		params := stackmon.BeatSidecarParams{
+			Monitored:       &kb,
+			Namer:           kbname.KBNamer,
+			Version:         kb.Spec.Version,
+			Labels:          label.NewLabels(kb.Name),
+			AssociationConf: metricsConf,
+			Modules:         []map[string]interface{}{metricbeatModule(kb)},
+		}
+		if kb.Spec.HTTP.TLS.Enabled() {
+			params.VolumeMounts = []corev1.VolumeMount{{
+				Name:      certificates.HTTPCertificatesSecretVolumeName,
+				MountPath: httpCertsMountPath,
+				ReadOnly:  true,
+			}}
+		}
+		// Kibana metrics are collected with the credentials Kibana uses to connect to Elasticsearch
+		if usesUserCredentials(kb) {
+			params.Env = []corev1.EnvVar{{
+				Name: monitoringPasswordEnvVarName,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: kb.AssociationConf().GetAuthSecretName()},
+						Key:                  kb.AssociationConf().GetAuthSecretKey(),
+					},
+				},
+			}}
+		}
+		metricbeat, err := stackmon.NewMetricbeatSidecar(params)
+		if err != nil {
+			return nil, err
+		}
+		sidecars = append(sidecars, metricbeat)
+	}
+
+	logsConf, err := association.GetAssociationConfFromAnnotation(&kb, annotation.MonitoringLogsAssociationConfAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if IsLogsMonitoringDefined(kb) && logsConf.IsConfigured() {
+		logsMount := volume.KibanaLogsVolume.VolumeMount()
+		logsMount.ReadOnly = true
+		filebeat, err := stackmon.NewFilebeatSidecar(stackmon.BeatSidecarParams{
+			Monitored:       &kb,
+			Namer:           kbname.KBNamer,
+			Version:         kb.Spec.Version,
+			Labels:          label.NewLabels(kb.Name),
+			AssociationConf: logsConf,
+			Modules: []map[string]interface{}{{
+				"module": "kibana",
+				"log": map[string]interface{}{
+					"enabled":   true,
+					"var.paths": []string{filepath.Join(volume.LogsVolumeMountPath, LogsFile)},
+				},
+			}},
+			VolumeMounts: []corev1.VolumeMount{logsMount},
+		})
+		if err != nil {
+			return nil, err
+		}
+		sidecars = append(sidecars, filebeat)
+	}
+
+	return sidecars, nil
+}
+
+// metricbeatModule returns the configuration of the Metricbeat module collecting the metrics of the local Kibana.
+func metricbeatModule(kb kbv1.Kibana) map[string]interface{} {
+	module := map[string]interface{}{
+		"module":        "kibana",
+		"metricsets":    []string{"stats"},
+		"xpack.enabled": true,
+		"period":        "10s",
+		"hosts":         []string{fmt.Sprintf("%s://localhost:%d", kb.Spec.HTTP.Protocol(), pod.HTTPPort)},
+	}
+	if usesUserCredentials(kb) {
+		module["username"] = kb.AssociationConf().GetAuthSecretKey()
+		module["password"] = "${" + monitoringPasswordEnvVarName + "}"
+	}
+	if kb.Spec.HTTP.TLS.Enabled() {
+		module["ssl.certificate_authorities"] = []string{filepath.Join(httpCertsMountPath, certificates.CAFileName)}
+		// the certificate is not issued for localhost
+		module["ssl.verification_mode"] = "certificate"
+	}
+	return module
+}
+
+// usesUserCredentials returns true if Kibana authenticates against Elasticsearch as a user, whose credentials can be
+// used to collect its metrics.
+func usesUserCredentials(kb kbv1.Kibana) bool {
+	return kb.AssociationConf().AuthIsConfigured() && kb.AssociationConf().GetAuthType() == commonv1.BasicAuth
+}
+
+// WithMonitoring adds the monitoring sidecars of the given Kibana to the Pod template.
+// Kibana writes its logs to a file shared with Filebeat, as soon as logs monitoring is defined.
+func WithMonitoring(podTemplate corev1.PodTemplateSpec, kb kbv1.Kibana) (corev1.PodTemplateSpec, error) {
+	builder := defaults.NewPodTemplateBuilder(podTemplate, kbv1.KibanaContainerName)
+	if IsLogsMonitoringDefined(kb) {
+		builder = builder.
+			WithVolumes(volume.KibanaLogsVolume.Volume()).
+			WithVolumeMounts(volume.KibanaLogsVolume.VolumeMount())
+	}
+	sidecars, err := Sidecars(kb)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	return stackmon.WithBeatSidecars(builder, sidecars...).PodTemplate, nil
+}
+
+// ReconcileConfigSecrets reconciles the configuration Secrets of the monitoring sidecars of the given Kibana.
+func ReconcileConfigSecrets(c k8s.Client, kb kbv1.Kibana) error {
+	sidecars, err := Sidecars(kb)
+	if err != nil {
+		return err
+	}
+	return stackmon.ReconcileConfigSecrets(c, &kb, kbname.KBNamer, sidecars...)
+}
@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package stackmon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/stackmon"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/volume"
+)
+
+const assocConf = `{"authSecretName":"kb-kb-monitoring-metrics-user","authSecretKey":"ns-kb-kb-monitoring-metrics-user","caCertProvided":true,"caSecretName":"kb-kb-monitoring-metrics-ca","url":"https://monitoring-es-http.observability.svc:9200"}`
+
+func TestWithMonitoring(t *testing.T) {
+	ref := []commonv1.ObjectSelector{{Namespace: "observability", Name: "monitoring"}}
+	kb := kbv1.Kibana{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb", Annotations: map[string]string{
+			annotation.MonitoringMetricsAssociationConfAnnotation: assocConf,
+			annotation.MonitoringLogsAssociationConfAnnotation:    assocConf,
+		}},
+		Spec: kbv1.KibanaSpec{
+			Version:          "7.14.0",
+			ElasticsearchRef: commonv1.ObjectSelector{Name: "es"},
+			Monitoring: commonv1.Monitoring{
+				Metrics: commonv1.MetricsMonitoring{ElasticsearchRefs: ref},
+				Logs:    commonv1.LogsMonitoring{ElasticsearchRefs: ref},
+			},
+		},
+	}
+	kb.SetAssociationConf(&commonv1.AssociationConf{
+		AuthSecretName: "kb-kibana-user",
+		AuthSecretKey:  "ns-kb-kibana-user",
+		URL:            "https://es-es-http.ns.svc:9200",
+	})
+
+	podTemplate, err := WithMonitoring(corev1.PodTemplateSpec{}, kb)
+	require.NoError(t, err)
+
+	containers := podTemplate.Spec.Containers
+	require.Len(t, containers, 3)
+	require.Equal(t, kbv1.KibanaContainerName, containers[0].Name)
+	require.Equal(t, stackmon.MetricbeatType, containers[1].Name)
+	require.Equal(t, stackmon.FilebeatType, containers[2].Name)
+	// Kibana and Filebeat share the logs volume
+	require.Contains(t, containers[0].VolumeMounts, volume.KibanaLogsVolume.VolumeMount())
+	logsMount := volume.KibanaLogsVolume.VolumeMount()
+	logsMount.ReadOnly = true
+	require.Contains(t, containers[2].VolumeMounts, logsMount)
+	require.Contains(t, podTemplate.Spec.Volumes, volume.KibanaLogsVolume.Volume())
+	// Metricbeat collects the metrics with the credentials of Kibana
+	require.Contains(t, containers[1].Env, corev1.EnvVar{
+		Name: "KB_MONITORING_PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "kb-kibana-user"},
+				Key:                  "ns-kb-kibana-user",
+			},
+		},
+	})
+	require.NotEmpty(t, podTemplate.Annotations[stackmon.ConfigHashAnnotationName])
+}
+
+func TestWithMonitoring_NotDefined(t *testing.T) {
+	kb := kbv1.Kibana{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kb"}, Spec: kbv1.KibanaSpec{Version: "7.14.0"}}
+	podTemplate, err := WithMonitoring(corev1.PodTemplateSpec{}, kb)
+	require.NoError(t, err)
+	require.Len(t, podTemplate.Spec.Containers, 1)
+	require.Empty(t, podTemplate.Spec.Volumes)
+	require.Empty(t, podTemplate.Annotations)
+}
@@ -10,6 +10,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/version"
 )
 
 // State holds the accumulated state during the reconcile loop including the response and a pointer to a Kibana
@@ -27,8 +29,8 @@ func NewState(request reconcile.Request, kb *kbv1.Kibana) State {
 	return State{Request: request, Kibana: kb, originalKibana: kb.DeepCopy()}
 }
 
-// UpdateKibanaState updates the Kibana status based on the given deployment.
-func (s State) UpdateKibanaState(deployment appsv1.Deployment) {
+// UpdateKibanaState updates the Kibana status based on the given deployment and the lowest version running in its Pods.
+func (s State) UpdateKibanaState(deployment appsv1.Deployment, lowestVersion *version.Version) {
 	s.Kibana.Status.AvailableNodes = deployment.Status.AvailableReplicas
 	s.Kibana.Status.Health = kbv1.KibanaRed
 	for _, c := range deployment.Status.Conditions {
@@ -36,4 +38,13 @@ func (s State) UpdateKibanaState(deployment appsv1.Deployment) {
 			s.Kibana.Status.Health = kbv1.KibanaGreen
 		}
 	}
+	common.ReportRunningVersion(&s.Kibana.Status.ReconcilerStatus, lowestVersion, s.Kibana.Spec.Version)
+}
+
+// UpdateReconciliationStatus reports the reconciliation of the current Kibana generation, see common.UpdateReconciliationStatus.
+func (s State) UpdateReconciliationStatus(err error) {
+	common.UpdateReconciliationStatus(
+		&s.Kibana.Status.ReconcilerStatus, s.Kibana.Generation, err,
+		s.Kibana.Status.Health == kbv1.KibanaGreen, "Kibana is not available",
+	)
 }
@@ -11,9 +11,15 @@ import (
 const (
 	DataVolumeName      = "kibana-data"
 	DataVolumeMountPath = "/usr/share/kibana/data"
+
+	LogsVolumeName      = "kibana-logs"
+	LogsVolumeMountPath = "/usr/share/kibana/logs"
 )
 
 // KibanaDataVolume is used to propagate the keystore file from the init container to
 // Kibana running in the main container.
 // Since Kibana is stateless and the keystore is created on pod start, an EmptyDir is fine here.
 var KibanaDataVolume = volume.NewEmptyDirVolume(DataVolumeName, DataVolumeMountPath)
+
+// KibanaLogsVolume holds the logs written by Kibana to be collected by the Filebeat monitoring sidecar.
+var KibanaLogsVolume = volume.NewEmptyDirVolume(LogsVolumeName, LogsVolumeMountPath)
@@ -31,7 +31,9 @@ import (
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
+	esuser "github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
 	"github.com/elastic/cloud-on-k8s/pkg/controller/kibana/label"
+	kbstackmon "github.com/elastic/cloud-on-k8s/pkg/controller/kibana/stackmon"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/maps"
 	"github.com/elastic/cloud-on-k8s/pkg/utils/rbac"
@@ -88,6 +90,7 @@ func newReconciler(mgr manager.Manager, accessReviewer rbac.AccessReviewer, para
 		accessReviewer: accessReviewer,
 		watches:        watches.NewDynamicWatches(),
 		recorder:       mgr.GetEventRecorderFor(name),
+		newESClient:    association.NewESClientProvider(params.Dialer),
 		Parameters:     params,
 	}
 }
@@ -100,6 +103,7 @@ type ReconcileAssociation struct {
 	accessReviewer rbac.AccessReviewer
 	recorder       record.EventRecorder
 	watches        watches.DynamicWatches
+	newESClient    association.ESClientProvider
 	operator.Parameters
 	// iteration is the number of times this controller has run its Reconcile method
 	iteration uint64
@@ -152,7 +156,7 @@ func (r *ReconcileAssociation) Reconcile(request reconcile.Request) (reconcile.R
 	}
 
 	results := reconciler.NewResult(ctx)
-	newStatus, err := r.reconcileInternal(ctx, &kibana)
+	newStatus, requeueAfter, err := r.reconcileInternal(ctx, &kibana)
 	if err != nil {
 		results.WithError(err)
 		k8s.EmitErrorEvent(r.recorder, err, &kibana, events.EventReconciliationError, "Reconciliation error: %v", err)
@@ -166,6 +170,7 @@ func (r *ReconcileAssociation) Reconcile(request reconcile.Request) (reconcile.R
 	return results.
 		WithResult(association.RequeueRbacCheck(r.accessReviewer)).
 		WithResult(resultFromStatus(newStatus)).
+		WithResult(reconcile.Result{RequeueAfter: requeueAfter}).
 		Aggregate()
 }
 
@@ -213,7 +218,7 @@ func (r *ReconcileAssociation) isCompatible(ctx context.Context, kibana *kbv1.Ki
 	return compat, err
 }
 
-func (r *ReconcileAssociation) reconcileInternal(ctx context.Context, kibana *kbv1.Kibana) (commonv1.AssociationStatus, error) {
+func (r *ReconcileAssociation) reconcileInternal(ctx context.Context, kibana *kbv1.Kibana) (commonv1.AssociationStatus, time.Duration, error) {
 	kibanaKey := k8s.ExtractNamespacedName(kibana)
 	// garbage collect leftover resources that are not required anymore
 	if err := deleteOrphanedResources(ctx, r, kibana); err != nil {
@@ -223,10 +228,10 @@ func (r *ReconcileAssociation) reconcileInternal(ctx context.Context, kibana *kb
 	if kibana.Spec.ElasticsearchRef.Name == "" {
 		// clean up watchers and remove artifacts related to the association
 		if err := r.onDelete(kibanaKey); err != nil {
-			return commonv1.AssociationFailed, err
+			return commonv1.AssociationFailed, 0, err
 		}
 		// remove the configuration in the annotation, other leftover resources are already garbage-collected
-		return commonv1.AssociationUnknown, association.RemoveAssociationConf(r.Client, kibana)
+		return commonv1.AssociationUnknown, 0, association.RemoveAssociationConf(r.Client, kibana)
 	}
 
 	// this Kibana instance references an Elasticsearch cluster
@@ -243,7 +248,7 @@ func (r *ReconcileAssociation) reconcileInternal(ctx context.Context, kibana *kb
 		Watched: []types.NamespacedName{esRefKey},
 		Watcher: kibanaKey,
 	}); err != nil {
-		return commonv1.AssociationFailed, err
+		return commonv1.AssociationFailed, 0, err
 	}
 
 	userSecretKey := association.UserKey(kibana, kibanaUserSuffix)
@@ -253,12 +258,12 @@ func (r *ReconcileAssociation) reconcileInternal(ctx context.Context, kibana *kb
 		Watched: []types.NamespacedName{userSecretKey},
 		Watcher: kibanaKey,
 	}); err != nil {
-		return commonv1.AssociationFailed, err
+		return commonv1.AssociationFailed, 0, err
 	}
 
 	es, status, err := r.getElasticsearch(ctx, kibana, esRefKey)
 	if status != "" || err != nil {
-		return status, err
+		return status, 0, err
 	}
 
 	// Check if reference to Elasticsearch is allowed to be established
@@ -269,37 +274,106 @@ func (r *ReconcileAssociation) reconcileInternal(ctx context.Context, kibana *kb
 		r,
 		r.recorder,
 	); err != nil || !allowed {
-		return commonv1.AssociationPending, err
+		return commonv1.AssociationPending, 0, err
 	}
 
-	if err := association.ReconcileEsUser(
-		ctx,
-		r.Client,
-		kibana,
-		associationLabels(kibana),
-		KibanaSystemUserBuiltinRole,
-		kibanaUserSuffix,
-		es); err != nil {
-		return commonv1.AssociationPending, err
+	authSecret, authType, requeueAfter, err := r.reconcileAuth(ctx, kibana, es)
+	if err != nil {
+		return commonv1.AssociationPending, 0, err
 	}
 
 	caSecret, err := r.reconcileElasticsearchCA(ctx, kibana, esRefKey)
 	if err != nil {
-		return commonv1.AssociationPending, err
+		return commonv1.AssociationPending, 0, err
 	}
 
 	// construct the expected association configuration
-	authSecret := association.ClearTextSecretKeySelector(kibana, kibanaUserSuffix)
 	expectedESAssoc := &commonv1.AssociationConf{
 		AuthSecretName: authSecret.Name,
 		AuthSecretKey:  authSecret.Key,
+		AuthType:       authType,
 		CACertProvided: caSecret.CACertProvided,
 		CASecretName:   caSecret.Name,
 		URL:            services.ExternalServiceURL(es),
 	}
 
 	// update the association configuration if necessary
-	return r.updateAssociationConf(ctx, expectedESAssoc, kibana)
+	status, err = r.updateAssociationConf(ctx, expectedESAssoc, kibana)
+	return status, requeueAfter, err
+}
+
+// reconcileAuth reconciles the credentials Kibana uses to authenticate against Elasticsearch: a client certificate
+// if client authentication is enabled on the HTTP layer of Elasticsearch, other credentials otherwise.
+func (r *ReconcileAssociation) reconcileAuth(
+	ctx context.Context,
+	kibana *kbv1.Kibana,
+	es esv1.Elasticsearch,
+) (*corev1.SecretKeySelector, commonv1.AuthType, time.Duration, error) {
+	// the Metricbeat sidecar collects the Kibana metrics with the credentials of Kibana, as a user
+	if !es.Spec.HTTP.TLS.ClientAuthenticationEnabled() || kbstackmon.IsMetricsMonitoringDefined(*kibana) {
+		return r.reconcileCredentials(ctx, kibana, es)
+	}
+	ready, err := association.ClientCertificateAuthReady(r.Client, es)
+	if err != nil {
+		return nil, commonv1.ClientCertificateAuth, 0, err
+	}
+	if ready {
+		authSecret, requeueAfter, err := association.ReconcileClientCertificate(
+			ctx,
+			r.Client,
+			kibana,
+			associationLabels(kibana),
+			KibanaSystemUserBuiltinRole,
+			kibanaUserSuffix,
+			es,
+			r.CertRotation,
+			time.Now(),
+		)
+		return authSecret, commonv1.ClientCertificateAuth, requeueAfter, err
+	}
+	// keep the current credentials until all the Elasticsearch Pods request client certificates
+	authSecret, authType, requeueAfter, err := r.reconcileCredentials(ctx, kibana, es)
+	if requeueAfter == 0 || requeueAfter > association.ClientCertificateCheckInterval {
+		requeueAfter = association.ClientCertificateCheckInterval
+	}
+	return authSecret, authType, requeueAfter, err
+}
+
+// reconcileCredentials reconciles the credentials Kibana uses to authenticate against Elasticsearch: a token of the
+// elastic/kibana service account if supported, a file realm user otherwise.
+func (r *ReconcileAssociation) reconcileCredentials(
+	ctx context.Context,
+	kibana *kbv1.Kibana,
+	es esv1.Elasticsearch,
+) (*corev1.SecretKeySelector, commonv1.AuthType, time.Duration, error) {
+	// the Metricbeat sidecar collects the Kibana metrics with the credentials of Kibana, as a user
+	if association.SupportsCredentials(es, kibana.Spec.Version) && !kbstackmon.IsMetricsMonitoringDefined(*kibana) {
+		authSecret, requeueAfter, err := association.ReconcileCredentials(
+			ctx,
+			r.Client,
+			r.newESClient,
+			kibana,
+			associationLabels(kibana),
+			kibanaUserSuffix,
+			es,
+			association.CredentialsSpec{AuthType: commonv1.ServiceAccountTokenAuth, ServiceAccount: esuser.KibanaServiceAccount},
+			time.Now(),
+		)
+		return authSecret, commonv1.ServiceAccountTokenAuth, requeueAfter, err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
+		ctx,
+		r.Client,
+		kibana,
+		associationLabels(kibana),
+		KibanaSystemUserBuiltinRole,
+		kibanaUserSuffix,
+		es)
+	if err != nil {
+		return nil, commonv1.BasicAuth, 0, err
+	}
+	return authSecretRef, commonv1.BasicAuth, 0, nil
 }
 
 func (r *ReconcileAssociation) updateAssociationConf(ctx context.Context, expectedESAssoc *commonv1.AssociationConf, kibana *kbv1.Kibana) (commonv1.AssociationStatus, error) {
@@ -0,0 +1,451 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package monitoringassociation
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"go.elastic.co/apm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/events"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/reconciler"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/tracing"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/services"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/rbac"
+)
+
+const name = "monitoring-association-controller"
+
+var (
+	log            = logf.Log.WithName(name)
+	defaultRequeue = reconcile.Result{Requeue: true, RequeueAfter: 10 * time.Second}
+)
+
+// monitoredKind describes a kind of resource whose metrics and logs can be shipped to monitoring Elasticsearch clusters.
+type monitoredKind struct {
+	// name of the kind, used in logs and events
+	name string
+	// shortName of the kind, used to name the controller and the resources it creates
+	shortName string
+	// newObject returns an empty resource of this kind
+	newObject func() commonv1.Monitored
+	// nameLabel and namespaceLabel identify the resources created for a monitored resource of this kind
+	nameLabel, namespaceLabel string
+}
+
+var (
+	elasticsearchKind = monitoredKind{
+		name:           "Elasticsearch",
+		shortName:      "es",
+		newObject:      func() commonv1.Monitored { return &esv1.Elasticsearch{} },
+		nameLabel:      ElasticsearchAssociationLabelName,
+		namespaceLabel: ElasticsearchAssociationLabelNamespace,
+	}
+	kibanaKind = monitoredKind{
+		name:           "Kibana",
+		shortName:      "kb",
+		newObject:      func() commonv1.Monitored { return &kbv1.Kibana{} },
+		nameLabel:      KibanaAssociationLabelName,
+		namespaceLabel: KibanaAssociationLabelNamespace,
+	}
+)
+
+// monitoringType describes a type of monitoring data shipped to a monitoring Elasticsearch cluster.
+type monitoringType struct {
+	name string
+	// annotationName is the annotation holding the association configuration on the monitored resource
+	annotationName string
+	// ref returns the reference to the monitoring Elasticsearch cluster receiving this type of data, if any
+	ref func(commonv1.Monitoring) *commonv1.ObjectSelector
+	// userRole is the role of the user shipping this type of data to the monitoring Elasticsearch cluster
+	userRole string
+}
+
+var (
+	metricsType = monitoringType{
+		name:           "metrics",
+		annotationName: annotation.MonitoringMetricsAssociationConfAnnotation,
+		ref:            commonv1.Monitoring.MetricsRef,
+		userRole:       user.RemoteMonitoringAgentBuiltinRole,
+	}
+	logsType = monitoringType{
+		name:           "logs",
+		annotationName: annotation.MonitoringLogsAssociationConfAnnotation,
+		ref:            commonv1.Monitoring.LogsRef,
+		userRole:       user.BeatUserRoleV7,
+	}
+	monitoringTypes = []monitoringType{metricsType, logsType}
+)
+
+// monitoringAssociation is the association between a monitored resource and the Elasticsearch cluster receiving one
+// type of its monitoring data. It allows the association helpers to manage several associations for the same resource.
+type monitoringAssociation struct {
+	commonv1.Monitored
+	ref  commonv1.ObjectSelector
+	conf *commonv1.AssociationConf
+}
+
+var _ commonv1.Associated = &monitoringAssociation{}
+var _ association.Wrapper = &monitoringAssociation{}
+
+func (m *monitoringAssociation) ElasticsearchRef() commonv1.ObjectSelector {
+	return m.ref
+}
+
+func (m *monitoringAssociation) AssociationConf() *commonv1.AssociationConf {
+	return m.conf
+}
+
+func (m *monitoringAssociation) Unwrap() runtime.Object {
+	return m.Monitored
+}
+
+// Add creates the monitoring association controllers of Elasticsearch and Kibana, and adds them to the Manager.
+func Add(mgr manager.Manager, accessReviewer rbac.AccessReviewer, params operator.Parameters) error {
+	for _, kind := range []monitoredKind{elasticsearchKind, kibanaKind} {
+		r := newReconciler(mgr, kind, accessReviewer, params)
+		c, err := common.NewController(mgr, kind.shortName+"-"+name, r, params)
+		if err != nil {
+			return err
+		}
+		if err := addWatches(c, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newReconciler(mgr manager.Manager, kind monitoredKind, accessReviewer rbac.AccessReviewer, params operator.Parameters) *ReconcileMonitoringAssociation {
+	client := k8s.WrapClient(mgr.GetClient())
+	return &ReconcileMonitoringAssociation{
+		Client:         client,
+		kind:           kind,
+		accessReviewer: accessReviewer,
+		watches:        watches.NewDynamicWatches(),
+		recorder:       mgr.GetEventRecorderFor(kind.shortName + "-" + name),
+		Parameters:     params,
+	}
+}
+
+func addWatches(c controller.Controller, r *ReconcileMonitoringAssociation) error {
+	// Watch for changes to the monitored resources
+	if err := c.Watch(&source.Kind{Type: r.kind.newObject()}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// Dynamically watch referenced monitoring Elasticsearch clusters
+	if err := c.Watch(&source.Kind{Type: &esv1.Elasticsearch{}}, r.watches.ElasticsearchClusters); err != nil {
+		return err
+	}
+
+	// Dynamically watch the public CA secrets of the monitoring Elasticsearch clusters
+	if err := c.Watch(&source.Kind{Type: &corev1.Secret{}}, r.watches.Secrets); err != nil {
+		return err
+	}
+
+	// Watch Secrets owned by a monitored resource
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    r.kind.newObject(),
+		IsController: true,
+	})
+}
+
+var _ reconcile.Reconciler = &ReconcileMonitoringAssociation{}
+
+// ReconcileMonitoringAssociation reconciles the associations between a monitored resource and the Elasticsearch
+// clusters receiving its metrics and its logs.
+type ReconcileMonitoringAssociation struct {
+	k8s.Client
+	kind           monitoredKind
+	accessReviewer rbac.AccessReviewer
+	recorder       record.EventRecorder
+	watches        watches.DynamicWatches
+	operator.Parameters
+	// iteration is the number of times this controller has run its Reconcile method
+	iteration uint64
+}
+
+// unbinderFunc allows a function to be used as an association.Unbinder.
+type unbinderFunc func(associated commonv1.Associated) error
+
+func (f unbinderFunc) Unbind(associated commonv1.Associated) error {
+	return f(associated)
+}
+
+func (r *ReconcileMonitoringAssociation) elasticsearchWatchName(monitored types.NamespacedName, monitoringType monitoringType) string {
+	return monitored.Namespace + "-" + monitored.Name + "-" + r.kind.shortName + "-monitoring-" + monitoringType.name + "-es-watch"
+}
+
+func (r *ReconcileMonitoringAssociation) esCAWatchName(monitored types.NamespacedName, monitoringType monitoringType) string {
+	return monitored.Namespace + "-" + monitored.Name + "-" + r.kind.shortName + "-monitoring-" + monitoringType.name + "-es-ca-watch"
+}
+
+// userSuffix is the suffix of the user shipping the given type of monitoring data.
+// It includes the kind of the monitored resource, since resources of different kinds may share the same name.
+func (r *ReconcileMonitoringAssociation) userSuffix(monitoringType monitoringType) string {
+	return r.kind.shortName + "-monitoring-" + monitoringType.name + "-user"
+}
+
+// caSecretSuffix is the suffix of the copy of the monitoring Elasticsearch cluster CA for the given type of data.
+func (r *ReconcileMonitoringAssociation) caSecretSuffix(monitoringType monitoringType) string {
+	return r.kind.shortName + "-monitoring-" + monitoringType.name + "-ca"
+}
+
+func (r *ReconcileMonitoringAssociation) onDelete(obj types.NamespacedName) error {
+	// Clean up memory
+	for _, monitoringType := range monitoringTypes {
+		r.watches.ElasticsearchClusters.RemoveHandlerForKey(r.elasticsearchWatchName(obj, monitoringType))
+		r.watches.Secrets.RemoveHandlerForKey(r.esCAWatchName(obj, monitoringType))
+	}
+	// Delete users
+	return k8s.DeleteSecretMatching(r.Client, r.kind.newUserLabelSelector(obj))
+}
+
+// Reconcile reads the state of the cluster for a monitored resource and makes changes to its monitoring associations
+// based on the state read and what is in its specification.
+func (r *ReconcileMonitoringAssociation) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	defer common.LogReconciliationRun(log, request, "name", &r.iteration)()
+	tx, ctx := tracing.NewTransaction(r.Tracer, request.NamespacedName, r.kind.shortName+"-monitoring-association")
+	defer tracing.EndTransaction(tx)
+
+	monitored := r.kind.newObject()
+	if err := r.Get(request.NamespacedName, monitored); err != nil {
+		if apierrors.IsNotFound(err) {
+			// the monitored resource has been deleted, remove artifacts related to the associations.
+			return reconcile.Result{}, r.onDelete(request.NamespacedName)
+		}
+		return reconcile.Result{}, tracing.CaptureError(ctx, err)
+	}
+
+	if common.IsUnmanaged(metav1.ObjectMeta{Namespace: monitored.GetNamespace(), Name: monitored.GetName(), Annotations: monitored.GetAnnotations()}) {
+		log.Info("Object is currently not managed by this controller. Skipping reconciliation",
+			"kind", r.kind.name, "namespace", monitored.GetNamespace(), "name", monitored.GetName())
+		return reconcile.Result{}, nil
+	}
+
+	// the monitored resource is being deleted, short-circuit reconciliation and remove artifacts related to the associations.
+	if !monitored.GetDeletionTimestamp().IsZero() {
+		return reconcile.Result{}, tracing.CaptureError(ctx, r.onDelete(request.NamespacedName))
+	}
+
+	results := reconciler.NewResult(ctx)
+	for _, monitoringType := range monitoringTypes {
+		status, err := r.reconcileAssociation(ctx, monitored, monitoringType)
+		if err != nil {
+			results.WithError(err)
+		}
+		results.WithResult(resultFromStatus(status))
+	}
+
+	if err := r.deleteOrphanedResources(ctx, monitored); err != nil {
+		log.Error(err, "Error while trying to delete orphaned resources. Continuing.",
+			"kind", r.kind.name, "namespace", monitored.GetNamespace(), "name", monitored.GetName())
+	}
+
+	return results.WithResult(association.RequeueRbacCheck(r.accessReviewer)).Aggregate()
+}
+
+// reconcileAssociation reconciles the association between the monitored resource and the Elasticsearch cluster
+// receiving the given type of monitoring data: it creates a user in the monitoring cluster, copies its CA in the
+// namespace of the monitored resource, and stores the association configuration in an annotation of the monitored
+// resource.
+func (r *ReconcileMonitoringAssociation) reconcileAssociation(
+	ctx context.Context,
+	monitored commonv1.Monitored,
+	monitoringType monitoringType,
+) (commonv1.AssociationStatus, error) {
+	span, _ := apm.StartSpan(ctx, "reconcile_"+monitoringType.name+"_association", tracing.SpanTypeApp)
+	defer span.End()
+
+	key := k8s.ExtractNamespacedName(monitored)
+	ref := monitoringType.ref(monitored.MonitoringSpec())
+	if !ref.IsDefined() {
+		// no monitoring cluster for this type of data, remove any leftover configuration
+		r.watches.ElasticsearchClusters.RemoveHandlerForKey(r.elasticsearchWatchName(key, monitoringType))
+		r.watches.Secrets.RemoveHandlerForKey(r.esCAWatchName(key, monitoringType))
+		return commonv1.AssociationUnknown, association.RemoveAssociationConfAnnotation(r.Client, monitored, monitoringType.annotationName)
+	}
+	esRef := ref.WithDefaultNamespace(monitored.GetNamespace())
+
+	// Make sure we see events from the monitoring Elasticsearch cluster using a dynamic watch
+	if err := r.watches.ElasticsearchClusters.AddHandler(watches.NamedWatch{
+		Name:    r.elasticsearchWatchName(key, monitoringType),
+		Watched: []types.NamespacedName{esRef.NamespacedName()},
+		Watcher: key,
+	}); err != nil {
+		return commonv1.AssociationFailed, err
+	}
+
+	var es esv1.Elasticsearch
+	if err := r.Get(esRef.NamespacedName(), &es); err != nil {
+		k8s.EmitErrorEvent(r.recorder, err, monitored, events.EventAssociationError,
+			"Failed to find referenced monitoring cluster %s: %v", esRef.NamespacedName(), err)
+		if apierrors.IsNotFound(err) {
+			// the monitoring cluster is not found, remove any existing configuration and retry in a bit.
+			if err := association.RemoveAssociationConfAnnotation(r.Client, monitored, monitoringType.annotationName); err != nil && !apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, err
+			}
+			return commonv1.AssociationPending, nil
+		}
+		return commonv1.AssociationFailed, err
+	}
+
+	assocConf, err := association.GetAssociationConfFromAnnotation(monitored, monitoringType.annotationName)
+	if err != nil {
+		return commonv1.AssociationFailed, err
+	}
+	assoc := &monitoringAssociation{Monitored: monitored, ref: esRef, conf: assocConf}
+
+	// Check if reference to the monitoring cluster is allowed to be established
+	if allowed, err := association.CheckAndUnbind(
+		r.accessReviewer,
+		assoc,
+		&es,
+		unbinderFunc(r.unbind(monitoringType)),
+		r.recorder,
+	); err != nil || !allowed {
+		return commonv1.AssociationPending, err
+	}
+
+	authSecretRef, err := association.ReconcileEsUser(
+		ctx,
+		r.Client,
+		assoc,
+		r.kind.associationLabels(key, monitoringType),
+		monitoringType.userRole,
+		r.userSuffix(monitoringType),
+		es,
+	)
+	if err != nil {
+		return commonv1.AssociationPending, err
+	}
+
+	// watch the monitoring cluster CA secret to reconcile on any change
+	if err := r.watches.Secrets.AddHandler(watches.NamedWatch{
+		Name:    r.esCAWatchName(key, monitoringType),
+		Watched: []types.NamespacedName{certificates.PublicCertsSecretRef(esv1.ESNamer, esRef.NamespacedName())},
+		Watcher: key,
+	}); err != nil {
+		return commonv1.AssociationFailed, err
+	}
+	caSecret, err := association.ReconcileCASecret(
+		r.Client,
+		assoc,
+		esRef.NamespacedName(),
+		r.kind.associationLabels(key, monitoringType),
+		r.caSecretSuffix(monitoringType),
+	)
+	if err != nil {
+		return commonv1.AssociationPending, err // maybe not created yet
+	}
+
+	expectedAssocConf := &commonv1.AssociationConf{
+		AuthSecretName: authSecretRef.Name,
+		AuthSecretKey:  authSecretRef.Key,
+		CACertProvided: caSecret.CACertProvided,
+		CASecretName:   caSecret.Name,
+		URL:            services.ExternalServiceURL(es),
+	}
+	if !reflect.DeepEqual(expectedAssocConf, assocConf) {
+		log.Info("Updating monitoring association configuration", "kind", r.kind.name,
+			"namespace", monitored.GetNamespace(), "name", monitored.GetName(), "type", monitoringType.name)
+		if err := association.UpdateAssociationConfAnnotation(r.Client, monitored, monitoringType.annotationName, expectedAssocConf); err != nil {
+			if apierrors.IsConflict(err) {
+				return commonv1.AssociationPending, nil
+			}
+			return commonv1.AssociationPending, err
+		}
+	}
+
+	return commonv1.AssociationEstablished, nil
+}
+
+// deleteOrphanedResources deletes resources created by this controller that are left over from previous
+// reconciliation attempts: either because a monitoring cluster reference has been removed, or because the namespace
+// of the monitoring cluster holding the user has changed.
+func (r *ReconcileMonitoringAssociation) deleteOrphanedResources(ctx context.Context, monitored commonv1.Monitored) error {
+	span, _ := apm.StartSpan(ctx, "delete_orphaned_resources", tracing.SpanTypeApp)
+	defer span.End()
+
+	key := k8s.ExtractNamespacedName(monitored)
+	var secrets corev1.SecretList
+	matchLabels := client.MatchingLabels{
+		r.kind.nameLabel:      key.Name,
+		r.kind.namespaceLabel: key.Namespace,
+	}
+	if err := r.List(&secrets, matchLabels); err != nil {
+		return err
+	}
+
+	for _, s := range secrets.Items {
+		var ref *commonv1.ObjectSelector
+		switch s.Labels[AssociationLabelType] {
+		case metricsType.name:
+			ref = metricsType.ref(monitored.MonitoringSpec())
+		case logsType.name:
+			ref = logsType.ref(monitored.MonitoringSpec())
+		default:
+			continue
+		}
+		isUser := s.Labels[common.TypeLabelName] == user.AssociatedUserType
+		if ref.IsDefined() && (!isUser || ref.WithDefaultNamespace(key.Namespace).Namespace == s.Namespace) {
+			continue
+		}
+		log.Info("Deleting secret", "namespace", s.Namespace, "secret_name", s.Name, "kind", r.kind.name, "name", key.Name)
+		if err := r.Delete(&s); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func resultFromStatus(status commonv1.AssociationStatus) reconcile.Result {
+	switch status {
+	case commonv1.AssociationPending:
+		return defaultRequeue // retry
+	default:
+		return reconcile.Result{} // we are done or there is not much we can do
+	}
+}
+
+// unbind returns a function removing the resources related to the association for the given type of monitoring data.
+func (r *ReconcileMonitoringAssociation) unbind(monitoringType monitoringType) func(associated commonv1.Associated) error {
+	return func(associated commonv1.Associated) error {
+		key := k8s.ExtractNamespacedName(associated)
+		// Ensure that user in Elasticsearch is deleted to prevent illegitimate access
+		if err := k8s.DeleteSecretMatching(r.Client, r.kind.newTypeUserLabelSelector(key, monitoringType)); err != nil {
+			return err
+		}
+		// Also remove the association configuration
+		monitored := associated.(*monitoringAssociation).Monitored
+		return association.RemoveAssociationConfAnnotation(r.Client, monitored, monitoringType.annotationName)
+	}
+}
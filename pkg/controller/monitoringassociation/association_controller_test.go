@@ -0,0 +1,147 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package monitoringassociation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	commonv1 "github.com/elastic/cloud-on-k8s/pkg/apis/common/v1"
+	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/annotation"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/association"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/watches"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/rbac"
+)
+
+var (
+	monitoringES = esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "observability", Name: "monitoring"},
+		Spec:       esv1.ElasticsearchSpec{Version: "7.14.0"},
+	}
+	monitoringESCA = corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "observability",
+			Name:      certificates.PublicCertsSecretRef(esv1.ESNamer, k8s.ExtractNamespacedName(&monitoringES)).Name,
+		},
+		Data: map[string][]byte{certificates.CAFileName: []byte("ca")},
+	}
+)
+
+func monitoredES(metrics, logs bool) *esv1.Elasticsearch {
+	es := esv1.Elasticsearch{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "es"},
+		Spec:       esv1.ElasticsearchSpec{Version: "7.14.0"},
+	}
+	ref := []commonv1.ObjectSelector{{Namespace: "observability", Name: "monitoring"}}
+	if metrics {
+		es.Spec.Monitoring.Metrics.ElasticsearchRefs = ref
+	}
+	if logs {
+		es.Spec.Monitoring.Logs.ElasticsearchRefs = ref
+	}
+	return &es
+}
+
+func newTestReconciler(c k8s.Client) *ReconcileMonitoringAssociation {
+	return &ReconcileMonitoringAssociation{
+		Client:         c,
+		kind:           elasticsearchKind,
+		accessReviewer: rbac.NewPermissiveAccessReviewer(),
+		recorder:       record.NewFakeRecorder(10),
+		watches:        watches.NewDynamicWatches(),
+	}
+}
+
+func TestReconcileMonitoringAssociation_Reconcile(t *testing.T) {
+	monitored := monitoredES(true, true)
+	c := k8s.WrappedFakeClient(monitored, &monitoringES, &monitoringESCA)
+	r := newTestReconciler(c)
+	request := reconcile.Request{NamespacedName: k8s.ExtractNamespacedName(monitored)}
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+
+	var updated esv1.Elasticsearch
+	require.NoError(t, c.Get(request.NamespacedName, &updated))
+	for _, monitoringType := range monitoringTypes {
+		conf, err := association.GetAssociationConfFromAnnotation(&updated, monitoringType.annotationName)
+		require.NoError(t, err)
+		require.Equal(t, &commonv1.AssociationConf{
+			AuthSecretName: "es-es-monitoring-" + monitoringType.name + "-user",
+			AuthSecretKey:  "ns-es-es-monitoring-" + monitoringType.name + "-user",
+			CACertProvided: true,
+			CASecretName:   "es-es-monitoring-" + monitoringType.name + "-ca",
+			URL:            "https://monitoring-es-http.observability.svc:9200",
+		}, conf)
+
+		// the user is created in the namespace of the monitoring cluster with the expected role
+		var userSecret corev1.Secret
+		require.NoError(t, c.Get(types.NamespacedName{Namespace: "observability", Name: conf.AuthSecretKey}, &userSecret))
+		require.Equal(t, monitoringType.userRole, string(userSecret.Data[user.UserRolesField]))
+		require.Equal(t, monitoringType.name, userSecret.Labels[AssociationLabelType])
+	}
+
+	// remove logs monitoring
+	updated.Spec.Monitoring.Logs.ElasticsearchRefs = nil
+	require.NoError(t, c.Update(&updated))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+
+	updated = esv1.Elasticsearch{}
+	require.NoError(t, c.Get(request.NamespacedName, &updated))
+	require.Contains(t, updated.Annotations, annotation.MonitoringMetricsAssociationConfAnnotation)
+	require.NotContains(t, updated.Annotations, annotation.MonitoringLogsAssociationConfAnnotation)
+	var secret corev1.Secret
+	err = c.Get(types.NamespacedName{Namespace: "observability", Name: "ns-es-es-monitoring-logs-user"}, &secret)
+	require.True(t, apierrors.IsNotFound(err))
+	err = c.Get(types.NamespacedName{Namespace: "ns", Name: "es-es-monitoring-logs-user"}, &secret)
+	require.True(t, apierrors.IsNotFound(err))
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "observability", Name: "ns-es-es-monitoring-metrics-user"}, &secret))
+}
+
+func TestReconcileMonitoringAssociation_Reconcile_MonitoringClusterNotFound(t *testing.T) {
+	monitored := monitoredES(true, false)
+	c := k8s.WrappedFakeClient(monitored)
+	r := newTestReconciler(c)
+	request := reconcile.Request{NamespacedName: k8s.ExtractNamespacedName(monitored)}
+
+	res, err := r.Reconcile(request)
+	require.NoError(t, err)
+	require.Equal(t, defaultRequeue, res)
+
+	var updated esv1.Elasticsearch
+	require.NoError(t, c.Get(request.NamespacedName, &updated))
+	require.NotContains(t, updated.Annotations, annotation.MonitoringMetricsAssociationConfAnnotation)
+}
+
+func TestReconcileMonitoringAssociation_onDelete(t *testing.T) {
+	monitored := monitoredES(true, true)
+	c := k8s.WrappedFakeClient(monitored, &monitoringES, &monitoringESCA)
+	r := newTestReconciler(c)
+	request := reconcile.Request{NamespacedName: k8s.ExtractNamespacedName(monitored)}
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Delete(monitored))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+
+	var users corev1.SecretList
+	require.NoError(t, c.List(&users, elasticsearchKind.newUserLabelSelector(request.NamespacedName)))
+	require.Empty(t, users.Items)
+	require.Empty(t, r.watches.ElasticsearchClusters.Registrations())
+	require.Empty(t, r.watches.Secrets.Registrations())
+}
@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package monitoringassociation
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/user"
+)
+
+const (
+	// ElasticsearchAssociationLabelName marks resources created by this controller for a monitored Elasticsearch cluster.
+	ElasticsearchAssociationLabelName = "esmonitoringassociation.k8s.elastic.co/name"
+	// ElasticsearchAssociationLabelNamespace marks resources created by this controller for a monitored Elasticsearch cluster.
+	ElasticsearchAssociationLabelNamespace = "esmonitoringassociation.k8s.elastic.co/namespace"
+	// KibanaAssociationLabelName marks resources created by this controller for a monitored Kibana.
+	KibanaAssociationLabelName = "kbmonitoringassociation.k8s.elastic.co/name"
+	// KibanaAssociationLabelNamespace marks resources created by this controller for a monitored Kibana.
+	KibanaAssociationLabelNamespace = "kbmonitoringassociation.k8s.elastic.co/namespace"
+	// AssociationLabelType marks resources created by this controller with the type of the monitoring data
+	// (metrics or logs).
+	AssociationLabelType = "monitoringassociation.k8s.elastic.co/type"
+)
+
+// newUserLabelSelector returns labels matching the users created for the given monitored resource, for all types of
+// monitoring data.
+func (k monitoredKind) newUserLabelSelector(namespacedName types.NamespacedName) client.MatchingLabels {
+	return map[string]string{
+		k.nameLabel:          namespacedName.Name,
+		k.namespaceLabel:     namespacedName.Namespace,
+		common.TypeLabelName: user.AssociatedUserType,
+	}
+}
+
+// newTypeUserLabelSelector returns labels matching the users created for the given monitored resource and type of
+// monitoring data.
+func (k monitoredKind) newTypeUserLabelSelector(namespacedName types.NamespacedName, monitoringType monitoringType) client.MatchingLabels {
+	labels := k.newUserLabelSelector(namespacedName)
+	labels[AssociationLabelType] = monitoringType.name
+	return labels
+}
+
+func (k monitoredKind) associationLabels(namespacedName types.NamespacedName, monitoringType monitoringType) map[string]string {
+	return map[string]string{
+		k.nameLabel:          namespacedName.Name,
+		k.namespaceLabel:     namespacedName.Namespace,
+		AssociationLabelType: monitoringType.name,
+	}
+}
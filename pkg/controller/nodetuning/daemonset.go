@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodetuning
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/daemonset"
+)
+
+const (
+	// DaemonSetName is the name of the node tuning DaemonSet, in the operator namespace.
+	DaemonSetName = "elastic-operator-node-tuning"
+	// Type is the type label value of the node tuning DaemonSet and Pods.
+	Type = "node-tuning"
+	// NodeLabelName is the label set by the operator on the Kubernetes nodes hosting Elasticsearch Pods, on which
+	// the node tuning Pods are scheduled.
+	NodeLabelName = "elasticsearch.k8s.elastic.co/node-tuning"
+	// VMMaxMapCount is the minimum value of the vm.max_map_count kernel setting Elasticsearch requires to use memory
+	// mapping, set by the node tuning Pods.
+	VMMaxMapCount = 262144
+	// DefaultImage is the default image of the node tuning Pods, which only requires sh and sysctl.
+	DefaultImage = "docker.io/library/busybox:1.32"
+
+	sysctlContainerName = "sysctl"
+	sleepContainerName  = "sleep"
+)
+
+var (
+	// resources are the requests and limits of the node tuning containers, which barely do anything.
+	resources = corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("8Mi"),
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("8Mi"),
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+		},
+	}
+
+	// sysctlScript raises vm.max_map_count, leaving higher values set on the node untouched.
+	sysctlScript = fmt.Sprintf(
+		"if [ $(sysctl -n vm.max_map_count) -lt %[1]d ]; then sysctl -w vm.max_map_count=%[1]d; fi", VMMaxMapCount,
+	)
+	// sleepScript keeps the Pod running until it is deleted, so that the init container runs again if the node reboots.
+	sleepScript = "trap 'exit 0' TERM; while true; do sleep 3600 & wait $!; done"
+)
+
+// Labels returns the labels of the node tuning DaemonSet and Pods.
+func Labels() map[string]string {
+	return map[string]string{common.TypeLabelName: Type}
+}
+
+// NewDaemonSet returns the node tuning DaemonSet, running a privileged init container raising vm.max_map_count on the
+// Kubernetes nodes labeled with NodeLabelName.
+func NewDaemonSet(namespace string, image string) appsv1.DaemonSet {
+	privileged := true
+	automountServiceAccountToken := false
+	var rootUser int64
+	return daemonset.New(daemonset.Params{
+		Name:      DaemonSetName,
+		Namespace: namespace,
+		Selector:  Labels(),
+		Labels:    Labels(),
+		PodTemplateSpec: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: Labels()},
+			Spec: corev1.PodSpec{
+				NodeSelector: map[string]string{NodeLabelName: "true"},
+				// Elasticsearch Pods may run on tainted nodes, the node selector is enough to restrict the scheduling
+				Tolerations:                  []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+				AutomountServiceAccountToken: &automountServiceAccountToken,
+				InitContainers: []corev1.Container{{
+					Name:            sysctlContainerName,
+					Image:           image,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Command:         []string{"sh", "-c", sysctlScript},
+					SecurityContext: &corev1.SecurityContext{Privileged: &privileged, RunAsUser: &rootUser},
+					Resources:       resources,
+				}},
+				Containers: []corev1.Container{{
+					Name:            sleepContainerName,
+					Image:           image,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Command:         []string{"sh", "-c", sleepScript},
+					Resources:       resources,
+				}},
+			},
+		},
+		UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType},
+	})
+}
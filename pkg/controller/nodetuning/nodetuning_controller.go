@@ -0,0 +1,236 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package nodetuning raises the vm.max_map_count kernel setting of the Kubernetes nodes hosting Elasticsearch Pods,
+// through a privileged DaemonSet in the operator namespace, so that Elasticsearch Pods do not have to run privileged
+// init containers.
+package nodetuning
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/daemonset"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/operator"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+const name = "node-tuning-controller"
+
+var log = logf.Log.WithName(name)
+
+// Add creates a new node tuning controller and adds it to the manager. The controller reconciles the node tuning
+// DaemonSet, and labels the Kubernetes nodes hosting Elasticsearch Pods for it to be scheduled on them.
+func Add(mgr manager.Manager, params operator.Parameters) error {
+	r := &ReconcileNodeTuning{
+		Client:            k8s.WrapClient(mgr.GetClient()),
+		nodeReader:        mgr.GetAPIReader(),
+		operatorNamespace: params.OperatorNamespace,
+		image:             params.NodeTuningImage,
+	}
+	c, err := common.NewController(mgr, name, r, params)
+	if err != nil {
+		return err
+	}
+	return addWatches(c, r.request())
+}
+
+func addWatches(c controller.Controller, request reconcile.Request) error {
+	// all the events lead to the same single reconciliation request
+	toRequest := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(handler.MapObject) []reconcile.Request {
+			return []reconcile.Request{request}
+		}),
+	}
+
+	// Watch the scheduling and removal of Elasticsearch Pods
+	if err := c.Watch(&source.Kind{Type: &corev1.Pod{}}, toRequest, predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isElasticsearchPod(e.Meta.GetLabels())
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, oldOk := e.ObjectOld.(*corev1.Pod)
+			newPod, newOk := e.ObjectNew.(*corev1.Pod)
+			return oldOk && newOk && isElasticsearchPod(newPod.Labels) && oldPod.Spec.NodeName != newPod.Spec.NodeName
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isElasticsearchPod(e.Meta.GetLabels())
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Watch the node label, in case it is modified by someone else
+	if err := c.Watch(&source.Kind{Type: &corev1.Node{}}, toRequest, predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.MetaOld.GetLabels()[NodeLabelName] != e.MetaNew.GetLabels()[NodeLabelName]
+		},
+		DeleteFunc: func(event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Watch the node tuning DaemonSet
+	return c.Watch(&source.Kind{Type: &appsv1.DaemonSet{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(object handler.MapObject) []reconcile.Request {
+			if object.Meta.GetNamespace() != request.Namespace || object.Meta.GetName() != request.Name {
+				return nil
+			}
+			return []reconcile.Request{request}
+		}),
+	})
+}
+
+func isElasticsearchPod(labels map[string]string) bool {
+	return labels[common.TypeLabelName] == label.Type
+}
+
+var _ reconcile.Reconciler = &ReconcileNodeTuning{}
+
+// ReconcileNodeTuning reconciles the node tuning DaemonSet and the labels of the Kubernetes nodes.
+type ReconcileNodeTuning struct {
+	k8s.Client
+	// nodeReader reads the Kubernetes nodes from the API server, since they are not in the cache of an operator
+	// restricted to some namespaces
+	nodeReader        client.Reader
+	operatorNamespace string
+	image             string
+	// iteration is the number of times this controller has run its Reconcile method
+	iteration uint64
+}
+
+// request is the single reconciliation request of the controller.
+func (r *ReconcileNodeTuning) request() reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: r.operatorNamespace, Name: DaemonSetName}}
+}
+
+// Reconcile reconciles the node tuning DaemonSet, and labels the Kubernetes nodes hosting Elasticsearch Pods.
+func (r *ReconcileNodeTuning) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	defer common.LogReconciliationRun(log, request, "daemonset_name", &r.iteration)()
+
+	// the DaemonSet is not owned by any resource, it is shared by all the Elasticsearch clusters
+	if _, err := daemonset.Reconcile(r.Client, NewDaemonSet(r.operatorNamespace, r.image), nil); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, ReconcileNodeLabels(r.Client, r.nodeReader)
+}
+
+// ReconcileNodeLabels sets NodeLabelName on the Kubernetes nodes hosting Elasticsearch Pods, and removes it from the
+// other nodes. The nodes are read with the given reader, and updated with the client.
+func ReconcileNodeLabels(c k8s.Client, nodeReader client.Reader) error {
+	var pods corev1.PodList
+	if err := c.List(&pods, client.MatchingLabels{common.TypeLabelName: label.Type}); err != nil {
+		return err
+	}
+	expected := make(map[string]struct{})
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" {
+			expected[pod.Spec.NodeName] = struct{}{}
+		}
+	}
+
+	var nodes corev1.NodeList
+	if err := nodeReader.List(context.Background(), &nodes, client.HasLabels{NodeLabelName}); err != nil {
+		return err
+	}
+	labeled := make(map[string]struct{})
+	for i := range nodes.Items {
+		node := nodes.Items[i]
+		labeled[node.Name] = struct{}{}
+		if _, exists := expected[node.Name]; exists {
+			if node.Labels[NodeLabelName] == "true" {
+				continue
+			}
+			// the label value was modified by someone else
+			node.Labels[NodeLabelName] = "true"
+		} else {
+			log.Info("Removing node tuning label", "node_name", node.Name)
+			delete(node.Labels, NodeLabelName)
+		}
+		if err := c.Update(&node); err != nil {
+			return err
+		}
+	}
+
+	for nodeName := range expected {
+		if _, exists := labeled[nodeName]; exists {
+			continue
+		}
+		var node corev1.Node
+		if err := nodeReader.Get(context.Background(), types.NamespacedName{Name: nodeName}, &node); err != nil {
+			return err
+		}
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		log.Info("Setting node tuning label", "node_name", node.Name)
+		node.Labels[NodeLabelName] = "true"
+		if err := c.Update(&node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GarbageCollect deletes the node tuning DaemonSet and removes NodeLabelName from the Kubernetes nodes, once the node
+// tuning is no longer managed by the operator. The nodes the operator is not allowed to update anymore, if the
+// permissions granted for the node tuning were revoked as well, are logged for the label to be removed manually.
+func GarbageCollect(c k8s.Client, reader client.Reader, operatorNamespace string) error {
+	var ds appsv1.DaemonSet
+	err := reader.Get(context.Background(), types.NamespacedName{Namespace: operatorNamespace, Name: DaemonSetName}, &ds)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		log.Info("Deleting node tuning DaemonSet", "namespace", ds.Namespace, "daemonset_name", ds.Name)
+		if err := c.Delete(&ds); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	var nodes corev1.NodeList
+	if err := reader.List(context.Background(), &nodes, client.HasLabels{NodeLabelName}); err != nil {
+		return err
+	}
+	for i := range nodes.Items {
+		node := nodes.Items[i]
+		log.Info("Removing node tuning label", "node_name", node.Name)
+		delete(node.Labels, NodeLabelName)
+		if err := c.Update(&node); err != nil {
+			if apierrors.IsForbidden(err) {
+				log.Info("Not allowed to remove the node tuning label, it must be removed manually",
+					"node_name", node.Name, "label", NodeLabelName)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
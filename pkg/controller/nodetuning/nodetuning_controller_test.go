@@ -0,0 +1,132 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package nodetuning
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common"
+	"github.com/elastic/cloud-on-k8s/pkg/controller/elasticsearch/label"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+func node(name string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func pod(name string, labels map[string]string, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Labels: labels},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+// namespacedClient simulates the cached client of an operator restricted to some namespaces, which cannot read the
+// cluster-scoped Kubernetes nodes.
+type namespacedClient struct {
+	client.Client
+}
+
+func (c namespacedClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if _, isNode := obj.(*corev1.Node); isNode {
+		return errors.New("nodes are not cached")
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c namespacedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if _, isNodeList := list.(*corev1.NodeList); isNodeList {
+		return errors.New("nodes are not cached")
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestReconcileNodeTuning_Reconcile(t *testing.T) {
+	esLabels := map[string]string{common.TypeLabelName: label.Type}
+	tuned := map[string]string{NodeLabelName: "true"}
+	fakeClient := k8s.FakeClient(
+		// Elasticsearch Pods
+		pod("es-0", esLabels, "node-1"),
+		pod("es-1", esLabels, "node-2"),
+		pod("es-2", esLabels, ""),
+		// other Pods
+		pod("kb", map[string]string{common.TypeLabelName: "kibana"}, "node-3"),
+		node("node-1", nil),
+		node("node-2", map[string]string{NodeLabelName: "false", "a": "b"}),
+		node("node-3", tuned),
+		node("node-4", nil),
+	)
+	// the nodes are read from the API server, the operator being restricted to some namespaces
+	c := k8s.WrapClient(namespacedClient{Client: fakeClient})
+	r := &ReconcileNodeTuning{Client: c, nodeReader: fakeClient, operatorNamespace: "elastic-system", image: "busybox"}
+
+	_, err := r.Reconcile(r.request())
+	require.NoError(t, err)
+
+	var ds appsv1.DaemonSet
+	require.NoError(t, c.Get(types.NamespacedName{Namespace: "elastic-system", Name: DaemonSetName}, &ds))
+	require.Equal(t, map[string]string{NodeLabelName: "true"}, ds.Spec.Template.Spec.NodeSelector)
+	require.Equal(t, "busybox", ds.Spec.Template.Spec.InitContainers[0].Image)
+	require.True(t, *ds.Spec.Template.Spec.InitContainers[0].SecurityContext.Privileged)
+
+	nodeLabels := func(name string) map[string]string {
+		var n corev1.Node
+		require.NoError(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: name}, &n))
+		return n.Labels
+	}
+	require.Equal(t, tuned, nodeLabels("node-1"))
+	require.Equal(t, map[string]string{NodeLabelName: "true", "a": "b"}, nodeLabels("node-2"))
+	// no Elasticsearch Pod
+	require.Empty(t, nodeLabels("node-3"))
+	require.Empty(t, nodeLabels("node-4"))
+}
+
+// forbiddenNodeClient simulates an operator not allowed to update the given Kubernetes node.
+type forbiddenNodeClient struct {
+	client.Client
+	nodeName string
+}
+
+func (c forbiddenNodeClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if n, isNode := obj.(*corev1.Node); isNode && n.Name == c.nodeName {
+		return apierrors.NewForbidden(schema.GroupResource{Resource: "nodes"}, n.Name, errors.New("forbidden"))
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestGarbageCollect(t *testing.T) {
+	tuned := map[string]string{NodeLabelName: "true", "a": "b"}
+	ds := NewDaemonSet("elastic-system", "busybox")
+	fakeClient := k8s.FakeClient(&ds, node("node-1", tuned), node("node-2", tuned), node("node-3", map[string]string{"a": "b"}))
+	c := k8s.WrapClient(forbiddenNodeClient{Client: fakeClient, nodeName: "node-2"})
+
+	require.NoError(t, GarbageCollect(c, fakeClient, "elastic-system"))
+	err := fakeClient.Get(context.Background(), types.NamespacedName{Namespace: "elastic-system", Name: DaemonSetName}, &appsv1.DaemonSet{})
+	require.True(t, apierrors.IsNotFound(err))
+	nodeLabels := func(name string) map[string]string {
+		var n corev1.Node
+		require.NoError(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: name}, &n))
+		return n.Labels
+	}
+	require.Equal(t, map[string]string{"a": "b"}, nodeLabels("node-1"))
+	// not allowed to be updated, left for the label to be removed manually
+	require.Equal(t, tuned, nodeLabels("node-2"))
+	require.Equal(t, map[string]string{"a": "b"}, nodeLabels("node-3"))
+
+	// nothing left to garbage collect
+	require.NoError(t, GarbageCollect(k8s.WrapClient(fakeClient), fakeClient, "elastic-system"))
+}
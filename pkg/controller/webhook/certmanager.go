@@ -0,0 +1,87 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package webhook
+
+import (
+	"bytes"
+
+	"k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
+	"github.com/elastic/cloud-on-k8s/pkg/utils/k8s"
+)
+
+var certificatesResource = schema.GroupVersionResource{
+	Group:    certificates.CertManagerGroup,
+	Version:  certificates.CertManagerCertificateGVK.Version,
+	Resource: "certificates",
+}
+
+// reconcileCertManagerResources reconciles a cert-manager Certificate issuing the webhook server certificate directly
+// into the webhook server secret, and sets the CA of the issued certificate in the webhook configuration.
+func (w *Params) reconcileCertManagerResources(
+	clientset kubernetes.Interface,
+	webhookServerSecret *corev1.Secret,
+	webhookConfiguration *v1beta1.ValidatingWebhookConfiguration,
+) error {
+	expected := certificates.CertManagerCertificate{
+		Name:       w.SecretName,
+		Namespace:  w.Namespace,
+		Issuer:     *w.CertManagerIssuer,
+		CommonName: "elastic-webhook",
+		DNSNames: k8s.GetServiceDNSName(corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: w.Namespace,
+				Name:      WebhookServiceName,
+			},
+		}),
+		Rotation: w.Rotation,
+	}.Unstructured()
+
+	certs := w.DynamicClient.Resource(certificatesResource).Namespace(w.Namespace)
+	reconciled, err := certs.Get(expected.GetName(), metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		log.Info("Creating webhook cert-manager certificate", "namespace", w.Namespace, "name", expected.GetName())
+		if _, err := certs.Create(expected, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !equality.Semantic.DeepDerivative(expected.Object["spec"], reconciled.Object["spec"]):
+		log.Info("Updating webhook cert-manager certificate", "namespace", w.Namespace, "name", expected.GetName())
+		reconciled.Object["spec"] = expected.Object["spec"]
+		if _, err := certs.Update(reconciled, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	caCert := webhookServerSecret.Data[certificates.CAFileName]
+	if len(webhookServerSecret.Data[certificates.CertFileName]) == 0 || len(caCert) == 0 {
+		// the webhook server secret is watched, the webhook configuration is updated once the certificate is issued
+		log.Info("Webhook certificate not issued yet by cert-manager", "issuer", w.CertManagerIssuer.String())
+		return nil
+	}
+
+	needsUpdate := false
+	for i := range webhookConfiguration.Webhooks {
+		if !bytes.Equal(webhookConfiguration.Webhooks[i].ClientConfig.CABundle, caCert) {
+			webhookConfiguration.Webhooks[i].ClientConfig.CABundle = caCert
+			needsUpdate = true
+		}
+	}
+	if !needsUpdate {
+		return nil
+	}
+	log.Info("Updating webhook CA bundle", "webhook", webhookConfiguration.Name)
+	_, err = clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Update(webhookConfiguration)
+	return err
+}
@@ -6,6 +6,7 @@ package webhook
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
@@ -19,6 +20,11 @@ type Params struct {
 
 	// Certificate options
 	Rotation certificates.RotationParams
+
+	// CertManagerIssuer is the cert-manager issuer of the webhook server certificate, nil if the certificate is self-signed.
+	CertManagerIssuer *certificates.IssuerRef
+	// DynamicClient is used to manage the cert-manager Certificate.
+	DynamicClient dynamic.Interface
 }
 
 // ReconcileResources reconciles the certificates used by the webhook client and the webhook server.
@@ -38,6 +44,10 @@ func (w *Params) ReconcileResources(clientset kubernetes.Interface) error {
 		return err
 	}
 
+	if w.CertManagerIssuer != nil {
+		return w.reconcileCertManagerResources(clientset, webhookServerSecret, webhookConfiguration)
+	}
+
 	// check if we need to renew the certificates used in the resources
 	if w.shouldRenewCertificates(webhookServerSecret, webhookConfiguration) {
 		log.Info(
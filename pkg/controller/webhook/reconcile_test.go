@@ -13,6 +13,8 @@ import (
 	"k8s.io/api/admissionregistration/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/elastic/cloud-on-k8s/pkg/controller/common/certificates"
@@ -95,6 +97,62 @@ func TestParams_ReconcileResources(t *testing.T) {
 
 }
 
+func TestParams_ReconcileResources_CertManager(t *testing.T) {
+	w := Params{
+		Namespace:                "elastic-system",
+		SecretName:               "elastic-webhook-server-cert",
+		WebhookConfigurationName: "elastic-webhook.k8s.elastic.co",
+		Rotation: certificates.RotationParams{
+			Validity:     certificates.DefaultCertValidity,
+			RotateBefore: certificates.DefaultRotateBefore,
+		},
+		CertManagerIssuer: &certificates.IssuerRef{Kind: certificates.IssuerKind, Name: "my-issuer"},
+		DynamicClient:     dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+	}
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "elastic-system",
+				Name:      "elastic-webhook-server-cert",
+			},
+		},
+		&v1beta1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "elastic-webhook.k8s.elastic.co",
+			},
+			Webhooks: []v1beta1.ValidatingWebhook{
+				{
+					Name:         "elastic-es-validation-v1.k8s.elastic.co",
+					ClientConfig: v1beta1.WebhookClientConfig{},
+				},
+			},
+		},
+	)
+
+	// a Certificate issuing the webhook server certificate into the webhook secret is created
+	assert.NoError(t, w.ReconcileResources(clientset))
+	cert, err := w.DynamicClient.Resource(certificatesResource).Namespace(w.Namespace).Get(w.SecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, w.SecretName, cert.Object["spec"].(map[string]interface{})["secretName"])
+	// nothing is self-signed while waiting for cert-manager
+	webhookServerSecret, err := clientset.CoreV1().Secrets(w.Namespace).Get(w.SecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, webhookServerSecret.Data)
+
+	// the CA of the issued certificate is set in the webhook configuration
+	webhookServerSecret.Data = map[string][]byte{
+		certificates.CertFileName: []byte("cert"),
+		certificates.KeyFileName:  []byte("key"),
+		certificates.CAFileName:   []byte("ca"),
+	}
+	_, err = clientset.CoreV1().Secrets(w.Namespace).Update(webhookServerSecret)
+	assert.NoError(t, err)
+	assert.NoError(t, w.ReconcileResources(clientset))
+	webhookConfiguration, err := clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get(w.WebhookConfigurationName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ca"), webhookConfiguration.Webhooks[0].ClientConfig.CABundle)
+}
+
 func verifyCertificates(t *testing.T, rootCert []byte, serverCert []byte) {
 	ca := x509.NewCertPool()
 	ok := ca.AppendCertsFromPEM(rootCert)
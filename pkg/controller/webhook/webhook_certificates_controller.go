@@ -64,6 +64,16 @@ func (r *ReconcileWebhookResources) reconcileInternal() *reconciler.Results {
 	if err != nil {
 		return res.WithError(err)
 	}
+	if serverCert, err := certificates.GetPrimaryCertificate(webhookServerSecret.Data[certificates.CertFileName]); err == nil {
+		certificates.ReportExpiration(
+			certificates.KindOf(webhookServerSecret), k8s.ExtractNamespacedName(webhookServerSecret),
+			certificates.WebhookCertificate, serverCert.NotAfter,
+		)
+	}
+	if r.webhookParams.CertManagerIssuer != nil {
+		// renewals are handled by cert-manager
+		return res
+	}
 	serverCA := certificates.BuildCAFromSecret(*webhookServerSecret)
 	if serverCA == nil {
 		return res.WithError(
@@ -0,0 +1,171 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package chrono
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears bounds the search for the next activation of a schedule, which may never happen (eg. February 30th).
+const maxSearchYears = 5
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	dayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// cronField describes the allowed values of a field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField     = cronField{name: "minute", min: 0, max: 59}
+	hourField       = cronField{name: "hour", min: 0, max: 23}
+	dayOfMonthField = cronField{name: "day of month", min: 1, max: 31}
+	monthField      = cronField{name: "month", min: 1, max: 12, names: monthNames}
+	// 7 is accepted as an alias for Sunday
+	dayOfWeekField = cronField{name: "day of week", min: 0, max: 7, names: dayNames}
+)
+
+// CronSchedule is a parsed cron expression, evaluated in UTC.
+type CronSchedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek uint64
+	// restricted days of month and days of week are combined with a logical OR, as in the standard cron
+	dayOfMonthStar, dayOfWeekStar bool
+}
+
+// ParseCronSchedule parses a standard cron expression made of 5 space-separated fields: minute, hour, day of month,
+// month and day of week. Each field can be a wildcard (*), a value, a range (1-5), a list (1,3,5), or a wildcard or
+// range with a step (*/15, 0-30/10). Months and days of week can also be specified with their 3-letter English names.
+func ParseCronSchedule(expr string) (CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return CronSchedule{}, fmt.Errorf("expected 5 fields in cron expression %q, found %d", expr, len(fields))
+	}
+	var s CronSchedule
+	var err error
+	if s.minutes, err = minuteField.parse(fields[0]); err != nil {
+		return CronSchedule{}, err
+	}
+	if s.hours, err = hourField.parse(fields[1]); err != nil {
+		return CronSchedule{}, err
+	}
+	if s.daysOfMonth, err = dayOfMonthField.parse(fields[2]); err != nil {
+		return CronSchedule{}, err
+	}
+	if s.months, err = monthField.parse(fields[3]); err != nil {
+		return CronSchedule{}, err
+	}
+	if s.daysOfWeek, err = dayOfWeekField.parse(fields[4]); err != nil {
+		return CronSchedule{}, err
+	}
+	if s.daysOfWeek&(1<<7) != 0 {
+		s.daysOfWeek |= 1
+	}
+	s.dayOfMonthStar = strings.HasPrefix(fields[2], "*")
+	s.dayOfWeekStar = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parse returns the bitset of the values matched by the given field expression.
+func (f cronField) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangeExpr = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, part)
+			}
+		}
+		var low, high int
+		switch {
+		case rangeExpr == "*":
+			low, high = f.min, f.max
+		case strings.Contains(rangeExpr, "-"):
+			bounds := strings.SplitN(rangeExpr, "-", 2)
+			var err error
+			if low, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range in %s field %q", f.name, part)
+			}
+		default:
+			value, err := f.value(rangeExpr)
+			if err != nil {
+				return 0, err
+			}
+			low, high = value, value
+			if step > 1 {
+				// a single value with a step ranges until the max value
+				high = f.max
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single value of the field.
+func (f cronField) value(expr string) (int, error) {
+	if v, exists := f.names[strings.ToLower(expr)]; exists {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q, expected a value between %d and %d", f.name, expr, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time strictly after t matching the schedule, with a minute precision.
+// The zero time is returned if the schedule does not match any time in the next few years.
+func (s CronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s CronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.dayOfMonthStar || s.dayOfWeekStar {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package chrono
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	for _, expr := range []string{
+		"* * * * *",
+		"0 2 * * 6,0",
+		"0 2 * * SAT,sun",
+		"*/15 0-6 1,15 jan-jun 1-5",
+		"0-30/10 * * * 7",
+		"5/20 * * * *",
+	} {
+		_, err := ParseCronSchedule(expr)
+		require.NoError(t, err, expr)
+	}
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+	} {
+		_, err := ParseCronSchedule(expr)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2021, 3, 10, 14, 27, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "every minute",
+			expr: "* * * * *",
+			from: now,
+			want: time.Date(2021, 3, 10, 14, 28, 0, 0, time.UTC),
+		},
+		{
+			name: "strictly after the given time",
+			expr: "28 14 * * *",
+			from: time.Date(2021, 3, 10, 14, 28, 0, 0, time.UTC),
+			want: time.Date(2021, 3, 11, 14, 28, 0, 0, time.UTC),
+		},
+		{
+			name: "every 15 minutes",
+			expr: "*/15 * * * *",
+			from: now,
+			want: time.Date(2021, 3, 10, 14, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "weekends at 2am",
+			expr: "0 2 * * sat,sun",
+			from: now,
+			want: time.Date(2021, 3, 13, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Sunday as 7",
+			expr: "0 2 * * 7",
+			from: now,
+			want: time.Date(2021, 3, 14, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 0 1 * 5",
+			from: now,
+			want: time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "next year",
+			expr: "0 0 1 jan *",
+			from: now,
+			want: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leap day",
+			expr: "0 0 29 2 *",
+			from: now,
+			want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "never",
+			expr: "0 0 30 2 *",
+			from: now,
+			want: time.Time{},
+		},
+		{
+			name: "evaluated in UTC",
+			expr: "0 2 * * *",
+			// 1am UTC
+			from: time.Date(2021, 3, 10, 3, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+			want: time.Date(2021, 3, 10, 2, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCronSchedule(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, s.Next(tt.from))
+		})
+	}
+}